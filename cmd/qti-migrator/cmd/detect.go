@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/qti-migrator/pkg/qtimigrate"
+	"github.com/spf13/cobra"
+)
+
+var detectCmd = &cobra.Command{
+	Use:   "detect",
+	Short: "Detect the QTI version of a file or content package",
+	Long: `Detect the QTI version of a file from its root element, namespace, version
+attribute and schema location, or of a zipped content package from the
+resource types of its manifest. Conflicting signals are reported as an error.`,
+	RunE: runDetect,
+	// Detection errors are not usage errors
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(detectCmd)
+
+	detectCmd.Flags().StringVarP(&inputFile, "input", "i", "-", "Input file path (use '-' for stdin)")
+}
+
+func runDetect(cmd *cobra.Command, args []string) error {
+	var input io.Reader = os.Stdin
+	if inputFile != "-" {
+		file, err := os.Open(inputFile)
+		if err != nil {
+			return fmt.Errorf("error opening input file: %w", err)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	detection, err := qtimigrate.Detect(input)
+	if err != nil {
+		return fmt.Errorf("error detecting QTI version: %w", err)
+	}
+
+	fmt.Println(detection.Version)
+
+	if verbosity >= 2 {
+		fmt.Fprintf(os.Stderr, "Evidence: %s\n", strings.Join(detection.Evidence, ", "))
+	}
+
+	return nil
+}
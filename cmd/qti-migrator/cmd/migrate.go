@@ -1,14 +1,17 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
-	"github.com/qti-migrator/internal/migrator"
-	"github.com/qti-migrator/internal/preprocessor"
-	"github.com/qti-migrator/internal/report"
+	"github.com/qti-migrator/pkg/qtimigrate"
+	"github.com/qti-migrator/pkg/registry"
 )
 
 var (
@@ -18,13 +21,31 @@ var (
 	toVersion    string
 	previewOnly  bool
 	forceOverwrite bool
+	strict         bool
+	identifiers    string
+	layout         string
+	profile        string
+	validate       bool
 )
 
 var migrateCmd = &cobra.Command{
 	Use:   "migrate",
 	Short: "Migrate QTI files between versions",
-	Long:  `Migrate QTI files from one version to another. Supports migration from QTI 1.2 to 2.1.`,
-	RunE:  runMigrate,
+	Long: `Migrate QTI files from one version to another along one of the supported
+paths listed below. Versions without a direct migration are reached through
+the intermediate ones.
+
+The input may be a single QTI XML document or a zipped IMS Content Package. For
+packages every QTI resource listed in imsmanifest.xml is migrated and a new
+package with a manifest for the target version is written.
+
+A document holding several items is written as one file per item plus a test
+that references them. Give an output path ending in .zip for a content
+package, or any other path for a directory holding the files and their
+imsmanifest.xml.`,
+	RunE: runMigrate,
+	// Migration errors are not usage errors
+	SilenceUsage: true,
 }
 
 func init() {
@@ -32,23 +53,86 @@ func init() {
 
 	migrateCmd.Flags().StringVarP(&inputFile, "input", "i", "-", "Input file path (use '-' for stdin)")
 	migrateCmd.Flags().StringVarP(&outputFile, "output", "o", "-", "Output file path (use '-' for stdout)")
-	migrateCmd.Flags().StringVarP(&fromVersion, "from", "f", "", "Source QTI version (1.2, 2.1)")
-	migrateCmd.Flags().StringVarP(&toVersion, "to", "t", "", "Target QTI version (2.1, 3.0)")
+	sources, targets := registeredVersions()
+	migrateCmd.Long += "\n\nSupported migration paths:\n" + supportedPaths()
+
+	migrateCmd.Flags().StringVarP(&fromVersion, "from", "f", "", fmt.Sprintf("Source QTI version (%s); detected from the input when omitted", strings.Join(sources, ", ")))
+	migrateCmd.Flags().StringVarP(&toVersion, "to", "t", "", fmt.Sprintf("Target QTI version (%s)", strings.Join(targets, ", ")))
 	migrateCmd.Flags().BoolVarP(&previewOnly, "preview", "p", false, "Preview migration without executing")
 	migrateCmd.Flags().BoolVarP(&forceOverwrite, "force", "", false, "Force overwrite output file if it exists")
+	migrateCmd.Flags().BoolVar(&strict, "strict", false, "Fail on content that would be approximated or dropped")
+	migrateCmd.Flags().StringVar(&identifiers, "identifiers", "keep", "Handling of invalid identifiers (keep, sanitize, reject)")
+	migrateCmd.Flags().StringVar(&layout, "layout", "files", "Layout of documents holding several items (files, flat, single)")
+	migrateCmd.Flags().StringVar(&profile, "profile", "", "Version the output is written for within the target's family, e.g. 2.2 for --to 2.1")
+	migrateCmd.Flags().BoolVar(&validate, "validate", false, "Check the input and the output against the bundled QTI structure rules and report violations")
 
-	if err := migrateCmd.MarkFlagRequired("from"); err != nil {
-		panic(err)
-	}
 	if err := migrateCmd.MarkFlagRequired("to"); err != nil {
 		panic(err)
 	}
 }
 
+// supportedPaths lists the migrations the registered steps make possible,
+// followed by the steps and their capabilities.
+func supportedPaths() string {
+	var builder strings.Builder
+	for _, path := range registry.Default.Paths() {
+		fmt.Fprintf(&builder, "  %s\n", path)
+	}
+	builder.WriteString("\nMigration steps:\n")
+	for _, step := range registry.Default.Steps() {
+		fmt.Fprintf(&builder, "  %-12s %s\n", step, strings.Join(registry.Default.Capabilities(step), ", "))
+	}
+	return builder.String()
+}
+
+// migrationOptions reads the options of the migration steps from the flags.
+func migrationOptions() (registry.MigrationOptions, error) {
+	options := registry.MigrationOptions{Strict: strict, Profile: profile}
+
+	var err error
+	if options.Identifiers, err = registry.ParseIdentifierPolicy(identifiers); err != nil {
+		return options, err
+	}
+	if options.Layout, err = registry.ParseLayout(layout); err != nil {
+		return options, err
+	}
+	return options, nil
+}
+
+// registeredVersions returns the versions a migration path can start from and
+// the versions the registered steps migrate to, sorted.
+func registeredVersions() ([]string, []string) {
+	starts, targets := map[string]bool{}, map[string]bool{}
+	for _, path := range registry.Default.Paths() {
+		starts[path.From] = true
+		targets[path.To] = true
+	}
+	// Sources are the versions read by the parser of a path's start
+	sources := map[string]bool{}
+	for _, version := range registry.Default.Versions() {
+		if versionParser, err := registry.Default.Parser(version); err == nil && starts[versionParser.Version()] {
+			sources[version] = true
+		}
+	}
+	return sortedKeys(sources), sortedKeys(targets)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	var keys []string
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func runMigrate(cmd *cobra.Command, args []string) error {
+	options, err := migrationOptions()
+	if err != nil {
+		return err
+	}
+
 	var input io.Reader
-	var output io.Writer
-	var err error
 
 	if inputFile == "-" {
 		input = os.Stdin
@@ -61,66 +145,129 @@ func runMigrate(cmd *cobra.Command, args []string) error {
 		input = file
 	}
 
-	if !previewOnly {
-		if outputFile == "-" {
-			output = os.Stdout
-		} else {
-			if !forceOverwrite {
-				if _, err := os.Stat(outputFile); err == nil {
-					return fmt.Errorf("output file already exists: %s (use --force to overwrite)", outputFile)
-				}
-			}
-			file, err := os.Create(outputFile)
-			if err != nil {
-				return fmt.Errorf("error creating output file: %w", err)
-			}
-			defer file.Close()
-			output = file
+	if !previewOnly && outputFile != "-" && !forceOverwrite {
+		if _, err := os.Stat(outputFile); err == nil {
+			return fmt.Errorf("output file already exists: %s (use --force to overwrite)", outputFile)
 		}
 	}
 
-	content, err := io.ReadAll(input)
+	result, err := qtimigrate.Migrate(cmd.Context(), input, qtimigrate.Options{
+		From:             fromVersion,
+		To:               toVersion,
+		Verbosity:        verbosity,
+		Preview:          previewOnly,
+		Validate:         validate,
+		MigrationOptions: options,
+	})
+	if result != nil {
+		printReports(result)
+	}
 	if err != nil {
-		return fmt.Errorf("error reading input: %w", err)
+		return commandError(err)
+	}
+
+	if previewOnly {
+		return nil
 	}
 
-	processor := preprocessor.New(verbosity)
-	analysisReport, err := processor.Analyze(content, fromVersion, toVersion)
+	if result.IsPackage() {
+		return writePackage(result)
+	}
+	if len(result.Files) > 1 {
+		return writeFiles(result)
+	}
+
+	err = writeOutput(result.Write)
 	if err != nil {
-		return fmt.Errorf("error analyzing file: %w", err)
+		return fmt.Errorf("error writing output: %w", err)
 	}
 
-	reporter := report.New(verbosity)
-	reportOutput := reporter.Generate(analysisReport)
-	
-	if verbosity >= 1 || previewOnly {
-		fmt.Fprintln(os.Stderr, reportOutput)
+	if verbosity >= 1 && outputFile != "-" {
+		fmt.Fprintf(os.Stderr, "Migration completed successfully. Output written to: %s\n", outputFile)
 	}
 
-	if previewOnly {
-		return nil
+	return nil
+}
+
+// printReports prints how the source version was detected and the analysis
+// report of the document or of each resource of a content package.
+func printReports(result *qtimigrate.Result) {
+	if result.Detection != nil && verbosity >= 2 {
+		fmt.Fprintf(os.Stderr, "Detected QTI %s (%s)\n", result.Detection.Version, strings.Join(result.Detection.Evidence, ", "))
+	}
+	if verbosity < 1 && !previewOnly {
+		return
 	}
 
-	if analysisReport.HasErrors() {
-		return fmt.Errorf("migration cannot proceed due to errors. See report above for details")
+	if result.Report != nil {
+		fmt.Fprintln(os.Stderr, qtimigrate.FormatReport(result.Report, verbosity))
+	}
+	for _, resourceReport := range result.Resources {
+		fmt.Fprintf(os.Stderr, "Resource: %s (%s)\n", resourceReport.ResourceID, resourceReport.Href)
+		fmt.Fprintln(os.Stderr, qtimigrate.FormatReport(resourceReport.Report, verbosity))
 	}
+}
 
-	m := migrator.New()
-	result, err := m.Migrate(content, fromVersion, toVersion)
-	if err != nil {
-		return fmt.Errorf("error during migration: %w", err)
+// commandError words the errors of a migration for the command line.
+func commandError(err error) error {
+	var migrationErr *qtimigrate.Error
+	if !errors.As(err, &migrationErr) {
+		return err
 	}
+	switch migrationErr.Kind {
+	case qtimigrate.KindDetection:
+		return fmt.Errorf("could not detect source version, use --from: %w", migrationErr.Err)
+	case qtimigrate.KindBlocked:
+		return fmt.Errorf("migration cannot proceed due to errors. See report above for details")
+	default:
+		return err
+	}
+}
 
-	if output != nil {
-		_, err = output.Write(result)
-		if err != nil {
+// writeFiles writes the item and test files of a migration as a content
+// package when the output is a .zip file or stdout, and to a directory
+// otherwise.
+func writeFiles(result *qtimigrate.Result) error {
+	if outputFile == "-" || strings.EqualFold(filepath.Ext(outputFile), ".zip") {
+		if err := writeOutput(result.Write); err != nil {
 			return fmt.Errorf("error writing output: %w", err)
 		}
+	} else if err := result.WriteDir(outputFile); err != nil {
+		return fmt.Errorf("error writing output: %w", err)
 	}
 
 	if verbosity >= 1 && outputFile != "-" {
-		fmt.Fprintf(os.Stderr, "Migration completed successfully. Output written to: %s\n", outputFile)
+		fmt.Fprintf(os.Stderr, "Migration completed successfully. %d files written to: %s\n", len(result.Files), outputFile)
+	}
+	return nil
+}
+
+// writeOutput runs write on stdout or on the output file, which is created
+// only now so that a failed migration leaves no empty file behind.
+func writeOutput(write func(io.Writer) error) error {
+	if outputFile == "-" {
+		return write(os.Stdout)
+	}
+
+	file, err := os.Create(outputFile)
+	if err != nil {
+		return fmt.Errorf("error creating output file: %w", err)
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func writePackage(result *qtimigrate.Result) error {
+	if err := writeOutput(result.Write); err != nil {
+		return fmt.Errorf("error writing output: %w", err)
+	}
+
+	if verbosity >= 1 && outputFile != "-" {
+		fmt.Fprintf(os.Stderr, "Migrated %d resources. Package written to: %s\n", len(result.Resources), outputFile)
 	}
 
 	return nil
-}
\ No newline at end of file
+}
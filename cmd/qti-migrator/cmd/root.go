@@ -16,9 +16,13 @@ var (
 var rootCmd = &cobra.Command{
 	Use:   "qti-migrator",
 	Short: "QTI file migration tool",
-	Long: `QTI Migrater is a CLI tool for migrating QTI (Question and Test Interoperability) 
-files between different versions. Currently supports migration from QTI 1.2 to 2.1, 
-with future support planned for QTI 2.1 to 3.0 (JSON format).`,
+	Long: `QTI Migrator is a CLI tool for migrating QTI (Question and Test Interoperability)
+documents and content packages between versions: QTI 1.2 to 2.1 and 3.0, and
+QTI 2.1 and 2.2 to 3.0. It also detects the QTI version of a file and checks
+files against the structure rules of their version. Run "migrate --help" for
+the migration paths and steps.`,
+	// Execute prints the error
+	SilenceErrors: true,
 }
 
 func Execute() {
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/qti-migrator/pkg/qtimigrate"
+	"github.com/spf13/cobra"
+)
+
+var rulesVersion string
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check a QTI file against the structure rules of its version",
+	Long: `Check a QTI document or a zipped IMS Content Package against the structure
+rules of its QTI version: the elements, attributes and content models the QTI
+specifications describe. The rules are written by hand and built into the
+command. They are not the official IMS Global schemas, so this is not schema
+validation: a file that passes may still be rejected by the official schemas,
+and the other way round. For packages the main file of every QTI resource
+listed in imsmanifest.xml is checked.
+
+Violations are printed one per line with the path of the element they are
+found at, and the command fails when there are any.`,
+	RunE: runValidate,
+	// Violations are not a usage error
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	validateCmd.Flags().StringVarP(&inputFile, "input", "i", "-", "Input file path (use '-' for stdin)")
+	validateCmd.Flags().StringVar(&rulesVersion, "qti-version", "", fmt.Sprintf("QTI version whose structure rules to check against (%s); detected from the input when omitted", strings.Join(qtimigrate.RuleVersions(), ", ")))
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	var input io.Reader = os.Stdin
+	if inputFile != "-" {
+		file, err := os.Open(inputFile)
+		if err != nil {
+			return fmt.Errorf("error opening input file: %w", err)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	violations, err := qtimigrate.Validate(input, rulesVersion)
+	if err != nil {
+		return fmt.Errorf("error checking input: %w", err)
+	}
+
+	for _, violation := range violations {
+		fmt.Println(violation)
+	}
+	switch len(violations) {
+	case 0:
+	case 1:
+		return fmt.Errorf("1 structure violation found")
+	default:
+		return fmt.Errorf("%d structure violations found", len(violations))
+	}
+	if verbosity >= 1 {
+		fmt.Println("valid")
+	}
+	return nil
+}
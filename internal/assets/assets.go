@@ -0,0 +1,112 @@
+// Package assets finds the images, media and stylesheets that QTI documents
+// reference, and rewrites those references when a document moves to another
+// directory.
+package assets
+
+import (
+	"encoding/xml"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/qti-migrator/internal/markup"
+)
+
+// references maps the elements that reference an asset, in QTI 2.x and 3.0,
+// to the attribute holding its URI.
+var references = map[string]string{
+	"img":            "src",
+	"object":         "data",
+	"audio":          "src",
+	"video":          "src",
+	"source":         "src",
+	"track":          "src",
+	"stylesheet":     "href",
+	"qti-stylesheet": "href",
+}
+
+// List returns the relative asset references of a document in the order
+// they appear, each once. A document that cannot be tokenized as XML has
+// none.
+func List(content []byte) []string {
+	var hrefs []string
+	seen := make(map[string]bool)
+	_, ok := rewrite(string(content), func(href string) string {
+		if !seen[href] {
+			seen[href] = true
+			hrefs = append(hrefs, href)
+		}
+		return href
+	})
+	if !ok {
+		return nil
+	}
+	return hrefs
+}
+
+// Rebase rewrites the relative asset references of a document written to
+// dir, relative to the directory the references were written for. For an
+// item moved to items/, src="images/a.png" becomes src="../images/a.png".
+// Absolute URIs, rooted paths and fragments are left alone, as is a document
+// that cannot be tokenized as XML.
+func Rebase(content []byte, dir string) []byte {
+	dir = path.Clean(dir)
+	if dir == "." {
+		return content
+	}
+	prefix := strings.Repeat("../", strings.Count(dir, "/")+1)
+	rewritten, ok := rewrite(string(content), func(href string) string {
+		return prefix + href
+	})
+	if !ok {
+		return content
+	}
+	return []byte(rewritten)
+}
+
+// rewrite replaces each relative asset reference of content by what replace
+// returns for it. Tags that do not change are copied as written.
+func rewrite(content string, replace func(href string) string) (string, bool) {
+	var out strings.Builder
+	err := markup.Walk(content, func(token markup.Token) {
+		start, isStart := token.Token.(xml.StartElement)
+		if !isStart || !rewriteReference(&start, replace) {
+			out.WriteString(token.Raw)
+			return
+		}
+		out.WriteString(markup.StartTag(start, token.SelfClosing))
+	})
+	if err != nil {
+		return "", false
+	}
+	return out.String(), true
+}
+
+// rewriteReference replaces the asset reference of start, and tells whether
+// it changed.
+func rewriteReference(start *xml.StartElement, replace func(href string) string) bool {
+	name, ok := references[start.Name.Local]
+	if !ok || start.Name.Space != "" {
+		return false
+	}
+	for i, attr := range start.Attr {
+		if attr.Name.Space != "" || attr.Name.Local != name || !isRelative(attr.Value) {
+			continue
+		}
+		if replaced := replace(attr.Value); replaced != attr.Value {
+			start.Attr[i].Value = replaced
+			return true
+		}
+	}
+	return false
+}
+
+// isRelative tells whether href is a relative path, which resolves against
+// the directory of the document.
+func isRelative(href string) bool {
+	if href == "" || strings.HasPrefix(href, "/") || strings.HasPrefix(href, "#") {
+		return false
+	}
+	uri, err := url.Parse(href)
+	return err == nil && uri.Scheme == "" && uri.Host == ""
+}
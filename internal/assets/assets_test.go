@@ -0,0 +1,71 @@
+package assets
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRebase(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		dir      string
+		expected string
+	}{
+		{
+			name:     "relative references",
+			content:  `<itemBody><img src="a.png" alt="a"/><object data="media/b.mp4" type="video/mp4"></object><stylesheet href="style.css"/></itemBody>`,
+			dir:      "items",
+			expected: `<itemBody><img src="../a.png" alt="a"/><object data="../media/b.mp4" type="video/mp4"></object><stylesheet href="../style.css"/></itemBody>`,
+		},
+		{
+			name:     "nested directory",
+			content:  `<qti-item-body><audio src="../sound.mp3"/><qti-stylesheet href="style.css"/></qti-item-body>`,
+			dir:      "a/b/",
+			expected: `<qti-item-body><audio src="../../../sound.mp3"/><qti-stylesheet href="../../style.css"/></qti-item-body>`,
+		},
+		{
+			name:     "absolute and rooted references",
+			content:  `<p><img src="https://example.com/a.png"/><img src="/a.png"/><img src="data:image/png;base64,AA=="/><object data="#part"/></p>`,
+			dir:      "items",
+			expected: `<p><img src="https://example.com/a.png"/><img src="/a.png"/><img src="data:image/png;base64,AA=="/><object data="#part"/></p>`,
+		},
+		{
+			name:     "other attributes and text",
+			content:  `<p><a href="page.html">link</a> <![CDATA[<img src="a.png"/>]]> <img alt="src"/></p>`,
+			dir:      "items",
+			expected: `<p><a href="page.html">link</a> <![CDATA[<img src="a.png"/>]]> <img alt="src"/></p>`,
+		},
+		{
+			name:     "same directory",
+			content:  `<p><img src="a.png"/></p>`,
+			dir:      "",
+			expected: `<p><img src="a.png"/></p>`,
+		},
+		{
+			name:     "not XML",
+			content:  `<p><img src="a.png"`,
+			dir:      "items",
+			expected: `<p><img src="a.png"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rebased := string(Rebase([]byte(tt.content), tt.dir)); rebased != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, rebased)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	content := `<qti-assessment-item><qti-stylesheet href="style.css"/><qti-item-body><img src="a.png"/><img src="https://example.com/b.png"/><video src="c.mp4"><source src="c.webm"/></video><img src="a.png"/></qti-item-body></qti-assessment-item>`
+	expected := "style.css a.png c.mp4 c.webm"
+	if hrefs := strings.Join(List([]byte(content)), " "); hrefs != expected {
+		t.Errorf("Expected assets %s, got %s", expected, hrefs)
+	}
+	if hrefs := List([]byte(`<p><img src="a.png"/><b`)); len(hrefs) != 0 {
+		t.Errorf("Expected no assets in markup that is not XML, got %v", hrefs)
+	}
+}
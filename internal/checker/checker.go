@@ -0,0 +1,139 @@
+// Package checker finds items that are well-formed but do not make sense,
+// such as interactions bound to undeclared responses or correct responses
+// naming choices that do not exist. The rules run on the model every parser
+// produces, so they apply to documents of every QTI version.
+package checker
+
+import (
+	"fmt"
+
+	"github.com/qti-migrator/internal/parser"
+	"github.com/qti-migrator/pkg/analysis"
+	"github.com/qti-migrator/pkg/models"
+)
+
+// Severity tells whether a finding is reported as a warning or an error.
+// Neither blocks the migration.
+type Severity int
+
+const (
+	SeverityWarning Severity = iota
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// Rule is a single consistency check.
+type Rule struct {
+	// Stable identifier shown in reports, e.g. duplicate-identifier
+	ID          string
+	Severity    Severity
+	Description string
+	check       func(items []*models.Item) []finding
+}
+
+// finding is a problem a rule found in an item.
+type finding struct {
+	itemID     string
+	path       string
+	message    string
+	suggestion string
+}
+
+// Checker runs a set of rules on documents.
+type Checker struct {
+	rules []Rule
+}
+
+// New returns a checker with all built-in rules.
+func New() *Checker {
+	return &Checker{rules: rules()}
+}
+
+// Rules returns the rules of the checker in the order they run.
+func (c *Checker) Rules() []Rule {
+	return append([]Rule(nil), c.rules...)
+}
+
+// Check runs the rules on every item of doc, including the items of its
+// sections, and adds the findings to report labelled with step.
+func (c *Checker) Check(doc *models.QTIDocument, report *analysis.Report, step string) {
+	items := documentItems(doc)
+	for _, rule := range c.rules {
+		for _, f := range rule.check(items) {
+			if rule.Severity == SeverityError {
+				report.Errors = append(report.Errors, analysis.Error{
+					ItemID:      f.itemID,
+					ElementPath: f.path,
+					Message:     f.message,
+					Step:        step,
+					Rule:        rule.ID,
+				})
+				continue
+			}
+			report.Warnings = append(report.Warnings, analysis.Warning{
+				ItemID:      f.itemID,
+				ElementPath: f.path,
+				Message:     f.message,
+				Suggestion:  f.suggestion,
+				Step:        step,
+				Rule:        rule.ID,
+			})
+		}
+	}
+}
+
+// CheckContent parses a document of version and checks it. The findings are
+// labelled with the version.
+func (c *Checker) CheckContent(content []byte, version string, report *analysis.Report) error {
+	versionParser, err := parser.GetParser(version)
+	if err != nil {
+		return err
+	}
+	doc, err := versionParser.Parse(content)
+	if err != nil {
+		return fmt.Errorf("failed to parse document: %w", err)
+	}
+	c.Check(doc, report, version)
+	return nil
+}
+
+// documentItems returns the items of doc followed by those of its sections.
+func documentItems(doc *models.QTIDocument) []*models.Item {
+	var items []*models.Item
+	for i := range doc.Items {
+		items = append(items, &doc.Items[i])
+	}
+	if doc.Assessment != nil {
+		for i := range doc.Assessment.Sections {
+			items = sectionItems(&doc.Assessment.Sections[i], items)
+		}
+	}
+	return items
+}
+
+func sectionItems(section *models.Section, items []*models.Item) []*models.Item {
+	for i := range section.Items {
+		items = append(items, &section.Items[i])
+	}
+	for i := range section.Sections {
+		items = sectionItems(&section.Sections[i], items)
+	}
+	return items
+}
+
+// eachItem turns a check of a single item into the check of a rule.
+func eachItem(check func(item *models.Item) []finding) func(items []*models.Item) []finding {
+	return func(items []*models.Item) []finding {
+		var findings []finding
+		for _, item := range items {
+			findings = append(findings, check(item)...)
+		}
+		return findings
+	}
+}
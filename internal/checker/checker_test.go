@@ -0,0 +1,285 @@
+package checker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/qti-migrator/pkg/analysis"
+	"github.com/qti-migrator/pkg/models"
+)
+
+func TestNew(t *testing.T) {
+	seen := map[string]bool{}
+	for _, rule := range New().Rules() {
+		if rule.ID == "" || rule.Description == "" || rule.check == nil {
+			t.Errorf("Rule %+v is incomplete", rule)
+		}
+		if seen[rule.ID] {
+			t.Errorf("Rule ID %s is used twice", rule.ID)
+		}
+		seen[rule.ID] = true
+	}
+}
+
+func TestChecker_CheckContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		content string
+		// Rule IDs of the findings in the order they are reported,
+		// errors first
+		expected []string
+	}{
+		{
+			name:    "consistent QTI 2.1 item",
+			version: "2.1",
+			content: `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="q1" title="Capital" adaptive="false" timeDependent="false">
+  <responseDeclaration identifier="RESPONSE" cardinality="multiple" baseType="identifier">
+    <correctResponse><value>A</value><value>B</value></correctResponse>
+    <mapping defaultValue="0"><mapEntry mapKey="A" mappedValue="1"/></mapping>
+  </responseDeclaration>
+  <responseDeclaration identifier="PAIRS" cardinality="multiple" baseType="directedPair">
+    <correctResponse><value>A X</value></correctResponse>
+  </responseDeclaration>
+  <responseDeclaration identifier="NAME" cardinality="single" baseType="string"/>
+  <outcomeDeclaration identifier="FEEDBACK" cardinality="single" baseType="identifier"/>
+  <itemBody>
+    <choiceInteraction responseIdentifier="RESPONSE" shuffle="false" maxChoices="0">
+      <simpleChoice identifier="A">Paris</simpleChoice>
+      <simpleChoice identifier="B">Lyon</simpleChoice>
+    </choiceInteraction>
+    <matchInteraction responseIdentifier="PAIRS" shuffle="false" maxAssociations="1">
+      <simpleMatchSet><simpleAssociableChoice identifier="A" matchMax="1">a</simpleAssociableChoice></simpleMatchSet>
+      <simpleMatchSet><simpleAssociableChoice identifier="X" matchMax="1">x</simpleAssociableChoice></simpleMatchSet>
+    </matchInteraction>
+    <p>Name: <textEntryInteraction responseIdentifier="NAME"/></p>
+  </itemBody>
+  <modalFeedback outcomeIdentifier="FEEDBACK" identifier="fb" showHide="show">Yes</modalFeedback>
+  <modalFeedback outcomeIdentifier="FEEDBACK" identifier="fb" showHide="show">Indeed</modalFeedback>
+  <modalFeedback outcomeIdentifier="completionStatus" identifier="completed" showHide="show">Done</modalFeedback>
+</assessmentItem>`,
+		},
+		{
+			name:    "inconsistent QTI 2.1 item",
+			version: "2.1",
+			content: `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="q1" title="Capital" adaptive="false" timeDependent="false">
+  <responseDeclaration identifier="RESPONSE" cardinality="multiple" baseType="identifier">
+    <correctResponse><value>A</value><value>C</value></correctResponse>
+    <mapping defaultValue="0"><mapEntry mapKey="Z" mappedValue="1"/></mapping>
+  </responseDeclaration>
+  <outcomeDeclaration identifier="RESPONSE" cardinality="single" baseType="float"/>
+  <itemBody>
+    <choiceInteraction responseIdentifier="RESPONSE" shuffle="false" maxChoices="1">
+      <simpleChoice identifier="A">Paris</simpleChoice>
+      <simpleChoice identifier="A">Lyon</simpleChoice>
+    </choiceInteraction>
+    <p>Name: <textEntryInteraction responseIdentifier="NAME"/></p>
+  </itemBody>
+  <modalFeedback outcomeIdentifier="FEEDBACK" identifier="fb" showHide="show">Yes</modalFeedback>
+</assessmentItem>`,
+			expected: []string{
+				"undeclared-response",
+				"unknown-correct-value",
+				"max-choices-below-correct",
+				"duplicate-identifier",
+				"duplicate-identifier",
+				"undeclared-feedback-outcome",
+				"unknown-mapped-value",
+			},
+		},
+		{
+			// As the 1.2 to 2.1 migration writes a multiple response
+			// item: maxChoices is omitted, so it is 1
+			name:    "QTI 2.1 choice interaction without maxChoices",
+			version: "2.1",
+			content: `<?xml version="1.0" encoding="UTF-8"?>
+<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="q1" title="Cities" adaptive="false" timeDependent="false">
+  <responseDeclaration identifier="RESPONSE" cardinality="multiple" baseType="identifier">
+    <correctResponse>
+      <value>A</value>
+      <value>B</value>
+    </correctResponse>
+  </responseDeclaration>
+  <outcomeDeclaration identifier="SCORE" cardinality="single" baseType="float">
+    <defaultValue>
+      <value>0</value>
+    </defaultValue>
+  </outcomeDeclaration>
+  <itemBody>
+    <choiceInteraction responseIdentifier="RESPONSE">
+      <simpleChoice identifier="A">Paris</simpleChoice>
+      <simpleChoice identifier="B">Lyon</simpleChoice>
+      <simpleChoice identifier="C">Berlin</simpleChoice>
+    </choiceInteraction>
+  </itemBody>
+  <responseProcessing>
+    <responseCondition>
+      <responseIf>
+        <and>
+          <member>
+            <baseValue baseType="identifier">A</baseValue>
+            <variable identifier="RESPONSE"></variable>
+          </member>
+          <member>
+            <baseValue baseType="identifier">B</baseValue>
+            <variable identifier="RESPONSE"></variable>
+          </member>
+        </and>
+        <setOutcomeValue identifier="SCORE">
+          <baseValue baseType="float">1</baseValue>
+        </setOutcomeValue>
+      </responseIf>
+    </responseCondition>
+  </responseProcessing>
+</assessmentItem>`,
+			expected: []string{
+				"max-choices-below-correct",
+			},
+		},
+		{
+			name:    "inconsistent QTI 3.0 item",
+			version: "3.0",
+			content: `<qti-assessment-item xmlns="http://www.imsglobal.org/xsd/imsqtiasi_v3p0" identifier="q1" title="Capital" time-dependent="false">
+  <qti-response-declaration identifier="RESPONSE" cardinality="single" base-type="identifier">
+    <qti-correct-response><qti-value>C</qti-value></qti-correct-response>
+  </qti-response-declaration>
+  <qti-item-body>
+    <qti-choice-interaction response-identifier="RESPONSE" max-choices="1">
+      <qti-simple-choice identifier="A">Paris</qti-simple-choice>
+    </qti-choice-interaction>
+    <p><qti-text-entry-interaction response-identifier="NAME"/></p>
+  </qti-item-body>
+</qti-assessment-item>`,
+			expected: []string{
+				"undeclared-response",
+				"unknown-correct-value",
+			},
+		},
+		{
+			name:    "consistent QTI 1.2 item",
+			version: "1.2",
+			content: `<questestinterop>
+  <item ident="q1">
+    <presentation>
+      <response_lid ident="R1" rcardinality="Single">
+        <render_choice>
+          <response_label ident="A"><material><mattext>a</mattext></material></response_label>
+          <response_label ident="B"><material><mattext>b</mattext></material></response_label>
+        </render_choice>
+      </response_lid>
+    </presentation>
+    <resprocessing>
+      <respcondition><conditionvar><varequal respident="R1">b</varequal></conditionvar></respcondition>
+      <respcondition><conditionvar><not><unanswered respident="R1"/></not></conditionvar><displayfeedback linkrefid="fb"/></respcondition>
+    </resprocessing>
+    <itemfeedback ident="fb"><material><mattext>x</mattext></material></itemfeedback>
+  </item>
+</questestinterop>`,
+		},
+		{
+			name:    "inconsistent QTI 1.2 items",
+			version: "1.2",
+			content: `<questestinterop>
+  <item ident="q1">
+    <presentation>
+      <response_lid ident="R1" rcardinality="Single">
+        <render_choice>
+          <response_label ident="A"><material><mattext>a</mattext></material></response_label>
+          <response_label ident="A"><material><mattext>b</mattext></material></response_label>
+        </render_choice>
+      </response_lid>
+    </presentation>
+    <resprocessing>
+      <respcondition><conditionvar><varequal respident="R1" case="Yes">a</varequal></conditionvar></respcondition>
+      <respcondition><conditionvar><or><varequal respident="R2">A</varequal></or></conditionvar></respcondition>
+    </resprocessing>
+    <itemfeedback ident="fb"><material><mattext>x</mattext></material></itemfeedback>
+    <itemfeedback ident="fb"><material><mattext>y</mattext></material></itemfeedback>
+  </item>
+  <assessment ident="a1">
+    <section ident="s1">
+      <item ident="q1"/>
+    </section>
+  </assessment>
+</questestinterop>`,
+			expected: []string{
+				"undeclared-response",
+				"duplicate-identifier",
+				"duplicate-identifier",
+				"duplicate-identifier",
+				"unknown-condition-value",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := &analysis.Report{}
+			if err := New().CheckContent([]byte(tt.content), tt.version, report); err != nil {
+				t.Fatalf("CheckContent() error = %v", err)
+			}
+
+			var rules, findings []string
+			for _, err := range report.Errors {
+				rules = append(rules, err.Rule)
+				findings = append(findings, err.Message)
+				if err.Fatal || err.Step != tt.version {
+					t.Errorf("Expected a non-fatal error labelled %s, got %+v", tt.version, err)
+				}
+			}
+			for _, warning := range report.Warnings {
+				rules = append(rules, warning.Rule)
+				findings = append(findings, warning.Message)
+				if warning.Suggestion == "" {
+					t.Errorf("Expected warning %q to suggest a fix", warning.Message)
+				}
+			}
+			if strings.Join(rules, " ") != strings.Join(tt.expected, " ") {
+				t.Errorf("Expected findings of %v, got %v:\n%s", tt.expected, rules, strings.Join(findings, "\n"))
+			}
+		})
+	}
+}
+
+func TestChecker_CheckContent_Unparsable(t *testing.T) {
+	if err := New().CheckContent([]byte("<assessmentItem"), "2.1", &analysis.Report{}); err == nil {
+		t.Error("Expected an error for a truncated document")
+	}
+	if err := New().CheckContent([]byte("<item/>"), "9.9", &analysis.Report{}); err == nil {
+		t.Error("Expected an error for an unknown version")
+	}
+}
+
+func TestChecker_Check_Paths(t *testing.T) {
+	doc := &models.QTIDocument{
+		Items: []models.Item{{
+			Ident: "q1",
+			ResponseDecl: []models.ResponseDecl{{
+				Identifier:      "RESPONSE",
+				CorrectResponse: &models.CorrectResponse{Value: models.NewValues("A", "B")},
+			}},
+			ItemBody: &models.ItemBody{
+				ChoiceInteraction: []models.ChoiceInteraction{{
+					ResponseIdent: "RESPONSE",
+					MaxChoices:    1,
+					SimpleChoice:  []models.SimpleChoice{{Identifier: "A"}, {Identifier: "B"}},
+				}},
+			},
+		}},
+	}
+
+	report := &analysis.Report{}
+	New().Check(doc, report, "")
+	if len(report.Errors) != 1 {
+		t.Fatalf("Expected a single error, got %+v", report.Errors)
+	}
+	expected := analysis.Error{
+		ItemID:      "q1",
+		ElementPath: "item[@ident='q1']/itemBody/choiceInteraction[@responseIdentifier='RESPONSE']",
+		Message:     "maxChoices is 1 but the correct response has 2 values, so it cannot be given",
+		Rule:        "max-choices-below-correct",
+	}
+	if report.Errors[0] != expected {
+		t.Errorf("Expected %+v, got %+v", expected, report.Errors[0])
+	}
+}
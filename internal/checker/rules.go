@@ -0,0 +1,557 @@
+package checker
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/qti-migrator/pkg/models"
+)
+
+// Outcomes every item has without declaring them
+var builtinOutcomes = map[string]bool{
+	"completionStatus": true,
+}
+
+func rules() []Rule {
+	return []Rule{
+		{
+			ID:          "undeclared-response",
+			Severity:    SeverityError,
+			Description: "An interaction or response condition refers to a response the item does not declare",
+			check:       eachItem(checkUndeclaredResponses),
+		},
+		{
+			ID:          "unknown-correct-value",
+			Severity:    SeverityError,
+			Description: "A correct response value matches no choice of the interaction",
+			check:       eachItem(checkCorrectValues),
+		},
+		{
+			ID:          "max-choices-below-correct",
+			Severity:    SeverityError,
+			Description: "maxChoices is lower than the number of correct values",
+			check:       eachItem(checkMaxChoices),
+		},
+		{
+			ID:          "duplicate-identifier",
+			Severity:    SeverityError,
+			Description: "An item, declaration, choice, response or feedback identifier is used more than once",
+			check:       checkDuplicateIdentifiers,
+		},
+		{
+			ID:          "undeclared-feedback-outcome",
+			Severity:    SeverityError,
+			Description: "A modalFeedback is shown on an outcome the item does not declare",
+			check:       eachItem(checkFeedbackOutcomes),
+		},
+		{
+			ID:          "unknown-mapped-value",
+			Severity:    SeverityWarning,
+			Description: "A mapEntry maps a value that matches no choice of the interaction",
+			check:       eachItem(checkMappedValues),
+		},
+		{
+			ID:          "unknown-condition-value",
+			Severity:    SeverityWarning,
+			Description: "A QTI 1.2 varequal compares a response_lid with a value that is none of its response_labels",
+			check:       eachItem(checkConditionValues),
+		},
+	}
+}
+
+// interaction is an element of an item body bound to a response.
+type interaction struct {
+	name          string
+	responseIdent string
+	path          string
+	// Most choices the candidate may select; 0 for no limit
+	maxChoices int
+	// Set for choice, order, associate and match interactions, whose
+	// choices are known
+	typed bool
+	// Choices of the interaction; the sources and targets of a match
+	// interaction
+	choices []string
+	sources []string
+	targets []string
+}
+
+// offers tells whether value names a choice of the interaction, or a pair of
+// choices for associate and match interactions.
+func (in interaction) offers(value string) bool {
+	switch in.name {
+	case models.BodyMatchInteraction:
+		pair := strings.Fields(value)
+		return len(pair) == 2 && contains(in.sources, pair[0]) && contains(in.targets, pair[1])
+	case models.BodyAssociateInteraction:
+		pair := strings.Fields(value)
+		return len(pair) == 2 && contains(in.choices, pair[0]) && contains(in.choices, pair[1])
+	default:
+		return contains(in.choices, strings.TrimSpace(value))
+	}
+}
+
+// interactions returns the interactions of the item body: the typed ones,
+// and any other element of the body markup with a responseIdentifier, such
+// as inline text entries in a paragraph.
+func interactions(item *models.Item) []interaction {
+	body := item.ItemBody
+	if body == nil {
+		return nil
+	}
+	typedPath := func(name, responseIdent string) string {
+		return fmt.Sprintf("item[@ident='%s']/itemBody/%s[@responseIdentifier='%s']", item.Ident, name, responseIdent)
+	}
+
+	var result []interaction
+	for _, choice := range body.ChoiceInteraction {
+		result = append(result, interaction{
+			name:          models.BodyChoiceInteraction,
+			responseIdent: choice.ResponseIdent,
+			path:          typedPath(models.BodyChoiceInteraction, choice.ResponseIdent),
+			maxChoices:    choiceMaxChoices(choice),
+			typed:         true,
+			choices:       simpleChoices(choice.SimpleChoice),
+		})
+	}
+	for _, order := range body.OrderInteraction {
+		result = append(result, interaction{
+			name:          models.BodyOrderInteraction,
+			responseIdent: order.ResponseIdent,
+			path:          typedPath(models.BodyOrderInteraction, order.ResponseIdent),
+			maxChoices:    order.MaxChoices,
+			typed:         true,
+			choices:       simpleChoices(order.SimpleChoice),
+		})
+	}
+	for _, associate := range body.AssociateInteraction {
+		result = append(result, interaction{
+			name:          models.BodyAssociateInteraction,
+			responseIdent: associate.ResponseIdent,
+			path:          typedPath(models.BodyAssociateInteraction, associate.ResponseIdent),
+			typed:         true,
+			choices:       associableChoices(associate.SimpleAssociableChoice),
+		})
+	}
+	for _, match := range body.MatchInteraction {
+		in := interaction{
+			name:          models.BodyMatchInteraction,
+			responseIdent: match.ResponseIdent,
+			path:          typedPath(models.BodyMatchInteraction, match.ResponseIdent),
+		}
+		for _, set := range match.SimpleMatchSet {
+			in.choices = append(in.choices, associableChoices(set.SimpleAssociableChoice)...)
+		}
+		if len(match.SimpleMatchSet) == 2 {
+			in.typed = true
+			in.sources = associableChoices(match.SimpleMatchSet[0].SimpleAssociableChoice)
+			in.targets = associableChoices(match.SimpleMatchSet[1].SimpleAssociableChoice)
+		}
+		result = append(result, in)
+	}
+	for _, entry := range body.TextEntryInteraction {
+		result = append(result, interaction{
+			name:          models.BodyTextEntryInteraction,
+			responseIdent: entry.ResponseIdent,
+			path:          typedPath(models.BodyTextEntryInteraction, entry.ResponseIdent),
+		})
+	}
+	for _, text := range body.ExtendedTextInteraction {
+		result = append(result, interaction{
+			name:          models.BodyExtendedTextInteraction,
+			responseIdent: text.ResponseIdent,
+			path:          typedPath(models.BodyExtendedTextInteraction, text.ResponseIdent),
+		})
+	}
+
+	for _, p := range body.P {
+		result = append(result, embeddedInteractions(item.Ident, p.Content)...)
+	}
+	for _, div := range body.Div {
+		result = append(result, embeddedInteractions(item.Ident, div.Content)...)
+	}
+	for _, other := range body.Other {
+		for _, attr := range other.Attrs {
+			if attr.Name.Local == "responseIdentifier" {
+				result = append(result, interaction{
+					name:          other.XMLName.Local,
+					responseIdent: attr.Value,
+					path:          typedPath(other.XMLName.Local, attr.Value),
+				})
+			}
+		}
+		result = append(result, embeddedInteractions(item.Ident, other.Content)...)
+	}
+	return result
+}
+
+// embeddedInteractions returns the elements of body markup that are bound to
+// a response. Markup that cannot be read is skipped.
+func embeddedInteractions(itemID, content string) []interaction {
+	if !strings.Contains(content, "responseIdentifier") {
+		return nil
+	}
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	var result []interaction
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return result
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		for _, attr := range start.Attr {
+			if attr.Name.Local == "responseIdentifier" {
+				result = append(result, interaction{
+					name:          start.Name.Local,
+					responseIdent: attr.Value,
+					path:          fmt.Sprintf("item[@ident='%s']/itemBody//%s[@responseIdentifier='%s']", itemID, start.Name.Local, attr.Value),
+				})
+			}
+		}
+	}
+}
+
+func simpleChoices(choices []models.SimpleChoice) []string {
+	identifiers := make([]string, len(choices))
+	for i, choice := range choices {
+		identifiers[i] = choice.Identifier
+	}
+	return identifiers
+}
+
+func associableChoices(choices []models.SimpleAssociableChoice) []string {
+	identifiers := make([]string, len(choices))
+	for i, choice := range choices {
+		identifiers[i] = choice.Identifier
+	}
+	return identifiers
+}
+
+// boundInteraction returns the first interaction with known choices that is
+// bound to response.
+func boundInteraction(item *models.Item, response string) (interaction, bool) {
+	for _, in := range interactions(item) {
+		if in.typed && in.responseIdent == response {
+			return in, true
+		}
+	}
+	return interaction{}, false
+}
+
+func declPath(item *models.Item, decl models.ResponseDecl) string {
+	return fmt.Sprintf("item[@ident='%s']/responseDeclaration[@identifier='%s']", item.Ident, decl.Identifier)
+}
+
+func checkUndeclaredResponses(item *models.Item) []finding {
+	var findings []finding
+
+	if item.ItemBody != nil {
+		declared := map[string]bool{}
+		for _, decl := range item.ResponseDecl {
+			declared[decl.Identifier] = true
+		}
+		for _, in := range interactions(item) {
+			if in.responseIdent != "" && !declared[in.responseIdent] {
+				findings = append(findings, finding{
+					itemID:  item.Ident,
+					path:    in.path,
+					message: fmt.Sprintf("%s is bound to response '%s', which has no responseDeclaration", in.name, in.responseIdent),
+				})
+			}
+		}
+	}
+
+	// QTI 1.2 conditions refer to the responses of the presentation
+	if item.ResponseProc != nil {
+		defined := map[string]bool{}
+		if item.Presentation != nil {
+			for _, response := range item.Presentation.Responses() {
+				defined[response.Ident] = true
+			}
+		}
+		eachCondition(item, func(path string, condition models.XMLNode) {
+			respIdent := condition.Attr("respident")
+			if respIdent != "" && !defined[respIdent] {
+				findings = append(findings, finding{
+					itemID:  item.Ident,
+					path:    path,
+					message: fmt.Sprintf("%s refers to response '%s', which the presentation does not define", condition.XMLName.Local, respIdent),
+				})
+			}
+		})
+	}
+	return findings
+}
+
+func checkCorrectValues(item *models.Item) []finding {
+	var findings []finding
+	for _, decl := range item.ResponseDecl {
+		if decl.CorrectResponse == nil {
+			continue
+		}
+		in, ok := boundInteraction(item, decl.Identifier)
+		if !ok {
+			continue
+		}
+		for _, value := range decl.CorrectResponse.Value {
+			if value.FieldIdentifier == "" && !in.offers(value.Content) {
+				findings = append(findings, finding{
+					itemID:  item.Ident,
+					path:    declPath(item, decl) + "/correctResponse",
+					message: fmt.Sprintf("correct response value '%s' matches no choice of the %s", strings.TrimSpace(value.Content), in.name),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+func checkMappedValues(item *models.Item) []finding {
+	var findings []finding
+	for _, decl := range item.ResponseDecl {
+		if decl.Mapping == nil {
+			continue
+		}
+		in, ok := boundInteraction(item, decl.Identifier)
+		if !ok {
+			continue
+		}
+		for _, entry := range decl.Mapping.MapEntry {
+			if !in.offers(entry.MapKey) {
+				findings = append(findings, finding{
+					itemID:     item.Ident,
+					path:       fmt.Sprintf("%s/mapping/mapEntry[@mapKey='%s']", declPath(item, decl), entry.MapKey),
+					message:    fmt.Sprintf("mapKey '%s' matches no choice of the %s and is never scored", entry.MapKey, in.name),
+					suggestion: "Correct the mapKey or remove the mapEntry",
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// choiceMaxChoices returns the maxChoices of a choice interaction, which is
+// 1 when the interaction does not give it.
+func choiceMaxChoices(choice models.ChoiceInteraction) int {
+	if choice.MaxChoices == 0 && !choice.MaxChoicesSet {
+		return 1
+	}
+	return choice.MaxChoices
+}
+
+func checkMaxChoices(item *models.Item) []finding {
+	var findings []finding
+	for _, in := range interactions(item) {
+		if in.maxChoices <= 0 {
+			continue
+		}
+		for _, decl := range item.ResponseDecl {
+			// The values of a record are its fields
+			if decl.Identifier != in.responseIdent || decl.CorrectResponse == nil || decl.Cardinality == "record" {
+				continue
+			}
+			if correct := len(decl.CorrectResponse.Value); correct > in.maxChoices {
+				findings = append(findings, finding{
+					itemID:  item.Ident,
+					path:    in.path,
+					message: fmt.Sprintf("maxChoices is %d but the correct response has %d values, so it cannot be given", in.maxChoices, correct),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+func checkFeedbackOutcomes(item *models.Item) []finding {
+	declared := map[string]bool{}
+	for _, decl := range item.OutcomeDecl {
+		declared[decl.Identifier] = true
+	}
+	undeclared := func(identifier, outcome string) *finding {
+		if outcome == "" || declared[outcome] || builtinOutcomes[outcome] {
+			return nil
+		}
+		return &finding{
+			itemID:  item.Ident,
+			path:    fmt.Sprintf("item[@ident='%s']/modalFeedback[@identifier='%s']", item.Ident, identifier),
+			message: fmt.Sprintf("modalFeedback '%s' is shown on outcome '%s', which has no outcomeDeclaration", identifier, outcome),
+		}
+	}
+
+	var findings []finding
+	for _, feedback := range item.Feedback {
+		if f := undeclared(feedback.Ident, feedback.OutcomeIdentifier); f != nil {
+			findings = append(findings, *f)
+		}
+	}
+	for _, feedback := range item.ModalFeedback {
+		if f := undeclared(feedback.Identifier, feedback.OutcomeIdentifier); f != nil {
+			findings = append(findings, *f)
+		}
+	}
+	return findings
+}
+
+func checkConditionValues(item *models.Item) []finding {
+	if item.ResponseProc == nil || item.Presentation == nil {
+		return nil
+	}
+	labels := map[string][]models.ResponseLabel{}
+	for _, response := range item.Presentation.Responses() {
+		if response.Kind() == models.ResponseLid && response.RenderChoice != nil {
+			labels[response.Ident] = response.RenderChoice.Labels()
+		}
+	}
+
+	var findings []finding
+	eachCondition(item, func(path string, condition models.XMLNode) {
+		responseLabels, ok := labels[condition.Attr("respident")]
+		if condition.XMLName.Local != "varequal" || !ok {
+			return
+		}
+		value := strings.TrimSpace(condition.Value)
+		for _, label := range responseLabels {
+			if label.Ident == value || (condition.Attr("case") != "Yes" && strings.EqualFold(label.Ident, value)) {
+				return
+			}
+		}
+		findings = append(findings, finding{
+			itemID:     item.Ident,
+			path:       path,
+			message:    fmt.Sprintf("varequal compares response '%s' with '%s', which is none of its response_labels", condition.Attr("respident"), value),
+			suggestion: "Correct the value or add the response_label; the condition never matches",
+		})
+	})
+	return findings
+}
+
+// eachCondition calls visit for every condition of the QTI 1.2 response
+// processing of item, including those nested in not, and and or.
+func eachCondition(item *models.Item, visit func(path string, condition models.XMLNode)) {
+	var walk func(path string, conditions []models.XMLNode)
+	walk = func(path string, conditions []models.XMLNode) {
+		for _, condition := range conditions {
+			conditionPath := path + "/" + condition.XMLName.Local
+			switch condition.XMLName.Local {
+			case "not", "and", "or":
+				walk(conditionPath, condition.Children)
+			default:
+				visit(conditionPath, condition)
+			}
+		}
+	}
+	for i, condition := range item.ResponseProc.ResCondition {
+		if condition.ConditionVar != nil {
+			path := fmt.Sprintf("item[@ident='%s']/resprocessing/respcondition[%d]/conditionvar", item.Ident, i+1)
+			walk(path, condition.ConditionVar.Conditions())
+		}
+	}
+}
+
+func checkDuplicateIdentifiers(items []*models.Item) []finding {
+	var findings []finding
+	add := func(item *models.Item, path, message string) {
+		findings = append(findings, finding{itemID: item.Ident, path: path, message: message})
+	}
+
+	seenItems := map[string]bool{}
+	for _, item := range items {
+		if item.Ident != "" && seenItems[item.Ident] {
+			add(item, fmt.Sprintf("item[@ident='%s']", item.Ident), fmt.Sprintf("item identifier '%s' is used by another item", item.Ident))
+		}
+		seenItems[item.Ident] = true
+	}
+
+	for _, item := range items {
+		// Responses, outcomes and template variables share one namespace
+		declaredBy := map[string]string{}
+		declare := func(kind, identifier string) {
+			if first, ok := declaredBy[identifier]; ok {
+				add(item, fmt.Sprintf("item[@ident='%s']/%s[@identifier='%s']", item.Ident, kind, identifier),
+					fmt.Sprintf("identifier '%s' is already declared by a %s", identifier, first))
+				return
+			}
+			declaredBy[identifier] = kind
+		}
+		for _, decl := range item.ResponseDecl {
+			declare("responseDeclaration", decl.Identifier)
+		}
+		for _, decl := range item.OutcomeDecl {
+			declare("outcomeDeclaration", decl.Identifier)
+		}
+		for _, decl := range item.TemplateDecl {
+			declare("templateDeclaration", decl.Identifier)
+		}
+
+		for _, in := range interactions(item) {
+			for _, identifier := range repeated(in.choices) {
+				add(item, in.path, fmt.Sprintf("choice identifier '%s' is used more than once in the %s", identifier, in.name))
+			}
+		}
+
+		if item.Presentation != nil {
+			var idents []string
+			for _, response := range item.Presentation.Responses() {
+				idents = append(idents, response.Ident)
+				if response.RenderChoice == nil {
+					continue
+				}
+				var labels []string
+				for _, label := range response.RenderChoice.Labels() {
+					labels = append(labels, label.Ident)
+				}
+				for _, ident := range repeated(labels) {
+					add(item, fmt.Sprintf("item[@ident='%s']/presentation/%s[@ident='%s']", item.Ident, response.Kind(), response.Ident),
+						fmt.Sprintf("response_label ident '%s' is used more than once in the %s", ident, response.Kind()))
+				}
+			}
+			for _, ident := range repeated(idents) {
+				add(item, fmt.Sprintf("item[@ident='%s']/presentation", item.Ident), fmt.Sprintf("response ident '%s' is used by more than one response", ident))
+			}
+		}
+
+		// Feedback read from modalFeedback may share an identifier, which
+		// then shows all of them
+		var feedbackIdents []string
+		for _, feedback := range item.Feedback {
+			if feedback.OutcomeIdentifier == "" {
+				feedbackIdents = append(feedbackIdents, feedback.Ident)
+			}
+		}
+		for _, ident := range repeated(feedbackIdents) {
+			add(item, fmt.Sprintf("item[@ident='%s']/itemfeedback[@ident='%s']", item.Ident, ident), fmt.Sprintf("itemfeedback ident '%s' is used more than once", ident))
+		}
+	}
+	return findings
+}
+
+// repeated returns the non-empty identifiers that occur more than once, in
+// the order they first repeat.
+func repeated(identifiers []string) []string {
+	count := map[string]int{}
+	var result []string
+	for _, identifier := range identifiers {
+		count[identifier]++
+		if identifier != "" && count[identifier] == 2 {
+			result = append(result, identifier)
+		}
+	}
+	return result
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+// Package markup rewrites XML markup on its token stream. Each token comes
+// with the text it was read from, so a rewrite can copy whatever it leaves
+// alone exactly as written: text, comments, CDATA sections and the quoting
+// and entities of attribute values.
+package markup
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// Token is an XML token together with the markup it was read from.
+type Token struct {
+	xml.Token
+	// Raw is the markup of the token as written
+	Raw string
+	// SelfClosing is set on the start of an element written as <br/>, which
+	// has no end token of its own
+	SelfClosing bool
+}
+
+// Walk calls visit for each token of content in order. The decoder is not
+// strict, so HTML entities and undeclared prefixes do not stop it, and it
+// returns the error of markup that cannot be tokenized.
+func Walk(content string, visit func(Token)) error {
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	decoder.Strict = false
+
+	offset := int64(0)
+	selfClosing := false
+	for {
+		// The decoder reports <br/> as a start and an empty end element
+		afterSelfClosing := selfClosing
+		selfClosing = false
+
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		end := decoder.InputOffset()
+		raw := content[offset:end]
+		offset = end
+
+		switch token.(type) {
+		case xml.StartElement:
+			selfClosing = strings.HasSuffix(raw, "/>")
+		case xml.EndElement:
+			if afterSelfClosing && raw == "" {
+				continue
+			}
+		}
+		visit(Token{Token: token, Raw: raw, SelfClosing: selfClosing})
+	}
+}
+
+// StartTag writes start as a start tag, or as an empty element tag when
+// selfClosing is set, with its attribute values escaped.
+func StartTag(start xml.StartElement, selfClosing bool) string {
+	var tag strings.Builder
+	tag.WriteString("<" + qualifiedName(start.Name))
+	for _, attr := range start.Attr {
+		tag.WriteString(" " + qualifiedName(attr.Name) + `="` + attrEscaper.Replace(attr.Value) + `"`)
+	}
+	if selfClosing {
+		tag.WriteString("/>")
+	} else {
+		tag.WriteString(">")
+	}
+	return tag.String()
+}
+
+// qualifiedName writes a name read by RawToken, whose Space is the prefix.
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+var attrEscaper = strings.NewReplacer(`&`, "&amp;", `<`, "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
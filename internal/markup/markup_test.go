@@ -0,0 +1,58 @@
+package markup
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	content := `<p class='a'>x &amp; y<br/><m:math><m:mi>x</m:mi></m:math><!-- note --><![CDATA[<b>]]></p>`
+
+	var raw strings.Builder
+	var kinds []string
+	err := Walk(content, func(token Token) {
+		raw.WriteString(token.Raw)
+		switch t := token.Token.(type) {
+		case xml.StartElement:
+			kind := "<" + qualifiedName(t.Name)
+			if token.SelfClosing {
+				kind += "/"
+			}
+			kinds = append(kinds, kind)
+		case xml.EndElement:
+			kinds = append(kinds, "</"+qualifiedName(t.Name))
+		}
+	})
+	if err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	if raw.String() != content {
+		t.Errorf("Expected the raw tokens to add up to the content, got %s", raw.String())
+	}
+	expected := "<p <br/ <m:math <m:mi </m:mi </m:math </p"
+	if got := strings.Join(kinds, " "); got != expected {
+		t.Errorf("Expected elements %q, got %q", expected, got)
+	}
+
+	if err := Walk(`<p>unclosed <`, func(Token) {}); err == nil {
+		t.Error("Expected an error for markup that cannot be tokenized")
+	}
+}
+
+func TestStartTag(t *testing.T) {
+	start := xml.StartElement{
+		Name: xml.Name{Space: "m", Local: "mi"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "title"}, Value: "a < \"b\" & c\n"},
+			{Name: xml.Name{Space: "xml", Local: "lang"}, Value: "en"},
+		},
+	}
+	expected := `<m:mi title="a &lt; &quot;b&quot; &amp; c&#xA;" xml:lang="en">`
+	if tag := StartTag(start, false); tag != expected {
+		t.Errorf("Expected %s, got %s", expected, tag)
+	}
+	if tag := StartTag(xml.StartElement{Name: xml.Name{Local: "br"}}, true); tag != "<br/>" {
+		t.Errorf("Expected <br/>, got %s", tag)
+	}
+}
@@ -3,22 +3,45 @@ package migrator
 import (
 	"fmt"
 
-	"github.com/qti-migrator/internal/migrator/qti12to21"
-	"github.com/qti-migrator/internal/migrator/qti21to30"
+	// Built-in migration steps
+	_ "github.com/qti-migrator/internal/migrator/qti12to21"
+	_ "github.com/qti-migrator/internal/migrator/qti21to30"
 	"github.com/qti-migrator/internal/parser"
+	"github.com/qti-migrator/pkg/models"
+	"github.com/qti-migrator/pkg/registry"
 )
 
-type Migrator interface {
-	Migrate(doc interface{}) ([]byte, error)
-}
+type Migrator = registry.Migrator
 
-type MigratorService struct{}
+type MigratorService struct {
+	// Options of every migration the service runs
+	Options registry.MigrationOptions
+}
 
 func New() *MigratorService {
 	return &MigratorService{}
 }
 
+// Migrate migrates content to a single document of the target version.
+// Documents that the target version stores in several files fail.
 func (m *MigratorService) Migrate(content []byte, fromVersion, toVersion string) ([]byte, error) {
+	options := m.Options
+	options.Layout = registry.LayoutSingle
+	files, err := m.migrateFiles(content, fromVersion, toVersion, options)
+	if err != nil {
+		return nil, err
+	}
+	return files[0].Content, nil
+}
+
+// MigrateFiles migrates content to the files of the target version: a test
+// first, followed by the items it references, or a single document. A
+// document without items has no href.
+func (m *MigratorService) MigrateFiles(content []byte, fromVersion, toVersion string) ([]models.File, error) {
+	return m.migrateFiles(content, fromVersion, toVersion, m.Options)
+}
+
+func (m *MigratorService) migrateFiles(content []byte, fromVersion, toVersion string, options registry.MigrationOptions) ([]models.File, error) {
 	sourceParser, err := parser.GetParser(fromVersion)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get parser for version %s: %w", fromVersion, err)
@@ -29,19 +52,25 @@ func (m *MigratorService) Migrate(content []byte, fromVersion, toVersion string)
 		return nil, fmt.Errorf("failed to parse source document: %w", err)
 	}
 
-	var migrator Migrator
-	if fromVersion == "1.2" && toVersion == "2.1" {
-		migrator = qti12to21.New()
-	} else if fromVersion == "2.1" && toVersion == "3.0" {
-		migrator = qti21to30.New()
-	} else {
-		return nil, fmt.Errorf("unsupported migration path: %s to %s", fromVersion, toVersion)
+	plan, err := registry.Default.Plan(fromVersion, toVersion)
+	if err != nil {
+		return nil, err
+	}
+	serializer, err := registry.Default.Serializer(toVersion)
+	if err != nil {
+		return nil, err
 	}
 
-	result, err := migrator.Migrate(doc)
+	for _, step := range plan {
+		doc, err = step.Migrate(doc, options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	files, err := serializer.Serialize(doc, options)
 	if err != nil {
 		return nil, fmt.Errorf("migration failed: %w", err)
 	}
-
-	return result, nil
-}
\ No newline at end of file
+	return files, nil
+}
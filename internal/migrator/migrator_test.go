@@ -3,6 +3,9 @@ package migrator
 import (
 	"strings"
 	"testing"
+
+	"github.com/qti-migrator/pkg/models"
+	"github.com/qti-migrator/pkg/registry"
 )
 
 func TestMigratorService_New(t *testing.T) {
@@ -12,6 +15,24 @@ func TestMigratorService_New(t *testing.T) {
 	}
 }
 
+func TestBuiltinSteps(t *testing.T) {
+	var paths []string
+	for _, path := range registry.Default.Paths() {
+		paths = append(paths, path.String())
+	}
+	if strings.Join(paths, "; ") != "1.2 → 2.1; 1.2 → 3.0 (via 2.1); 2.1 → 3.0" {
+		t.Errorf("Unexpected built-in paths: %v", paths)
+	}
+
+	// Every built-in step can end a migration
+	for _, step := range registry.Default.Steps() {
+		capabilities := strings.Join(registry.Default.Capabilities(step), ", ")
+		if capabilities != "files, analysis" {
+			t.Errorf("Unexpected capabilities of %s: %s", step, capabilities)
+		}
+	}
+}
+
 func TestMigratorService_Migrate_QTI12to21(t *testing.T) {
 	qti12XML := `<?xml version="1.0" encoding="UTF-8"?>
 <questestinterop version="1.2">
@@ -63,9 +84,9 @@ func TestMigratorService_Migrate_QTI12to21(t *testing.T) {
 		t.Error("Expected XML header in result")
 	}
 	
-	// Check version was updated
-	if !strings.Contains(resultStr, `version="2.1"`) {
-		t.Error("Expected version to be updated to 2.1")
+	// Check the item is written as a QTI 2.1 assessmentItem
+	if !strings.Contains(resultStr, `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1"`) {
+		t.Error("Expected a QTI 2.1 assessmentItem")
 	}
 	
 	// Check that itemBody element is present (QTI 2.1 structure)
@@ -111,7 +132,7 @@ func TestMigratorService_Migrate_UnsupportedMigrationPath(t *testing.T) {
 </questestinterop>`
 
 	m := New()
-	_, err := m.Migrate([]byte(qti12XML), "1.2", "3.0")
+	_, err := m.Migrate([]byte(qti12XML), "1.2", "1.2")
 	
 	if err == nil {
 		t.Error("Expected error for unsupported migration path, but got none")
@@ -267,27 +288,29 @@ func TestMigratorService_Migrate_ComplexDocument(t *testing.T) {
 </questestinterop>`
 
 	m := New()
-	result, err := m.Migrate([]byte(complexQTI12XML), "1.2", "2.1")
+	files, err := m.MigrateFiles([]byte(complexQTI12XML), "1.2", "2.1")
 	
 	if err != nil {
 		t.Fatalf("Migration of complex document failed: %v", err)
 	}
+	if len(files) != 2 {
+		t.Fatalf("Expected a test and an item file, got %d files", len(files))
+	}
 	
-	resultStr := string(result)
+	testStr := string(files[0].Content)
+	resultStr := string(files[1].Content)
 	
-	// Check assessment structure is preserved
-	if !strings.Contains(resultStr, `<assessment`) {
-		t.Error("Expected assessment element to be preserved")
+	// Check the assessment and section become a test
+	if !strings.Contains(testStr, `<assessmentTest xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="test001" title="Sample Test">`) {
+		t.Error("Expected assessment to become an assessmentTest")
 	}
 	
-	// Check section structure is preserved
-	if !strings.Contains(resultStr, `<section`) {
-		t.Error("Expected section element to be preserved")
+	if !strings.Contains(testStr, `<assessmentSection identifier="sec001" title="Section 1" visible="true">`) {
+		t.Error("Expected section to become an assessmentSection")
 	}
 	
-	// Check metadata is preserved
-	if !strings.Contains(resultStr, `<metadata>`) {
-		t.Error("Expected metadata to be preserved")
+	if !strings.Contains(testStr, `<assessmentItemRef identifier="q001" href="items/q001.xml">`) {
+		t.Error("Expected an assessmentItemRef for the item")
 	}
 	
 	// Check QTI 2.1 specific elements
@@ -304,9 +327,9 @@ func TestMigratorService_Migrate_ComplexDocument(t *testing.T) {
 		t.Error("Expected shuffle attribute to be omitted when false (omitempty)")
 	}
 	
-	// Check feedback is preserved
-	if !strings.Contains(resultStr, `<itemfeedback`) {
-		t.Error("Expected itemfeedback to be preserved")
+	// Check feedback is preserved as modal feedback
+	if !strings.Contains(resultStr, `<modalFeedback identifier="correct_fb" outcomeIdentifier="FEEDBACK" showHide="show"`) {
+		t.Error("Expected itemfeedback to be preserved as modalFeedback")
 	}
 }
 
@@ -338,6 +361,90 @@ func TestMigratorService_Migrate_EmptyDocument(t *testing.T) {
 	}
 }
 
+func TestMigratorService_MigrateFiles(t *testing.T) {
+	qti21XML := `<questestinterop version="2.1">
+	<item ident="q001" title="First"><itemBody><p>One</p></itemBody></item>
+	<item ident="q002" title="Second"><itemBody><p>Two</p></itemBody></item>
+</questestinterop>`
+
+	m := New()
+	if _, err := m.Migrate([]byte(qti21XML), "2.1", "3.0"); err == nil {
+		t.Error("Expected Migrate to refuse a document that needs several files")
+	}
+
+	files, err := m.MigrateFiles([]byte(qti21XML), "2.1", "3.0")
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("Expected a test and two items, got %d files", len(files))
+	}
+	if files[0].Kind != models.FileTest || files[1].Href != "items/q001.xml" || files[2].Href != "items/q002.xml" {
+		t.Errorf("Unexpected files: %s %s %s", files[0].Kind, files[1].Href, files[2].Href)
+	}
+
+	files, err = m.MigrateFiles([]byte(`<questestinterop version="1.2"></questestinterop>`), "1.2", "2.1")
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	if len(files) != 1 || files[0].Href != "" || len(files[0].Content) == 0 {
+		t.Errorf("Expected a single document without href, got %+v", files)
+	}
+}
+
+func TestMigratorService_MigrateFiles_QTI12to30(t *testing.T) {
+	qti12XML := `<questestinterop>
+	<assessment ident="quiz" title="Quiz">
+		<duration>PT10M</duration>
+		<section ident="s1">
+			<section ident="inner"><item ident="q001"><presentation><material><mattext>One</mattext></material></presentation></item></section>
+			<item ident="q002" maxattempts="3">
+				<presentation>
+					<response_lid ident="RESPONSE" rcardinality="single">
+						<render_choice><response_label ident="A"><material><mattext>A</mattext></material></response_label></render_choice>
+					</response_lid>
+				</presentation>
+				<itemfeedback ident="fb"><material><mattext>Well done</mattext></material></itemfeedback>
+			</item>
+		</section>
+	</assessment>
+</questestinterop>`
+
+	files, err := New().MigrateFiles([]byte(qti12XML), "1.2", "3.0")
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	if len(files) != 3 || files[1].Href != "items/q001.xml" || files[2].Href != "items/q002.xml" {
+		t.Fatalf("Expected a test and two items, got %+v", files)
+	}
+
+	test := string(files[0].Content)
+	for _, expected := range []string{
+		`<qti-assessment-test xmlns="http://www.imsglobal.org/xsd/imsqtiasi_v3p0" identifier="quiz" title="Quiz">`,
+		`<qti-time-limits max-time="600"></qti-time-limits>`,
+		`<qti-assessment-item-ref identifier="q002" href="items/q002.xml">`,
+		`<qti-item-session-control max-attempts="3"></qti-item-session-control>`,
+	} {
+		if !strings.Contains(test, expected) {
+			t.Errorf("Expected %s in test:\n%s", expected, test)
+		}
+	}
+	// The sub-section comes before the item that follows it
+	if strings.Index(test, `identifier="inner"`) > strings.Index(test, `identifier="q002"`) {
+		t.Errorf("Expected section order to be kept:\n%s", test)
+	}
+
+	item := string(files[2].Content)
+	for _, expected := range []string{
+		`<qti-choice-interaction response-identifier="RESPONSE"`,
+		`<qti-modal-feedback outcome-identifier="FEEDBACK" identifier="fb" show-hide="show">Well done</qti-modal-feedback>`,
+	} {
+		if !strings.Contains(item, expected) {
+			t.Errorf("Expected %s in item:\n%s", expected, item)
+		}
+	}
+}
+
 func BenchmarkMigratorService_Migrate_SimpleDocument(b *testing.B) {
 	qti12XML := `<?xml version="1.0" encoding="UTF-8"?>
 <questestinterop version="1.2">
@@ -0,0 +1,376 @@
+package qti12to21
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/qti-migrator/internal/xhtml"
+	"github.com/qti-migrator/pkg/analysis"
+	"github.com/qti-migrator/pkg/models"
+)
+
+// analyzer collects the findings of a QTI 1.2 to 2.1 migration.
+type analyzer struct {
+	verbosity int
+}
+
+// Analyze reports what migrating doc to QTI 2.1 changes and what needs a
+// manual review. It is the analysis hook of the registered step.
+func Analyze(doc *models.QTIDocument, report *analysis.Report, verbosity int) {
+	a := &analyzer{verbosity: verbosity}
+	a.analyzeQTI12to21(doc, report)
+}
+
+func (a *analyzer) analyzeQTI12to21(doc *models.QTIDocument, report *analysis.Report) {
+	for _, item := range doc.Items {
+		a.analyzeItem12to21(&item, report)
+	}
+
+	if doc.Assessment != nil {
+		for i := range doc.Assessment.Sections {
+			a.analyzeSection12to21(&doc.Assessment.Sections[i], report)
+		}
+	}
+
+	// Attempts, durations and selection_ordering become test controls
+	for _, change := range ControlChanges(doc) {
+		if change.Problem != "" {
+			report.Warnings = append(report.Warnings, analysis.Warning{
+				ItemID:      change.ItemID,
+				ElementPath: change.Path,
+				Message:     fmt.Sprintf("%s is not migrated: %s", change.Original, change.Problem),
+				Suggestion:  "Set the control on the QTI 2.1 test by hand",
+			})
+			continue
+		}
+		report.MigrationDetails = append(report.MigrationDetails, analysis.MigrationDetail{
+			ItemID:      change.ItemID,
+			ElementPath: change.Path,
+			OldValue:    change.Original,
+			NewValue:    change.Control,
+			Action:      "transform",
+			Description: "Test control migrated to the QTI 2.1 test",
+		})
+	}
+}
+
+func (a *analyzer) analyzeSection12to21(section *models.Section, report *analysis.Report) {
+	for i := range section.Items {
+		a.analyzeItem12to21(&section.Items[i], report)
+	}
+	for i := range section.Sections {
+		a.analyzeSection12to21(&section.Sections[i], report)
+	}
+}
+
+func (a *analyzer) analyzeItem12to21(item *models.Item, report *analysis.Report) {
+	if item.Presentation != nil {
+		responses := item.Presentation.Responses()
+		matching := models.IsMatchingLayout(responses)
+		if matching {
+			var premises []string
+			for _, response := range responses {
+				premises = append(premises, response.Ident)
+			}
+			report.MigrationDetails = append(report.MigrationDetails, analysis.MigrationDetail{
+				ItemID:      item.Ident,
+				ElementPath: fmt.Sprintf("item[@ident='%s']/presentation", item.Ident),
+				OldValue:    strings.Join(premises, ", "),
+				NewValue:    "matchInteraction",
+				Action:      "transform",
+				Description: "Combine response_lids that share their answers into one matchInteraction with directedPair responses",
+			})
+		}
+
+		for _, response := range responses {
+			if !matching {
+				a.analyzeResponse12to21(item.Ident, &response, report)
+			}
+
+			if response.RenderChoice != nil && response.RenderChoice.Shuffle == "yes" {
+				report.MigrationDetails = append(report.MigrationDetails, analysis.MigrationDetail{
+					ItemID:      item.Ident,
+					ElementPath: fmt.Sprintf("item[@ident='%s']/presentation/%s[@ident='%s']/render_choice", item.Ident, response.Kind(), response.Ident),
+					OldValue:    `shuffle="yes"`,
+					NewValue:    `shuffle="true"`,
+					Action:      "transform",
+					Description: "Convert shuffle attribute from yes/no to true/false",
+				})
+			}
+		}
+
+		a.analyzeFlowContent12to21(item.Ident, "presentation", item.Presentation.Children(), report)
+	}
+
+	for _, feedback := range item.Feedback {
+		path := fmt.Sprintf("itemfeedback[@ident='%s']", feedback.Ident)
+		if feedback.Material != nil {
+			a.analyzeMaterial12to21(item.Ident, path+"/material", feedback.Material, report)
+		}
+		for i, flowMat := range feedback.FlowMat {
+			if flowMat.Material != nil {
+				a.analyzeMaterial12to21(item.Ident, fmt.Sprintf("%s/flow_mat[%d]/material", path, i+1), flowMat.Material, report)
+			}
+		}
+	}
+
+	if item.ResponseProc != nil {
+		if item.ResponseProc.ScoreModel == "" {
+			report.Warnings = append(report.Warnings, analysis.Warning{
+				ItemID:      item.Ident,
+				ElementPath: fmt.Sprintf("item[@ident='%s']/resprocessing", item.Ident),
+				Message:     "Score model not specified in QTI 1.2",
+				Suggestion:  "Default score model 'SumOfScores' will be applied",
+			})
+		}
+
+		for i, condition := range item.ResponseProc.ResCondition {
+			path := fmt.Sprintf("item[@ident='%s']/resprocessing/respcondition[%d]", item.Ident, i+1)
+			if strings.EqualFold(condition.Continue, "yes") {
+				report.MigrationDetails = append(report.MigrationDetails, analysis.MigrationDetail{
+					ItemID:      item.Ident,
+					ElementPath: path,
+					OldValue:    fmt.Sprintf(`continue="%s"`, condition.Continue),
+					NewValue:    "responseCondition",
+					Action:      "transform",
+					Description: "Following conditions are evaluated as separate responseCondition rules",
+				})
+			} else if strings.EqualFold(condition.Continue, "no") {
+				report.MigrationDetails = append(report.MigrationDetails, analysis.MigrationDetail{
+					ItemID:      item.Ident,
+					ElementPath: path,
+					OldValue:    fmt.Sprintf(`continue="%s"`, condition.Continue),
+					NewValue:    "responseElse",
+					Action:      "transform",
+					Description: "Following conditions are nested in responseElse so they only run when this one does not match",
+				})
+			}
+
+			if condition.ConditionVar != nil {
+				a.analyzeConditions12to21(item.Ident, path+"/conditionvar", condition.ConditionVar.Conditions(), report)
+			}
+
+			for _, displayFeedback := range condition.DisplayFeedback {
+				a.analyzeDisplayFeedback12to21(item, path, displayFeedback, report)
+			}
+		}
+	}
+
+	if item.Metadata != nil && item.Metadata.QTIMetadata != nil {
+		meta := item.Metadata.QTIMetadata
+		if meta.InteractionType != "" {
+			if !isValidQTI21InteractionType(meta.InteractionType) {
+				report.Warnings = append(report.Warnings, analysis.Warning{
+					ItemID:      item.Ident,
+					ElementPath: fmt.Sprintf("item[@ident='%s']/metadata/qtimetadata/interactiontype", item.Ident),
+					Message:     fmt.Sprintf("Interaction type '%s' may need adjustment for QTI 2.1", meta.InteractionType),
+					Suggestion:  "Review interaction type mapping for QTI 2.1 compliance",
+				})
+			}
+		}
+	}
+}
+
+// analyzeDisplayFeedback12to21 records the FEEDBACK rule a displayfeedback
+// becomes and warns when it links to feedback the item does not define.
+func (a *analyzer) analyzeDisplayFeedback12to21(item *models.Item, path string, displayFeedback models.DisplayFeedback, report *analysis.Report) {
+	for _, feedback := range item.Feedback {
+		if feedback.Ident == displayFeedback.LinkRefId {
+			report.MigrationDetails = append(report.MigrationDetails, analysis.MigrationDetail{
+				ItemID:      item.Ident,
+				ElementPath: path + "/displayfeedback",
+				OldValue:    fmt.Sprintf(`linkrefid="%s"`, displayFeedback.LinkRefId),
+				NewValue:    "setOutcomeValue FEEDBACK",
+				Action:      "transform",
+				Description: "Feedback is shown as modalFeedback bound to the FEEDBACK outcome",
+			})
+			return
+		}
+	}
+
+	report.Warnings = append(report.Warnings, analysis.Warning{
+		ItemID:      item.Ident,
+		ElementPath: path + "/displayfeedback",
+		Message:     fmt.Sprintf("displayfeedback links to unknown itemfeedback '%s'", displayFeedback.LinkRefId),
+		Suggestion:  "Add the itemfeedback or remove the displayfeedback",
+	})
+}
+
+// analyzeResponse12to21 records which 2.1 interaction a response becomes
+// and warns about responses that will not produce one.
+func (a *analyzer) analyzeResponse12to21(itemID string, response *models.Response, report *analysis.Report) {
+	path := fmt.Sprintf("item[@ident='%s']/presentation/%s[@ident='%s']", itemID, response.Kind(), response.Ident)
+
+	if response.RenderExtension != nil {
+		report.Warnings = append(report.Warnings, analysis.Warning{
+			ItemID:      itemID,
+			ElementPath: path + "/render_extension",
+			Message:     "Proprietary render_extension has no QTI 2.1 equivalent",
+			Suggestion:  "The extension is kept verbatim inside a customInteraction; review it for the target delivery system",
+		})
+		return
+	}
+
+	interaction := ""
+	switch {
+	case response.RenderSlider != nil:
+		interaction = "sliderInteraction"
+	case response.Kind() == models.ResponseGrp:
+		interaction = "associateInteraction"
+		if _, _, ok := response.MatchSets(); ok {
+			interaction = "matchInteraction"
+		}
+	case response.Kind() == models.ResponseXY:
+		interaction = "selectPointInteraction"
+	case response.RenderChoice != nil && response.IsOrdered():
+		interaction = "orderInteraction"
+	case response.RenderChoice != nil:
+		interaction = "choiceInteraction"
+	case response.RenderFib != nil && response.RenderFib.Rows > 1:
+		interaction = "extendedTextInteraction"
+	case response.RenderFib != nil:
+		interaction = "textEntryInteraction"
+	case response.RenderHotspot != nil:
+		interaction = "hotspotInteraction"
+	}
+
+	if interaction == "" {
+		report.Warnings = append(report.Warnings, analysis.Warning{
+			ItemID:      itemID,
+			ElementPath: path,
+			Message:     fmt.Sprintf("%s has no supported rendering and will not produce an interaction", response.Kind()),
+			Suggestion:  "Recreate the interaction manually after migration",
+		})
+		return
+	}
+
+	if a.verbosity >= 2 {
+		report.MigrationDetails = append(report.MigrationDetails, analysis.MigrationDetail{
+			ItemID:      itemID,
+			ElementPath: path,
+			OldValue:    response.Kind(),
+			NewValue:    interaction,
+			Action:      "convert",
+			Description: fmt.Sprintf("Convert %s to %s with a matching responseDeclaration", response.Kind(), interaction),
+		})
+	}
+}
+
+// analyzeConditions12to21 warns about conditions that have no response
+// processing equivalent and will never match after migration.
+func (a *analyzer) analyzeConditions12to21(itemID, path string, conditions []models.XMLNode, report *analysis.Report) {
+	for _, condition := range conditions {
+		switch condition.XMLName.Local {
+		case "varequal", "varlt", "varlte", "vargt", "vargte", "varsubstring", "varsubset", "varinside", "unanswered", "other":
+		case "not", "and", "or":
+			a.analyzeConditions12to21(itemID, path+"/"+condition.XMLName.Local, condition.Children, report)
+		default:
+			report.Warnings = append(report.Warnings, analysis.Warning{
+				ItemID:      itemID,
+				ElementPath: path + "/" + condition.XMLName.Local,
+				Message:     fmt.Sprintf("Condition %s is not supported and will never match", condition.XMLName.Local),
+				Suggestion:  "Rewrite the scoring rule manually in the migrated responseProcessing",
+			})
+		}
+	}
+}
+
+// analyzeFlowContent12to21 analyzes the materials of presentation or flow
+// content, including the prompts and labels of its responses.
+func (a *analyzer) analyzeFlowContent12to21(itemID, path string, children []models.FlowContent12, report *analysis.Report) {
+	materials, flows := 0, 0
+	for _, child := range children {
+		switch {
+		case child.Material != nil:
+			materials++
+			a.analyzeMaterial12to21(itemID, fmt.Sprintf("%s/material[%d]", path, materials), child.Material, report)
+		case child.Response != nil:
+			responsePath := fmt.Sprintf("%s/%s[@ident='%s']", path, child.Response.Kind(), child.Response.Ident)
+			if child.Response.Material != nil {
+				a.analyzeMaterial12to21(itemID, responsePath+"/material", child.Response.Material, report)
+			}
+			if child.Response.RenderChoice != nil {
+				for _, label := range child.Response.RenderChoice.Labels() {
+					if label.Material != nil {
+						a.analyzeMaterial12to21(itemID, fmt.Sprintf("%s/render_choice/response_label[@ident='%s']/material", responsePath, label.Ident), label.Material, report)
+					}
+				}
+			}
+		case child.Flow != nil:
+			flows++
+			a.analyzeFlowContent12to21(itemID, fmt.Sprintf("%s/flow[%d]", path, flows), child.Flow.Children(), report)
+		}
+	}
+}
+
+// analyzeMaterial12to21 reports every fix needed to turn the HTML of
+// mattext into XHTML, and images without a type.
+func (a *analyzer) analyzeMaterial12to21(itemID, path string, material *models.Material, report *analysis.Report) {
+	for i, matText := range material.MatText {
+		if !isHTMLText(matText) {
+			continue
+		}
+		textPath := fmt.Sprintf("%s/mattext[%d]", path, i+1)
+
+		if a.verbosity >= 2 {
+			report.MigrationDetails = append(report.MigrationDetails, analysis.MigrationDetail{
+				ItemID:      itemID,
+				ElementPath: textPath,
+				OldValue:    "text/html content",
+				NewValue:    "XHTML content (validated)",
+				Action:      "validate",
+				Description: "HTML content will be validated and converted to XHTML if necessary",
+			})
+		}
+
+		_, fixes := xhtml.Convert(matText.Content)
+		for _, fix := range fixes {
+			action := "transform"
+			if fix.Replacement == "" {
+				action = "remove"
+			}
+			report.MigrationDetails = append(report.MigrationDetails, analysis.MigrationDetail{
+				ItemID:      itemID,
+				ElementPath: textPath,
+				OldValue:    fix.Original,
+				NewValue:    fix.Replacement,
+				Action:      action,
+				Description: fix.Description,
+			})
+		}
+	}
+
+	for i, matImage := range material.MatImage {
+		if matImage.ImageType == "" && a.verbosity >= 2 {
+			report.Warnings = append(report.Warnings, analysis.Warning{
+				ItemID:      itemID,
+				ElementPath: fmt.Sprintf("%s/matimage[%d]", path, i+1),
+				Message:     "Image type not specified",
+				Suggestion:  "Image type will be inferred from file extension or set to 'image/jpeg' as default",
+			})
+		}
+	}
+}
+
+func isValidQTI21InteractionType(interactionType string) bool {
+	validTypes := map[string]bool{
+		"choiceInteraction":           true,
+		"orderInteraction":            true,
+		"associateInteraction":        true,
+		"matchInteraction":            true,
+		"gapMatchInteraction":         true,
+		"inlineChoiceInteraction":     true,
+		"textEntryInteraction":        true,
+		"extendedTextInteraction":     true,
+		"hotspotInteraction":          true,
+		"selectPointInteraction":      true,
+		"graphicOrderInteraction":     true,
+		"graphicAssociateInteraction": true,
+		"positionObjectInteraction":   true,
+		"sliderInteraction":           true,
+		"drawingInteraction":          true,
+		"uploadInteraction":           true,
+		"customInteraction":           true,
+	}
+	return validTypes[interactionType]
+}
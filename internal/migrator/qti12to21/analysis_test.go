@@ -0,0 +1,40 @@
+package qti12to21
+
+import (
+	"testing"
+)
+
+func TestIsValidQTI21InteractionType(t *testing.T) {
+	validTypes := []string{
+		"choiceInteraction",
+		"orderInteraction",
+		"associateInteraction",
+		"matchInteraction",
+		"textEntryInteraction",
+		"extendedTextInteraction",
+		"hotspotInteraction",
+		"sliderInteraction",
+		"uploadInteraction",
+		"customInteraction",
+	}
+
+	invalidTypes := []string{
+		"multiple_choice",
+		"fill_in_blank",
+		"invalid_type",
+		"",
+		"CHOICEINTERACTION", // Case sensitive
+	}
+
+	for _, validType := range validTypes {
+		if !isValidQTI21InteractionType(validType) {
+			t.Errorf("Expected '%s' to be a valid QTI 2.1 interaction type", validType)
+		}
+	}
+
+	for _, invalidType := range invalidTypes {
+		if isValidQTI21InteractionType(invalidType) {
+			t.Errorf("Expected '%s' to be an invalid QTI 2.1 interaction type", invalidType)
+		}
+	}
+}
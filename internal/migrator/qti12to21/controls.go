@@ -0,0 +1,150 @@
+package qti12to21
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/qti-migrator/pkg/models"
+)
+
+// ControlChange records a QTI 1.2 test control and the QTI 2.1 control it
+// migrates to. Controls that cannot be migrated have no Control and give the
+// reason in Problem.
+type ControlChange struct {
+	// Path of the assessment, section or item, e.g. section[@ident='s1']
+	Path string
+	// Set for the controls of an item
+	ItemID string
+	// The control as written, such as <duration>PT30M</duration>
+	Original string
+	Control  string
+	Problem  string
+}
+
+// testControls are the QTI 2.1 controls of a QTI 1.2 assessment, section or
+// item, with the changes that produced them.
+type testControls struct {
+	itemSessionControl *models.ItemSessionControl
+	timeLimits         *models.TimeLimits
+	selection          *models.Selection
+	ordering           *models.Ordering
+	changes            []ControlChange
+}
+
+// controlsOf maps maxattempts to itemSessionControl, duration to timeLimits
+// and selection_ordering to selection and ordering.
+func controlsOf(path string, maxAttempts int, duration string, selectionOrdering *models.SelectionOrdering) testControls {
+	var controls testControls
+	change := func(original, control string) {
+		controls.changes = append(controls.changes, ControlChange{Path: path, Original: original, Control: control})
+	}
+	problem := func(original, problem string) {
+		controls.changes = append(controls.changes, ControlChange{Path: path, Original: original, Problem: problem})
+	}
+
+	if maxAttempts > 0 {
+		attempts := maxAttempts
+		controls.itemSessionControl = &models.ItemSessionControl{MaxAttempts: &attempts}
+		change(fmt.Sprintf(`maxattempts="%d"`, maxAttempts), fmt.Sprintf(`itemSessionControl maxAttempts="%d"`, maxAttempts))
+	}
+
+	if duration = strings.TrimSpace(duration); duration != "" {
+		original := "<duration>" + duration + "</duration>"
+		if seconds, ok := parseDuration(duration); ok && seconds > 0 {
+			controls.timeLimits = &models.TimeLimits{MaxTime: seconds}
+			change(original, fmt.Sprintf(`timeLimits maxTime="%s"`, strconv.FormatFloat(seconds, 'f', -1, 64)))
+		} else {
+			problem(original, "not an ISO 8601 duration in days, hours, minutes and seconds")
+		}
+	}
+
+	if selectionOrdering == nil {
+		return controls
+	}
+	for _, selection := range selectionOrdering.Selection {
+		original := "<selection>"
+		switch {
+		case selection.SourcebankRef != "" || len(selection.Conditions) > 0:
+			problem(original, "selection from source banks or by metadata has no QTI 2.1 equivalent")
+		case selection.SelectionNumber <= 0:
+			problem(original, "selection without selection_number")
+		case controls.selection != nil:
+			problem(original, "QTI 2.1 allows a single selection per section")
+		default:
+			controls.selection = &models.Selection{Select: selection.SelectionNumber}
+			change(fmt.Sprintf("<selection_number>%d</selection_number>", selection.SelectionNumber), fmt.Sprintf(`selection select="%d"`, selection.SelectionNumber))
+		}
+	}
+	if order := selectionOrdering.Order; order != nil && strings.EqualFold(order.OrderType, "Random") {
+		controls.ordering = &models.Ordering{Shuffle: true}
+		change(fmt.Sprintf("order_type=%q", order.OrderType), `ordering shuffle="true"`)
+	}
+	return controls
+}
+
+// ControlChanges lists the test controls of a QTI 1.2 document and what
+// they migrate to, in document order. Items written to a file of their own,
+// without a test, have no item ref to carry their controls.
+func ControlChanges(doc *models.QTIDocument) []ControlChange {
+	var changes []ControlChange
+	itemChanges := func(item *models.Item, standalone bool) {
+		controls := controlsOf(fmt.Sprintf("item[@ident='%s']", item.Ident), item.MaxAttempts, item.Duration, nil)
+		for _, change := range controls.changes {
+			change.ItemID = item.Ident
+			if standalone && change.Control != "" {
+				change.Control = ""
+				change.Problem = "QTI 2.1 keeps item controls on the assessmentItemRef of a test; a single item file has none"
+			}
+			changes = append(changes, change)
+		}
+	}
+	var sectionChanges func(section *models.Section)
+	sectionChanges = func(section *models.Section) {
+		changes = append(changes, controlsOf(fmt.Sprintf("section[@ident='%s']", section.Ident), section.MaxAttempts, section.Duration, section.SelectionOrdering).changes...)
+		for _, child := range section.Children() {
+			switch c := child.(type) {
+			case *models.Item:
+				itemChanges(c, false)
+			case *models.Section:
+				sectionChanges(c)
+			}
+		}
+	}
+
+	if assessment := doc.Assessment; assessment != nil {
+		changes = append(changes, controlsOf(fmt.Sprintf("assessment[@ident='%s']", assessment.Ident), assessment.MaxAttempts, assessment.Duration, assessment.SelectionOrdering).changes...)
+		for i := range assessment.Sections {
+			sectionChanges(&assessment.Sections[i])
+		}
+	}
+	for i := range doc.Items {
+		itemChanges(&doc.Items[i], len(doc.Items) == 1 && doc.Assessment == nil)
+	}
+	return changes
+}
+
+var isoDuration = regexp.MustCompile(`^P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// parseDuration returns the seconds of an ISO 8601 duration such as PT30M
+// or P0Y0M0DT1H0M0S. Years and months have no fixed length and are only
+// accepted when zero.
+func parseDuration(duration string) (float64, bool) {
+	match := isoDuration.FindStringSubmatch(strings.ToUpper(duration))
+	if match == nil || duration == "P" || strings.HasSuffix(duration, "T") {
+		return 0, false
+	}
+
+	var values [7]float64
+	for i, part := range match[1:] {
+		if part != "" {
+			values[i], _ = strconv.ParseFloat(part, 64)
+		}
+	}
+	if values[0] != 0 || values[1] != 0 {
+		return 0, false
+	}
+	weeks, days, hours, minutes, seconds := values[2], values[3], values[4], values[5], values[6]
+	return (((weeks*7+days)*24+hours)*60+minutes)*60 + seconds, true
+}
@@ -0,0 +1,121 @@
+package qti12to21
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/qti-migrator/internal/parser/qti12"
+	"github.com/qti-migrator/pkg/registry"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		duration string
+		seconds  float64
+		ok       bool
+	}{
+		{"PT30M", 1800, true},
+		{"P0Y0M0DT1H0M0S", 3600, true},
+		{"P1DT1.5S", 86401.5, true},
+		{"P1W", 604800, true},
+		{"pt90s", 90, true},
+		{"P1M", 0, false},
+		{"PT", 0, false},
+		{"P", 0, false},
+		{"30", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.duration, func(t *testing.T) {
+			seconds, ok := parseDuration(tt.duration)
+			if ok != tt.ok || seconds != tt.seconds {
+				t.Errorf("parseDuration(%q) = %v, %v, want %v, %v", tt.duration, seconds, ok, tt.seconds, tt.ok)
+			}
+		})
+	}
+}
+
+func TestMigrateFiles_TestControls(t *testing.T) {
+	doc, err := qti12.New().Parse([]byte(`<questestinterop>
+	<assessment ident="quiz" title="Quiz" maxattempts="2">
+		<duration>PT45M</duration>
+		<selection_ordering><order order_type="Random"/></selection_ordering>
+		<section ident="pool" title="Pool" maxattempts="3">
+			<duration>PT20M</duration>
+			<selection_ordering>
+				<selection><selection_number>1</selection_number></selection>
+				<order order_type="Random"/>
+			</selection_ordering>
+			<item ident="p1" maxattempts="1"><duration>PT90S</duration></item>
+			<item ident="p2"/>
+		</section>
+		<section ident="meta">
+			<selection_ordering><selection><selection_metadata mdname="difficulty">hard</selection_metadata></selection></selection_ordering>
+			<item ident="m1"/>
+		</section>
+	</assessment>
+</questestinterop>`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	files, err := migrateFiles(doc, registry.MigrationOptions{})
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	test := string(files[0].Content)
+
+	for _, expected := range []string{
+		`<timeLimits maxTime="2700"></timeLimits>`,
+		`<itemSessionControl maxAttempts="2"></itemSessionControl>`,
+		`<assessmentSection identifier="quiz-sections" title="Quiz" visible="false">
+      <ordering shuffle="true"></ordering>
+      <assessmentSection identifier="pool" title="Pool" visible="true">
+        <itemSessionControl maxAttempts="3"></itemSessionControl>
+        <timeLimits maxTime="1200"></timeLimits>
+        <selection select="1"></selection>
+        <ordering shuffle="true"></ordering>
+        <assessmentItemRef identifier="p1" href="items/p1.xml">
+          <itemSessionControl maxAttempts="1"></itemSessionControl>
+          <timeLimits maxTime="90"></timeLimits>
+        </assessmentItemRef>`,
+		`<assessmentSection identifier="meta" title="meta" visible="true">
+        <assessmentItemRef identifier="m1" href="items/m1.xml"></assessmentItemRef>`,
+	} {
+		if !strings.Contains(test, expected) {
+			t.Errorf("Expected test to contain %q, got:\n%s", expected, test)
+		}
+	}
+	if strings.Contains(string(files[1].Content), "maxattempts") {
+		t.Errorf("Expected no maxattempts attribute on the item, got:\n%s", files[1].Content)
+	}
+
+	var problems []string
+	for _, change := range ControlChanges(doc) {
+		if change.Problem != "" {
+			problems = append(problems, change.Path)
+		}
+	}
+	if len(problems) != 1 || problems[0] != "section[@ident='meta']" {
+		t.Errorf("Expected only the metadata selection to be reported as a problem, got %v", problems)
+	}
+}
+
+func TestControlChanges_StandaloneItem(t *testing.T) {
+	doc, err := qti12.New().Parse([]byte(`<questestinterop>
+	<item ident="q1" maxattempts="3"><duration>PT1M</duration></item>
+</questestinterop>`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	changes := ControlChanges(doc)
+	if len(changes) != 2 {
+		t.Fatalf("Expected two changes, got %+v", changes)
+	}
+	for _, change := range changes {
+		if change.Control != "" || change.Problem == "" || change.ItemID != "q1" {
+			t.Errorf("Expected the controls of a single item file to be reported as lost, got %+v", change)
+		}
+	}
+}
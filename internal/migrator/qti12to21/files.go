@@ -0,0 +1,326 @@
+package qti12to21
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/qti-migrator/internal/assets"
+	"github.com/qti-migrator/internal/testfiles"
+	"github.com/qti-migrator/pkg/models"
+	"github.com/qti-migrator/pkg/registry"
+)
+
+// Namespaces of the QTI 2.x profiles Serializer21 writes
+const (
+	namespaceQTI21 = "http://www.imsglobal.org/xsd/imsqti_v2p1"
+	namespaceQTI22 = "http://www.imsglobal.org/xsd/imsqti_v2p2"
+)
+
+// QTI21AssessmentItem is a QTI 2.1 item written to a file of its own. Its
+// XMLName carries the namespace of the profile written.
+type QTI21AssessmentItem struct {
+	XMLName            xml.Name
+	Identifier         string                     `xml:"identifier,attr"`
+	Title              string                     `xml:"title,attr"`
+	Adaptive           bool                       `xml:"adaptive,attr"`
+	TimeDependent      bool                       `xml:"timeDependent,attr"`
+	ToolName           string                     `xml:"toolName,attr,omitempty"`
+	ToolVersion        string                     `xml:"toolVersion,attr,omitempty"`
+	ResponseDecl       []models.ResponseDecl      `xml:"responseDeclaration,omitempty"`
+	OutcomeDecl        []models.OutcomeDecl       `xml:"outcomeDeclaration,omitempty"`
+	TemplateDecl       []models.TemplateDecl      `xml:"templateDeclaration,omitempty"`
+	TemplateProcessing *models.TemplateProcessing `xml:"templateProcessing,omitempty"`
+	Stylesheets        []models.Stylesheet        `xml:"stylesheet,omitempty"`
+	ItemBody           *models.ItemBody           `xml:"itemBody,omitempty"`
+	ResponseProcessing *models.ResponseProcessing `xml:"responseProcessing,omitempty"`
+	ModalFeedback      []models.ModalFeedback     `xml:"modalFeedback,omitempty"`
+}
+
+// QTI21AssessmentTest is a QTI 2.1 test whose items live in separate files
+type QTI21AssessmentTest struct {
+	XMLName           xml.Name
+	Identifier        string                    `xml:"identifier,attr"`
+	Title             string                    `xml:"title,attr"`
+	ToolName          string                    `xml:"toolName,attr,omitempty"`
+	ToolVersion       string                    `xml:"toolVersion,attr,omitempty"`
+	OutcomeDecl       []models.OutcomeDecl      `xml:"outcomeDeclaration,omitempty"`
+	TimeLimits        *models.TimeLimits        `xml:"timeLimits,omitempty"`
+	TestParts         []QTI21TestPart           `xml:"testPart"`
+	OutcomeProcessing *models.OutcomeProcessing `xml:"outcomeProcessing,omitempty"`
+	TestFeedback      []models.TestFeedback     `xml:"testFeedback,omitempty"`
+}
+
+type QTI21TestPart struct {
+	XMLName            xml.Name                   `xml:"testPart"`
+	Identifier         string                     `xml:"identifier,attr"`
+	NavigationMode     string                     `xml:"navigationMode,attr"`
+	SubmissionMode     string                     `xml:"submissionMode,attr"`
+	ItemSessionControl *models.ItemSessionControl `xml:"itemSessionControl,omitempty"`
+	TimeLimits         *models.TimeLimits         `xml:"timeLimits,omitempty"`
+	Sections           []QTI21AssessmentSection   `xml:"assessmentSection"`
+	TestFeedback       []models.TestFeedback      `xml:"testFeedback,omitempty"`
+}
+
+type QTI21AssessmentSection struct {
+	XMLName            xml.Name                   `xml:"assessmentSection"`
+	Identifier         string                     `xml:"identifier,attr"`
+	Title              string                     `xml:"title,attr"`
+	Visible            bool                       `xml:"visible,attr"`
+	ItemSessionControl *models.ItemSessionControl `xml:"itemSessionControl,omitempty"`
+	TimeLimits         *models.TimeLimits         `xml:"timeLimits,omitempty"`
+	Selection          *models.Selection          `xml:"selection,omitempty"`
+	Ordering           *models.Ordering           `xml:"ordering,omitempty"`
+	RubricBlocks       []models.RubricBlock       `xml:"rubricBlock,omitempty"`
+	// Item refs followed by items and sub-sections in document order
+	Content []interface{} `xml:",any"`
+}
+
+// Serializer21 writes the QTI 2.1 model as QTI 2.1 or 2.2 files.
+type Serializer21 struct{}
+
+func NewSerializer() *Serializer21 {
+	return &Serializer21{}
+}
+
+// Serialize writes doc as QTI 2.1 files. A document with a single item
+// becomes one assessmentItem file. Several items, or a test, become one file
+// per item and an assessmentTest that references them, which comes first. A
+// document without items keeps the questestinterop wrapper and has no href.
+func (s *Serializer21) Serialize(doc *models.QTIDocument, options registry.MigrationOptions) ([]models.File, error) {
+	namespace, err := profileNamespace(options.Profile)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(doc.Items) == 0 && doc.Assessment == nil {
+		output, err := xml.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal migrated document: %w", err)
+		}
+		return []models.File{{Content: append([]byte(xml.Header), output...)}}, nil
+	}
+
+	if len(doc.Items) == 1 && doc.Assessment == nil {
+		item := &doc.Items[0]
+		content, err := writeItem(item, namespace)
+		if err != nil {
+			return nil, err
+		}
+		return []models.File{{
+			Href:       testfiles.FileName(item.Ident, "item1") + ".xml",
+			Identifier: item.Ident,
+			Kind:       models.FileItem,
+			Content:    content,
+			Assets:     assets.List(content),
+		}}, nil
+	}
+
+	w := &testWriter{
+		namespace: namespace,
+		items:     testfiles.NewItems(options.Layout.ItemDirectory()),
+	}
+	test := w.test(doc)
+	if err := w.items.Err(); err != nil {
+		return nil, err
+	}
+	if options.Layout == registry.LayoutSingle && w.items.Len() > 0 {
+		return nil, fmt.Errorf("document holds %d items, which QTI 2.1 stores in separate files: migrate it to a directory or package", w.items.Len())
+	}
+	return w.items.Files(test.Identifier, test)
+}
+
+// profileNamespace returns the namespace of a QTI 2.x profile.
+func profileNamespace(profile string) (string, error) {
+	switch profile {
+	case "", "2.1":
+		return namespaceQTI21, nil
+	case "2.2":
+		return namespaceQTI22, nil
+	default:
+		return "", fmt.Errorf("QTI 2.1 output has no profile %q, expected 2.1 or 2.2", profile)
+	}
+}
+
+// writeItem writes an item as a standalone assessmentItem. Feedback read
+// from modalFeedback is written as modalFeedback again.
+func writeItem(item *models.Item, namespace string) ([]byte, error) {
+	assessmentItem := QTI21AssessmentItem{
+		XMLName:            xml.Name{Space: namespace, Local: "assessmentItem"},
+		Identifier:         item.Ident,
+		Title:              item.Title,
+		Adaptive:           item.Adaptive,
+		TimeDependent:      item.TimeDependent,
+		ToolName:           item.ToolName,
+		ToolVersion:        item.ToolVersion,
+		ResponseDecl:       item.ResponseDecl,
+		OutcomeDecl:        item.OutcomeDecl,
+		TemplateDecl:       item.TemplateDecl,
+		TemplateProcessing: item.TemplateProcessing,
+		Stylesheets:        item.Stylesheets,
+		ItemBody:           item.ItemBody,
+		ResponseProcessing: item.ResponseProcessing,
+		ModalFeedback:      item.ModalFeedback,
+	}
+	for _, feedback := range item.Feedback {
+		assessmentItem.ModalFeedback = append(assessmentItem.ModalFeedback, models.ModalFeedback{
+			XMLName:           xml.Name{Local: "modalFeedback"},
+			Identifier:        feedback.Ident,
+			OutcomeIdentifier: feedback.OutcomeIdentifier,
+			ShowHide:          feedback.ShowHide,
+			Title:             feedback.Title,
+			Content:           feedbackContent(&feedback),
+		})
+	}
+
+	output, err := xml.MarshalIndent(assessmentItem, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal migrated item: %w", err)
+	}
+	return append([]byte(xml.Header), output...), nil
+}
+
+func feedbackContent(feedback *models.Feedback) string {
+	var content strings.Builder
+	materials := []*models.Material{feedback.Material}
+	for _, flowMat := range feedback.FlowMat {
+		materials = append(materials, flowMat.Material)
+	}
+	for _, material := range materials {
+		if material == nil {
+			continue
+		}
+		for _, matText := range material.MatText {
+			content.WriteString(matText.Content)
+		}
+	}
+	return content.String()
+}
+
+// testWriter builds an assessmentTest and collects the item files it
+// references.
+type testWriter struct {
+	namespace string
+	items     *testfiles.Items
+	// Sections written so far, for naming sections without an identifier
+	sections int
+}
+
+func (w *testWriter) test(doc *models.QTIDocument) *QTI21AssessmentTest {
+	test := &QTI21AssessmentTest{
+		XMLName:    xml.Name{Space: w.namespace, Local: "assessmentTest"},
+		Identifier: "test",
+	}
+
+	var sections []QTI21AssessmentSection
+	if assessment := doc.Assessment; assessment != nil {
+		if assessment.Ident != "" {
+			test.Identifier = assessment.Ident
+		}
+		test.Title = assessment.Title
+		test.TimeLimits = assessment.TimeLimits
+		test.ToolName = assessment.ToolName
+		test.ToolVersion = assessment.ToolVersion
+		test.OutcomeDecl = assessment.OutcomeDecl
+		test.OutcomeProcessing = assessment.OutcomeProcessing
+		test.TestFeedback = assessment.TestFeedback
+
+		// Top-level sections with a navigation mode stand for test parts
+		for i := range assessment.Sections {
+			section := &assessment.Sections[i]
+			if section.NavigationMode == "" {
+				sections = append(sections, w.section(section))
+				continue
+			}
+			var partSections []QTI21AssessmentSection
+			for j := range section.Sections {
+				partSections = append(partSections, w.section(&section.Sections[j]))
+			}
+			part := w.testPart(section.Ident, section.NavigationMode, section.SubmissionMode, partSections)
+			part.ItemSessionControl = section.ItemSessionControl
+			part.TimeLimits = section.TimeLimits
+			part.TestFeedback = section.TestFeedback
+			test.TestParts = append(test.TestParts, part)
+		}
+	}
+	if test.Title == "" {
+		test.Title = test.Identifier
+	}
+	if len(sections) > 0 || len(test.TestParts) == 0 {
+		test.TestParts = append(test.TestParts, w.testPart("", "", "", sections))
+	}
+
+	// Items outside the test get a section of their own
+	if len(doc.Items) > 0 {
+		identifier := "items"
+		if doc.Assessment == nil {
+			identifier = "section1"
+		}
+		section := QTI21AssessmentSection{Identifier: identifier, Title: identifier, Visible: true}
+		for i := range doc.Items {
+			section.Content = append(section.Content, w.itemRef(&doc.Items[i]))
+		}
+		part := &test.TestParts[len(test.TestParts)-1]
+		part.Sections = append(part.Sections, section)
+	}
+	return test
+}
+
+func (w *testWriter) testPart(identifier, navigationMode, submissionMode string, sections []QTI21AssessmentSection) QTI21TestPart {
+	return QTI21TestPart{
+		Identifier:     testfiles.OrDefault(identifier, testfiles.DefaultTestPart),
+		NavigationMode: testfiles.OrDefault(navigationMode, testfiles.DefaultNavigationMode),
+		SubmissionMode: testfiles.OrDefault(submissionMode, testfiles.DefaultSubmissionMode),
+		Sections:       sections,
+	}
+}
+
+func (w *testWriter) section(section *models.Section) QTI21AssessmentSection {
+	w.sections++
+	migrated := QTI21AssessmentSection{
+		Identifier:         testfiles.OrDefault(section.Ident, "section"+strconv.Itoa(w.sections)),
+		Visible:            section.Visible == nil || *section.Visible,
+		ItemSessionControl: section.ItemSessionControl,
+		TimeLimits:         section.TimeLimits,
+		Selection:          section.Selection,
+		Ordering:           section.Ordering,
+		RubricBlocks:       section.RubricBlocks,
+	}
+	migrated.Title = testfiles.OrDefault(section.Title, migrated.Identifier)
+
+	// Items of a QTI 2.x test are already in files of their own
+	for _, ref := range section.ItemRefs {
+		w.items.Reserve(ref.Identifier)
+		migrated.Content = append(migrated.Content, ref)
+	}
+	for _, child := range section.Children() {
+		switch c := child.(type) {
+		case *models.Item:
+			migrated.Content = append(migrated.Content, w.itemRef(c))
+		case *models.Section:
+			migrated.Content = append(migrated.Content, w.section(c))
+		}
+	}
+	return migrated
+}
+
+// itemRef writes an item to a file of its own and returns the reference to
+// it. Items sharing an identifier get distinct refs and files.
+func (w *testWriter) itemRef(item *models.Item) models.ItemRef {
+	identifier, href := w.items.Add(item, func(item *models.Item) ([]byte, error) {
+		return writeItem(item, w.namespace)
+	})
+	ref := models.ItemRef{
+		Identifier:         identifier,
+		Href:               href,
+		ItemSessionControl: item.ItemSessionControl,
+		TimeLimits:         item.TimeLimits,
+	}
+	// Legacy 2.1 documents put maxattempts on the item itself
+	if ref.ItemSessionControl == nil && item.MaxAttempts > 0 {
+		attempts := item.MaxAttempts
+		ref.ItemSessionControl = &models.ItemSessionControl{MaxAttempts: &attempts}
+	}
+	return ref
+}
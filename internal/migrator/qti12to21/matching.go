@@ -0,0 +1,173 @@
+package qti12to21
+
+import (
+	"encoding/xml"
+	"strings"
+
+	"github.com/qti-migrator/pkg/models"
+)
+
+// matchingResponseIdent identifies the response_grp that replaces a matching
+// question laid out as separate response_lids.
+const matchingResponseIdent = "RESPONSE"
+
+// normalizeMatching rewrites an item whose premises are separate
+// response_lids into a single response_grp, with the premises as sources and
+// the shared answers as targets, and writes the values of pair responses in
+// the "source target" form of QTI 2.1. The original item is left untouched.
+func normalizeMatching(item *models.Item) *models.Item {
+	if item.Presentation == nil {
+		return item
+	}
+
+	premises := make(map[string]bool)
+	pairs := make(map[string]bool)
+	normalized := *item
+
+	responses := item.Presentation.Responses()
+	if models.IsMatchingLayout(responses) {
+		for _, response := range responses {
+			premises[response.Ident] = true
+		}
+		grp := matchingResponse(responses)
+		placed := false
+
+		presentation := *item.Presentation
+		presentation.Content = replaceResponses(presentation.Children(), premises, grp, &placed)
+		normalized.Presentation = &presentation
+		pairs[grp.Ident] = true
+	}
+
+	for _, response := range responses {
+		if response.Kind() == models.ResponseGrp {
+			pairs[response.Ident] = true
+		}
+	}
+
+	if item.ResponseProc != nil && (len(premises) > 0 || len(pairs) > 0) {
+		responseProc := *item.ResponseProc
+		responseProc.ResCondition = make([]models.ResCondition, len(item.ResponseProc.ResCondition))
+		for i, condition := range item.ResponseProc.ResCondition {
+			if condition.ConditionVar != nil {
+				conditionVar := *condition.ConditionVar
+				conditionVar.VarEqual = rewritePairVarEquals(condition.ConditionVar.VarEqual, premises, pairs)
+				if condition.ConditionVar.And != nil {
+					and := *condition.ConditionVar.And
+					and.VarEqual = rewritePairVarEquals(and.VarEqual, premises, pairs)
+					conditionVar.And = &and
+				}
+				conditionVar.Expressions = rewritePairConditions(condition.ConditionVar.Expressions, premises, pairs)
+				condition.ConditionVar = &conditionVar
+			}
+			responseProc.ResCondition[i] = condition
+		}
+		normalized.ResponseProc = &responseProc
+	}
+
+	return &normalized
+}
+
+// matchingResponse builds the response_grp for the premises of a matching
+// layout. Each premise may be matched once; answers may be reused.
+func matchingResponse(premises []models.Response12) *models.Response12 {
+	first := premises[0]
+	sources := models.FlowLabel{XMLName: xml.Name{Local: "flow_label"}}
+	for _, premise := range premises {
+		sources.ResponseLabel = append(sources.ResponseLabel, models.ResponseLabel{
+			XMLName:  xml.Name{Local: "response_label"},
+			Ident:    premise.Ident,
+			MatchMax: 1,
+			Material: premise.Material,
+		})
+	}
+
+	return &models.Response12{
+		XMLName:      xml.Name{Local: models.ResponseGrp},
+		Ident:        matchingResponseIdent,
+		RCardinality: "Multiple",
+		RenderChoice: &models.RenderChoice{
+			XMLName:   xml.Name{Local: "render_choice"},
+			Shuffle:   first.RenderChoice.Shuffle,
+			MaxNumber: len(premises),
+			FlowLabel: []models.FlowLabel{
+				sources,
+				{XMLName: xml.Name{Local: "flow_label"}, ResponseLabel: first.RenderChoice.Labels()},
+			},
+		},
+	}
+}
+
+// replaceResponses puts grp in place of the first premise and drops the
+// others, along with flows that held nothing but premises.
+func replaceResponses(children []models.FlowContent12, premises map[string]bool, grp *models.Response12, placed *bool) []models.FlowContent12 {
+	var replaced []models.FlowContent12
+	for _, child := range children {
+		switch {
+		case child.Response != nil && premises[child.Response.Ident]:
+			if !*placed {
+				replaced = append(replaced, models.FlowContent12{Response: grp})
+				*placed = true
+			}
+		case child.Flow != nil:
+			flow := *child.Flow
+			flow.Content = replaceResponses(child.Flow.Children(), premises, grp, placed)
+			if len(flow.Content) > 0 {
+				replaced = append(replaced, models.FlowContent12{Flow: &flow})
+			}
+		default:
+			replaced = append(replaced, child)
+		}
+	}
+	return replaced
+}
+
+func rewritePairVarEquals(varEquals []models.VarEqual, premises, pairs map[string]bool) []models.VarEqual {
+	if varEquals == nil {
+		return nil
+	}
+	rewritten := make([]models.VarEqual, len(varEquals))
+	for i, varEqual := range varEquals {
+		varEqual.RespIdent, varEqual.Value = pairValue(varEqual.RespIdent, varEqual.Value, premises, pairs)
+		rewritten[i] = varEqual
+	}
+	return rewritten
+}
+
+// rewritePairConditions applies pairValue to the conditions of a
+// conditionvar tree.
+func rewritePairConditions(nodes []models.XMLNode, premises, pairs map[string]bool) []models.XMLNode {
+	if nodes == nil {
+		return nil
+	}
+	rewritten := make([]models.XMLNode, len(nodes))
+	for i, node := range nodes {
+		if respIdent := node.Attr("respident"); respIdent != "" {
+			node.Attrs = append([]xml.Attr(nil), node.Attrs...)
+			for j, attr := range node.Attrs {
+				if attr.Name.Local == "respident" && premises[respIdent] {
+					node.Attrs[j].Value = matchingResponseIdent
+				}
+			}
+			if node.XMLName.Local == "varequal" {
+				_, node.Value = pairValue(respIdent, node.Value, premises, pairs)
+			}
+		}
+		node.Children = rewritePairConditions(node.Children, premises, pairs)
+		rewritten[i] = node
+	}
+	return rewritten
+}
+
+// pairValue moves a premise value onto the matching response_grp as the
+// pair "premise answer", and separates the identifiers of a pair value,
+// which QTI 1.2 also writes as "A,B", by a single space.
+func pairValue(respIdent, value string, premises, pairs map[string]bool) (string, string) {
+	switch {
+	case premises[respIdent]:
+		return matchingResponseIdent, respIdent + " " + strings.TrimSpace(value)
+	case pairs[respIdent]:
+		return respIdent, strings.Join(strings.Fields(strings.ReplaceAll(value, ",", " ")), " ")
+	default:
+		return respIdent, value
+	}
+}
@@ -0,0 +1,207 @@
+package qti12to21
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/qti-migrator/pkg/models"
+)
+
+func TestMigrator12to21_Migrate_MatchingLayout(t *testing.T) {
+	output := migrateItem12(t, `<questestinterop>
+	<item ident="q001" title="Capitals">
+		<presentation>
+			<material><mattext>Match each country to its capital.</mattext></material>
+			<response_lid ident="FR" rcardinality="Single">
+				<material><mattext>France</mattext></material>
+				<render_choice shuffle="yes">
+					<response_label ident="P"><material><mattext>Paris</mattext></material></response_label>
+					<response_label ident="B"><material><mattext>Berlin</mattext></material></response_label>
+				</render_choice>
+			</response_lid>
+			<response_lid ident="DE" rcardinality="Single">
+				<material><mattext>Germany</mattext></material>
+				<render_choice shuffle="yes">
+					<response_label ident="P"><material><mattext>Paris</mattext></material></response_label>
+					<response_label ident="B"><material><mattext>Berlin</mattext></material></response_label>
+				</render_choice>
+			</response_lid>
+		</presentation>
+		<resprocessing>
+			<outcomes><decvar varname="SCORE" vartype="Decimal" defaultval="0"/></outcomes>
+			<respcondition continue="Yes">
+				<conditionvar><varequal respident="FR">P</varequal></conditionvar>
+				<setvar action="Add" varname="SCORE">1</setvar>
+			</respcondition>
+			<respcondition continue="Yes">
+				<conditionvar><varequal respident="DE">B</varequal></conditionvar>
+				<setvar action="Add" varname="SCORE">1</setvar>
+			</respcondition>
+		</resprocessing>
+	</item>
+</questestinterop>`)
+
+	expectedContent := []string{
+		`<p>Match each country to its capital.</p>`,
+		`<matchInteraction responseIdentifier="RESPONSE" shuffle="true" maxAssociations="2">`,
+		`<simpleAssociableChoice identifier="FR" matchMax="1">France</simpleAssociableChoice>`,
+		`<simpleAssociableChoice identifier="P" matchMax="0">Paris</simpleAssociableChoice>`,
+		`<responseDeclaration identifier="RESPONSE" cardinality="multiple" baseType="directedPair">`,
+		`<value>FR P</value>`,
+		`<value>DE B</value>`,
+		`<mapEntry mapKey="DE B" mappedValue="1"></mapEntry>`,
+		`<responseProcessing template="` + models.TemplateMapResponse21 + `">`,
+	}
+	for _, expected := range expectedContent {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain '%s', got:\n%s", expected, output)
+		}
+	}
+
+	if strings.Contains(output, "choiceInteraction") || strings.Contains(output, `identifier="FR" cardinality`) {
+		t.Errorf("Expected premises to be combined into one interaction, got:\n%s", output)
+	}
+}
+
+func TestMigrator12to21_Migrate_SeparateChoicesAreNotMatching(t *testing.T) {
+	output := migrateItem12(t, `<questestinterop>
+	<item ident="q001" title="Two questions">
+		<presentation>
+			<response_lid ident="Q1" rcardinality="Single">
+				<material><mattext>First?</mattext></material>
+				<render_choice>
+					<response_label ident="A"><material><mattext>Red</mattext></material></response_label>
+				</render_choice>
+			</response_lid>
+			<response_lid ident="Q2" rcardinality="Single">
+				<material><mattext>Second?</mattext></material>
+				<render_choice>
+					<response_label ident="A"><material><mattext>Blue</mattext></material></response_label>
+				</render_choice>
+			</response_lid>
+		</presentation>
+	</item>
+</questestinterop>`)
+
+	if strings.Count(output, "<choiceInteraction") != 2 || strings.Contains(output, "matchInteraction") {
+		t.Errorf("Expected two choice interactions, got:\n%s", output)
+	}
+}
+
+func TestMigrator12to21_Migrate_ResponseGrpMatch(t *testing.T) {
+	output := migrateItem12(t, `<questestinterop>
+	<item ident="q001" title="Sounds">
+		<presentation>
+			<response_grp ident="RESPONSE" rcardinality="Multiple">
+				<material><mattext>Match the animals to their sounds.</mattext></material>
+				<render_choice>
+					<flow_label class="Block">
+						<response_label ident="S1"><material><mattext>Cat</mattext></material></response_label>
+						<response_label ident="S2" match_max="2"><material><mattext>Dog</mattext></material></response_label>
+					</flow_label>
+					<flow_label class="Block">
+						<response_label ident="T1"><material><mattext>Meow</mattext></material></response_label>
+						<response_label ident="T2"><material><mattext>Woof</mattext></material></response_label>
+					</flow_label>
+				</render_choice>
+			</response_grp>
+		</presentation>
+		<resprocessing>
+			<outcomes><decvar varname="SCORE" vartype="Decimal" defaultval="0"/></outcomes>
+			<respcondition>
+				<conditionvar>
+					<varequal respident="RESPONSE">S1,T1</varequal>
+					<varequal respident="RESPONSE">S2 T2</varequal>
+				</conditionvar>
+				<setvar action="Set" varname="SCORE">1</setvar>
+			</respcondition>
+		</resprocessing>
+	</item>
+</questestinterop>`)
+
+	expectedContent := []string{
+		`<matchInteraction responseIdentifier="RESPONSE" maxAssociations="2">`,
+		`<prompt>Match the animals to their sounds.</prompt>`,
+		`<simpleAssociableChoice identifier="S1" matchMax="1">Cat</simpleAssociableChoice>`,
+		`<simpleAssociableChoice identifier="S2" matchMax="2">Dog</simpleAssociableChoice>`,
+		`<simpleAssociableChoice identifier="T2" matchMax="0">Woof</simpleAssociableChoice>`,
+		`<responseDeclaration identifier="RESPONSE" cardinality="multiple" baseType="directedPair">`,
+		`<value>S1 T1</value>`,
+		`<baseValue baseType="directedPair">S1 T1</baseValue>`,
+	}
+	for _, expected := range expectedContent {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain '%s', got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestMigrator12to21_Migrate_ResponseGrpAssociate(t *testing.T) {
+	output := migrateItem12(t, `<questestinterop>
+	<item ident="q001" title="Pairs">
+		<presentation>
+			<response_grp ident="RESPONSE">
+				<render_choice maxnumber="2">
+					<response_label ident="A" match_max="1"><material><mattext>Salt</mattext></material></response_label>
+					<response_label ident="B" match_max="1"><material><mattext>Pepper</mattext></material></response_label>
+				</render_choice>
+			</response_grp>
+		</presentation>
+	</item>
+</questestinterop>`)
+
+	expectedContent := []string{
+		`<associateInteraction responseIdentifier="RESPONSE" maxAssociations="2">`,
+		`<simpleAssociableChoice identifier="A" matchMax="1">Salt</simpleAssociableChoice>`,
+		`<responseDeclaration identifier="RESPONSE" cardinality="multiple" baseType="pair">`,
+	}
+	for _, expected := range expectedContent {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain '%s', got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestMigrator12to21_Migrate_OrderedResponse(t *testing.T) {
+	output := migrateItem12(t, `<questestinterop>
+	<item ident="q001" title="Order">
+		<presentation>
+			<response_lid ident="RESPONSE" rcardinality="Ordered">
+				<material><mattext>Order the planets by distance from the sun.</mattext></material>
+				<render_choice shuffle="yes">
+					<response_label ident="EARTH"><material><mattext>Earth</mattext></material></response_label>
+					<response_label ident="MERCURY"><material><mattext>Mercury</mattext></material></response_label>
+					<response_label ident="VENUS"><material><mattext>Venus</mattext></material></response_label>
+				</render_choice>
+			</response_lid>
+		</presentation>
+		<resprocessing>
+			<outcomes><decvar varname="SCORE" vartype="Decimal" defaultval="0"/></outcomes>
+			<respcondition>
+				<conditionvar>
+					<and>
+						<varequal respident="RESPONSE" index="3">EARTH</varequal>
+						<varequal respident="RESPONSE" index="1">MERCURY</varequal>
+						<varequal respident="RESPONSE" index="2">VENUS</varequal>
+					</and>
+				</conditionvar>
+				<setvar action="Set" varname="SCORE">1</setvar>
+			</respcondition>
+		</resprocessing>
+	</item>
+</questestinterop>`)
+
+	expectedContent := []string{
+		`<orderInteraction responseIdentifier="RESPONSE" shuffle="true">`,
+		`<prompt>Order the planets by distance from the sun.</prompt>`,
+		`<simpleChoice identifier="EARTH">Earth</simpleChoice>`,
+		`<responseDeclaration identifier="RESPONSE" cardinality="ordered" baseType="identifier">`,
+		"<value>MERCURY</value>\n      <value>VENUS</value>\n      <value>EARTH</value>",
+		`<index n="3">`,
+	}
+	for _, expected := range expectedContent {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain '%s', got:\n%s", expected, output)
+		}
+	}
+}
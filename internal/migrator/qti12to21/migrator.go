@@ -3,32 +3,43 @@ package qti12to21
 import (
 	"encoding/xml"
 	"fmt"
+	"math"
+	"mime"
+	"path"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/qti-migrator/internal/xhtml"
 	"github.com/qti-migrator/pkg/models"
+	"github.com/qti-migrator/pkg/registry"
 )
 
-type Migrator12to21 struct{}
+type Migrator12to21 struct {
+	// Options of the running migration
+	options registry.MigrationOptions
+}
 
 func New() *Migrator12to21 {
 	return &Migrator12to21{}
 }
 
-func (m *Migrator12to21) Migrate(doc interface{}) ([]byte, error) {
-	qtiDoc, ok := doc.(*models.QTIDocument)
-	if !ok {
-		return nil, fmt.Errorf("invalid document type for QTI 1.2 to 2.1 migration")
+func init() {
+	err := registry.RegisterStep(registry.Step{
+		From:    "1.2",
+		To:      "2.1",
+		New:     func() registry.Migrator { return New() },
+		Analyze: Analyze,
+	})
+	if err == nil {
+		err = registry.RegisterSerializer(registry.SerializerInfo{
+			Version: "2.1",
+			New:     func() registry.Serializer { return NewSerializer() },
+		})
 	}
-
-	migratedDoc := m.migrateDocument(qtiDoc)
-
-	output, err := xml.MarshalIndent(migratedDoc, "", "  ")
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal migrated document: %w", err)
+		panic(err)
 	}
-
-	xmlHeader := []byte(xml.Header)
-	return append(xmlHeader, output...), nil
 }
 
 func (m *Migrator12to21) migrateDocument(doc *models.QTIDocument) *models.QTIDocument {
@@ -90,10 +101,17 @@ func (m *Migrator12to21) migrateSection(section *models.Section) *models.Section
 		migratedSection.Items = append(migratedSection.Items, *migratedItem)
 	}
 
+	for _, subsection := range section.Sections {
+		migratedSection.Sections = append(migratedSection.Sections, *m.migrateSection(&subsection))
+	}
+	migratedSection.Order = section.Order
+
 	return migratedSection
 }
 
 func (m *Migrator12to21) migrateItem(item *models.Item) *models.Item {
+	item = normalizeMatching(item)
+
 	migratedItem := &models.Item{
 		XMLName:     item.XMLName,
 		Title:       item.Title,
@@ -113,10 +131,15 @@ func (m *Migrator12to21) migrateItem(item *models.Item) *models.Item {
 
 	if item.ResponseProc != nil {
 		migratedItem.OutcomeDecl = m.extractOutcomeDeclarations(item.ResponseProc)
+		migratedItem.ResponseProcessing, migratedItem.OutcomeDecl = m.convertResponseProcessing(item.ResponseProc, migratedItem.ResponseDecl, migratedItem.OutcomeDecl)
 	}
 
 	for _, feedback := range item.Feedback {
-		migratedItem.Feedback = append(migratedItem.Feedback, m.migrateFeedback(&feedback))
+		migratedItem.ModalFeedback = append(migratedItem.ModalFeedback, m.migrateFeedback(&feedback))
+	}
+
+	if len(migratedItem.ModalFeedback) > 0 && !hasOutcome(migratedItem.OutcomeDecl, feedbackOutcome) {
+		migratedItem.OutcomeDecl = append(migratedItem.OutcomeDecl, feedbackOutcomeDeclaration("single"))
 	}
 
 	return migratedItem
@@ -137,65 +160,178 @@ func (m *Migrator12to21) convertPresentationToItemBody(presentation *models.Pres
 		XMLName: xml.Name{Local: "itemBody"},
 	}
 
-	if presentation.Material != nil {
-		itemBody.P = m.convertMaterialToParagraphs(presentation.Material)
+	m.convertFlowContent(presentation.Children(), itemBody)
+
+	return itemBody
+}
+
+func (m *Migrator12to21) processFlow(flow *models.Flow, itemBody *models.ItemBody) {
+	m.convertFlowContent(flow.Children(), itemBody)
+}
+
+// convertFlowContent adds the children of a presentation or flow to the
+// item body in document order. Material next to single-line text entries
+// is joined into one paragraph, so the blanks of a fill-in-the-blank
+// sentence stay inside the text.
+func (m *Migrator12to21) convertFlowContent(children []models.FlowContent12, itemBody *models.ItemBody) {
+	var run []models.FlowContent12
+
+	for _, child := range children {
+		switch {
+		case child.Material != nil || (child.Response != nil && isTextEntry(child.Response)):
+			run = append(run, child)
+		case child.Response != nil:
+			m.convertInlineRun(run, itemBody)
+			run = nil
+			m.convertResponseToInteraction(child.Response, itemBody)
+		case child.Flow != nil:
+			m.convertInlineRun(run, itemBody)
+			run = nil
+			m.processFlow(child.Flow, itemBody)
+		}
 	}
 
-	for _, response := range presentation.Response {
-		if response.RenderChoice != nil {
-			choiceInteraction := m.convertResponseToChoiceInteraction(&response)
-			itemBody.ChoiceInteraction = append(itemBody.ChoiceInteraction, *choiceInteraction)
-		} else if response.RenderFib != nil {
-			if response.RenderFib.Rows > 1 {
-				extTextInteraction := m.convertResponseToExtendedTextInteraction(&response)
-				itemBody.ExtendedTextInteraction = append(itemBody.ExtendedTextInteraction, *extTextInteraction)
-			} else {
-				textEntryInteraction := m.convertResponseToTextEntryInteraction(&response)
-				itemBody.TextEntryInteraction = append(itemBody.TextEntryInteraction, *textEntryInteraction)
+	m.convertInlineRun(run, itemBody)
+}
+
+// convertInlineRun adds a run of material and text entries. Without a text
+// entry every text and image gets its own paragraph; with one the run is a
+// single paragraph, or a div when its HTML has block content.
+func (m *Migrator12to21) convertInlineRun(run []models.FlowContent12, itemBody *models.ItemBody) {
+	hasTextEntry := false
+	for _, child := range run {
+		hasTextEntry = hasTextEntry || child.Response != nil
+	}
+
+	if !hasTextEntry {
+		for _, child := range run {
+			for _, paragraph := range m.convertMaterialToParagraphs(child.Material) {
+				itemBody.Append(paragraph)
 			}
 		}
+		return
 	}
 
-	for _, flow := range presentation.Flow {
-		m.processFlow(&flow, itemBody)
+	var content strings.Builder
+	for _, child := range run {
+		if child.Material != nil {
+			content.WriteString(m.extractMaterialContent(child.Material))
+			continue
+		}
+
+		textEntry, err := xml.Marshal(m.convertResponseToTextEntryInteraction(child.Response))
+		if err != nil {
+			textEntry = []byte(fmt.Sprintf(`<textEntryInteraction responseIdentifier="%s"/>`, child.Response.Ident))
+		}
+		content.Write(textEntry)
 	}
 
-	return itemBody
+	if xhtml.HasBlocks(content.String()) {
+		itemBody.Append(models.Div{
+			XMLName: xml.Name{Local: "div"},
+			Content: content.String(),
+		})
+		return
+	}
+	itemBody.Append(models.P{
+		XMLName: xml.Name{Local: "p"},
+		Content: content.String(),
+	})
 }
 
-func (m *Migrator12to21) processFlow(flow *models.Flow, itemBody *models.ItemBody) {
-	for _, material := range flow.Material {
-		paragraphs := m.convertMaterialToParagraphs(&material)
-		itemBody.P = append(itemBody.P, paragraphs...)
-	}
-
-	for _, response := range flow.Response {
-		if response.RenderChoice != nil {
-			choiceInteraction := m.convertResponseToChoiceInteraction(&response)
-			itemBody.ChoiceInteraction = append(itemBody.ChoiceInteraction, *choiceInteraction)
-		} else if response.RenderFib != nil {
-			if response.RenderFib.Rows > 1 {
-				extTextInteraction := m.convertResponseToExtendedTextInteraction(&response)
-				itemBody.ExtendedTextInteraction = append(itemBody.ExtendedTextInteraction, *extTextInteraction)
-			} else {
-				textEntryInteraction := m.convertResponseToTextEntryInteraction(&response)
-				itemBody.TextEntryInteraction = append(itemBody.TextEntryInteraction, *textEntryInteraction)
-			}
+// isTextEntry tells whether a response becomes an inline textEntryInteraction.
+func isTextEntry(response *models.Response) bool {
+	return response.RenderExtension == nil && response.RenderSlider == nil &&
+		response.Kind() != models.ResponseGrp && response.Kind() != models.ResponseXY &&
+		response.RenderChoice == nil && response.RenderFib != nil && response.RenderFib.Rows <= 1
+}
+
+// convertResponseToInteraction adds the 2.1 interaction for a response of
+// any QTI 1.2 response type to the item body.
+func (m *Migrator12to21) convertResponseToInteraction(response *models.Response, itemBody *models.ItemBody) {
+	switch {
+	case response.RenderExtension != nil:
+		itemBody.Append(m.convertResponseToCustomInteraction(response))
+	case response.RenderSlider != nil:
+		itemBody.Append(m.convertResponseToSliderInteraction(response))
+	case response.Kind() == models.ResponseGrp:
+		if _, _, ok := response.MatchSets(); ok {
+			itemBody.Append(m.convertResponseToMatchInteraction(response))
+		} else {
+			itemBody.Append(m.convertResponseToAssociateInteraction(response))
 		}
+	case response.Kind() == models.ResponseXY:
+		itemBody.Append(m.convertResponseToSelectPointInteraction(response))
+	case response.RenderChoice != nil && response.IsOrdered():
+		itemBody.Append(m.convertResponseToOrderInteraction(response))
+	case response.RenderChoice != nil:
+		itemBody.Append(*m.convertResponseToChoiceInteraction(response))
+	case response.RenderFib != nil:
+		if response.RenderFib.Rows > 1 {
+			itemBody.Append(*m.convertResponseToExtendedTextInteraction(response))
+		} else {
+			itemBody.Append(*m.convertResponseToTextEntryInteraction(response))
+		}
+	case response.RenderHotspot != nil:
+		itemBody.Append(m.convertResponseToHotspotInteraction(response))
 	}
+}
 
-	for _, subFlow := range flow.Flow {
-		m.processFlow(&subFlow, itemBody)
+// checkStrict fails on the responses a strict migration cannot convert:
+// render_extensions, which are only kept inside a customInteraction, and
+// responses without a rendering, which are dropped.
+func checkStrict(doc *models.QTIDocument) error {
+	items := make([]*models.Item, 0, len(doc.Items))
+	for i := range doc.Items {
+		items = append(items, &doc.Items[i])
 	}
+	var sections []*models.Section
+	if doc.Assessment != nil {
+		for i := range doc.Assessment.Sections {
+			sections = append(sections, &doc.Assessment.Sections[i])
+		}
+	}
+	for len(sections) > 0 {
+		section := sections[0]
+		sections = sections[1:]
+		for i := range section.Items {
+			items = append(items, &section.Items[i])
+		}
+		for i := range section.Sections {
+			sections = append(sections, &section.Sections[i])
+		}
+	}
+
+	for _, item := range items {
+		if item.Presentation == nil {
+			continue
+		}
+		for _, response := range item.Presentation.Responses() {
+			switch {
+			case response.RenderExtension != nil:
+				return fmt.Errorf("item %s: render_extension of %s %s has no QTI 2.1 equivalent", item.Ident, response.Kind(), response.Ident)
+			case response.RenderSlider == nil && response.Kind() != models.ResponseGrp && response.Kind() != models.ResponseXY &&
+				response.RenderChoice == nil && response.RenderFib == nil && response.RenderHotspot == nil:
+				return fmt.Errorf("item %s: %s %s has no supported rendering", item.Ident, response.Kind(), response.Ident)
+			}
+		}
+	}
+	return nil
 }
 
-func (m *Migrator12to21) convertMaterialToParagraphs(material *models.Material) []models.P {
-	var paragraphs []models.P
+// convertMaterialToParagraphs turns each text and image of a material into a
+// paragraph, or a div for HTML that has block content of its own.
+func (m *Migrator12to21) convertMaterialToParagraphs(material *models.Material) []interface{} {
+	var paragraphs []interface{}
 
 	for _, matText := range material.MatText {
-		content := matText.Content
-		if matText.TextType == "text/html" {
-			content = m.sanitizeHTMLContent(content)
+		content := m.materialText(matText)
+		if isHTMLText(matText) && xhtml.HasBlocks(content) {
+			paragraphs = append(paragraphs, models.Div{
+				XMLName: xml.Name{Local: "div"},
+				Content: content,
+			})
+			continue
 		}
 		paragraphs = append(paragraphs, models.P{
 			XMLName: xml.Name{Local: "p"},
@@ -204,18 +340,9 @@ func (m *Migrator12to21) convertMaterialToParagraphs(material *models.Material)
 	}
 
 	for _, matImage := range material.MatImage {
-		imgTag := fmt.Sprintf(`<img src="%s"`, matImage.URI)
-		if matImage.Width > 0 {
-			imgTag += fmt.Sprintf(` width="%d"`, matImage.Width)
-		}
-		if matImage.Height > 0 {
-			imgTag += fmt.Sprintf(` height="%d"`, matImage.Height)
-		}
-		imgTag += " />"
-		
 		paragraphs = append(paragraphs, models.P{
 			XMLName: xml.Name{Local: "p"},
-			Content: imgTag,
+			Content: imageTag(matImage),
 		})
 	}
 
@@ -229,16 +356,21 @@ func (m *Migrator12to21) convertResponseToChoiceInteraction(response *models.Res
 	}
 
 	if response.RenderChoice != nil {
-		choiceInteraction.Shuffle = response.RenderChoice.Shuffle == "yes"
+		choiceInteraction.Shuffle = strings.EqualFold(response.RenderChoice.Shuffle, "yes")
 		
+		// maxChoices defaults to 1 in QTI 2.1, so a multiple response with
+		// no maxnumber gives 0 to leave the choices unlimited
 		if response.RenderChoice.MaxNumber > 0 {
 			choiceInteraction.MaxChoices = response.RenderChoice.MaxNumber
+			choiceInteraction.MaxChoicesSet = true
+		} else if strings.EqualFold(response.RCardinality, "multiple") {
+			choiceInteraction.MaxChoicesSet = true
 		}
 		if response.RenderChoice.MInNumber > 0 {
 			choiceInteraction.MinChoices = response.RenderChoice.MInNumber
 		}
 
-		for _, label := range response.RenderChoice.ResponseLabel {
+		for _, label := range response.RenderChoice.Labels() {
 			simpleChoice := models.SimpleChoice{
 				XMLName:    xml.Name{Local: "simpleChoice"},
 				Identifier: label.Ident,
@@ -289,27 +421,352 @@ func (m *Migrator12to21) convertResponseToExtendedTextInteraction(response *mode
 	return extText
 }
 
+// convertResponseToOrderInteraction turns an ordered response_lid into an
+// orderInteraction; the candidate orders all of its labels.
+func (m *Migrator12to21) convertResponseToOrderInteraction(response *models.Response) models.OrderInteraction {
+	interaction := models.OrderInteraction{
+		XMLName:       xml.Name{Local: "orderInteraction"},
+		ResponseIdent: response.Ident,
+		Shuffle:       strings.EqualFold(response.RenderChoice.Shuffle, "yes"),
+		MaxChoices:    response.RenderChoice.MaxNumber,
+		MinChoices:    response.RenderChoice.MInNumber,
+	}
+
+	if response.Material != nil {
+		interaction.Prompt = &models.Prompt{
+			XMLName: xml.Name{Local: "prompt"},
+			Content: m.extractMaterialContent(response.Material),
+		}
+	}
+
+	for _, label := range response.RenderChoice.Labels() {
+		choice := models.SimpleChoice{
+			XMLName:    xml.Name{Local: "simpleChoice"},
+			Identifier: label.Ident,
+		}
+		if label.Material != nil {
+			choice.Content = m.extractMaterialContent(label.Material)
+		}
+		interaction.SimpleChoice = append(interaction.SimpleChoice, choice)
+	}
+
+	return interaction
+}
+
+// convertResponseToMatchInteraction turns a response_grp whose labels form a
+// source and a target set into a matchInteraction. Sources are matched once
+// unless match_max says otherwise; targets may be reused.
+func (m *Migrator12to21) convertResponseToMatchInteraction(response *models.Response) models.MatchInteraction {
+	sources, targets, _ := response.MatchSets()
+	interaction := models.MatchInteraction{
+		XMLName:         xml.Name{Local: "matchInteraction"},
+		ResponseIdent:   response.Ident,
+		Shuffle:         strings.EqualFold(response.RenderChoice.Shuffle, "yes"),
+		MaxAssociations: response.RenderChoice.MaxNumber,
+		MinAssociations: response.RenderChoice.MInNumber,
+	}
+	if interaction.MaxAssociations == 0 {
+		interaction.MaxAssociations = len(sources)
+	}
+
+	if response.Material != nil {
+		interaction.Prompt = &models.Prompt{
+			XMLName: xml.Name{Local: "prompt"},
+			Content: m.extractMaterialContent(response.Material),
+		}
+	}
+
+	sourceSet := models.SimpleMatchSet{XMLName: xml.Name{Local: "simpleMatchSet"}}
+	for _, label := range sources {
+		choice := m.convertLabelToAssociableChoice(label)
+		if label.MatchMax == 0 {
+			choice.MatchMax = 1
+		}
+		sourceSet.SimpleAssociableChoice = append(sourceSet.SimpleAssociableChoice, choice)
+	}
+	targetSet := models.SimpleMatchSet{XMLName: xml.Name{Local: "simpleMatchSet"}}
+	for _, label := range targets {
+		targetSet.SimpleAssociableChoice = append(targetSet.SimpleAssociableChoice, m.convertLabelToAssociableChoice(label))
+	}
+	interaction.SimpleMatchSet = []models.SimpleMatchSet{sourceSet, targetSet}
+
+	return interaction
+}
+
+// convertResponseToAssociateInteraction turns a response_grp into an
+// associateInteraction; every label becomes an associable choice.
+func (m *Migrator12to21) convertResponseToAssociateInteraction(response *models.Response) models.AssociateInteraction {
+	interaction := models.AssociateInteraction{
+		XMLName:       xml.Name{Local: "associateInteraction"},
+		ResponseIdent: response.Ident,
+	}
+
+	if response.Material != nil {
+		interaction.Prompt = &models.Prompt{
+			XMLName: xml.Name{Local: "prompt"},
+			Content: m.extractMaterialContent(response.Material),
+		}
+	}
+
+	if response.RenderChoice != nil {
+		interaction.Shuffle = strings.EqualFold(response.RenderChoice.Shuffle, "yes")
+		interaction.MaxAssociations = response.RenderChoice.MaxNumber
+		interaction.MinAssociations = response.RenderChoice.MInNumber
+
+		for _, label := range response.RenderChoice.Labels() {
+			interaction.SimpleAssociableChoice = append(interaction.SimpleAssociableChoice, m.convertLabelToAssociableChoice(label))
+		}
+	}
+
+	return interaction
+}
+
+func (m *Migrator12to21) convertLabelToAssociableChoice(label models.ResponseLabel) models.SimpleAssociableChoice {
+	choice := models.SimpleAssociableChoice{
+		XMLName:    xml.Name{Local: "simpleAssociableChoice"},
+		Identifier: label.Ident,
+		MatchMax:   label.MatchMax,
+	}
+	if label.Material != nil {
+		choice.Content = m.extractMaterialContent(label.Material)
+	}
+	return choice
+}
+
+// convertResponseToSelectPointInteraction turns a response_xy into a
+// selectPointInteraction on the image of its render_hotspot.
+func (m *Migrator12to21) convertResponseToSelectPointInteraction(response *models.Response) models.RawElement21 {
+	maxChoices := 1
+	if strings.EqualFold(response.RCardinality, "multiple") {
+		maxChoices = 0
+	}
+	if response.RenderHotspot != nil && response.RenderHotspot.MaxNumber > 0 {
+		maxChoices = response.RenderHotspot.MaxNumber
+	}
+
+	var content strings.Builder
+	if response.Material != nil && len(response.Material.MatText) > 0 {
+		content.WriteString("<prompt>" + m.extractMaterialContent(&models.Material{MatText: response.Material.MatText}) + "</prompt>")
+	}
+	if image := m.hotspotImage(response); image != nil {
+		content.WriteString(m.convertImageToObject(image))
+	}
+
+	return models.RawElement21{
+		XMLName: xml.Name{Local: "selectPointInteraction"},
+		Attrs: []xml.Attr{
+			rawAttr("responseIdentifier", response.Ident),
+			rawAttr("maxChoices", fmt.Sprintf("%d", maxChoices)),
+		},
+		Content: content.String(),
+	}
+}
+
+// convertResponseToHotspotInteraction turns a response_lid rendered as
+// hotspots into a hotspotInteraction; every labelled area becomes a
+// hotspotChoice.
+func (m *Migrator12to21) convertResponseToHotspotInteraction(response *models.Response) models.RawElement21 {
+	hotspot := response.RenderHotspot
+	maxChoices := hotspot.MaxNumber
+	if maxChoices == 0 && !strings.EqualFold(response.RCardinality, "multiple") {
+		maxChoices = 1
+	}
+
+	var content strings.Builder
+	if response.Material != nil && len(response.Material.MatText) > 0 {
+		content.WriteString("<prompt>" + m.extractMaterialContent(&models.Material{MatText: response.Material.MatText}) + "</prompt>")
+	}
+	if image := m.hotspotImage(response); image != nil {
+		content.WriteString(m.convertImageToObject(image))
+	}
+
+	for _, label := range hotspot.ResponseLabel {
+		shape, coords := convertArea(label.RArea, label.Content)
+		content.WriteString(fmt.Sprintf(`<hotspotChoice identifier="%s" shape="%s" coords="%s"/>`, label.Ident, shape, coords))
+	}
+
+	attrs := []xml.Attr{
+		rawAttr("responseIdentifier", response.Ident),
+		rawAttr("maxChoices", fmt.Sprintf("%d", maxChoices)),
+	}
+	if hotspot.MinNumber > 0 {
+		attrs = append(attrs, rawAttr("minChoices", fmt.Sprintf("%d", hotspot.MinNumber)))
+	}
+
+	return models.RawElement21{
+		XMLName: xml.Name{Local: "hotspotInteraction"},
+		Attrs:   attrs,
+		Content: content.String(),
+	}
+}
+
+// convertResponseToSliderInteraction turns a render_slider into a
+// sliderInteraction with the same bounds, step and orientation.
+func (m *Migrator12to21) convertResponseToSliderInteraction(response *models.Response) models.RawElement21 {
+	slider := response.RenderSlider
+	attrs := []xml.Attr{
+		rawAttr("responseIdentifier", response.Ident),
+		rawAttr("lowerBound", slider.LowerBound),
+		rawAttr("upperBound", slider.UpperBound),
+	}
+	if slider.Step != "" {
+		attrs = append(attrs, rawAttr("step", slider.Step))
+	}
+	if slider.StepLabel == "yes" {
+		attrs = append(attrs, rawAttr("stepLabel", "true"))
+	}
+	if strings.EqualFold(slider.Orientation, "vertical") {
+		attrs = append(attrs, rawAttr("orientation", "vertical"))
+	}
+
+	content := ""
+	if response.Material != nil {
+		content = "<prompt>" + m.extractMaterialContent(response.Material) + "</prompt>"
+	}
+
+	return models.RawElement21{
+		XMLName: xml.Name{Local: "sliderInteraction"},
+		Attrs:   attrs,
+		Content: content,
+	}
+}
+
+// convertResponseToCustomInteraction keeps a proprietary render_extension
+// verbatim inside a customInteraction, the 2.1 extension point for
+// interactions the specification does not define.
+func (m *Migrator12to21) convertResponseToCustomInteraction(response *models.Response) models.RawElement21 {
+	content, err := xml.Marshal(response.RenderExtension)
+	if err != nil {
+		content = []byte("<render_extension>" + response.RenderExtension.Content + "</render_extension>")
+	}
+
+	return models.RawElement21{
+		XMLName: xml.Name{Local: "customInteraction"},
+		Attrs:   []xml.Attr{rawAttr("responseIdentifier", response.Ident)},
+		Content: string(content),
+	}
+}
+
+// hotspotImage returns the background image of a hotspot response.
+func (m *Migrator12to21) hotspotImage(response *models.Response) *models.MatImage {
+	if response.RenderHotspot != nil && response.RenderHotspot.Material != nil && len(response.RenderHotspot.Material.MatImage) > 0 {
+		return &response.RenderHotspot.Material.MatImage[0]
+	}
+	if response.Material != nil && len(response.Material.MatImage) > 0 {
+		return &response.Material.MatImage[0]
+	}
+	return nil
+}
+
+// convertArea converts a QTI 1.2 area to a QTI 2.1 shape and coords.
+// QTI 1.2 gives rectangles as left, top, width, height and ellipses as
+// centre, width, height, while QTI 2.1 uses left, top, right, bottom and
+// centre, radii. Ellipses with equal radii become circles.
+func convertArea(area, coords string) (string, string) {
+	values := parseCoords(coords)
+	switch strings.ToLower(area) {
+	case "rectangle":
+		if len(values) == 4 {
+			return "rect", formatCoords(values[0], values[1], values[0]+values[2], values[1]+values[3])
+		}
+		return "rect", strings.TrimSpace(coords)
+	case "ellipse":
+		if len(values) == 4 {
+			if values[2] == values[3] {
+				return "circle", formatCoords(values[0], values[1], values[2]/2)
+			}
+			return "ellipse", formatCoords(values[0], values[1], values[2]/2, values[3]/2)
+		}
+		return "ellipse", strings.TrimSpace(coords)
+	case "bounded":
+		return "poly", strings.TrimSpace(coords)
+	default:
+		return "default", strings.TrimSpace(coords)
+	}
+}
+
+func parseCoords(coords string) []float64 {
+	var values []float64
+	for _, field := range strings.FieldsFunc(coords, func(r rune) bool { return r == ',' || r == ' ' }) {
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+func formatCoords(values ...float64) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return strings.Join(parts, ",")
+}
+
+// convertImageToObject renders a matimage as the object element graphic
+// interactions use for their background image.
+func (m *Migrator12to21) convertImageToObject(image *models.MatImage) string {
+	imageType := image.ImageType
+	if imageType == "" {
+		imageType = mime.TypeByExtension(path.Ext(image.URI))
+	}
+	if imageType == "" {
+		imageType = "image/jpeg"
+	}
+
+	var object strings.Builder
+	object.WriteString("<object")
+	writeAttr(&object, "type", imageType)
+	writeAttr(&object, "data", image.URI)
+	writeSize(&object, image)
+	object.WriteString("></object>")
+	return object.String()
+}
+
+// imageTag renders a matimage as an img element. Its label is the alt text,
+// which is empty for an image without one.
+func imageTag(image models.MatImage) string {
+	var tag strings.Builder
+	tag.WriteString("<img")
+	writeAttr(&tag, "src", image.URI)
+	writeAttr(&tag, "alt", image.Label)
+	writeSize(&tag, &image)
+	tag.WriteString(" />")
+	return tag.String()
+}
+
+// writeSize writes the width and height a matimage gives.
+func writeSize(tag *strings.Builder, image *models.MatImage) {
+	if image.Width > 0 {
+		writeAttr(tag, "width", strconv.Itoa(image.Width))
+	}
+	if image.Height > 0 {
+		writeAttr(tag, "height", strconv.Itoa(image.Height))
+	}
+}
+
+// writeAttr writes an attribute with its value escaped.
+func writeAttr(tag *strings.Builder, name, value string) {
+	tag.WriteString(" " + name + `="`)
+	xml.EscapeText(tag, []byte(value))
+	tag.WriteString(`"`)
+}
+
+func rawAttr(name, value string) xml.Attr {
+	return xml.Attr{Name: xml.Name{Local: name}, Value: value}
+}
+
 func (m *Migrator12to21) extractMaterialContent(material *models.Material) string {
 	var content strings.Builder
 
 	for _, matText := range material.MatText {
-		text := matText.Content
-		if matText.TextType == "text/html" {
-			text = m.sanitizeHTMLContent(text)
-		}
-		content.WriteString(text)
+		content.WriteString(m.materialText(matText))
 	}
 
 	for _, matImage := range material.MatImage {
-		imgTag := fmt.Sprintf(`<img src="%s"`, matImage.URI)
-		if matImage.Width > 0 {
-			imgTag += fmt.Sprintf(` width="%d"`, matImage.Width)
-		}
-		if matImage.Height > 0 {
-			imgTag += fmt.Sprintf(` height="%d"`, matImage.Height)
-		}
-		imgTag += " />"
-		content.WriteString(imgTag)
+		content.WriteString(imageTag(matImage))
 	}
 
 	return content.String()
@@ -318,7 +775,7 @@ func (m *Migrator12to21) extractMaterialContent(material *models.Material) strin
 func (m *Migrator12to21) extractResponseDeclarations(presentation *models.Presentation, responseProc *models.ResponseProc) []models.ResponseDecl {
 	var responseDecls []models.ResponseDecl
 
-	for _, response := range presentation.Response {
+	for _, response := range presentation.Responses() {
 		responseDecl := models.ResponseDecl{
 			XMLName:     xml.Name{Local: "responseDeclaration"},
 			Identifier:  response.Ident,
@@ -331,6 +788,16 @@ func (m *Migrator12to21) extractResponseDeclarations(presentation *models.Presen
 			if correctResponse != nil {
 				responseDecl.CorrectResponse = correctResponse
 			}
+
+			if response.Kind() == models.ResponseXY {
+				responseDecl.AreaMapping = m.extractAreaMapping(response.Ident, responseProc)
+			} else {
+				responseDecl.Mapping = m.extractMapping(responseDecl, responseProc)
+			}
+
+			if responseDecl.CorrectResponse == nil && responseDecl.Mapping != nil {
+				responseDecl.CorrectResponse = m.correctResponseFromMapping(responseDecl)
+			}
 		}
 
 		responseDecls = append(responseDecls, responseDecl)
@@ -341,7 +808,7 @@ func (m *Migrator12to21) extractResponseDeclarations(presentation *models.Presen
 
 func (m *Migrator12to21) determineCardinality(response *models.Response) string {
 	if response.RCardinality != "" {
-		switch response.RCardinality {
+		switch strings.ToLower(response.RCardinality) {
 		case "single":
 			return "single"
 		case "multiple":
@@ -351,6 +818,10 @@ func (m *Migrator12to21) determineCardinality(response *models.Response) string
 		}
 	}
 
+	if response.Kind() == models.ResponseGrp {
+		return "multiple"
+	}
+
 	if response.RenderChoice != nil && response.RenderChoice.MaxNumber > 1 {
 		return "multiple"
 	}
@@ -359,7 +830,25 @@ func (m *Migrator12to21) determineCardinality(response *models.Response) string
 }
 
 func (m *Migrator12to21) determineBaseType(response *models.Response) string {
-	if response.RenderChoice != nil {
+	switch response.Kind() {
+	case models.ResponseGrp:
+		if _, _, ok := response.MatchSets(); ok {
+			return "directedPair"
+		}
+		return "pair"
+	case models.ResponseXY:
+		return "point"
+	case models.ResponseNum:
+		if response.RenderChoice == nil {
+			return m.determineNumericBaseType(response)
+		}
+	}
+
+	if response.RenderSlider != nil {
+		return m.determineNumericBaseType(response)
+	}
+
+	if response.RenderChoice != nil || response.RenderHotspot != nil {
 		return "identifier"
 	} else if response.RenderFib != nil {
 		if response.RenderFib.FibType == "integer" {
@@ -372,32 +861,185 @@ func (m *Migrator12to21) determineBaseType(response *models.Response) string {
 	return "string"
 }
 
-func (m *Migrator12to21) extractCorrectResponse(responseIdent string, responseProc *models.ResponseProc) *models.CorrectResponse {
+// extractAreaMapping builds an areaMapping from the varinside conditions
+// that score a response_xy; each area is worth the value its condition
+// sets or adds.
+func (m *Migrator12to21) extractAreaMapping(responseIdent string, responseProc *models.ResponseProc) *models.AreaMapping {
+	var entries []models.AreaMapEntry
+
+	for _, condition := range responseProc.ResCondition {
+		if condition.ConditionVar == nil {
+			continue
+		}
+		for _, varInside := range condition.ConditionVar.VarInside {
+			if varInside.RespIdent != responseIdent {
+				continue
+			}
+			mappedValue := 0.0
+			for _, setVar := range condition.SetVar {
+				if action := setVarAction(setVar); action == "set" || action == "add" {
+					if value, err := strconv.ParseFloat(strings.TrimSpace(setVar.Value), 64); err == nil {
+						mappedValue = value
+					}
+				}
+			}
+			shape, coords := convertArea(varInside.AreaType, varInside.Value)
+			entries = append(entries, models.AreaMapEntry{
+				XMLName:     xml.Name{Local: "areaMapEntry"},
+				Shape:       shape,
+				Coords:      coords,
+				MappedValue: mappedValue,
+			})
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil
+	}
+
+	return &models.AreaMapping{
+		XMLName:      xml.Name{Local: "areaMapping"},
+		AreaMapEntry: entries,
+	}
+}
+
+// extractMapping builds a partial-credit mapping from the conditions that
+// add or subtract SCORE points for single values of a response. The bounds
+// are the lowest and highest scores the entries can produce, narrowed by the
+// minvalue and maxvalue of the SCORE decvar.
+func (m *Migrator12to21) extractMapping(decl models.ResponseDecl, responseProc *models.ResponseProc) *models.Mapping {
+	var conditions []models.ResCondition
+	for _, condition := range responseProc.ResCondition {
+		if refersTo(condition, decl.Identifier) {
+			conditions = append(conditions, condition)
+		}
+	}
+
+	entries, ok := additiveEntries(conditions, decl)
+	if !ok {
+		return nil
+	}
+
+	mapping := &models.Mapping{
+		XMLName: xml.Name{Local: "mapping"},
+	}
+	lowerBound, upperBound := 0.0, 0.0
+	for _, condition := range conditions {
+		key, _ := singleVarEqual(condition, decl.Identifier)
+		value := entries[key]
+		mapping.MapEntry = append(mapping.MapEntry, models.MapEntry{
+			XMLName:     xml.Name{Local: "mapEntry"},
+			MapKey:      key,
+			MappedValue: value,
+		})
+
+		if decl.Cardinality == "single" {
+			lowerBound = math.Min(lowerBound, value)
+			upperBound = math.Max(upperBound, value)
+		} else if value < 0 {
+			lowerBound += value
+		} else {
+			upperBound += value
+		}
+	}
+
+	if decVar := scoreDecVar(responseProc); decVar != nil {
+		if minValue, err := strconv.ParseFloat(strings.TrimSpace(decVar.MinValue), 64); err == nil && minValue > lowerBound {
+			lowerBound = minValue
+		}
+		if maxValue, err := strconv.ParseFloat(strings.TrimSpace(decVar.MaxValue), 64); err == nil && maxValue < upperBound {
+			upperBound = maxValue
+		}
+	}
+	mapping.LowerBound = &lowerBound
+	mapping.UpperBound = &upperBound
+
+	return mapping
+}
+
+// correctResponseFromMapping treats the values worth points as correct: all
+// of them for a multiple response, the highest scoring one otherwise.
+func (m *Migrator12to21) correctResponseFromMapping(decl models.ResponseDecl) *models.CorrectResponse {
 	var correctValues []string
+	best := 0.0
+	for _, entry := range decl.Mapping.MapEntry {
+		if entry.MappedValue <= 0 {
+			continue
+		}
+		if decl.Cardinality != "single" {
+			correctValues = append(correctValues, entry.MapKey)
+		} else if entry.MappedValue > best {
+			correctValues = []string{entry.MapKey}
+			best = entry.MappedValue
+		}
+	}
+
+	if len(correctValues) == 0 {
+		return nil
+	}
+	return &models.CorrectResponse{
+		XMLName: xml.Name{Local: "correctResponse"},
+		Value:   models.NewValues(correctValues...),
+	}
+}
+
+// determineNumericBaseType maps the numtype of a response_num, which
+// defaults to Integer, falling back to the fibtype of its render_fib.
+func (m *Migrator12to21) determineNumericBaseType(response *models.Response) string {
+	switch strings.ToLower(response.NumType) {
+	case "integer":
+		return "integer"
+	case "decimal", "scientific":
+		return "float"
+	}
+
+	if response.RenderFib != nil && response.RenderFib.FibType == "decimal" {
+		return "float"
+	}
+	return "integer"
+}
+
+// extractCorrectResponse collects the values whose conditions, alone or
+// joined by and, set the score to 1. Values of an ordered response are
+// sorted by their varequal index.
+func (m *Migrator12to21) extractCorrectResponse(responseIdent string, responseProc *models.ResponseProc) *models.CorrectResponse {
+	var correct []models.VarEqual
 
 	for _, condition := range responseProc.ResCondition {
 		if condition.ConditionVar != nil {
-			for _, varEqual := range condition.ConditionVar.VarEqual {
+			varEquals := condition.ConditionVar.VarEqual
+			if condition.ConditionVar.And != nil {
+				varEquals = append(varEquals[:len(varEquals):len(varEquals)], condition.ConditionVar.And.VarEqual...)
+			}
+			for _, varEqual := range varEquals {
 				if varEqual.RespIdent == responseIdent {
 					isCorrect := false
 					for _, setVar := range condition.SetVar {
-						if setVar.Action == "set" && setVar.Value == "1" {
+						if setVarAction(setVar) == "set" && strings.TrimSpace(setVar.Value) == "1" {
 							isCorrect = true
 							break
 						}
 					}
 					if isCorrect {
-						correctValues = append(correctValues, varEqual.Value)
+						correct = append(correct, varEqual)
 					}
 				}
 			}
 		}
 	}
 
+	sort.SliceStable(correct, func(i, j int) bool {
+		return correct[i].Index < correct[j].Index
+	})
+	var correctValues []string
+	for _, varEqual := range correct {
+		correctValues = append(correctValues, varEqual.Value)
+	}
+
 	if len(correctValues) > 0 {
 		return &models.CorrectResponse{
 			XMLName: xml.Name{Local: "correctResponse"},
-			Value:   correctValues,
+			Value:   models.NewValues(correctValues...),
 		}
 	}
 
@@ -411,7 +1053,7 @@ func (m *Migrator12to21) extractOutcomeDeclarations(responseProc *models.Respons
 		for _, decVar := range responseProc.Outcomes.DecVar {
 			outcomeDecl := models.OutcomeDecl{
 				XMLName:     xml.Name{Local: "outcomeDeclaration"},
-				Identifier:  decVar.VarName,
+				Identifier:  decVarName(decVar),
 				Cardinality: "single",
 				BaseType:    m.convertVarType(decVar.VarType),
 			}
@@ -419,32 +1061,47 @@ func (m *Migrator12to21) extractOutcomeDeclarations(responseProc *models.Respons
 			if decVar.DefaultVal != "" {
 				outcomeDecl.DefaultValue = &models.DefaultValue{
 					XMLName: xml.Name{Local: "defaultValue"},
-					Value:   decVar.DefaultVal,
+					Value:   models.NewValues(decVar.DefaultVal),
 				}
 			}
 
+			// The range of the variable is what QTI 2.1 normalizes it by
+			outcomeDecl.NormalMinimum = parseBound(decVar.MinValue)
+			outcomeDecl.NormalMaximum = parseBound(decVar.MaxValue)
+
 			outcomeDecls = append(outcomeDecls, outcomeDecl)
 		}
 	}
 
 	if len(outcomeDecls) == 0 {
-		outcomeDecls = append(outcomeDecls, models.OutcomeDecl{
-			XMLName:     xml.Name{Local: "outcomeDeclaration"},
-			Identifier:  "SCORE",
-			Cardinality: "single",
-			BaseType:    "float",
-			DefaultValue: &models.DefaultValue{
-				XMLName: xml.Name{Local: "defaultValue"},
-				Value:   "0.0",
-			},
-		})
+		outcomeDecls = append(outcomeDecls, m.scoreOutcome())
 	}
 
 	return outcomeDecls
 }
 
+// scoreOutcome returns the outcome declared for items that declare none:
+// the one of the options, or SCORE, a float defaulting to 0.0.
+func (m *Migrator12to21) scoreOutcome() models.OutcomeDecl {
+	if m.options.ScoreOutcome != nil {
+		outcomeDecl := *m.options.ScoreOutcome
+		outcomeDecl.XMLName = xml.Name{Local: "outcomeDeclaration"}
+		return outcomeDecl
+	}
+	return models.OutcomeDecl{
+		XMLName:     xml.Name{Local: "outcomeDeclaration"},
+		Identifier:  defaultOutcome,
+		Cardinality: "single",
+		BaseType:    "float",
+		DefaultValue: &models.DefaultValue{
+			XMLName: xml.Name{Local: "defaultValue"},
+			Value:   models.NewValues("0.0"),
+		},
+	}
+}
+
 func (m *Migrator12to21) convertVarType(varType string) string {
-	switch varType {
+	switch strings.ToLower(varType) {
 	case "integer":
 		return "integer"
 	case "decimal", "scientific":
@@ -456,30 +1113,59 @@ func (m *Migrator12to21) convertVarType(varType string) string {
 	}
 }
 
-func (m *Migrator12to21) migrateFeedback(feedback *models.Feedback) models.Feedback {
-	migratedFeedback := models.Feedback{
-		XMLName: feedback.XMLName,
-		Ident:   feedback.Ident,
-		Title:   feedback.Title,
+// parseBound reads the minvalue or maxvalue of a decvar; nil when unset.
+func parseBound(value string) *float64 {
+	bound, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return nil
 	}
+	return &bound
+}
 
+// migrateFeedback turns an itemfeedback into modalFeedback that is shown
+// when the displayfeedback rules put its identifier in FEEDBACK.
+func (m *Migrator12to21) migrateFeedback(feedback *models.Feedback) models.ModalFeedback {
+	var content strings.Builder
 	if feedback.Material != nil {
-		migratedFeedback.Material = feedback.Material
+		content.WriteString(m.extractMaterialContent(feedback.Material))
+	}
+	for _, flowMat := range feedback.FlowMat {
+		if flowMat.Material != nil {
+			content.WriteString(m.extractMaterialContent(flowMat.Material))
+		}
 	}
 
-	migratedFeedback.FlowMat = append(migratedFeedback.FlowMat, feedback.FlowMat...)
-
-	return migratedFeedback
+	return models.ModalFeedback{
+		XMLName:           xml.Name{Local: "modalFeedback"},
+		Identifier:        feedback.Ident,
+		OutcomeIdentifier: feedbackOutcome,
+		ShowHide:          "show",
+		Title:             feedback.Title,
+		Content:           content.String(),
+	}
 }
 
-func (m *Migrator12to21) sanitizeHTMLContent(content string) string {
-	content = strings.ReplaceAll(content, "<br>", "<br/>")
-	content = strings.ReplaceAll(content, "<hr>", "<hr/>")
-	content = strings.ReplaceAll(content, "<img ", "<img ")
-	
-	if !strings.Contains(content, "/>") && strings.Contains(content, "<img") {
-		content = strings.ReplaceAll(content, ">", "/>")
+// materialText returns the text of a mattext as item body markup: HTML is
+// converted to XHTML and any other text is escaped.
+func (m *Migrator12to21) materialText(matText models.MatText) string {
+	if isHTMLText(matText) {
+		return m.sanitizeHTMLContent(matText.Content)
 	}
+	return textEscaper.Replace(matText.Content)
+}
 
-	return content
+var textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// isHTMLText tells whether a mattext holds HTML: text/html, or text of
+// another type that still has HTML tags or entities, as LMS exports often
+// label HTML text/plain.
+func isHTMLText(matText models.MatText) bool {
+	return matText.TextType == "text/html" || xhtml.LooksLikeHTML(matText.Content)
+}
+
+// sanitizeHTMLContent converts the HTML of a mattext to XHTML; the
+// preprocessor reports the fixes this makes.
+func (m *Migrator12to21) sanitizeHTMLContent(content string) string {
+	converted, _ := xhtml.Convert(content)
+	return converted
 }
\ No newline at end of file
@@ -1,10 +1,14 @@
 package qti12to21
 
 import (
+	"encoding/xml"
+	"io"
 	"strings"
 	"testing"
 
+	"github.com/qti-migrator/internal/parser/qti12"
 	"github.com/qti-migrator/pkg/models"
+	"github.com/qti-migrator/pkg/registry"
 )
 
 func TestMigrator12to21_New(t *testing.T) {
@@ -14,6 +18,24 @@ func TestMigrator12to21_New(t *testing.T) {
 	}
 }
 
+// migrate migrates doc and writes it as a single file.
+func migrate(doc *models.QTIDocument) ([]byte, error) {
+	files, err := migrateFiles(doc, registry.MigrationOptions{Layout: registry.LayoutSingle})
+	if err != nil {
+		return nil, err
+	}
+	return files[0].Content, nil
+}
+
+// migrateFiles migrates doc and writes its files.
+func migrateFiles(doc *models.QTIDocument, options registry.MigrationOptions) ([]models.File, error) {
+	migrated, err := New().Migrate(doc, options)
+	if err != nil {
+		return nil, err
+	}
+	return NewSerializer().Serialize(migrated, options)
+}
+
 func TestMigrator12to21_Migrate_SimpleItem(t *testing.T) {
 	doc := &models.QTIDocument{
 		Version: "1.2",
@@ -55,8 +77,7 @@ func TestMigrator12to21_Migrate_SimpleItem(t *testing.T) {
 		},
 	}
 
-	m := New()
-	result, err := m.Migrate(doc)
+	result, err := migrate(doc)
 	
 	if err != nil {
 		t.Fatalf("Migration failed: %v", err)
@@ -73,8 +94,8 @@ func TestMigrator12to21_Migrate_SimpleItem(t *testing.T) {
 		t.Error("Expected XML header")
 	}
 	
-	if !strings.Contains(resultStr, `version="2.1"`) {
-		t.Error("Expected version to be updated to 2.1")
+	if !strings.Contains(resultStr, `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="q001"`) {
+		t.Error("Expected a QTI 2.1 assessmentItem")
 	}
 	
 	if !strings.Contains(resultStr, `<itemBody>`) {
@@ -90,19 +111,6 @@ func TestMigrator12to21_Migrate_SimpleItem(t *testing.T) {
 	}
 }
 
-func TestMigrator12to21_Migrate_InvalidDocumentType(t *testing.T) {
-	m := New()
-	_, err := m.Migrate("invalid document type")
-	
-	if err == nil {
-		t.Error("Expected error for invalid document type, but got none")
-	}
-	
-	if !strings.Contains(err.Error(), "invalid document type") {
-		t.Errorf("Expected invalid document type error, got: %v", err)
-	}
-}
-
 func TestMigrator12to21_ConvertPresentationToItemBody(t *testing.T) {
 	m := New()
 	presentation := &models.Presentation{
@@ -342,8 +350,37 @@ func TestMigrator12to21_ExtractCorrectResponse(t *testing.T) {
 		t.Errorf("Expected 1 correct value, got %d", len(correctResponse.Value))
 	}
 	
-	if correctResponse.Value[0] != "A" {
-		t.Errorf("Expected correct value 'A', got '%s'", correctResponse.Value[0])
+	if correctResponse.Value[0].Content != "A" {
+		t.Errorf("Expected correct value 'A', got '%s'", correctResponse.Value[0].Content)
+	}
+}
+
+func TestMigrator12to21_ExtractOutcomeDeclarations(t *testing.T) {
+	m := New()
+	responseProc := &models.ResponseProc{
+		Outcomes: &models.Outcomes{
+			DecVar: []models.DecVar{
+				{VarName: "SCORE", VarType: "Integer", DefaultVal: "0", MinValue: "0", MaxValue: "5"},
+				{VarName: "BONUS"},
+			},
+		},
+	}
+
+	decls := m.extractOutcomeDeclarations(responseProc)
+	if len(decls) != 2 {
+		t.Fatalf("Expected 2 outcome declarations, got %d", len(decls))
+	}
+
+	score := decls[0]
+	if score.DefaultValue.First() != "0" {
+		t.Errorf("Expected default value 0, got %+v", score.DefaultValue)
+	}
+	if score.NormalMinimum == nil || *score.NormalMinimum != 0 || score.NormalMaximum == nil || *score.NormalMaximum != 5 {
+		t.Errorf("Expected normal range 0..5 from minvalue and maxvalue, got %v..%v", score.NormalMinimum, score.NormalMaximum)
+	}
+
+	if bonus := decls[1]; bonus.NormalMinimum != nil || bonus.NormalMaximum != nil || bonus.DefaultValue != nil {
+		t.Errorf("Expected no range or default without minvalue, maxvalue and defaultval, got %+v", bonus)
 	}
 }
 
@@ -398,6 +435,11 @@ func TestMigrator12to21_SanitizeHTMLContent(t *testing.T) {
 			input:    `<img src="test.jpg"/>`,
 			expected: `<img src="test.jpg"/>`,
 		},
+		{
+			name:     "Img tag among other tags",
+			input:    `<P>See <img src=test.jpg> and <b>this`,
+			expected: `<p>See <img src="test.jpg"/> and <b>this</b></p>`,
+		},
 	}
 	
 	for _, tc := range testCases {
@@ -420,6 +462,7 @@ func TestMigrator12to21_ExtractMaterialContent(t *testing.T) {
 		MatImage: []models.MatImage{
 			{URI: "image1.jpg", Width: 100, Height: 50},
 			{URI: "image2.png"},
+			{URI: "a&b.png", Label: `Say "cheese"`},
 		},
 	}
 	
@@ -433,13 +476,17 @@ func TestMigrator12to21_ExtractMaterialContent(t *testing.T) {
 		t.Error("Expected HTML content")
 	}
 	
-	if !strings.Contains(content, `<img src="image1.jpg" width="100" height="50" />`) {
+	if !strings.Contains(content, `<img src="image1.jpg" alt="" width="100" height="50" />`) {
 		t.Error("Expected first image with dimensions")
 	}
 	
-	if !strings.Contains(content, `<img src="image2.png" />`) {
+	if !strings.Contains(content, `<img src="image2.png" alt="" />`) {
 		t.Error("Expected second image without dimensions")
 	}
+	
+	if !strings.Contains(content, `<img src="a&amp;b.png" alt="Say &#34;cheese&#34;" />`) {
+		t.Errorf("Expected third image with escaped attributes, got %s", content)
+	}
 }
 
 func TestMigrator12to21_ComplexDocumentStructure(t *testing.T) {
@@ -469,31 +516,90 @@ func TestMigrator12to21_ComplexDocumentStructure(t *testing.T) {
 		},
 	}
 	
-	m := New()
-	result, err := m.Migrate(doc)
-	
+	if _, err := migrate(doc); err == nil {
+		t.Error("Expected an error when a test with its items does not fit in one file")
+	}
+
+	files, err := migrateFiles(doc, registry.MigrationOptions{})
 	if err != nil {
 		t.Fatalf("Migration failed: %v", err)
 	}
-	
-	resultStr := string(result)
-	
-	// Check that assessment structure is preserved
-	if !strings.Contains(resultStr, `ident="test001"`) {
-		t.Error("Expected assessment ident to be preserved")
+	if len(files) != 3 {
+		t.Fatalf("Expected a test and two item files, got %d files", len(files))
 	}
-	
-	if !strings.Contains(resultStr, `ident="sec001"`) {
-		t.Error("Expected section ident to be preserved")
+
+	test := files[0]
+	if test.Kind != models.FileTest || test.Href != "test001.xml" {
+		t.Errorf("Expected test file test001.xml, got %s %s", test.Kind, test.Href)
 	}
-	
-	// Check both standalone items and items in sections are processed
-	if !strings.Contains(resultStr, `ident="q001"`) {
-		t.Error("Expected section item to be preserved")
+	if files[1].Href != "items/q001.xml" || files[2].Href != "items/standalone_q001.xml" {
+		t.Errorf("Unexpected item files: %s %s", files[1].Href, files[2].Href)
 	}
-	
-	if !strings.Contains(resultStr, `ident="standalone_q001"`) {
-		t.Error("Expected standalone item to be preserved")
+	if len(test.Dependencies) != 2 {
+		t.Errorf("Expected the test to depend on both items, got %v", test.Dependencies)
+	}
+
+	// Check both standalone items and items in sections are referenced
+	for _, expected := range []string{
+		`<assessmentTest xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="test001" title="Test Assessment">`,
+		`<testPart identifier="testPart1" navigationMode="linear" submissionMode="individual">`,
+		`<assessmentSection identifier="sec001" title="Section 1" visible="true">`,
+		`<assessmentItemRef identifier="q001" href="items/q001.xml"></assessmentItemRef>`,
+		`<assessmentSection identifier="items" title="items" visible="true">`,
+		`<assessmentItemRef identifier="standalone_q001" href="items/standalone_q001.xml"></assessmentItemRef>`,
+	} {
+		if !strings.Contains(string(test.Content), expected) {
+			t.Errorf("Expected test to contain %q, got:\n%s", expected, test.Content)
+		}
+	}
+	if !strings.Contains(string(files[2].Content), `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="standalone_q001" title="Standalone Question" adaptive="false" timeDependent="false">`) {
+		t.Errorf("Expected an assessmentItem file, got:\n%s", files[2].Content)
+	}
+}
+
+func TestMigrator12to21_NestedSections(t *testing.T) {
+	content := []byte(`<questestinterop>
+	<assessment ident="bank" title="Bank">
+		<section ident="outer" title="Outer">
+			<item ident="first" title="First"/>
+			<section ident="inner" title="Inner">
+				<item ident="second" title="Second"/>
+			</section>
+			<item ident="third" title="Third"/>
+			<section title="Untitled">
+				<item ident="first" title="Duplicate"/>
+			</section>
+		</section>
+	</assessment>
+</questestinterop>`)
+
+	doc, err := qti12.New().Parse(content)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	files, err := migrateFiles(doc, registry.MigrationOptions{})
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	if len(files) != 5 {
+		t.Fatalf("Expected a test and four item files, got %d files", len(files))
+	}
+
+	expected := `<assessmentSection identifier="outer" title="Outer" visible="true">
+      <assessmentItemRef identifier="first" href="items/first.xml"></assessmentItemRef>
+      <assessmentSection identifier="inner" title="Inner" visible="true">
+        <assessmentItemRef identifier="second" href="items/second.xml"></assessmentItemRef>
+      </assessmentSection>
+      <assessmentItemRef identifier="third" href="items/third.xml"></assessmentItemRef>
+      <assessmentSection identifier="section3" title="Untitled" visible="true">
+        <assessmentItemRef identifier="first-2" href="items/first-2.xml"></assessmentItemRef>
+      </assessmentSection>
+    </assessmentSection>`
+	if !strings.Contains(string(files[0].Content), expected) {
+		t.Errorf("Expected sections in document order, got:\n%s", files[0].Content)
+	}
+	if files[4].Href != "items/first-2.xml" || !strings.Contains(string(files[4].Content), `title="Duplicate"`) {
+		t.Errorf("Expected the duplicate item in items/first-2.xml, got %s", files[4].Href)
 	}
 }
 
@@ -525,13 +631,373 @@ func BenchmarkMigrator12to21_Migrate(b *testing.B) {
 		},
 	}
 	
-	m := New()
-	
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := m.Migrate(doc)
+		_, err := migrate(doc)
 		if err != nil {
 			b.Fatalf("Migration failed: %v", err)
 		}
 	}
-}
\ No newline at end of file
+}
+func TestMigrator12to21_Migrate_ResponseTypes(t *testing.T) {
+	doc := &models.QTIDocument{
+		Version: "1.2",
+		Items: []models.Item{
+			{
+				Ident: "q001",
+				Title: "Response Types",
+				Presentation: &models.Presentation{
+					Response: []models.Response{
+						{
+							XMLName:   xml.Name{Local: "response_str"},
+							Ident:     "STR",
+							RenderFib: &models.RenderFib{MaxChars: 20},
+						},
+						{
+							XMLName:   xml.Name{Local: "response_num"},
+							Ident:     "NUM",
+							NumType:   "Decimal",
+							RenderFib: &models.RenderFib{},
+						},
+					},
+					Flow: []models.Flow{
+						{
+							Response: []models.Response{
+								{
+									XMLName: xml.Name{Local: "response_grp"},
+									Ident:   "GRP",
+									RenderChoice: &models.RenderChoice{
+										ResponseLabel: []models.ResponseLabel{
+											{Ident: "A", Material: &models.Material{MatText: []models.MatText{{Content: "Paris"}}}},
+											{Ident: "B", Material: &models.Material{MatText: []models.MatText{{Content: "France"}}}},
+										},
+									},
+								},
+								{
+									XMLName: xml.Name{Local: "response_xy"},
+									Ident:   "XY",
+									RenderHotspot: &models.RenderHotspot{
+										Material: &models.Material{MatImage: []models.MatImage{{URI: "map.png"}}},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	result, err := migrate(doc)
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+
+	output := string(result)
+	expectedContent := []string{
+		`<textEntryInteraction responseIdentifier="STR" expectedLength="20">`,
+		`<textEntryInteraction responseIdentifier="NUM">`,
+		`<associateInteraction responseIdentifier="GRP"`,
+		`<simpleAssociableChoice identifier="A" matchMax="0">Paris</simpleAssociableChoice>`,
+		`<selectPointInteraction responseIdentifier="XY" maxChoices="1">`,
+		`<object type="image/png" data="map.png"></object>`,
+		`<responseDeclaration identifier="STR" cardinality="single" baseType="string">`,
+		`<responseDeclaration identifier="NUM" cardinality="single" baseType="float">`,
+		`<responseDeclaration identifier="GRP" cardinality="multiple" baseType="pair">`,
+		`<responseDeclaration identifier="XY" cardinality="single" baseType="point">`,
+	}
+
+	for _, expected := range expectedContent {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain '%s'", expected)
+		}
+	}
+}
+
+func TestMigrator12to21_Migrate_GraphicAndSliderResponses(t *testing.T) {
+	qti12XML := `<questestinterop>
+	<item ident="q001" title="Graphics">
+		<presentation>
+			<response_lid ident="HOT" rcardinality="single">
+				<render_hotspot>
+					<material><matimage imagetype="image/png" uri="cell.png" width="400" height="300"/></material>
+					<response_label ident="NUCLEUS" rarea="Ellipse">100,100,40,40</response_label>
+					<response_label ident="WALL" rarea="Rectangle">10,20,30,40</response_label>
+				</render_hotspot>
+			</response_lid>
+			<response_xy ident="POINT" rcardinality="single">
+				<render_hotspot>
+					<material><matimage uri="map.gif"/></material>
+				</render_hotspot>
+			</response_xy>
+			<response_num ident="TEMP" numtype="Decimal">
+				<render_slider lowerbound="0" upperbound="100" step="5" steplabel="yes"/>
+			</response_num>
+			<response_lid ident="EXT">
+				<render_extension><vendor:widget xmlns:vendor="urn:vendor" kind="dial"/></render_extension>
+			</response_lid>
+		</presentation>
+		<resprocessing>
+			<respcondition>
+				<conditionvar><varequal respident="HOT">NUCLEUS</varequal></conditionvar>
+				<setvar action="Set" varname="SCORE">1</setvar>
+			</respcondition>
+			<respcondition>
+				<conditionvar><varinside respident="POINT" areatype="Rectangle">0,0,50,50</varinside></conditionvar>
+				<setvar action="Add" varname="SCORE">2</setvar>
+			</respcondition>
+		</resprocessing>
+	</item>
+</questestinterop>`
+
+	var doc12 models.QTIDocument12
+	if err := xml.Unmarshal([]byte(qti12XML), &doc12); err != nil {
+		t.Fatalf("Failed to unmarshal test document: %v", err)
+	}
+	item := doc12.Items[0]
+	doc := &models.QTIDocument{
+		Version: "1.2",
+		Items: []models.Item{{
+			Ident:        item.Ident,
+			Title:        item.Title,
+			Presentation: item.Presentation,
+			ResponseProc: item.ResponseProc,
+		}},
+	}
+
+	result, err := migrate(doc)
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+
+	output := string(result)
+	expectedContent := []string{
+		`<hotspotInteraction responseIdentifier="HOT" maxChoices="1">`,
+		`<object type="image/png" data="cell.png" width="400" height="300"></object>`,
+		`<hotspotChoice identifier="NUCLEUS" shape="circle" coords="100,100,20"/>`,
+		`<hotspotChoice identifier="WALL" shape="rect" coords="10,20,40,60"/>`,
+		`<selectPointInteraction responseIdentifier="POINT" maxChoices="1">`,
+		`<object type="image/gif" data="map.gif"></object>`,
+		`<areaMapEntry shape="rect" coords="0,0,50,50" mappedValue="2">`,
+		`<sliderInteraction responseIdentifier="TEMP" lowerBound="0" upperBound="100" step="5" stepLabel="true">`,
+		`<customInteraction responseIdentifier="EXT">`,
+		`kind="dial"`,
+		`<responseDeclaration identifier="HOT" cardinality="single" baseType="identifier">`,
+		`<responseDeclaration identifier="TEMP" cardinality="single" baseType="float">`,
+	}
+
+	for _, expected := range expectedContent {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain '%s'", expected)
+		}
+	}
+}
+
+func TestConvertArea(t *testing.T) {
+	testCases := []struct {
+		area, coords  string
+		shape, result string
+	}{
+		{"Rectangle", "10,20,30,40", "rect", "10,20,40,60"},
+		{"Ellipse", "50,50,20,20", "circle", "50,50,10"},
+		{"Ellipse", "50,50,40,20", "ellipse", "50,50,20,10"},
+		{"Bounded", "0,0,10,0,5,5", "poly", "0,0,10,0,5,5"},
+	}
+
+	for _, tc := range testCases {
+		shape, coords := convertArea(tc.area, tc.coords)
+		if shape != tc.shape || coords != tc.result {
+			t.Errorf("convertArea(%s, %s): expected (%s, %s), got (%s, %s)", tc.area, tc.coords, tc.shape, tc.result, shape, coords)
+		}
+	}
+}
+
+func TestMigrator12to21_Migrate_ItemBodyOrder(t *testing.T) {
+	qti12XML := `<questestinterop>
+	<item ident="q001" title="Blanks">
+		<presentation>
+			<material><mattext>Answer both parts.</mattext></material>
+			<response_lid ident="CHOICE">
+				<render_choice>
+					<response_label ident="A"><material><mattext>Yes</mattext></material></response_label>
+				</render_choice>
+			</response_lid>
+			<flow>
+				<material><mattext>The capital of France is </mattext></material>
+				<response_str ident="BLANK"><render_fib fibtype="String" maxchars="10"/></response_str>
+				<material><mattext>.</mattext></material>
+			</flow>
+			<material><mattext>Good luck.</mattext></material>
+		</presentation>
+	</item>
+</questestinterop>`
+
+	var doc12 models.QTIDocument12
+	if err := xml.Unmarshal([]byte(qti12XML), &doc12); err != nil {
+		t.Fatalf("Failed to unmarshal test document: %v", err)
+	}
+	doc := &models.QTIDocument{
+		Version: "1.2",
+		Items: []models.Item{{
+			Ident:        doc12.Items[0].Ident,
+			Title:        doc12.Items[0].Title,
+			Presentation: doc12.Items[0].Presentation,
+		}},
+	}
+
+	result, err := migrate(doc)
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+
+	output := string(result)
+	expectedOrder := []string{
+		`<p>Answer both parts.</p>`,
+		`<choiceInteraction responseIdentifier="CHOICE">`,
+		`<p>The capital of France is <textEntryInteraction responseIdentifier="BLANK" expectedLength="10"></textEntryInteraction>.</p>`,
+		`<p>Good luck.</p>`,
+	}
+
+	last := -1
+	for _, expected := range expectedOrder {
+		index := strings.Index(output, expected)
+		if index < 0 {
+			t.Errorf("Expected output to contain '%s', got:\n%s", expected, output)
+			continue
+		}
+		if index < last {
+			t.Errorf("Expected '%s' to follow the previous content", expected)
+		}
+		last = index
+	}
+}
+
+func TestMigrator12to21_Migrate_HTMLMaterial(t *testing.T) {
+	output := migrateItem12(t, `<questestinterop>
+	<item ident="q001" title="HTML">
+		<presentation>
+			<material><mattext texttype="text/html"><![CDATA[<P>Which city&nbsp;<img src=paris.png> is the capital?<ul><li>France<li>Spain</ul>]]></mattext></material>
+			<material><mattext texttype="text/html"><![CDATA[Answer <b>below</b>]]></mattext></material>
+		</presentation>
+	</item>
+</questestinterop>`)
+
+	expectedContent := []string{
+		`<div><p>Which city&#160;<img src="paris.png"/> is the capital?</p><ul><li>France</li><li>Spain</li></ul></div>`,
+		`<p>Answer <b>below</b></p>`,
+	}
+	for _, expected := range expectedContent {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain '%s', got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestMigrator12to21_Migrate_TextEntryNextToBlocks(t *testing.T) {
+	output := migrateItem12(t, `<questestinterop>
+	<item ident="q001" title="Blocks">
+		<presentation>
+			<material><mattext texttype="text/html"><![CDATA[<p>Read the list.</p><ul><li>Paris</li></ul>Capital:]]></mattext></material>
+			<response_str ident="BLANK"><render_fib rows="1"/></response_str>
+		</presentation>
+	</item>
+</questestinterop>`)
+
+	expected := `<div><p>Read the list.</p><ul><li>Paris</li></ul>Capital:<textEntryInteraction responseIdentifier="BLANK"></textEntryInteraction></div>`
+	if !strings.Contains(output, expected) {
+		t.Errorf("Expected output to contain '%s', got:\n%s", expected, output)
+	}
+	if strings.Contains(output, "<p><p>") {
+		t.Errorf("Expected no paragraph around block content, got:\n%s", output)
+	}
+}
+
+func TestMigrator12to21_Migrate_PlainMaterial(t *testing.T) {
+	output := migrateItem12(t, `<questestinterop>
+	<item ident="q001" title="Plain">
+		<presentation>
+			<material><mattext><![CDATA[Price:&nbsp;5]]></mattext></material>
+			<material><mattext texttype="text/plain"><![CDATA[1 < 2 & 3]]></mattext></material>
+		</presentation>
+	</item>
+</questestinterop>`)
+
+	expectedContent := []string{
+		`<p>Price:&#160;5</p>`,
+		`<p>1 &lt; 2 &amp; 3</p>`,
+	}
+	for _, expected := range expectedContent {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain '%s', got:\n%s", expected, output)
+		}
+	}
+
+	decoder := xml.NewDecoder(strings.NewReader(output))
+	for {
+		if _, err := decoder.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Expected well-formed output, got %v:\n%s", err, output)
+		}
+	}
+}
+
+func TestMigrator12to21_Migrate_MultipleChoice(t *testing.T) {
+	output := migrateItem12(t, `<questestinterop>
+	<item ident="q001" title="Multiple">
+		<presentation>
+			<response_lid ident="RESPONSE" rcardinality="Multiple">
+				<render_choice shuffle="Yes">
+					<response_label ident="A"><material><mattext>Red</mattext></material></response_label>
+					<response_label ident="B"><material><mattext>Blue</mattext></material></response_label>
+				</render_choice>
+			</response_lid>
+		</presentation>
+	</item>
+</questestinterop>`)
+
+	expected := `<choiceInteraction responseIdentifier="RESPONSE" shuffle="true" maxChoices="0">`
+	if !strings.Contains(output, expected) {
+		t.Errorf("Expected output to contain '%s', got:\n%s", expected, output)
+	}
+}
+
+func TestMigrate_Options(t *testing.T) {
+	doc, err := qti12.New().Parse([]byte(`<questestinterop>
+	<item ident="q001">
+		<presentation>
+			<response_lid ident="RESPONSE"><render_extension/></response_lid>
+		</presentation>
+		<resprocessing>
+			<respcondition><conditionvar><other/></conditionvar></respcondition>
+		</resprocessing>
+	</item>
+</questestinterop>`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if _, err := migrateFiles(doc, registry.MigrationOptions{Strict: true}); err == nil || !strings.Contains(err.Error(), "render_extension") {
+		t.Errorf("Expected a strict migration to fail on the render_extension, got %v", err)
+	}
+	if _, err := migrateFiles(doc, registry.MigrationOptions{Profile: "3.0"}); err == nil {
+		t.Error("Expected QTI 2.1 output to refuse a 3.0 profile")
+	}
+
+	files, err := migrateFiles(doc, registry.MigrationOptions{
+		ScoreOutcome: &models.OutcomeDecl{Identifier: "POINTS", Cardinality: "single", BaseType: "integer"},
+		Profile:      "2.2",
+	})
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	output := string(files[0].Content)
+	for _, expected := range []string{
+		`<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p2" identifier="q001"`,
+		`<outcomeDeclaration identifier="POINTS" cardinality="single" baseType="integer"></outcomeDeclaration>`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %s in:\n%s", expected, output)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package qti12to21
+
+import (
+	"encoding/xml"
+	"strconv"
+
+	"github.com/qti-migrator/internal/testfiles"
+	"github.com/qti-migrator/pkg/models"
+	"github.com/qti-migrator/pkg/registry"
+)
+
+// Migrate migrates a QTI 1.2 document to the model the QTI 2.1 parser reads,
+// which Serializer21 writes and a following migration step starts from. The
+// model has the shape of the files it is written as: a single item, or a
+// test with one test part whose sections hold their items inline instead of
+// item refs.
+func (m *Migrator12to21) Migrate(doc *models.QTIDocument, options registry.MigrationOptions) (*models.QTIDocument, error) {
+	m.options = options
+	if options.Strict {
+		if err := checkStrict(doc); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(doc.Items) == 0 && doc.Assessment == nil {
+		return m.migrateDocument(doc), nil
+	}
+
+	migrated := &models.QTIDocument{
+		XMLName: xml.Name{Local: "questestinterop"},
+		Version: "2.1",
+	}
+	w := &modelWriter{migrator: m}
+	if len(doc.Items) == 1 && doc.Assessment == nil {
+		migrated.Items = []models.Item{w.item(&doc.Items[0], false)}
+	} else {
+		migrated.Assessment = w.test(doc)
+	}
+	if err := options.Identifiers.Apply(migrated); err != nil {
+		return nil, err
+	}
+	return migrated, nil
+}
+
+// modelWriter builds the QTI 2.1 model of a test the way testWriter writes
+// its files.
+type modelWriter struct {
+	migrator *Migrator12to21
+	// Sections written so far, for naming sections without an identifier
+	sections int
+}
+
+func (w *modelWriter) test(doc *models.QTIDocument) *models.Assessment {
+	test := &models.Assessment{XMLName: xml.Name{Local: "assessment"}, Ident: "test"}
+	part := models.Section{
+		XMLName:        xml.Name{Local: "section"},
+		Ident:          testfiles.DefaultTestPart,
+		NavigationMode: testfiles.DefaultNavigationMode,
+		SubmissionMode: testfiles.DefaultSubmissionMode,
+	}
+
+	if assessment := doc.Assessment; assessment != nil {
+		if assessment.Ident != "" {
+			test.Ident = assessment.Ident
+		}
+		test.Title = assessment.Title
+		for i := range assessment.Sections {
+			part.Sections = append(part.Sections, w.section(&assessment.Sections[i]))
+		}
+	}
+	if test.Title == "" {
+		test.Title = test.Ident
+	}
+
+	if assessment := doc.Assessment; assessment != nil {
+		controls := controlsOf("", assessment.MaxAttempts, assessment.Duration, assessment.SelectionOrdering)
+		part.ItemSessionControl = controls.itemSessionControl
+		test.TimeLimits = controls.timeLimits
+		if controls.selection != nil || controls.ordering != nil {
+			part.Sections = []models.Section{{
+				XMLName:   xml.Name{Local: "section"},
+				Title:     test.Title,
+				Ident:     test.Ident + "-sections",
+				Sections:  part.Sections,
+				Visible:   visible(false),
+				Selection: controls.selection,
+				Ordering:  controls.ordering,
+			}}
+		}
+	}
+
+	if len(doc.Items) > 0 {
+		identifier := "items"
+		if doc.Assessment == nil {
+			identifier = "section1"
+		}
+		section := models.Section{
+			XMLName: xml.Name{Local: "section"},
+			Title:   identifier,
+			Ident:   identifier,
+			Visible: visible(true),
+		}
+		for i := range doc.Items {
+			section.Items = append(section.Items, w.item(&doc.Items[i], true))
+		}
+		part.Sections = append(part.Sections, section)
+	}
+
+	test.Sections = []models.Section{part}
+	return test
+}
+
+func (w *modelWriter) section(section *models.Section) models.Section {
+	w.sections++
+	migrated := models.Section{
+		XMLName: xml.Name{Local: "section"},
+		Title:   section.Title,
+		Ident:   section.Ident,
+		Order:   section.Order,
+		Visible: visible(true),
+	}
+	if migrated.Ident == "" {
+		migrated.Ident = "section" + strconv.Itoa(w.sections)
+	}
+	if migrated.Title == "" {
+		migrated.Title = migrated.Ident
+	}
+	controls := controlsOf("", section.MaxAttempts, section.Duration, section.SelectionOrdering)
+	migrated.ItemSessionControl = controls.itemSessionControl
+	migrated.TimeLimits = controls.timeLimits
+	migrated.Selection = controls.selection
+	migrated.Ordering = controls.ordering
+
+	for _, child := range section.Children() {
+		switch c := child.(type) {
+		case *models.Item:
+			migrated.Items = append(migrated.Items, w.item(c, true))
+		case *models.Section:
+			migrated.Sections = append(migrated.Sections, w.section(c))
+		}
+	}
+	return migrated
+}
+
+// item migrates an item and reads its modal feedback back into Feedback, as
+// the QTI 2.1 parser does. Items of a test carry the controls of their item
+// ref.
+func (w *modelWriter) item(item *models.Item, inTest bool) models.Item {
+	migrated := *w.migrator.migrateItem(item)
+	for _, feedback := range migrated.ModalFeedback {
+		migrated.Feedback = append(migrated.Feedback, models.Feedback{
+			XMLName:           xml.Name{Local: "itemfeedback"},
+			Ident:             feedback.Identifier,
+			Title:             feedback.Title,
+			OutcomeIdentifier: feedback.OutcomeIdentifier,
+			ShowHide:          feedback.ShowHide,
+			Material:          &models.Material{MatText: []models.MatText{{Content: feedback.Content}}},
+		})
+	}
+	migrated.ModalFeedback = nil
+
+	if inTest {
+		controls := controlsOf("", item.MaxAttempts, item.Duration, nil)
+		migrated.ItemSessionControl = controls.itemSessionControl
+		migrated.TimeLimits = controls.timeLimits
+	}
+	return migrated
+}
+
+func visible(shown bool) *bool {
+	return &shown
+}
@@ -0,0 +1,58 @@
+package qti12to21
+
+import (
+	"testing"
+
+	"github.com/qti-migrator/internal/parser/qti12"
+	"github.com/qti-migrator/pkg/registry"
+)
+
+func TestMigrate_Model(t *testing.T) {
+	doc, err := qti12.New().Parse([]byte(`<questestinterop>
+	<assessment ident="quiz" maxattempts="2">
+		<selection_ordering><order order_type="Random"/></selection_ordering>
+		<section>
+			<item ident="q001" maxattempts="1">
+				<itemfeedback ident="fb"><material><mattext>Well done</mattext></material></itemfeedback>
+			</item>
+			<section ident="inner"/>
+		</section>
+	</assessment>
+	<item ident="q002"/>
+</questestinterop>`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	migrated, err := New().Migrate(doc, registry.MigrationOptions{})
+	if err != nil {
+		t.Fatalf("MigrateModel failed: %v", err)
+	}
+	test := migrated.Assessment
+	if migrated.Version != "2.1" || test == nil || test.Ident != "quiz" || test.Title != "quiz" || len(test.Sections) != 1 {
+		t.Fatalf("Expected test quiz with a single test part, got %+v", migrated)
+	}
+
+	part := test.Sections[0]
+	if part.Ident != "testPart1" || part.NavigationMode != "linear" || part.ItemSessionControl == nil || *part.ItemSessionControl.MaxAttempts != 2 {
+		t.Errorf("Unexpected test part: %+v", part)
+	}
+	if len(part.Sections) != 2 || part.Sections[0].Ident != "quiz-sections" || *part.Sections[0].Visible || part.Sections[0].Ordering == nil {
+		t.Fatalf("Expected the hidden shuffled wrapper and the items section, got %+v", part.Sections)
+	}
+	if items := part.Sections[1]; items.Ident != "items" || len(items.Items) != 1 || items.Items[0].Ident != "q002" {
+		t.Errorf("Expected q002 in section items, got %+v", items)
+	}
+
+	section := part.Sections[0].Sections[0]
+	if section.Ident != "section1" || !*section.Visible || len(section.Items) != 1 || len(section.Sections) != 1 {
+		t.Fatalf("Unexpected section: %+v", section)
+	}
+	item := section.Items[0]
+	if item.ItemSessionControl == nil || *item.ItemSessionControl.MaxAttempts != 1 {
+		t.Errorf("Expected item ref controls on q001, got %+v", item.ItemSessionControl)
+	}
+	if len(item.ModalFeedback) != 0 || len(item.Feedback) != 1 || item.Feedback[0].Material.MatText[0].Content != "Well done" {
+		t.Errorf("Expected modal feedback read back into Feedback, got %+v", item.Feedback)
+	}
+}
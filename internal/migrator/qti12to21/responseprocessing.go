@@ -0,0 +1,497 @@
+package qti12to21
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+
+	"github.com/qti-migrator/pkg/models"
+)
+
+// defaultOutcome is the variable a QTI 1.2 setvar changes when it names none.
+const defaultOutcome = "SCORE"
+
+// feedbackOutcome holds the identifiers of the feedback to show; modal
+// feedback is bound to it.
+const feedbackOutcome = "FEEDBACK"
+
+// responseProcessingConverter translates QTI 1.2 resprocessing into QTI 2.1
+// responseProcessing. Standard cases use the match_correct or map_response
+// templates; everything else becomes explicit responseCondition rules.
+type responseProcessingConverter struct {
+	responses map[string]models.ResponseDecl
+	outcomes  map[string]models.OutcomeDecl
+}
+
+// hasDisplayFeedback tells whether any condition shows feedback.
+func hasDisplayFeedback(responseProc *models.ResponseProc) bool {
+	for _, condition := range responseProc.ResCondition {
+		if len(condition.DisplayFeedback) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// feedbackCardinality returns "multiple" when more than one feedback can be
+// shown at once: a condition shows several, or a condition that continues
+// is followed by another one that shows feedback.
+func feedbackCardinality(responseProc *models.ResponseProc) string {
+	continued := false
+	for _, condition := range responseProc.ResCondition {
+		if len(condition.DisplayFeedback) > 1 || (continued && len(condition.DisplayFeedback) > 0) {
+			return "multiple"
+		}
+		if len(condition.DisplayFeedback) > 0 && isContinue(condition) {
+			continued = true
+		}
+	}
+	return "single"
+}
+
+func newResponseProcessingConverter(responseDecls []models.ResponseDecl, outcomeDecls []models.OutcomeDecl) *responseProcessingConverter {
+	c := &responseProcessingConverter{
+		responses: make(map[string]models.ResponseDecl),
+		outcomes:  make(map[string]models.OutcomeDecl),
+	}
+	for _, decl := range responseDecls {
+		c.responses[decl.Identifier] = decl
+	}
+	for _, decl := range outcomeDecls {
+		c.outcomes[decl.Identifier] = decl
+	}
+	return c
+}
+
+// convertResponseProcessing returns the 2.1 response processing for an item
+// and its outcome declarations, extended by outcomes that setvar changes
+// without a matching decvar.
+func (m *Migrator12to21) convertResponseProcessing(responseProc *models.ResponseProc, responseDecls []models.ResponseDecl, outcomeDecls []models.OutcomeDecl) (*models.ResponseProcessing, []models.OutcomeDecl) {
+	if len(responseProc.ResCondition) == 0 {
+		return nil, outcomeDecls
+	}
+
+	for _, condition := range responseProc.ResCondition {
+		for _, setVar := range condition.SetVar {
+			name := setVarName(setVar)
+			if !hasOutcome(outcomeDecls, name) {
+				outcomeDecls = append(outcomeDecls, models.OutcomeDecl{
+					XMLName:     xml.Name{Local: "outcomeDeclaration"},
+					Identifier:  name,
+					Cardinality: "single",
+					BaseType:    "float",
+				})
+			}
+		}
+	}
+
+	showsFeedback := hasDisplayFeedback(responseProc)
+	if showsFeedback && !hasOutcome(outcomeDecls, feedbackOutcome) {
+		outcomeDecls = append(outcomeDecls, feedbackOutcomeDeclaration(feedbackCardinality(responseProc)))
+	}
+
+	c := newResponseProcessingConverter(responseDecls, outcomeDecls)
+	responseProcessing := &models.ResponseProcessing{
+		XMLName: xml.Name{Local: "responseProcessing"},
+	}
+
+	// The templates only set SCORE, so shown feedback needs explicit rules
+	if template := c.template(responseProc); template != "" && !showsFeedback {
+		responseProcessing.Template = template
+		return responseProcessing, outcomeDecls
+	}
+
+	responseProcessing.Rules = c.convertConditions(responseProc.ResCondition)
+	return responseProcessing, outcomeDecls
+}
+
+// template returns the URI of the standard template that scores exactly like
+// the 1.2 conditions, or "" when explicit rules are needed. The templates
+// are bound to the RESPONSE and SCORE identifiers.
+func (c *responseProcessingConverter) template(responseProc *models.ResponseProc) string {
+	decl, ok := c.responses["RESPONSE"]
+	if !ok || len(c.responses) != 1 {
+		return ""
+	}
+	score, ok := c.outcomes[defaultOutcome]
+	if !ok || score.BaseType != "float" || !isZero(defaultValue(score)) {
+		return ""
+	}
+
+	if c.isMapResponse(responseProc, decl) {
+		return models.TemplateMapResponse21
+	}
+	if c.isMatchCorrect(responseProc, decl) {
+		return models.TemplateMatchCorrect21
+	}
+	return ""
+}
+
+// isMatchCorrect recognises a single condition that sets SCORE to 1 for the
+// correct identifier, optionally followed by conditions that set it to 0.
+func (c *responseProcessingConverter) isMatchCorrect(responseProc *models.ResponseProc, decl models.ResponseDecl) bool {
+	if decl.Cardinality != "single" || decl.BaseType != "identifier" ||
+		decl.CorrectResponse == nil || len(decl.CorrectResponse.Value) != 1 {
+		return false
+	}
+
+	found := false
+	for _, condition := range responseProc.ResCondition {
+		if !onlySetsScore(condition) {
+			return false
+		}
+
+		if found {
+			for _, setVar := range condition.SetVar {
+				if setVarAction(setVar) != "set" || !isZero(setVar.Value) {
+					return false
+				}
+			}
+			continue
+		}
+
+		value, ok := singleVarEqual(condition, decl.Identifier)
+		if !ok || value != decl.CorrectResponse.Value[0].Content || isContinue(condition) ||
+			len(condition.SetVar) != 1 || setVarAction(condition.SetVar[0]) != "set" || !isOne(condition.SetVar[0].Value) {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// isMapResponse recognises conditions that each add points to SCORE for one
+// response value, and whose values agree with the declaration's mapping.
+func (c *responseProcessingConverter) isMapResponse(responseProc *models.ResponseProc, decl models.ResponseDecl) bool {
+	if decl.Mapping == nil || decl.Mapping.DefaultValue != 0 {
+		return false
+	}
+
+	entries, ok := additiveEntries(responseProc.ResCondition, decl)
+	if !ok || len(entries) != len(decl.Mapping.MapEntry) {
+		return false
+	}
+	for _, entry := range decl.Mapping.MapEntry {
+		value, found := entries[entry.MapKey]
+		if !found || value != entry.MappedValue {
+			return false
+		}
+	}
+	return true
+}
+
+// additiveEntries returns the points each response value adds to SCORE when
+// every condition is a single varequal on the response followed by a single
+// setvar Add or Subtract. Conditions must continue unless only one value can
+// match.
+func additiveEntries(conditions []models.ResCondition, decl models.ResponseDecl) (map[string]float64, bool) {
+	entries := make(map[string]float64)
+	for _, condition := range conditions {
+		if !onlySetsScore(condition) || len(condition.SetVar) != 1 {
+			return nil, false
+		}
+		sign := 1.0
+		switch setVarAction(condition.SetVar[0]) {
+		case "add":
+		case "subtract":
+			sign = -1
+		default:
+			return nil, false
+		}
+		if decl.Cardinality != "single" && !isContinue(condition) {
+			return nil, false
+		}
+		value, ok := singleVarEqual(condition, decl.Identifier)
+		if !ok {
+			return nil, false
+		}
+		if _, duplicate := entries[value]; duplicate {
+			return nil, false
+		}
+		points, err := strconv.ParseFloat(strings.TrimSpace(condition.SetVar[0].Value), 64)
+		if err != nil {
+			return nil, false
+		}
+		entries[value] = sign * points
+	}
+	return entries, len(entries) > 0
+}
+
+// convertConditions turns respconditions into responseCondition rules. A
+// true condition stops 1.2 processing unless it has continue="Yes", so the
+// conditions after it are nested in its responseElse.
+func (c *responseProcessingConverter) convertConditions(conditions []models.ResCondition) []models.XMLNode {
+	var rules []models.XMLNode
+	for i, condition := range conditions {
+		responseIf := models.NewXMLNode("responseIf", nil, c.conditionExpression(condition.ConditionVar))
+		responseIf.Children = append(responseIf.Children, c.convertActions(condition)...)
+		rule := models.NewXMLNode("responseCondition", nil, responseIf)
+
+		if !isContinue(condition) && i < len(conditions)-1 {
+			rule.Children = append(rule.Children, models.NewXMLNode("responseElse", nil, c.convertConditions(conditions[i+1:])...))
+			return append(rules, rule)
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+func (c *responseProcessingConverter) convertActions(condition models.ResCondition) []models.XMLNode {
+	var actions []models.XMLNode
+	for _, setVar := range condition.SetVar {
+		name := setVarName(setVar)
+		value := c.outcomeValue(name, setVar.Value)
+		variable := models.NewXMLNode("variable", []string{"identifier", name})
+
+		var expression models.XMLNode
+		switch setVarAction(setVar) {
+		case "add":
+			expression = models.NewXMLNode("sum", nil, variable, value)
+		case "subtract":
+			expression = models.NewXMLNode("subtract", nil, variable, value)
+		case "multiply":
+			expression = models.NewXMLNode("product", nil, variable, value)
+		case "divide":
+			expression = models.NewXMLNode("divide", nil, variable, value)
+		default:
+			expression = value
+		}
+		actions = append(actions, models.NewXMLNode("setOutcomeValue", []string{"identifier", name}, expression))
+	}
+
+	for _, displayFeedback := range condition.DisplayFeedback {
+		value := baseValue("identifier", displayFeedback.LinkRefId)
+		if c.outcomes[feedbackOutcome].Cardinality == "multiple" {
+			value = models.NewXMLNode("multiple", nil, models.NewXMLNode("variable", []string{"identifier", feedbackOutcome}), value)
+		}
+		actions = append(actions, models.NewXMLNode("setOutcomeValue", []string{"identifier", feedbackOutcome}, value))
+	}
+	return actions
+}
+
+func feedbackOutcomeDeclaration(cardinality string) models.OutcomeDecl {
+	return models.OutcomeDecl{
+		XMLName:     xml.Name{Local: "outcomeDeclaration"},
+		Identifier:  feedbackOutcome,
+		Cardinality: cardinality,
+		BaseType:    "identifier",
+	}
+}
+
+// conditionExpression combines the children of a conditionvar, which QTI
+// 1.2 treats as an implicit and.
+func (c *responseProcessingConverter) conditionExpression(conditionVar *models.ConditionVar) models.XMLNode {
+	if conditionVar == nil {
+		return baseValue("boolean", "true")
+	}
+	return c.combine("and", conditionVar.Conditions())
+}
+
+func (c *responseProcessingConverter) combine(operator string, nodes []models.XMLNode) models.XMLNode {
+	var expressions []models.XMLNode
+	for _, node := range nodes {
+		expressions = append(expressions, c.expression(node))
+	}
+	switch len(expressions) {
+	case 0:
+		return baseValue("boolean", "true")
+	case 1:
+		return expressions[0]
+	default:
+		return models.NewXMLNode(operator, nil, expressions...)
+	}
+}
+
+// expression converts a single QTI 1.2 condition. Unknown conditions are
+// reported by the preprocessor and never match here.
+func (c *responseProcessingConverter) expression(node models.XMLNode) models.XMLNode {
+	respIdent := node.Attr("respident")
+	decl := c.response(respIdent)
+	variable := models.NewXMLNode("variable", []string{"identifier", respIdent})
+	value := strings.TrimSpace(node.Value)
+
+	switch node.XMLName.Local {
+	case "varequal":
+		base := baseValue(decl.BaseType, value)
+		if index := node.Attr("index"); index != "" && decl.Cardinality == "ordered" {
+			return models.NewXMLNode("match", nil, models.NewXMLNode("index", []string{"n", index}, variable), base)
+		}
+		if decl.Cardinality == "multiple" || decl.Cardinality == "ordered" {
+			return models.NewXMLNode("member", nil, base, variable)
+		}
+		switch decl.BaseType {
+		case "string":
+			return models.NewXMLNode("stringMatch", []string{"caseSensitive", strconv.FormatBool(isYes(node.Attr("case")))}, variable, base)
+		case "float":
+			return models.NewXMLNode("equal", []string{"toleranceMode", "exact"}, variable, base)
+		default:
+			return models.NewXMLNode("match", nil, variable, base)
+		}
+	case "varlt", "varlte", "vargt", "vargte":
+		operator := strings.TrimPrefix(node.XMLName.Local, "var")
+		return models.NewXMLNode(operator, nil, variable, baseValue(numericBaseType(decl.BaseType), value))
+	case "varsubstring":
+		return models.NewXMLNode("substring", []string{"caseSensitive", strconv.FormatBool(isYes(node.Attr("case")))},
+			baseValue("string", value), variable)
+	case "varsubset":
+		multiple := models.NewXMLNode("multiple", nil)
+		for _, member := range strings.Split(value, ",") {
+			multiple.Children = append(multiple.Children, baseValue(decl.BaseType, strings.TrimSpace(member)))
+		}
+		return models.NewXMLNode("contains", nil, variable, multiple)
+	case "varinside":
+		shape, coords := convertArea(node.Attr("areatype"), value)
+		return models.NewXMLNode("inside", []string{"shape", shape, "coords", coords}, variable)
+	case "unanswered":
+		return models.NewXMLNode("isNull", nil, variable)
+	case "other":
+		return baseValue("boolean", "true")
+	case "not":
+		return models.NewXMLNode("not", nil, c.combine("and", node.Children))
+	case "and", "or":
+		return c.combine(node.XMLName.Local, node.Children)
+	default:
+		return baseValue("boolean", "false")
+	}
+}
+
+func (c *responseProcessingConverter) response(identifier string) models.ResponseDecl {
+	if decl, ok := c.responses[identifier]; ok {
+		return decl
+	}
+	return models.ResponseDecl{Identifier: identifier, Cardinality: "single", BaseType: "identifier"}
+}
+
+func (c *responseProcessingConverter) outcomeValue(name, value string) models.XMLNode {
+	baseType := "float"
+	if decl, ok := c.outcomes[name]; ok && decl.BaseType != "" {
+		baseType = decl.BaseType
+	}
+	return baseValue(baseType, strings.TrimSpace(value))
+}
+
+func baseValue(baseType, value string) models.XMLNode {
+	if baseType == "" {
+		baseType = "identifier"
+	}
+	node := models.NewXMLNode("baseValue", []string{"baseType", baseType})
+	node.Value = value
+	return node
+}
+
+func numericBaseType(baseType string) string {
+	if baseType == "integer" {
+		return "integer"
+	}
+	return "float"
+}
+
+// singleVarEqual returns the value of a condition that consists of exactly
+// one varequal on the given response.
+func singleVarEqual(condition models.ResCondition, respIdent string) (string, bool) {
+	if condition.ConditionVar == nil {
+		return "", false
+	}
+	conditions := condition.ConditionVar.Conditions()
+	if len(conditions) != 1 || conditions[0].XMLName.Local != "varequal" || conditions[0].Attr("respident") != respIdent ||
+		conditions[0].Attr("index") != "" {
+		return "", false
+	}
+	return strings.TrimSpace(conditions[0].Value), true
+}
+
+// refersTo reports whether any condition of a respcondition tests the given
+// response.
+func refersTo(condition models.ResCondition, respIdent string) bool {
+	if condition.ConditionVar == nil {
+		return false
+	}
+	for _, node := range condition.ConditionVar.Conditions() {
+		if node.Attr("respident") == respIdent {
+			return true
+		}
+	}
+	return false
+}
+
+// scoreDecVar returns the decvar that declares SCORE, if any.
+func scoreDecVar(responseProc *models.ResponseProc) *models.DecVar {
+	if responseProc.Outcomes == nil {
+		return nil
+	}
+	for i, decVar := range responseProc.Outcomes.DecVar {
+		if decVarName(decVar) == defaultOutcome {
+			return &responseProc.Outcomes.DecVar[i]
+		}
+	}
+	return nil
+}
+
+func onlySetsScore(condition models.ResCondition) bool {
+	for _, setVar := range condition.SetVar {
+		if setVarName(setVar) != defaultOutcome {
+			return false
+		}
+	}
+	return true
+}
+
+func setVarName(setVar models.SetVar) string {
+	if setVar.VarName == "" {
+		return defaultOutcome
+	}
+	return setVar.VarName
+}
+
+// setVarAction returns the lower-case action of a setvar, set when it has
+// none.
+func setVarAction(setVar models.SetVar) string {
+	if setVar.Action == "" {
+		return "set"
+	}
+	return strings.ToLower(setVar.Action)
+}
+
+// decVarName returns the name of the variable a decvar declares, SCORE when
+// it has no varname.
+func decVarName(decVar models.DecVar) string {
+	if decVar.VarName == "" {
+		return defaultOutcome
+	}
+	return decVar.VarName
+}
+
+func hasOutcome(outcomeDecls []models.OutcomeDecl, name string) bool {
+	for _, decl := range outcomeDecls {
+		if decl.Identifier == name {
+			return true
+		}
+	}
+	return false
+}
+
+func defaultValue(decl models.OutcomeDecl) string {
+	return decl.DefaultValue.First()
+}
+
+func isContinue(condition models.ResCondition) bool {
+	return isYes(condition.Continue)
+}
+
+func isYes(value string) bool {
+	return strings.EqualFold(value, "yes")
+}
+
+func isZero(value string) bool {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return true
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	return err == nil && number == 0
+}
+
+func isOne(value string) bool {
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	return err == nil && number == 1
+}
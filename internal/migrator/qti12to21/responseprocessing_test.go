@@ -0,0 +1,372 @@
+package qti12to21
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/qti-migrator/internal/parser/qti12"
+	"github.com/qti-migrator/pkg/models"
+)
+
+func migrateItem12(t *testing.T, qti12XML string) string {
+	t.Helper()
+
+	doc, err := qti12.New().Parse([]byte(qti12XML))
+	if err != nil {
+		t.Fatalf("Failed to parse test document: %v", err)
+	}
+
+	result, err := migrate(doc)
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	return string(result)
+}
+
+func TestMigrator12to21_ResponseProcessing_MatchCorrectTemplate(t *testing.T) {
+	output := migrateItem12(t, `<questestinterop>
+	<item ident="q001" title="Capital">
+		<presentation>
+			<response_lid ident="RESPONSE" rcardinality="Single">
+				<render_choice>
+					<response_label ident="A"><material><mattext>London</mattext></material></response_label>
+					<response_label ident="B"><material><mattext>Paris</mattext></material></response_label>
+				</render_choice>
+			</response_lid>
+		</presentation>
+		<resprocessing>
+			<outcomes><decvar varname="SCORE" vartype="Decimal" defaultval="0"/></outcomes>
+			<respcondition>
+				<conditionvar><varequal respident="RESPONSE">B</varequal></conditionvar>
+				<setvar action="Set" varname="SCORE">1</setvar>
+			</respcondition>
+		</resprocessing>
+	</item>
+</questestinterop>`)
+
+	expected := `<responseProcessing template="` + models.TemplateMatchCorrect21 + `">`
+	if !strings.Contains(output, expected) {
+		t.Errorf("Expected output to contain '%s', got:\n%s", expected, output)
+	}
+	if strings.Contains(output, "<responseCondition>") {
+		t.Error("Expected template response processing without explicit rules")
+	}
+}
+
+func TestMigrator12to21_ResponseProcessing_DefaultAction(t *testing.T) {
+	output := migrateItem12(t, `<questestinterop>
+	<item ident="q001" title="Capital">
+		<presentation>
+			<response_lid ident="RESPONSE" rcardinality="Single">
+				<render_choice>
+					<response_label ident="A"><material><mattext>London</mattext></material></response_label>
+					<response_label ident="B"><material><mattext>Paris</mattext></material></response_label>
+				</render_choice>
+			</response_lid>
+		</presentation>
+		<resprocessing>
+			<outcomes><decvar varname="SCORE" vartype="Decimal" defaultval="0"/></outcomes>
+			<respcondition>
+				<conditionvar><varequal respident="RESPONSE">B</varequal></conditionvar>
+				<setvar varname="SCORE">1</setvar>
+			</respcondition>
+		</resprocessing>
+	</item>
+</questestinterop>`)
+
+	expectedContent := []string{
+		`<value>B</value>`,
+		`<responseProcessing template="` + models.TemplateMatchCorrect21 + `">`,
+	}
+	for _, expected := range expectedContent {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain '%s', got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestMigrator12to21_ResponseProcessing_Rules(t *testing.T) {
+	output := migrateItem12(t, `<questestinterop>
+	<item ident="q001" title="Rules">
+		<presentation>
+			<response_lid ident="CHOICE" rcardinality="Multiple">
+				<render_choice>
+					<response_label ident="A"><material><mattext>One</mattext></material></response_label>
+					<response_label ident="B"><material><mattext>Two</mattext></material></response_label>
+				</render_choice>
+			</response_lid>
+			<response_str ident="TEXT">
+				<render_fib fibtype="String"/>
+			</response_str>
+		</presentation>
+		<resprocessing>
+			<outcomes><decvar varname="SCORE" vartype="Decimal" defaultval="0"/></outcomes>
+			<respcondition continue="Yes">
+				<conditionvar>
+					<and>
+						<varequal respident="CHOICE">A</varequal>
+						<not><varequal respident="CHOICE">B</varequal></not>
+					</and>
+				</conditionvar>
+				<setvar action="Add" varname="SCORE">2</setvar>
+			</respcondition>
+			<respcondition continue="Yes">
+				<conditionvar><varsubstring respident="TEXT" case="No">paris</varsubstring></conditionvar>
+				<setvar action="Add" varname="SCORE">1</setvar>
+			</respcondition>
+			<respcondition>
+				<conditionvar><unanswered respident="TEXT"/></conditionvar>
+				<setvar action="Subtract" varname="SCORE">1</setvar>
+			</respcondition>
+			<respcondition>
+				<conditionvar><other/></conditionvar>
+				<setvar action="Set" varname="PENALTY">1</setvar>
+			</respcondition>
+		</resprocessing>
+	</item>
+</questestinterop>`)
+
+	expectedContent := []string{
+		`<outcomeDeclaration identifier="PENALTY" cardinality="single" baseType="float">`,
+		`<responseProcessing>`,
+		`<and><member><baseValue baseType="identifier">A</baseValue><variable identifier="CHOICE"></variable></member><not><member><baseValue baseType="identifier">B</baseValue><variable identifier="CHOICE"></variable></member></not></and>`,
+		`<setOutcomeValue identifier="SCORE"><sum><variable identifier="SCORE"></variable><baseValue baseType="float">2</baseValue></sum></setOutcomeValue>`,
+		`<substring caseSensitive="false"><baseValue baseType="string">paris</baseValue><variable identifier="TEXT"></variable></substring>`,
+		`<isNull><variable identifier="TEXT"></variable></isNull>`,
+		`<subtract><variable identifier="SCORE"></variable><baseValue baseType="float">1</baseValue></subtract>`,
+		`<responseElse><responseCondition><responseIf><baseValue baseType="boolean">true</baseValue>`,
+	}
+
+	compact := strings.Join(strings.Fields(output), " ")
+	compact = strings.ReplaceAll(compact, "> <", "><")
+	for _, expected := range expectedContent {
+		if !strings.Contains(compact, expected) {
+			t.Errorf("Expected output to contain '%s', got:\n%s", expected, output)
+		}
+	}
+	if strings.Contains(output, "template=") {
+		t.Error("Expected explicit rules instead of a template")
+	}
+}
+
+func TestMigrator12to21_ResponseProcessing_PartialCredit(t *testing.T) {
+	output := migrateItem12(t, `<questestinterop>
+	<item ident="q001" title="Primes">
+		<presentation>
+			<response_lid ident="RESPONSE" rcardinality="Multiple">
+				<render_choice>
+					<response_label ident="A"><material><mattext>2</mattext></material></response_label>
+					<response_label ident="B"><material><mattext>3</mattext></material></response_label>
+					<response_label ident="C"><material><mattext>4</mattext></material></response_label>
+				</render_choice>
+			</response_lid>
+		</presentation>
+		<resprocessing>
+			<outcomes><decvar varname="SCORE" vartype="Decimal" defaultval="0" minvalue="0"/></outcomes>
+			<respcondition continue="Yes">
+				<conditionvar><varequal respident="RESPONSE">A</varequal></conditionvar>
+				<setvar action="Add" varname="SCORE">1</setvar>
+			</respcondition>
+			<respcondition continue="Yes">
+				<conditionvar><varequal respident="RESPONSE">B</varequal></conditionvar>
+				<setvar action="Add" varname="SCORE">1.5</setvar>
+			</respcondition>
+			<respcondition continue="Yes">
+				<conditionvar><varequal respident="RESPONSE">C</varequal></conditionvar>
+				<setvar action="Subtract" varname="SCORE">1</setvar>
+			</respcondition>
+		</resprocessing>
+	</item>
+</questestinterop>`)
+
+	expectedContent := []string{
+		`<mapping lowerBound="0" upperBound="2.5" defaultValue="0">`,
+		`<mapEntry mapKey="A" mappedValue="1"></mapEntry>`,
+		`<mapEntry mapKey="B" mappedValue="1.5"></mapEntry>`,
+		`<mapEntry mapKey="C" mappedValue="-1"></mapEntry>`,
+		`<value>A</value>`,
+		`<value>B</value>`,
+		`<responseProcessing template="` + models.TemplateMapResponse21 + `">`,
+	}
+	for _, expected := range expectedContent {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain '%s', got:\n%s", expected, output)
+		}
+	}
+	if strings.Contains(output, "<value>C</value>") {
+		t.Error("Expected the penalised choice to be left out of the correct response")
+	}
+}
+
+func TestMigrator12to21_ExtractMapping_SingleCardinality(t *testing.T) {
+	responseProc := &models.ResponseProc{
+		ResCondition: []models.ResCondition{
+			{
+				ConditionVar: &models.ConditionVar{VarEqual: []models.VarEqual{{RespIdent: "R", Value: "A"}}},
+				SetVar:       []models.SetVar{{Action: "Add", Value: "2"}},
+			},
+			{
+				ConditionVar: &models.ConditionVar{VarEqual: []models.VarEqual{{RespIdent: "R", Value: "B"}}},
+				SetVar:       []models.SetVar{{Action: "Add", Value: "0.5"}},
+			},
+		},
+	}
+	decl := models.ResponseDecl{Identifier: "R", Cardinality: "single", BaseType: "identifier"}
+
+	m := New()
+	mapping := m.extractMapping(decl, responseProc)
+	if mapping == nil || len(mapping.MapEntry) != 2 {
+		t.Fatalf("Expected mapping with 2 entries, got %+v", mapping)
+	}
+	if *mapping.LowerBound != 0 || *mapping.UpperBound != 2 {
+		t.Errorf("Expected bounds 0..2, got %v..%v", *mapping.LowerBound, *mapping.UpperBound)
+	}
+
+	decl.Mapping = mapping
+	correct := m.correctResponseFromMapping(decl)
+	if correct == nil || len(correct.Value) != 1 || correct.Value[0].Content != "A" {
+		t.Errorf("Expected correct response A, got %+v", correct)
+	}
+
+	responseProc.ResCondition[1].SetVar[0].Action = "Set"
+	if m.extractMapping(decl, responseProc) != nil {
+		t.Error("Expected no mapping when a condition sets the score")
+	}
+}
+
+func TestMigrator12to21_ResponseProcessing_DisplayFeedback(t *testing.T) {
+	output := migrateItem12(t, `<questestinterop>
+	<item ident="q001" title="Feedback">
+		<presentation>
+			<response_lid ident="RESPONSE" rcardinality="Single">
+				<render_choice>
+					<response_label ident="A"><material><mattext>Paris</mattext></material></response_label>
+					<response_label ident="B"><material><mattext>Lyon</mattext></material></response_label>
+				</render_choice>
+			</response_lid>
+		</presentation>
+		<resprocessing>
+			<outcomes><decvar varname="SCORE" vartype="Decimal" defaultval="0"/></outcomes>
+			<respcondition>
+				<conditionvar><varequal respident="RESPONSE">A</varequal></conditionvar>
+				<setvar action="Set" varname="SCORE">1</setvar>
+				<displayfeedback feedbacktype="Response" linkrefid="correct"/>
+			</respcondition>
+			<respcondition>
+				<conditionvar><other/></conditionvar>
+				<displayfeedback feedbacktype="Response" linkrefid="incorrect"/>
+			</respcondition>
+		</resprocessing>
+		<itemfeedback ident="correct" title="Correct"><material><mattext>Well done.</mattext></material></itemfeedback>
+		<itemfeedback ident="incorrect"><flow_mat><material><mattext>Try again.</mattext></material></flow_mat></itemfeedback>
+	</item>
+</questestinterop>`)
+
+	expectedContent := []string{
+		`<outcomeDeclaration identifier="FEEDBACK" cardinality="single" baseType="identifier">`,
+		`<setOutcomeValue identifier="FEEDBACK">`,
+		`<baseValue baseType="identifier">correct</baseValue>`,
+		`<baseValue baseType="identifier">incorrect</baseValue>`,
+		`<modalFeedback identifier="correct" outcomeIdentifier="FEEDBACK" showHide="show" title="Correct">Well done.</modalFeedback>`,
+		`<modalFeedback identifier="incorrect" outcomeIdentifier="FEEDBACK" showHide="show">Try again.</modalFeedback>`,
+	}
+	for _, expected := range expectedContent {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain '%s', got:\n%s", expected, output)
+		}
+	}
+	if strings.Contains(output, "template=") || strings.Contains(output, "<itemfeedback") {
+		t.Error("Expected explicit rules and no itemfeedback when feedback is shown")
+	}
+}
+
+func TestFeedbackCardinality(t *testing.T) {
+	feedback := func(continues string, links ...string) models.ResCondition {
+		condition := models.ResCondition{Continue: continues}
+		for _, link := range links {
+			condition.DisplayFeedback = append(condition.DisplayFeedback, models.DisplayFeedback{LinkRefId: link})
+		}
+		return condition
+	}
+
+	tests := []struct {
+		name       string
+		conditions []models.ResCondition
+		expected   string
+	}{
+		{"exclusive conditions", []models.ResCondition{feedback("No", "a"), feedback("No", "b")}, "single"},
+		{"several links", []models.ResCondition{feedback("No", "a", "b")}, "multiple"},
+		{"continued condition", []models.ResCondition{feedback("Yes", "a"), feedback("No", "b")}, "multiple"},
+		{"continue without feedback", []models.ResCondition{feedback("Yes"), feedback("No", "b")}, "single"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := feedbackCardinality(&models.ResponseProc{ResCondition: tt.conditions})
+			if result != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestMigrator12to21_ResponseProcessing_BareDecVar(t *testing.T) {
+	output := migrateItem12(t, `<questestinterop>
+	<item ident="q001" title="Bare">
+		<presentation>
+			<response_lid ident="RESPONSE" rcardinality="Single">
+				<render_choice>
+					<response_label ident="A"><material><mattext>Yes</mattext></material></response_label>
+					<response_label ident="B"><material><mattext>No</mattext></material></response_label>
+				</render_choice>
+			</response_lid>
+		</presentation>
+		<resprocessing>
+			<outcomes><decvar/></outcomes>
+			<respcondition>
+				<conditionvar><varequal respident="RESPONSE">A</varequal></conditionvar>
+				<setvar action="Set">1</setvar>
+			</respcondition>
+		</resprocessing>
+	</item>
+</questestinterop>`)
+
+	if strings.Contains(output, `identifier=""`) {
+		t.Errorf("Expected no outcome without an identifier, got:\n%s", output)
+	}
+	if count := strings.Count(output, `<outcomeDeclaration identifier="SCORE"`); count != 1 {
+		t.Errorf("Expected the bare decvar to declare SCORE once, got %d declarations:\n%s", count, output)
+	}
+}
+
+func TestMigrator12to21_ResponseProcessing_BareDecVarBounds(t *testing.T) {
+	output := migrateItem12(t, `<questestinterop>
+	<item ident="q001" title="Bare">
+		<presentation>
+			<response_lid ident="RESPONSE" rcardinality="Single">
+				<render_choice>
+					<response_label ident="A"><material><mattext>Yes</mattext></material></response_label>
+				</render_choice>
+			</response_lid>
+		</presentation>
+		<resprocessing>
+			<outcomes><decvar defaultval="2" minvalue="0" maxvalue="5"/></outcomes>
+			<respcondition>
+				<conditionvar><varequal respident="RESPONSE">A</varequal></conditionvar>
+				<setvar action="Add">1</setvar>
+			</respcondition>
+		</resprocessing>
+	</item>
+</questestinterop>`)
+
+	expected := []string{
+		`<outcomeDeclaration identifier="SCORE" cardinality="single" baseType="float" normalMaximum="5" normalMinimum="0">`,
+		`<defaultValue>`,
+		`<value>2</value>`,
+	}
+	for _, s := range expected {
+		if !strings.Contains(output, s) {
+			t.Errorf("Expected output to contain '%s', got:\n%s", s, output)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package qti21to30
+
+import (
+	"fmt"
+
+	"github.com/qti-migrator/pkg/analysis"
+	"github.com/qti-migrator/pkg/models"
+)
+
+// analyzer collects the findings of a QTI 2.1 to 3.0 migration.
+type analyzer struct {
+	verbosity int
+}
+
+// Analyze reports what migrating doc to QTI 3.0 changes. It is the analysis
+// hook of the registered step.
+func Analyze(doc *models.QTIDocument, report *analysis.Report, verbosity int) {
+	a := &analyzer{verbosity: verbosity}
+	a.analyzeQTI21to30(doc, report)
+}
+
+func (a *analyzer) analyzeQTI21to30(doc *models.QTIDocument, report *analysis.Report) {
+	for _, item := range doc.Items {
+		a.analyzeItem21to30(&item, report)
+	}
+
+	if doc.Assessment != nil {
+		for i := range doc.Assessment.Sections {
+			a.analyzeSection21to30(&doc.Assessment.Sections[i], report)
+		}
+
+		// Assessment structure will change in QTI 3.0
+		report.MigrationDetails = append(report.MigrationDetails, analysis.MigrationDetail{
+			ItemID:      doc.Assessment.Ident,
+			ElementPath: "assessment",
+			OldValue:    "assessment",
+			NewValue:    "qti-assessment-test",
+			Action:      "rename",
+			Description: "Assessment element renamed to qti-assessment-test in QTI 3.0",
+		})
+	}
+}
+
+func (a *analyzer) analyzeSection21to30(section *models.Section, report *analysis.Report) {
+	for i := range section.Items {
+		a.analyzeItem21to30(&section.Items[i], report)
+	}
+	for i := range section.Sections {
+		a.analyzeSection21to30(&section.Sections[i], report)
+	}
+}
+
+func (a *analyzer) analyzeItem21to30(item *models.Item, report *analysis.Report) {
+	// Item element naming changes
+	report.MigrationDetails = append(report.MigrationDetails, analysis.MigrationDetail{
+		ItemID:      item.Ident,
+		ElementPath: fmt.Sprintf("item[@ident='%s']", item.Ident),
+		OldValue:    "item",
+		NewValue:    "qti-assessment-item",
+		Action:      "rename",
+		Description: "Item element renamed to qti-assessment-item in QTI 3.0",
+	})
+
+	// ItemBody changes
+	if item.ItemBody != nil {
+		report.MigrationDetails = append(report.MigrationDetails, analysis.MigrationDetail{
+			ItemID:      item.Ident,
+			ElementPath: fmt.Sprintf("item[@ident='%s']/itemBody", item.Ident),
+			OldValue:    "itemBody",
+			NewValue:    "qti-item-body",
+			Action:      "rename",
+			Description: "ItemBody element renamed to qti-item-body in QTI 3.0",
+		})
+
+		// Analyze interactions
+		for _, interaction := range item.ItemBody.ChoiceInteraction {
+			report.MigrationDetails = append(report.MigrationDetails, analysis.MigrationDetail{
+				ItemID:      item.Ident,
+				ElementPath: fmt.Sprintf("item[@ident='%s']/itemBody/choiceInteraction[@responseIdentifier='%s']", item.Ident, interaction.ResponseIdent),
+				OldValue:    "choiceInteraction",
+				NewValue:    "qti-choice-interaction",
+				Action:      "rename",
+				Description: "Interaction elements prefixed with 'qti-' in QTI 3.0",
+			})
+
+			report.MigrationDetails = append(report.MigrationDetails, analysis.MigrationDetail{
+				ItemID:      item.Ident,
+				ElementPath: fmt.Sprintf("item[@ident='%s']/itemBody/choiceInteraction/simpleChoice", item.Ident),
+				OldValue:    "simpleChoice",
+				NewValue:    "qti-simple-choice",
+				Action:      "rename",
+				Description: "SimpleChoice elements renamed to qti-simple-choice in QTI 3.0",
+			})
+		}
+
+		// Presentation hints become classes of the shared CSS vocabulary
+		for _, change := range ClassChanges(item.ItemBody) {
+			report.MigrationDetails = append(report.MigrationDetails, analysis.MigrationDetail{
+				ItemID:      item.Ident,
+				ElementPath: fmt.Sprintf("item[@ident='%s']/itemBody//%s", item.Ident, change.Element),
+				OldValue:    change.Original,
+				NewValue:    fmt.Sprintf("class=%q", change.Class),
+				Action:      "transform",
+				Description: "Presentation hint mapped to the QTI 3.0 shared CSS vocabulary",
+			})
+		}
+	}
+
+	// Response declaration changes
+	for _, decl := range item.ResponseDecl {
+		report.MigrationDetails = append(report.MigrationDetails, analysis.MigrationDetail{
+			ItemID:      item.Ident,
+			ElementPath: fmt.Sprintf("item[@ident='%s']/responseDeclaration[@identifier='%s']", item.Ident, decl.Identifier),
+			OldValue:    "responseDeclaration",
+			NewValue:    "qti-response-declaration",
+			Action:      "rename",
+			Description: "Response declaration renamed in QTI 3.0",
+		})
+
+		// Base type changes
+		if decl.BaseType == "pair" {
+			report.MigrationDetails = append(report.MigrationDetails, analysis.MigrationDetail{
+				ItemID:      item.Ident,
+				ElementPath: fmt.Sprintf("item[@ident='%s']/responseDeclaration[@identifier='%s']/@baseType", item.Ident, decl.Identifier),
+				OldValue:    "pair",
+				NewValue:    "directedPair",
+				Action:      "transform",
+				Description: "BaseType 'pair' renamed to 'directedPair' in QTI 3.0",
+			})
+		}
+	}
+
+	// Outcome declaration changes
+	for _, decl := range item.OutcomeDecl {
+		report.MigrationDetails = append(report.MigrationDetails, analysis.MigrationDetail{
+			ItemID:      item.Ident,
+			ElementPath: fmt.Sprintf("item[@ident='%s']/outcomeDeclaration[@identifier='%s']", item.Ident, decl.Identifier),
+			OldValue:    "outcomeDeclaration",
+			NewValue:    "qti-outcome-declaration",
+			Action:      "rename",
+			Description: "Outcome declaration renamed in QTI 3.0",
+		})
+	}
+
+	// Metadata changes
+	if item.Metadata != nil && item.Metadata.QTIMetadata != nil {
+		report.MigrationDetails = append(report.MigrationDetails, analysis.MigrationDetail{
+			ItemID:      item.Ident,
+			ElementPath: fmt.Sprintf("item[@ident='%s']/metadata/qtimetadata", item.Ident),
+			OldValue:    "qtimetadata",
+			NewValue:    "qti-metadata-container",
+			Action:      "rename",
+			Description: "QTI metadata container renamed in QTI 3.0",
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package qti21to30
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/qti-migrator/pkg/models"
+)
+
+// sharedClasses maps presentation classes written by QTI 2.1 authoring tools
+// to the QTI 3.0 shared CSS vocabulary. Classes that are not listed are kept
+// as they are.
+var sharedClasses = map[string]string{
+	// TAO list styles for choice labels
+	"list-style-none":        "qti-labels-none",
+	"list-style-decimal":     "qti-labels-decimal",
+	"list-style-lower-alpha": "qti-labels-lower-alpha",
+	"list-style-upper-alpha": "qti-labels-upper-alpha",
+}
+
+// interactionClasses maps the layout classes TAO and Onyx write on
+// interactions to the shared CSS vocabulary. The names are too generic to
+// map on other elements, where they keep their own meaning.
+var interactionClasses = map[string]string{
+	"horizontal":      "qti-orientation-horizontal",
+	"vertical":        "qti-orientation-vertical",
+	"stacked":         "qti-choices-stacked",
+	"choices-stacked": "qti-choices-stacked",
+}
+
+// orientationClasses maps the orientation attribute of interactions, such as
+// choice and order interactions, to its shared class.
+var orientationClasses = map[string]string{
+	"horizontal": "qti-orientation-horizontal",
+	"vertical":   "qti-orientation-vertical",
+}
+
+// inputWidths are the widths of the qti-input-width classes, in characters.
+var inputWidths = []int{1, 2, 3, 4, 6, 10, 15, 20, 25, 30, 35, 40, 45, 50, 72}
+
+// heightLines are the heights of the qti-height-lines classes, in lines.
+var heightLines = []int{3, 6, 15}
+
+// ClassChange records a QTI 2.1 presentation class or attribute replaced by
+// a class of the QTI 3.0 shared CSS vocabulary.
+type ClassChange struct {
+	// QTI 2.1 element the class belongs to
+	Element string
+	// The class or attribute as written, such as class="horizontal"
+	Original string
+	Class    string
+}
+
+// presentation holds the QTI 2.1 attributes of an element that carry
+// rendering hints.
+type presentation struct {
+	class          string
+	orientation    string
+	expectedLength int
+	expectedLines  int
+}
+
+// ClassChanges lists the classes and presentation attributes of an item
+// body that the migration maps to the shared CSS vocabulary, in document
+// order.
+func ClassChanges(itemBody *models.ItemBody) []ClassChange {
+	var changes []ClassChange
+	for _, element := range itemBody.Elements() {
+		_, elementChanges := sharedClass(elementPresentation(element))
+		changes = append(changes, elementChanges...)
+		for _, content := range elementContents(element) {
+			_, contentChanges := rewriteBody(content)
+			changes = append(changes, contentChanges...)
+		}
+	}
+	return changes
+}
+
+// elementClass returns the class of an item body element in the shared CSS
+// vocabulary.
+func elementClass(element interface{}) string {
+	class, _ := sharedClass(elementPresentation(element))
+	return class
+}
+
+// elementPresentation returns the QTI 2.1 name and presentation attributes
+// of an item body element.
+func elementPresentation(element interface{}) (string, presentation) {
+	switch e := element.(type) {
+	case models.Div:
+		return "div", presentation{class: e.Class}
+	case models.ChoiceInteraction:
+		return "choiceInteraction", presentation{class: e.Class, orientation: e.Orientation}
+	case models.TextEntryInteraction:
+		return "textEntryInteraction", presentation{class: e.Class, expectedLength: e.ExpectedLength}
+	case models.ExtendedTextInteraction:
+		return "extendedTextInteraction", presentation{class: e.Class, expectedLines: e.ExpectedLines}
+	case models.OrderInteraction:
+		return "orderInteraction", presentation{class: e.Class, orientation: e.Orientation}
+	case models.MatchInteraction:
+		return "matchInteraction", presentation{class: e.Class}
+	case models.AssociateInteraction:
+		return "associateInteraction", presentation{class: e.Class}
+	case models.RawElement21:
+		return e.XMLName.Local, presentationOf(e.Attrs)
+	default:
+		return "", presentation{}
+	}
+}
+
+// elementContents returns the markup held by an item body element.
+func elementContents(element interface{}) []string {
+	var contents []string
+	addPrompt := func(prompt *models.Prompt) {
+		if prompt != nil {
+			contents = append(contents, prompt.Content)
+		}
+	}
+
+	switch e := element.(type) {
+	case models.P:
+		contents = append(contents, e.Content)
+	case models.Div:
+		contents = append(contents, e.Content)
+	case models.ChoiceInteraction:
+		addPrompt(e.Prompt)
+		for _, choice := range e.SimpleChoice {
+			contents = append(contents, choice.Content)
+		}
+	case models.ExtendedTextInteraction:
+		addPrompt(e.Prompt)
+	case models.OrderInteraction:
+		addPrompt(e.Prompt)
+		for _, choice := range e.SimpleChoice {
+			contents = append(contents, choice.Content)
+		}
+	case models.MatchInteraction:
+		addPrompt(e.Prompt)
+		for _, set := range e.SimpleMatchSet {
+			for _, choice := range set.SimpleAssociableChoice {
+				contents = append(contents, choice.Content)
+			}
+		}
+	case models.AssociateInteraction:
+		addPrompt(e.Prompt)
+		for _, choice := range e.SimpleAssociableChoice {
+			contents = append(contents, choice.Content)
+		}
+	case models.RawElement21:
+		contents = append(contents, e.Content)
+	}
+	return contents
+}
+
+// presentationOf reads the presentation attributes of a QTI 2.1 element.
+func presentationOf(attrs []xml.Attr) presentation {
+	hints := presentation{
+		class:       attrValue(attrs, "class"),
+		orientation: attrValue(attrs, "orientation"),
+	}
+	hints.expectedLength, _ = strconv.Atoi(attrValue(attrs, "expectedLength"))
+	hints.expectedLines, _ = strconv.Atoi(attrValue(attrs, "expectedLines"))
+	return hints
+}
+
+// withClass sets the class attribute, replacing the one in attrs if any.
+func withClass(attrs []xml.Attr, class string) []xml.Attr {
+	for i, attr := range attrs {
+		if attr.Name.Space == "" && attr.Name.Local == "class" {
+			attrs[i].Value = class
+			return attrs
+		}
+	}
+	return append(attrs, xml.Attr{Name: xml.Name{Local: "class"}, Value: class})
+}
+
+// sharedClass returns the class of a QTI 2.1 element in the shared CSS
+// vocabulary: its own classes, with known vendor classes replaced, followed
+// by the classes for its presentation attributes. Layout classes and
+// orientations are only mapped on interactions. Classes only appear once.
+func sharedClass(element string, hints presentation) (string, []ClassChange) {
+	var classes []string
+	var changes []ClassChange
+	seen := make(map[string]bool)
+	addClass := func(class string) {
+		if !seen[class] {
+			seen[class] = true
+			classes = append(classes, class)
+		}
+	}
+
+	interaction := strings.HasSuffix(element, "Interaction")
+	for _, class := range strings.Fields(hints.class) {
+		shared, ok := sharedClasses[class]
+		if !ok && interaction {
+			shared, ok = interactionClasses[class]
+		}
+		if ok {
+			changes = append(changes, ClassChange{Element: element, Original: fmt.Sprintf("class=%q", class), Class: shared})
+			class = shared
+		}
+		addClass(class)
+	}
+
+	if shared, ok := orientationClasses[hints.orientation]; ok && interaction {
+		changes = append(changes, ClassChange{Element: element, Original: fmt.Sprintf("orientation=%q", hints.orientation), Class: shared})
+		addClass(shared)
+	}
+	if element == "textEntryInteraction" && hints.expectedLength > 0 {
+		shared := "qti-input-width-" + strconv.Itoa(fitting(inputWidths, hints.expectedLength))
+		changes = append(changes, ClassChange{Element: element, Original: fmt.Sprintf(`expectedLength="%d"`, hints.expectedLength), Class: shared})
+		addClass(shared)
+	}
+	if element == "extendedTextInteraction" && hints.expectedLines > 0 {
+		shared := "qti-height-lines-" + strconv.Itoa(fitting(heightLines, hints.expectedLines))
+		changes = append(changes, ClassChange{Element: element, Original: fmt.Sprintf(`expectedLines="%d"`, hints.expectedLines), Class: shared})
+		addClass(shared)
+	}
+
+	return strings.Join(classes, " "), changes
+}
+
+// fitting returns the smallest of sizes that holds n, or the largest size.
+func fitting(sizes []int, n int) int {
+	for _, size := range sizes {
+		if size >= n {
+			return size
+		}
+	}
+	return sizes[len(sizes)-1]
+}
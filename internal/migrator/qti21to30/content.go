@@ -0,0 +1,308 @@
+package qti21to30
+
+import (
+	"encoding/xml"
+	"strings"
+
+	"github.com/qti-migrator/internal/markup"
+	"github.com/qti-migrator/internal/xhtml"
+)
+
+// openElement is an element of the rewritten content that has not been
+// closed yet.
+type openElement struct {
+	// Name of the end tag to write, or "" to copy the end tag as written
+	name string
+	// Set on a rubric block, whose content is wrapped in qti-content-body
+	contentBody bool
+	// Dropped elements are left out together with their content
+	drop bool
+	// Set on audio and video made from an object, whose params are dropped
+	media bool
+	// Set on an image object, which becomes an img once its fallback text,
+	// used as the alt text, has been read
+	image *xml.StartElement
+	alt   strings.Builder
+}
+
+// contentRewriter rewrites QTI 2.1 body markup to QTI 3.0 one token at a
+// time. Tags that do not change, text, comments and the whole of a MathML
+// subtree are copied exactly as written.
+type contentRewriter struct {
+	out   strings.Builder
+	open  []*openElement
+	drops int
+	// Depth inside a math element
+	math int
+	// Classes mapped to the shared CSS vocabulary
+	changes []ClassChange
+}
+
+// rewriteBody rewrites body markup to the QTI 3.0 vocabulary, see
+// rewriteContent. Markup that is not well-formed XML is made well-formed
+// XHTML first, keeping its QTI elements.
+func rewriteBody(content string) (string, []ClassChange) {
+	if rewritten, changes, ok := rewriteContent(content); ok {
+		return rewritten, changes
+	}
+	normalized, _ := xhtml.ConvertKeeping(content, isQTIElement)
+	rewritten, changes, _ := rewriteContent(normalized)
+	return rewritten, changes
+}
+
+// rewriteContent rewrites body markup to the QTI 3.0 vocabulary on its XML
+// token stream, so only element names and attributes change and text such
+// as "class=" in a code sample is left alone:
+//
+//   - QTI elements and their attributes are renamed to kebab case
+//   - presentation classes and attributes are mapped to the shared CSS
+//     vocabulary, see sharedClass
+//   - objects holding an image, audio or video become img, audio and video,
+//     other objects stay object, which QTI 3.0 takes from HTML
+//   - rubric blocks wrap their content in qti-content-body
+//   - img, audio, video and math keep their names, and MathML is copied as
+//     is; the prefixes it uses are declared on the body element holding it
+//
+// It returns the class changes made, and ok is false when content cannot be
+// tokenized as XML.
+func rewriteContent(content string) (rewritten string, changes []ClassChange, ok bool) {
+	r := &contentRewriter{}
+	err := markup.Walk(content, func(token markup.Token) {
+		switch t := token.Token.(type) {
+		case xml.StartElement:
+			r.startElement(t, token.Raw, token.SelfClosing)
+		case xml.EndElement:
+			r.endElement(token.Raw)
+		case xml.CharData:
+			r.text(token.Raw, string(t))
+		default:
+			r.write(token.Raw)
+		}
+	})
+	if err != nil || len(r.open) > 0 || r.math > 0 {
+		return "", nil, false
+	}
+	return r.out.String(), r.changes, true
+}
+
+func (r *contentRewriter) write(raw string) {
+	if r.drops == 0 {
+		r.out.WriteString(raw)
+	}
+}
+
+func (r *contentRewriter) text(raw, text string) {
+	if r.drops > 0 {
+		if image := r.openImage(); image != nil {
+			image.alt.WriteString(text)
+		}
+		return
+	}
+	r.out.WriteString(raw)
+}
+
+func (r *contentRewriter) startElement(start xml.StartElement, raw string, selfClosing bool) {
+	if r.math > 0 {
+		r.out.WriteString(raw)
+		if !selfClosing {
+			r.math++
+		}
+		return
+	}
+
+	element := &openElement{}
+	switch local := start.Name.Local; {
+	case r.drops > 0 || local == "param" && r.inMedia():
+		element.drop = true
+	case start.Name.Space != "" && local != "math":
+		r.out.WriteString(raw)
+	case local == "math":
+		r.out.WriteString(raw)
+		if !selfClosing {
+			r.math = 1
+		}
+		return
+	case local == "rubricBlock":
+		element.name = r.startRubricBlock(start, selfClosing)
+		element.contentBody = true
+	case isQTIElement(local):
+		r.applySharedClass(local, &start)
+		element.name = qtiElementName(local)
+		start.Name.Local = element.name
+		for i, attr := range start.Attr {
+			if attr.Name.Space == "" {
+				start.Attr[i].Name.Local = camelToKebab(attr.Name.Local)
+			}
+		}
+		r.out.WriteString(markup.StartTag(start, selfClosing))
+	case local == "object":
+		element.name = r.startObject(start, selfClosing, element)
+	default:
+		if r.applySharedClass(local, &start) {
+			r.out.WriteString(markup.StartTag(start, selfClosing))
+		} else {
+			r.out.WriteString(raw)
+		}
+	}
+
+	if selfClosing {
+		return
+	}
+	if element.drop {
+		r.drops++
+	}
+	r.open = append(r.open, element)
+}
+
+// startObject writes the start of an object and returns the name of its end
+// tag, or "" when the end tag is copied as written. An image object is written once its end tag is read, and media
+// objects lose their param elements.
+func (r *contentRewriter) startObject(start xml.StartElement, selfClosing bool, element *openElement) string {
+	r.applySharedClass("object", &start)
+	media := objectMedia(attrValue(start.Attr, "type"))
+	if media == "" {
+		r.out.WriteString(markup.StartTag(start, selfClosing))
+		return ""
+	}
+
+	media.attrs(&start)
+	if media == "img" {
+		if selfClosing {
+			r.out.WriteString(markup.StartTag(start, true))
+		} else {
+			element.image = &start
+			element.drop = true
+		}
+		return ""
+	}
+	element.media = true
+	r.out.WriteString(markup.StartTag(start, selfClosing))
+	return start.Name.Local
+}
+
+// startRubricBlock writes the start of a rubric block and the
+// qti-content-body QTI 3.0 wraps its content in, and returns the name of
+// its end tag.
+func (r *contentRewriter) startRubricBlock(start xml.StartElement, selfClosing bool) string {
+	start.Name.Local = qtiElementName(start.Name.Local)
+	start.Attr = migrateAttrs(start.Attr)
+	for i, attr := range start.Attr {
+		if attr.Name.Space == "" && attr.Name.Local == "view" {
+			start.Attr[i].Value = migrateViews(attr.Value)
+		}
+	}
+	r.out.WriteString(markup.StartTag(start, false) + "<qti-content-body>")
+	if selfClosing {
+		r.out.WriteString("</qti-content-body></" + start.Name.Local + ">")
+	}
+	return start.Name.Local
+}
+
+func (r *contentRewriter) inMedia() bool {
+	return len(r.open) > 0 && r.open[len(r.open)-1].media
+}
+
+func (r *contentRewriter) endElement(raw string) {
+	if r.math > 0 {
+		r.out.WriteString(raw)
+		r.math--
+		return
+	}
+	if len(r.open) == 0 {
+		r.out.WriteString(raw)
+		return
+	}
+
+	element := r.open[len(r.open)-1]
+	r.open = r.open[:len(r.open)-1]
+	if element.drop {
+		r.drops--
+	}
+	switch {
+	case element.image != nil:
+		if alt := strings.Join(strings.Fields(element.alt.String()), " "); alt != "" {
+			element.image.Attr = append(element.image.Attr, xml.Attr{Name: xml.Name{Local: "alt"}, Value: alt})
+		}
+		r.out.WriteString(markup.StartTag(*element.image, true))
+	case element.drop:
+	case element.contentBody:
+		r.out.WriteString("</qti-content-body></" + element.name + ">")
+	case element.name != "":
+		r.out.WriteString("</" + element.name + ">")
+	default:
+		r.out.WriteString(raw)
+	}
+}
+
+// openImage returns the innermost image object being read, whose text
+// becomes its alt text.
+func (r *contentRewriter) openImage() *openElement {
+	for i := len(r.open) - 1; i >= 0; i-- {
+		if r.open[i].image != nil {
+			return r.open[i]
+		}
+	}
+	return nil
+}
+
+// mediaElement is the HTML element that replaces an object of a given
+// media type.
+type mediaElement string
+
+func objectMedia(mimeType string) mediaElement {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return "img"
+	case strings.HasPrefix(mimeType, "audio/"):
+		return "audio"
+	case strings.HasPrefix(mimeType, "video/"):
+		return "video"
+	default:
+		return ""
+	}
+}
+
+// attrs turns the start of an object into the start of the media element:
+// data becomes src, the type is dropped and audio and video get controls,
+// a boolean in QTI 3.0.
+func (media mediaElement) attrs(start *xml.StartElement) {
+	start.Name.Local = string(media)
+	var attrs []xml.Attr
+	for _, attr := range start.Attr {
+		switch {
+		case attr.Name.Space != "":
+		case attr.Name.Local == "data":
+			attr.Name.Local = "src"
+		case attr.Name.Local == "type":
+			continue
+		}
+		attrs = append(attrs, attr)
+	}
+	if media != "img" {
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "controls"}, Value: "true"})
+	}
+	start.Attr = attrs
+}
+
+// applySharedClass maps the class and presentation attributes of start, a
+// QTI 2.1 element, to the shared CSS vocabulary and reports whether its
+// class changed.
+func (r *contentRewriter) applySharedClass(element string, start *xml.StartElement) bool {
+	hints := presentationOf(start.Attr)
+	class, changes := sharedClass(element, hints)
+	r.changes = append(r.changes, changes...)
+	if class == hints.class {
+		return false
+	}
+	start.Attr = withClass(start.Attr, class)
+	return true
+}
+
+func attrValue(attrs []xml.Attr, name string) string {
+	for _, attr := range attrs {
+		if attr.Name.Space == "" && attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
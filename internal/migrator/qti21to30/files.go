@@ -0,0 +1,350 @@
+package qti21to30
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+
+	"github.com/qti-migrator/internal/assets"
+	"github.com/qti-migrator/internal/testfiles"
+	"github.com/qti-migrator/pkg/models"
+	"github.com/qti-migrator/pkg/registry"
+)
+
+// QTI3AssessmentTest is a QTI 3.0 test whose items live in separate files
+type QTI3AssessmentTest struct {
+	XMLName           xml.Name               `xml:"http://www.imsglobal.org/xsd/imsqtiasi_v3p0 qti-assessment-test"`
+	Identifier        string                 `xml:"identifier,attr"`
+	Title             string                 `xml:"title,attr"`
+	ToolName          string                 `xml:"tool-name,attr,omitempty"`
+	ToolVersion       string                 `xml:"tool-version,attr,omitempty"`
+	OutcomeDecl       []QTI3OutcomeDecl      `xml:"qti-outcome-declaration,omitempty"`
+	TimeLimits        *QTI3TimeLimits        `xml:"qti-time-limits,omitempty"`
+	TestParts         []QTI3TestPart         `xml:"qti-test-part"`
+	OutcomeProcessing *QTI3OutcomeProcessing `xml:"qti-outcome-processing,omitempty"`
+	TestFeedback      []QTI3TestFeedback     `xml:"qti-test-feedback,omitempty"`
+}
+
+type QTI3TestPart struct {
+	XMLName            xml.Name                `xml:"qti-test-part"`
+	Identifier         string                  `xml:"identifier,attr"`
+	NavigationMode     string                  `xml:"navigation-mode,attr"`
+	SubmissionMode     string                  `xml:"submission-mode,attr"`
+	ItemSessionControl *QTI3ItemSessionControl `xml:"qti-item-session-control,omitempty"`
+	TimeLimits         *QTI3TimeLimits         `xml:"qti-time-limits,omitempty"`
+	Sections           []QTI3AssessmentSection `xml:"qti-assessment-section"`
+	TestFeedback       []QTI3TestFeedback      `xml:"qti-test-feedback,omitempty"`
+}
+
+type QTI3AssessmentSection struct {
+	XMLName            xml.Name                `xml:"qti-assessment-section"`
+	Identifier         string                  `xml:"identifier,attr"`
+	Title              string                  `xml:"title,attr"`
+	Visible            bool                    `xml:"visible,attr"`
+	ItemSessionControl *QTI3ItemSessionControl `xml:"qti-item-session-control,omitempty"`
+	TimeLimits         *QTI3TimeLimits         `xml:"qti-time-limits,omitempty"`
+	Selection          *QTI3Selection          `xml:"qti-selection,omitempty"`
+	Ordering           *QTI3Ordering           `xml:"qti-ordering,omitempty"`
+	RubricBlocks       []QTI3RubricBlock       `xml:"qti-rubric-block,omitempty"`
+	// Item refs followed by items and sub-sections in document order
+	Content []interface{} `xml:",any"`
+}
+
+type QTI3AssessmentItemRef struct {
+	XMLName            xml.Name                `xml:"qti-assessment-item-ref"`
+	Identifier         string                  `xml:"identifier,attr"`
+	Href               string                  `xml:"href,attr"`
+	Category           string                  `xml:"category,attr,omitempty"`
+	ItemSessionControl *QTI3ItemSessionControl `xml:"qti-item-session-control,omitempty"`
+	TimeLimits         *QTI3TimeLimits         `xml:"qti-time-limits,omitempty"`
+	Weights            []QTI3Weight            `xml:"qti-weight,omitempty"`
+}
+
+type QTI3Weight struct {
+	XMLName    xml.Name `xml:"qti-weight"`
+	Identifier string   `xml:"identifier,attr"`
+	Value      float64  `xml:"value,attr"`
+}
+
+type QTI3OutcomeProcessing struct {
+	XMLName xml.Name         `xml:"qti-outcome-processing"`
+	Rules   []models.XMLNode `xml:",any"`
+}
+
+// QTI3ContentBody wraps the content of rubric blocks and test feedback
+type QTI3ContentBody struct {
+	XMLName xml.Name `xml:"qti-content-body"`
+	Content string   `xml:",innerxml"`
+}
+
+type QTI3RubricBlock struct {
+	XMLName     xml.Name        `xml:"qti-rubric-block"`
+	Use         string          `xml:"use,attr,omitempty"`
+	View        string          `xml:"view,attr"`
+	Attrs       []xml.Attr      `xml:",any,attr"`
+	ContentBody QTI3ContentBody `xml:"qti-content-body"`
+}
+
+type QTI3TestFeedback struct {
+	XMLName           xml.Name        `xml:"qti-test-feedback"`
+	Access            string          `xml:"access,attr"`
+	OutcomeIdentifier string          `xml:"outcome-identifier,attr"`
+	ShowHide          string          `xml:"show-hide,attr"`
+	Identifier        string          `xml:"identifier,attr"`
+	Title             string          `xml:"title,attr,omitempty"`
+	ContentBody       QTI3ContentBody `xml:"qti-content-body"`
+}
+
+type QTI3ItemSessionControl struct {
+	XMLName     xml.Name `xml:"qti-item-session-control"`
+	MaxAttempts *int     `xml:"max-attempts,attr,omitempty"`
+}
+
+type QTI3TimeLimits struct {
+	XMLName             xml.Name `xml:"qti-time-limits"`
+	MinTime             float64  `xml:"min-time,attr,omitempty"`
+	MaxTime             float64  `xml:"max-time,attr,omitempty"`
+	AllowLateSubmission bool     `xml:"allow-late-submission,attr,omitempty"`
+}
+
+type QTI3Selection struct {
+	XMLName         xml.Name `xml:"qti-selection"`
+	Select          int      `xml:"select,attr"`
+	WithReplacement bool     `xml:"with-replacement,attr,omitempty"`
+}
+
+type QTI3Ordering struct {
+	XMLName xml.Name `xml:"qti-ordering"`
+	Shuffle bool     `xml:"shuffle,attr"`
+}
+
+// Serializer30 writes the QTI 2.1 model as QTI 3.0 files.
+type Serializer30 struct {
+	migrator *Migrator21to30
+}
+
+func NewSerializer() *Serializer30 {
+	return &Serializer30{migrator: New()}
+}
+
+// Serialize writes doc as QTI 3.0 files. A document with a single item
+// becomes one qti-assessment-item file. Several items, or a test, become one
+// file per item and a qti-assessment-test that references them, which comes
+// first. A document without items keeps its wrapper and has no href.
+func (s *Serializer30) Serialize(doc *models.QTIDocument, options registry.MigrationOptions) ([]models.File, error) {
+	if options.Profile != "" && options.Profile != "3.0" {
+		return nil, fmt.Errorf("QTI 3.0 output has no profile %q, expected 3.0", options.Profile)
+	}
+
+	if len(doc.Items) == 0 && doc.Assessment == nil {
+		return nil, fmt.Errorf("document holds no items or assessment test to write as QTI 3.0")
+	}
+
+	if len(doc.Items) == 1 && doc.Assessment == nil {
+		item := &doc.Items[0]
+		content, err := s.migrator.migrateSingleItem(item)
+		if err != nil {
+			return nil, err
+		}
+		return []models.File{{
+			Href:       testfiles.FileName(item.Ident, "item1") + ".xml",
+			Identifier: item.Ident,
+			Kind:       models.FileItem,
+			Content:    content,
+			Assets:     assets.List(content),
+		}}, nil
+	}
+
+	w := &testWriter{
+		migrator: s.migrator,
+		items:    testfiles.NewItems(options.Layout.ItemDirectory()),
+	}
+	test := w.test(doc)
+	if err := w.items.Err(); err != nil {
+		return nil, err
+	}
+	if options.Layout == registry.LayoutSingle && w.items.Len() > 0 {
+		return nil, fmt.Errorf("document holds %d items, which QTI 3.0 stores in separate files: migrate it to a directory or package", w.items.Len())
+	}
+	return w.items.Files(test.Identifier, test)
+}
+
+// testWriter builds a qti-assessment-test and collects the item files it
+// references.
+type testWriter struct {
+	migrator *Migrator21to30
+	items    *testfiles.Items
+	// Sections written so far, for naming sections without an identifier
+	sections int
+}
+
+func (w *testWriter) test(doc *models.QTIDocument) *QTI3AssessmentTest {
+	test := &QTI3AssessmentTest{Identifier: "test"}
+	if doc.Assessment == nil {
+		section := QTI3AssessmentSection{Identifier: "section1", Title: "section1", Visible: true}
+		for i := range doc.Items {
+			section.Content = append(section.Content, w.itemRef(&doc.Items[i]))
+		}
+		test.Title = test.Identifier
+		test.TestParts = []QTI3TestPart{w.testPart(testfiles.DefaultTestPart, "", "", []QTI3AssessmentSection{section})}
+		return test
+	}
+
+	assessment := doc.Assessment
+	if assessment.Ident != "" {
+		test.Identifier = assessment.Ident
+	}
+	test.Title = testfiles.OrDefault(assessment.Title, test.Identifier)
+	test.TimeLimits = migrateTimeLimits(assessment.TimeLimits)
+	test.ToolName = assessment.ToolName
+	test.ToolVersion = assessment.ToolVersion
+	for i := range assessment.OutcomeDecl {
+		test.OutcomeDecl = append(test.OutcomeDecl, w.migrator.migrateOutcomeDeclarationToQTI3(&assessment.OutcomeDecl[i]))
+	}
+	if assessment.OutcomeProcessing != nil {
+		test.OutcomeProcessing = &QTI3OutcomeProcessing{}
+		for _, rule := range assessment.OutcomeProcessing.Rules {
+			test.OutcomeProcessing.Rules = append(test.OutcomeProcessing.Rules, w.migrator.migrateRuleToQTI3(rule))
+		}
+	}
+	test.TestFeedback = w.testFeedback(assessment.TestFeedback)
+
+	// Sections read from a QTI 2.x test stand for its test parts
+	var sections []QTI3AssessmentSection
+	for i := range assessment.Sections {
+		section := &assessment.Sections[i]
+		if section.NavigationMode == "" {
+			sections = append(sections, w.section(section))
+			continue
+		}
+		var partSections []QTI3AssessmentSection
+		for j := range section.Sections {
+			partSections = append(partSections, w.section(&section.Sections[j]))
+		}
+		part := w.testPart(section.Ident, section.NavigationMode, section.SubmissionMode, partSections)
+		part.ItemSessionControl = migrateItemSessionControl(section.ItemSessionControl)
+		part.TimeLimits = migrateTimeLimits(section.TimeLimits)
+		part.TestFeedback = w.testFeedback(section.TestFeedback)
+		test.TestParts = append(test.TestParts, part)
+	}
+	if len(sections) > 0 || len(test.TestParts) == 0 {
+		test.TestParts = append(test.TestParts, w.testPart(testfiles.DefaultTestPart, "", "", sections))
+	}
+
+	// Items outside the assessment get a section of their own
+	if len(doc.Items) > 0 {
+		section := QTI3AssessmentSection{Identifier: "items", Title: "items", Visible: true}
+		for i := range doc.Items {
+			section.Content = append(section.Content, w.itemRef(&doc.Items[i]))
+		}
+		part := &test.TestParts[len(test.TestParts)-1]
+		part.Sections = append(part.Sections, section)
+	}
+	return test
+}
+
+func (w *testWriter) testPart(identifier, navigationMode, submissionMode string, sections []QTI3AssessmentSection) QTI3TestPart {
+	return QTI3TestPart{
+		Identifier:     testfiles.OrDefault(identifier, testfiles.DefaultTestPart),
+		NavigationMode: testfiles.OrDefault(navigationMode, testfiles.DefaultNavigationMode),
+		SubmissionMode: testfiles.OrDefault(submissionMode, testfiles.DefaultSubmissionMode),
+		Sections:       sections,
+	}
+}
+
+func (w *testWriter) section(section *models.Section) QTI3AssessmentSection {
+	w.sections++
+	migrated := QTI3AssessmentSection{
+		Identifier: testfiles.OrDefault(section.Ident, "section"+strconv.Itoa(w.sections)),
+		Visible:    section.Visible == nil || *section.Visible,
+	}
+	migrated.Title = testfiles.OrDefault(section.Title, migrated.Identifier)
+	migrated.ItemSessionControl = migrateItemSessionControl(section.ItemSessionControl)
+	migrated.TimeLimits = migrateTimeLimits(section.TimeLimits)
+	if section.Selection != nil {
+		migrated.Selection = &QTI3Selection{Select: section.Selection.Select, WithReplacement: section.Selection.WithReplacement}
+	}
+	if section.Ordering != nil {
+		migrated.Ordering = &QTI3Ordering{Shuffle: section.Ordering.Shuffle}
+	}
+	for _, rubricBlock := range section.RubricBlocks {
+		migrated.RubricBlocks = append(migrated.RubricBlocks, w.migrator.migrateRubricBlockToQTI3(rubricBlock))
+	}
+
+	// Items of a QTI 2.x test are already in files of their own
+	for _, ref := range section.ItemRefs {
+		w.items.Reserve(ref.Identifier)
+		migrated.Content = append(migrated.Content, QTI3AssessmentItemRef{
+			Identifier:         ref.Identifier,
+			Href:               ref.Href,
+			Category:           ref.Category,
+			ItemSessionControl: migrateItemSessionControl(ref.ItemSessionControl),
+			TimeLimits:         migrateTimeLimits(ref.TimeLimits),
+			Weights:            migrateWeights(ref.Weights),
+		})
+	}
+	for _, child := range section.Children() {
+		switch c := child.(type) {
+		case *models.Item:
+			migrated.Content = append(migrated.Content, w.itemRef(c))
+		case *models.Section:
+			migrated.Content = append(migrated.Content, w.section(c))
+		}
+	}
+	return migrated
+}
+
+// itemRef migrates an item to a file of its own and returns the reference to
+// it. Items sharing an identifier get distinct refs and files.
+func (w *testWriter) itemRef(item *models.Item) QTI3AssessmentItemRef {
+	identifier, href := w.items.Add(item, w.migrator.migrateSingleItem)
+	ref := QTI3AssessmentItemRef{
+		Identifier:         identifier,
+		Href:               href,
+		ItemSessionControl: migrateItemSessionControl(item.ItemSessionControl),
+		TimeLimits:         migrateTimeLimits(item.TimeLimits),
+	}
+	// Legacy 2.1 documents put maxattempts on the item itself
+	if ref.ItemSessionControl == nil && item.MaxAttempts > 0 {
+		attempts := item.MaxAttempts
+		ref.ItemSessionControl = &QTI3ItemSessionControl{MaxAttempts: &attempts}
+	}
+	return ref
+}
+
+func (w *testWriter) testFeedback(feedbacks []models.TestFeedback) []QTI3TestFeedback {
+	var migrated []QTI3TestFeedback
+	for _, feedback := range feedbacks {
+		migrated = append(migrated, QTI3TestFeedback{
+			Access:            feedback.Access,
+			OutcomeIdentifier: feedback.OutcomeIdentifier,
+			ShowHide:          feedback.ShowHide,
+			Identifier:        feedback.Identifier,
+			Title:             feedback.Title,
+			ContentBody:       QTI3ContentBody{Content: w.migrator.updateHTMLContent(feedback.Content)},
+		})
+	}
+	return migrated
+}
+
+func migrateWeights(weights []models.Weight) []QTI3Weight {
+	var migrated []QTI3Weight
+	for _, weight := range weights {
+		migrated = append(migrated, QTI3Weight{Identifier: weight.Identifier, Value: weight.Value})
+	}
+	return migrated
+}
+
+func migrateItemSessionControl(control *models.ItemSessionControl) *QTI3ItemSessionControl {
+	if control == nil {
+		return nil
+	}
+	return &QTI3ItemSessionControl{MaxAttempts: control.MaxAttempts}
+}
+
+func migrateTimeLimits(limits *models.TimeLimits) *QTI3TimeLimits {
+	if limits == nil {
+		return nil
+	}
+	return &QTI3TimeLimits{MinTime: limits.MinTime, MaxTime: limits.MaxTime, AllowLateSubmission: limits.AllowLateSubmission}
+}
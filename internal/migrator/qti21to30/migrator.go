@@ -3,9 +3,11 @@ package qti21to30
 import (
 	"encoding/xml"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/qti-migrator/pkg/models"
+	"github.com/qti-migrator/pkg/registry"
 )
 
 type Migrator21to30 struct{}
@@ -14,33 +16,61 @@ func New() *Migrator21to30 {
 	return &Migrator21to30{}
 }
 
+func init() {
+	err := registry.RegisterStep(registry.Step{
+		From:    "2.1",
+		To:      "3.0",
+		New:     func() registry.Migrator { return New() },
+		Analyze: Analyze,
+	})
+	if err == nil {
+		err = registry.RegisterSerializer(registry.SerializerInfo{
+			Version: "3.0",
+			New:     func() registry.Serializer { return NewSerializer() },
+		})
+	}
+	if err != nil {
+		panic(err)
+	}
+}
+
 // QTI 3.0 specific structs to handle XML naming properly
 type QTI3ItemBody struct {
-	XMLName                 xml.Name                      `xml:"qti-item-body"`
-	P                       []QTI3P                       `xml:"p,omitempty"`
-	Div                     []QTI3Div                     `xml:"div,omitempty"`
-	ChoiceInteraction       []QTI3ChoiceInteraction       `xml:"qti-choice-interaction,omitempty"`
-	TextEntryInteraction    []QTI3TextEntryInteraction    `xml:"qti-text-entry-interaction,omitempty"`
-	ExtendedTextInteraction []QTI3ExtendedTextInteraction `xml:"qti-extended-text-interaction,omitempty"`
+	XMLName xml.Name   `xml:"qti-item-body"`
+	Attrs   []xml.Attr `xml:",any,attr"`
+	// Paragraphs, divs, interactions and raw elements in document order
+	Content []interface{} `xml:",any"`
+}
+
+// QTI3RawElement is a body element the 2.1 model kept as raw markup
+type QTI3RawElement struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Content string     `xml:",innerxml"`
 }
 
 type QTI3P struct {
-	XMLName xml.Name `xml:"p"`
-	Content string   `xml:",innerxml"`
+	XMLName xml.Name   `xml:"p"`
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Content string     `xml:",innerxml"`
 }
 
 type QTI3Div struct {
-	XMLName xml.Name `xml:"div"`
-	Class   string   `xml:"data-qti-class,attr,omitempty"`
-	Content string   `xml:",innerxml"`
+	XMLName xml.Name   `xml:"div"`
+	Class   string     `xml:"class,attr,omitempty"`
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Content string     `xml:",innerxml"`
 }
 
 type QTI3ChoiceInteraction struct {
 	XMLName       xml.Name           `xml:"qti-choice-interaction"`
+	Class         string             `xml:"class,attr,omitempty"`
 	ResponseIdent string             `xml:"response-identifier,attr"`
 	Shuffle       bool               `xml:"shuffle,attr,omitempty"`
 	MaxChoices    int                `xml:"max-choices,attr,omitempty"`
 	MinChoices    int                `xml:"min-choices,attr,omitempty"`
+	Orientation   string             `xml:"orientation,attr,omitempty"`
+	Attrs         []xml.Attr         `xml:",any,attr"`
 	Prompt        *QTI3Prompt        `xml:"qti-prompt,omitempty"`
 	SimpleChoice  []QTI3SimpleChoice `xml:"qti-simple-choice"`
 }
@@ -48,40 +78,100 @@ type QTI3ChoiceInteraction struct {
 type QTI3SimpleChoice struct {
 	XMLName    xml.Name `xml:"qti-simple-choice"`
 	Identifier string   `xml:"identifier,attr"`
-	Fixed      bool     `xml:"fixed,attr,omitempty"`
-	Content    string   `xml:",innerxml"`
+	Fixed      bool       `xml:"fixed,attr,omitempty"`
+	Attrs      []xml.Attr `xml:",any,attr"`
+	Content    string     `xml:",innerxml"`
 }
 
 type QTI3Prompt struct {
-	XMLName xml.Name `xml:"qti-prompt"`
-	Content string   `xml:",innerxml"`
+	XMLName xml.Name   `xml:"qti-prompt"`
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Content string     `xml:",innerxml"`
 }
 
 type QTI3TextEntryInteraction struct {
-	XMLName         xml.Name `xml:"qti-text-entry-interaction"`
-	ResponseIdent   string   `xml:"response-identifier,attr"`
-	ExpectedLength  int      `xml:"expected-length,attr,omitempty"`
-	PatternMask     string   `xml:"pattern-mask,attr,omitempty"`
-	PlaceholderText string   `xml:"placeholder-text,attr,omitempty"`
+	XMLName         xml.Name   `xml:"qti-text-entry-interaction"`
+	Class           string     `xml:"class,attr,omitempty"`
+	ResponseIdent   string     `xml:"response-identifier,attr"`
+	ExpectedLength  int        `xml:"expected-length,attr,omitempty"`
+	PatternMask     string     `xml:"pattern-mask,attr,omitempty"`
+	PlaceholderText string     `xml:"placeholder-text,attr,omitempty"`
+	Attrs           []xml.Attr `xml:",any,attr"`
 }
 
 type QTI3ExtendedTextInteraction struct {
 	XMLName        xml.Name    `xml:"qti-extended-text-interaction"`
+	Class          string      `xml:"class,attr,omitempty"`
 	ResponseIdent  string      `xml:"response-identifier,attr"`
 	MinStrings     int         `xml:"min-strings,attr,omitempty"`
 	MaxStrings     int         `xml:"max-strings,attr,omitempty"`
 	ExpectedLines  int         `xml:"expected-lines,attr,omitempty"`
 	ExpectedLength int         `xml:"expected-length,attr,omitempty"`
+	Attrs          []xml.Attr  `xml:",any,attr"`
 	Prompt         *QTI3Prompt `xml:"qti-prompt,omitempty"`
 }
 
+type QTI3OrderInteraction struct {
+	XMLName       xml.Name           `xml:"qti-order-interaction"`
+	Class         string             `xml:"class,attr,omitempty"`
+	ResponseIdent string             `xml:"response-identifier,attr"`
+	Shuffle       bool               `xml:"shuffle,attr,omitempty"`
+	MaxChoices    int                `xml:"max-choices,attr,omitempty"`
+	MinChoices    int                `xml:"min-choices,attr,omitempty"`
+	Orientation   string             `xml:"orientation,attr,omitempty"`
+	Attrs         []xml.Attr         `xml:",any,attr"`
+	Prompt        *QTI3Prompt        `xml:"qti-prompt,omitempty"`
+	SimpleChoice  []QTI3SimpleChoice `xml:"qti-simple-choice"`
+}
+
+type QTI3MatchInteraction struct {
+	XMLName         xml.Name             `xml:"qti-match-interaction"`
+	Class           string               `xml:"class,attr,omitempty"`
+	ResponseIdent   string               `xml:"response-identifier,attr"`
+	Shuffle         bool                 `xml:"shuffle,attr,omitempty"`
+	MaxAssociations int                  `xml:"max-associations,attr"`
+	MinAssociations int                  `xml:"min-associations,attr,omitempty"`
+	Attrs           []xml.Attr           `xml:",any,attr"`
+	Prompt          *QTI3Prompt          `xml:"qti-prompt,omitempty"`
+	SimpleMatchSet  []QTI3SimpleMatchSet `xml:"qti-simple-match-set"`
+}
+
+type QTI3SimpleMatchSet struct {
+	XMLName                xml.Name                     `xml:"qti-simple-match-set"`
+	Attrs                  []xml.Attr                   `xml:",any,attr"`
+	SimpleAssociableChoice []QTI3SimpleAssociableChoice `xml:"qti-simple-associable-choice"`
+}
+
+type QTI3AssociateInteraction struct {
+	XMLName                xml.Name                     `xml:"qti-associate-interaction"`
+	Class                  string                       `xml:"class,attr,omitempty"`
+	ResponseIdent          string                       `xml:"response-identifier,attr"`
+	Shuffle                bool                         `xml:"shuffle,attr,omitempty"`
+	MaxAssociations        int                          `xml:"max-associations,attr"`
+	MinAssociations        int                          `xml:"min-associations,attr,omitempty"`
+	Attrs                  []xml.Attr                   `xml:",any,attr"`
+	Prompt                 *QTI3Prompt                  `xml:"qti-prompt,omitempty"`
+	SimpleAssociableChoice []QTI3SimpleAssociableChoice `xml:"qti-simple-associable-choice"`
+}
+
+type QTI3SimpleAssociableChoice struct {
+	XMLName    xml.Name   `xml:"qti-simple-associable-choice"`
+	Identifier string     `xml:"identifier,attr"`
+	Fixed      bool       `xml:"fixed,attr,omitempty"`
+	MatchMax   int        `xml:"match-max,attr"`
+	MatchMin   int        `xml:"match-min,attr,omitempty"`
+	Attrs      []xml.Attr `xml:",any,attr"`
+	Content    string     `xml:",innerxml"`
+}
+
 type QTI3ResponseDecl struct {
-	XMLName         xml.Name              `xml:"qti-response-declaration"`
-	Identifier      string                `xml:"identifier,attr"`
-	Cardinality     string                `xml:"cardinality,attr"`
-	BaseType        string                `xml:"base-type,attr,omitempty"`
-	CorrectResponse *QTI3CorrectResponse  `xml:"qti-correct-response,omitempty"`
-	Mapping         *QTI3Mapping          `xml:"qti-mapping,omitempty"`
+	XMLName         xml.Name             `xml:"qti-response-declaration"`
+	Identifier      string               `xml:"identifier,attr"`
+	Cardinality     string               `xml:"cardinality,attr"`
+	BaseType        string               `xml:"base-type,attr,omitempty"`
+	CorrectResponse *QTI3CorrectResponse `xml:"qti-correct-response,omitempty"`
+	Mapping         *QTI3Mapping         `xml:"qti-mapping,omitempty"`
+	AreaMapping     *QTI3AreaMapping     `xml:"qti-area-mapping,omitempty"`
 }
 
 type QTI3CorrectResponse struct {
@@ -90,15 +180,17 @@ type QTI3CorrectResponse struct {
 }
 
 type QTI3Value struct {
-	XMLName xml.Name `xml:"qti-value"`
-	Content string   `xml:",chardata"`
+	XMLName         xml.Name `xml:"qti-value"`
+	FieldIdentifier string   `xml:"field-identifier,attr,omitempty"`
+	BaseType        string   `xml:"base-type,attr,omitempty"`
+	Content         string   `xml:",chardata"`
 }
 
 type QTI3Mapping struct {
 	XMLName      xml.Name       `xml:"qti-mapping"`
-	LowerBound   float64        `xml:"lower-bound,attr,omitempty"`
-	UpperBound   float64        `xml:"upper-bound,attr,omitempty"`
-	DefaultValue float64        `xml:"default-value,attr,omitempty"`
+	LowerBound   *float64       `xml:"lower-bound,attr,omitempty"`
+	UpperBound   *float64       `xml:"upper-bound,attr,omitempty"`
+	DefaultValue float64        `xml:"default-value,attr"`
 	MapEntry     []QTI3MapEntry `xml:"qti-map-entry"`
 }
 
@@ -108,12 +200,43 @@ type QTI3MapEntry struct {
 	MappedValue float64  `xml:"mapped-value,attr"`
 }
 
+type QTI3AreaMapping struct {
+	XMLName      xml.Name           `xml:"qti-area-mapping"`
+	LowerBound   float64            `xml:"lower-bound,attr,omitempty"`
+	UpperBound   float64            `xml:"upper-bound,attr,omitempty"`
+	DefaultValue float64            `xml:"default-value,attr"`
+	AreaMapEntry []QTI3AreaMapEntry `xml:"qti-area-map-entry"`
+}
+
+type QTI3AreaMapEntry struct {
+	XMLName     xml.Name `xml:"qti-area-map-entry"`
+	Shape       string   `xml:"shape,attr"`
+	Coords      string   `xml:"coords,attr"`
+	MappedValue float64  `xml:"mapped-value,attr"`
+}
+
 type QTI3OutcomeDecl struct {
-	XMLName      xml.Name          `xml:"qti-outcome-declaration"`
-	Identifier   string            `xml:"identifier,attr"`
-	Cardinality  string            `xml:"cardinality,attr"`
-	BaseType     string            `xml:"base-type,attr,omitempty"`
-	DefaultValue *QTI3DefaultValue `xml:"qti-default-value,omitempty"`
+	XMLName            xml.Name          `xml:"qti-outcome-declaration"`
+	Identifier         string            `xml:"identifier,attr"`
+	Cardinality        string            `xml:"cardinality,attr"`
+	BaseType           string            `xml:"base-type,attr,omitempty"`
+	View               string            `xml:"view,attr,omitempty"`
+	Interpretation     string            `xml:"interpretation,attr,omitempty"`
+	LongInterpretation string            `xml:"long-interpretation,attr,omitempty"`
+	NormalMaximum      *float64          `xml:"normal-maximum,attr,omitempty"`
+	NormalMinimum      *float64          `xml:"normal-minimum,attr,omitempty"`
+	MasteryValue       *float64          `xml:"mastery-value,attr,omitempty"`
+	DefaultValue       *QTI3DefaultValue `xml:"qti-default-value,omitempty"`
+}
+
+type QTI3TemplateDecl struct {
+	XMLName       xml.Name          `xml:"qti-template-declaration"`
+	Identifier    string            `xml:"identifier,attr"`
+	Cardinality   string            `xml:"cardinality,attr"`
+	BaseType      string            `xml:"base-type,attr,omitempty"`
+	ParamVariable bool              `xml:"param-variable,attr,omitempty"`
+	MathVariable  bool              `xml:"math-variable,attr,omitempty"`
+	DefaultValue  *QTI3DefaultValue `xml:"qti-default-value,omitempty"`
 }
 
 type QTI3DefaultValue struct {
@@ -122,55 +245,155 @@ type QTI3DefaultValue struct {
 }
 
 type QTI3Feedback struct {
-	XMLName xml.Name `xml:"qti-modal-feedback"`
-	Ident   string   `xml:"identifier,attr"`
+	XMLName      xml.Name `xml:"qti-modal-feedback"`
+	OutcomeIdent string   `xml:"outcome-identifier,attr,omitempty"`
+	Ident        string   `xml:"identifier,attr"`
+	ShowHide     string   `xml:"show-hide,attr,omitempty"`
+	Title        string   `xml:"title,attr,omitempty"`
+	Content      string   `xml:",innerxml"`
+}
+
+type QTI3ResponseProcessing struct {
+	XMLName          xml.Name         `xml:"qti-response-processing"`
+	Template         string           `xml:"template,attr,omitempty"`
+	TemplateLocation string           `xml:"template-location,attr,omitempty"`
+	Rules            []models.XMLNode `xml:",any"`
+}
+
+type QTI3TemplateProcessing struct {
+	XMLName xml.Name         `xml:"qti-template-processing"`
+	Rules   []models.XMLNode `xml:",any"`
+}
+
+type QTI3Stylesheet struct {
+	XMLName xml.Name `xml:"qti-stylesheet"`
+	Href    string   `xml:"href,attr"`
+	Type    string   `xml:"type,attr"`
+	Media   string   `xml:"media,attr,omitempty"`
 	Title   string   `xml:"title,attr,omitempty"`
-	Content string   `xml:",innerxml"`
 }
 
 // QTI3Item represents a QTI 3.0 assessment item
 type QTI3Item struct {
-	XMLName         xml.Name                      `xml:"http://www.imsglobal.org/xsd/imsqtiasi_v3p0 qti-assessment-item"`
-	Identifier      string                        `xml:"identifier,attr"`
-	Title           string                        `xml:"title,attr"`
-	Adaptive        string                        `xml:"adaptive,attr,omitempty"`
-	TimeDependent   string                        `xml:"time-dependent,attr,omitempty"`
-	ResponseDecl    []QTI3ResponseDecl            `xml:"qti-response-declaration,omitempty"`
-	OutcomeDecl     []QTI3OutcomeDecl             `xml:"qti-outcome-declaration,omitempty"`
-	ItemBody        *QTI3ItemBody                 `xml:"qti-item-body,omitempty"`
-	Feedback        []QTI3Feedback                `xml:"qti-modal-feedback,omitempty"`
+	XMLName            xml.Name                `xml:"http://www.imsglobal.org/xsd/imsqtiasi_v3p0 qti-assessment-item"`
+	Identifier         string                  `xml:"identifier,attr"`
+	Title              string                  `xml:"title,attr"`
+	Adaptive           string                  `xml:"adaptive,attr,omitempty"`
+	TimeDependent      string                  `xml:"time-dependent,attr,omitempty"`
+	ToolName           string                  `xml:"tool-name,attr,omitempty"`
+	ToolVersion        string                  `xml:"tool-version,attr,omitempty"`
+	ResponseDecl       []QTI3ResponseDecl      `xml:"qti-response-declaration,omitempty"`
+	OutcomeDecl        []QTI3OutcomeDecl       `xml:"qti-outcome-declaration,omitempty"`
+	TemplateDecl       []QTI3TemplateDecl      `xml:"qti-template-declaration,omitempty"`
+	TemplateProcessing *QTI3TemplateProcessing `xml:"qti-template-processing,omitempty"`
+	Stylesheets        []QTI3Stylesheet        `xml:"qti-stylesheet,omitempty"`
+	ItemBody           *QTI3ItemBody           `xml:"qti-item-body,omitempty"`
+	ResponseProcessing *QTI3ResponseProcessing `xml:"qti-response-processing,omitempty"`
+	Feedback           []QTI3Feedback          `xml:"qti-modal-feedback,omitempty"`
 }
 
-func (m *Migrator21to30) Migrate(doc interface{}) ([]byte, error) {
-	qtiDoc, ok := doc.(*models.QTIDocument)
-	if !ok {
-		return nil, fmt.Errorf("invalid document type for QTI 2.1 to 3.0 migration")
+// Migrate migrates a document of the QTI 2.1 model to QTI 3.0. Both versions
+// share the model, so items and tests are copied as they are and
+// Serializer30 writes them in the QTI 3.0 vocabulary. A document without
+// items only carries metadata and is migrated as a whole.
+func (m *Migrator21to30) Migrate(doc *models.QTIDocument, options registry.MigrationOptions) (*models.QTIDocument, error) {
+	if len(doc.Items) == 0 && doc.Assessment == nil {
+		return nil, fmt.Errorf("document holds no items or assessment test to migrate to QTI 3.0")
+	}
+	if options.Strict {
+		if err := checkStrict(doc); err != nil {
+			return nil, err
+		}
 	}
 
-	// For simplicity, we'll handle single item documents with the proper QTI 3.0 structure
-	if len(qtiDoc.Items) == 1 && qtiDoc.Assessment == nil {
-		return m.migrateSingleItem(&qtiDoc.Items[0])
+	migrated := copyDocument(doc)
+	migrated.Version = "3.0"
+	if err := options.Identifiers.Apply(migrated); err != nil {
+		return nil, err
 	}
+	return migrated, nil
+}
 
-	// For documents with assessments or multiple items, create a proper QTI 3.0 structure
-	// But for now, just migrate using the document structure and apply QTI 3.0 element names
-	migratedDoc := m.migrateDocument(qtiDoc)
+// copyDocument copies the items and sections of doc, so that the copy can be
+// changed without changing doc.
+func copyDocument(doc *models.QTIDocument) *models.QTIDocument {
+	copied := *doc
+	copied.Items = append([]models.Item(nil), doc.Items...)
+	if doc.Assessment != nil {
+		assessment := *doc.Assessment
+		assessment.Sections = copySections(doc.Assessment.Sections)
+		copied.Assessment = &assessment
+	}
+	return &copied
+}
 
-	output, err := xml.MarshalIndent(migratedDoc, "", "  ")
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal migrated document: %w", err)
+func copySections(sections []models.Section) []models.Section {
+	var copied []models.Section
+	for _, section := range sections {
+		section.ItemRefs = append([]models.ItemRef(nil), section.ItemRefs...)
+		section.Items = append([]models.Item(nil), section.Items...)
+		section.Sections = copySections(section.Sections)
+		copied = append(copied, section)
 	}
+	return copied
+}
 
-	xmlHeader := []byte(xml.Header)
-	return append(xmlHeader, output...), nil
+// checkStrict fails on body markup that is not well-formed XML, which a
+// migration has to repair before it can rewrite it.
+func checkStrict(doc *models.QTIDocument) error {
+	items := make([]*models.Item, 0, len(doc.Items))
+	for i := range doc.Items {
+		items = append(items, &doc.Items[i])
+	}
+	var sections []*models.Section
+	if doc.Assessment != nil {
+		for i := range doc.Assessment.Sections {
+			sections = append(sections, &doc.Assessment.Sections[i])
+		}
+	}
+	for len(sections) > 0 {
+		section := sections[0]
+		sections = sections[1:]
+		for i := range section.Items {
+			items = append(items, &section.Items[i])
+		}
+		for i := range section.Sections {
+			sections = append(sections, &section.Sections[i])
+		}
+	}
+
+	for _, item := range items {
+		if item.ItemBody == nil {
+			continue
+		}
+		for _, element := range item.ItemBody.Elements() {
+			var content string
+			switch e := element.(type) {
+			case models.P:
+				content = e.Content
+			case models.Div:
+				content = e.Content
+			case models.RawElement21:
+				content = e.Content
+			default:
+				continue
+			}
+			if _, _, ok := rewriteContent(content); !ok {
+				return fmt.Errorf("item %s: body markup is not well-formed XML and cannot be rewritten to QTI 3.0", item.Ident)
+			}
+		}
+	}
+	return nil
 }
 
 func (m *Migrator21to30) migrateSingleItem(item *models.Item) ([]byte, error) {
 	qti3Item := QTI3Item{
 		Identifier:    item.Ident,
 		Title:         item.Title,
-		Adaptive:      "false",
-		TimeDependent: "false",
+		Adaptive:      strconv.FormatBool(item.Adaptive),
+		TimeDependent: strconv.FormatBool(item.TimeDependent),
+		ToolName:      item.ToolName,
+		ToolVersion:   item.ToolVersion,
 	}
 
 	// Migrate response declarations
@@ -183,11 +406,39 @@ func (m *Migrator21to30) migrateSingleItem(item *models.Item) ([]byte, error) {
 		qti3Item.OutcomeDecl = append(qti3Item.OutcomeDecl, m.migrateOutcomeDeclarationToQTI3(&decl))
 	}
 
+	// Migrate template declarations
+	for _, decl := range item.TemplateDecl {
+		qti3Item.TemplateDecl = append(qti3Item.TemplateDecl, m.migrateTemplateDeclarationToQTI3(&decl))
+	}
+
+	// Migrate template processing
+	if item.TemplateProcessing != nil {
+		qti3Item.TemplateProcessing = &QTI3TemplateProcessing{}
+		for _, rule := range item.TemplateProcessing.Rules {
+			qti3Item.TemplateProcessing.Rules = append(qti3Item.TemplateProcessing.Rules, m.migrateRuleToQTI3(rule))
+		}
+	}
+
+	// Migrate stylesheets
+	for _, stylesheet := range item.Stylesheets {
+		qti3Item.Stylesheets = append(qti3Item.Stylesheets, QTI3Stylesheet{
+			Href:  stylesheet.Href,
+			Type:  stylesheet.Type,
+			Media: stylesheet.Media,
+			Title: stylesheet.Title,
+		})
+	}
+
 	// Migrate item body
 	if item.ItemBody != nil {
 		qti3Item.ItemBody = m.migrateItemBodyToQTI3(item.ItemBody)
 	}
 
+	// Migrate response processing
+	if item.ResponseProcessing != nil {
+		qti3Item.ResponseProcessing = m.migrateResponseProcessingToQTI3(item.ResponseProcessing)
+	}
+
 	// Migrate feedback
 	for _, feedback := range item.Feedback {
 		qti3Item.Feedback = append(qti3Item.Feedback, m.migrateFeedbackToQTI3(&feedback))
@@ -202,38 +453,6 @@ func (m *Migrator21to30) migrateSingleItem(item *models.Item) ([]byte, error) {
 	return append(xmlHeader, output...), nil
 }
 
-func (m *Migrator21to30) migrateDocument(doc *models.QTIDocument) *models.QTIDocument {
-	// For QTI 3.0, the root element changes based on content
-	rootName := "questestinterop"
-	if doc.Assessment != nil {
-		// Assessment document becomes qti-assessment-test
-		rootName = "qti-assessment-test"
-	}
-	
-	migratedDoc := &models.QTIDocument{
-		XMLName: xml.Name{
-			Space: "http://www.imsglobal.org/xsd/imsqtiasi_v3p0",
-			Local: rootName,
-		},
-		Version: "3.0",
-	}
-
-	for _, item := range doc.Items {
-		migratedItem := m.migrateItem(&item)
-		migratedDoc.Items = append(migratedDoc.Items, *migratedItem)
-	}
-
-	if doc.Assessment != nil {
-		migratedDoc.Assessment = m.migrateAssessment(doc.Assessment)
-	}
-
-	if doc.Metadata != nil {
-		migratedDoc.Metadata = m.migrateMetadata(doc.Metadata)
-	}
-
-	return migratedDoc
-}
-
 func (m *Migrator21to30) migrateAssessment(assessment *models.Assessment) *models.Assessment {
 	migratedAssessment := &models.Assessment{
 		XMLName:     xml.Name{Local: "qti-assessment-test"},
@@ -271,6 +490,12 @@ func (m *Migrator21to30) migrateSection(section *models.Section) *models.Section
 		migratedSection.Items = append(migratedSection.Items, *migratedItem)
 	}
 
+	for _, subSection := range section.Sections {
+		migratedSection.Sections = append(migratedSection.Sections, *m.migrateSection(&subSection))
+	}
+
+	migratedSection.ItemRefs = section.ItemRefs
+
 	return migratedSection
 }
 
@@ -385,33 +610,37 @@ func (m *Migrator21to30) migrateInteractionType(interactionType string) string {
 func (m *Migrator21to30) migrateItemBody(itemBody *models.ItemBody) *models.ItemBody {
 	migratedItemBody := &models.ItemBody{
 		XMLName: xml.Name{Local: "qti-item-body"},
+		Attrs:   itemBody.Attrs,
 	}
 
-	for _, p := range itemBody.P {
-		migratedItemBody.P = append(migratedItemBody.P, models.P{
-			XMLName: xml.Name{Local: "p"},
-			Content: m.updateHTMLContent(p.Content),
-		})
-	}
-
-	for _, div := range itemBody.Div {
-		migratedItemBody.Div = append(migratedItemBody.Div, models.Div{
-			XMLName: xml.Name{Local: "div"},
-			Class:   div.Class,
-			Content: m.updateHTMLContent(div.Content),
-		})
-	}
-
-	for _, interaction := range itemBody.ChoiceInteraction {
-		migratedItemBody.ChoiceInteraction = append(migratedItemBody.ChoiceInteraction, m.migrateChoiceInteraction(&interaction))
-	}
-
-	for _, interaction := range itemBody.TextEntryInteraction {
-		migratedItemBody.TextEntryInteraction = append(migratedItemBody.TextEntryInteraction, m.migrateTextEntryInteraction(&interaction))
-	}
-
-	for _, interaction := range itemBody.ExtendedTextInteraction {
-		migratedItemBody.ExtendedTextInteraction = append(migratedItemBody.ExtendedTextInteraction, m.migrateExtendedTextInteraction(&interaction))
+	for _, element := range itemBody.Elements() {
+		switch e := element.(type) {
+		case models.P:
+			migratedItemBody.Append(models.P{
+				XMLName: xml.Name{Local: "p"},
+				Content: m.updateHTMLContent(e.Content),
+			})
+		case models.Div:
+			migratedItemBody.Append(models.Div{
+				XMLName: xml.Name{Local: "div"},
+				Class:   elementClass(e),
+				Content: m.updateHTMLContent(e.Content),
+			})
+		case models.ChoiceInteraction:
+			migratedItemBody.Append(m.migrateChoiceInteraction(&e))
+		case models.TextEntryInteraction:
+			migratedItemBody.Append(m.migrateTextEntryInteraction(&e))
+		case models.ExtendedTextInteraction:
+			migratedItemBody.Append(m.migrateExtendedTextInteraction(&e))
+		case models.OrderInteraction:
+			migratedItemBody.Append(rawElement(m.migrateOrderInteractionToQTI3(&e)))
+		case models.MatchInteraction:
+			migratedItemBody.Append(rawElement(m.migrateMatchInteractionToQTI3(&e)))
+		case models.AssociateInteraction:
+			migratedItemBody.Append(rawElement(m.migrateAssociateInteractionToQTI3(&e)))
+		case models.RawElement21:
+			migratedItemBody.Append(m.migrateRawElement(e))
+		}
 	}
 
 	return migratedItemBody
@@ -420,10 +649,13 @@ func (m *Migrator21to30) migrateItemBody(itemBody *models.ItemBody) *models.Item
 func (m *Migrator21to30) migrateChoiceInteraction(interaction *models.ChoiceInteraction) models.ChoiceInteraction {
 	migratedInteraction := models.ChoiceInteraction{
 		XMLName:       xml.Name{Local: "qti-choice-interaction"},
+		Class:         elementClass(*interaction),
 		ResponseIdent: interaction.ResponseIdent,
 		Shuffle:       interaction.Shuffle,
 		MaxChoices:    interaction.MaxChoices,
+		MaxChoicesSet: interaction.MaxChoicesSet,
 		MinChoices:    interaction.MinChoices,
+		Orientation:   interaction.Orientation,
 	}
 
 	if interaction.Prompt != nil {
@@ -448,6 +680,7 @@ func (m *Migrator21to30) migrateChoiceInteraction(interaction *models.ChoiceInte
 func (m *Migrator21to30) migrateTextEntryInteraction(interaction *models.TextEntryInteraction) models.TextEntryInteraction {
 	return models.TextEntryInteraction{
 		XMLName:         xml.Name{Local: "qti-text-entry-interaction"},
+		Class:           elementClass(*interaction),
 		ResponseIdent:   interaction.ResponseIdent,
 		ExpectedLength:  interaction.ExpectedLength,
 		PatternMask:     interaction.PatternMask,
@@ -458,6 +691,7 @@ func (m *Migrator21to30) migrateTextEntryInteraction(interaction *models.TextEnt
 func (m *Migrator21to30) migrateExtendedTextInteraction(interaction *models.ExtendedTextInteraction) models.ExtendedTextInteraction {
 	migratedInteraction := models.ExtendedTextInteraction{
 		XMLName:        xml.Name{Local: "qti-extended-text-interaction"},
+		Class:          elementClass(*interaction),
 		ResponseIdent:  interaction.ResponseIdent,
 		MinStrings:     interaction.MinStrings,
 		MaxStrings:     interaction.MaxStrings,
@@ -486,7 +720,7 @@ func (m *Migrator21to30) migrateResponseDeclaration(decl *models.ResponseDecl) m
 	if decl.CorrectResponse != nil {
 		migratedDecl.CorrectResponse = &models.CorrectResponse{
 			XMLName: xml.Name{Local: "qti-correct-response"},
-			Value:   decl.CorrectResponse.Value,
+			Value:   m.migrateValues(decl.CorrectResponse.Value),
 		}
 	}
 
@@ -494,6 +728,8 @@ func (m *Migrator21to30) migrateResponseDeclaration(decl *models.ResponseDecl) m
 		migratedDecl.Mapping = m.migrateMapping(decl.Mapping)
 	}
 
+	migratedDecl.AreaMapping = decl.AreaMapping
+
 	return migratedDecl
 }
 
@@ -543,16 +779,22 @@ func (m *Migrator21to30) migrateMapping(mapping *models.Mapping) *models.Mapping
 
 func (m *Migrator21to30) migrateOutcomeDeclaration(decl *models.OutcomeDecl) models.OutcomeDecl {
 	migratedDecl := models.OutcomeDecl{
-		XMLName:     xml.Name{Local: "qti-outcome-declaration"},
-		Identifier:  decl.Identifier,
-		Cardinality: decl.Cardinality,
-		BaseType:    m.migrateBaseType(decl.BaseType),
+		XMLName:            xml.Name{Local: "qti-outcome-declaration"},
+		Identifier:         decl.Identifier,
+		Cardinality:        decl.Cardinality,
+		BaseType:           m.migrateBaseType(decl.BaseType),
+		View:               migrateViews(decl.View),
+		Interpretation:     decl.Interpretation,
+		LongInterpretation: decl.LongInterpretation,
+		NormalMaximum:      decl.NormalMaximum,
+		NormalMinimum:      decl.NormalMinimum,
+		MasteryValue:       decl.MasteryValue,
 	}
 
 	if decl.DefaultValue != nil {
 		migratedDecl.DefaultValue = &models.DefaultValue{
 			XMLName: xml.Name{Local: "qti-default-value"},
-			Value:   decl.DefaultValue.Value,
+			Value:   m.migrateValues(decl.DefaultValue.Value),
 		}
 	}
 
@@ -566,23 +808,39 @@ func (m *Migrator21to30) migrateTemplateDeclaration(decl *models.TemplateDecl) m
 		Cardinality:   decl.Cardinality,
 		BaseType:      m.migrateBaseType(decl.BaseType),
 		ParamVariable: decl.ParamVariable,
+		MathVariable:  decl.MathVariable,
 	}
 
 	if decl.DefaultValue != nil {
 		migratedDecl.DefaultValue = &models.DefaultValue{
 			XMLName: xml.Name{Local: "qti-default-value"},
-			Value:   decl.DefaultValue.Value,
+			Value:   m.migrateValues(decl.DefaultValue.Value),
 		}
 	}
 
 	return migratedDecl
 }
 
+func (m *Migrator21to30) migrateValues(values []models.Value) []models.Value {
+	var migrated []models.Value
+	for _, value := range values {
+		migrated = append(migrated, models.Value{
+			XMLName:         xml.Name{Local: "qti-value"},
+			FieldIdentifier: value.FieldIdentifier,
+			BaseType:        m.migrateBaseType(value.BaseType),
+			Content:         value.Content,
+		})
+	}
+	return migrated
+}
+
 func (m *Migrator21to30) migrateFeedback(feedback *models.Feedback) models.Feedback {
 	migratedFeedback := models.Feedback{
-		XMLName: xml.Name{Local: "qti-modal-feedback"},
-		Ident:   feedback.Ident,
-		Title:   feedback.Title,
+		XMLName:           xml.Name{Local: "qti-modal-feedback"},
+		Ident:             feedback.Ident,
+		Title:             feedback.Title,
+		OutcomeIdentifier: feedback.OutcomeIdentifier,
+		ShowHide:          feedback.ShowHide,
 	}
 
 	if feedback.Material != nil {
@@ -634,12 +892,39 @@ func (m *Migrator21to30) migrateRubricBlock(rubricBlock *models.RubricBlock) *mo
 	return &models.RubricBlock{
 		XMLName: xml.Name{Local: "qti-rubric-block"},
 		Use:     rubricBlock.Use,
-		View:    m.migrateView(rubricBlock.View),
+		View:    migrateView(rubricBlock.View),
 		Content: m.updateHTMLContent(rubricBlock.Content),
 	}
 }
 
-func (m *Migrator21to30) migrateView(view string) string {
+// migrateRubricBlockToQTI3 migrates a rubric block, whose content QTI 3.0
+// wraps in qti-content-body.
+func (m *Migrator21to30) migrateRubricBlockToQTI3(rubricBlock models.RubricBlock) QTI3RubricBlock {
+	return QTI3RubricBlock{
+		Use:         rubricBlock.Use,
+		View:        migrateViews(rubricBlock.View),
+		ContentBody: QTI3ContentBody{Content: m.updateHTMLContent(rubricBlock.Content)},
+	}
+}
+
+// migrateBodyRubricBlockToQTI3 migrates a rubric block of an item body,
+// which the model keeps as raw markup.
+func (m *Migrator21to30) migrateBodyRubricBlockToQTI3(element models.RawElement21) QTI3RubricBlock {
+	rubricBlock := m.migrateRubricBlockToQTI3(models.RubricBlock{
+		Use:     attrValue(element.Attrs, "use"),
+		View:    attrValue(element.Attrs, "view"),
+		Content: element.Content,
+	})
+	for _, attr := range element.Attrs {
+		if attr.Name.Space == "" && (attr.Name.Local == "use" || attr.Name.Local == "view") {
+			continue
+		}
+		rubricBlock.Attrs = append(rubricBlock.Attrs, migrateAttrs([]xml.Attr{attr})...)
+	}
+	return rubricBlock
+}
+
+func migrateView(view string) string {
 	switch view {
 	case "author":
 		return "author"
@@ -658,67 +943,103 @@ func (m *Migrator21to30) migrateView(view string) string {
 	}
 }
 
+// updateHTMLContent rewrites body markup to QTI 3.0, see rewriteBody.
 func (m *Migrator21to30) updateHTMLContent(content string) string {
-	content = strings.ReplaceAll(content, "class=", "data-qti-class=")
-	
-	content = strings.ReplaceAll(content, "<object", "<qti-object")
-	content = strings.ReplaceAll(content, "</object>", "</qti-object>")
-	
-	return content
+	rewritten, _ := rewriteBody(content)
+	return rewritten
 }
 
 // Migration functions for QTI3 types
 func (m *Migrator21to30) migrateItemBodyToQTI3(itemBody *models.ItemBody) *QTI3ItemBody {
-	qti3ItemBody := &QTI3ItemBody{}
-
-	for _, p := range itemBody.P {
-		qti3ItemBody.P = append(qti3ItemBody.P, QTI3P{
-			Content: m.updateHTMLContent(p.Content),
-		})
+	qti3ItemBody := &QTI3ItemBody{Attrs: itemBody.Attrs}
+
+	for _, element := range itemBody.Elements() {
+		switch e := element.(type) {
+		case models.P:
+			qti3ItemBody.Content = append(qti3ItemBody.Content, QTI3P{
+				Attrs:   e.Attrs,
+				Content: m.updateHTMLContent(e.Content),
+			})
+		case models.Div:
+			qti3ItemBody.Content = append(qti3ItemBody.Content, QTI3Div{
+				Class:   elementClass(e),
+				Attrs:   e.Attrs,
+				Content: m.updateHTMLContent(e.Content),
+			})
+		case models.ChoiceInteraction:
+			qti3ItemBody.Content = append(qti3ItemBody.Content, m.migrateChoiceInteractionToQTI3(&e))
+		case models.TextEntryInteraction:
+			qti3ItemBody.Content = append(qti3ItemBody.Content, m.migrateTextEntryInteractionToQTI3(&e))
+		case models.ExtendedTextInteraction:
+			qti3ItemBody.Content = append(qti3ItemBody.Content, m.migrateExtendedTextInteractionToQTI3(&e))
+		case models.OrderInteraction:
+			qti3ItemBody.Content = append(qti3ItemBody.Content, m.migrateOrderInteractionToQTI3(&e))
+		case models.MatchInteraction:
+			qti3ItemBody.Content = append(qti3ItemBody.Content, m.migrateMatchInteractionToQTI3(&e))
+		case models.AssociateInteraction:
+			qti3ItemBody.Content = append(qti3ItemBody.Content, m.migrateAssociateInteractionToQTI3(&e))
+		case models.RawElement21:
+			if e.XMLName.Space == "" && e.XMLName.Local == "rubricBlock" {
+				qti3ItemBody.Content = append(qti3ItemBody.Content, m.migrateBodyRubricBlockToQTI3(e))
+				continue
+			}
+			raw := m.migrateRawElement(e)
+			qti3ItemBody.Content = append(qti3ItemBody.Content, QTI3RawElement(raw))
+		}
 	}
 
-	for _, div := range itemBody.Div {
-		qti3ItemBody.Div = append(qti3ItemBody.Div, QTI3Div{
-			Class:   div.Class,
-			Content: m.updateHTMLContent(div.Content),
-		})
-	}
+	return qti3ItemBody
+}
 
-	for _, interaction := range itemBody.ChoiceInteraction {
-		qti3ItemBody.ChoiceInteraction = append(qti3ItemBody.ChoiceInteraction, m.migrateChoiceInteractionToQTI3(&interaction))
+// migrateRawElement renames a QTI element kept as raw markup, such as a
+// hotspotInteraction, and its attributes to the QTI 3.0 vocabulary. Elements
+// of other namespaces, such as MathML, keep their namespace.
+func (m *Migrator21to30) migrateRawElement(element models.RawElement21) models.RawElement21 {
+	name := element.XMLName.Local
+	migrated := models.RawElement21{
+		XMLName: element.XMLName,
+		Content: m.updateHTMLContent(element.Content),
 	}
-
-	for _, interaction := range itemBody.TextEntryInteraction {
-		qti3ItemBody.TextEntryInteraction = append(qti3ItemBody.TextEntryInteraction, m.migrateTextEntryInteractionToQTI3(&interaction))
+	attrs := append([]xml.Attr(nil), element.Attrs...)
+	if class := elementClass(element); class != attrValue(attrs, "class") {
+		attrs = withClass(attrs, class)
 	}
-
-	for _, interaction := range itemBody.ExtendedTextInteraction {
-		qti3ItemBody.ExtendedTextInteraction = append(qti3ItemBody.ExtendedTextInteraction, m.migrateExtendedTextInteractionToQTI3(&interaction))
+	if element.XMLName.Space != "" || !isQTIElement(name) {
+		migrated.Attrs = attrs
+		return migrated
 	}
 
-	return qti3ItemBody
+	migrated.XMLName.Local = qtiElementName(name)
+	migrated.Attrs = migrateAttrs(attrs)
+	return migrated
+}
+
+// migrateAttrs renames the attributes of a QTI element, such as
+// templateIdentifier, to the QTI 3.0 vocabulary.
+func migrateAttrs(attrs []xml.Attr) []xml.Attr {
+	var migrated []xml.Attr
+	for _, attr := range attrs {
+		migrated = append(migrated, xml.Attr{Name: xml.Name{Space: attr.Name.Space, Local: camelToKebab(attr.Name.Local)}, Value: attr.Value})
+	}
+	return migrated
 }
 
 func (m *Migrator21to30) migrateChoiceInteractionToQTI3(interaction *models.ChoiceInteraction) QTI3ChoiceInteraction {
 	qti3Interaction := QTI3ChoiceInteraction{
+		Class:         elementClass(*interaction),
 		ResponseIdent: interaction.ResponseIdent,
 		Shuffle:       interaction.Shuffle,
 		MaxChoices:    interaction.MaxChoices,
 		MinChoices:    interaction.MinChoices,
+		Orientation:   interaction.Orientation,
+		Attrs:         migrateAttrs(interaction.Attrs),
+		Prompt:        m.migratePromptToQTI3(interaction.Prompt),
+		SimpleChoice:  m.migrateSimpleChoicesToQTI3(interaction.SimpleChoice),
 	}
-
-	if interaction.Prompt != nil {
-		qti3Interaction.Prompt = &QTI3Prompt{
-			Content: m.updateHTMLContent(interaction.Prompt.Content),
-		}
-	}
-
-	for _, choice := range interaction.SimpleChoice {
-		qti3Interaction.SimpleChoice = append(qti3Interaction.SimpleChoice, QTI3SimpleChoice{
-			Identifier: choice.Identifier,
-			Fixed:      choice.Fixed,
-			Content:    m.updateHTMLContent(choice.Content),
-		})
+	// An explicit maxChoices of 0 allows any number of choices, unlike the
+	// default of 1 an omitted attribute means
+	if interaction.MaxChoicesSet && interaction.MaxChoices == 0 {
+		qti3Interaction.Attrs = append([]xml.Attr{{Name: xml.Name{Local: "max-choices"}, Value: "0"}}, qti3Interaction.Attrs...)
 	}
 
 	return qti3Interaction
@@ -726,31 +1047,132 @@ func (m *Migrator21to30) migrateChoiceInteractionToQTI3(interaction *models.Choi
 
 func (m *Migrator21to30) migrateTextEntryInteractionToQTI3(interaction *models.TextEntryInteraction) QTI3TextEntryInteraction {
 	return QTI3TextEntryInteraction{
+		Class:           elementClass(*interaction),
 		ResponseIdent:   interaction.ResponseIdent,
 		ExpectedLength:  interaction.ExpectedLength,
 		PatternMask:     interaction.PatternMask,
 		PlaceholderText: interaction.PlaceholderText,
+		Attrs:           migrateAttrs(interaction.Attrs),
 	}
 }
 
 func (m *Migrator21to30) migrateExtendedTextInteractionToQTI3(interaction *models.ExtendedTextInteraction) QTI3ExtendedTextInteraction {
 	qti3Interaction := QTI3ExtendedTextInteraction{
+		Class:          elementClass(*interaction),
 		ResponseIdent:  interaction.ResponseIdent,
 		MinStrings:     interaction.MinStrings,
 		MaxStrings:     interaction.MaxStrings,
 		ExpectedLines:  interaction.ExpectedLines,
 		ExpectedLength: interaction.ExpectedLength,
+		Attrs:          migrateAttrs(interaction.Attrs),
+		Prompt:         m.migratePromptToQTI3(interaction.Prompt),
 	}
 
-	if interaction.Prompt != nil {
-		qti3Interaction.Prompt = &QTI3Prompt{
-			Content: m.updateHTMLContent(interaction.Prompt.Content),
-		}
+	return qti3Interaction
+}
+
+func (m *Migrator21to30) migrateOrderInteractionToQTI3(interaction *models.OrderInteraction) QTI3OrderInteraction {
+	qti3Interaction := QTI3OrderInteraction{
+		Class:         elementClass(*interaction),
+		ResponseIdent: interaction.ResponseIdent,
+		Shuffle:       interaction.Shuffle,
+		MaxChoices:    interaction.MaxChoices,
+		MinChoices:    interaction.MinChoices,
+		Orientation:   interaction.Orientation,
+		Attrs:         migrateAttrs(interaction.Attrs),
+		Prompt:        m.migratePromptToQTI3(interaction.Prompt),
+		SimpleChoice:  m.migrateSimpleChoicesToQTI3(interaction.SimpleChoice),
 	}
 
 	return qti3Interaction
 }
 
+func (m *Migrator21to30) migrateMatchInteractionToQTI3(interaction *models.MatchInteraction) QTI3MatchInteraction {
+	qti3Interaction := QTI3MatchInteraction{
+		Class:           elementClass(*interaction),
+		ResponseIdent:   interaction.ResponseIdent,
+		Shuffle:         interaction.Shuffle,
+		MaxAssociations: interaction.MaxAssociations,
+		MinAssociations: interaction.MinAssociations,
+		Attrs:           migrateAttrs(interaction.Attrs),
+		Prompt:          m.migratePromptToQTI3(interaction.Prompt),
+	}
+
+	for _, set := range interaction.SimpleMatchSet {
+		qti3Interaction.SimpleMatchSet = append(qti3Interaction.SimpleMatchSet, QTI3SimpleMatchSet{
+			Attrs:                  migrateAttrs(set.Attrs),
+			SimpleAssociableChoice: m.migrateAssociableChoicesToQTI3(set.SimpleAssociableChoice),
+		})
+	}
+
+	return qti3Interaction
+}
+
+func (m *Migrator21to30) migrateAssociateInteractionToQTI3(interaction *models.AssociateInteraction) QTI3AssociateInteraction {
+	return QTI3AssociateInteraction{
+		Class:                  elementClass(*interaction),
+		ResponseIdent:          interaction.ResponseIdent,
+		Shuffle:                interaction.Shuffle,
+		MaxAssociations:        interaction.MaxAssociations,
+		MinAssociations:        interaction.MinAssociations,
+		Attrs:                  migrateAttrs(interaction.Attrs),
+		Prompt:                 m.migratePromptToQTI3(interaction.Prompt),
+		SimpleAssociableChoice: m.migrateAssociableChoicesToQTI3(interaction.SimpleAssociableChoice),
+	}
+}
+
+func (m *Migrator21to30) migrateAssociableChoicesToQTI3(choices []models.SimpleAssociableChoice) []QTI3SimpleAssociableChoice {
+	var qti3Choices []QTI3SimpleAssociableChoice
+	for _, choice := range choices {
+		qti3Choices = append(qti3Choices, QTI3SimpleAssociableChoice{
+			Identifier: choice.Identifier,
+			Fixed:      choice.Fixed,
+			MatchMax:   choice.MatchMax,
+			MatchMin:   choice.MatchMin,
+			Attrs:      migrateAttrs(choice.Attrs),
+			Content:    m.updateHTMLContent(choice.Content),
+		})
+	}
+	return qti3Choices
+}
+
+func (m *Migrator21to30) migrateSimpleChoicesToQTI3(choices []models.SimpleChoice) []QTI3SimpleChoice {
+	var qti3Choices []QTI3SimpleChoice
+	for _, choice := range choices {
+		qti3Choices = append(qti3Choices, QTI3SimpleChoice{
+			Identifier: choice.Identifier,
+			Fixed:      choice.Fixed,
+			Attrs:      migrateAttrs(choice.Attrs),
+			Content:    m.updateHTMLContent(choice.Content),
+		})
+	}
+	return qti3Choices
+}
+
+func (m *Migrator21to30) migratePromptToQTI3(prompt *models.Prompt) *QTI3Prompt {
+	if prompt == nil {
+		return nil
+	}
+	return &QTI3Prompt{
+		Attrs:   migrateAttrs(prompt.Attrs),
+		Content: m.updateHTMLContent(prompt.Content),
+	}
+}
+
+// rawElement keeps a QTI 3.0 interaction that the generic item body has no
+// field for as raw markup.
+func rawElement(interaction interface{}) models.RawElement21 {
+	var raw models.RawElement21
+	content, err := xml.Marshal(interaction)
+	if err == nil {
+		err = xml.Unmarshal(content, &raw)
+	}
+	if err != nil {
+		return models.RawElement21{}
+	}
+	return raw
+}
+
 func (m *Migrator21to30) migrateResponseDeclarationToQTI3(decl *models.ResponseDecl) QTI3ResponseDecl {
 	qti3Decl := QTI3ResponseDecl{
 		Identifier:  decl.Identifier,
@@ -759,11 +1181,8 @@ func (m *Migrator21to30) migrateResponseDeclarationToQTI3(decl *models.ResponseD
 	}
 
 	if decl.CorrectResponse != nil {
-		qti3Decl.CorrectResponse = &QTI3CorrectResponse{}
-		for _, value := range decl.CorrectResponse.Value {
-			qti3Decl.CorrectResponse.Value = append(qti3Decl.CorrectResponse.Value, QTI3Value{
-				Content: value,
-			})
+		qti3Decl.CorrectResponse = &QTI3CorrectResponse{
+			Value: m.migrateValuesToQTI3(decl.CorrectResponse.Value),
 		}
 	}
 
@@ -781,30 +1200,86 @@ func (m *Migrator21to30) migrateResponseDeclarationToQTI3(decl *models.ResponseD
 		}
 	}
 
+	if decl.AreaMapping != nil {
+		qti3Decl.AreaMapping = &QTI3AreaMapping{
+			LowerBound:   decl.AreaMapping.LowerBound,
+			UpperBound:   decl.AreaMapping.UpperBound,
+			DefaultValue: decl.AreaMapping.DefaultValue,
+		}
+		for _, entry := range decl.AreaMapping.AreaMapEntry {
+			qti3Decl.AreaMapping.AreaMapEntry = append(qti3Decl.AreaMapping.AreaMapEntry, QTI3AreaMapEntry{
+				Shape:       entry.Shape,
+				Coords:      entry.Coords,
+				MappedValue: entry.MappedValue,
+			})
+		}
+	}
+
 	return qti3Decl
 }
 
 func (m *Migrator21to30) migrateOutcomeDeclarationToQTI3(decl *models.OutcomeDecl) QTI3OutcomeDecl {
 	qti3Decl := QTI3OutcomeDecl{
-		Identifier:  decl.Identifier,
-		Cardinality: decl.Cardinality,
-		BaseType:    m.migrateBaseType(decl.BaseType),
+		Identifier:         decl.Identifier,
+		Cardinality:        decl.Cardinality,
+		BaseType:           m.migrateBaseType(decl.BaseType),
+		View:               migrateViews(decl.View),
+		Interpretation:     decl.Interpretation,
+		LongInterpretation: decl.LongInterpretation,
+		NormalMaximum:      decl.NormalMaximum,
+		NormalMinimum:      decl.NormalMinimum,
+		MasteryValue:       decl.MasteryValue,
+		DefaultValue:       m.migrateDefaultValueToQTI3(decl.DefaultValue),
 	}
 
-	if decl.DefaultValue != nil {
-		qti3Decl.DefaultValue = &QTI3DefaultValue{}
-		qti3Decl.DefaultValue.Value = append(qti3Decl.DefaultValue.Value, QTI3Value{
-			Content: decl.DefaultValue.Value,
+	return qti3Decl
+}
+
+func (m *Migrator21to30) migrateTemplateDeclarationToQTI3(decl *models.TemplateDecl) QTI3TemplateDecl {
+	return QTI3TemplateDecl{
+		Identifier:    decl.Identifier,
+		Cardinality:   decl.Cardinality,
+		BaseType:      m.migrateBaseType(decl.BaseType),
+		ParamVariable: decl.ParamVariable,
+		MathVariable:  decl.MathVariable,
+		DefaultValue:  m.migrateDefaultValueToQTI3(decl.DefaultValue),
+	}
+}
+
+func (m *Migrator21to30) migrateDefaultValueToQTI3(defaultValue *models.DefaultValue) *QTI3DefaultValue {
+	if defaultValue == nil {
+		return nil
+	}
+	return &QTI3DefaultValue{Value: m.migrateValuesToQTI3(defaultValue.Value)}
+}
+
+func (m *Migrator21to30) migrateValuesToQTI3(values []models.Value) []QTI3Value {
+	var qti3Values []QTI3Value
+	for _, value := range values {
+		qti3Values = append(qti3Values, QTI3Value{
+			FieldIdentifier: value.FieldIdentifier,
+			BaseType:        m.migrateBaseType(value.BaseType),
+			Content:         value.Content,
 		})
 	}
+	return qti3Values
+}
 
-	return qti3Decl
+// migrateViews migrates a space-separated list of views.
+func migrateViews(views string) string {
+	fields := strings.Fields(views)
+	for i, view := range fields {
+		fields[i] = migrateView(view)
+	}
+	return strings.Join(fields, " ")
 }
 
 func (m *Migrator21to30) migrateFeedbackToQTI3(feedback *models.Feedback) QTI3Feedback {
 	qti3Feedback := QTI3Feedback{
-		Ident: feedback.Ident,
-		Title: feedback.Title,
+		OutcomeIdent: feedback.OutcomeIdentifier,
+		Ident:        feedback.Ident,
+		ShowHide:     feedback.ShowHide,
+		Title:        feedback.Title,
 	}
 
 	// Convert material/flowmat content to simple content
@@ -824,4 +1299,56 @@ func (m *Migrator21to30) migrateFeedbackToQTI3(feedback *models.Feedback) QTI3Fe
 	qti3Feedback.Content = content.String()
 
 	return qti3Feedback
-}
\ No newline at end of file
+}
+
+// migrateResponseProcessingToQTI3 points standard templates at their QTI 3.0
+// location and renames the elements and attributes of explicit rules.
+func (m *Migrator21to30) migrateResponseProcessingToQTI3(responseProcessing *models.ResponseProcessing) *QTI3ResponseProcessing {
+	qti3ResponseProcessing := &QTI3ResponseProcessing{
+		Template:         m.migrateTemplateURI(responseProcessing.Template),
+		TemplateLocation: responseProcessing.TemplateLocation,
+	}
+
+	for _, rule := range responseProcessing.Rules {
+		qti3ResponseProcessing.Rules = append(qti3ResponseProcessing.Rules, m.migrateRuleToQTI3(rule))
+	}
+
+	return qti3ResponseProcessing
+}
+
+func (m *Migrator21to30) migrateTemplateURI(template string) string {
+	idx := strings.LastIndex(template, "/rptemplates/")
+	if idx < 0 {
+		return template
+	}
+	name := strings.TrimSuffix(template[idx+len("/rptemplates/"):], ".xml")
+	return "https://purl.imsglobal.org/spec/qti/v3p0/rptemplates/" + name + ".xml"
+}
+
+func (m *Migrator21to30) migrateRuleToQTI3(node models.XMLNode) models.XMLNode {
+	migrated := models.XMLNode{
+		XMLName: xml.Name{Local: "qti-" + camelToKebab(node.XMLName.Local)},
+		Value:   node.Value,
+	}
+	for _, attr := range node.Attrs {
+		migrated.Attrs = append(migrated.Attrs, xml.Attr{Name: xml.Name{Local: camelToKebab(attr.Name.Local)}, Value: attr.Value})
+	}
+	for _, child := range node.Children {
+		migrated.Children = append(migrated.Children, m.migrateRuleToQTI3(child))
+	}
+	return migrated
+}
+
+func camelToKebab(name string) string {
+	var result strings.Builder
+	for i, r := range name {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 {
+				result.WriteByte('-')
+			}
+			r += 'a' - 'A'
+		}
+		result.WriteRune(r)
+	}
+	return result.String()
+}
@@ -5,7 +5,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/qti-migrator/internal/parser/qti21"
 	"github.com/qti-migrator/pkg/models"
+	"github.com/qti-migrator/pkg/registry"
 )
 
 func TestNew(t *testing.T) {
@@ -15,6 +17,24 @@ func TestNew(t *testing.T) {
 	}
 }
 
+// migrate migrates doc and writes it as a single file.
+func migrate(doc *models.QTIDocument) ([]byte, error) {
+	files, err := migrateFiles(doc, registry.MigrationOptions{Layout: registry.LayoutSingle})
+	if err != nil {
+		return nil, err
+	}
+	return files[0].Content, nil
+}
+
+// migrateFiles migrates doc and writes its files.
+func migrateFiles(doc *models.QTIDocument, options registry.MigrationOptions) ([]models.File, error) {
+	migrated, err := New().Migrate(doc, options)
+	if err != nil {
+		return nil, err
+	}
+	return NewSerializer().Serialize(migrated, options)
+}
+
 func TestMigrate_BasicItem(t *testing.T) {
 	qtiDoc := &models.QTIDocument{
 		XMLName: xml.Name{Local: "questestinterop"},
@@ -37,8 +57,7 @@ func TestMigrate_BasicItem(t *testing.T) {
 		},
 	}
 
-	m := New()
-	result, err := m.Migrate(qtiDoc)
+	result, err := migrate(qtiDoc)
 	
 	if err != nil {
 		t.Fatalf("Migration failed: %v", err)
@@ -103,8 +122,7 @@ func TestMigrate_ChoiceInteraction(t *testing.T) {
 		},
 	}
 
-	m := New()
-	result, err := m.Migrate(qtiDoc)
+	result, err := migrate(qtiDoc)
 	
 	if err != nil {
 		t.Fatalf("Migration failed: %v", err)
@@ -139,7 +157,7 @@ func TestMigrate_ResponseDeclaration(t *testing.T) {
 						BaseType:    "identifier",
 						CorrectResponse: &models.CorrectResponse{
 							XMLName: xml.Name{Local: "correctResponse"},
-							Value:   []string{"B"},
+							Value:   models.NewValues("B"),
 						},
 					},
 				},
@@ -147,8 +165,7 @@ func TestMigrate_ResponseDeclaration(t *testing.T) {
 		},
 	}
 
-	m := New()
-	result, err := m.Migrate(qtiDoc)
+	result, err := migrate(qtiDoc)
 	
 	if err != nil {
 		t.Fatalf("Migration failed: %v", err)
@@ -183,7 +200,7 @@ func TestMigrate_OutcomeDeclaration(t *testing.T) {
 						BaseType:    "float",
 						DefaultValue: &models.DefaultValue{
 							XMLName: xml.Name{Local: "defaultValue"},
-							Value:   "0.0",
+							Value:   models.NewValues("0.0"),
 						},
 					},
 				},
@@ -191,8 +208,7 @@ func TestMigrate_OutcomeDeclaration(t *testing.T) {
 		},
 	}
 
-	m := New()
-	result, err := m.Migrate(qtiDoc)
+	result, err := migrate(qtiDoc)
 	
 	if err != nil {
 		t.Fatalf("Migration failed: %v", err)
@@ -235,31 +251,260 @@ func TestMigrate_Assessment(t *testing.T) {
 		},
 	}
 
-	m := New()
-	result, err := m.Migrate(qtiDoc)
-	
+	if _, err := migrate(qtiDoc); err == nil {
+		t.Error("Expected an error when a test with its items does not fit in one file")
+	}
+
+	files, err := migrateFiles(qtiDoc, registry.MigrationOptions{})
 	if err != nil {
 		t.Fatalf("Migration failed: %v", err)
 	}
-	
-	resultStr := string(result)
-	
-	// Debug: print the result
-	t.Logf("Assessment migration result:\n%s", resultStr)
-	
-	// Check assessment migration (currently uses old element names in full document structure)
-	// TODO: Future enhancement would be to use QTI 3.0 assessment structures
-	if !strings.Contains(resultStr, `<assessment`) {
-		t.Error("Expected assessment element")
+	if len(files) != 2 {
+		t.Fatalf("Expected a test and an item file, got %d files", len(files))
 	}
-	
-	if !strings.Contains(resultStr, `<section`) {
-		t.Error("Expected section element")
+
+	test, item := files[0], files[1]
+	if test.Kind != models.FileTest || test.Href != "test001.xml" {
+		t.Errorf("Expected test file test001.xml, got %s %s", test.Kind, test.Href)
 	}
-	
-	// Check QTI 3.0 version
-	if !strings.Contains(resultStr, `version="3.0"`) {
-		t.Error("Expected version 3.0")
+	if item.Kind != models.FileItem || item.Href != "items/q001.xml" {
+		t.Errorf("Expected item file items/q001.xml, got %s %s", item.Kind, item.Href)
+	}
+	if len(test.Dependencies) != 1 || test.Dependencies[0] != item.Href {
+		t.Errorf("Expected the test to depend on %s, got %v", item.Href, test.Dependencies)
+	}
+
+	for _, expected := range []string{
+		`<qti-assessment-test xmlns="http://www.imsglobal.org/xsd/imsqtiasi_v3p0" identifier="test001" title="Sample Test">`,
+		`<qti-test-part identifier="testPart1" navigation-mode="linear" submission-mode="individual">`,
+		`<qti-assessment-section identifier="sec001" title="Section 1" visible="true">`,
+		`<qti-assessment-item-ref identifier="q001" href="items/q001.xml"></qti-assessment-item-ref>`,
+	} {
+		if !strings.Contains(string(test.Content), expected) {
+			t.Errorf("Expected test to contain %q, got:\n%s", expected, test.Content)
+		}
+	}
+	if strings.Contains(string(test.Content), `version="3.0"`) {
+		t.Error("Expected no version attribute on the QTI 3.0 test")
+	}
+	if !strings.Contains(string(item.Content), `<qti-assessment-item xmlns="http://www.imsglobal.org/xsd/imsqtiasi_v3p0" identifier="q001" title="Question 1"`) {
+		t.Errorf("Expected a qti-assessment-item file, got:\n%s", item.Content)
+	}
+}
+
+func TestMigrateFiles_TestPartsAndDuplicates(t *testing.T) {
+	qtiDoc := &models.QTIDocument{
+		XMLName: xml.Name{Local: "questestinterop"},
+		Assessment: &models.Assessment{
+			XMLName: xml.Name{Local: "assessment"},
+			Ident:   "exam",
+			Sections: []models.Section{
+				{
+					Ident:          "part1",
+					NavigationMode: "nonlinear",
+					SubmissionMode: "simultaneous",
+					Sections: []models.Section{
+						{
+							Ident:    "refs",
+							ItemRefs: []models.ItemRef{{Identifier: "old", Href: "old.xml"}},
+						},
+					},
+				},
+			},
+		},
+		Items: []models.Item{{Ident: "q 1"}, {Ident: "q 1"}},
+	}
+
+	files, err := migrateFiles(qtiDoc, registry.MigrationOptions{})
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("Expected a test and two item files, got %d files", len(files))
+	}
+	if files[1].Href != "items/q_1.xml" || files[2].Href != "items/q_1-2.xml" {
+		t.Errorf("Expected distinct item files, got %s and %s", files[1].Href, files[2].Href)
+	}
+
+	test := string(files[0].Content)
+	for _, expected := range []string{
+		`<qti-test-part identifier="part1" navigation-mode="nonlinear" submission-mode="simultaneous">`,
+		`<qti-assessment-item-ref identifier="old" href="old.xml">`,
+		`<qti-assessment-item-ref identifier="q 1" href="items/q_1.xml">`,
+		`<qti-assessment-item-ref identifier="q 1-2" href="items/q_1-2.xml">`,
+	} {
+		if !strings.Contains(test, expected) {
+			t.Errorf("Expected test to contain %q, got:\n%s", expected, test)
+		}
+	}
+}
+
+func TestMigrateFiles_ItemAssets(t *testing.T) {
+	item := models.Item{
+		Ident:       "q1",
+		Stylesheets: []models.Stylesheet{{Href: "style.css", Type: "text/css"}},
+		ItemBody: &models.ItemBody{
+			P: []models.P{{Content: `<img src="images/a.png" alt="A"/> <img src="https://example.com/b.png" alt="B"/> <object data="media/c.pdf" type="application/pdf">C</object>`}},
+		},
+	}
+	qtiDoc := &models.QTIDocument{Items: []models.Item{item, {Ident: "q2"}}}
+
+	for _, tt := range []struct {
+		layout   registry.Layout
+		expected []string
+	}{
+		{
+			layout: registry.LayoutFiles,
+			expected: []string{
+				`<qti-stylesheet href="../style.css"`,
+				`<img src="../images/a.png" alt="A"/>`,
+				`<img src="https://example.com/b.png" alt="B"/>`,
+				`<object data="../media/c.pdf" type="application/pdf">`,
+			},
+		},
+		{
+			layout: registry.LayoutFlat,
+			expected: []string{
+				`<qti-stylesheet href="style.css"`,
+				`<img src="images/a.png" alt="A"/>`,
+				`<object data="media/c.pdf" type="application/pdf">`,
+			},
+		},
+	} {
+		files, err := migrateFiles(qtiDoc, registry.MigrationOptions{Layout: tt.layout})
+		if err != nil {
+			t.Fatalf("Migration failed: %v", err)
+		}
+		for _, expected := range tt.expected {
+			if !strings.Contains(string(files[1].Content), expected) {
+				t.Errorf("Expected the %s layout to write %q, got:\n%s", tt.layout, expected, files[1].Content)
+			}
+		}
+	}
+}
+
+func TestMigrateFiles_SingleLayoutTest(t *testing.T) {
+	doc, err := qti21.New().Parse([]byte(`<assessmentTest xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="quiz" title="Quiz">
+	<testPart identifier="part1" navigationMode="linear" submissionMode="individual">
+		<assessmentSection identifier="s1" title="Section" visible="true">
+			<assessmentItemRef identifier="q1" href="q1.xml"/>
+		</assessmentSection>
+	</testPart>
+</assessmentTest>`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	files, err := migrateFiles(doc, registry.MigrationOptions{Layout: registry.LayoutSingle})
+	if err != nil {
+		t.Fatalf("Expected a test without items to fit a single file, got %v", err)
+	}
+	if len(files) != 1 || files[0].Kind != models.FileTest {
+		t.Fatalf("Expected the test alone, got %d files", len(files))
+	}
+
+	withItems := &models.QTIDocument{Items: []models.Item{{Ident: "q1"}, {Ident: "q2"}}}
+	if _, err := migrateFiles(withItems, registry.MigrationOptions{Layout: registry.LayoutSingle}); err == nil {
+		t.Error("Expected an error for items that need files of their own")
+	}
+}
+
+func TestMigrateFiles_TestControls(t *testing.T) {
+	doc, err := qti21.New().Parse([]byte(`<assessmentTest xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="quiz" title="Quiz">
+	<timeLimits maxTime="2700"/>
+	<testPart identifier="part1" navigationMode="linear" submissionMode="individual">
+		<itemSessionControl maxAttempts="0"/>
+		<assessmentSection identifier="pool" title="Pool" visible="false">
+			<selection select="2" withReplacement="true"/>
+			<ordering shuffle="true"/>
+			<assessmentItemRef identifier="q1" href="q1.xml">
+				<itemSessionControl maxAttempts="1"/>
+				<timeLimits minTime="10" maxTime="90"/>
+			</assessmentItemRef>
+		</assessmentSection>
+	</testPart>
+</assessmentTest>`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	files, err := migrateFiles(doc, registry.MigrationOptions{})
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+
+	test := string(files[0].Content)
+	for _, expected := range []string{
+		`<qti-time-limits max-time="2700"></qti-time-limits>`,
+		`<qti-item-session-control max-attempts="0"></qti-item-session-control>`,
+		`<qti-assessment-section identifier="pool" title="Pool" visible="false">
+      <qti-selection select="2" with-replacement="true"></qti-selection>
+      <qti-ordering shuffle="true"></qti-ordering>
+      <qti-assessment-item-ref identifier="q1" href="q1.xml">
+        <qti-item-session-control max-attempts="1"></qti-item-session-control>
+        <qti-time-limits min-time="10" max-time="90"></qti-time-limits>`,
+	} {
+		if !strings.Contains(test, expected) {
+			t.Errorf("Expected test to contain %q, got:\n%s", expected, test)
+		}
+	}
+
+	// Legacy documents carry maxattempts on the item itself
+	files, err = migrateFiles(&models.QTIDocument{Items: []models.Item{{Ident: "a", MaxAttempts: 2}, {Ident: "b"}}}, registry.MigrationOptions{})
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	if !strings.Contains(string(files[0].Content), `<qti-assessment-item-ref identifier="a" href="items/a.xml">
+        <qti-item-session-control max-attempts="2"></qti-item-session-control>`) {
+		t.Errorf("Expected maxattempts on the item ref, got:\n%s", files[0].Content)
+	}
+	if strings.Contains(string(files[1].Content), "maxattempts") {
+		t.Errorf("Expected no maxattempts attribute on the item, got:\n%s", files[1].Content)
+	}
+}
+
+func TestMigrateFiles_TestOutcomes(t *testing.T) {
+	doc, err := qti21.New().Parse([]byte(`<assessmentTest xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="quiz" title="Quiz">
+	<outcomeDeclaration identifier="SCORE" cardinality="single" baseType="float"/>
+	<testPart identifier="part1" navigationMode="linear" submissionMode="individual">
+		<assessmentSection identifier="s1" title="S1" visible="true">
+			<rubricBlock view="candidate testConstructor"><p>Read carefully</p></rubricBlock>
+			<assessmentItemRef identifier="q1" href="q1.xml" category="easy math">
+				<weight identifier="W1" value="2.5"/>
+			</assessmentItemRef>
+		</assessmentSection>
+		<testFeedback access="during" outcomeIdentifier="PART" showHide="show" identifier="pf">Part done</testFeedback>
+	</testPart>
+	<outcomeProcessing>
+		<setOutcomeValue identifier="SCORE"><sum><testVariables variableIdentifier="SCORE" weightIdentifier="W1"/></sum></setOutcomeValue>
+	</outcomeProcessing>
+	<testFeedback access="atEnd" outcomeIdentifier="SCORE" showHide="show" identifier="end" title="Done"><p>Well done</p></testFeedback>
+</assessmentTest>`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	files, err := migrateFiles(doc, registry.MigrationOptions{})
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+
+	test := string(files[0].Content)
+	for _, expected := range []string{
+		`<qti-outcome-declaration identifier="SCORE" cardinality="single" base-type="float"></qti-outcome-declaration>`,
+		`<qti-rubric-block view="candidate test-constructor">
+        <qti-content-body><p>Read carefully</p></qti-content-body>`,
+		`<qti-assessment-item-ref identifier="q1" href="q1.xml" category="easy math">
+        <qti-weight identifier="W1" value="2.5"></qti-weight>`,
+		`<qti-test-feedback access="during" outcome-identifier="PART" show-hide="show" identifier="pf">
+      <qti-content-body>Part done</qti-content-body>`,
+		`<qti-test-variables variable-identifier="SCORE" weight-identifier="W1"></qti-test-variables>`,
+		`<qti-test-feedback access="atEnd" outcome-identifier="SCORE" show-hide="show" identifier="end" title="Done">
+    <qti-content-body><p>Well done</p></qti-content-body>`,
+	} {
+		if !strings.Contains(test, expected) {
+			t.Errorf("Expected test to contain %q, got:\n%s", expected, test)
+		}
 	}
 }
 
@@ -280,32 +525,21 @@ func TestMigrate_Metadata(t *testing.T) {
 		},
 	}
 
-	m := New()
-	result, err := m.Migrate(qtiDoc)
-	
-	if err != nil {
-		t.Fatalf("Migration failed: %v", err)
-	}
-	
-	resultStr := string(result)
-	
-	// Debug: print the result
-	t.Logf("Metadata migration result:\n%s", resultStr)
-	
-	// Check metadata migration (currently uses old element names in full document structure)
-	// TODO: Future enhancement would be to use QTI 3.0 metadata structures  
-	if !strings.Contains(resultStr, `<metadata>`) {
-		t.Error("Expected metadata element")
+	// A document without items or a test has nothing to write as QTI 3.0
+	if _, err := migrate(qtiDoc); err == nil || !strings.Contains(err.Error(), "no items") {
+		t.Errorf("Expected migrating a document without items to fail, got %v", err)
 	}
+
+	metadata := New().migrateMetadata(qtiDoc.Metadata)
 	
 	// Check QTI 3.0 version
-	if !strings.Contains(resultStr, `version="3.0"`) {
-		t.Error("Expected version 3.0")
+	if metadata.SchemaVer != "3.0" {
+		t.Errorf("Expected schema version 3.0, got %q", metadata.SchemaVer)
 	}
 	
 	// Check interaction type migration
-	if !strings.Contains(resultStr, `qti-choice-interaction`) {
-		t.Error("Expected interaction type to be migrated to qti-choice-interaction")
+	if metadata.QTIMetadata.InteractionType != "qti-choice-interaction" {
+		t.Errorf("Expected interaction type to be migrated to qti-choice-interaction, got %q", metadata.QTIMetadata.InteractionType)
 	}
 }
 
@@ -333,8 +567,7 @@ func TestMigrate_TextEntryInteraction(t *testing.T) {
 		},
 	}
 
-	m := New()
-	result, err := m.Migrate(qtiDoc)
+	result, err := migrate(qtiDoc)
 	
 	if err != nil {
 		t.Fatalf("Migration failed: %v", err)
@@ -375,8 +608,7 @@ func TestMigrate_ExtendedTextInteraction(t *testing.T) {
 		},
 	}
 
-	m := New()
-	result, err := m.Migrate(qtiDoc)
+	result, err := migrate(qtiDoc)
 	
 	if err != nil {
 		t.Fatalf("Migration failed: %v", err)
@@ -394,19 +626,6 @@ func TestMigrate_ExtendedTextInteraction(t *testing.T) {
 	}
 }
 
-func TestMigrate_InvalidDocumentType(t *testing.T) {
-	m := New()
-	_, err := m.Migrate("invalid document")
-	
-	if err == nil {
-		t.Error("Expected error for invalid document type")
-	}
-	
-	if !strings.Contains(err.Error(), "invalid document type") {
-		t.Errorf("Expected invalid document type error, got: %v", err)
-	}
-}
-
 func TestMigrate_HTMLContentUpdate(t *testing.T) {
 	qtiDoc := &models.QTIDocument{
 		XMLName: xml.Name{Local: "questestinterop"},
@@ -429,8 +648,7 @@ func TestMigrate_HTMLContentUpdate(t *testing.T) {
 		},
 	}
 
-	m := New()
-	result, err := m.Migrate(qtiDoc)
+	result, err := migrate(qtiDoc)
 	
 	if err != nil {
 		t.Fatalf("Migration failed: %v", err)
@@ -439,12 +657,164 @@ func TestMigrate_HTMLContentUpdate(t *testing.T) {
 	resultStr := string(result)
 	
 	// Check HTML content updates
-	if !strings.Contains(resultStr, `data-qti-class="highlight"`) {
-		t.Error("Expected class attribute to be converted to data-qti-class")
+	if !strings.Contains(resultStr, `<span class="highlight">`) {
+		t.Error("Expected unknown class to be kept")
 	}
 	
-	if !strings.Contains(resultStr, `<qti-object`) {
-		t.Error("Expected object tag to be converted to qti-object")
+	if !strings.Contains(resultStr, `<object data="test.swf">object</object>`) || strings.Contains(resultStr, "qti-object") {
+		t.Error("Expected object tag to be kept, as QTI 3.0 has no qti-object")
+	}
+}
+
+func TestMigrate_UpdateHTMLContent(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Class in text",
+			input:    `<code>the class= syntax</code>`,
+			expected: `<code>the class= syntax</code>`,
+		},
+		{
+			name:     "Class attribute",
+			input:    `<span class="note" subclass="a">x</span>`,
+			expected: `<span class="note" subclass="a">x</span>`,
+		},
+		{
+			name:     "Vendor class",
+			input:    `<div class="list-style-upper-alpha note">x</div>`,
+			expected: `<div class="qti-labels-upper-alpha note">x</div>`,
+		},
+		{
+			name:     "Unchanged markup",
+			input:    `<p id='p1'>a &amp; b<br/>c<!-- note --></p>`,
+			expected: `<p id='p1'>a &amp; b<br/>c<!-- note --></p>`,
+		},
+		{
+			name:     "QTI element",
+			input:    `Fill <textEntryInteraction responseIdentifier="R1" expectedLength="5"/> in`,
+			expected: `Fill <qti-text-entry-interaction response-identifier="R1" expected-length="5" class="qti-input-width-6"/> in`,
+		},
+		{
+			name:     "Image object",
+			input:    `<object type="image/png" data="map.png" width="200">A <b>map</b></object>`,
+			expected: `<img src="map.png" width="200" alt="A map"/>`,
+		},
+		{
+			name:     "Audio object",
+			input:    `<object type="audio/mpeg" data="a.mp3"><param name="autoplay" value="false"/>Listen</object>`,
+			expected: `<audio src="a.mp3" controls="true">Listen</audio>`,
+		},
+		{
+			name:     "Video object",
+			input:    `<object type="video/mp4" data="v.mp4" height="90"/>`,
+			expected: `<video src="v.mp4" height="90" controls="true"/>`,
+		},
+		{
+			name:     "Other object",
+			input:    `<object data="test.swf">object</object>`,
+			expected: `<object data="test.swf">object</object>`,
+		},
+		{
+			name:     "HTML media",
+			input:    `<img src="a.png" alt="A"/><audio src="a.mp3"><source src="a.ogg"/></audio><video src="v.mp4"></video>`,
+			expected: `<img src="a.png" alt="A"/><audio src="a.mp3"><source src="a.ogg"/></audio><video src="v.mp4"></video>`,
+		},
+		{
+			name:     "MathML",
+			input:    `<math xmlns="http://www.w3.org/1998/Math/MathML" class="m"><mi mathvariant="bold">x</mi></math>`,
+			expected: `<math xmlns="http://www.w3.org/1998/Math/MathML" class="m"><mi mathvariant="bold">x</mi></math>`,
+		},
+		{
+			name:     "Not well-formed",
+			input:    `<p>Open <gap identifier="G1"/>`,
+			expected: `<p>Open <qti-gap identifier="G1"/></p>`,
+		},
+		{
+			name:     "HTML markup",
+			input:    `<p>A&nbsp;B<br><textEntryInteraction responseIdentifier="R1"/>`,
+			expected: `<p>A&#160;B<br/><qti-text-entry-interaction response-identifier="R1"/></p>`,
+		},
+		{
+			name:     "Rubric block",
+			input:    `<div><rubricBlock view="testConstructor" use="hint"><p>Note</p></rubricBlock><rubricBlock view="scorer"/></div>`,
+			expected: `<div><qti-rubric-block view="test-constructor" use="hint"><qti-content-body><p>Note</p></qti-content-body></qti-rubric-block><qti-rubric-block view="scorer"><qti-content-body></qti-content-body></qti-rubric-block></div>`,
+		},
+	}
+
+	m := New()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := m.updateHTMLContent(test.input)
+			if result != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestMigrate_SharedClasses(t *testing.T) {
+	itemBody := &models.ItemBody{XMLName: xml.Name{Local: "itemBody"}}
+	itemBody.Append(models.ChoiceInteraction{
+		XMLName:       xml.Name{Local: "choiceInteraction"},
+		ResponseIdent: "R1",
+		Class:         "list-style-upper-alpha custom",
+		Orientation:   "horizontal",
+	})
+	itemBody.Append(models.ExtendedTextInteraction{
+		XMLName:       xml.Name{Local: "extendedTextInteraction"},
+		ResponseIdent: "R2",
+		ExpectedLines: 5,
+	})
+	itemBody.Append(models.P{
+		XMLName: xml.Name{Local: "p"},
+		Content: `Name: <textEntryInteraction responseIdentifier="R3" expectedLength="12"/> <span class="stacked">a</span>`,
+	})
+	// Layout classes only stand for the shared ones on interactions
+	itemBody.Append(models.Div{
+		XMLName: xml.Name{Local: "div"},
+		Class:   "horizontal",
+		Content: `<gapMatchInteraction responseIdentifier="R4" class="stacked"/>`,
+	})
+
+	result, err := migrate(&models.QTIDocument{
+		XMLName: xml.Name{Local: "questestinterop"},
+		Items: []models.Item{
+			{XMLName: xml.Name{Local: "item"}, Ident: "q001", ItemBody: itemBody},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+
+	for _, expected := range []string{
+		`<qti-choice-interaction class="qti-labels-upper-alpha custom qti-orientation-horizontal" response-identifier="R1" orientation="horizontal">`,
+		`<qti-extended-text-interaction class="qti-height-lines-6" response-identifier="R2"`,
+		`expected-length="12" class="qti-input-width-15"/> <span class="stacked">a</span>`,
+		`<div class="horizontal"><qti-gap-match-interaction response-identifier="R4" class="qti-choices-stacked"/></div>`,
+	} {
+		if !strings.Contains(string(result), expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, result)
+		}
+	}
+
+	changes := ClassChanges(itemBody)
+	expected := []ClassChange{
+		{Element: "choiceInteraction", Original: `class="list-style-upper-alpha"`, Class: "qti-labels-upper-alpha"},
+		{Element: "choiceInteraction", Original: `orientation="horizontal"`, Class: "qti-orientation-horizontal"},
+		{Element: "extendedTextInteraction", Original: `expectedLines="5"`, Class: "qti-height-lines-6"},
+		{Element: "textEntryInteraction", Original: `expectedLength="12"`, Class: "qti-input-width-15"},
+		{Element: "gapMatchInteraction", Original: `class="stacked"`, Class: "qti-choices-stacked"},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %d class changes, got %d: %v", len(expected), len(changes), changes)
+	}
+	for i, change := range changes {
+		if change != expected[i] {
+			t.Errorf("Expected change %d to be %+v, got %+v", i, expected[i], change)
+		}
 	}
 }
 
@@ -488,9 +858,8 @@ func TestMigrate_ViewConversion(t *testing.T) {
 		{"custom", "custom"},
 	}
 	
-	m := New()
 	for _, test := range tests {
-		result := m.migrateView(test.input)
+		result := migrateView(test.input)
 		if result != test.expected {
 			t.Errorf("Expected view %s to be converted to %s, got %s", test.input, test.expected, result)
 		}
@@ -515,7 +884,7 @@ func TestMigrate_ComplexDocument(t *testing.T) {
 						BaseType:    "identifier",
 						CorrectResponse: &models.CorrectResponse{
 							XMLName: xml.Name{Local: "correctResponse"},
-							Value:   []string{"C"},
+							Value:   models.NewValues("C"),
 						},
 						Mapping: &models.Mapping{
 							XMLName:      xml.Name{Local: "mapping"},
@@ -548,7 +917,7 @@ func TestMigrate_ComplexDocument(t *testing.T) {
 						BaseType:    "float",
 						DefaultValue: &models.DefaultValue{
 							XMLName: xml.Name{Local: "defaultValue"},
-							Value:   "0.0",
+							Value:   models.NewValues("0.0"),
 						},
 					},
 				},
@@ -611,8 +980,7 @@ func TestMigrate_ComplexDocument(t *testing.T) {
 		},
 	}
 
-	m := New()
-	result, err := m.Migrate(qtiDoc)
+	result, err := migrate(qtiDoc)
 	
 	if err != nil {
 		t.Fatalf("Migration failed: %v", err)
@@ -642,4 +1010,309 @@ func TestMigrate_ComplexDocument(t *testing.T) {
 	if !strings.Contains(resultStr, `<qti-map-entry`) {
 		t.Error("Expected qti-map-entry")
 	}
-}
\ No newline at end of file
+}
+func TestMigrate_ResponseProcessing(t *testing.T) {
+	baseValue := models.NewXMLNode("baseValue", []string{"baseType", "float"})
+	baseValue.Value = "1"
+	qtiDoc := &models.QTIDocument{
+		Version: "2.1",
+		Items: []models.Item{
+			{
+				Ident: "q001",
+				ResponseProcessing: &models.ResponseProcessing{
+					Rules: []models.XMLNode{
+						models.NewXMLNode("responseCondition", nil,
+							models.NewXMLNode("responseIf", nil,
+								models.NewXMLNode("isNull", nil,
+									models.NewXMLNode("variable", []string{"identifier", "RESPONSE"})),
+								models.NewXMLNode("setOutcomeValue", []string{"identifier", "SCORE"}, baseValue))),
+					},
+				},
+			},
+			{
+				Ident: "q002",
+				ResponseProcessing: &models.ResponseProcessing{
+					Template: models.TemplateMapResponse21,
+				},
+			},
+		},
+	}
+
+	result, err := migrate(&models.QTIDocument{Version: "2.1", Items: qtiDoc.Items[:1]})
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+
+	resultStr := string(result)
+	expectedContent := []string{
+		`<qti-response-processing>`,
+		`<qti-response-condition>`,
+		`<qti-response-if>`,
+		`<qti-is-null>`,
+		`<qti-variable identifier="RESPONSE"></qti-variable>`,
+		`<qti-set-outcome-value identifier="SCORE">`,
+		`<qti-base-value base-type="float">1</qti-base-value>`,
+	}
+	for _, expected := range expectedContent {
+		if !strings.Contains(resultStr, expected) {
+			t.Errorf("Expected output to contain '%s', got:\n%s", expected, resultStr)
+		}
+	}
+
+	result, err = migrate(&models.QTIDocument{Version: "2.1", Items: qtiDoc.Items[1:]})
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+
+	expected := `<qti-response-processing template="https://purl.imsglobal.org/spec/qti/v3p0/rptemplates/map_response.xml">`
+	if !strings.Contains(string(result), expected) {
+		t.Errorf("Expected output to contain '%s', got:\n%s", expected, result)
+	}
+}
+
+func TestMigrate_ItemBodyOrder(t *testing.T) {
+	itemBody := &models.ItemBody{XMLName: xml.Name{Local: "itemBody"}}
+	itemBody.Append(models.ChoiceInteraction{ResponseIdent: "R1"})
+	itemBody.Append(models.P{Content: `Fill in: <textEntryInteraction responseIdentifier="R2" expectedLength="5"/>`})
+	itemBody.Append(models.RawElement21{
+		XMLName: xml.Name{Local: "hotspotInteraction"},
+		Attrs:   []xml.Attr{{Name: xml.Name{Local: "responseIdentifier"}, Value: "R3"}, {Name: xml.Name{Local: "maxChoices"}, Value: "1"}},
+		Content: `<hotspotChoice identifier="H1" shape="circle" coords="1,2,3"/>`,
+	})
+
+	qtiDoc := &models.QTIDocument{
+		Version: "2.1",
+		Items:   []models.Item{{Ident: "q001", ItemBody: itemBody}},
+	}
+
+	result, err := migrate(qtiDoc)
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+
+	resultStr := string(result)
+	expectedOrder := []string{
+		`<qti-choice-interaction response-identifier="R1">`,
+		`<p>Fill in: <qti-text-entry-interaction response-identifier="R2" expected-length="5" class="qti-input-width-6"/></p>`,
+		`<qti-hotspot-interaction response-identifier="R3" max-choices="1"><qti-hotspot-choice identifier="H1" shape="circle" coords="1,2,3"/></qti-hotspot-interaction>`,
+	}
+
+	last := -1
+	for _, expected := range expectedOrder {
+		index := strings.Index(resultStr, expected)
+		if index < 0 {
+			t.Errorf("Expected output to contain '%s', got:\n%s", expected, resultStr)
+			continue
+		}
+		if index < last {
+			t.Errorf("Expected '%s' to follow the previous content", expected)
+		}
+		last = index
+	}
+}
+
+func TestMigrate_MatchAndOrderInteractions(t *testing.T) {
+	itemBody := &models.ItemBody{XMLName: xml.Name{Local: "itemBody"}}
+	itemBody.Append(models.MatchInteraction{
+		ResponseIdent:   "R1",
+		MaxAssociations: 2,
+		Prompt:          &models.Prompt{Content: "Match"},
+		SimpleMatchSet: []models.SimpleMatchSet{
+			{SimpleAssociableChoice: []models.SimpleAssociableChoice{{Identifier: "S1", MatchMax: 1, Content: "Cat"}}},
+			{SimpleAssociableChoice: []models.SimpleAssociableChoice{{Identifier: "T1", Content: "Meow"}}},
+		},
+	})
+	itemBody.Append(models.OrderInteraction{
+		ResponseIdent: "R2",
+		Shuffle:       true,
+		SimpleChoice:  []models.SimpleChoice{{Identifier: "A", Content: "First"}},
+	})
+	itemBody.Append(models.AssociateInteraction{
+		ResponseIdent:          "R3",
+		SimpleAssociableChoice: []models.SimpleAssociableChoice{{Identifier: "X", MatchMax: 1, Content: "Salt"}},
+	})
+
+	qtiDoc := &models.QTIDocument{
+		Version: "2.1",
+		Items:   []models.Item{{Ident: "q001", ItemBody: itemBody}},
+	}
+
+	result, err := migrate(qtiDoc)
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+
+	resultStr := string(result)
+	expectedOrder := []string{
+		`<qti-match-interaction response-identifier="R1" max-associations="2">`,
+		`<qti-prompt>Match</qti-prompt>`,
+		`<qti-simple-match-set>`,
+		`<qti-simple-associable-choice identifier="S1" match-max="1">Cat</qti-simple-associable-choice>`,
+		`<qti-simple-associable-choice identifier="T1" match-max="0">Meow</qti-simple-associable-choice>`,
+		`<qti-order-interaction response-identifier="R2" shuffle="true">`,
+		`<qti-simple-choice identifier="A">First</qti-simple-choice>`,
+		`<qti-associate-interaction response-identifier="R3" max-associations="0">`,
+		`<qti-simple-associable-choice identifier="X" match-max="1">Salt</qti-simple-associable-choice>`,
+	}
+
+	last := -1
+	for _, expected := range expectedOrder {
+		index := strings.Index(resultStr, expected)
+		if index < 0 {
+			t.Errorf("Expected output to contain '%s', got:\n%s", expected, resultStr)
+			continue
+		}
+		if index < last {
+			t.Errorf("Expected '%s' to follow the previous content", expected)
+		}
+		last = index
+	}
+}
+
+func TestMigrate_KeepsDeclarationsAndBodyAttributes(t *testing.T) {
+	itemXML := `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="q001" title="Rich" adaptive="true" timeDependent="false">
+  <responseDeclaration identifier="RESPONSE" cardinality="record">
+    <correctResponse>
+      <value fieldIdentifier="x" baseType="integer">3</value>
+    </correctResponse>
+  </responseDeclaration>
+  <responseDeclaration identifier="POINT" cardinality="single" baseType="point">
+    <areaMapping defaultValue="0">
+      <areaMapEntry shape="circle" coords="10,10,5" mappedValue="1"/>
+    </areaMapping>
+  </responseDeclaration>
+  <outcomeDeclaration identifier="SCORE" cardinality="multiple" baseType="float" view="testConstructor" normalMaximum="10">
+    <defaultValue><value>1</value><value>2</value></defaultValue>
+  </outcomeDeclaration>
+  <templateDeclaration identifier="T" cardinality="single" baseType="integer" mathVariable="true"/>
+  <itemBody>
+    <p id="intro">Pick</p>
+    <choiceInteraction responseIdentifier="RESPONSE" maxChoices="1" data-extra="x">
+      <simpleChoice identifier="A" templateIdentifier="T" showHide="show">A</simpleChoice>
+    </choiceInteraction>
+    <rubricBlock view="scorer" id="key"><p>Key: A</p></rubricBlock>
+  </itemBody>
+  <responseProcessing template="http://www.imsglobal.org/question/qti_v2p1/rptemplates/match_correct" templateLocation="rp.xml"/>
+</assessmentItem>`
+
+	doc, err := qti21.New().Parse([]byte(itemXML))
+	if err != nil {
+		t.Fatalf("Failed to parse QTI 2.1 item: %v", err)
+	}
+
+	result, err := migrate(doc)
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+
+	resultStr := string(result)
+	expected := []string{
+		`adaptive="true" time-dependent="false"`,
+		`<qti-value field-identifier="x" base-type="integer">3</qti-value>`,
+		`<qti-area-map-entry shape="circle" coords="10,10,5" mapped-value="1">`,
+		`view="test-constructor" normal-maximum="10"`,
+		"<qti-value>1</qti-value>\n      <qti-value>2</qti-value>",
+		`<qti-template-declaration identifier="T" cardinality="single" base-type="integer" math-variable="true">`,
+		`<p id="intro">Pick</p>`,
+		`<qti-choice-interaction response-identifier="RESPONSE" max-choices="1" data-extra="x">`,
+		`<qti-simple-choice identifier="A" template-identifier="T" show-hide="show">A</qti-simple-choice>`,
+		`template-location="rp.xml"`,
+		`<qti-rubric-block view="scorer" id="key">`,
+		`<qti-content-body><p>Key: A</p></qti-content-body>`,
+	}
+	for _, s := range expected {
+		if !strings.Contains(resultStr, s) {
+			t.Errorf("Expected output to contain '%s', got:\n%s", s, resultStr)
+		}
+	}
+}
+
+func TestMigrate_Options(t *testing.T) {
+	itemBody := &models.ItemBody{XMLName: xml.Name{Local: "itemBody"}}
+	itemBody.Append(models.P{XMLName: xml.Name{Local: "p"}, Content: `<b>unclosed`})
+	doc := &models.QTIDocument{
+		Version: "2.1",
+		Items: []models.Item{
+			{Ident: "q 1", ItemBody: itemBody},
+			{Ident: "q2"},
+		},
+	}
+
+	if _, err := migrateFiles(doc, registry.MigrationOptions{Strict: true}); err == nil || !strings.Contains(err.Error(), "item q 1") {
+		t.Errorf("Expected a strict migration to fail on malformed markup, got %v", err)
+	}
+	if _, err := migrateFiles(doc, registry.MigrationOptions{Identifiers: registry.IdentifiersReject}); err == nil {
+		t.Error("Expected the invalid identifier to be rejected")
+	}
+	if _, err := migrateFiles(doc, registry.MigrationOptions{Profile: "2.2"}); err == nil {
+		t.Error("Expected QTI 3.0 output to refuse a 2.2 profile")
+	}
+
+	files, err := migrateFiles(doc, registry.MigrationOptions{Identifiers: registry.IdentifiersSanitize, Layout: registry.LayoutFlat})
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	if len(files) != 3 || files[1].Href != "q_1.xml" || files[2].Href != "q2.xml" {
+		t.Fatalf("Expected item files next to the test, got %+v", files)
+	}
+	if !strings.Contains(string(files[0].Content), `<qti-assessment-item-ref identifier="q_1" href="q_1.xml">`) {
+		t.Errorf("Expected a ref to the sanitized item, got:\n%s", files[0].Content)
+	}
+	if doc.Items[0].Ident != "q 1" {
+		t.Errorf("Expected the source document to be left alone, got %q", doc.Items[0].Ident)
+	}
+}
+
+func TestMigrate_MathMLNamespaces(t *testing.T) {
+	const mathML = "http://www.w3.org/1998/Math/MathML"
+	itemXML := `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" xmlns:m="http://www.w3.org/1998/Math/MathML" identifier="q001" title="Math">
+  <itemBody>
+    <m:math><m:mi>x</m:mi><m:mo>+</m:mo><m:mn>1</m:mn></m:math>
+    <math xmlns="http://www.w3.org/1998/Math/MathML"><mi>y</mi></math>
+    <p>Solve <m:math><m:mi>z</m:mi></m:math> and <math xmlns="http://www.w3.org/1998/Math/MathML"><mi>w</mi></math></p>
+  </itemBody>
+</assessmentItem>`
+
+	doc, err := qti21.New().Parse([]byte(itemXML))
+	if err != nil {
+		t.Fatalf("Failed to parse QTI 2.1 item: %v", err)
+	}
+	result, err := migrate(doc)
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+
+	// The decoder leaves the prefix as the namespace of an element whose
+	// prefix is not declared
+	decoder := xml.NewDecoder(strings.NewReader(string(result)))
+	identifiers := ""
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "math", "mi", "mo", "mn":
+			if start.Name.Space != mathML {
+				t.Errorf("Expected %s in the MathML namespace, got %q in:\n%s", start.Name.Local, start.Name.Space, result)
+			}
+			if start.Name.Local == "mi" {
+				var text string
+				if err := decoder.DecodeElement(&text, &start); err == nil {
+					identifiers += text
+				}
+			}
+		default:
+			if start.Name.Space != "http://www.imsglobal.org/xsd/imsqtiasi_v3p0" {
+				t.Errorf("Expected %s in the QTI 3.0 namespace, got %q", start.Name.Local, start.Name.Space)
+			}
+		}
+	}
+	if identifiers != "xyzw" {
+		t.Errorf("Expected every MathML identifier in the output, got %q in:\n%s", identifiers, result)
+	}
+}
@@ -0,0 +1,175 @@
+package qti21to30
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/qti-migrator/internal/parser/qti21"
+	"github.com/qti-migrator/internal/parser/qti30"
+	"github.com/qti-migrator/pkg/registry"
+)
+
+// roundTripItem and roundTripTest set every field the model carries for
+// standard QTI 2.1 items and tests
+const roundTripItem = `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="q1" title="Sum" adaptive="true" timeDependent="true" toolName="Editor" toolVersion="4.2">
+	<responseDeclaration identifier="RESPONSE" cardinality="single" baseType="identifier">
+		<correctResponse><value>A</value></correctResponse>
+		<mapping lowerBound="0" upperBound="2" defaultValue="0"><mapEntry mapKey="A" mappedValue="2"/></mapping>
+	</responseDeclaration>
+	<responseDeclaration identifier="PICKS" cardinality="multiple" baseType="identifier">
+		<correctResponse><value>B</value><value>C</value></correctResponse>
+	</responseDeclaration>
+	<responseDeclaration identifier="POINT" cardinality="single" baseType="point">
+		<areaMapping defaultValue="0"><areaMapEntry shape="circle" coords="10,10,5" mappedValue="1"/></areaMapping>
+	</responseDeclaration>
+	<outcomeDeclaration identifier="SCORE" cardinality="single" baseType="float" view="candidate" interpretation="Points" longInterpretation="scores.html" normalMaximum="2" normalMinimum="0" masteryValue="1">
+		<defaultValue><value>0</value></defaultValue>
+	</outcomeDeclaration>
+	<templateDeclaration identifier="X" cardinality="single" baseType="integer" paramVariable="true" mathVariable="true">
+		<defaultValue><value>1</value></defaultValue>
+	</templateDeclaration>
+	<templateProcessing>
+		<setTemplateValue identifier="X"><randomInteger min="1" max="9"/></setTemplateValue>
+	</templateProcessing>
+	<stylesheet href="style.css" type="text/css" media="screen" title="Default"/>
+	<itemBody id="body" class="sum" xml:lang="en">
+		<p>What is <printedVariable identifier="X"/> plus one?</p>
+		<choiceInteraction responseIdentifier="RESPONSE" shuffle="true" maxChoices="1" minChoices="1" orientation="horizontal">
+			<prompt>Pick one</prompt>
+			<simpleChoice identifier="A" fixed="true">Two</simpleChoice>
+		</choiceInteraction>
+		<choiceInteraction responseIdentifier="PICKS" maxChoices="0">
+			<simpleChoice identifier="B">Three</simpleChoice>
+			<simpleChoice identifier="C">Four</simpleChoice>
+		</choiceInteraction>
+	</itemBody>
+	<responseProcessing>
+		<responseCondition>
+			<responseIf>
+				<match><variable identifier="RESPONSE"/><correct identifier="RESPONSE"/></match>
+				<setOutcomeValue identifier="SCORE"><baseValue baseType="float">2</baseValue></setOutcomeValue>
+			</responseIf>
+		</responseCondition>
+	</responseProcessing>
+	<modalFeedback outcomeIdentifier="SCORE" identifier="fb" showHide="show" title="Well done">Right</modalFeedback>
+</assessmentItem>`
+
+const roundTripTest = `<assessmentTest xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="t1" title="Quiz" toolName="Editor" toolVersion="4.2">
+	<outcomeDeclaration identifier="TOTAL" cardinality="single" baseType="float"/>
+	<timeLimits minTime="60" maxTime="3600" allowLateSubmission="true"/>
+	<testPart identifier="part1" navigationMode="nonlinear" submissionMode="simultaneous">
+		<itemSessionControl maxAttempts="3"/>
+		<timeLimits maxTime="1800"/>
+		<assessmentSection identifier="s1" title="Section" visible="true">
+			<itemSessionControl maxAttempts="2"/>
+			<timeLimits maxTime="900"/>
+			<selection select="1" withReplacement="true"/>
+			<ordering shuffle="true"/>
+			<rubricBlock use="instructions" view="candidate"><p>Read carefully</p></rubricBlock>
+			<assessmentItemRef identifier="q1" href="q1.xml" category="easy">
+				<itemSessionControl maxAttempts="1"/>
+				<timeLimits maxTime="60"/>
+				<weight identifier="W1" value="2"/>
+			</assessmentItemRef>
+			<assessmentSection identifier="s2" title="Nested" visible="true">
+				<assessmentItemRef identifier="q2" href="q2.xml"/>
+			</assessmentSection>
+		</assessmentSection>
+		<testFeedback access="during" outcomeIdentifier="TOTAL" showHide="show" identifier="pf" title="Part">Part done</testFeedback>
+	</testPart>
+	<outcomeProcessing>
+		<setOutcomeValue identifier="TOTAL"><sum><testVariables variableIdentifier="SCORE" weightIdentifier="W1"/></sum></setOutcomeValue>
+	</outcomeProcessing>
+	<testFeedback access="atEnd" outcomeIdentifier="TOTAL" showHide="show" identifier="end" title="Done"><p>Finished</p></testFeedback>
+</assessmentTest>`
+
+func TestSerialize_RoundTrip(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		content string
+	}{
+		{name: "item", content: roundTripItem},
+		{name: "test", content: roundTripTest},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := qti21.New().Parse([]byte(tt.content))
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			files, err := migrateFiles(source, registry.MigrationOptions{})
+			if err != nil {
+				t.Fatalf("Migration failed: %v", err)
+			}
+			migrated, err := qti30.New().Parse(files[0].Content)
+			if err != nil {
+				t.Fatalf("Failed to parse the migrated document: %v\n%s", err, files[0].Content)
+			}
+
+			for _, field := range changedFields("document", reflect.ValueOf(*source), reflect.ValueOf(*migrated)) {
+				t.Errorf("%s differs between the QTI 2.1 source and QTI 3.0:\n%s", field, files[0].Content)
+			}
+		})
+	}
+}
+
+// changedFields returns the paths of the fields whose value differs between
+// source and migrated. Element names, the order of body elements and the
+// version of the document are not compared, since they are rewritten on the
+// way, and classes only need to be kept, since the migration adds the
+// shared classes of presentation attributes.
+func changedFields(path string, source, migrated reflect.Value) []string {
+	if source.Kind() != reflect.Struct && source.Kind() != reflect.Slice && source.Kind() != reflect.Ptr && source.Kind() != reflect.Interface {
+		if !reflect.DeepEqual(source.Interface(), migrated.Interface()) {
+			return []string{fmt.Sprintf("%s (%v, migrated %v)", path, source.Interface(), migrated.Interface())}
+		}
+		return nil
+	}
+
+	var changed []string
+	switch source.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if source.IsNil() != migrated.IsNil() {
+			return []string{path}
+		}
+		if !source.IsNil() {
+			return changedFields(path, source.Elem(), migrated.Elem())
+		}
+	case reflect.Slice:
+		if source.Len() != migrated.Len() {
+			return []string{fmt.Sprintf("%s (%d elements, migrated %d)", path, source.Len(), migrated.Len())}
+		}
+		for i := 0; i < source.Len(); i++ {
+			changed = append(changed, changedFields(path+"["+strconv.Itoa(i)+"]", source.Index(i), migrated.Index(i))...)
+		}
+	case reflect.Struct:
+		for i := 0; i < source.NumField(); i++ {
+			field := source.Type().Field(i)
+			if field.Name == "XMLName" || field.Name == "Order" || !field.IsExported() || path == "document" && field.Name == "Version" {
+				continue
+			}
+			if field.Name == "Class" {
+				changed = append(changed, changedClasses(path+".Class", source.Field(i).String(), migrated.Field(i).String())...)
+				continue
+			}
+			changed = append(changed, changedFields(path+"."+field.Name, source.Field(i), migrated.Field(i))...)
+		}
+	}
+	return changed
+}
+
+// changedClasses returns path when a class of source is missing in migrated.
+func changedClasses(path, source, migrated string) []string {
+	kept := make(map[string]bool)
+	for _, class := range strings.Fields(migrated) {
+		kept[class] = true
+	}
+	for _, class := range strings.Fields(source) {
+		if !kept[class] {
+			return []string{fmt.Sprintf("%s (%q, migrated %q)", path, source, migrated)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package qti21to30
+
+import (
+	"strings"
+	"unicode"
+)
+
+// lowerCaseElements are the QTI 2.1 body elements whose names contain no
+// capital letter and would otherwise be taken for HTML.
+var lowerCaseElements = map[string]bool{
+	"prompt":  true,
+	"gap":     true,
+	"hottext": true,
+}
+
+// isQTIElement tells a QTI 2.1 element, which is camelCase or one of a few
+// known lower-case names, from an HTML element.
+func isQTIElement(name string) bool {
+	return lowerCaseElements[name] || strings.IndexFunc(name, unicode.IsUpper) >= 0
+}
+
+func qtiElementName(name string) string {
+	return "qti-" + camelToKebab(name)
+}
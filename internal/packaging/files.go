@@ -0,0 +1,148 @@
+package packaging
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/qti-migrator/pkg/models"
+)
+
+// FromFiles builds a content package for the files of a migration to
+// version, with one resource per file and the test depending on its items.
+func FromFiles(files []models.File, version string) *Package {
+	pkg := &Package{
+		Manifest: &Manifest{Identifier: "MANIFEST"},
+		files:    make(map[string][]byte),
+	}
+	if len(files) > 0 {
+		pkg.Manifest.Identifier = resourceIdentifier("MANIFEST-"+files[0].Identifier, nil)
+	}
+	pkg.addFiles(files, "", version, nil)
+	pkg.Manifest.retarget(version)
+	return pkg
+}
+
+// WriteDir writes the files of the package, manifest included, below dir.
+func (p *Package) WriteDir(dir string) error {
+	manifestContent, err := p.Manifest.Marshal()
+	if err != nil {
+		return err
+	}
+
+	if err := writeFile(dir, ManifestName, manifestContent); err != nil {
+		return err
+	}
+	for _, name := range p.order {
+		if name == ManifestName {
+			continue
+		}
+		if err := writeFile(dir, name, p.files[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFile(dir, name string, data []byte) error {
+	target := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", name, err)
+	}
+	if err := os.WriteFile(target, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// addFiles stores migrated files below base and adds a resource for each,
+// listing the assets its file uses. When test is set, the test file replaces
+// that resource's main file instead of getting a resource of its own. The
+// test resource depends on the items it references.
+func (p *Package) addFiles(files []models.File, base, version string, test *Resource) {
+	used := make(map[string]bool)
+	for _, resource := range p.Manifest.Resources {
+		used[resource.Identifier] = true
+	}
+
+	var added []Resource
+	identifiers := make(map[string]string)
+	var testFile *models.File
+	for i := range files {
+		file := &files[i]
+		if test != nil && file.Kind == models.FileTest && testFile == nil {
+			testFile = file
+			continue
+		}
+		href := path.Join(base, file.Href)
+		p.SetFile(href, file.Content)
+		identifier := resourceIdentifier("RES-"+file.Identifier, used)
+		used[identifier] = true
+		identifiers[file.Href] = identifier
+		added = append(added, Resource{
+			Identifier: identifier,
+			Type:       resourceTypeFor(version, file.Kind),
+			Href:       href,
+			Files:      append([]File{{Href: href}}, assetFiles(base, file.Assets)...),
+		})
+	}
+
+	if testFile != nil {
+		p.SetFile(test.MainFile(), testFile.Content)
+		test.Type = resourceTypeFor(version, models.FileTest)
+		addDependencies(test, testFile.Dependencies, identifiers)
+	}
+	for i := range added {
+		for _, file := range files {
+			if file.Kind == models.FileTest && path.Join(base, file.Href) == added[i].Href {
+				addDependencies(&added[i], file.Dependencies, identifiers)
+			}
+		}
+	}
+	p.Manifest.Resources = append(p.Manifest.Resources, added...)
+}
+
+// assetFiles returns the file entries for the assets of a file below base.
+// Assets outside the package are left out.
+func assetFiles(base string, assets []string) []File {
+	var files []File
+	for _, asset := range assets {
+		href := path.Join(base, asset)
+		if href != ".." && !strings.HasPrefix(href, "../") {
+			files = append(files, File{Href: href})
+		}
+	}
+	return files
+}
+
+func addDependencies(resource *Resource, hrefs []string, identifiers map[string]string) {
+	for _, href := range hrefs {
+		identifier, ok := identifiers[href]
+		if ok && !resource.HasDependency(identifier) {
+			resource.Dependencies = append(resource.Dependencies, Dependency{IdentifierRef: identifier})
+		}
+	}
+}
+
+// resourceIdentifier turns name into an XML identifier that is not in used.
+func resourceIdentifier(name string, used map[string]bool) string {
+	identifier := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, strings.TrimSuffix(name, "-"))
+	if !used[identifier] {
+		return identifier
+	}
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", identifier, i)
+		if !used[candidate] {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package packaging
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+const (
+	ManifestName = "imsmanifest.xml"
+
+	namespaceCP   = "http://www.imsglobal.org/xsd/imscp_v1p1"
+	namespaceCP30 = "http://www.imsglobal.org/xsd/qti/qtiv3p0/imscp_v1p1"
+
+	ResourceTypeWebContent = "webcontent"
+)
+
+// Manifest is the imsmanifest.xml document of an IMS Content Package.
+// Only the parts the migrator rewrites are modelled; everything else is
+// carried through as raw XML.
+type Manifest struct {
+	XMLName       xml.Name          `xml:"manifest"`
+	Namespace     string            `xml:"xmlns,attr,omitempty"`
+	Identifier    string            `xml:"identifier,attr"`
+	Version       string            `xml:"version,attr,omitempty"`
+	Attrs         []xml.Attr        `xml:",any,attr"`
+	Metadata      *ManifestMetadata `xml:"metadata,omitempty"`
+	Organizations Organizations     `xml:"organizations"`
+	Resources     []Resource        `xml:"resources>resource"`
+}
+
+type ManifestMetadata struct {
+	Schema        string   `xml:"schema,omitempty"`
+	SchemaVersion string   `xml:"schemaversion,omitempty"`
+	Extra         []RawXML `xml:",any"`
+}
+
+type Organizations struct {
+	Default string `xml:"default,attr,omitempty"`
+	Content string `xml:",innerxml"`
+}
+
+type Resource struct {
+	Identifier   string            `xml:"identifier,attr"`
+	Type         string            `xml:"type,attr"`
+	Href         string            `xml:"href,attr,omitempty"`
+	Metadata     *ResourceMetadata `xml:"metadata,omitempty"`
+	Files        []File            `xml:"file"`
+	Dependencies []Dependency      `xml:"dependency,omitempty"`
+}
+
+type ResourceMetadata struct {
+	Extra []RawXML `xml:",any"`
+}
+
+type File struct {
+	Href string `xml:"href,attr"`
+}
+
+type Dependency struct {
+	IdentifierRef string `xml:"identifierref,attr"`
+}
+
+// RawXML keeps an element we do not interpret, including its attributes and
+// inner markup, so it survives a manifest rewrite.
+type RawXML struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Content string     `xml:",innerxml"`
+}
+
+func ParseManifest(content []byte) (*Manifest, error) {
+	var manifest Manifest
+	if err := xml.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", ManifestName, err)
+	}
+	return &manifest, nil
+}
+
+func (m *Manifest) Marshal() ([]byte, error) {
+	output, err := xml.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s: %w", ManifestName, err)
+	}
+	return append([]byte(xml.Header), output...), nil
+}
+
+// Resource returns the resource with the given identifier, or nil.
+func (m *Manifest) Resource(identifier string) *Resource {
+	for i := range m.Resources {
+		if m.Resources[i].Identifier == identifier {
+			return &m.Resources[i]
+		}
+	}
+	return nil
+}
+
+// MainFile returns the href of the file that holds the resource's content.
+func (r *Resource) MainFile() string {
+	if r.Href != "" {
+		return r.Href
+	}
+	if len(r.Files) > 0 {
+		return r.Files[0].Href
+	}
+	return ""
+}
+
+func (r *Resource) HasFile(href string) bool {
+	for _, file := range r.Files {
+		if file.Href == href {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Resource) HasDependency(identifier string) bool {
+	for _, dep := range r.Dependencies {
+		if dep.IdentifierRef == identifier {
+			return true
+		}
+	}
+	return false
+}
+
+// IsQTI tells whether the resource is QTI content, by its type.
+func (r *Resource) IsQTI() bool {
+	_, _, ok := parseResourceType(r.Type)
+	return ok
+}
+
+// retarget switches the manifest namespace and package metadata to the
+// conventions of the target QTI version. Prefixed namespace declarations are
+// kept so that raw metadata blocks stay well-formed.
+func (m *Manifest) retarget(toVersion string) {
+	namespace := namespaceCP
+	schema := "QTIv2.1 Package"
+	schemaVersion := "1.0.0"
+	switch {
+	case strings.HasPrefix(toVersion, "2.2"):
+		schema = "QTIv2.2 Package"
+	case strings.HasPrefix(toVersion, "3.0"):
+		namespace = namespaceCP30
+		schema = "QTI Package"
+		schemaVersion = "3.0.0"
+	}
+
+	m.Namespace = namespace
+
+	var attrs []xml.Attr
+	for _, attr := range m.Attrs {
+		switch {
+		case attr.Name.Space == "xmlns" && attr.Name.Local != "xsi":
+			attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "xmlns:" + attr.Name.Local}, Value: attr.Value})
+		case attr.Name.Space == "":
+			attrs = append(attrs, attr)
+		}
+	}
+	m.Attrs = attrs
+
+	if m.Metadata == nil {
+		m.Metadata = &ManifestMetadata{}
+	}
+	m.Metadata.Schema = schema
+	m.Metadata.SchemaVersion = schemaVersion
+}
@@ -0,0 +1,263 @@
+package packaging
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/qti-migrator/internal/migrator"
+	"github.com/qti-migrator/internal/preprocessor"
+	"github.com/qti-migrator/pkg/registry"
+)
+
+// Migrator runs every QTI resource of a content package through the regular
+// document migration and rewrites the manifest for the target version.
+type Migrator struct {
+	// Options of the migration of each resource
+	Options   registry.MigrationOptions
+	verbosity int
+}
+
+// ResourceReport is the analysis of a single QTI resource in a package.
+type ResourceReport struct {
+	ResourceID string
+	Href       string
+	Report     *preprocessor.AnalysisReport
+}
+
+func New(verbosity int) *Migrator {
+	return &Migrator{
+		verbosity: verbosity,
+	}
+}
+
+func (m *Migrator) Analyze(pkg *Package, fromVersion, toVersion string) ([]ResourceReport, error) {
+	processor := preprocessor.New(m.verbosity)
+	processor.Options = m.Options
+
+	var reports []ResourceReport
+	for _, resource := range pkg.Manifest.Resources {
+		content, ok, err := m.qtiResourceContent(pkg, &resource, fromVersion)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		// Findings of the source are labelled with the version of its
+		// resource type, e.g. 2.2
+		processor.SourceVersion, _, _ = parseResourceType(resource.Type)
+		report, err := processor.Analyze(content, fromVersion, toVersion)
+		if err != nil {
+			return nil, fmt.Errorf("error analyzing resource %s (%s): %w", resource.Identifier, resource.MainFile(), err)
+		}
+		reports = append(reports, ResourceReport{
+			ResourceID: resource.Identifier,
+			Href:       resource.MainFile(),
+			Report:     report,
+		})
+	}
+
+	return reports, nil
+}
+
+// Migrate migrates the package in place: QTI resource files are replaced by
+// their migrated content, resource types are switched to the target version
+// and asset files owned by webcontent resources become dependencies.
+func (m *Migrator) Migrate(pkg *Package, fromVersion, toVersion string) error {
+	service := migrator.New()
+	service.Options = m.Options
+	owners := m.assetOwners(pkg.Manifest)
+
+	// Resources are typed for the profile written, e.g. 2.2
+	target := toVersion
+	if m.Options.Profile != "" {
+		target = m.Options.Profile
+	}
+
+	for i := range pkg.Manifest.Resources {
+		resource := &pkg.Manifest.Resources[i]
+		content, ok, err := m.qtiResourceContent(pkg, resource, fromVersion)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		files, err := service.MigrateFiles(content, fromVersion, toVersion)
+		if err != nil {
+			return fmt.Errorf("error migrating resource %s (%s): %w", resource.Identifier, resource.MainFile(), err)
+		}
+
+		if len(files) == 1 {
+			pkg.SetFile(resource.MainFile(), files[0].Content)
+			resource.Type = resourceTypeFor(target, documentKind(files[0].Content))
+			m.rewriteDependencies(resource, owners)
+			continue
+		}
+
+		// The resource becomes the test; its items are added next to it and
+		// depend on the webcontent resources owning their assets
+		added := len(pkg.Manifest.Resources)
+		pkg.addFiles(files, path.Dir(normalizePath(resource.MainFile())), target, resource)
+		m.rewriteDependencies(&pkg.Manifest.Resources[i], owners)
+		for j := added; j < len(pkg.Manifest.Resources); j++ {
+			m.rewriteDependencies(&pkg.Manifest.Resources[j], owners)
+		}
+	}
+
+	pkg.Manifest.retarget(target)
+	return nil
+}
+
+// qtiResourceContent returns the main file of a QTI resource. Resources that
+// are not QTI content (webcontent, learning applications, ...) report false.
+func (m *Migrator) qtiResourceContent(pkg *Package, resource *Resource, fromVersion string) ([]byte, bool, error) {
+	version, _, ok := parseResourceType(resource.Type)
+	if !ok {
+		return nil, false, nil
+	}
+	if !versionMatches(version, fromVersion) {
+		return nil, false, fmt.Errorf("resource %s has type %s, which is not QTI %s content", resource.Identifier, resource.Type, fromVersion)
+	}
+
+	href := resource.MainFile()
+	if href == "" {
+		return nil, false, fmt.Errorf("resource %s does not reference a file", resource.Identifier)
+	}
+	content, found := pkg.File(href)
+	if !found {
+		return nil, false, fmt.Errorf("resource %s references missing file %s", resource.Identifier, href)
+	}
+	return content, true, nil
+}
+
+func (m *Migrator) assetOwners(manifest *Manifest) map[string]string {
+	owners := make(map[string]string)
+	for _, resource := range manifest.Resources {
+		if _, _, ok := parseResourceType(resource.Type); ok {
+			continue
+		}
+		for _, file := range resource.Files {
+			owners[normalizePath(file.Href)] = resource.Identifier
+		}
+	}
+	return owners
+}
+
+// rewriteDependencies replaces file entries of a QTI resource that belong to
+// another resource with a dependency on that resource.
+func (m *Migrator) rewriteDependencies(resource *Resource, owners map[string]string) {
+	mainFile := normalizePath(resource.MainFile())
+
+	var files []File
+	for _, file := range resource.Files {
+		name := normalizePath(file.Href)
+		owner, owned := owners[name]
+		if name == mainFile || !owned || owner == resource.Identifier {
+			files = append(files, file)
+			continue
+		}
+		if !resource.HasDependency(owner) {
+			resource.Dependencies = append(resource.Dependencies, Dependency{IdentifierRef: owner})
+		}
+	}
+	resource.Files = files
+}
+
+// parseResourceType splits a QTI resource type such as "imsqti_item_xmlv2p1"
+// into its version ("2.1") and kind ("item"). QTI 1.2 types have no kind.
+func parseResourceType(resourceType string) (version, kind string, ok bool) {
+	if !strings.HasPrefix(resourceType, "imsqti_") {
+		return "", "", false
+	}
+	rest := strings.TrimPrefix(resourceType, "imsqti_")
+	idx := strings.LastIndex(rest, "xmlv")
+	if idx < 0 {
+		return "", "", false
+	}
+	kind = strings.TrimSuffix(rest[:idx], "_")
+	version = strings.Replace(rest[idx+len("xmlv"):], "p", ".", 1)
+	return version, kind, true
+}
+
+func resourceTypeFor(version, kind string) string {
+	suffix := "xmlv" + strings.Replace(version, ".", "p", 1)
+	if strings.HasPrefix(version, "1.") {
+		return "imsqti_" + suffix
+	}
+	return fmt.Sprintf("imsqti_%s_%s", kind, suffix)
+}
+
+func versionMatches(resourceVersion, version string) bool {
+	family := func(v string) string {
+		if strings.HasPrefix(v, "2.1") || strings.HasPrefix(v, "2.2") {
+			return "2.x"
+		}
+		return v
+	}
+	return family(resourceVersion) == family(version)
+}
+
+// documentKind tells whether a migrated document is an item or a test by
+// looking at its root element.
+func documentKind(content []byte) string {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	root := ""
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if root == "" {
+			root = start.Name.Local
+			if root != "questestinterop" {
+				break
+			}
+			continue
+		}
+		if start.Name.Local == "assessment" {
+			return "test"
+		}
+		if err := decoder.Skip(); err != nil {
+			break
+		}
+	}
+
+	switch root {
+	case "assessmentTest", "qti-assessment-test":
+		return "test"
+	default:
+		return "item"
+	}
+}
+
+// SourceVersion returns the QTI version of the package's QTI resources as
+// declared by their manifest resource types.
+func SourceVersion(pkg *Package) (string, error) {
+	version := ""
+	for _, resource := range pkg.Manifest.Resources {
+		resourceVersion, _, ok := parseResourceType(resource.Type)
+		if !ok {
+			continue
+		}
+		if version != "" && !versionMatches(resourceVersion, version) {
+			return "", fmt.Errorf("content package mixes QTI %s and QTI %s resources", version, resourceVersion)
+		}
+		if version == "" {
+			version = resourceVersion
+		}
+	}
+	if version == "" {
+		return "", fmt.Errorf("content package has no QTI resources")
+	}
+	return version, nil
+}
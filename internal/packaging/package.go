@@ -0,0 +1,136 @@
+package packaging
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Package is an IMS Content Package held in memory: the parsed manifest plus
+// every file of the archive, keyed by its slash-separated path.
+type Package struct {
+	Manifest *Manifest
+	files    map[string][]byte
+	order    []string
+}
+
+// IsPackage reports whether content looks like a zip archive.
+func IsPackage(content []byte) bool {
+	return bytes.HasPrefix(content, []byte("PK\x03\x04"))
+}
+
+func Read(content []byte) (*Package, error) {
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open content package: %w", err)
+	}
+
+	pkg := &Package{files: make(map[string][]byte)}
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s in content package: %w", file.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s in content package: %w", file.Name, err)
+		}
+		pkg.SetFile(file.Name, data)
+	}
+
+	manifestContent, ok := pkg.File(ManifestName)
+	if !ok {
+		return nil, fmt.Errorf("content package has no %s", ManifestName)
+	}
+	pkg.Manifest, err = ParseManifest(manifestContent)
+	if err != nil {
+		return nil, err
+	}
+
+	return pkg, nil
+}
+
+// File returns the content of the file at href, resolved against the package
+// root. Hrefs in manifests are URL-encoded, so both forms are accepted.
+func (p *Package) File(href string) ([]byte, bool) {
+	data, ok := p.files[normalizePath(href)]
+	return data, ok
+}
+
+func (p *Package) SetFile(href string, data []byte) {
+	name := normalizePath(href)
+	if _, exists := p.files[name]; !exists {
+		p.order = append(p.order, name)
+	}
+	p.files[name] = data
+}
+
+// Files returns the paths of all files in the package in archive order.
+func (p *Package) Files() []string {
+	return append([]string(nil), p.order...)
+}
+
+// QTIFiles returns the main file of a QTI resource followed by the main files
+// of the QTI resources it depends on, such as the items of a test.
+func (p *Package) QTIFiles(resource *Resource) []string {
+	files := []string{resource.MainFile()}
+	for _, dependency := range resource.Dependencies {
+		if dependent := p.Manifest.Resource(dependency.IdentifierRef); dependent != nil && dependent.IsQTI() {
+			files = append(files, dependent.MainFile())
+		}
+	}
+	return files
+}
+
+// Write serialises the package as a zip archive with the manifest first.
+func (p *Package) Write(w io.Writer) error {
+	manifestContent, err := p.Manifest.Marshal()
+	if err != nil {
+		return err
+	}
+
+	writer := zip.NewWriter(w)
+	if err := writeEntry(writer, ManifestName, manifestContent); err != nil {
+		return err
+	}
+	for _, name := range p.order {
+		if name == ManifestName {
+			continue
+		}
+		if err := writeEntry(writer, name, p.files[name]); err != nil {
+			return err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to finish content package: %w", err)
+	}
+	return nil
+}
+
+func writeEntry(writer *zip.Writer, name string, data []byte) error {
+	entry, err := writer.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s to content package: %w", name, err)
+	}
+	if _, err := entry.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s to content package: %w", name, err)
+	}
+	return nil
+}
+
+func normalizePath(href string) string {
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+	href = strings.ReplaceAll(href, "\\", "/")
+	return strings.TrimPrefix(path.Clean("/"+href), "/")
+}
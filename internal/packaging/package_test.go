@@ -0,0 +1,396 @@
+package packaging
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/qti-migrator/internal/packaging/packagetest"
+	"github.com/qti-migrator/pkg/models"
+)
+
+const testManifest12 = `<?xml version="1.0" encoding="UTF-8"?>
+<manifest identifier="MANIFEST1" xmlns="http://www.imsglobal.org/xsd/imscp_v1p1" xmlns:imsmd="http://www.imsglobal.org/xsd/imsmd_v1p2">
+	<metadata>
+		<schema>IMS Content</schema>
+		<schemaversion>1.1.3</schemaversion>
+	</metadata>
+	<organizations/>
+	<resources>
+		<resource identifier="RES1" type="imsqti_xmlv1p2" href="items/q001.xml">
+			<file href="items/q001.xml"/>
+			<file href="media/image.png"/>
+		</resource>
+		<resource identifier="MEDIA1" type="webcontent">
+			<file href="media/image.png"/>
+		</resource>
+	</resources>
+</manifest>`
+
+const testItem12 = `<?xml version="1.0" encoding="UTF-8"?>
+<questestinterop version="1.2">
+	<item ident="q001" title="Test Question">
+		<presentation>
+			<material>
+				<mattext texttype="text/plain">What is 2 + 2?</mattext>
+			</material>
+			<response_lid ident="RESPONSE" rcardinality="single">
+				<render_choice>
+					<response_label ident="A"><material><mattext>3</mattext></material></response_label>
+					<response_label ident="B"><material><mattext>4</mattext></material></response_label>
+				</render_choice>
+			</response_lid>
+		</presentation>
+	</item>
+</questestinterop>`
+
+func TestIsPackage(t *testing.T) {
+	content := packagetest.Zip(t, map[string]string{ManifestName: testManifest12})
+	if !IsPackage(content) {
+		t.Error("Expected zip content to be detected as a package")
+	}
+	if IsPackage([]byte(testItem12)) {
+		t.Error("Expected XML content not to be detected as a package")
+	}
+}
+
+func TestRead_MissingManifest(t *testing.T) {
+	content := packagetest.Zip(t, map[string]string{"items/q001.xml": testItem12})
+
+	_, err := Read(content)
+	if err == nil {
+		t.Fatal("Expected error for package without manifest")
+	}
+	if !strings.Contains(err.Error(), ManifestName) {
+		t.Errorf("Expected error to mention %s, got: %v", ManifestName, err)
+	}
+}
+
+func TestMigrator_Migrate_QTI12to21(t *testing.T) {
+	content := packagetest.Zip(t, map[string]string{
+		ManifestName:      testManifest12,
+		"items/q001.xml":  testItem12,
+		"media/image.png": "PNGDATA",
+	})
+
+	pkg, err := Read(content)
+	if err != nil {
+		t.Fatalf("Failed to read package: %v", err)
+	}
+
+	m := New(1)
+	reports, err := m.Analyze(pkg, "1.2", "2.1")
+	if err != nil {
+		t.Fatalf("Analysis failed: %v", err)
+	}
+	if len(reports) != 1 || reports[0].ResourceID != "RES1" {
+		t.Fatalf("Expected one report for RES1, got %+v", reports)
+	}
+
+	if err := m.Migrate(pkg, "1.2", "2.1"); err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := pkg.Write(&out); err != nil {
+		t.Fatalf("Failed to write package: %v", err)
+	}
+
+	migrated, err := Read(out.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to read migrated package: %v", err)
+	}
+
+	resource := migrated.Manifest.Resource("RES1")
+	if resource == nil {
+		t.Fatal("Expected resource RES1 in migrated manifest")
+	}
+	if resource.Type != "imsqti_item_xmlv2p1" {
+		t.Errorf("Expected resource type imsqti_item_xmlv2p1, got %s", resource.Type)
+	}
+	if !resource.HasDependency("MEDIA1") {
+		t.Error("Expected dependency on MEDIA1")
+	}
+	if resource.HasFile("media/image.png") {
+		t.Error("Expected asset file to be replaced by a dependency")
+	}
+
+	if migrated.Manifest.Metadata.Schema != "QTIv2.1 Package" {
+		t.Errorf("Expected schema 'QTIv2.1 Package', got %s", migrated.Manifest.Metadata.Schema)
+	}
+	if migrated.Manifest.Namespace != namespaceCP {
+		t.Errorf("Expected manifest namespace %s, got %s", namespaceCP, migrated.Manifest.Namespace)
+	}
+
+	item, ok := migrated.File("items/q001.xml")
+	if !ok {
+		t.Fatal("Expected migrated item file")
+	}
+	if !strings.Contains(string(item), "<choiceInteraction") {
+		t.Error("Expected item file to contain migrated QTI 2.1 content")
+	}
+
+	asset, ok := migrated.File("media/image.png")
+	if !ok || string(asset) != "PNGDATA" {
+		t.Error("Expected asset file to be copied unchanged")
+	}
+}
+
+func TestMigrator_Migrate_WrongResourceVersion(t *testing.T) {
+	content := packagetest.Zip(t, map[string]string{
+		ManifestName:     testManifest12,
+		"items/q001.xml": testItem12,
+	})
+
+	pkg, err := Read(content)
+	if err != nil {
+		t.Fatalf("Failed to read package: %v", err)
+	}
+
+	err = New(1).Migrate(pkg, "2.1", "3.0")
+	if err == nil {
+		t.Fatal("Expected error when resource version does not match source version")
+	}
+	if !strings.Contains(err.Error(), "imsqti_xmlv1p2") {
+		t.Errorf("Expected error to mention resource type, got: %v", err)
+	}
+}
+
+func TestMigrator_Migrate_SplitsItems(t *testing.T) {
+	manifest := `<manifest identifier="M" xmlns="http://www.imsglobal.org/xsd/imscp_v1p1">
+	<organizations/>
+	<resources>
+		<resource identifier="BANK" type="imsqti_item_xmlv2p1" href="bank/bank.xml">
+			<file href="bank/bank.xml"/>
+		</resource>
+	</resources>
+</manifest>`
+	bank := `<questestinterop version="2.1">
+	<item ident="q001" title="First"><itemBody><p>One</p></itemBody></item>
+	<item ident="q002" title="Second"><itemBody><p>Two</p></itemBody></item>
+</questestinterop>`
+
+	pkg, err := Read(packagetest.Zip(t, map[string]string{ManifestName: manifest, "bank/bank.xml": bank}))
+	if err != nil {
+		t.Fatalf("Failed to read package: %v", err)
+	}
+	if err := New(1).Migrate(pkg, "2.1", "3.0"); err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+
+	test := pkg.Manifest.Resource("BANK")
+	if test == nil || test.Type != "imsqti_test_xmlv3p0" {
+		t.Fatalf("Expected BANK to become a QTI 3.0 test, got %+v", test)
+	}
+	if !test.HasDependency("RES-q001") || !test.HasDependency("RES-q002") {
+		t.Errorf("Expected the test to depend on its items, got %+v", test.Dependencies)
+	}
+	content, _ := pkg.File("bank/bank.xml")
+	if !strings.Contains(string(content), `<qti-assessment-item-ref identifier="q001" href="items/q001.xml">`) {
+		t.Errorf("Expected the test to reference its items, got:\n%s", content)
+	}
+
+	item := pkg.Manifest.Resource("RES-q002")
+	if item == nil || item.Type != "imsqti_item_xmlv3p0" || item.Href != "bank/items/q002.xml" {
+		t.Fatalf("Expected an item resource for q002, got %+v", item)
+	}
+	if content, ok := pkg.File("bank/items/q002.xml"); !ok || !strings.Contains(string(content), "<qti-assessment-item") {
+		t.Error("Expected the item file next to the test")
+	}
+	if files := strings.Join(pkg.QTIFiles(test), " "); files != "bank/bank.xml bank/items/q001.xml bank/items/q002.xml" {
+		t.Errorf("Expected the QTI files of the test and its items, got %s", files)
+	}
+}
+
+func TestMigrator_Migrate_ItemAssets(t *testing.T) {
+	manifest := `<manifest identifier="M" xmlns="http://www.imsglobal.org/xsd/imscp_v1p1">
+	<organizations/>
+	<resources>
+		<resource identifier="QUIZ" type="imsqti_xmlv1p2" href="quiz/quiz.xml">
+			<file href="quiz/quiz.xml"/>
+			<file href="quiz/media/map.png"/>
+			<file href="quiz/media/chart.png"/>
+		</resource>
+		<resource identifier="MEDIA" type="webcontent">
+			<file href="quiz/media/map.png"/>
+		</resource>
+	</resources>
+</manifest>`
+	quiz := `<questestinterop>
+	<item ident="q001" title="Map">
+		<presentation><material><mattext>Where?</mattext><matimage imagtype="image/png" uri="media/map.png"/></material></presentation>
+	</item>
+	<item ident="q002" title="Chart">
+		<presentation><material><mattext>How many?</mattext><matimage imagtype="image/png" uri="media/chart.png"/></material></presentation>
+	</item>
+</questestinterop>`
+
+	pkg, err := Read(packagetest.Zip(t, map[string]string{
+		ManifestName:           manifest,
+		"quiz/quiz.xml":        quiz,
+		"quiz/media/map.png":   "map",
+		"quiz/media/chart.png": "chart",
+	}))
+	if err != nil {
+		t.Fatalf("Failed to read package: %v", err)
+	}
+	if err := New(1).Migrate(pkg, "1.2", "2.1"); err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+
+	for _, tt := range []struct {
+		resource string
+		image    string
+	}{
+		{resource: "RES-q001", image: "quiz/media/map.png"},
+		{resource: "RES-q002", image: "quiz/media/chart.png"},
+	} {
+		item := pkg.Manifest.Resource(tt.resource)
+		if item == nil {
+			t.Fatalf("Expected an item resource %s, got %+v", tt.resource, pkg.Manifest.Resources)
+		}
+		content, _ := pkg.File(item.MainFile())
+		match := regexp.MustCompile(`<img src="([^"]+)"`).FindSubmatch(content)
+		if match == nil {
+			t.Fatalf("Expected %s to show an image, got:\n%s", item.MainFile(), content)
+		}
+		if resolved := path.Join(path.Dir(item.MainFile()), string(match[1])); resolved != tt.image {
+			t.Errorf("Expected the image of %s to resolve to %s, got %s", item.MainFile(), tt.image, resolved)
+		}
+		if _, ok := pkg.File(tt.image); !ok {
+			t.Errorf("Expected %s in the package", tt.image)
+		}
+	}
+
+	// map.png belongs to the webcontent resource, chart.png to no resource
+	if item := pkg.Manifest.Resource("RES-q001"); !item.HasDependency("MEDIA") || item.HasFile("quiz/media/map.png") {
+		t.Errorf("Expected q001 to depend on the resource owning its image, got %+v", item)
+	}
+	if item := pkg.Manifest.Resource("RES-q002"); !item.HasFile("quiz/media/chart.png") {
+		t.Errorf("Expected q002 to list its image, got %+v", item)
+	}
+}
+
+func TestFromFiles_WriteDir(t *testing.T) {
+	files := []models.File{
+		{Href: "exam.xml", Identifier: "exam", Kind: models.FileTest, Content: []byte("<test/>"), Dependencies: []string{"items/q 1.xml"}},
+		{Href: "items/q 1.xml", Identifier: "q 1", Kind: models.FileItem, Content: []byte("<item/>")},
+	}
+
+	pkg := FromFiles(files, "3.0")
+	if pkg.Manifest.Namespace != namespaceCP30 {
+		t.Errorf("Expected manifest namespace %s, got %s", namespaceCP30, pkg.Manifest.Namespace)
+	}
+	test := pkg.Manifest.Resource("RES-exam")
+	if test == nil || test.Type != "imsqti_test_xmlv3p0" || !test.HasDependency("RES-q_1") {
+		t.Fatalf("Expected test resource depending on RES-q_1, got %+v", pkg.Manifest.Resources)
+	}
+
+	dir := t.TempDir()
+	if err := pkg.WriteDir(dir); err != nil {
+		t.Fatalf("Failed to write directory: %v", err)
+	}
+	for _, name := range []string{ManifestName, "exam.xml", "items/q 1.xml"} {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			t.Errorf("Expected %s to be written: %v", name, err)
+		}
+	}
+}
+
+func TestMigrator_Migrate_Profile(t *testing.T) {
+	pkg, err := Read(packagetest.Zip(t, map[string]string{
+		ManifestName:     testManifest12,
+		"items/q001.xml": testItem12,
+	}))
+	if err != nil {
+		t.Fatalf("Failed to read package: %v", err)
+	}
+
+	m := New(1)
+	m.Options.Profile = "2.2"
+	if err := m.Migrate(pkg, "1.2", "2.1"); err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+
+	if resource := pkg.Manifest.Resource("RES1"); resource == nil || resource.Type != "imsqti_item_xmlv2p2" {
+		t.Errorf("Expected RES1 to be typed for QTI 2.2, got %+v", resource)
+	}
+	if pkg.Manifest.Metadata.Schema != "QTIv2.2 Package" {
+		t.Errorf("Expected a QTI 2.2 manifest, got schema %q", pkg.Manifest.Metadata.Schema)
+	}
+	content, _ := pkg.File("items/q001.xml")
+	if !strings.Contains(string(content), "imsqti_v2p2") {
+		t.Errorf("Expected a QTI 2.2 item, got:\n%s", content)
+	}
+}
+
+func TestParseResourceType(t *testing.T) {
+	testCases := []struct {
+		input   string
+		version string
+		kind    string
+		ok      bool
+	}{
+		{"imsqti_xmlv1p2", "1.2", "", true},
+		{"imsqti_item_xmlv2p1", "2.1", "item", true},
+		{"imsqti_test_xmlv2p2", "2.2", "test", true},
+		{"imsqti_item_xmlv3p0", "3.0", "item", true},
+		{"webcontent", "", "", false},
+	}
+
+	for _, tc := range testCases {
+		version, kind, ok := parseResourceType(tc.input)
+		if version != tc.version || kind != tc.kind || ok != tc.ok {
+			t.Errorf("parseResourceType(%s): expected (%s, %s, %t), got (%s, %s, %t)",
+				tc.input, tc.version, tc.kind, tc.ok, version, kind, ok)
+		}
+	}
+}
+
+func TestResource_IsQTI(t *testing.T) {
+	for resourceType, expected := range map[string]bool{
+		"imsqti_xmlv1p2":      true,
+		"imsqti_item_xmlv3p0": true,
+		"webcontent":          false,
+		"associatedcontent/imscc_xmlv1p1/learning-application-resource": false,
+	} {
+		resource := Resource{Type: resourceType}
+		if resource.IsQTI() != expected {
+			t.Errorf("IsQTI() of %s = %v, expected %v", resourceType, !expected, expected)
+		}
+	}
+}
+
+func TestResourceTypeFor(t *testing.T) {
+	testCases := map[string][2]string{
+		"imsqti_xmlv1p2":      {"1.2", "item"},
+		"imsqti_item_xmlv2p1": {"2.1", "item"},
+		"imsqti_test_xmlv3p0": {"3.0", "test"},
+	}
+
+	for expected, args := range testCases {
+		if result := resourceTypeFor(args[0], args[1]); result != expected {
+			t.Errorf("resourceTypeFor(%s, %s): expected %s, got %s", args[0], args[1], expected, result)
+		}
+	}
+}
+
+func TestSourceVersion(t *testing.T) {
+	content := packagetest.Zip(t, map[string]string{ManifestName: testManifest12})
+	pkg, err := Read(content)
+	if err != nil {
+		t.Fatalf("Failed to read package: %v", err)
+	}
+
+	version, err := SourceVersion(pkg)
+	if err != nil {
+		t.Fatalf("Failed to get source version: %v", err)
+	}
+	if version != "1.2" {
+		t.Errorf("Expected source version 1.2, got %s", version)
+	}
+}
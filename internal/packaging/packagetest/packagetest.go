@@ -0,0 +1,36 @@
+// Package packagetest builds content packages for tests.
+package packagetest
+
+import (
+	"archive/zip"
+	"bytes"
+	"sort"
+	"testing"
+)
+
+// Zip returns a zip archive holding files, keyed by their path in the
+// archive, in order of their paths.
+func Zip(t testing.TB, files map[string]string) []byte {
+	t.Helper()
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for _, name := range names {
+		entry, err := writer.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to create zip entry: %v", err)
+		}
+		if _, err := entry.Write([]byte(files[name])); err != nil {
+			t.Fatalf("Failed to write zip entry: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close zip: %v", err)
+	}
+	return buf.Bytes()
+}
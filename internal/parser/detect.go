@@ -0,0 +1,170 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	NamespaceQTI12 = "http://www.imsglobal.org/xsd/ims_qtiasiv1p2"
+	NamespaceQTI21 = "http://www.imsglobal.org/xsd/imsqti_v2p1"
+	NamespaceQTI22 = "http://www.imsglobal.org/xsd/imsqti_v2p2"
+	NamespaceQTI30 = "http://www.imsglobal.org/xsd/imsqtiasi_v3p0"
+)
+
+// Detection is the result of sniffing a document for its QTI version.
+type Detection struct {
+	Version     string
+	RootElement string
+	Namespace   string
+	Evidence    []string
+}
+
+// Detect looks at the root element name, its namespace URI, the version
+// attribute and the xsi:schemaLocation hints and returns the QTI version all
+// of them agree on. Conflicting signals are reported as an error rather than
+// guessed.
+func Detect(content []byte) (*Detection, error) {
+	root, err := readRootElement(content)
+	if err != nil {
+		return nil, err
+	}
+
+	detection := &Detection{
+		RootElement: root.Name.Local,
+		Namespace:   root.Name.Space,
+	}
+
+	candidates := versionsForRootElement(root.Name.Local)
+	if candidates == nil {
+		return nil, fmt.Errorf("root element <%s> is not a QTI document", root.Name.Local)
+	}
+	detection.Evidence = append(detection.Evidence, fmt.Sprintf("root element <%s>", root.Name.Local))
+
+	narrow := func(source string, versions []string) error {
+		if versions == nil {
+			return nil
+		}
+		remaining := intersectVersions(candidates, versions)
+		if len(remaining) == 0 {
+			return fmt.Errorf("conflicting QTI version signals: %s points to QTI %s, but %s",
+				source, strings.Join(versions, "/"), strings.Join(detection.Evidence, ", "))
+		}
+		candidates = remaining
+		detection.Evidence = append(detection.Evidence, source)
+		return nil
+	}
+
+	if root.Name.Space != "" {
+		if err := narrow(fmt.Sprintf("namespace %s", root.Name.Space), versionsForNamespace(root.Name.Space)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, attr := range root.Attr {
+		switch {
+		case attr.Name.Local == "version" && attr.Name.Space == "":
+			if err := narrow(fmt.Sprintf(`version="%s"`, attr.Value), versionsForVersionAttr(attr.Value)); err != nil {
+				return nil, err
+			}
+		case attr.Name.Local == "schemaLocation":
+			if err := narrow("xsi:schemaLocation", versionsForSchemaLocation(attr.Value)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	detection.Version = candidates[0]
+	return detection, nil
+}
+
+func readRootElement(content []byte) (*xml.StartElement, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("document has no root element")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read document: %w", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return &start, nil
+		}
+	}
+}
+
+// versionsForRootElement lists the versions a root element can belong to,
+// most likely first. Earlier releases of this tool wrote QTI 2.1 under a
+// questestinterop root with version="2.1", which the 2.1 parser still reads,
+// so that root does not pin down 1.2 on its own.
+func versionsForRootElement(name string) []string {
+	switch name {
+	case "questestinterop":
+		return []string{"1.2", "2.1"}
+	case "assessmentItem", "assessmentTest":
+		return []string{"2.1", "2.2"}
+	case "qti-assessment-item", "qti-assessment-test":
+		return []string{"3.0"}
+	default:
+		return nil
+	}
+}
+
+func versionsForNamespace(namespace string) []string {
+	switch namespace {
+	case NamespaceQTI12:
+		return []string{"1.2"}
+	case NamespaceQTI21:
+		return []string{"2.1"}
+	case NamespaceQTI22:
+		return []string{"2.2"}
+	case NamespaceQTI30:
+		return []string{"3.0"}
+	default:
+		return nil
+	}
+}
+
+func versionsForVersionAttr(version string) []string {
+	version = strings.TrimSpace(version)
+	for _, known := range []string{"1.2", "2.1", "2.2", "3.0"} {
+		if strings.HasPrefix(version, known) {
+			return []string{known}
+		}
+	}
+	return nil
+}
+
+// versionsForSchemaLocation recognises the XSD file names of the official
+// schemas, e.g. imsqti_v2p1.xsd or imsqti_asiv3p0_v1p0.xsd.
+func versionsForSchemaLocation(schemaLocation string) []string {
+	location := strings.ToLower(schemaLocation)
+	switch {
+	case strings.Contains(location, "qtiasiv1p2"):
+		return []string{"1.2"}
+	case strings.Contains(location, "imsqti_v2p1"):
+		return []string{"2.1"}
+	case strings.Contains(location, "imsqti_v2p2"):
+		return []string{"2.2"}
+	case strings.Contains(location, "asiv3p0") || strings.Contains(location, "qtiv3p0"):
+		return []string{"3.0"}
+	default:
+		return nil
+	}
+}
+
+func intersectVersions(candidates, versions []string) []string {
+	var result []string
+	for _, candidate := range candidates {
+		for _, version := range versions {
+			if candidate == version {
+				result = append(result, candidate)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,94 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "QTI 1.2 without version",
+			content:  `<questestinterop><item ident="q1"/></questestinterop>`,
+			expected: "1.2",
+		},
+		{
+			name:     "QTI 1.2 with namespace",
+			content:  `<questestinterop xmlns="http://www.imsglobal.org/xsd/ims_qtiasiv1p2"><item ident="q1"/></questestinterop>`,
+			expected: "1.2",
+		},
+		{
+			name:     "Tool generated QTI 2.1",
+			content:  `<?xml version="1.0" encoding="UTF-8"?><questestinterop version="2.1"></questestinterop>`,
+			expected: "2.1",
+		},
+		{
+			name: "QTI 2.1 assessmentItem",
+			content: `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1"
+				xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"
+				xsi:schemaLocation="http://www.imsglobal.org/xsd/imsqti_v2p1 http://www.imsglobal.org/xsd/qti/qtiv2p1/imsqti_v2p1.xsd"
+				identifier="q1" title="Q1" adaptive="false" timeDependent="false"/>`,
+			expected: "2.1",
+		},
+		{
+			name:     "QTI 2.2 assessmentTest",
+			content:  `<assessmentTest xmlns="http://www.imsglobal.org/xsd/imsqti_v2p2" identifier="t1" title="T1"/>`,
+			expected: "2.2",
+		},
+		{
+			name:     "QTI 2.x without namespace",
+			content:  `<assessmentItem identifier="q1" title="Q1"/>`,
+			expected: "2.1",
+		},
+		{
+			name:     "QTI 3.0 item",
+			content:  `<qti-assessment-item xmlns="http://www.imsglobal.org/xsd/imsqtiasi_v3p0" identifier="q1" title="Q1"/>`,
+			expected: "3.0",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			detection, err := Detect([]byte(tc.content))
+			if err != nil {
+				t.Fatalf("Detection failed: %v", err)
+			}
+			if detection.Version != tc.expected {
+				t.Errorf("Expected version %s, got %s (evidence: %v)", tc.expected, detection.Version, detection.Evidence)
+			}
+		})
+	}
+}
+
+func TestDetect_ConflictingSignals(t *testing.T) {
+	content := `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqtiasi_v3p0" identifier="q1"/>`
+
+	_, err := Detect([]byte(content))
+	if err == nil {
+		t.Fatal("Expected error for conflicting version signals")
+	}
+	if !strings.Contains(err.Error(), "conflicting QTI version signals") {
+		t.Errorf("Expected conflicting signals error, got: %v", err)
+	}
+}
+
+func TestDetect_NotQTI(t *testing.T) {
+	_, err := Detect([]byte(`<html><body/></html>`))
+	if err == nil {
+		t.Fatal("Expected error for non-QTI document")
+	}
+	if !strings.Contains(err.Error(), "not a QTI document") {
+		t.Errorf("Expected not a QTI document error, got: %v", err)
+	}
+}
+
+func TestDetect_Empty(t *testing.T) {
+	_, err := Detect([]byte(``))
+	if err == nil {
+		t.Fatal("Expected error for empty document")
+	}
+}
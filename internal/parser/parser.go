@@ -1,10 +1,7 @@
 package parser
 
 import (
-	"github.com/qti-migrator/pkg/models"
+	"github.com/qti-migrator/pkg/registry"
 )
 
-type Parser interface {
-	Parse(content []byte) (*models.QTIDocument, error)
-	Version() string
-}
\ No newline at end of file
+type Parser = registry.Parser
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/qti-migrator/pkg/models"
+	"github.com/qti-migrator/pkg/registry"
 )
 
 type Parser12 struct{}
@@ -13,6 +14,16 @@ func New() *Parser12 {
 	return &Parser12{}
 }
 
+func init() {
+	err := registry.RegisterParser(registry.ParserInfo{
+		Version: "1.2",
+		New:     func() registry.Parser { return New() },
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
 func (p *Parser12) Version() string {
 	return "1.2"
 }
@@ -63,6 +74,7 @@ func convertItems12ToGeneric(items []models.Item12) []models.Item {
 			Title:        item.Title,
 			Ident:        item.Ident,
 			MaxAttempts:  item.MaxAttempts,
+			Duration:     item.Duration,
 			Metadata:     item.Metadata,
 			Presentation: item.Presentation,
 			ResponseProc: item.ResponseProc,
@@ -78,13 +90,16 @@ func convertAssessment12ToGeneric(assessment *models.Assessment12) *models.Asses
 		return nil
 	}
 	return &models.Assessment{
-		XMLName:     assessment.XMLName,
-		Title:       assessment.Title,
-		Ident:       assessment.Ident,
-		Sections:    convertSections12ToGeneric(assessment.Sections),
-		Metadata:    assessment.Metadata,
-		Objectives:  assessment.Objectives,
-		RubricBlock: assessment.RubricBlock,
+		XMLName:           assessment.XMLName,
+		Title:             assessment.Title,
+		Ident:             assessment.Ident,
+		Sections:          convertSections12ToGeneric(assessment.Sections),
+		Metadata:          assessment.Metadata,
+		Objectives:        assessment.Objectives,
+		RubricBlock:       assessment.RubricBlock,
+		MaxAttempts:       assessment.MaxAttempts,
+		Duration:          assessment.Duration,
+		SelectionOrdering: assessment.SelectionOrdering,
 	}
 }
 
@@ -92,11 +107,16 @@ func convertSections12ToGeneric(sections []models.Section12) []models.Section {
 	genericSections := make([]models.Section, len(sections))
 	for i, section := range sections {
 		genericSections[i] = models.Section{
-			XMLName:  section.XMLName,
-			Title:    section.Title,
-			Ident:    section.Ident,
-			Items:    convertItems12ToGeneric(section.Items),
-			Metadata: section.Metadata,
+			XMLName:           section.XMLName,
+			Title:             section.Title,
+			Ident:             section.Ident,
+			Items:             convertItems12ToGeneric(section.Items),
+			Metadata:          section.Metadata,
+			Sections:          convertSections12ToGeneric(section.Sections),
+			Order:             section.Order,
+			MaxAttempts:       section.MaxAttempts,
+			Duration:          section.Duration,
+			SelectionOrdering: section.SelectionOrdering,
 		}
 	}
 	return genericSections
@@ -105,7 +125,13 @@ func convertSections12ToGeneric(sections []models.Section12) []models.Section {
 func convertFeedback12ToGeneric(feedbacks []models.Feedback12) []models.Feedback {
 	genericFeedbacks := make([]models.Feedback, len(feedbacks))
 	for i, feedback := range feedbacks {
-		genericFeedbacks[i] = models.Feedback(feedback)
+		genericFeedbacks[i] = models.Feedback{
+			XMLName:  feedback.XMLName,
+			Ident:    feedback.Ident,
+			Title:    feedback.Title,
+			FlowMat:  feedback.FlowMat,
+			Material: feedback.Material,
+		}
 	}
 	return genericFeedbacks
 }
\ No newline at end of file
@@ -3,6 +3,8 @@ package qti12
 import (
 	"strings"
 	"testing"
+
+	"github.com/qti-migrator/pkg/models"
 )
 
 func TestParser12_Version(t *testing.T) {
@@ -285,4 +287,174 @@ func BenchmarkParser12_Parse(b *testing.B) {
 			b.Fatalf("Parse failed: %v", err)
 		}
 	}
-}
\ No newline at end of file
+}
+func TestParser12_Parse_ResponseTypes(t *testing.T) {
+	xmlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<questestinterop>
+	<item ident="q001" title="Response Types">
+		<presentation>
+			<response_str ident="STR" rcardinality="single">
+				<render_fib fibtype="String" maxchars="20"/>
+			</response_str>
+			<response_num ident="NUM" numtype="Decimal">
+				<render_fib fibtype="Decimal"/>
+			</response_num>
+			<flow>
+				<response_grp ident="GRP" rcardinality="multiple">
+					<render_choice>
+						<response_label ident="A"><material><mattext>Paris</mattext></material></response_label>
+						<response_label ident="B"><material><mattext>France</mattext></material></response_label>
+					</render_choice>
+				</response_grp>
+				<response_xy ident="XY">
+					<render_hotspot>
+						<material><matimage imagetype="image/png" uri="map.png"/></material>
+					</render_hotspot>
+				</response_xy>
+			</flow>
+			<response_extension>vendor data</response_extension>
+		</presentation>
+	</item>
+</questestinterop>`
+
+	doc, err := New().Parse([]byte(xmlContent))
+	if err != nil {
+		t.Fatalf("Failed to parse document: %v", err)
+	}
+
+	presentation := doc.Items[0].Presentation
+	if len(presentation.Response) != 2 {
+		t.Fatalf("Expected 2 top-level responses, got %d", len(presentation.Response))
+	}
+
+	responses := presentation.Responses()
+	expected := []string{"response_str", "response_num", "response_grp", "response_xy"}
+	if len(responses) != len(expected) {
+		t.Fatalf("Expected %d responses, got %d", len(expected), len(responses))
+	}
+
+	for i, kind := range expected {
+		if responses[i].Kind() != kind {
+			t.Errorf("Expected response %d to be %s, got %s", i, kind, responses[i].Kind())
+		}
+	}
+
+	if responses[1].NumType != "Decimal" {
+		t.Errorf("Expected numtype 'Decimal', got '%s'", responses[1].NumType)
+	}
+
+	if responses[2].RenderChoice == nil || len(responses[2].RenderChoice.ResponseLabel) != 2 {
+		t.Error("Expected response_grp labels to be parsed")
+	}
+
+	if responses[3].RenderHotspot == nil || responses[3].RenderHotspot.Material == nil {
+		t.Error("Expected response_xy hotspot rendering to be parsed")
+	}
+}
+
+func TestParser12_Parse_PresentationOrder(t *testing.T) {
+	xmlContent := `<questestinterop>
+	<item ident="q001" title="Order">
+		<presentation>
+			<response_lid ident="FIRST"><render_choice/></response_lid>
+			<material><mattext>Question</mattext></material>
+			<flow>
+				<material><mattext>The answer is </mattext></material>
+				<response_str ident="BLANK"><render_fib/></response_str>
+				<material><mattext>.</mattext></material>
+			</flow>
+			<unknown_element/>
+		</presentation>
+	</item>
+</questestinterop>`
+
+	doc, err := New().Parse([]byte(xmlContent))
+	if err != nil {
+		t.Fatalf("Failed to parse document: %v", err)
+	}
+
+	presentation := doc.Items[0].Presentation
+	children := presentation.Children()
+	if len(children) != 3 || children[0].Response == nil || children[1].Material == nil || children[2].Flow == nil {
+		t.Fatalf("Expected response, material and flow in document order, got %+v", children)
+	}
+
+	flowChildren := children[2].Flow.Children()
+	if len(flowChildren) != 3 || flowChildren[0].Material == nil || flowChildren[1].Response == nil || flowChildren[2].Material == nil {
+		t.Errorf("Expected material, response and material in the flow, got %+v", flowChildren)
+	}
+
+	if presentation.Material == nil || len(presentation.Response) != 1 || len(presentation.Flow) != 1 {
+		t.Error("Expected typed fields to be filled alongside the ordered content")
+	}
+}
+
+func TestParser12_Parse_NestedSections(t *testing.T) {
+	doc, err := New().Parse([]byte(`<questestinterop>
+	<assessment ident="test001">
+		<section ident="outer" title="Outer">
+			<item ident="q1"/>
+			<section ident="inner" title="Inner"><item ident="q2"/></section>
+			<item ident="q3"/>
+		</section>
+	</assessment>
+</questestinterop>`))
+	if err != nil {
+		t.Fatalf("Failed to parse nested sections: %v", err)
+	}
+
+	outer := &doc.Assessment.Sections[0]
+	if outer.Title != "Outer" || len(outer.Sections) != 1 || outer.Sections[0].Items[0].Ident != "q2" {
+		t.Fatalf("Expected the inner section below the outer one, got %+v", outer)
+	}
+
+	var children []string
+	for _, child := range outer.Children() {
+		switch c := child.(type) {
+		case *models.Item:
+			children = append(children, c.Ident)
+		case *models.Section:
+			children = append(children, c.Ident)
+		}
+	}
+	if strings.Join(children, " ") != "q1 inner q3" {
+		t.Errorf("Expected children in document order, got %v", children)
+	}
+}
+
+func TestParser12_Parse_TestControls(t *testing.T) {
+	doc, err := New().Parse([]byte(`<questestinterop>
+	<assessment ident="test001" maxattempts="2">
+		<duration>PT1H</duration>
+		<section ident="sec001" maxattempts="3">
+			<duration>PT20M</duration>
+			<selection_ordering sequence_type="Normal">
+				<selection><selection_number>2</selection_number></selection>
+				<order order_type="Random"/>
+			</selection_ordering>
+			<item ident="q1" maxattempts="1"><duration>PT1M</duration></item>
+		</section>
+	</assessment>
+</questestinterop>`))
+	if err != nil {
+		t.Fatalf("Failed to parse test controls: %v", err)
+	}
+
+	assessment := doc.Assessment
+	if assessment.MaxAttempts != 2 || assessment.Duration != "PT1H" {
+		t.Errorf("Expected assessment controls, got maxattempts %d and duration %q", assessment.MaxAttempts, assessment.Duration)
+	}
+
+	section := assessment.Sections[0]
+	if section.MaxAttempts != 3 || section.Duration != "PT20M" {
+		t.Errorf("Expected section controls, got maxattempts %d and duration %q", section.MaxAttempts, section.Duration)
+	}
+	selectionOrdering := section.SelectionOrdering
+	if selectionOrdering == nil || len(selectionOrdering.Selection) != 1 || selectionOrdering.Selection[0].SelectionNumber != 2 ||
+		selectionOrdering.Order == nil || selectionOrdering.Order.OrderType != "Random" {
+		t.Errorf("Expected selection of 2 in random order, got %+v", selectionOrdering)
+	}
+	if item := section.Items[0]; item.MaxAttempts != 1 || item.Duration != "PT1M" {
+		t.Errorf("Expected item controls, got maxattempts %d and duration %q", item.MaxAttempts, item.Duration)
+	}
+}
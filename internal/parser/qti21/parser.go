@@ -1,10 +1,13 @@
 package qti21
 
 import (
+	"bytes"
 	"encoding/xml"
 	"fmt"
+	"strings"
 
 	"github.com/qti-migrator/pkg/models"
+	"github.com/qti-migrator/pkg/registry"
 )
 
 type Parser21 struct{}
@@ -13,11 +16,29 @@ func New() *Parser21 {
 	return &Parser21{}
 }
 
+func init() {
+	err := registry.RegisterParser(registry.ParserInfo{
+		Version: "2.1",
+		Accepts: []string{"2.1", "2.2"},
+		New:     func() registry.Parser { return New() },
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
 func (p *Parser21) Version() string {
 	return "2.1"
 }
 
 func (p *Parser21) Parse(content []byte) (*models.QTIDocument, error) {
+	switch rootElement(content) {
+	case "assessmentItem":
+		return p.parseAssessmentItem(content)
+	case "assessmentTest":
+		return p.parseAssessmentTest(content)
+	}
+
 	var doc models.QTIDocument21
 	err := xml.Unmarshal(content, &doc)
 	if err != nil {
@@ -44,6 +65,83 @@ func (p *Parser21) Parse(content []byte) (*models.QTIDocument, error) {
 	return genericDoc, nil
 }
 
+// parseAssessmentItem reads a standard assessmentItem document and wraps the
+// item in a generic document.
+func (p *Parser21) parseAssessmentItem(content []byte) (*models.QTIDocument, error) {
+	var item models.AssessmentItem21
+	err := xml.Unmarshal(content, &item)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse QTI 2.1 document: %w", err)
+	}
+
+	return &models.QTIDocument{
+		XMLName: xml.Name{Local: "questestinterop"},
+		Version: versionFromNamespace(item.XMLName.Space),
+		Items:   []models.Item{convertAssessmentItem21ToGeneric(item)},
+	}, nil
+}
+
+// parseAssessmentTest reads a standard assessmentTest document. Test parts
+// become top-level sections; the items themselves live in separate files and
+// are kept as references.
+func (p *Parser21) parseAssessmentTest(content []byte) (*models.QTIDocument, error) {
+	var test models.AssessmentTest21
+	err := xml.Unmarshal(content, &test)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse QTI 2.1 document: %w", err)
+	}
+
+	assessment := &models.Assessment{
+		XMLName:           xml.Name{Local: "assessment"},
+		Title:             test.Title,
+		Ident:             test.Identifier,
+		TimeLimits:        test.TimeLimits,
+		ToolName:          test.ToolName,
+		ToolVersion:       test.ToolVersion,
+		OutcomeDecl:       convertOutcomeDecl21ToGeneric(test.OutcomeDecl),
+		OutcomeProcessing: test.OutcomeProcessing,
+		TestFeedback:      test.TestFeedback,
+	}
+	for _, testPart := range test.TestParts {
+		assessment.Sections = append(assessment.Sections, models.Section{
+			XMLName:            xml.Name{Local: "section"},
+			Ident:              testPart.Identifier,
+			Sections:           convertAssessmentSections21ToGeneric(testPart.Sections),
+			NavigationMode:     testPart.NavigationMode,
+			SubmissionMode:     testPart.SubmissionMode,
+			ItemSessionControl: testPart.ItemSessionControl,
+			TimeLimits:         testPart.TimeLimits,
+			TestFeedback:       testPart.TestFeedback,
+		})
+	}
+
+	return &models.QTIDocument{
+		XMLName:    xml.Name{Local: "questestinterop"},
+		Version:    versionFromNamespace(test.XMLName.Space),
+		Assessment: assessment,
+	}, nil
+}
+
+func rootElement(content []byte) string {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local
+		}
+	}
+}
+
+func versionFromNamespace(namespace string) string {
+	if strings.HasSuffix(namespace, "imsqti_v2p2") {
+		return "2.2"
+	}
+	return "2.1"
+}
+
 func isValidQTI21Version(version string) bool {
 	switch version {
 	case "2.1", "2.1.0", "2.1.1", "2.2", "2.2.0", "2.2.1", "2.2.2", "2.2.3", "2.2.4":
@@ -59,24 +157,88 @@ func convertItems21ToGeneric(items []models.Item21) []models.Item {
 	genericItems := make([]models.Item, len(items))
 	for i, item := range items {
 		genericItems[i] = models.Item{
-			XMLName:      item.XMLName,
-			Title:        item.Title,
-			Ident:        item.Ident,
-			MaxAttempts:  item.MaxAttempts,
-			Metadata:     item.Metadata,
-			Presentation: item.Presentation,
-			ResponseProc: item.ResponseProc,
-			ItemBody:     item.ItemBody,
-			ResponseDecl: convertResponseDecl21ToGeneric(item.ResponseDecl),
-			OutcomeDecl:  convertOutcomeDecl21ToGeneric(item.OutcomeDecl),
-			TemplateDecl: convertTemplateDecl21ToGeneric(item.TemplateDecl),
-			Feedback:     convertFeedback21ToGeneric(item.Feedback),
-			RubricBlock:  item.RubricBlock,
+			XMLName:            item.XMLName,
+			Title:              item.Title,
+			Ident:              item.Ident,
+			MaxAttempts:        item.MaxAttempts,
+			Metadata:           item.Metadata,
+			Presentation:       item.Presentation,
+			ResponseProc:       item.ResponseProc,
+			ItemBody:           item.ItemBody,
+			ResponseDecl:       convertResponseDecl21ToGeneric(item.ResponseDecl),
+			OutcomeDecl:        convertOutcomeDecl21ToGeneric(item.OutcomeDecl),
+			TemplateDecl:       convertTemplateDecl21ToGeneric(item.TemplateDecl),
+			Feedback:           append(convertFeedback21ToGeneric(item.Feedback), convertModalFeedback21ToGeneric(item.ModalFeedback)...),
+			RubricBlock:        item.RubricBlock,
+			ResponseProcessing: item.ResponseProcessing,
 		}
 	}
 	return genericItems
 }
 
+func convertAssessmentItem21ToGeneric(item models.AssessmentItem21) models.Item {
+	return models.Item{
+		XMLName:            xml.Name{Local: "item"},
+		Title:              item.Title,
+		Ident:              item.Identifier,
+		Adaptive:           item.Adaptive,
+		TimeDependent:      item.TimeDependent,
+		ToolName:           item.ToolName,
+		ToolVersion:        item.ToolVersion,
+		ItemBody:           item.ItemBody,
+		ResponseDecl:       convertResponseDecl21ToGeneric(item.ResponseDecl),
+		OutcomeDecl:        convertOutcomeDecl21ToGeneric(item.OutcomeDecl),
+		TemplateDecl:       convertTemplateDecl21ToGeneric(item.TemplateDecl),
+		ResponseProcessing: item.ResponseProcessing,
+		TemplateProcessing: item.TemplateProcessing,
+		Stylesheets:        item.Stylesheet,
+		// Note: standard QTI 2.1 uses modalFeedback instead of itemfeedback
+		Feedback: convertModalFeedback21ToGeneric(item.ModalFeedback),
+	}
+}
+
+func convertAssessmentSections21ToGeneric(sections []models.AssessmentSection21) []models.Section {
+	var genericSections []models.Section
+	for _, section := range sections {
+		visible := section.Visible
+		genericSections = append(genericSections, models.Section{
+			XMLName:            xml.Name{Local: "section"},
+			Title:              section.Title,
+			Ident:              section.Identifier,
+			Sections:           convertAssessmentSections21ToGeneric(section.Sections),
+			ItemRefs:           section.ItemRefs,
+			Visible:            &visible,
+			ItemSessionControl: section.ItemSessionControl,
+			TimeLimits:         section.TimeLimits,
+			Selection:          section.Selection,
+			Ordering:           section.Ordering,
+			RubricBlocks:       section.RubricBlock,
+		})
+	}
+	return genericSections
+}
+
+func convertModalFeedback21ToGeneric(feedbacks []models.ModalFeedback21) []models.Feedback {
+	var genericFeedbacks []models.Feedback
+	for _, feedback := range feedbacks {
+		genericFeedbacks = append(genericFeedbacks, models.Feedback{
+			XMLName:           xml.Name{Local: "itemfeedback"},
+			Ident:             feedback.Identifier,
+			Title:             feedback.Title,
+			OutcomeIdentifier: feedback.OutcomeIdentifier,
+			ShowHide:          feedback.ShowHide,
+			Material: &models.Material{
+				MatText: []models.MatText{
+					{
+						Content: feedback.Content,
+					},
+				},
+			},
+		})
+	}
+	return genericFeedbacks
+}
+
 func convertAssessment21ToGeneric(assessment *models.Assessment21) *models.Assessment {
 	if assessment == nil {
 		return nil
@@ -106,48 +268,19 @@ func convertSections21ToGeneric(sections []models.Section21) []models.Section {
 	return genericSections
 }
 
+// The declarations of the model are the QTI 2.1 ones, so
+// they are copied whole.
+
 func convertResponseDecl21ToGeneric(decls []models.ResponseDecl21) []models.ResponseDecl {
-	genericDecls := make([]models.ResponseDecl, len(decls))
-	for i, decl := range decls {
-		genericDecls[i] = models.ResponseDecl{
-			XMLName:         decl.XMLName,
-			Identifier:      decl.Identifier,
-			Cardinality:     decl.Cardinality,
-			BaseType:        decl.BaseType,
-			CorrectResponse: (*models.CorrectResponse)(decl.CorrectResponse),
-			Mapping:         (*models.Mapping)(decl.Mapping),
-		}
-	}
-	return genericDecls
+	return append(make([]models.ResponseDecl, 0, len(decls)), decls...)
 }
 
 func convertOutcomeDecl21ToGeneric(decls []models.OutcomeDecl21) []models.OutcomeDecl {
-	genericDecls := make([]models.OutcomeDecl, len(decls))
-	for i, decl := range decls {
-		genericDecls[i] = models.OutcomeDecl{
-			XMLName:      decl.XMLName,
-			Identifier:   decl.Identifier,
-			Cardinality:  decl.Cardinality,
-			BaseType:     decl.BaseType,
-			DefaultValue: (*models.DefaultValue)(decl.DefaultValue),
-		}
-	}
-	return genericDecls
+	return append(make([]models.OutcomeDecl, 0, len(decls)), decls...)
 }
 
 func convertTemplateDecl21ToGeneric(decls []models.TemplateDecl21) []models.TemplateDecl {
-	genericDecls := make([]models.TemplateDecl, len(decls))
-	for i, decl := range decls {
-		genericDecls[i] = models.TemplateDecl{
-			XMLName:       decl.XMLName,
-			Identifier:    decl.Identifier,
-			Cardinality:   decl.Cardinality,
-			BaseType:      decl.BaseType,
-			ParamVariable: decl.ParamVariable,
-			DefaultValue:  (*models.DefaultValue)(decl.DefaultValue),
-		}
-	}
-	return genericDecls
+	return append(make([]models.TemplateDecl, 0, len(decls)), decls...)
 }
 
 func convertFeedback21ToGeneric(feedbacks []models.Feedback21) []models.Feedback {
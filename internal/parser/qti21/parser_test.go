@@ -1,6 +1,7 @@
 package qti21
 
 import (
+	"encoding/xml"
 	"strings"
 	"testing"
 )
@@ -268,4 +269,204 @@ func BenchmarkParser21_Parse(b *testing.B) {
 			b.Fatalf("Parse failed: %v", err)
 		}
 	}
-}
\ No newline at end of file
+}
+func TestParser21_Parse_AssessmentItem(t *testing.T) {
+	itemXML := `<?xml version="1.0" encoding="UTF-8"?>
+<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p2"
+	xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"
+	xsi:schemaLocation="http://www.imsglobal.org/xsd/imsqti_v2p2 http://www.imsglobal.org/xsd/qti/qtiv2p2/imsqti_v2p2.xsd"
+	identifier="choice" title="Unattended Luggage" adaptive="false" timeDependent="false">
+	<responseDeclaration identifier="RESPONSE" cardinality="single" baseType="identifier">
+		<correctResponse>
+			<value>ChoiceA</value>
+		</correctResponse>
+	</responseDeclaration>
+	<outcomeDeclaration identifier="SCORE" cardinality="single" baseType="float">
+		<defaultValue>
+			<value>0</value>
+		</defaultValue>
+	</outcomeDeclaration>
+	<itemBody>
+		<p>Look at the text in the picture.</p>
+		<choiceInteraction responseIdentifier="RESPONSE" shuffle="false" maxChoices="1">
+			<prompt>What does it say?</prompt>
+			<simpleChoice identifier="ChoiceA">You must stay with your luggage at all times.</simpleChoice>
+			<simpleChoice identifier="ChoiceB">Do not let someone else look after your luggage.</simpleChoice>
+		</choiceInteraction>
+	</itemBody>
+	<responseProcessing template="http://www.imsglobal.org/question/qti_v2p2/rptemplates/match_correct"/>
+	<modalFeedback outcomeIdentifier="FEEDBACK" identifier="correct" showHide="show">Well done.</modalFeedback>
+</assessmentItem>`
+
+	parser := New()
+	doc, err := parser.Parse([]byte(itemXML))
+	if err != nil {
+		t.Fatalf("Failed to parse assessmentItem: %v", err)
+	}
+
+	if doc.Version != "2.2" {
+		t.Errorf("Expected version '2.2' from namespace, got '%s'", doc.Version)
+	}
+
+	if len(doc.Items) != 1 {
+		t.Fatalf("Expected 1 item, got %d", len(doc.Items))
+	}
+
+	item := doc.Items[0]
+	if item.Ident != "choice" || item.Title != "Unattended Luggage" {
+		t.Errorf("Expected identifier and title to be mapped, got '%s' / '%s'", item.Ident, item.Title)
+	}
+
+	if len(item.ResponseDecl) != 1 || item.ResponseDecl[0].CorrectResponse == nil {
+		t.Error("Expected response declaration with correct response")
+	}
+
+	if item.ItemBody == nil || len(item.ItemBody.ChoiceInteraction) != 1 {
+		t.Fatal("Expected item body with one choice interaction")
+	}
+
+	if item.ItemBody.ChoiceInteraction[0].Prompt == nil {
+		t.Error("Expected prompt to be parsed")
+	}
+
+	if item.ResponseProcessing == nil || !strings.HasSuffix(item.ResponseProcessing.Template, "match_correct") {
+		t.Error("Expected response processing template to be kept")
+	}
+
+	if len(item.Feedback) != 1 || item.Feedback[0].Ident != "correct" {
+		t.Error("Expected modal feedback to be mapped")
+	}
+}
+
+func TestParser21_Parse_AssessmentTest(t *testing.T) {
+	testXML := `<?xml version="1.0" encoding="UTF-8"?>
+<assessmentTest xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="test001" title="Sample Test">
+	<testPart identifier="part1" navigationMode="linear" submissionMode="individual">
+		<assessmentSection identifier="sec1" title="Section 1" visible="true">
+			<assessmentItemRef identifier="q001" href="items/q001.xml"/>
+			<assessmentSection identifier="sec1a" title="Section 1a" visible="true">
+				<assessmentItemRef identifier="q002" href="items/q002.xml"/>
+			</assessmentSection>
+		</assessmentSection>
+	</testPart>
+</assessmentTest>`
+
+	parser := New()
+	doc, err := parser.Parse([]byte(testXML))
+	if err != nil {
+		t.Fatalf("Failed to parse assessmentTest: %v", err)
+	}
+
+	if doc.Version != "2.1" {
+		t.Errorf("Expected version '2.1', got '%s'", doc.Version)
+	}
+
+	if doc.Assessment == nil {
+		t.Fatal("Expected assessment to be mapped")
+	}
+
+	if doc.Assessment.Ident != "test001" || doc.Assessment.Title != "Sample Test" {
+		t.Errorf("Expected assessment identifier and title, got '%s' / '%s'", doc.Assessment.Ident, doc.Assessment.Title)
+	}
+
+	if len(doc.Assessment.Sections) != 1 || doc.Assessment.Sections[0].Ident != "part1" {
+		t.Fatal("Expected test part to be mapped to a section")
+	}
+
+	sections := doc.Assessment.Sections[0].Sections
+	if len(sections) != 1 || sections[0].Ident != "sec1" {
+		t.Fatal("Expected assessment section to be nested under the test part")
+	}
+
+	if len(sections[0].ItemRefs) != 1 || sections[0].ItemRefs[0].Href != "items/q001.xml" {
+		t.Error("Expected item reference to be kept")
+	}
+
+	if len(sections[0].Sections) != 1 || len(sections[0].Sections[0].ItemRefs) != 1 {
+		t.Error("Expected nested section with its item reference")
+	}
+}
+
+func TestParser21_Parse_ItemBodyOrder(t *testing.T) {
+	itemXML := `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="order" title="Order">
+	<itemBody>
+		<choiceInteraction responseIdentifier="R1" maxChoices="1">
+			<simpleChoice identifier="A">Alpha</simpleChoice>
+		</choiceInteraction>
+		<p>Between</p>
+		<hotspotInteraction responseIdentifier="R2" maxChoices="1"><object data="map.png" type="image/png"/></hotspotInteraction>
+		<p>Fill in: <textEntryInteraction responseIdentifier="R3"/></p>
+	</itemBody>
+</assessmentItem>`
+
+	doc, err := New().Parse([]byte(itemXML))
+	if err != nil {
+		t.Fatalf("Failed to parse assessmentItem: %v", err)
+	}
+
+	body := doc.Items[0].ItemBody
+	expected := []string{"choiceInteraction", "p", "other", "p"}
+	if strings.Join(body.Order, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected order %v, got %v", expected, body.Order)
+	}
+
+	if len(body.Other) != 1 || body.Other[0].XMLName.Space != "" {
+		t.Errorf("Expected raw hotspot interaction without namespace, got %+v", body.Other)
+	}
+
+	output, err := xml.Marshal(body)
+	if err != nil {
+		t.Fatalf("Failed to marshal item body: %v", err)
+	}
+
+	result := string(output)
+	choice := strings.Index(result, "<choiceInteraction")
+	between := strings.Index(result, "<p>Between</p>")
+	hotspot := strings.Index(result, "<hotspotInteraction")
+	fillIn := strings.Index(result, `<p>Fill in: <textEntryInteraction responseIdentifier="R3"/></p>`)
+	if choice < 0 || choice > between || between > hotspot || hotspot > fillIn {
+		t.Errorf("Expected children in document order, got %s", result)
+	}
+}
+
+func TestParser21_Parse_ItemBodyNamespaces(t *testing.T) {
+	itemXML := `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" xmlns:m="http://www.w3.org/1998/Math/MathML" identifier="math" title="Math">
+	<itemBody>
+		<m:math><m:mi>x</m:mi></m:math>
+		<math xmlns="http://www.w3.org/1998/Math/MathML"><mi>y</mi></math>
+		<p>Solve <m:math><m:mi>z</m:mi></m:math></p>
+	</itemBody>
+</assessmentItem>`
+
+	doc, err := New().Parse([]byte(itemXML))
+	if err != nil {
+		t.Fatalf("Failed to parse assessmentItem: %v", err)
+	}
+
+	body := doc.Items[0].ItemBody
+	if len(body.Other) != 2 {
+		t.Fatalf("Expected 2 raw math elements, got %+v", body.Other)
+	}
+	for _, raw := range body.Other {
+		if raw.XMLName.Space != "http://www.w3.org/1998/Math/MathML" {
+			t.Errorf("Expected math to keep the MathML namespace, got %+v", raw.XMLName)
+		}
+	}
+
+	output, err := xml.Marshal(body)
+	if err != nil {
+		t.Fatalf("Failed to marshal item body: %v", err)
+	}
+
+	result := string(output)
+	expected := []string{
+		`<math xmlns="http://www.w3.org/1998/Math/MathML" xmlns:m="http://www.w3.org/1998/Math/MathML"><m:mi>x</m:mi></math>`,
+		`<math xmlns="http://www.w3.org/1998/Math/MathML"><mi>y</mi></math>`,
+		`<p xmlns:m="http://www.w3.org/1998/Math/MathML">Solve <m:math><m:mi>z</m:mi></m:math></p>`,
+	}
+	for _, s := range expected {
+		if !strings.Contains(result, s) {
+			t.Errorf("Expected output to contain '%s', got:\n%s", s, result)
+		}
+	}
+}
@@ -1,10 +1,13 @@
 package qti30
 
 import (
+	"bytes"
 	"encoding/xml"
 	"fmt"
+	"strings"
 
 	"github.com/qti-migrator/pkg/models"
+	"github.com/qti-migrator/pkg/registry"
 )
 
 type Parser30 struct{}
@@ -13,11 +16,41 @@ func New() *Parser30 {
 	return &Parser30{}
 }
 
+func init() {
+	err := registry.RegisterParser(registry.ParserInfo{
+		Version: "3.0",
+		New:     func() registry.Parser { return New() },
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
 func (p *Parser30) Version() string {
 	return "3.0"
 }
 
 func (p *Parser30) Parse(content []byte) (*models.QTIDocument, error) {
+	root, err := rootElement(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse QTI 3.0 document: %w", err)
+	}
+
+	if root.Space != "" && root.Space != models.Namespace30 {
+		return nil, fmt.Errorf("unexpected namespace for QTI 3.0 document: %s", root.Space)
+	}
+
+	switch root.Local {
+	case "qti-assessment-item":
+		return p.parseAssessmentItem(content)
+	case "qti-assessment-test":
+		return p.parseAssessmentTest(content)
+	default:
+		return nil, fmt.Errorf("unsupported QTI 3.0 root element: %s", root.Local)
+	}
+}
+
+func (p *Parser30) parseAssessmentItem(content []byte) (*models.QTIDocument, error) {
 	var doc models.QTIDocument30
 	err := xml.Unmarshal(content, &doc)
 	if err != nil {
@@ -32,18 +65,76 @@ func (p *Parser30) Parse(content []byte) (*models.QTIDocument, error) {
 		return nil, fmt.Errorf("invalid QTI version for 3.0 parser: %s", doc.Version)
 	}
 
-	// QTI 3.0 has a completely different structure, so we need to transform it
-	// to the generic format for backward compatibility
+	item, err := convertItem30ToGeneric(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse QTI 3.0 item %s: %w", doc.Identifier, err)
+	}
+
+	// The item is renamed to the QTI 2.1 vocabulary of the model
 	genericDoc := &models.QTIDocument{
 		XMLName:  xml.Name{Local: "questestinterop"},
 		Version:  doc.Version,
-		Items:    []models.Item{convertItem30ToGeneric(doc)},
+		Items:    []models.Item{item},
 		Metadata: doc.Metadata,
 	}
 
 	return genericDoc, nil
 }
 
+func (p *Parser30) parseAssessmentTest(content []byte) (*models.QTIDocument, error) {
+	var test models.Assessment30
+	err := xml.Unmarshal(content, &test)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse QTI 3.0 assessment test: %w", err)
+	}
+
+	// Test parts become top-level sections so the part structure survives
+	// in the generic model
+	assessment := &models.Assessment{
+		XMLName:           xml.Name{Local: "assessment"},
+		Title:             test.Title,
+		Ident:             test.Identifier,
+		Metadata:          test.Metadata,
+		TimeLimits:        convertTimeLimits30ToGeneric(test.TimeLimits),
+		ToolName:          test.ToolName,
+		ToolVersion:       test.ToolVersion,
+		OutcomeDecl:       convertOutcomeDecl30ToGeneric(test.OutcomeDecl),
+		OutcomeProcessing: convertOutcomeProcessing30ToGeneric(test.OutcomeProcessing),
+		TestFeedback:      convertTestFeedback30ToGeneric(test.TestFeedback),
+	}
+	for _, part := range test.TestParts {
+		assessment.Sections = append(assessment.Sections, models.Section{
+			XMLName:            xml.Name{Local: "section"},
+			Ident:              part.Identifier,
+			Sections:           convertAssessmentSections30ToGeneric(part.Sections),
+			NavigationMode:     part.NavigationMode,
+			SubmissionMode:     part.SubmissionMode,
+			ItemSessionControl: convertItemSessionControl30ToGeneric(part.ItemSessionControl),
+			TimeLimits:         convertTimeLimits30ToGeneric(part.TimeLimits),
+			TestFeedback:       convertTestFeedback30ToGeneric(part.TestFeedback),
+		})
+	}
+
+	return &models.QTIDocument{
+		XMLName:    xml.Name{Local: "questestinterop"},
+		Version:    "3.0",
+		Assessment: assessment,
+	}, nil
+}
+
+func rootElement(content []byte) (xml.Name, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.Name{}, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name, nil
+		}
+	}
+}
+
 func isValidQTI30Version(version string) bool {
 	switch version {
 	case "3.0", "3.0.0":
@@ -54,32 +145,277 @@ func isValidQTI30Version(version string) bool {
 }
 
 // Converter function to transform a QTI 3.0 document (which is an item) to generic Item
-func convertItem30ToGeneric(doc models.QTIDocument30) models.Item {
+func convertItem30ToGeneric(doc models.QTIDocument30) (models.Item, error) {
+	itemBody, err := convertItemBody30ToGeneric(doc.ItemBody, doc.Attrs)
+	if err != nil {
+		return models.Item{}, err
+	}
 	return models.Item{
-		XMLName:      xml.Name{Local: "item"},
-		Title:        doc.Title,
-		Ident:        doc.Identifier,
-		Metadata:     doc.Metadata,
-		ItemBody:     convertItemBody30ToGeneric(doc.ItemBody),
-		ResponseDecl: convertResponseDecl30ToGeneric(doc.ResponseDeclarations),
-		OutcomeDecl:  convertOutcomeDecl30ToGeneric(doc.OutcomeDeclarations),
-		TemplateDecl: convertTemplateDecl30ToGeneric(doc.TemplateDeclarations),
+		XMLName:            xml.Name{Local: "item"},
+		Title:              doc.Title,
+		Ident:              doc.Identifier,
+		Metadata:           doc.Metadata,
+		Adaptive:           doc.Adaptive,
+		TimeDependent:      doc.TimeDependent,
+		ToolName:           doc.ToolName,
+		ToolVersion:        doc.ToolVersion,
+		ItemBody:           itemBody,
+		ResponseDecl:       convertResponseDecl30ToGeneric(doc.ResponseDeclarations),
+		OutcomeDecl:        convertOutcomeDecl30ToGeneric(doc.OutcomeDeclarations),
+		TemplateDecl:       convertTemplateDecl30ToGeneric(doc.TemplateDeclarations),
+		ResponseProcessing: convertResponseProcessing30ToGeneric(doc.ResponseProcessing),
+		TemplateProcessing: convertTemplateProcessing30ToGeneric(doc.TemplateProcessing),
+		Stylesheets:        convertStylesheets30ToGeneric(doc.Stylesheets),
 		// Note: QTI 3.0 uses modalFeedback instead of itemfeedback
 		Feedback: convertModalFeedback30ToGeneric(doc.ModalFeedback),
+	}, nil
+}
+
+func convertAssessmentSections30ToGeneric(sections []models.AssessmentSection30) []models.Section {
+	var genericSections []models.Section
+	for _, section := range sections {
+		visible := section.Visible
+		genericSection := models.Section{
+			XMLName:            xml.Name{Local: "section"},
+			Title:              section.Title,
+			Ident:              section.Identifier,
+			Metadata:           section.Metadata,
+			Sections:           convertAssessmentSections30ToGeneric(section.Sections),
+			Visible:            &visible,
+			ItemSessionControl: convertItemSessionControl30ToGeneric(section.ItemSessionControl),
+			TimeLimits:         convertTimeLimits30ToGeneric(section.TimeLimits),
+		}
+		for _, rubricBlock := range section.RubricBlock {
+			genericSection.RubricBlocks = append(genericSection.RubricBlocks, models.RubricBlock{
+				XMLName: xml.Name{Local: "rubricBlock"},
+				Use:     rubricBlock.Use,
+				View:    convertViews30ToGeneric(rubricBlock.View),
+				Content: toQTI21Vocabulary(contentBody30(rubricBlock.ContentBody, rubricBlock.Content)),
+			})
+		}
+		if section.Selection != nil {
+			genericSection.Selection = &models.Selection{
+				XMLName:         xml.Name{Local: "selection"},
+				Select:          section.Selection.Select,
+				WithReplacement: section.Selection.WithReplacement,
+			}
+		}
+		if section.Ordering != nil {
+			genericSection.Ordering = &models.Ordering{
+				XMLName: xml.Name{Local: "ordering"},
+				Shuffle: section.Ordering.Shuffle,
+			}
+		}
+		for _, ref := range section.ItemRefs {
+			genericSection.ItemRefs = append(genericSection.ItemRefs, models.ItemRef{
+				XMLName:            xml.Name{Local: "assessmentItemRef"},
+				Identifier:         ref.Identifier,
+				Href:               ref.Href,
+				Category:           strings.Join(ref.Category, " "),
+				ItemSessionControl: convertItemSessionControl30ToGeneric(ref.ItemSessionControl),
+				TimeLimits:         convertTimeLimits30ToGeneric(ref.TimeLimits),
+				Weights:            convertWeights30ToGeneric(ref.Weights),
+			})
+		}
+		genericSections = append(genericSections, genericSection)
+	}
+	return genericSections
+}
+
+func convertWeights30ToGeneric(weights []models.Weight30) []models.Weight {
+	var genericWeights []models.Weight
+	for _, weight := range weights {
+		genericWeights = append(genericWeights, models.Weight{
+			XMLName:    xml.Name{Local: "weight"},
+			Identifier: weight.Identifier,
+			Value:      weight.Value,
+		})
+	}
+	return genericWeights
+}
+
+// convertViews30ToGeneric renames a list of views to the 2.1 vocabulary,
+// e.g. test-constructor to testConstructor.
+func convertViews30ToGeneric(views []string) string {
+	var fields []string
+	for _, view := range views {
+		for _, field := range strings.Fields(view) {
+			fields = append(fields, kebabToCamel(field))
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// contentBody30 returns the markup of an element whose content may be
+// wrapped in qti-content-body.
+func contentBody30(body *models.ContentBody30, content string) string {
+	if body != nil {
+		return body.Content
+	}
+	return content
+}
+
+func convertOutcomeProcessing30ToGeneric(op *models.OutcomeProcessing30) *models.OutcomeProcessing {
+	if op == nil {
+		return nil
+	}
+	outcomeProcessing := &models.OutcomeProcessing{XMLName: xml.Name{Local: "outcomeProcessing"}}
+	for _, rule := range op.OutcomeRules {
+		outcomeProcessing.Rules = append(outcomeProcessing.Rules, convertRule30ToGeneric(rule))
+	}
+	return outcomeProcessing
+}
+
+func convertTestFeedback30ToGeneric(feedbacks []models.TestFeedback30) []models.TestFeedback {
+	var genericFeedbacks []models.TestFeedback
+	for _, feedback := range feedbacks {
+		genericFeedbacks = append(genericFeedbacks, models.TestFeedback{
+			XMLName:           xml.Name{Local: "testFeedback"},
+			Access:            feedback.Access,
+			OutcomeIdentifier: feedback.OutcomeIdentifier,
+			ShowHide:          feedback.ShowHide,
+			Identifier:        feedback.Identifier,
+			Title:             feedback.Title,
+			Content:           toQTI21Vocabulary(contentBody30(feedback.ContentBody, feedback.Content)),
+		})
+	}
+	return genericFeedbacks
+}
+
+func convertItemSessionControl30ToGeneric(control *models.ItemSessionControl30) *models.ItemSessionControl {
+	if control == nil {
+		return nil
+	}
+	return &models.ItemSessionControl{
+		XMLName:     xml.Name{Local: "itemSessionControl"},
+		MaxAttempts: control.MaxAttempts,
 	}
 }
 
-func convertItemBody30ToGeneric(body *models.ItemBody30) *models.ItemBody {
+func convertTimeLimits30ToGeneric(limits *models.TimeLimits30) *models.TimeLimits {
+	if limits == nil {
+		return nil
+	}
+	return &models.TimeLimits{
+		XMLName:             xml.Name{Local: "timeLimits"},
+		MinTime:             limits.MinTime,
+		MaxTime:             limits.MaxTime,
+		AllowLateSubmission: limits.AllowLateSubmission,
+	}
+}
+
+// convertItemBody30ToGeneric decodes an item body. itemAttrs are the
+// attributes of the item, whose namespace declarations the body content may
+// use.
+func convertItemBody30ToGeneric(body *models.ItemBody30, itemAttrs []xml.Attr) (*models.ItemBody, error) {
 	if body == nil {
+		return nil, nil
+	}
+	// The body is rewritten to the 2.1 vocabulary and decoded into the
+	// generic item body, which shares its structure with QTI 2.1. The
+	// wrapper carries the attributes of the body and declares the prefixes
+	// bound on the item and the body, so prefixed content such as MathML
+	// keeps its namespace.
+	start := xml.StartElement{Name: xml.Name{Local: "itemBody"}, Attr: namespaceDeclarations(itemAttrs, body.Attrs)}
+	for _, attr := range body.Attrs {
+		if attr.Name.Space != "xmlns" && !(attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+			start.Attr = append(start.Attr, attr)
+		}
+	}
+	var content bytes.Buffer
+	encoder := xml.NewEncoder(&content)
+	if err := encoder.EncodeToken(start); err != nil {
+		return nil, fmt.Errorf("failed to decode item body: %w", err)
+	}
+	if err := encoder.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to decode item body: %w", err)
+	}
+	content.WriteString(toQTI21Vocabulary(body.Content) + "</itemBody>")
+
+	var itemBody models.ItemBody
+	if err := xml.Unmarshal(content.Bytes(), &itemBody); err != nil {
+		return nil, fmt.Errorf("failed to decode item body: %w", err)
+	}
+	return &itemBody, nil
+}
+
+// namespaceDeclarations returns the prefix declarations of attribute lists
+// as plain xmlns:prefix attributes, which encoding/xml writes unchanged. A
+// later list overrides the prefixes of an earlier one.
+func namespaceDeclarations(attrLists ...[]xml.Attr) []xml.Attr {
+	var declarations []xml.Attr
+	index := make(map[string]int)
+	for _, attrs := range attrLists {
+		for _, attr := range attrs {
+			if attr.Name.Space != "xmlns" {
+				continue
+			}
+			declaration := xml.Attr{Name: xml.Name{Local: "xmlns:" + attr.Name.Local}, Value: attr.Value}
+			if i, ok := index[attr.Name.Local]; ok {
+				declarations[i] = declaration
+				continue
+			}
+			index[attr.Name.Local] = len(declarations)
+			declarations = append(declarations, declaration)
+		}
+	}
+	return declarations
+}
+
+func convertResponseProcessing30ToGeneric(rp *models.ResponseProcessing30) *models.ResponseProcessing {
+	if rp == nil {
+		return nil
+	}
+	responseProcessing := &models.ResponseProcessing{
+		XMLName:          xml.Name{Local: "responseProcessing"},
+		Template:         rp.Template,
+		TemplateLocation: rp.TemplateLocation,
+	}
+	for _, rule := range rp.ResponseRules {
+		responseProcessing.Rules = append(responseProcessing.Rules, convertRule30ToGeneric(rule))
+	}
+	return responseProcessing
+}
+
+func convertTemplateProcessing30ToGeneric(tp *models.TemplateProcessing30) *models.TemplateProcessing {
+	if tp == nil {
 		return nil
 	}
-	// QTI 3.0 ItemBody has a more flexible structure
-	// For now, we'll create a simple generic ItemBody
-	return &models.ItemBody{
-		XMLName: body.XMLName,
-		// The content needs special handling as it's an interface{} slice in QTI 3.0
-		// This would require more sophisticated conversion based on actual content types
+	templateProcessing := &models.TemplateProcessing{XMLName: xml.Name{Local: "templateProcessing"}}
+	for _, rule := range tp.TemplateRules {
+		templateProcessing.Rules = append(templateProcessing.Rules, convertRule30ToGeneric(rule))
+	}
+	return templateProcessing
+}
+
+func convertStylesheets30ToGeneric(stylesheets []models.Stylesheet30) []models.Stylesheet {
+	var genericStylesheets []models.Stylesheet
+	for _, stylesheet := range stylesheets {
+		genericStylesheets = append(genericStylesheets, models.Stylesheet{
+			XMLName: xml.Name{Local: "stylesheet"},
+			Href:    stylesheet.Href,
+			Type:    stylesheet.Type,
+			Media:   stylesheet.Media,
+			Title:   stylesheet.Title,
+		})
+	}
+	return genericStylesheets
+}
+
+// convertRule30ToGeneric renames a response rule and its expressions to the
+// 2.1 vocabulary, e.g. qti-set-outcome-value to setOutcomeValue.
+func convertRule30ToGeneric(node models.XMLNode) models.XMLNode {
+	rule := models.XMLNode{
+		XMLName: xml.Name{Local: kebabToCamel(strings.TrimPrefix(node.XMLName.Local, "qti-"))},
+		Value:   node.Value,
+	}
+	for _, attr := range node.Attrs {
+		rule.Attrs = append(rule.Attrs, xml.Attr{Name: xml.Name{Local: kebabToCamel(attr.Name.Local)}, Value: attr.Value})
+	}
+	for _, child := range node.Children {
+		rule.Children = append(rule.Children, convertRule30ToGeneric(child))
 	}
+	return rule
 }
 
 func convertResponseDecl30ToGeneric(decls []models.ResponseDecl30) []models.ResponseDecl {
@@ -91,7 +427,8 @@ func convertResponseDecl30ToGeneric(decls []models.ResponseDecl30) []models.Resp
 			Cardinality:  decl.Cardinality,
 			BaseType:     decl.BaseType,
 			CorrectResponse: convertCorrectResponse30ToGeneric(decl.CorrectResponse),
-			Mapping:      convertMapping30ToGeneric(decl.Mapping),
+			Mapping:         convertMapping30ToGeneric(decl.Mapping),
+			AreaMapping:     convertAreaMapping30ToGeneric(decl.AreaMapping),
 		}
 	}
 	return genericDecls
@@ -101,14 +438,23 @@ func convertCorrectResponse30ToGeneric(cr *models.CorrectResponse30) *models.Cor
 	if cr == nil {
 		return nil
 	}
-	values := make([]string, len(cr.Value))
-	for i, v := range cr.Value {
-		values[i] = v.Content
-	}
 	return &models.CorrectResponse{
-		XMLName: cr.XMLName,
-		Value:   values,
+		XMLName: xml.Name{Local: "correctResponse"},
+		Value:   convertValues30ToGeneric(cr.Value),
+	}
+}
+
+func convertValues30ToGeneric(values []models.Value30) []models.Value {
+	genericValues := make([]models.Value, len(values))
+	for i, v := range values {
+		genericValues[i] = models.Value{
+			XMLName:         xml.Name{Local: "value"},
+			FieldIdentifier: v.FieldIdentifier,
+			BaseType:        v.BaseType,
+			Content:         v.Content,
+		}
 	}
+	return genericValues
 }
 
 func convertMapping30ToGeneric(m *models.Mapping30) *models.Mapping {
@@ -128,6 +474,23 @@ func convertMapping30ToGeneric(m *models.Mapping30) *models.Mapping {
 	}
 }
 
+func convertAreaMapping30ToGeneric(m *models.AreaMapping30) *models.AreaMapping {
+	if m == nil {
+		return nil
+	}
+	entries := make([]models.AreaMapEntry, len(m.AreaMapEntry))
+	for i, e := range m.AreaMapEntry {
+		entries[i] = models.AreaMapEntry(e)
+	}
+	return &models.AreaMapping{
+		XMLName:      m.XMLName,
+		LowerBound:   m.LowerBound,
+		UpperBound:   m.UpperBound,
+		DefaultValue: m.DefaultValue,
+		AreaMapEntry: entries,
+	}
+}
+
 func convertOutcomeDecl30ToGeneric(decls []models.OutcomeDecl30) []models.OutcomeDecl {
 	genericDecls := make([]models.OutcomeDecl, len(decls))
 	for i, decl := range decls {
@@ -136,6 +499,12 @@ func convertOutcomeDecl30ToGeneric(decls []models.OutcomeDecl30) []models.Outcom
 			Identifier:   decl.Identifier,
 			Cardinality:  decl.Cardinality,
 			BaseType:     decl.BaseType,
+			View:               strings.Join(decl.View, " "),
+			Interpretation:     decl.Interpretation,
+			LongInterpretation: decl.LongInterpretation,
+			NormalMaximum:      decl.NormalMaximum,
+			NormalMinimum:      decl.NormalMinimum,
+			MasteryValue:       decl.MasteryValue,
 			DefaultValue: convertDefaultValue30ToGeneric(decl.DefaultValue),
 		}
 	}
@@ -146,14 +515,9 @@ func convertDefaultValue30ToGeneric(dv *models.DefaultValue30) *models.DefaultVa
 	if dv == nil {
 		return nil
 	}
-	// Take the first value for simplicity
-	value := ""
-	if len(dv.Value) > 0 {
-		value = dv.Value[0].Content
-	}
 	return &models.DefaultValue{
-		XMLName: dv.XMLName,
-		Value:   value,
+		XMLName: xml.Name{Local: "defaultValue"},
+		Value:   convertValues30ToGeneric(dv.Value),
 	}
 }
 
@@ -166,6 +530,7 @@ func convertTemplateDecl30ToGeneric(decls []models.TemplateDecl30) []models.Temp
 			Cardinality:   decl.Cardinality,
 			BaseType:      decl.BaseType,
 			ParamVariable: decl.ParamVariable,
+			MathVariable:  decl.MathVariable,
 			DefaultValue:  convertDefaultValue30ToGeneric(decl.DefaultValue),
 		}
 	}
@@ -177,13 +542,15 @@ func convertModalFeedback30ToGeneric(feedbacks []models.ModalFeedback30) []model
 	for i, feedback := range feedbacks {
 		// Convert modalFeedback to itemfeedback format
 		genericFeedbacks[i] = models.Feedback{
-			XMLName: xml.Name{Local: "itemfeedback"},
-			Ident:   feedback.Identifier,
-			Title:   feedback.Title,
+			XMLName:           xml.Name{Local: "itemfeedback"},
+			Ident:             feedback.Identifier,
+			Title:             feedback.Title,
+			OutcomeIdentifier: feedback.OutcomeIdentifier,
+			ShowHide:          feedback.ShowHide,
 			Material: &models.Material{
 				MatText: []models.MatText{
 					{
-						Content: feedback.Content,
+						Content: toQTI21Vocabulary(feedback.Content),
 					},
 				},
 			},
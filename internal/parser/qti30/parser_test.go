@@ -0,0 +1,484 @@
+package qti30
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestParser30_Parse_AssessmentItem(t *testing.T) {
+	itemXML := `<?xml version="1.0" encoding="UTF-8"?>
+<qti-assessment-item xmlns="http://www.imsglobal.org/xsd/imsqtiasi_v3p0" identifier="q001" title="Capital Cities" adaptive="false" time-dependent="false">
+  <qti-response-declaration identifier="RESPONSE" cardinality="single" base-type="identifier">
+    <qti-correct-response>
+      <qti-value>B</qti-value>
+    </qti-correct-response>
+    <qti-mapping lower-bound="0" upper-bound="1" default-value="0">
+      <qti-map-entry map-key="B" mapped-value="1"/>
+    </qti-mapping>
+  </qti-response-declaration>
+  <qti-outcome-declaration identifier="SCORE" cardinality="single" base-type="float">
+    <qti-default-value>
+      <qti-value>0</qti-value>
+    </qti-default-value>
+  </qti-outcome-declaration>
+  <qti-item-body>
+    <qti-choice-interaction response-identifier="RESPONSE" max-choices="1">
+      <qti-prompt>What is the capital of France?</qti-prompt>
+      <qti-simple-choice identifier="A">London</qti-simple-choice>
+      <qti-simple-choice identifier="B">Paris</qti-simple-choice>
+    </qti-choice-interaction>
+  </qti-item-body>
+  <qti-response-processing template="https://purl.imsglobal.org/spec/qti/v3p0/rptemplates/match_correct.xml"/>
+  <qti-modal-feedback outcome-identifier="FEEDBACK" identifier="correct" show-hide="show">Correct!</qti-modal-feedback>
+</qti-assessment-item>`
+
+	parser := New()
+	doc, err := parser.Parse([]byte(itemXML))
+	if err != nil {
+		t.Fatalf("Failed to parse QTI 3.0 item: %v", err)
+	}
+
+	if doc.Version != "3.0" {
+		t.Errorf("Expected version '3.0', got '%s'", doc.Version)
+	}
+
+	if len(doc.Items) != 1 {
+		t.Fatalf("Expected 1 item, got %d", len(doc.Items))
+	}
+
+	item := doc.Items[0]
+	if item.Ident != "q001" || item.Title != "Capital Cities" {
+		t.Errorf("Expected identifier and title to be mapped, got '%s' / '%s'", item.Ident, item.Title)
+	}
+
+	if len(item.ResponseDecl) != 1 {
+		t.Fatalf("Expected 1 response declaration, got %d", len(item.ResponseDecl))
+	}
+
+	decl := item.ResponseDecl[0]
+	if decl.BaseType != "identifier" {
+		t.Errorf("Expected base-type 'identifier', got '%s'", decl.BaseType)
+	}
+
+	if decl.CorrectResponse == nil || len(decl.CorrectResponse.Value) != 1 || decl.CorrectResponse.Value[0].Content != "B" {
+		t.Error("Expected correct response 'B'")
+	}
+
+	if decl.Mapping == nil || len(decl.Mapping.MapEntry) != 1 || decl.Mapping.MapEntry[0].MappedValue != 1 {
+		t.Error("Expected mapping entry to be parsed")
+	}
+
+	if len(item.OutcomeDecl) != 1 || item.OutcomeDecl[0].DefaultValue == nil || item.OutcomeDecl[0].DefaultValue.First() != "0" {
+		t.Error("Expected outcome declaration with default value")
+	}
+
+	if len(item.Feedback) != 1 || item.Feedback[0].Ident != "correct" {
+		t.Error("Expected modal feedback to be mapped")
+	}
+}
+
+func TestParser30_Parse_AssessmentTest(t *testing.T) {
+	testXML := `<?xml version="1.0" encoding="UTF-8"?>
+<qti-assessment-test xmlns="http://www.imsglobal.org/xsd/imsqtiasi_v3p0" identifier="test001" title="Sample Test">
+  <qti-test-part identifier="part1" navigation-mode="linear" submission-mode="individual">
+    <qti-assessment-section identifier="sec1" title="Section 1" visible="true">
+      <qti-assessment-item-ref identifier="q001" href="items/q001.xml"/>
+      <qti-assessment-section identifier="sec1a" title="Section 1a" visible="true">
+        <qti-assessment-item-ref identifier="q002" href="items/q002.xml"/>
+      </qti-assessment-section>
+    </qti-assessment-section>
+  </qti-test-part>
+</qti-assessment-test>`
+
+	parser := New()
+	doc, err := parser.Parse([]byte(testXML))
+	if err != nil {
+		t.Fatalf("Failed to parse QTI 3.0 test: %v", err)
+	}
+
+	if doc.Assessment == nil || doc.Assessment.Ident != "test001" {
+		t.Fatal("Expected assessment to be mapped")
+	}
+
+	if len(doc.Assessment.Sections) != 1 || len(doc.Assessment.Sections[0].Sections) != 1 {
+		t.Fatal("Expected test part with one section")
+	}
+
+	section := doc.Assessment.Sections[0].Sections[0]
+	if len(section.ItemRefs) != 1 || section.ItemRefs[0].Href != "items/q001.xml" {
+		t.Error("Expected item reference to be kept")
+	}
+
+	if len(section.Sections) != 1 || len(section.Sections[0].ItemRefs) != 1 {
+		t.Error("Expected nested section with its item reference")
+	}
+}
+
+func TestParser30_Parse_WrongNamespace(t *testing.T) {
+	itemXML := `<qti-assessment-item xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="q001" title="Q"/>`
+
+	_, err := New().Parse([]byte(itemXML))
+	if err == nil {
+		t.Fatal("Expected error for non QTI 3.0 namespace")
+	}
+	if !strings.Contains(err.Error(), "namespace") {
+		t.Errorf("Expected namespace error, got: %v", err)
+	}
+}
+
+func TestParser30_Parse_CamelCaseRoot(t *testing.T) {
+	_, err := New().Parse([]byte(`<qtiAssessmentItem identifier="q001" title="Q"/>`))
+	if err == nil {
+		t.Fatal("Expected error for camelCase root element")
+	}
+}
+
+func TestParser30_Parse_ItemBody(t *testing.T) {
+	itemXML := `<qti-assessment-item xmlns="http://www.imsglobal.org/xsd/imsqtiasi_v3p0" identifier="q002" title="Body">
+  <qti-item-body>
+    <p>Fill in: <qti-text-entry-interaction response-identifier="R1" expected-length="5"/></p>
+    <div class="stem"><img src="media/map.png" alt="Map"/></div>
+    <ul><li>First</li><li>Second</li></ul>
+    <qti-choice-interaction response-identifier="R2" max-choices="1" shuffle="true">
+      <qti-prompt>Pick <strong>one</strong></qti-prompt>
+      <qti-simple-choice identifier="A" fixed="true">Alpha</qti-simple-choice>
+      <qti-simple-choice identifier="B">Beta</qti-simple-choice>
+    </qti-choice-interaction>
+    <qti-extended-text-interaction response-identifier="R3" expected-lines="4">
+      <qti-prompt>Explain.</qti-prompt>
+    </qti-extended-text-interaction>
+    <qti-order-interaction response-identifier="R4" data-max-selections-message="x">
+      <qti-prompt>Order these</qti-prompt>
+      <qti-simple-choice identifier="C1">One</qti-simple-choice>
+    </qti-order-interaction>
+  </qti-item-body>
+</qti-assessment-item>`
+
+	doc, err := New().Parse([]byte(itemXML))
+	if err != nil {
+		t.Fatalf("Failed to parse QTI 3.0 item: %v", err)
+	}
+
+	body := doc.Items[0].ItemBody
+	if body == nil {
+		t.Fatal("Expected item body to be kept")
+	}
+
+	if len(body.P) != 1 || !strings.Contains(body.P[0].Content, `<textEntryInteraction responseIdentifier="R1" expectedLength="5"/>`) {
+		t.Errorf("Expected paragraph with inline text entry in 2.1 vocabulary, got %+v", body.P)
+	}
+
+	if len(body.Div) != 1 || body.Div[0].Class != "stem" || !strings.Contains(body.Div[0].Content, "<img") {
+		t.Errorf("Expected div with image, got %+v", body.Div)
+	}
+
+	if len(body.ChoiceInteraction) != 1 {
+		t.Fatalf("Expected 1 choice interaction, got %d", len(body.ChoiceInteraction))
+	}
+
+	choice := body.ChoiceInteraction[0]
+	if choice.ResponseIdent != "R2" || choice.MaxChoices != 1 || !choice.Shuffle {
+		t.Errorf("Expected choice interaction attributes to be decoded, got %+v", choice)
+	}
+
+	if choice.Prompt == nil || choice.Prompt.Content != "Pick <strong>one</strong>" {
+		t.Error("Expected prompt with markup to be kept")
+	}
+
+	if len(choice.SimpleChoice) != 2 || !choice.SimpleChoice[0].Fixed || choice.SimpleChoice[1].Content != "Beta" {
+		t.Errorf("Expected simple choices to be decoded, got %+v", choice.SimpleChoice)
+	}
+
+	if len(body.ExtendedTextInteraction) != 1 || body.ExtendedTextInteraction[0].ExpectedLines != 4 {
+		t.Error("Expected extended text interaction to be decoded")
+	}
+
+	if len(body.Other) != 1 {
+		t.Fatalf("Expected list to be kept, got %d elements", len(body.Other))
+	}
+
+	if body.Other[0].XMLName.Local != "ul" || !strings.Contains(body.Other[0].Content, "<li>Second</li>") {
+		t.Errorf("Expected list to be kept, got %+v", body.Other[0])
+	}
+
+	if len(body.OrderInteraction) != 1 {
+		t.Fatalf("Expected 1 order interaction, got %d", len(body.OrderInteraction))
+	}
+
+	order := body.OrderInteraction[0]
+	if order.ResponseIdent != "R4" || order.Prompt == nil || order.Prompt.Content != "Order these" {
+		t.Errorf("Expected order interaction attributes to be decoded, got %+v", order)
+	}
+	if len(order.SimpleChoice) != 1 || order.SimpleChoice[0].Identifier != "C1" || order.SimpleChoice[0].Content != "One" {
+		t.Errorf("Expected nested choices in 2.1 vocabulary, got %+v", order.SimpleChoice)
+	}
+}
+
+func TestToQTI21Vocabulary(t *testing.T) {
+	testCases := map[string]string{
+		`<qti-inline-choice-interaction response-identifier="R" required="true"/>`: `<inlineChoiceInteraction responseIdentifier="R" required="true"/>`,
+		`<qti-gap-text identifier="G" match-max="1">x</qti-gap-text>`:              `<gapText identifier="G" matchMax="1">x</gapText>`,
+		`<span data-qti-class="a-b" aria-label="x-y">text-with-dashes</span>`:      `<span data-qti-class="a-b" aria-label="x-y">text-with-dashes</span>`,
+		`<qti-prompt title="a > b" data-extra-info="1">p</qti-prompt>`:             `<prompt title="a > b" data-extra-info="1">p</prompt>`,
+		// Only element and attribute names are renamed
+		`<p><![CDATA[<qti-prompt max-choices="1">]]></p>`:                             `<p><![CDATA[<qti-prompt max-choices="1">]]></p>`,
+		`<!-- <qti-prompt/> --><qti-prompt>x</qti-prompt>`:                            `<!-- <qti-prompt/> --><prompt>x</prompt>`,
+		`<code title="<qti-prompt>">set max-choices="1" in &lt;qti-prompt&gt;</code>`: `<code title="<qti-prompt>">set max-choices="1" in &lt;qti-prompt&gt;</code>`,
+		`<qti-simple-choice identifier="A" template-identifier="qti-x"/>`:             `<simpleChoice identifier="A" templateIdentifier="qti-x"/>`,
+	}
+
+	for input, expected := range testCases {
+		if result := toQTI21Vocabulary(input); result != expected {
+			t.Errorf("toQTI21Vocabulary(%s): expected %s, got %s", input, expected, result)
+		}
+	}
+}
+
+func TestParser30_Parse_ResponseProcessing(t *testing.T) {
+	itemXML := `<qti-assessment-item xmlns="http://www.imsglobal.org/xsd/imsqtiasi_v3p0" identifier="q003">
+  <qti-response-processing>
+    <qti-response-condition>
+      <qti-response-if>
+        <qti-match><qti-variable identifier="RESPONSE"/><qti-correct identifier="RESPONSE"/></qti-match>
+        <qti-set-outcome-value identifier="SCORE"><qti-base-value base-type="float">1</qti-base-value></qti-set-outcome-value>
+      </qti-response-if>
+    </qti-response-condition>
+  </qti-response-processing>
+</qti-assessment-item>`
+
+	doc, err := New().Parse([]byte(itemXML))
+	if err != nil {
+		t.Fatalf("Failed to parse QTI 3.0 item: %v", err)
+	}
+
+	rp := doc.Items[0].ResponseProcessing
+	if rp == nil || len(rp.Rules) != 1 {
+		t.Fatalf("Expected one response rule, got %+v", rp)
+	}
+
+	responseIf := rp.Rules[0].Children[0]
+	if rp.Rules[0].XMLName.Local != "responseCondition" || responseIf.XMLName.Local != "responseIf" {
+		t.Errorf("Expected rules in 2.1 vocabulary, got %s/%s", rp.Rules[0].XMLName.Local, responseIf.XMLName.Local)
+	}
+
+	baseValue := responseIf.Children[1].Children[0]
+	if baseValue.XMLName.Local != "baseValue" || baseValue.Attr("baseType") != "float" || baseValue.Value != "1" {
+		t.Errorf("Expected baseValue with baseType float, got %+v", baseValue)
+	}
+}
+
+func TestParser30_Parse_Declarations(t *testing.T) {
+	itemXML := `<qti-assessment-item xmlns="http://www.imsglobal.org/xsd/imsqtiasi_v3p0" identifier="q004" title="Declarations" adaptive="true">
+  <qti-response-declaration identifier="RESPONSE" cardinality="record">
+    <qti-correct-response>
+      <qti-value field-identifier="x" base-type="integer">3</qti-value>
+      <qti-value field-identifier="y" base-type="string">b</qti-value>
+    </qti-correct-response>
+  </qti-response-declaration>
+  <qti-outcome-declaration identifier="SCORE" cardinality="multiple" base-type="float" view="author scorer" normal-maximum="10" normal-minimum="0" mastery-value="7">
+    <qti-default-value>
+      <qti-value>1</qti-value>
+      <qti-value>2</qti-value>
+    </qti-default-value>
+  </qti-outcome-declaration>
+  <qti-template-declaration identifier="T" cardinality="single" base-type="integer" math-variable="true"/>
+</qti-assessment-item>`
+
+	doc, err := New().Parse([]byte(itemXML))
+	if err != nil {
+		t.Fatalf("Failed to parse QTI 3.0 item: %v", err)
+	}
+
+	item := doc.Items[0]
+	if !item.Adaptive {
+		t.Error("Expected adaptive to be kept")
+	}
+
+	values := item.ResponseDecl[0].CorrectResponse.Value
+	if len(values) != 2 || values[0].FieldIdentifier != "x" || values[0].BaseType != "integer" || values[1].BaseType != "string" {
+		t.Errorf("Expected record values with field identifiers and base types, got %+v", values)
+	}
+
+	outcome := item.OutcomeDecl[0]
+	if outcome.DefaultValue == nil || len(outcome.DefaultValue.Value) != 2 || outcome.DefaultValue.Value[1].Content != "2" {
+		t.Errorf("Expected both default values, got %+v", outcome.DefaultValue)
+	}
+	if outcome.NormalMaximum == nil || *outcome.NormalMaximum != 10 || outcome.NormalMinimum == nil || *outcome.NormalMinimum != 0 {
+		t.Errorf("Expected normal range 0..10, got %v..%v", outcome.NormalMinimum, outcome.NormalMaximum)
+	}
+	if outcome.MasteryValue == nil || *outcome.MasteryValue != 7 || outcome.View != "author scorer" {
+		t.Errorf("Expected mastery value and views, got %+v", outcome)
+	}
+
+	if len(item.TemplateDecl) != 1 || !item.TemplateDecl[0].MathVariable {
+		t.Errorf("Expected math variable template declaration, got %+v", item.TemplateDecl)
+	}
+}
+
+func TestParser30_Parse_BodyAttributes(t *testing.T) {
+	itemXML := `<qti-assessment-item xmlns="http://www.imsglobal.org/xsd/imsqtiasi_v3p0" identifier="q005">
+  <qti-item-body>
+    <p id="intro">Pick</p>
+    <qti-choice-interaction response-identifier="R" max-choices="1" data-extra="x">
+      <qti-simple-choice identifier="A" template-identifier="T" show-hide="show">A</qti-simple-choice>
+    </qti-choice-interaction>
+    <qti-text-entry-interaction response-identifier="R2" base="16" string-identifier="S"/>
+  </qti-item-body>
+</qti-assessment-item>`
+
+	doc, err := New().Parse([]byte(itemXML))
+	if err != nil {
+		t.Fatalf("Failed to parse QTI 3.0 item: %v", err)
+	}
+
+	body := doc.Items[0].ItemBody
+	if len(body.P) != 1 || attr(body.P[0].Attrs, "id") != "intro" {
+		t.Errorf("Expected paragraph id to be kept, got %+v", body.P)
+	}
+
+	choice := body.ChoiceInteraction[0]
+	if attr(choice.Attrs, "data-extra") != "x" {
+		t.Errorf("Expected data attribute to be kept, got %+v", choice.Attrs)
+	}
+	simpleChoice := choice.SimpleChoice[0]
+	if attr(simpleChoice.Attrs, "templateIdentifier") != "T" || attr(simpleChoice.Attrs, "showHide") != "show" {
+		t.Errorf("Expected choice attributes in 2.1 vocabulary, got %+v", simpleChoice.Attrs)
+	}
+
+	textEntry := body.TextEntryInteraction[0]
+	if attr(textEntry.Attrs, "base") != "16" || attr(textEntry.Attrs, "stringIdentifier") != "S" {
+		t.Errorf("Expected text entry attributes to be kept, got %+v", textEntry.Attrs)
+	}
+}
+
+func TestParser30_Parse_TestControls(t *testing.T) {
+	testXML := `<qti-assessment-test xmlns="http://www.imsglobal.org/xsd/imsqtiasi_v3p0" identifier="t1" title="Controls">
+  <qti-time-limits max-time="3600"/>
+  <qti-test-part identifier="part1" navigation-mode="nonlinear" submission-mode="simultaneous">
+    <qti-item-session-control max-attempts="2"/>
+    <qti-assessment-section identifier="s1" title="S1" visible="false">
+      <qti-selection select="1"/>
+      <qti-ordering shuffle="true"/>
+      <qti-assessment-item-ref identifier="q1" href="q1.xml">
+        <qti-time-limits max-time="60"/>
+      </qti-assessment-item-ref>
+    </qti-assessment-section>
+  </qti-test-part>
+</qti-assessment-test>`
+
+	doc, err := New().Parse([]byte(testXML))
+	if err != nil {
+		t.Fatalf("Failed to parse QTI 3.0 test: %v", err)
+	}
+
+	test := doc.Assessment
+	if test.TimeLimits == nil || test.TimeLimits.MaxTime != 3600 {
+		t.Errorf("Expected test time limits, got %+v", test.TimeLimits)
+	}
+
+	part := test.Sections[0]
+	if part.ItemSessionControl == nil || part.ItemSessionControl.MaxAttempts == nil || *part.ItemSessionControl.MaxAttempts != 2 {
+		t.Errorf("Expected test part item session control, got %+v", part.ItemSessionControl)
+	}
+
+	section := part.Sections[0]
+	if section.Visible == nil || *section.Visible {
+		t.Error("Expected invisible section")
+	}
+	if section.Selection == nil || section.Selection.Select != 1 || section.Ordering == nil || !section.Ordering.Shuffle {
+		t.Errorf("Expected selection and ordering, got %+v / %+v", section.Selection, section.Ordering)
+	}
+	if ref := section.ItemRefs[0]; ref.TimeLimits == nil || ref.TimeLimits.MaxTime != 60 {
+		t.Errorf("Expected item ref time limits, got %+v", ref.TimeLimits)
+	}
+}
+
+func TestParser30_Parse_TestOutcomes(t *testing.T) {
+	testXML := `<qti-assessment-test xmlns="http://www.imsglobal.org/xsd/imsqtiasi_v3p0" identifier="t1" title="Outcomes">
+  <qti-outcome-declaration identifier="SCORE" cardinality="single" base-type="float"/>
+  <qti-test-part identifier="part1" navigation-mode="linear" submission-mode="individual">
+    <qti-assessment-section identifier="s1" title="S1" visible="true">
+      <qti-rubric-block view="test-constructor"><qti-content-body><p>Note</p></qti-content-body></qti-rubric-block>
+      <qti-assessment-item-ref identifier="q1" href="q1.xml" category="easy">
+        <qti-weight identifier="W1" value="2"/>
+      </qti-assessment-item-ref>
+    </qti-assessment-section>
+    <qti-test-feedback access="during" outcome-identifier="PART" show-hide="show" identifier="pf"><p>Part</p></qti-test-feedback>
+  </qti-test-part>
+  <qti-outcome-processing>
+    <qti-set-outcome-value identifier="SCORE"><qti-test-variables variable-identifier="SCORE"/></qti-set-outcome-value>
+  </qti-outcome-processing>
+  <qti-test-feedback access="atEnd" outcome-identifier="SCORE" show-hide="show" identifier="end"><qti-content-body><p>Done</p></qti-content-body></qti-test-feedback>
+</qti-assessment-test>`
+
+	doc, err := New().Parse([]byte(testXML))
+	if err != nil {
+		t.Fatalf("Failed to parse QTI 3.0 test: %v", err)
+	}
+
+	test := doc.Assessment
+	if len(test.OutcomeDecl) != 1 || test.OutcomeDecl[0].Identifier != "SCORE" {
+		t.Errorf("Expected the test outcome declaration, got %+v", test.OutcomeDecl)
+	}
+	if test.OutcomeProcessing == nil || len(test.OutcomeProcessing.Rules) != 1 {
+		t.Fatalf("Expected outcome processing with one rule, got %+v", test.OutcomeProcessing)
+	}
+	rule := test.OutcomeProcessing.Rules[0]
+	if rule.XMLName.Local != "setOutcomeValue" || rule.Children[0].XMLName.Local != "testVariables" || rule.Children[0].Attr("variableIdentifier") != "SCORE" {
+		t.Errorf("Expected rules in the 2.1 vocabulary, got %+v", rule)
+	}
+	if len(test.TestFeedback) != 1 || test.TestFeedback[0].Access != "atEnd" || test.TestFeedback[0].Content != "<p>Done</p>" {
+		t.Errorf("Expected test feedback without its content body, got %+v", test.TestFeedback)
+	}
+
+	part := test.Sections[0]
+	if len(part.TestFeedback) != 1 || part.TestFeedback[0].Content != "<p>Part</p>" {
+		t.Errorf("Expected test part feedback, got %+v", part.TestFeedback)
+	}
+	section := part.Sections[0]
+	if len(section.RubricBlocks) != 1 || section.RubricBlocks[0].View != "testConstructor" || section.RubricBlocks[0].Content != "<p>Note</p>" {
+		t.Errorf("Expected rubric block, got %+v", section.RubricBlocks)
+	}
+	ref := section.ItemRefs[0]
+	if ref.Category != "easy" || len(ref.Weights) != 1 || ref.Weights[0].Identifier != "W1" || ref.Weights[0].Value != 2 {
+		t.Errorf("Expected item ref category and weight, got %+v", ref)
+	}
+}
+
+func attr(attrs []xml.Attr, name string) string {
+	for _, a := range attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func TestParser30_Parse_ItemBodyNamespaces(t *testing.T) {
+	itemXML := `<qti-assessment-item xmlns="http://www.imsglobal.org/xsd/imsqtiasi_v3p0" xmlns:m="http://www.w3.org/1998/Math/MathML" identifier="q006" title="Math">
+  <qti-item-body>
+    <m:math><m:mi>x</m:mi></m:math>
+    <p>Solve <m:math><m:mi>y</m:mi></m:math></p>
+  </qti-item-body>
+</qti-assessment-item>`
+
+	doc, err := New().Parse([]byte(itemXML))
+	if err != nil {
+		t.Fatalf("Failed to parse QTI 3.0 item: %v", err)
+	}
+
+	body := doc.Items[0].ItemBody
+	if len(body.Other) != 1 || body.Other[0].XMLName.Space != "http://www.w3.org/1998/Math/MathML" {
+		t.Fatalf("Expected math in the MathML namespace, got %+v", body.Other)
+	}
+
+	output, err := xml.Marshal(body)
+	if err != nil {
+		t.Fatalf("Failed to marshal item body: %v", err)
+	}
+	expected := `<p xmlns:m="http://www.w3.org/1998/Math/MathML">Solve <m:math><m:mi>y</m:mi></m:math></p>`
+	if !strings.Contains(string(output), expected) {
+		t.Errorf("Expected output to contain '%s', got:\n%s", expected, output)
+	}
+}
@@ -0,0 +1,67 @@
+package qti30
+
+import (
+	"encoding/xml"
+	"strings"
+
+	"github.com/qti-migrator/internal/markup"
+)
+
+// toQTI21Vocabulary rewrites QTI 3.0 markup to the QTI 2.1 vocabulary:
+// qti-choice-interaction becomes choiceInteraction and its max-choices
+// attribute becomes maxChoices. The markup is rewritten on its XML token
+// stream, so text, comments, CDATA sections, attribute values, HTML elements
+// and data-* / aria-* attributes are copied as written. Markup that cannot be
+// tokenized is returned unchanged.
+func toQTI21Vocabulary(content string) string {
+	var out strings.Builder
+	err := markup.Walk(content, func(token markup.Token) {
+		switch t := token.Token.(type) {
+		case xml.StartElement:
+			if !isQTIName(t.Name) {
+				out.WriteString(token.Raw)
+				return
+			}
+			t.Name.Local = renameQTIElement(t.Name.Local)
+			for i, attr := range t.Attr {
+				name := attr.Name.Local
+				if attr.Name.Space == "" && !strings.HasPrefix(name, "data-") && !strings.HasPrefix(name, "aria-") {
+					t.Attr[i].Name.Local = kebabToCamel(name)
+				}
+			}
+			out.WriteString(markup.StartTag(t, token.SelfClosing))
+		case xml.EndElement:
+			if isQTIName(t.Name) {
+				out.WriteString("</" + renameQTIElement(t.Name.Local) + ">")
+				return
+			}
+			out.WriteString(token.Raw)
+		default:
+			out.WriteString(token.Raw)
+		}
+	})
+	if err != nil {
+		return content
+	}
+	return out.String()
+}
+
+// isQTIName tells whether an element is in the QTI 3.0 vocabulary, e.g.
+// qti-prompt.
+func isQTIName(name xml.Name) bool {
+	return name.Space == "" && strings.HasPrefix(name.Local, "qti-")
+}
+
+func renameQTIElement(name string) string {
+	return kebabToCamel(strings.TrimPrefix(name, "qti-"))
+}
+
+func kebabToCamel(name string) string {
+	words := strings.Split(name, "-")
+	for i := 1; i < len(words); i++ {
+		if words[i] != "" {
+			words[i] = strings.ToUpper(words[i][:1]) + words[i][1:]
+		}
+	}
+	return strings.Join(words, "")
+}
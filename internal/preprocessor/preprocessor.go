@@ -2,49 +2,35 @@ package preprocessor
 
 import (
 	"fmt"
-	"strings"
 
+	"github.com/qti-migrator/internal/checker"
+	// Built-in migration steps
+	_ "github.com/qti-migrator/internal/migrator/qti12to21"
+	_ "github.com/qti-migrator/internal/migrator/qti21to30"
 	"github.com/qti-migrator/internal/parser"
+	"github.com/qti-migrator/pkg/analysis"
 	"github.com/qti-migrator/pkg/models"
+	"github.com/qti-migrator/pkg/registry"
 )
 
 type Preprocessor struct {
 	verbosity int
+	// Options of the migration analyzed, used to migrate the document
+	// between the steps of a migration with several
+	Options registry.MigrationOptions
+	// Version the document was detected or declared as, e.g. 2.2 for a
+	// document read by the 2.1 parser. It labels the consistency findings of
+	// the source, which are labelled with the version Analyze parses when it
+	// is empty.
+	SourceVersion string
 }
 
-type AnalysisReport struct {
-	SourceVersion      string
-	TargetVersion      string
-	TotalItems         int
-	CompatibleItems    int
-	IncompatibleItems  int
-	Warnings           []Warning
-	Errors             []Error
-	MigrationDetails   []MigrationDetail
-}
-
-type Warning struct {
-	ItemID      string
-	ElementPath string
-	Message     string
-	Suggestion  string
-}
-
-type Error struct {
-	ItemID      string
-	ElementPath string
-	Message     string
-	Fatal       bool
-}
-
-type MigrationDetail struct {
-	ItemID      string
-	ElementPath string
-	OldValue    string
-	NewValue    string
-	Action      string
-	Description string
-}
+// The report types live in pkg/analysis so that analysis hooks registered
+// from other modules can fill them in
+type AnalysisReport = analysis.Report
+type Warning = analysis.Warning
+type Error = analysis.Error
+type MigrationDetail = analysis.MigrationDetail
 
 func New(verbosity int) *Preprocessor {
 	return &Preprocessor{
@@ -63,281 +49,91 @@ func (p *Preprocessor) Analyze(content []byte, fromVersion, toVersion string) (*
 		return nil, fmt.Errorf("failed to parse document: %w", err)
 	}
 
+	plan, err := registry.Default.Plan(fromVersion, toVersion)
+	if err != nil {
+		return nil, err
+	}
+
 	report := &AnalysisReport{
 		SourceVersion: fromVersion,
 		TargetVersion: toVersion,
-		TotalItems:    len(doc.Items),
+		Path:          []string{fromVersion},
+		TotalItems:    countItems(doc),
 	}
 
-	if fromVersion == "1.2" && toVersion == "2.1" {
-		p.analyzeQTI12to21(doc, report)
-	} else if fromVersion == "2.1" && toVersion == "3.0" {
-		p.analyzeQTI21to30(doc, report)
-	} else {
-		return nil, fmt.Errorf("unsupported migration path: %s to %s", fromVersion, toVersion)
+	// Consistency findings of the source are labelled with its version
+	sourceVersion := p.SourceVersion
+	if sourceVersion == "" {
+		sourceVersion = fromVersion
 	}
+	checker.New().Check(doc, report, sourceVersion)
 
-	report.CompatibleItems = report.TotalItems - report.IncompatibleItems
-
-	return report, nil
-}
-
-func (p *Preprocessor) analyzeQTI12to21(doc *models.QTIDocument, report *AnalysisReport) {
-	for _, item := range doc.Items {
-		p.analyzeItem12to21(&item, report)
-	}
-
-	if doc.Assessment != nil {
-		for _, section := range doc.Assessment.Sections {
-			for _, item := range section.Items {
-				p.analyzeItem12to21(&item, report)
-			}
+	// Each step is analyzed on the document the previous step migrated to
+	incompatible := map[string]bool{}
+	for i, step := range plan {
+		if step.Analyze != nil {
+			stepReport := &AnalysisReport{}
+			step.Analyze(doc, stepReport, p.verbosity)
+			merge(report, stepReport, plan, step, incompatible)
 		}
-	}
-}
+		report.Path = append(report.Path, step.To)
 
-func (p *Preprocessor) analyzeItem12to21(item *models.Item, report *AnalysisReport) {
-	if item.Presentation != nil {
-		for _, response := range item.Presentation.Response {
-			if response.RenderChoice != nil && response.RenderChoice.Shuffle == "yes" {
-				report.MigrationDetails = append(report.MigrationDetails, MigrationDetail{
-					ItemID:      item.Ident,
-					ElementPath: fmt.Sprintf("item[@ident='%s']/presentation/response_lid[@ident='%s']/render_choice", item.Ident, response.Ident),
-					OldValue:    `shuffle="yes"`,
-					NewValue:    `shuffle="true"`,
-					Action:      "transform",
-					Description: "Convert shuffle attribute from yes/no to true/false",
-				})
+		if i < len(plan)-1 {
+			doc, err = step.Migrate(doc, p.Options)
+			if err != nil {
+				return nil, err
 			}
 		}
-
-		if item.Presentation.Material != nil {
-			p.analyzeMaterial12to21(item.Ident, "presentation/material", item.Presentation.Material, report)
-		}
 	}
 
-	if item.ResponseProc != nil {
-		if item.ResponseProc.ScoreModel == "" {
-			report.Warnings = append(report.Warnings, Warning{
-				ItemID:      item.Ident,
-				ElementPath: fmt.Sprintf("item[@ident='%s']/resprocessing", item.Ident),
-				Message:     "Score model not specified in QTI 1.2",
-				Suggestion:  "Default score model 'SumOfScores' will be applied",
-			})
-		}
-
-		for _, condition := range item.ResponseProc.ResCondition {
-			if condition.Continue == "yes" || condition.Continue == "no" {
-				report.MigrationDetails = append(report.MigrationDetails, MigrationDetail{
-					ItemID:      item.Ident,
-					ElementPath: fmt.Sprintf("item[@ident='%s']/resprocessing/respcondition", item.Ident),
-					OldValue:    fmt.Sprintf(`continue="%s"`, condition.Continue),
-					NewValue:    fmt.Sprintf(`continue="%t"`, condition.Continue == "yes"),
-					Action:      "transform",
-					Description: "Convert continue attribute from yes/no to true/false",
-				})
-			}
-		}
-	}
-
-	if item.Metadata != nil && item.Metadata.QTIMetadata != nil {
-		meta := item.Metadata.QTIMetadata
-		if meta.InteractionType != "" {
-			if !isValidQTI21InteractionType(meta.InteractionType) {
-				report.Warnings = append(report.Warnings, Warning{
-					ItemID:      item.Ident,
-					ElementPath: fmt.Sprintf("item[@ident='%s']/metadata/qtimetadata/interactiontype", item.Ident),
-					Message:     fmt.Sprintf("Interaction type '%s' may need adjustment for QTI 2.1", meta.InteractionType),
-					Suggestion:  "Review interaction type mapping for QTI 2.1 compliance",
-				})
-			}
-		}
-	}
-}
-
-func (p *Preprocessor) analyzeMaterial12to21(itemID, path string, material *models.Material, report *AnalysisReport) {
-	for i, matText := range material.MatText {
-		if matText.TextType == "text/html" && p.verbosity >= 2 {
-			report.MigrationDetails = append(report.MigrationDetails, MigrationDetail{
-				ItemID:      itemID,
-				ElementPath: fmt.Sprintf("%s/mattext[%d]", path, i+1),
-				OldValue:    "text/html content",
-				NewValue:    "XHTML content (validated)",
-				Action:      "validate",
-				Description: "HTML content will be validated and converted to XHTML if necessary",
-			})
-		}
-	}
+	report.IncompatibleItems = len(incompatible)
+	report.CompatibleItems = report.TotalItems - report.IncompatibleItems
 
-	for i, matImage := range material.MatImage {
-		if matImage.ImageType == "" && p.verbosity >= 2 {
-			report.Warnings = append(report.Warnings, Warning{
-				ItemID:      itemID,
-				ElementPath: fmt.Sprintf("%s/matimage[%d]", path, i+1),
-				Message:     "Image type not specified",
-				Suggestion:  "Image type will be inferred from file extension or set to 'image/jpeg' as default",
-			})
-		}
-	}
+	return report, nil
 }
 
-func (p *Preprocessor) analyzeQTI21to30(doc *models.QTIDocument, report *AnalysisReport) {
-	for _, item := range doc.Items {
-		p.analyzeItem21to30(&item, report)
-	}
-
+// countItems returns the number of items of doc, including those inside its
+// sections, as in QTI 1.2 banks.
+func countItems(doc *models.QTIDocument) int {
+	count := len(doc.Items)
 	if doc.Assessment != nil {
-		for _, section := range doc.Assessment.Sections {
-			for _, item := range section.Items {
-				p.analyzeItem21to30(&item, report)
-			}
+		for i := range doc.Assessment.Sections {
+			count += countSectionItems(&doc.Assessment.Sections[i])
 		}
-		
-		// Assessment structure will change in QTI 3.0
-		report.MigrationDetails = append(report.MigrationDetails, MigrationDetail{
-			ItemID:      doc.Assessment.Ident,
-			ElementPath: "assessment",
-			OldValue:    "assessment",
-			NewValue:    "qti-assessment-test",
-			Action:      "rename",
-			Description: "Assessment element renamed to qti-assessment-test in QTI 3.0",
-		})
 	}
+	return count
 }
 
-func (p *Preprocessor) analyzeItem21to30(item *models.Item, report *AnalysisReport) {
-	// Item element naming changes
-	report.MigrationDetails = append(report.MigrationDetails, MigrationDetail{
-		ItemID:      item.Ident,
-		ElementPath: fmt.Sprintf("item[@ident='%s']", item.Ident),
-		OldValue:    "item",
-		NewValue:    "qti-assessment-item",
-		Action:      "rename",
-		Description: "Item element renamed to qti-assessment-item in QTI 3.0",
-	})
-
-	// ItemBody changes
-	if item.ItemBody != nil {
-		report.MigrationDetails = append(report.MigrationDetails, MigrationDetail{
-			ItemID:      item.Ident,
-			ElementPath: fmt.Sprintf("item[@ident='%s']/itemBody", item.Ident),
-			OldValue:    "itemBody",
-			NewValue:    "qti-item-body",
-			Action:      "rename",
-			Description: "ItemBody element renamed to qti-item-body in QTI 3.0",
-		})
-
-		// Analyze interactions
-		for _, interaction := range item.ItemBody.ChoiceInteraction {
-			report.MigrationDetails = append(report.MigrationDetails, MigrationDetail{
-				ItemID:      item.Ident,
-				ElementPath: fmt.Sprintf("item[@ident='%s']/itemBody/choiceInteraction[@responseIdentifier='%s']", item.Ident, interaction.ResponseIdent),
-				OldValue:    "choiceInteraction",
-				NewValue:    "qti-choice-interaction",
-				Action:      "rename",
-				Description: "Interaction elements prefixed with 'qti-' in QTI 3.0",
-			})
-
-			report.MigrationDetails = append(report.MigrationDetails, MigrationDetail{
-				ItemID:      item.Ident,
-				ElementPath: fmt.Sprintf("item[@ident='%s']/itemBody/choiceInteraction/simpleChoice", item.Ident),
-				OldValue:    "simpleChoice",
-				NewValue:    "qti-simple-choice",
-				Action:      "rename",
-				Description: "SimpleChoice elements renamed to qti-simple-choice in QTI 3.0",
-			})
-		}
-
-		// Check for HTML content that needs updating
-		for _, para := range item.ItemBody.P {
-			if strings.Contains(para.Content, "class=") && p.verbosity >= 2 {
-				report.Warnings = append(report.Warnings, Warning{
-					ItemID:      item.Ident,
-					ElementPath: fmt.Sprintf("item[@ident='%s']/itemBody/p", item.Ident),
-					Message:     "HTML class attributes found in content",
-					Suggestion:  "Class attributes will be converted to data-qti-class in QTI 3.0",
-				})
-			}
-		}
+func countSectionItems(section *models.Section) int {
+	count := len(section.Items)
+	for i := range section.Sections {
+		count += countSectionItems(&section.Sections[i])
 	}
+	return count
+}
 
-	// Response declaration changes
-	for _, decl := range item.ResponseDecl {
-		report.MigrationDetails = append(report.MigrationDetails, MigrationDetail{
-			ItemID:      item.Ident,
-			ElementPath: fmt.Sprintf("item[@ident='%s']/responseDeclaration[@identifier='%s']", item.Ident, decl.Identifier),
-			OldValue:    "responseDeclaration",
-			NewValue:    "qti-response-declaration",
-			Action:      "rename",
-			Description: "Response declaration renamed in QTI 3.0",
-		})
-
-		// Base type changes
-		if decl.BaseType == "pair" {
-			report.MigrationDetails = append(report.MigrationDetails, MigrationDetail{
-				ItemID:      item.Ident,
-				ElementPath: fmt.Sprintf("item[@ident='%s']/responseDeclaration[@identifier='%s']/@baseType", item.Ident, decl.Identifier),
-				OldValue:    "pair",
-				NewValue:    "directedPair",
-				Action:      "transform",
-				Description: "BaseType 'pair' renamed to 'directedPair' in QTI 3.0",
-			})
+// merge adds the findings of one step to the report. Findings of a migration
+// with several steps are labelled with the step they come from. The items
+// the step reports errors on are added to incompatible, so an item counts
+// once however many steps it is incompatible with.
+func merge(report, stepReport *AnalysisReport, plan []registry.Step, step registry.Step, incompatible map[string]bool) {
+	label := ""
+	if len(plan) > 1 {
+		label = step.String()
+	}
+	for _, warning := range stepReport.Warnings {
+		warning.Step = label
+		report.Warnings = append(report.Warnings, warning)
+	}
+	for _, err := range stepReport.Errors {
+		err.Step = label
+		report.Errors = append(report.Errors, err)
+		if err.ItemID != "" {
+			incompatible[err.ItemID] = true
 		}
 	}
-
-	// Outcome declaration changes
-	for _, decl := range item.OutcomeDecl {
-		report.MigrationDetails = append(report.MigrationDetails, MigrationDetail{
-			ItemID:      item.Ident,
-			ElementPath: fmt.Sprintf("item[@ident='%s']/outcomeDeclaration[@identifier='%s']", item.Ident, decl.Identifier),
-			OldValue:    "outcomeDeclaration",
-			NewValue:    "qti-outcome-declaration",
-			Action:      "rename",
-			Description: "Outcome declaration renamed in QTI 3.0",
-		})
+	for _, detail := range stepReport.MigrationDetails {
+		detail.Step = label
+		report.MigrationDetails = append(report.MigrationDetails, detail)
 	}
-
-	// Metadata changes
-	if item.Metadata != nil && item.Metadata.QTIMetadata != nil {
-		report.MigrationDetails = append(report.MigrationDetails, MigrationDetail{
-			ItemID:      item.Ident,
-			ElementPath: fmt.Sprintf("item[@ident='%s']/metadata/qtimetadata", item.Ident),
-			OldValue:    "qtimetadata",
-			NewValue:    "qti-metadata-container",
-			Action:      "rename",
-			Description: "QTI metadata container renamed in QTI 3.0",
-		})
-	}
-}
-
-func isValidQTI21InteractionType(interactionType string) bool {
-	validTypes := map[string]bool{
-		"choiceInteraction":        true,
-		"orderInteraction":         true,
-		"associateInteraction":     true,
-		"matchInteraction":         true,
-		"gapMatchInteraction":      true,
-		"inlineChoiceInteraction":  true,
-		"textEntryInteraction":     true,
-		"extendedTextInteraction":  true,
-		"hotspotInteraction":       true,
-		"selectPointInteraction":   true,
-		"graphicOrderInteraction":  true,
-		"graphicAssociateInteraction": true,
-		"positionObjectInteraction": true,
-		"sliderInteraction":        true,
-		"drawingInteraction":       true,
-		"uploadInteraction":        true,
-		"customInteraction":        true,
-	}
-	return validTypes[interactionType]
 }
-
-func (r *AnalysisReport) HasErrors() bool {
-	for _, err := range r.Errors {
-		if err.Fatal {
-			return true
-		}
-	}
-	return false
-}
\ No newline at end of file
@@ -1,7 +1,10 @@
 package preprocessor
 
 import (
+	"strings"
 	"testing"
+
+	"github.com/qti-migrator/pkg/registry"
 )
 
 func TestPreprocessor_New(t *testing.T) {
@@ -135,6 +138,45 @@ func TestPreprocessor_Analyze_QTI21to30(t *testing.T) {
 	}
 }
 
+func TestPreprocessor_Analyze_QTI21to30_SharedClasses(t *testing.T) {
+	qti21XML := `<questestinterop version="2.1">
+	<item ident="q001" title="Styled">
+		<itemBody>
+			<choiceInteraction responseIdentifier="R1" class="list-style-upper-alpha" orientation="horizontal">
+				<simpleChoice identifier="A">Yes</simpleChoice>
+			</choiceInteraction>
+			<p>Name: <textEntryInteraction responseIdentifier="R2" expectedLength="8"/> <span class="note">and the class= syntax</span></p>
+		</itemBody>
+	</item>
+</questestinterop>`
+
+	report, err := New(1).Analyze([]byte(qti21XML), "2.1", "3.0")
+	if err != nil {
+		t.Fatalf("Analysis failed: %v", err)
+	}
+
+	expected := map[string]string{
+		`class="list-style-upper-alpha"`: `class="qti-labels-upper-alpha"`,
+		`orientation="horizontal"`:       `class="qti-orientation-horizontal"`,
+		`expectedLength="8"`:             `class="qti-input-width-10"`,
+	}
+	found := 0
+	for _, detail := range report.MigrationDetails {
+		if newValue, ok := expected[detail.OldValue]; ok {
+			found++
+			if detail.NewValue != newValue {
+				t.Errorf("Expected %s to become %s, got %s", detail.OldValue, newValue, detail.NewValue)
+			}
+		}
+		if strings.Contains(detail.OldValue, "note") {
+			t.Errorf("Expected unknown class to be left out of the report, got %+v", detail)
+		}
+	}
+	if found != len(expected) {
+		t.Errorf("Expected %d class details, got %d: %+v", len(expected), found, report.MigrationDetails)
+	}
+}
+
 func TestPreprocessor_Analyze_UnsupportedMigrationPath(t *testing.T) {
 	qti12XML := `<?xml version="1.0" encoding="UTF-8"?>
 <questestinterop version="1.2">
@@ -143,13 +185,13 @@ func TestPreprocessor_Analyze_UnsupportedMigrationPath(t *testing.T) {
 </questestinterop>`
 
 	p := New(1)
-	_, err := p.Analyze([]byte(qti12XML), "1.2", "3.0")
+	_, err := p.Analyze([]byte(qti12XML), "1.2", "1.2")
 	
 	if err == nil {
 		t.Error("Expected error for unsupported migration path, but got none")
 	}
 	
-	if err.Error() != "unsupported migration path: 1.2 to 3.0" {
+	if err.Error() != "unsupported migration path: 1.2 to 1.2" {
 		t.Errorf("Expected unsupported migration path error, got: %v", err)
 	}
 }
@@ -340,41 +382,6 @@ func TestAnalysisReport_HasErrors(t *testing.T) {
 	}
 }
 
-func TestIsValidQTI21InteractionType(t *testing.T) {
-	validTypes := []string{
-		"choiceInteraction",
-		"orderInteraction",
-		"associateInteraction",
-		"matchInteraction",
-		"textEntryInteraction",
-		"extendedTextInteraction",
-		"hotspotInteraction",
-		"sliderInteraction",
-		"uploadInteraction",
-		"customInteraction",
-	}
-	
-	invalidTypes := []string{
-		"multiple_choice",
-		"fill_in_blank",
-		"invalid_type",
-		"",
-		"CHOICEINTERACTION",  // Case sensitive
-	}
-	
-	for _, validType := range validTypes {
-		if !isValidQTI21InteractionType(validType) {
-			t.Errorf("Expected '%s' to be a valid QTI 2.1 interaction type", validType)
-		}
-	}
-	
-	for _, invalidType := range invalidTypes {
-		if isValidQTI21InteractionType(invalidType) {
-			t.Errorf("Expected '%s' to be an invalid QTI 2.1 interaction type", invalidType)
-		}
-	}
-}
-
 func BenchmarkPreprocessor_Analyze(b *testing.B) {
 	qti12XML := `<?xml version="1.0" encoding="UTF-8"?>
 <questestinterop version="1.2">
@@ -407,4 +414,425 @@ func BenchmarkPreprocessor_Analyze(b *testing.B) {
 			b.Fatalf("Analysis failed: %v", err)
 		}
 	}
-}
\ No newline at end of file
+}
+func TestPreprocessor_Analyze_QTI12to21_ResponseTypes(t *testing.T) {
+	qti12XML := `<?xml version="1.0" encoding="UTF-8"?>
+<questestinterop version="1.2">
+	<item ident="q001" title="Numeric">
+		<presentation>
+			<response_num ident="NUM" numtype="Integer">
+				<render_fib/>
+			</response_num>
+			<response_str ident="STR"/>
+		</presentation>
+	</item>
+</questestinterop>`
+
+	report, err := New(2).Analyze([]byte(qti12XML), "1.2", "2.1")
+	if err != nil {
+		t.Fatalf("Analysis failed: %v", err)
+	}
+
+	found := false
+	for _, detail := range report.MigrationDetails {
+		if detail.OldValue == "response_num" && detail.NewValue == "textEntryInteraction" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected migration detail for response_num conversion")
+	}
+
+	found = false
+	for _, warning := range report.Warnings {
+		if strings.Contains(warning.ElementPath, "response_str[@ident='STR']") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected warning for response_str without rendering")
+	}
+}
+
+func TestPreprocessor_Analyze_QTI12to21_RenderExtension(t *testing.T) {
+	qti12XML := `<questestinterop>
+	<item ident="q001" title="Extension">
+		<presentation>
+			<response_lid ident="EXT">
+				<render_extension><widget/></render_extension>
+			</response_lid>
+			<response_num ident="TEMP">
+				<render_slider lowerbound="0" upperbound="10"/>
+			</response_num>
+		</presentation>
+	</item>
+</questestinterop>`
+
+	report, err := New(2).Analyze([]byte(qti12XML), "1.2", "2.1")
+	if err != nil {
+		t.Fatalf("Analysis failed: %v", err)
+	}
+
+	found := false
+	for _, warning := range report.Warnings {
+		if strings.HasSuffix(warning.ElementPath, "response_lid[@ident='EXT']/render_extension") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected warning for render_extension")
+	}
+
+	found = false
+	for _, detail := range report.MigrationDetails {
+		if detail.NewValue == "sliderInteraction" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected migration detail for render_slider conversion")
+	}
+}
+
+func TestPreprocessor_Analyze_QTI12to21_DisplayFeedback(t *testing.T) {
+	qti12XML := `<questestinterop>
+	<item ident="q001" title="Feedback">
+		<resprocessing>
+			<respcondition>
+				<conditionvar><varequal respident="RESPONSE">A</varequal></conditionvar>
+				<displayfeedback linkrefid="correct"/>
+				<displayfeedback linkrefid="missing"/>
+			</respcondition>
+		</resprocessing>
+		<itemfeedback ident="correct"><material><mattext>Well done.</mattext></material></itemfeedback>
+	</item>
+</questestinterop>`
+
+	report, err := New(1).Analyze([]byte(qti12XML), "1.2", "2.1")
+	if err != nil {
+		t.Fatalf("Analysis failed: %v", err)
+	}
+
+	found := false
+	for _, detail := range report.MigrationDetails {
+		if detail.OldValue == `linkrefid="correct"` && detail.NewValue == "setOutcomeValue FEEDBACK" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected migration detail for displayfeedback")
+	}
+
+	found = false
+	for _, warning := range report.Warnings {
+		if strings.Contains(warning.Message, "unknown itemfeedback 'missing'") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected warning for displayfeedback without itemfeedback")
+	}
+}
+
+func TestPreprocessor_Analyze_QTI12to21_MatchingAndOrdering(t *testing.T) {
+	qti12XML := `<questestinterop>
+	<item ident="q001" title="Matching">
+		<presentation>
+			<response_lid ident="FR">
+				<material><mattext>France</mattext></material>
+				<render_choice><response_label ident="P"><material><mattext>Paris</mattext></material></response_label></render_choice>
+			</response_lid>
+			<response_lid ident="DE">
+				<material><mattext>Germany</mattext></material>
+				<render_choice><response_label ident="P"><material><mattext>Paris</mattext></material></response_label></render_choice>
+			</response_lid>
+		</presentation>
+	</item>
+	<item ident="q002" title="Ordering">
+		<presentation>
+			<response_lid ident="RESPONSE" rcardinality="Ordered">
+				<render_choice><response_label ident="A"/><response_label ident="B"/></render_choice>
+			</response_lid>
+		</presentation>
+	</item>
+</questestinterop>`
+
+	report, err := New(2).Analyze([]byte(qti12XML), "1.2", "2.1")
+	if err != nil {
+		t.Fatalf("Analysis failed: %v", err)
+	}
+
+	matching, ordering := false, false
+	for _, detail := range report.MigrationDetails {
+		if detail.ItemID == "q001" && detail.OldValue == "FR, DE" && detail.NewValue == "matchInteraction" {
+			matching = true
+		}
+		if detail.ItemID == "q001" && detail.NewValue == "choiceInteraction" {
+			t.Error("Expected matching premises not to be reported as choice interactions")
+		}
+		if detail.ItemID == "q002" && detail.NewValue == "orderInteraction" {
+			ordering = true
+		}
+	}
+	if !matching {
+		t.Error("Expected migration detail for the matching layout")
+	}
+	if !ordering {
+		t.Error("Expected migration detail for the ordered response")
+	}
+}
+
+func TestPreprocessor_Analyze_QTI12to21_HTMLFixes(t *testing.T) {
+	qti12XML := `<questestinterop>
+	<item ident="q001" title="Messy HTML">
+		<presentation>
+			<flow>
+				<material><mattext texttype="text/html"><![CDATA[<P>Pick one&nbsp;<font>now</font>]]></mattext></material>
+			</flow>
+			<response_lid ident="RESPONSE">
+				<render_choice>
+					<response_label ident="A"><material><mattext texttype="text/html"><![CDATA[Line<br>break]]></mattext></material></response_label>
+				</render_choice>
+			</response_lid>
+		</presentation>
+	</item>
+</questestinterop>`
+
+	report, err := New(1).Analyze([]byte(qti12XML), "1.2", "2.1")
+	if err != nil {
+		t.Fatalf("Analysis failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"&nbsp;": "presentation/flow[1]/material[1]/mattext[1]",
+		"<font>": "presentation/flow[1]/material[1]/mattext[1]",
+		"<p>":    "presentation/flow[1]/material[1]/mattext[1]",
+		"<br>":   "presentation/response_lid[@ident='RESPONSE']/render_choice/response_label[@ident='A']/material/mattext[1]",
+	}
+	for original, path := range expected {
+		found := false
+		for _, detail := range report.MigrationDetails {
+			if detail.OldValue == original && detail.ElementPath == path {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected migration detail for %s at %s, got %+v", original, path, report.MigrationDetails)
+		}
+	}
+
+	for _, detail := range report.MigrationDetails {
+		if detail.OldValue == "<font>" && detail.Action != "remove" {
+			t.Errorf("Expected removed tag to be reported as remove, got %s", detail.Action)
+		}
+	}
+}
+
+func TestPreprocessor_Analyze_QTI12to21_TestControls(t *testing.T) {
+	qti12XML := `<questestinterop>
+	<assessment ident="quiz" maxattempts="2">
+		<section ident="pool">
+			<duration>PT20M</duration>
+			<selection_ordering>
+				<selection><selection_number>1</selection_number></selection>
+				<selection><sourcebank_ref>bank1</sourcebank_ref></selection>
+			</selection_ordering>
+			<section ident="inner"><item ident="q001"><duration>P1M</duration></item></section>
+		</section>
+	</assessment>
+</questestinterop>`
+
+	report, err := New(1).Analyze([]byte(qti12XML), "1.2", "2.1")
+	if err != nil {
+		t.Fatalf("Analysis failed: %v", err)
+	}
+
+	expected := map[string]string{
+		`maxattempts="2"`:                        `itemSessionControl maxAttempts="2"`,
+		"<duration>PT20M</duration>":             `timeLimits maxTime="1200"`,
+		"<selection_number>1</selection_number>": `selection select="1"`,
+	}
+	found := 0
+	for _, detail := range report.MigrationDetails {
+		if newValue, ok := expected[detail.OldValue]; ok {
+			found++
+			if detail.NewValue != newValue {
+				t.Errorf("Expected %s to become %s, got %s", detail.OldValue, newValue, detail.NewValue)
+			}
+		}
+	}
+	if found != len(expected) {
+		t.Errorf("Expected %d control details, got %d: %+v", len(expected), found, report.MigrationDetails)
+	}
+
+	var paths []string
+	for _, warning := range report.Warnings {
+		paths = append(paths, warning.ElementPath)
+	}
+	if strings.Join(paths, " ") != "section[@ident='pool'] item[@ident='q001']" {
+		t.Errorf("Expected warnings for the source bank selection and the month duration, got %+v", report.Warnings)
+	}
+}
+
+func TestPreprocessor_Analyze_QTI12to30(t *testing.T) {
+	qti12XML := `<questestinterop>
+	<assessment ident="quiz">
+		<section ident="s1">
+			<item ident="q001" maxattempts="2"><presentation><material><mattext>One</mattext></material></presentation></item>
+		</section>
+	</assessment>
+</questestinterop>`
+
+	report, err := New(1).Analyze([]byte(qti12XML), "1.2", "3.0")
+	if err != nil {
+		t.Fatalf("Analysis failed: %v", err)
+	}
+	if report.SourceVersion != "1.2" || report.TargetVersion != "3.0" || strings.Join(report.Path, " ") != "1.2 2.1 3.0" {
+		t.Errorf("Expected the path 1.2 2.1 3.0, got %s to %s via %v", report.SourceVersion, report.TargetVersion, report.Path)
+	}
+
+	// Both steps contribute, each labelled with its step
+	steps := map[string]bool{}
+	for _, detail := range report.MigrationDetails {
+		steps[detail.Step] = true
+		if detail.Step == "2.1 → 3.0" && detail.NewValue == "qti-assessment-item" && detail.ItemID != "q001" {
+			t.Errorf("Expected the 2.1 item q001 to be analyzed, got %+v", detail)
+		}
+	}
+	if len(steps) != 2 || !steps["1.2 → 2.1"] || !steps["2.1 → 3.0"] {
+		t.Errorf("Expected details from both steps, got %v", steps)
+	}
+
+	report, err = New(1).Analyze([]byte(qti12XML), "1.2", "2.1")
+	if err != nil {
+		t.Fatalf("Analysis failed: %v", err)
+	}
+	for _, detail := range report.MigrationDetails {
+		if detail.Step != "" {
+			t.Errorf("Expected no step labels on a single-step analysis, got %+v", detail)
+		}
+	}
+}
+
+func TestPreprocessor_Analyze_Consistency(t *testing.T) {
+	qti21XML := `<questestinterop version="2.1">
+	<item ident="q001" title="Test Question">
+		<responseDeclaration identifier="RESPONSE" cardinality="single" baseType="identifier">
+			<correctResponse><value>C</value></correctResponse>
+		</responseDeclaration>
+		<itemBody>
+			<choiceInteraction responseIdentifier="RESPONSE" maxChoices="1">
+				<simpleChoice identifier="A">3</simpleChoice>
+				<simpleChoice identifier="B">4</simpleChoice>
+			</choiceInteraction>
+		</itemBody>
+	</item>
+</questestinterop>`
+
+	report, err := New(1).Analyze([]byte(qti21XML), "2.1", "3.0")
+	if err != nil {
+		t.Fatalf("Analysis failed: %v", err)
+	}
+	if report.HasErrors() {
+		t.Error("Expected consistency errors not to block the migration")
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Rule != "unknown-correct-value" || report.Errors[0].Step != "2.1" {
+		t.Errorf("Expected the unknown correct value to be reported for the 2.1 source, got %+v", report.Errors)
+	}
+}
+
+func TestPreprocessor_Analyze_StepOptions(t *testing.T) {
+	qti12XML := `<questestinterop>
+	<item ident="q 1" title="Test Question">
+		<presentation><material><mattext>Text</mattext></material></presentation>
+	</item>
+</questestinterop>`
+
+	p := New(1)
+	p.Options.Identifiers = registry.IdentifiersSanitize
+	report, err := p.Analyze([]byte(qti12XML), "1.2", "3.0")
+	if err != nil {
+		t.Fatalf("Analysis failed: %v", err)
+	}
+
+	// The 2.1 → 3.0 step sees the identifiers the first step sanitized
+	found := false
+	for _, detail := range report.MigrationDetails {
+		if detail.Step == "2.1 → 3.0" {
+			found = true
+			if detail.ItemID != "q_1" {
+				t.Errorf("Expected findings of the second step on item q_1, got %+v", detail)
+			}
+		}
+	}
+	if !found {
+		t.Error("Expected migration details of the 2.1 → 3.0 step")
+	}
+}
+
+func TestMerge_IncompatibleItems(t *testing.T) {
+	plan, err := registry.Default.Plan("1.2", "3.0")
+	if err != nil {
+		t.Fatalf("Failed to plan migration: %v", err)
+	}
+
+	report := &AnalysisReport{}
+	incompatible := map[string]bool{}
+	merge(report, &AnalysisReport{Errors: []Error{{ItemID: "q1"}}}, plan, plan[0], incompatible)
+	merge(report, &AnalysisReport{Errors: []Error{{ItemID: "q1"}, {ItemID: "q2"}, {ItemID: "q2"}}}, plan, plan[1], incompatible)
+	if len(incompatible) != 2 || !incompatible["q1"] || !incompatible["q2"] {
+		t.Errorf("Expected q1 and q2 to be incompatible once each, got %v", incompatible)
+	}
+	if len(report.Errors) != 4 {
+		t.Errorf("Expected the errors of both steps, got %+v", report.Errors)
+	}
+}
+
+func TestPreprocessor_Analyze_SectionItems(t *testing.T) {
+	qti12XML := `<questestinterop>
+	<assessment ident="a1" title="Bank">
+		<section ident="s1">
+			<item ident="q1" title="One">
+				<presentation><material><mattext>One</mattext></material></presentation>
+			</item>
+			<section ident="s2">
+				<item ident="q2" title="Two">
+					<presentation><material><mattext>Two</mattext></material></presentation>
+				</item>
+			</section>
+		</section>
+	</assessment>
+</questestinterop>`
+
+	report, err := New(1).Analyze([]byte(qti12XML), "1.2", "3.0")
+	if err != nil {
+		t.Fatalf("Analysis failed: %v", err)
+	}
+	if report.TotalItems != 2 {
+		t.Errorf("Expected the 2 items of the sections, got %d", report.TotalItems)
+	}
+	if report.CompatibleItems+report.IncompatibleItems != report.TotalItems {
+		t.Errorf("Expected every item to be compatible or not, got %d and %d of %d",
+			report.CompatibleItems, report.IncompatibleItems, report.TotalItems)
+	}
+}
+
+func TestPreprocessor_Analyze_SourceVersionLabel(t *testing.T) {
+	qti22XML := `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p2" identifier="q1" title="Q1" adaptive="false" timeDependent="false">
+	<itemBody><p><textEntryInteraction responseIdentifier="NAME"/></p></itemBody>
+</assessmentItem>`
+
+	p := New(1)
+	p.SourceVersion = "2.2"
+	report, err := p.Analyze([]byte(qti22XML), "2.1", "3.0")
+	if err != nil {
+		t.Fatalf("Analysis failed: %v", err)
+	}
+
+	if len(report.Errors) != 1 || report.Errors[0].Rule != "undeclared-response" {
+		t.Fatalf("Expected the undeclared response to be found, got %+v", report.Errors)
+	}
+	if report.Errors[0].Step != "2.2" {
+		t.Errorf("Expected the finding to be labelled with the source version 2.2, got %q", report.Errors[0].Step)
+	}
+}
@@ -23,8 +23,20 @@ func (r *Reporter) Generate(report *preprocessor.AnalysisReport) string {
 	builder.WriteString(r.generateHeader(report))
 	builder.WriteString(r.generateSummary(report))
 
-	if len(report.Errors) > 0 {
-		builder.WriteString(r.generateErrors(report))
+	// Only fatal errors block the migration; the others are listed apart
+	var blockers, errors []preprocessor.Error
+	for _, err := range report.Errors {
+		if err.Fatal {
+			blockers = append(blockers, err)
+		} else {
+			errors = append(errors, err)
+		}
+	}
+	if len(blockers) > 0 {
+		builder.WriteString(r.generateErrors("ERRORS (Migration Blockers)", blockers))
+	}
+	if len(errors) > 0 {
+		builder.WriteString(r.generateErrors("ERRORS (Not Blocking Migration)", errors))
 	}
 
 	if len(report.Warnings) > 0 && r.verbosity >= 1 {
@@ -45,10 +57,29 @@ func (r *Reporter) generateHeader(report *preprocessor.AnalysisReport) string {
 ================================================================================
                           QTI Migration Analysis Report
 ================================================================================
-Migration Path: QTI %s → QTI %s
+Migration Path: %s
 ================================================================================
 
-`, report.SourceVersion, report.TargetVersion)
+`, migrationPath(report))
+}
+
+// migrationPath lists the versions a migration passes through, e.g.
+// "QTI 1.2 → QTI 2.1 → QTI 3.0".
+func migrationPath(report *preprocessor.AnalysisReport) string {
+	versions := report.Path
+	if len(versions) < 2 {
+		versions = []string{report.SourceVersion, report.TargetVersion}
+	}
+	return "QTI " + strings.Join(versions, " → QTI ")
+}
+
+// stepLabel marks a finding of a migration with several steps with the step
+// it belongs to.
+func stepLabel(step string) string {
+	if step == "" {
+		return ""
+	}
+	return fmt.Sprintf("[QTI %s] ", step)
 }
 
 func (r *Reporter) generateSummary(report *preprocessor.AnalysisReport) string {
@@ -72,14 +103,15 @@ Warnings: %d
 	return summary
 }
 
-func (r *Reporter) generateErrors(report *preprocessor.AnalysisReport) string {
+func (r *Reporter) generateErrors(title string, errors []preprocessor.Error) string {
 	var builder strings.Builder
 
-	builder.WriteString("ERRORS (Migration Blockers)\n")
-	builder.WriteString("--------------------------\n")
+	builder.WriteString(title + "\n")
+	builder.WriteString(strings.Repeat("-", len(title)) + "\n")
 
-	for i, err := range report.Errors {
+	for i, err := range errors {
 		builder.WriteString(fmt.Sprintf("%d. ", i+1))
+		builder.WriteString(stepLabel(err.Step))
 		if err.ItemID != "" {
 			builder.WriteString(fmt.Sprintf("[Item: %s] ", err.ItemID))
 		}
@@ -88,6 +120,9 @@ func (r *Reporter) generateErrors(report *preprocessor.AnalysisReport) string {
 		if r.verbosity >= 2 && err.ElementPath != "" {
 			builder.WriteString(fmt.Sprintf("   Path: %s\n", err.ElementPath))
 		}
+		if err.Rule != "" {
+			builder.WriteString(fmt.Sprintf("   Rule: %s\n", err.Rule))
+		}
 		
 		if err.Fatal {
 			builder.WriteString("   ⚠️  This error must be resolved before migration can proceed.\n")
@@ -106,6 +141,7 @@ func (r *Reporter) generateWarnings(report *preprocessor.AnalysisReport) string
 
 	for i, warning := range report.Warnings {
 		builder.WriteString(fmt.Sprintf("%d. ", i+1))
+		builder.WriteString(stepLabel(warning.Step))
 		if warning.ItemID != "" {
 			builder.WriteString(fmt.Sprintf("[Item: %s] ", warning.ItemID))
 		}
@@ -114,6 +150,9 @@ func (r *Reporter) generateWarnings(report *preprocessor.AnalysisReport) string
 		if r.verbosity >= 2 && warning.ElementPath != "" {
 			builder.WriteString(fmt.Sprintf("   Path: %s\n", warning.ElementPath))
 		}
+		if warning.Rule != "" {
+			builder.WriteString(fmt.Sprintf("   Rule: %s\n", warning.Rule))
+		}
 		
 		if warning.Suggestion != "" {
 			builder.WriteString(fmt.Sprintf("   → %s\n", warning.Suggestion))
@@ -138,6 +177,7 @@ func (r *Reporter) generateMigrationDetails(report *preprocessor.AnalysisReport)
 
 		for i, detail := range details {
 			builder.WriteString(fmt.Sprintf("%d. ", i+1))
+			builder.WriteString(stepLabel(detail.Step))
 			if detail.ItemID != "" {
 				builder.WriteString(fmt.Sprintf("[Item: %s] ", detail.ItemID))
 			}
@@ -184,6 +224,8 @@ func (r *Reporter) generateFooter(report *preprocessor.AnalysisReport) string {
 	
 	if report.HasErrors() {
 		builder.WriteString("⚠️  MIGRATION BLOCKED: Please resolve the errors listed above before proceeding.\n")
+	} else if len(report.Errors) > 0 {
+		builder.WriteString(fmt.Sprintf("✓ Migration can proceed. Please review the %d non-blocking error(s) listed above.\n", len(report.Errors)))
 	} else if len(report.Warnings) > 0 {
 		builder.WriteString("✓ Migration can proceed. Please review warnings for potential issues.\n")
 	} else {
@@ -139,9 +139,14 @@ func TestReporter_Generate_ReadyReport(t *testing.T) {
 		t.Error("Expected READY status")
 	}
 	
-	// Should show success message in footer
-	if !strings.Contains(output, "Migration can proceed. Please review warnings") {
-		t.Error("Expected success message with warnings")
+	// Non-fatal errors are listed apart from the blockers
+	if strings.Contains(output, "Migration Blockers") || !strings.Contains(output, "ERRORS (Not Blocking Migration)\n-------------------------------\n1. [Item: q002] Non-fatal error") {
+		t.Errorf("Expected the non-fatal error outside the blockers, got:\n%s", output)
+	}
+
+	// Should show success message in footer that points at the error
+	if !strings.Contains(output, "Migration can proceed. Please review the 1 non-blocking error(s)") {
+		t.Error("Expected success message with the non-fatal error")
 	}
 }
 
@@ -392,6 +397,47 @@ func TestReporter_Generate_EmptyElementPaths(t *testing.T) {
 	}
 }
 
+func TestReporter_Generate_MultiStepReport(t *testing.T) {
+	report := &preprocessor.AnalysisReport{
+		SourceVersion: "1.2",
+		TargetVersion: "3.0",
+		Path:          []string{"1.2", "2.1", "3.0"},
+		Warnings: []preprocessor.Warning{
+			{ItemID: "q001", Message: "Test warning", Step: "1.2 → 2.1"},
+		},
+	}
+
+	output := New(1).Generate(report)
+
+	if !strings.Contains(output, "Migration Path: QTI 1.2 → QTI 2.1 → QTI 3.0") {
+		t.Error("Expected every version of the path in the header")
+	}
+	if !strings.Contains(output, "1. [QTI 1.2 → 2.1] [Item: q001] Test warning") {
+		t.Errorf("Expected the warning to name its step, got:\n%s", output)
+	}
+}
+
+func TestReporter_Generate_Rules(t *testing.T) {
+	report := &preprocessor.AnalysisReport{
+		SourceVersion: "2.1",
+		TargetVersion: "3.0",
+		Warnings: []preprocessor.Warning{
+			{ItemID: "q001", Message: "Test warning", Rule: "unknown-mapped-value"},
+		},
+		Errors: []preprocessor.Error{
+			{ItemID: "q001", Message: "Test error", Rule: "duplicate-identifier"},
+		},
+	}
+
+	output := New(1).Generate(report)
+
+	for _, expected := range []string{"   Rule: unknown-mapped-value\n", "   Rule: duplicate-identifier\n"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in the report, got:\n%s", expected, output)
+		}
+	}
+}
+
 func BenchmarkReporter_Generate_SmallReport(b *testing.B) {
 	report := &preprocessor.AnalysisReport{
 		SourceVersion:     "1.2",
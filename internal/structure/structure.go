@@ -0,0 +1,109 @@
+// Package structure checks QTI documents against the structure rules of
+// their version. The rules are XSD documents written by hand from the QTI
+// specifications and embedded in the binary. They are not the official IMS
+// Global schemas, so a document that passes them may still be invalid, and
+// the checks are no substitute for validation against the official files.
+// The checker is a pure-Go implementation of the part of XSD the rules use:
+// global and local element and attribute declarations, named and anonymous
+// types, sequence, choice and all groups with occurrence bounds, named
+// groups and attribute groups, extension and restriction of complex and
+// simple content, wildcards, substitution groups, and simple types
+// restricted by enumerations, patterns, lengths and bounds, lists and unions.
+package structure
+
+import (
+	"embed"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:embed xsd/*.xsd
+var ruleFiles embed.FS
+
+// Structure rules of each QTI version, in xsd/
+var ruleNames = map[string]string{
+	"1.2": "qti12.xsd",
+	"2.1": "qti21.xsd",
+	"2.2": "qti22.xsd",
+	"3.0": "qti30.xsd",
+}
+
+var (
+	mu       sync.Mutex
+	compiled = map[string]*Schema{}
+)
+
+// Violation is a place where a document does not follow the structure rules
+// of its version.
+type Violation struct {
+	// File of a content package the violation is in; empty for a single
+	// document
+	File string
+	// Path of the element, e.g. /assessmentItem/itemBody/p[2]
+	Path    string
+	Line    int
+	Message string
+}
+
+// String returns the violation as "file: path (line 3): message".
+func (v Violation) String() string {
+	var builder strings.Builder
+	if v.File != "" {
+		builder.WriteString(v.File + ": ")
+	}
+	builder.WriteString(v.Path)
+	if v.Line > 0 {
+		builder.WriteString(" (line " + strconv.Itoa(v.Line) + ")")
+	}
+	builder.WriteString(": " + v.Message)
+	return builder.String()
+}
+
+// Versions returns the QTI versions there are structure rules for, sorted.
+func Versions() []string {
+	var versions []string
+	for version := range ruleNames {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// For returns the structure rules of a QTI version, which may carry a patch
+// level such as 1.2.1. Rules are compiled on first use.
+func For(version string) (*Schema, error) {
+	key := ""
+	for known := range ruleNames {
+		if version == known || strings.HasPrefix(version, known+".") {
+			key = known
+		}
+	}
+	if key == "" {
+		return nil, fmt.Errorf("no structure rules for QTI %s, expected one of %s", version, strings.Join(Versions(), ", "))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if rules, ok := compiled[key]; ok {
+		return rules, nil
+	}
+	rules, err := compile(ruleFiles, "xsd/"+ruleNames[key])
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile the QTI %s structure rules: %w", key, err)
+	}
+	rules.version = key
+	compiled[key] = rules
+	return rules, nil
+}
+
+// Check checks content against the structure rules of a QTI version.
+func Check(content []byte, version string) ([]Violation, error) {
+	rules, err := For(version)
+	if err != nil {
+		return nil, err
+	}
+	return rules.Validate(content)
+}
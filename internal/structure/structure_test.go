@@ -0,0 +1,183 @@
+package structure
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFor(t *testing.T) {
+	for _, version := range Versions() {
+		rules, err := For(version)
+		if err != nil {
+			t.Fatalf("For(%s) error = %v", version, err)
+		}
+		if rules.Namespace() == "" {
+			t.Errorf("For(%s) has no target namespace", version)
+		}
+	}
+
+	patched, err := For("1.2.1")
+	if err != nil {
+		t.Fatalf("For(1.2.1) error = %v", err)
+	}
+	base, _ := For("1.2")
+	if patched != base {
+		t.Error("For(1.2.1) should return the QTI 1.2 structure rules")
+	}
+
+	if _, err := For("2.0"); err == nil || !strings.Contains(err.Error(), "no structure rules for QTI 2.0") {
+		t.Errorf("For(2.0) error = %v", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  string
+		content  string
+		expected []string
+	}{
+		{
+			name:    "QTI 1.2 item",
+			version: "1.2",
+			content: `<questestinterop>
+  <item ident="q1" title="Capital">
+    <presentation>
+      <material><mattext>Capital of France?</mattext></material>
+      <response_lid ident="RESPONSE" rcardinality="Single">
+        <render_choice shuffle="No">
+          <response_label ident="A"><material><mattext>Paris</mattext></material></response_label>
+        </render_choice>
+      </response_lid>
+    </presentation>
+    <resprocessing>
+      <outcomes><decvar varname="SCORE" vartype="Integer"/></outcomes>
+      <respcondition><conditionvar><varequal respident="RESPONSE">A</varequal></conditionvar><setvar action="Set">1</setvar></respcondition>
+    </resprocessing>
+  </item>
+</questestinterop>`,
+		},
+		{
+			name:    "QTI 1.2 item without ident",
+			version: "1.2",
+			content: `<questestinterop>
+  <item><presentation><response_lid ident="R" rcardinality="Many"><render_choice/></response_lid></presentation></item>
+</questestinterop>`,
+			expected: []string{
+				"/questestinterop/item (line 2): attribute ident is required on <item>",
+				"/questestinterop/item/presentation/response_lid (line 2): invalid value of attribute rcardinality on <response_lid>",
+			},
+		},
+		{
+			name:    "QTI 2.1 item",
+			version: "2.1",
+			content: `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="q1" title="Capital" adaptive="false" timeDependent="false">
+  <responseDeclaration identifier="RESPONSE" cardinality="single" baseType="identifier">
+    <correctResponse><value>A</value></correctResponse>
+  </responseDeclaration>
+  <outcomeDeclaration identifier="SCORE" cardinality="single" baseType="float"/>
+  <itemBody>
+    <choiceInteraction responseIdentifier="RESPONSE" shuffle="false" maxChoices="1">
+      <simpleChoice identifier="A">Paris</simpleChoice>
+      <simpleChoice identifier="B">Lyon</simpleChoice>
+    </choiceInteraction>
+  </itemBody>
+  <responseProcessing template="http://www.imsglobal.org/question/qti_v2p1/rptemplates/match_correct"/>
+</assessmentItem>`,
+		},
+		{
+			name:    "QTI 2.1 item without shuffle",
+			version: "2.1",
+			content: `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="q1" title="Capital" adaptive="false" timeDependent="false">
+  <itemBody>
+    <choiceInteraction responseIdentifier="RESPONSE" maxChoices="1">
+      <simpleChoice identifier="A">Paris</simpleChoice>
+    </choiceInteraction>
+  </itemBody>
+</assessmentItem>`,
+			expected: []string{
+				"/assessmentItem/itemBody/choiceInteraction (line 3): attribute shuffle is required on <choiceInteraction>",
+			},
+		},
+		{
+			name:    "QTI 2.2 item without shuffle",
+			version: "2.2",
+			content: `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p2" identifier="q1" title="Capital" timeDependent="false">
+  <itemBody>
+    <choiceInteraction responseIdentifier="RESPONSE">
+      <simpleChoice identifier="A">Paris</simpleChoice>
+    </choiceInteraction>
+  </itemBody>
+</assessmentItem>`,
+		},
+		{
+			name:    "QTI 3.0 item",
+			version: "3.0",
+			content: `<qti-assessment-item xmlns="http://www.imsglobal.org/xsd/imsqtiasi_v3p0" identifier="q1" title="Capital" time-dependent="false">
+  <qti-response-declaration identifier="RESPONSE" cardinality="single" base-type="identifier">
+    <qti-correct-response><qti-value>A</qti-value></qti-correct-response>
+  </qti-response-declaration>
+  <qti-item-body>
+    <p>Capital of France?</p>
+    <qti-choice-interaction response-identifier="RESPONSE" max-choices="1">
+      <qti-simple-choice identifier="A">Paris</qti-simple-choice>
+      <qti-simple-choice identifier="B">Lyon</qti-simple-choice>
+    </qti-choice-interaction>
+  </qti-item-body>
+</qti-assessment-item>`,
+		},
+		{
+			name:    "QTI 3.0 item with a 2.1 element",
+			version: "3.0",
+			content: `<qti-assessment-item xmlns="http://www.imsglobal.org/xsd/imsqtiasi_v3p0" identifier="q1" title="Capital" time-dependent="false">
+  <qti-item-body>
+    <p><img src="a.png"/></p>
+    <choiceInteraction/>
+  </qti-item-body>
+</qti-assessment-item>`,
+			expected: []string{
+				"/qti-assessment-item/qti-item-body/p/img (line 3): attribute alt is required on <img>",
+				"/qti-assessment-item/qti-item-body/choiceInteraction (line 4): element <choiceInteraction> is not expected in <qti-item-body>",
+			},
+		},
+		{
+			name:    "QTI 1.2 root labelled 3.0",
+			version: "3.0",
+			content: `<questestinterop version="3.0"><item ident="q1"/></questestinterop>`,
+			expected: []string{
+				"/questestinterop (line 1): element <questestinterop> is not declared in the QTI 3.0 structure rules",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations, err := Check([]byte(tt.content), tt.version)
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if len(violations) != len(tt.expected) {
+				t.Fatalf("Check() = %v, expected %d violations", violations, len(tt.expected))
+			}
+			for i, expected := range tt.expected {
+				if got := violations[i].String(); !strings.HasPrefix(got, expected) {
+					t.Errorf("violation %d = %q, expected it to start with %q", i, got, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestCheck_NotWellFormed(t *testing.T) {
+	if _, err := Check([]byte("<item ident='q1'>"), "1.2"); err == nil {
+		t.Error("Check() of a truncated document should fail")
+	}
+}
+
+func TestViolation_String(t *testing.T) {
+	v := Violation{File: "items/q1.xml", Path: "/assessmentItem", Line: 3, Message: "attribute title is required on <assessmentItem>"}
+	expected := "items/q1.xml: /assessmentItem (line 3): attribute title is required on <assessmentItem>"
+	if got := v.String(); got != expected {
+		t.Errorf("String() = %q, expected %q", got, expected)
+	}
+}
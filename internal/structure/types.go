@@ -0,0 +1,466 @@
+package structure
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// simpleType is a built-in type, or a restriction, list or union of other
+// simple types.
+type simpleType struct {
+	// Name of a built-in type; empty for derived types
+	builtin string
+	base    *simpleType
+	// Item type of a list
+	list *simpleType
+	// Member types of a union
+	union []*simpleType
+
+	enumeration []string
+	patterns    []pattern
+	length      *int
+	minLength   *int
+	maxLength   *int
+	// Numeric bounds, inclusive unless the exclusive flag is set
+	minimum, maximum                   *big.Rat
+	minimumExclusive, maximumExclusive bool
+}
+
+// pattern is a pattern facet, with the XSD expression it was compiled from.
+type pattern struct {
+	source string
+	regexp *regexp.Regexp
+}
+
+var builtinTypes = map[string]bool{
+	"anySimpleType": true, "string": true, "normalizedString": true, "token": true,
+	"boolean": true, "decimal": true, "float": true, "double": true,
+	"integer": true, "long": true, "int": true, "short": true, "byte": true,
+	"nonNegativeInteger": true, "positiveInteger": true, "nonPositiveInteger": true, "negativeInteger": true,
+	"unsignedLong": true, "unsignedInt": true, "unsignedShort": true, "unsignedByte": true,
+	"duration": true, "dateTime": true, "date": true, "time": true,
+	"gYear": true, "gYearMonth": true, "gMonth": true, "gMonthDay": true, "gDay": true,
+	"Name": true, "NCName": true, "QName": true, "ID": true, "IDREF": true, "IDREFS": true,
+	"NMTOKEN": true, "NMTOKENS": true, "ENTITY": true, "ENTITIES": true,
+	"language": true, "anyURI": true, "base64Binary": true, "hexBinary": true,
+}
+
+func builtinType(name string) (*simpleType, error) {
+	if !builtinTypes[name] {
+		return nil, fmt.Errorf("unsupported built-in type %s", name)
+	}
+	return &simpleType{builtin: name}, nil
+}
+
+// simpleType compiles an xs:simpleType.
+func (c *compiler) simpleType(node *xsdNode) (*simpleType, error) {
+	if compiled, ok := c.compiled[node]; ok {
+		return compiled.(*simpleType), nil
+	}
+	t := &simpleType{}
+	c.compiled[node] = t
+
+	for _, child := range node.children {
+		var err error
+		switch child.name {
+		case "restriction":
+			if base := child.attr("base"); base != "" {
+				t.base, err = c.simpleTypeRef(child, base)
+			} else {
+				t.base, err = c.inlineSimpleType(child)
+			}
+			if err != nil {
+				return nil, err
+			}
+			return t, c.facets(t, child)
+		case "list":
+			if itemType := child.attr("itemType"); itemType != "" {
+				t.list, err = c.simpleTypeRef(child, itemType)
+			} else {
+				t.list, err = c.inlineSimpleType(child)
+			}
+			return t, err
+		case "union":
+			for _, member := range strings.Fields(child.attr("memberTypes")) {
+				memberType, err := c.simpleTypeRef(child, member)
+				if err != nil {
+					return nil, err
+				}
+				t.union = append(t.union, memberType)
+			}
+			for _, inline := range child.children {
+				if inline.name == "simpleType" {
+					memberType, err := c.simpleType(inline)
+					if err != nil {
+						return nil, err
+					}
+					t.union = append(t.union, memberType)
+				}
+			}
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("simpleType without restriction, list or union")
+}
+
+func (c *compiler) simpleTypeRef(node *xsdNode, value string) (*simpleType, error) {
+	name, err := node.qname(value)
+	if err != nil {
+		return nil, err
+	}
+	typ, err := c.namedType(name)
+	if err != nil {
+		return nil, err
+	}
+	if typ.any {
+		return &simpleType{builtin: "anySimpleType"}, nil
+	}
+	if typ.simple == nil {
+		return nil, fmt.Errorf("%s is not a simple type", name.Local)
+	}
+	return typ.simple, nil
+}
+
+func (c *compiler) inlineSimpleType(node *xsdNode) (*simpleType, error) {
+	for _, child := range node.children {
+		if child.name == "simpleType" {
+			return c.simpleType(child)
+		}
+	}
+	return nil, fmt.Errorf("%s without a type", node.name)
+}
+
+// facets reads the facets of a restriction into t.
+func (c *compiler) facets(t *simpleType, node *xsdNode) error {
+	for _, facet := range node.children {
+		value := facet.attr("value")
+		var err error
+		switch facet.name {
+		case "enumeration":
+			t.enumeration = append(t.enumeration, value)
+		case "pattern":
+			var compiled *regexp.Regexp
+			compiled, err = compilePattern(value)
+			t.patterns = append(t.patterns, pattern{source: value, regexp: compiled})
+		case "length":
+			t.length, err = facetInt(value)
+		case "minLength":
+			t.minLength, err = facetInt(value)
+		case "maxLength":
+			t.maxLength, err = facetInt(value)
+		case "minInclusive", "minExclusive":
+			t.minimum, err = facetNumber(value)
+			t.minimumExclusive = facet.name == "minExclusive"
+		case "maxInclusive", "maxExclusive":
+			t.maximum, err = facetNumber(value)
+			t.maximumExclusive = facet.name == "maxExclusive"
+		}
+		if err != nil {
+			return fmt.Errorf("facet %s: %w", facet.name, err)
+		}
+	}
+	return nil
+}
+
+func facetInt(value string) (*int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func facetNumber(value string) (*big.Rat, error) {
+	n, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return nil, fmt.Errorf("invalid number %q", value)
+	}
+	return n, nil
+}
+
+// compilePattern translates an XSD regular expression, which matches the
+// whole value and has the \i and \c classes for name characters, to Go.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	replacer := strings.NewReplacer(
+		`\i`, `[\p{L}_:]`,
+		`\I`, `[^\p{L}_:]`,
+		`\c`, `[\p{L}\p{N}._:\-\p{Mn}\p{Mc}]`,
+		`\C`, `[^\p{L}\p{N}._:\-\p{Mn}\p{Mc}]`,
+	)
+	return regexp.Compile(`^(?:` + replacer.Replace(pattern) + `)$`)
+}
+
+// check returns why value is not valid for t, or an empty string.
+func (t *simpleType) check(value string) string {
+	if t.builtin != "" {
+		return checkBuiltin(t.builtin, value)
+	}
+
+	switch {
+	case t.list != nil:
+		items := strings.Fields(value)
+		for _, item := range items {
+			if problem := t.list.check(item); problem != "" {
+				return problem
+			}
+		}
+		return t.checkLength(len(items), value)
+	case t.union != nil:
+		for _, member := range t.union {
+			if member.check(value) == "" {
+				return ""
+			}
+		}
+		return fmt.Sprintf("%q is not valid for any member of the union", value)
+	}
+
+	if t.base.collapses() {
+		value = collapse(value)
+	}
+	if problem := t.base.check(value); problem != "" {
+		return problem
+	}
+	if len(t.enumeration) > 0 {
+		found := false
+		for _, allowed := range t.enumeration {
+			if value == allowed {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Sprintf("%q is not one of %s", value, strings.Join(t.enumeration, ", "))
+		}
+	}
+	for _, pattern := range t.patterns {
+		if !pattern.regexp.MatchString(value) {
+			return fmt.Sprintf("%q does not match the pattern %s", value, pattern.source)
+		}
+	}
+	if t.isList() {
+		if problem := t.checkLength(len(strings.Fields(value)), value); problem != "" {
+			return problem
+		}
+	} else if problem := t.checkLength(utf8.RuneCountInString(value), value); problem != "" {
+		return problem
+	}
+	return t.checkBounds(value)
+}
+
+// collapses tells whether whitespace in values of t is collapsed before
+// they are checked, as it is for every type but strings.
+func (t *simpleType) collapses() bool {
+	for ; t != nil; t = t.base {
+		switch t.builtin {
+		case "string", "normalizedString", "anySimpleType":
+			return false
+		case "":
+			if t.list != nil || t.union != nil {
+				return true
+			}
+		default:
+			return true
+		}
+	}
+	return true
+}
+
+func (t *simpleType) isList() bool {
+	for ; t != nil; t = t.base {
+		if t.list != nil || t.builtin == "IDREFS" || t.builtin == "NMTOKENS" || t.builtin == "ENTITIES" {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *simpleType) checkLength(length int, value string) string {
+	switch {
+	case t.length != nil && length != *t.length:
+		return fmt.Sprintf("%q has length %d, expected %d", value, length, *t.length)
+	case t.minLength != nil && length < *t.minLength:
+		return fmt.Sprintf("%q is shorter than %d", value, *t.minLength)
+	case t.maxLength != nil && length > *t.maxLength:
+		return fmt.Sprintf("%q is longer than %d", value, *t.maxLength)
+	}
+	return ""
+}
+
+func (t *simpleType) checkBounds(value string) string {
+	if t.minimum == nil && t.maximum == nil {
+		return ""
+	}
+	n, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return ""
+	}
+	if t.minimum != nil {
+		if cmp := n.Cmp(t.minimum); cmp < 0 || cmp == 0 && t.minimumExclusive {
+			return fmt.Sprintf("%s is below the minimum %s", value, t.minimum.RatString())
+		}
+	}
+	if t.maximum != nil {
+		if cmp := n.Cmp(t.maximum); cmp > 0 || cmp == 0 && t.maximumExclusive {
+			return fmt.Sprintf("%s is above the maximum %s", value, t.maximum.RatString())
+		}
+	}
+	return ""
+}
+
+func collapse(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+var (
+	patternDecimal  = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)$`)
+	patternInteger  = regexp.MustCompile(`^[+-]?\d+$`)
+	patternFloat    = regexp.MustCompile(`^([+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d+)?|-?INF|NaN)$`)
+	patternDuration = regexp.MustCompile(`^-?P(\d+Y)?(\d+M)?(\d+D)?(T(\d+H)?(\d+M)?(\d+(\.\d+)?S)?)?$`)
+	patternTimezone = `(Z|[+-]\d{2}:\d{2})?`
+	patternDateTime = regexp.MustCompile(`^-?\d{4,}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?` + patternTimezone + `$`)
+	patternDate     = regexp.MustCompile(`^-?\d{4,}-\d{2}-\d{2}` + patternTimezone + `$`)
+	patternTime     = regexp.MustCompile(`^\d{2}:\d{2}:\d{2}(\.\d+)?` + patternTimezone + `$`)
+	patternLanguage = regexp.MustCompile(`^[a-zA-Z]{1,8}(-[a-zA-Z0-9]{1,8})*$`)
+	patternBase64   = regexp.MustCompile(`^[A-Za-z0-9+/= ]*$`)
+	patternHex      = regexp.MustCompile(`^([0-9a-fA-F]{2})*$`)
+)
+
+// Bounds of the built-in integer types
+var integerRanges = map[string][2]string{
+	"long":               {"-9223372036854775808", "9223372036854775807"},
+	"int":                {"-2147483648", "2147483647"},
+	"short":              {"-32768", "32767"},
+	"byte":               {"-128", "127"},
+	"nonNegativeInteger": {"0", ""},
+	"positiveInteger":    {"1", ""},
+	"nonPositiveInteger": {"", "0"},
+	"negativeInteger":    {"", "-1"},
+	"unsignedLong":       {"0", "18446744073709551615"},
+	"unsignedInt":        {"0", "4294967295"},
+	"unsignedShort":      {"0", "65535"},
+	"unsignedByte":       {"0", "255"},
+}
+
+func checkBuiltin(name, value string) string {
+	switch name {
+	case "string", "anySimpleType":
+		return ""
+	case "normalizedString":
+		if strings.ContainsAny(value, "\t\n\r") {
+			return fmt.Sprintf("%q holds tabs or line breaks", value)
+		}
+		return ""
+	}
+
+	value = collapse(value)
+	valid := true
+	switch name {
+	case "token", "anyURI", "ENTITY", "ENTITIES":
+	case "boolean":
+		valid = value == "true" || value == "false" || value == "1" || value == "0"
+	case "decimal":
+		valid = patternDecimal.MatchString(value)
+	case "float", "double":
+		valid = patternFloat.MatchString(value)
+	case "duration":
+		valid = patternDuration.MatchString(value) && value != "P" && !strings.HasSuffix(value, "T")
+	case "dateTime":
+		valid = patternDateTime.MatchString(value)
+	case "date":
+		valid = patternDate.MatchString(value)
+	case "time":
+		valid = patternTime.MatchString(value)
+	case "gYear", "gYearMonth", "gMonth", "gMonthDay", "gDay":
+		valid = value != ""
+	case "Name":
+		valid = isName(value, true)
+	case "NCName", "ID", "IDREF":
+		valid = isName(value, false)
+	case "QName":
+		prefix, local, found := strings.Cut(value, ":")
+		valid = isName(prefix, false) && (!found || isName(local, false))
+	case "IDREFS":
+		valid = value != ""
+		for _, item := range strings.Fields(value) {
+			valid = valid && isName(item, false)
+		}
+	case "NMTOKEN":
+		valid = isNameToken(value)
+	case "NMTOKENS":
+		valid = value != ""
+		for _, item := range strings.Fields(value) {
+			valid = valid && isNameToken(item)
+		}
+	case "language":
+		valid = patternLanguage.MatchString(value)
+	case "base64Binary":
+		valid = patternBase64.MatchString(value)
+	case "hexBinary":
+		valid = patternHex.MatchString(value)
+	default:
+		return checkInteger(name, value)
+	}
+	if !valid {
+		return fmt.Sprintf("%q is not a valid %s", value, name)
+	}
+	return ""
+}
+
+func checkInteger(name, value string) string {
+	if !patternInteger.MatchString(value) {
+		return fmt.Sprintf("%q is not a valid %s", value, name)
+	}
+	bounds, ok := integerRanges[name]
+	if !ok {
+		return ""
+	}
+	n, _ := new(big.Int).SetString(strings.TrimPrefix(value, "+"), 10)
+	if bounds[0] != "" {
+		min, _ := new(big.Int).SetString(bounds[0], 10)
+		if n.Cmp(min) < 0 {
+			return fmt.Sprintf("%s is below the minimum %s of %s", value, bounds[0], name)
+		}
+	}
+	if bounds[1] != "" {
+		max, _ := new(big.Int).SetString(bounds[1], 10)
+		if n.Cmp(max) > 0 {
+			return fmt.Sprintf("%s is above the maximum %s of %s", value, bounds[1], name)
+		}
+	}
+	return ""
+}
+
+func isName(value string, colons bool) bool {
+	for i, r := range value {
+		if r == ':' && !colons {
+			return false
+		}
+		if i == 0 && !isNameStart(r) || !isNameRune(r) {
+			return false
+		}
+	}
+	return value != ""
+}
+
+func isNameToken(value string) bool {
+	for _, r := range value {
+		if !isNameRune(r) {
+			return false
+		}
+	}
+	return value != ""
+}
+
+func isNameStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_' || r == ':'
+}
+
+func isNameRune(r rune) bool {
+	return isNameStart(r) || unicode.IsDigit(r) || r == '.' || r == '-' ||
+		unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Mc, r) || r == '·'
+}
@@ -0,0 +1,500 @@
+package structure
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Number of expected elements listed in a violation before it is cut short
+const maxExpected = 8
+
+// node is an element of the document being validated.
+type node struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	children []*node
+	text     strings.Builder
+	path     string
+	line     int
+}
+
+// Validate checks a document against the schema. Documents whose root
+// element is in no namespace are validated as if their elements were in the
+// target namespace, as QTI 1.2 documents and some tools write them.
+// Violations are in document order. The error is only set when content is
+// not well-formed XML.
+func (s *Schema) Validate(content []byte) ([]Violation, error) {
+	root, err := parseDocument(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse document: %w", err)
+	}
+	if root.name.Space == "" {
+		qualify(root, s.namespace)
+	}
+
+	v := &validator{schema: s}
+	decl, ok := s.elements[root.name]
+	switch {
+	case !ok:
+		v.report(root, "element <%s>%s is not declared in %s", root.name.Local, namespaceNote(root.name.Space, s.namespace), s.describe())
+	case decl.abstract:
+		v.report(root, "element <%s> is abstract", root.name.Local)
+	default:
+		v.element(root, decl.typ)
+	}
+	sort.SliceStable(v.violations, func(i, j int) bool {
+		return v.violations[i].Line < v.violations[j].Line
+	})
+	return v.violations, nil
+}
+
+func (s *Schema) describe() string {
+	if s.version == "" {
+		return "the schema"
+	}
+	return "the QTI " + s.version + " structure rules"
+}
+
+func namespaceNote(namespace, target string) string {
+	if namespace == "" || namespace == target {
+		return ""
+	}
+	return " in namespace " + namespace
+}
+
+func parseDocument(content []byte) (*node, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	decoder.Strict = true
+	var stack []*node
+	var root *node
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			line, _ := decoder.InputPos()
+			n := &node{name: t.Name, attrs: t.Attr, line: line}
+			if len(stack) == 0 {
+				if root != nil {
+					return nil, fmt.Errorf("document has more than one root element")
+				}
+				root = n
+			} else {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("document has no root element")
+	}
+	setPaths(root, "/"+root.name.Local)
+	return root, nil
+}
+
+// setPaths names elements by their local names, with the position among
+// siblings of the same name when there are several.
+func setPaths(n *node, path string) {
+	n.path = path
+	counts := map[string]int{}
+	for _, child := range n.children {
+		counts[child.name.Local]++
+	}
+	seen := map[string]int{}
+	for _, child := range n.children {
+		childPath := path + "/" + child.name.Local
+		if counts[child.name.Local] > 1 {
+			seen[child.name.Local]++
+			childPath += "[" + strconv.Itoa(seen[child.name.Local]) + "]"
+		}
+		setPaths(child, childPath)
+	}
+}
+
+func qualify(n *node, namespace string) {
+	if n.name.Space == "" {
+		n.name.Space = namespace
+	}
+	for _, child := range n.children {
+		qualify(child, namespace)
+	}
+}
+
+type validator struct {
+	schema     *Schema
+	violations []Violation
+}
+
+func (v *validator) report(n *node, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{Path: n.path, Line: n.line, Message: fmt.Sprintf(format, args...)})
+}
+
+// element validates n and its descendants against t.
+func (v *validator) element(n *node, t *typeDef) {
+	if t.any {
+		return
+	}
+	v.attributes(n, t)
+
+	if t.simple != nil {
+		if len(n.children) > 0 {
+			v.report(n.children[0], "element <%s> is not allowed in <%s>, which only holds text", n.children[0].name.Local, n.name.Local)
+			return
+		}
+		if problem := t.simple.check(n.text.String()); problem != "" {
+			v.report(n, "invalid content of <%s>: %s", n.name.Local, problem)
+		}
+		return
+	}
+	if !t.mixed && strings.TrimSpace(n.text.String()) != "" {
+		v.report(n, "text is not allowed in <%s>", n.name.Local)
+	}
+	v.content(n, t)
+}
+
+func (v *validator) attributes(n *node, t *typeDef) {
+	present := map[xml.Name]bool{}
+	for _, attr := range n.attrs {
+		if attr.Name.Space == "xmlns" || attr.Name.Space == "" && attr.Name.Local == "xmlns" ||
+			attr.Name.Space == namespaceXML || attr.Name.Space == namespaceXSI {
+			continue
+		}
+		present[attr.Name] = true
+
+		decl, ok := t.attributes[attr.Name]
+		switch {
+		case ok:
+			if problem := decl.typ.check(attr.Value); problem != "" {
+				v.report(n, "invalid value of attribute %s on <%s>: %s", attr.Name.Local, n.name.Local, problem)
+			}
+		case t.anyAttribute != nil && t.anyAttribute.allows(attr.Name.Space):
+		default:
+			v.report(n, "attribute %s is not allowed on <%s>", attr.Name.Local, n.name.Local)
+		}
+	}
+
+	var missing []string
+	for name, decl := range t.attributes {
+		if decl.required && !present[name] {
+			missing = append(missing, name.Local)
+		}
+	}
+	sort.Strings(missing)
+	for _, name := range missing {
+		v.report(n, "attribute %s is required on <%s>", name, n.name.Local)
+	}
+}
+
+// content matches the child elements of n against the content model of t,
+// then validates each child against its declaration.
+func (v *validator) content(n *node, t *typeDef) {
+	m := &matcher{children: n.children, expected: map[string]bool{}, furthest: -1}
+	var ends []int
+	if t.content == nil {
+		ends = []int{0}
+	} else {
+		ends = m.match(t.content, 0)
+	}
+	if !contains(ends, len(n.children)) {
+		v.contentViolation(n, m)
+	}
+
+	for _, child := range n.children {
+		if decl, ok := t.children[child.name]; ok {
+			v.element(child, decl.typ)
+			continue
+		}
+		v.wildcardElement(child, t.content)
+	}
+}
+
+func (v *validator) contentViolation(n *node, m *matcher) {
+	expected := make([]string, 0, len(m.expected))
+	for name := range m.expected {
+		expected = append(expected, name)
+	}
+	sort.Strings(expected)
+	if len(expected) > maxExpected {
+		expected = append(expected[:maxExpected], "...")
+	}
+	list := strings.Join(expected, ", ")
+
+	switch {
+	case m.furthest < 0 && len(n.children) > 0:
+		v.report(n.children[0], "element <%s> is not allowed in <%s>, which must be empty", n.children[0].name.Local, n.name.Local)
+	case m.furthest < len(n.children):
+		child := n.children[m.furthest]
+		v.report(child, "element <%s> is not expected in <%s>, expected %s", child.name.Local, n.name.Local, list)
+	default:
+		v.report(n, "<%s> is incomplete, expected %s", n.name.Local, list)
+	}
+}
+
+// wildcardElement validates a child that only a wildcard of the content
+// model can match.
+func (v *validator) wildcardElement(n *node, content particle) {
+	w := findWildcard(content, n.name.Space, map[*groupParticle]bool{})
+	if w == nil || w.process == "skip" {
+		return
+	}
+	decl, ok := v.schema.elements[n.name]
+	switch {
+	case ok:
+		v.element(n, decl.typ)
+	case w.process == "strict" && n.name.Space == v.schema.namespace:
+		v.report(n, "element <%s> is not declared in %s", n.name.Local, v.schema.describe())
+	}
+}
+
+func collectElements(p particle, into map[xml.Name]*elementDecl, seen map[*groupParticle]bool) {
+	switch p := p.(type) {
+	case *elementParticle:
+		addElement(p.decl, into)
+	case *groupParticle:
+		if seen[p] {
+			return
+		}
+		seen[p] = true
+		for _, inner := range p.particles {
+			collectElements(inner, into, seen)
+		}
+	}
+}
+
+func addElement(decl *elementDecl, into map[xml.Name]*elementDecl) {
+	if _, ok := into[decl.name]; ok {
+		return
+	}
+	if !decl.abstract {
+		into[decl.name] = decl
+	}
+	for _, substitute := range decl.substitutes {
+		addElement(substitute, into)
+	}
+}
+
+func findWildcard(p particle, namespace string, seen map[*groupParticle]bool) *wildcard {
+	switch p := p.(type) {
+	case *anyParticle:
+		if p.wildcard.allows(namespace) {
+			return p.wildcard
+		}
+	case *groupParticle:
+		if seen[p] {
+			return nil
+		}
+		seen[p] = true
+		for _, inner := range p.particles {
+			if w := findWildcard(inner, namespace, seen); w != nil {
+				return w
+			}
+		}
+	}
+	return nil
+}
+
+// matcher matches child elements against a content model, keeping the
+// furthest position a particle failed at and what it expected there.
+type matcher struct {
+	children []*node
+	furthest int
+	expected map[string]bool
+}
+
+// match returns the positions the children matched by p may end at, when
+// its match starts at pos, sorted.
+func (m *matcher) match(p particle, pos int) []int {
+	min, max := p.bounds()
+	if group, ok := p.(*groupParticle); ok && group.kind == "all" {
+		return m.repeat(m.all(group), min, max, pos)
+	}
+	return m.repeat(func(pos int) []int { return m.once(p, pos) }, min, max, pos)
+}
+
+func (m *matcher) once(p particle, pos int) []int {
+	switch p := p.(type) {
+	case *elementParticle:
+		if pos < len(m.children) && matchesElement(p.decl, m.children[pos].name) {
+			return []int{pos + 1}
+		}
+		m.expect(pos, elementNames(p.decl, nil)...)
+	case *anyParticle:
+		if pos < len(m.children) && p.wildcard.allows(m.children[pos].name.Space) {
+			return []int{pos + 1}
+		}
+		m.expect(pos, "any element")
+	case *groupParticle:
+		positions := []int{pos}
+		if p.kind == "choice" {
+			positions = nil
+			for _, inner := range p.particles {
+				positions = union(positions, m.match(inner, pos))
+			}
+			return positions
+		}
+		for _, inner := range p.particles {
+			var next []int
+			for _, start := range positions {
+				next = union(next, m.match(inner, start))
+			}
+			positions = next
+			if len(positions) == 0 {
+				break
+			}
+		}
+		return positions
+	}
+	return nil
+}
+
+// all returns a match of an all group, whose particles each occur once at
+// most in any order.
+func (m *matcher) all(group *groupParticle) func(int) []int {
+	return func(pos int) []int {
+		type state struct{ pos, done int }
+		required := 0
+		for i, inner := range group.particles {
+			if min, _ := inner.bounds(); min > 0 {
+				required |= 1 << i
+			}
+		}
+
+		var ends []int
+		seen := map[state]bool{}
+		queue := []state{{pos, 0}}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			if seen[current] {
+				continue
+			}
+			seen[current] = true
+			if current.done&required == required {
+				ends = union(ends, []int{current.pos})
+			}
+			for i, inner := range group.particles {
+				if current.done&(1<<i) != 0 {
+					continue
+				}
+				for _, end := range m.once(inner, current.pos) {
+					queue = append(queue, state{end, current.done | 1<<i})
+				}
+			}
+		}
+		return ends
+	}
+}
+
+// repeat matches once between min and max times from pos. A maximum below
+// zero is unbounded.
+func (m *matcher) repeat(once func(int) []int, min, max, pos int) []int {
+	var ends []int
+	current := []int{pos}
+	seen := map[int]bool{}
+	for i := 0; ; i++ {
+		if i >= min {
+			var fresh []int
+			for _, position := range current {
+				if !seen[position] {
+					seen[position] = true
+					fresh = append(fresh, position)
+				}
+			}
+			ends = union(ends, fresh)
+			current = fresh
+		}
+		if len(current) == 0 || max >= 0 && i >= max {
+			return ends
+		}
+		var next []int
+		for _, start := range current {
+			next = union(next, once(start))
+		}
+		current = next
+	}
+}
+
+func (m *matcher) expect(pos int, names ...string) {
+	if pos > m.furthest {
+		m.furthest = pos
+		m.expected = map[string]bool{}
+	}
+	if pos == m.furthest {
+		for _, name := range names {
+			m.expected[name] = true
+		}
+	}
+}
+
+func matchesElement(decl *elementDecl, name xml.Name) bool {
+	if decl.name == name && !decl.abstract {
+		return true
+	}
+	for _, substitute := range decl.substitutes {
+		if matchesElement(substitute, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func elementNames(decl *elementDecl, names []string) []string {
+	if !decl.abstract {
+		names = append(names, "<"+decl.name.Local+">")
+	}
+	for _, substitute := range decl.substitutes {
+		names = elementNames(substitute, names)
+	}
+	return names
+}
+
+// union merges two sorted sets of positions.
+func union(a, b []int) []int {
+	if len(a) == 0 {
+		return b
+	}
+	merged := make([]int, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b) || i < len(a) && a[i] < b[j]:
+			merged = append(merged, a[i])
+			i++
+		case i == len(a) || b[j] < a[i]:
+			merged = append(merged, b[j])
+			j++
+		default:
+			merged = append(merged, a[i])
+			i++
+			j++
+		}
+	}
+	return merged
+}
+
+func contains(positions []int, pos int) bool {
+	for _, position := range positions {
+		if position == pos {
+			return true
+		}
+	}
+	return false
+}
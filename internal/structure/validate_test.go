@@ -0,0 +1,277 @@
+package structure
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+const testNamespace = "urn:test"
+
+// A schema using each construct the validator supports, with an import of a
+// schema that is not there.
+const testSchema = `<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns="urn:test" xmlns:ext="urn:ext"
+           targetNamespace="urn:test" elementFormDefault="qualified">
+  <xs:import namespace="urn:ext" schemaLocation="http://example.com/ext.xsd"/>
+  <xs:include schemaLocation="types.xsd"/>
+
+  <xs:attributeGroup name="common.AttrGroup">
+    <xs:attribute name="id" type="xs:ID"/>
+    <xs:attribute name="class" type="classes.Type"/>
+  </xs:attributeGroup>
+
+  <xs:complexType name="base.Type">
+    <xs:sequence>
+      <xs:element name="title" type="xs:string"/>
+    </xs:sequence>
+    <xs:attributeGroup ref="common.AttrGroup"/>
+  </xs:complexType>
+
+  <xs:element name="block" abstract="true"/>
+  <xs:element name="para" substitutionGroup="block">
+    <xs:complexType mixed="true">
+      <xs:choice minOccurs="0" maxOccurs="unbounded">
+        <xs:element name="em" type="xs:string"/>
+        <xs:any namespace="##other" processContents="lax"/>
+      </xs:choice>
+    </xs:complexType>
+  </xs:element>
+  <xs:element name="rule" substitutionGroup="block">
+    <xs:complexType/>
+  </xs:element>
+
+  <xs:element name="doc">
+    <xs:complexType>
+      <xs:complexContent>
+        <xs:extension base="base.Type">
+          <xs:sequence>
+            <xs:element name="meta" minOccurs="0">
+              <xs:complexType>
+                <xs:all>
+                  <xs:element name="author" type="xs:string"/>
+                  <xs:element name="date" type="xs:date" minOccurs="0"/>
+                </xs:all>
+              </xs:complexType>
+            </xs:element>
+            <xs:element ref="block" minOccurs="1" maxOccurs="3"/>
+            <xs:element ref="ext:note" minOccurs="0"/>
+          </xs:sequence>
+          <xs:attribute name="kind" type="kind.Type" use="required"/>
+          <xs:attribute name="size">
+            <xs:simpleType>
+              <xs:restriction base="xs:integer">
+                <xs:minInclusive value="1"/>
+                <xs:maxExclusive value="10"/>
+              </xs:restriction>
+            </xs:simpleType>
+          </xs:attribute>
+          <xs:attribute name="code">
+            <xs:simpleType>
+              <xs:restriction base="xs:string">
+                <xs:pattern value="[A-Z]{2}\d"/>
+              </xs:restriction>
+            </xs:simpleType>
+          </xs:attribute>
+          <xs:attribute name="class" use="prohibited"/>
+        </xs:extension>
+      </xs:complexContent>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`
+
+const testTypes = `<?xml version="1.0"?>
+<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns="urn:test" targetNamespace="urn:test">
+  <xs:simpleType name="kind.Type">
+    <xs:union>
+      <xs:simpleType>
+        <xs:restriction base="xs:NMTOKEN">
+          <xs:enumeration value="short"/>
+          <xs:enumeration value="long"/>
+        </xs:restriction>
+      </xs:simpleType>
+      <xs:simpleType>
+        <xs:restriction base="xs:positiveInteger"/>
+      </xs:simpleType>
+    </xs:union>
+  </xs:simpleType>
+  <xs:simpleType name="classes.Type">
+    <xs:list itemType="xs:NCName"/>
+  </xs:simpleType>
+</xs:schema>`
+
+func compileTestSchema(t *testing.T) *Schema {
+	t.Helper()
+	fsys := fstest.MapFS{
+		"test/doc.xsd":   {Data: []byte(testSchema)},
+		"test/types.xsd": {Data: []byte(testTypes)},
+	}
+	schema, err := compile(fsys, "test/doc.xsd")
+	if err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+	return schema
+}
+
+func TestSchema_Namespace(t *testing.T) {
+	if got := compileTestSchema(t).Namespace(); got != testNamespace {
+		t.Errorf("Namespace() = %q, expected %q", got, testNamespace)
+	}
+}
+
+func TestSchema_Validate(t *testing.T) {
+	schema := compileTestSchema(t)
+
+	tests := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{
+			name: "valid",
+			content: `<doc xmlns="urn:test" xmlns:ext="urn:ext" kind="short" size="9" code="AB1">
+  <title>Doc</title>
+  <meta><date>2024-05-01</date><author>Ann</author></meta>
+  <para>Some <em>text</em> and <ext:ref any="thing"/></para>
+  <rule/>
+  <ext:note>Free</ext:note>
+</doc>`,
+		},
+		{
+			name:    "no namespace",
+			content: `<doc kind="3"><title>Doc</title><para/></doc>`,
+		},
+		{
+			name:    "unknown root",
+			content: `<book xmlns="urn:other"/>`,
+			expected: []string{
+				"/book (line 1): element <book> in namespace urn:other is not declared in the schema",
+			},
+		},
+		{
+			name:    "abstract root",
+			content: `<block/>`,
+			expected: []string{
+				"/block (line 1): element <block> is abstract",
+			},
+		},
+		{
+			name: "attributes",
+			content: `<doc kind="medium" size="10" code="A12" class="x" lang="en">
+  <title>Doc</title><para/>
+</doc>`,
+			expected: []string{
+				"/doc (line 1): invalid value of attribute kind on <doc>",
+				"/doc (line 1): invalid value of attribute size on <doc>",
+				"/doc (line 1): invalid value of attribute code on <doc>",
+				"/doc (line 1): attribute class is not allowed on <doc>",
+				"/doc (line 1): attribute lang is not allowed on <doc>",
+			},
+		},
+		{
+			name:    "required attribute",
+			content: `<doc><title>Doc</title><para/></doc>`,
+			expected: []string{
+				"/doc (line 1): attribute kind is required on <doc>",
+			},
+		},
+		{
+			name: "unexpected element",
+			content: `<doc kind="long">
+  <title>Doc</title>
+  <para/>
+  <meta><author>Ann</author></meta>
+</doc>`,
+			expected: []string{
+				"/doc/meta (line 4): element <meta> is not expected in <doc>, expected <note>, <para>, <rule>",
+			},
+		},
+		{
+			name: "too many",
+			content: `<doc kind="long">
+  <title>Doc</title>
+  <para/><para/><para/><para/>
+</doc>`,
+			expected: []string{
+				"/doc/para[4] (line 3): element <para> is not expected in <doc>",
+			},
+		},
+		{
+			name:    "incomplete",
+			content: `<doc kind="long"><title>Doc</title><meta><date>2024-05-01</date></meta></doc>`,
+			expected: []string{
+				"/doc (line 1): <doc> is incomplete, expected <para>, <rule>",
+				"/doc/meta (line 1): <meta> is incomplete, expected <author>",
+			},
+		},
+		{
+			name: "content of children",
+			content: `<doc kind="long">
+  <title>Doc</title>
+  <meta><author>Ann</author><date>May</date></meta>
+  <para><em>a</em><em><b/></em></para>
+  <rule><para/></rule>
+  text
+</doc>`,
+			expected: []string{
+				"/doc (line 1): text is not allowed in <doc>",
+				"/doc/meta/date (line 3): invalid content of <date>",
+				"/doc/para/em[2]/b (line 4): element <b> is not allowed in <em>, which only holds text",
+				"/doc/rule/para (line 5): element <para> is not allowed in <rule>, which must be empty",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations, err := schema.Validate([]byte(tt.content))
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if len(violations) != len(tt.expected) {
+				t.Fatalf("Validate() = %v, expected %d violations", violations, len(tt.expected))
+			}
+			for i, expected := range tt.expected {
+				if got := violations[i].String(); !strings.HasPrefix(got, expected) {
+					t.Errorf("violation %d = %q, expected it to start with %q", i, got, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestSimpleType_Check(t *testing.T) {
+	tests := []struct {
+		builtin string
+		value   string
+		valid   bool
+	}{
+		{"boolean", "true", true},
+		{"boolean", "yes", false},
+		{"integer", " -12 ", true},
+		{"integer", "1.5", false},
+		{"nonNegativeInteger", "-1", false},
+		{"int", "2147483648", false},
+		{"double", "1.5E3", true},
+		{"double", "INF", true},
+		{"decimal", "1e3", false},
+		{"NCName", "a:b", false},
+		{"NMTOKEN", "a-b", true},
+		{"IDREFS", "a b", true},
+		{"anyURI", "items/a.xml", true},
+		{"date", "2024-05-01", true},
+		{"duration", "PT1H30M", true},
+		{"duration", "90", false},
+	}
+
+	for _, tt := range tests {
+		typ, err := builtinType(tt.builtin)
+		if err != nil {
+			t.Fatalf("builtinType(%s) error = %v", tt.builtin, err)
+		}
+		problem := typ.check(tt.value)
+		if (problem == "") != tt.valid {
+			t.Errorf("%s check(%q) = %q, expected valid = %v", tt.builtin, tt.value, problem, tt.valid)
+		}
+	}
+}
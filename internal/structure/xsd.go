@@ -0,0 +1,779 @@
+package structure
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	namespaceXSD = "http://www.w3.org/2001/XMLSchema"
+	namespaceXSI = "http://www.w3.org/2001/XMLSchema-instance"
+	namespaceXML = "http://www.w3.org/XML/1998/namespace"
+)
+
+// Schema is a compiled XML Schema.
+type Schema struct {
+	version string
+	// Target namespace of the schema document the schema was compiled from
+	namespace string
+	elements  map[xml.Name]*elementDecl
+}
+
+// Namespace returns the target namespace of the schema.
+func (s *Schema) Namespace() string {
+	return s.namespace
+}
+
+type elementDecl struct {
+	name     xml.Name
+	typ      *typeDef
+	abstract bool
+	// Elements that may appear in place of this one
+	substitutes []*elementDecl
+}
+
+// typeDef is a complex type, or a simple type used as the type of an
+// element.
+type typeDef struct {
+	// Content and attributes are not checked, as for xs:anyType
+	any bool
+	// Type of the text of elements with simple content
+	simple *simpleType
+	mixed  bool
+	// Element content; nil for empty content
+	content      particle
+	attributes   map[xml.Name]*attributeDecl
+	anyAttribute *wildcard
+	// Attributes of the base type a restriction removes
+	prohibited map[xml.Name]bool
+
+	// Type the type derives from, whose content and attributes are merged
+	// in once every type is compiled
+	base      *typeDef
+	extension bool
+	finalized bool
+
+	// Element declarations of content, by name
+	children map[xml.Name]*elementDecl
+}
+
+type attributeDecl struct {
+	name     xml.Name
+	typ      *simpleType
+	required bool
+}
+
+// particle is a term of a content model with its occurrence bounds. A
+// maximum below zero is unbounded.
+type particle interface {
+	bounds() (min, max int)
+}
+
+type elementParticle struct {
+	decl     *elementDecl
+	min, max int
+}
+
+type groupParticle struct {
+	// sequence, choice or all
+	kind      string
+	particles []particle
+	min, max  int
+}
+
+type anyParticle struct {
+	wildcard *wildcard
+	min, max int
+}
+
+func (p *elementParticle) bounds() (int, int) { return p.min, p.max }
+func (p *groupParticle) bounds() (int, int)   { return p.min, p.max }
+func (p *anyParticle) bounds() (int, int)     { return p.min, p.max }
+
+// wildcard is an xs:any or xs:anyAttribute.
+type wildcard struct {
+	// Any namespace but these, when other is set; otherwise only these.
+	// The empty string stands for no namespace.
+	namespaces []string
+	other      bool
+	// strict, lax or skip
+	process string
+}
+
+func (w *wildcard) allows(namespace string) bool {
+	for _, allowed := range w.namespaces {
+		if namespace == allowed {
+			return !w.other
+		}
+	}
+	return w.other
+}
+
+// xsdNode is an element of a schema document.
+type xsdNode struct {
+	name     string
+	attrs    map[string]string
+	children []*xsdNode
+	// Namespace prefixes in scope, for resolving QName values
+	prefixes map[string]string
+	doc      *xsdDocument
+}
+
+type xsdDocument struct {
+	targetNamespace string
+	qualified       bool
+}
+
+func (n *xsdNode) attr(name string) string {
+	return n.attrs[name]
+}
+
+// qname resolves a QName value such as xs:string.
+func (n *xsdNode) qname(value string) (xml.Name, error) {
+	prefix, local, found := strings.Cut(value, ":")
+	if !found {
+		return xml.Name{Space: n.prefixes[""], Local: value}, nil
+	}
+	namespace, ok := n.prefixes[prefix]
+	if !ok {
+		return xml.Name{}, fmt.Errorf("undeclared prefix in %q", value)
+	}
+	return xml.Name{Space: namespace, Local: local}, nil
+}
+
+func (n *xsdNode) occurs() (int, int, error) {
+	min, max := 1, 1
+	var err error
+	if value := n.attr("minOccurs"); value != "" {
+		if min, err = strconv.Atoi(value); err != nil {
+			return 0, 0, fmt.Errorf("invalid minOccurs %q", value)
+		}
+	}
+	switch value := n.attr("maxOccurs"); value {
+	case "":
+	case "unbounded":
+		max = -1
+	default:
+		if max, err = strconv.Atoi(value); err != nil {
+			return 0, 0, fmt.Errorf("invalid maxOccurs %q", value)
+		}
+	}
+	return min, max, nil
+}
+
+// compiler turns the schema documents into a Schema, resolving references
+// on first use.
+type compiler struct {
+	fsys fs.FS
+	// Schema documents read so far
+	loaded map[string]bool
+	// Namespaces imported without a schema document; references to them
+	// are not checked
+	unresolved map[string]bool
+
+	globalElements        map[xml.Name]*xsdNode
+	globalTypes           map[xml.Name]*xsdNode
+	globalGroups          map[xml.Name]*xsdNode
+	globalAttributeGroups map[xml.Name]*xsdNode
+	globalAttributes      map[xml.Name]*xsdNode
+
+	elements map[xml.Name]*elementDecl
+	// Compiled components by the node they come from, so that recursive
+	// content models are compiled once
+	compiled map[*xsdNode]interface{}
+}
+
+func compile(fsys fs.FS, name string) (*Schema, error) {
+	c := &compiler{
+		fsys:                  fsys,
+		loaded:                map[string]bool{},
+		unresolved:            map[string]bool{},
+		globalElements:        map[xml.Name]*xsdNode{},
+		globalTypes:           map[xml.Name]*xsdNode{},
+		globalGroups:          map[xml.Name]*xsdNode{},
+		globalAttributeGroups: map[xml.Name]*xsdNode{},
+		globalAttributes:      map[xml.Name]*xsdNode{},
+		elements:              map[xml.Name]*elementDecl{},
+		compiled:              map[*xsdNode]interface{}{},
+	}
+	root, err := c.load(name)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &Schema{namespace: root.doc.targetNamespace, elements: c.elements}
+	for elementName := range c.globalElements {
+		if _, err := c.globalElement(elementName); err != nil {
+			return nil, fmt.Errorf("element %s: %w", elementName.Local, err)
+		}
+	}
+	for _, compiled := range c.compiled {
+		if t, ok := compiled.(*typeDef); ok {
+			t.finalize()
+		}
+	}
+	// Substitution groups are known once every global element is compiled
+	for elementName, node := range c.globalElements {
+		if group := node.attr("substitutionGroup"); group != "" {
+			headName, err := node.qname(group)
+			if err != nil {
+				return nil, err
+			}
+			head, ok := c.elements[headName]
+			if !ok {
+				return nil, fmt.Errorf("element %s: unknown substitution group %s", elementName.Local, group)
+			}
+			head.substitutes = append(head.substitutes, c.elements[elementName])
+		}
+	}
+	for _, compiled := range c.compiled {
+		if t, ok := compiled.(*typeDef); ok && t.content != nil {
+			collectElements(t.content, t.children, map[*groupParticle]bool{})
+		}
+	}
+	return schema, nil
+}
+
+// load reads a schema document and the documents it includes and imports.
+func (c *compiler) load(name string) (*xsdNode, error) {
+	c.loaded[name] = true
+	content, err := fs.ReadFile(c.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	root, err := parseSchemaDocument(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	for _, child := range root.children {
+		key := xml.Name{Space: root.doc.targetNamespace, Local: child.attr("name")}
+		switch child.name {
+		case "include", "import":
+			location := child.attr("schemaLocation")
+			included := path.Join(path.Dir(name), path.Base(location))
+			if _, err := fs.Stat(c.fsys, included); location == "" || err != nil {
+				c.unresolved[child.attr("namespace")] = true
+				continue
+			}
+			if c.loaded[included] {
+				continue
+			}
+			if _, err := c.load(included); err != nil {
+				return nil, err
+			}
+		case "element":
+			c.globalElements[key] = child
+		case "complexType", "simpleType":
+			c.globalTypes[key] = child
+		case "group":
+			c.globalGroups[key] = child
+		case "attributeGroup":
+			c.globalAttributeGroups[key] = child
+		case "attribute":
+			c.globalAttributes[key] = child
+		}
+	}
+	return root, nil
+}
+
+// parseSchemaDocument reads the XSD elements of a schema document,
+// skipping annotations.
+func parseSchemaDocument(content []byte) (*xsdNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	doc := &xsdDocument{}
+	var stack []*xsdNode
+	var root *xsdNode
+	skip := 0
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if skip > 0 || t.Name.Local == "annotation" {
+				skip++
+				continue
+			}
+			node := &xsdNode{name: t.Name.Local, attrs: map[string]string{}, prefixes: map[string]string{}, doc: doc}
+			if len(stack) > 0 {
+				for prefix, namespace := range stack[len(stack)-1].prefixes {
+					node.prefixes[prefix] = namespace
+				}
+			}
+			for _, attr := range t.Attr {
+				switch {
+				case attr.Name.Space == "xmlns":
+					node.prefixes[attr.Name.Local] = attr.Value
+				case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+					node.prefixes[""] = attr.Value
+				case attr.Name.Space == "":
+					node.attrs[attr.Name.Local] = attr.Value
+				}
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			} else {
+				root = node
+				if node.name != "schema" {
+					return nil, fmt.Errorf("root element <%s> is not an XML Schema", node.name)
+				}
+				doc.targetNamespace = node.attr("targetNamespace")
+				doc.qualified = node.attr("elementFormDefault") == "qualified"
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+				continue
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("document has no root element")
+	}
+	return root, nil
+}
+
+func (c *compiler) globalElement(name xml.Name) (*elementDecl, error) {
+	if decl, ok := c.elements[name]; ok {
+		return decl, nil
+	}
+	node, ok := c.globalElements[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown element %s", name.Local)
+	}
+	decl := &elementDecl{name: name}
+	c.elements[name] = decl
+	return decl, c.elementType(node, decl)
+}
+
+// localElement compiles an element declared or referenced inside a content
+// model.
+func (c *compiler) localElement(node *xsdNode) (*elementDecl, error) {
+	if ref := node.attr("ref"); ref != "" {
+		name, err := node.qname(ref)
+		if err != nil {
+			return nil, err
+		}
+		if c.unresolved[name.Space] {
+			return &elementDecl{name: name, typ: &typeDef{any: true}}, nil
+		}
+		return c.globalElement(name)
+	}
+
+	if compiled, ok := c.compiled[node]; ok {
+		return compiled.(*elementDecl), nil
+	}
+	name := xml.Name{Local: node.attr("name")}
+	if form := node.attr("form"); form == "qualified" || form == "" && node.doc.qualified {
+		name.Space = node.doc.targetNamespace
+	}
+	decl := &elementDecl{name: name}
+	c.compiled[node] = decl
+	return decl, c.elementType(node, decl)
+}
+
+func (c *compiler) elementType(node *xsdNode, decl *elementDecl) error {
+	decl.abstract = node.attr("abstract") == "true"
+
+	if typeName := node.attr("type"); typeName != "" {
+		name, err := node.qname(typeName)
+		if err != nil {
+			return err
+		}
+		decl.typ, err = c.namedType(name)
+		return err
+	}
+	for _, child := range node.children {
+		var err error
+		switch child.name {
+		case "complexType":
+			decl.typ, err = c.complexType(child)
+			return err
+		case "simpleType":
+			simple, err := c.simpleType(child)
+			decl.typ = &typeDef{simple: simple}
+			return err
+		}
+	}
+
+	// Members of a substitution group have the type of its head by default
+	if group := node.attr("substitutionGroup"); group != "" {
+		name, err := node.qname(group)
+		if err != nil {
+			return err
+		}
+		head, err := c.globalElement(name)
+		if err != nil {
+			return err
+		}
+		decl.typ = head.typ
+		return nil
+	}
+	decl.typ = &typeDef{any: true}
+	return nil
+}
+
+// namedType returns a global type, or a built-in simple type as the type of
+// an element.
+func (c *compiler) namedType(name xml.Name) (*typeDef, error) {
+	if name.Space == namespaceXSD {
+		if name.Local == "anyType" {
+			return &typeDef{any: true}, nil
+		}
+		simple, err := builtinType(name.Local)
+		if err != nil {
+			return nil, err
+		}
+		return &typeDef{simple: simple}, nil
+	}
+	if c.unresolved[name.Space] {
+		return &typeDef{any: true}, nil
+	}
+
+	node, ok := c.globalTypes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown type %s", name.Local)
+	}
+	if node.name == "simpleType" {
+		simple, err := c.simpleType(node)
+		if err != nil {
+			return nil, err
+		}
+		return &typeDef{simple: simple}, nil
+	}
+	return c.complexType(node)
+}
+
+func (c *compiler) complexType(node *xsdNode) (*typeDef, error) {
+	if compiled, ok := c.compiled[node]; ok {
+		return compiled.(*typeDef), nil
+	}
+	t := &typeDef{
+		mixed:      node.attr("mixed") == "true",
+		attributes: map[xml.Name]*attributeDecl{},
+		children:   map[xml.Name]*elementDecl{},
+	}
+	c.compiled[node] = t
+
+	for _, child := range node.children {
+		switch child.name {
+		case "simpleContent":
+			if err := c.simpleContent(t, child); err != nil {
+				return nil, err
+			}
+		case "complexContent":
+			if child.attr("mixed") == "true" {
+				t.mixed = true
+			}
+			if err := c.complexContent(t, child); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := c.content(t, node); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// content adds the content model and attributes declared directly in node
+// to t.
+func (c *compiler) content(t *typeDef, node *xsdNode) error {
+	for _, child := range node.children {
+		switch child.name {
+		case "sequence", "choice", "all", "group":
+			content, err := c.particle(child)
+			if err != nil {
+				return err
+			}
+			if t.content == nil {
+				t.content = content
+			} else {
+				t.content = &groupParticle{kind: "sequence", particles: []particle{t.content, content}, min: 1, max: 1}
+			}
+		case "attribute", "attributeGroup", "anyAttribute":
+			if err := c.attributes(t, child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *compiler) simpleContent(t *typeDef, node *xsdNode) error {
+	for _, derivation := range node.children {
+		if derivation.name != "extension" && derivation.name != "restriction" {
+			continue
+		}
+		baseName, err := derivation.qname(derivation.attr("base"))
+		if err != nil {
+			return err
+		}
+		base, err := c.namedType(baseName)
+		if err != nil {
+			return err
+		}
+		t.simple = base.simple
+		if t.simple == nil {
+			t.simple = &simpleType{builtin: "string"}
+		}
+		t.base = base
+
+		if derivation.name == "restriction" {
+			restricted := &simpleType{base: t.simple}
+			if err := c.facets(restricted, derivation); err != nil {
+				return err
+			}
+			t.simple = restricted
+		}
+		return c.content(t, derivation)
+	}
+	return fmt.Errorf("simpleContent without extension or restriction")
+}
+
+func (c *compiler) complexContent(t *typeDef, node *xsdNode) error {
+	for _, derivation := range node.children {
+		if derivation.name != "extension" && derivation.name != "restriction" {
+			continue
+		}
+		baseName, err := derivation.qname(derivation.attr("base"))
+		if err != nil {
+			return err
+		}
+		base, err := c.namedType(baseName)
+		if err != nil {
+			return err
+		}
+		if !base.any {
+			t.base = base
+			t.extension = derivation.name == "extension"
+		}
+		return c.content(t, derivation)
+	}
+	return fmt.Errorf("complexContent without extension or restriction")
+}
+
+// finalize merges the content and attributes of the base type into t. An
+// extension appends its content to that of the base; a restriction restates
+// the content, and both inherit the attributes they do not declare.
+func (t *typeDef) finalize() {
+	if t.finalized || t.base == nil {
+		return
+	}
+	t.finalized = true
+	t.base.finalize()
+
+	for name, attribute := range t.base.attributes {
+		if _, ok := t.attributes[name]; !ok && !t.prohibited[name] {
+			t.attributes[name] = attribute
+		}
+	}
+	if t.anyAttribute == nil {
+		t.anyAttribute = t.base.anyAttribute
+	}
+	if !t.extension {
+		return
+	}
+	t.mixed = t.mixed || t.base.mixed
+	switch {
+	case t.base.content == nil:
+	case t.content == nil:
+		t.content = t.base.content
+	default:
+		t.content = &groupParticle{kind: "sequence", particles: []particle{t.base.content, t.content}, min: 1, max: 1}
+	}
+}
+
+func (c *compiler) attributes(t *typeDef, node *xsdNode) error {
+	switch node.name {
+	case "anyAttribute":
+		t.anyAttribute = newWildcard(node)
+	case "attributeGroup":
+		name, err := node.qname(node.attr("ref"))
+		if err != nil {
+			return err
+		}
+		group, ok := c.globalAttributeGroups[name]
+		if !ok {
+			return fmt.Errorf("unknown attribute group %s", name.Local)
+		}
+		for _, child := range group.children {
+			if err := c.attributes(t, child); err != nil {
+				return err
+			}
+		}
+	case "attribute":
+		if node.attr("use") == "prohibited" {
+			if t.prohibited == nil {
+				t.prohibited = map[xml.Name]bool{}
+			}
+			t.prohibited[xml.Name{Local: node.attr("name")}] = true
+			return nil
+		}
+		decl, err := c.attribute(node)
+		if err != nil {
+			return err
+		}
+		if decl != nil {
+			t.attributes[decl.name] = decl
+		}
+	}
+	return nil
+}
+
+// attribute compiles an attribute declaration or reference. References to
+// attributes of namespaces without a schema document return nil.
+func (c *compiler) attribute(node *xsdNode) (*attributeDecl, error) {
+	decl := &attributeDecl{required: node.attr("use") == "required"}
+	source := node
+	if ref := node.attr("ref"); ref != "" {
+		name, err := node.qname(ref)
+		if err != nil {
+			return nil, err
+		}
+		if name.Space == namespaceXML || c.unresolved[name.Space] {
+			return nil, nil
+		}
+		global, ok := c.globalAttributes[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown attribute %s", name.Local)
+		}
+		decl.name = name
+		source = global
+	} else {
+		decl.name = xml.Name{Local: node.attr("name")}
+		if node.attr("form") == "qualified" {
+			decl.name.Space = node.doc.targetNamespace
+		}
+	}
+
+	var err error
+	if typeName := source.attr("type"); typeName != "" {
+		name, err := source.qname(typeName)
+		if err != nil {
+			return nil, err
+		}
+		typ, err := c.namedType(name)
+		if err != nil {
+			return nil, err
+		}
+		decl.typ = typ.simple
+		if decl.typ == nil {
+			return nil, fmt.Errorf("attribute %s has complex type %s", decl.name.Local, name.Local)
+		}
+		return decl, nil
+	}
+	for _, child := range source.children {
+		if child.name == "simpleType" {
+			decl.typ, err = c.simpleType(child)
+			return decl, err
+		}
+	}
+	decl.typ = &simpleType{builtin: "string"}
+	return decl, nil
+}
+
+func (c *compiler) particle(node *xsdNode) (particle, error) {
+	min, max, err := node.occurs()
+	if err != nil {
+		return nil, err
+	}
+
+	switch node.name {
+	case "element":
+		decl, err := c.localElement(node)
+		if err != nil {
+			return nil, err
+		}
+		return &elementParticle{decl: decl, min: min, max: max}, nil
+	case "any":
+		return &anyParticle{wildcard: newWildcard(node), min: min, max: max}, nil
+	case "group":
+		name, err := node.qname(node.attr("ref"))
+		if err != nil {
+			return nil, err
+		}
+		group, ok := c.globalGroups[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown group %s", name.Local)
+		}
+		for _, child := range group.children {
+			if child.name == "sequence" || child.name == "choice" || child.name == "all" {
+				inner, err := c.modelGroup(child)
+				if err != nil {
+					return nil, err
+				}
+				return &groupParticle{kind: "sequence", particles: []particle{inner}, min: min, max: max}, nil
+			}
+		}
+		return nil, fmt.Errorf("group %s has no model group", name.Local)
+	default:
+		group, err := c.modelGroup(node)
+		if err != nil {
+			return nil, err
+		}
+		return &groupParticle{kind: "sequence", particles: []particle{group}, min: min, max: max}, nil
+	}
+}
+
+// modelGroup compiles a sequence, choice or all once, so that named groups
+// that recur through the types of their elements are shared.
+func (c *compiler) modelGroup(node *xsdNode) (*groupParticle, error) {
+	if compiled, ok := c.compiled[node]; ok {
+		return compiled.(*groupParticle), nil
+	}
+	group := &groupParticle{kind: node.name, min: 1, max: 1}
+	c.compiled[node] = group
+	for _, child := range node.children {
+		switch child.name {
+		case "element", "any", "group", "sequence", "choice", "all":
+			p, err := c.particle(child)
+			if err != nil {
+				return nil, err
+			}
+			group.particles = append(group.particles, p)
+		}
+	}
+	return group, nil
+}
+
+func newWildcard(node *xsdNode) *wildcard {
+	w := &wildcard{process: node.attr("processContents")}
+	if w.process == "" {
+		w.process = "strict"
+	}
+	switch namespace := node.attr("namespace"); namespace {
+	case "", "##any":
+		w.other = true
+	case "##other":
+		w.other = true
+		w.namespaces = []string{node.doc.targetNamespace, ""}
+	default:
+		for _, value := range strings.Fields(namespace) {
+			switch value {
+			case "##targetNamespace":
+				w.namespaces = append(w.namespaces, node.doc.targetNamespace)
+			case "##local":
+				w.namespaces = append(w.namespaces, "")
+			default:
+				w.namespaces = append(w.namespaces, value)
+			}
+		}
+	}
+	return w
+}
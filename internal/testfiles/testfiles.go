@@ -0,0 +1,143 @@
+// Package testfiles lays out the files of a migrated assessment test: the
+// test itself and a file for each item it references, with item ref
+// identifiers and file names kept distinct.
+package testfiles
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/qti-migrator/internal/assets"
+	"github.com/qti-migrator/pkg/models"
+)
+
+// The single test part of a test written without test parts of its own
+const (
+	DefaultTestPart       = "testPart1"
+	DefaultNavigationMode = "linear"
+	DefaultSubmissionMode = "individual"
+)
+
+// Items collects the item files a test references.
+type Items struct {
+	// Directory of the item files, relative to the test
+	directory string
+	files     []models.File
+	// Item file names and item ref identifiers in use
+	hrefs map[string]bool
+	refs  map[string]bool
+	err   error
+}
+
+// NewItems returns an empty collection of item files written to directory.
+func NewItems(directory string) *Items {
+	return &Items{
+		directory: directory,
+		hrefs:     make(map[string]bool),
+		refs:      make(map[string]bool),
+	}
+}
+
+// Reserve marks the identifier of an item ref that points to a file of its
+// own as in use.
+func (it *Items) Reserve(identifier string) {
+	it.refs[identifier] = true
+}
+
+// Add writes item to a file of its own with write and returns the
+// identifier and href of the item ref to it. Items sharing an identifier get
+// distinct refs and files.
+func (it *Items) Add(item *models.Item, write func(*models.Item) ([]byte, error)) (identifier, href string) {
+	number := strconv.Itoa(len(it.files) + 1)
+	identifier = unique(OrDefault(item.Ident, "item"+number), it.refs)
+	name := unique(FileName(identifier, "item"+number), it.hrefs)
+	it.refs[identifier] = true
+	it.hrefs[name] = true
+	href = path.Join(it.directory, name+".xml")
+
+	content, err := write(item)
+	if err != nil && it.err == nil {
+		it.err = fmt.Errorf("item %s: %w", item.Ident, err)
+	}
+	// Images and stylesheets stay where they are, next to the test
+	it.files = append(it.files, models.File{
+		Href:       href,
+		Identifier: item.Ident,
+		Kind:       models.FileItem,
+		Content:    assets.Rebase(content, it.directory),
+		Assets:     assets.List(content),
+	})
+	return identifier, href
+}
+
+// Len returns the number of item files.
+func (it *Items) Len() int {
+	return len(it.files)
+}
+
+// Err returns the first error writing an item file.
+func (it *Items) Err() error {
+	return it.err
+}
+
+// Files marshals test and returns its file, which depends on every item
+// file, followed by the item files.
+func (it *Items) Files(identifier string, test interface{}) ([]models.File, error) {
+	content, err := xml.MarshalIndent(test, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal migrated test: %w", err)
+	}
+
+	testFile := models.File{
+		Href:       FileName(identifier, "test") + ".xml",
+		Identifier: identifier,
+		Kind:       models.FileTest,
+		Content:    append([]byte(xml.Header), content...),
+	}
+	for _, item := range it.files {
+		testFile.Dependencies = append(testFile.Dependencies, item.Href)
+	}
+	return append([]models.File{testFile}, it.files...), nil
+}
+
+// unique returns name, or name with the lowest numeric suffix that is not in
+// used.
+func unique(name string, used map[string]bool) string {
+	if !used[name] {
+		return name
+	}
+	for i := 2; ; i++ {
+		candidate := name + "-" + strconv.Itoa(i)
+		if !used[candidate] {
+			return candidate
+		}
+	}
+}
+
+// FileName turns an identifier into a file name without extension, keeping
+// letters, digits, dots, dashes and underscores.
+func FileName(identifier, fallback string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, identifier)
+	if strings.Trim(name, "._") == "" {
+		return fallback
+	}
+	return name
+}
+
+// OrDefault returns value, or fallback when value is empty.
+func OrDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,75 @@
+package testfiles
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/qti-migrator/pkg/models"
+)
+
+func TestItems(t *testing.T) {
+	write := func(item *models.Item) ([]byte, error) {
+		if item.Ident == "broken" {
+			return nil, errors.New("cannot write")
+		}
+		return []byte(`<assessmentItem identifier="` + item.Ident + `"><img src="a.png"/></assessmentItem>`), nil
+	}
+
+	items := NewItems("items")
+	items.Reserve("q1")
+	refs := [][2]string{}
+	for _, ident := range []string{"q1", "q1", "", "a/b", "broken"} {
+		identifier, href := items.Add(&models.Item{Ident: ident}, write)
+		refs = append(refs, [2]string{identifier, href})
+	}
+
+	expected := [][2]string{
+		{"q1-2", "items/q1-2.xml"},
+		{"q1-3", "items/q1-3.xml"},
+		{"item3", "items/item3.xml"},
+		{"a/b", "items/a_b.xml"},
+		{"broken", "items/broken.xml"},
+	}
+	for i, ref := range refs {
+		if ref != expected[i] {
+			t.Errorf("Expected item ref %d to be %v, got %v", i+1, expected[i], ref)
+		}
+	}
+	if err := items.Err(); err == nil || !strings.Contains(err.Error(), "item broken") {
+		t.Errorf("Expected the error of the broken item, got %v", err)
+	}
+
+	type test struct {
+		XMLName    xml.Name `xml:"assessmentTest"`
+		Identifier string   `xml:"identifier,attr"`
+	}
+	files, err := items.Files("my test", test{Identifier: "my test"})
+	if err != nil {
+		t.Fatalf("Files failed: %v", err)
+	}
+	if len(files) != items.Len()+1 || files[0].Href != "my_test.xml" || files[0].Kind != models.FileTest {
+		t.Fatalf("Expected the test file first, got %+v", files[0])
+	}
+	if len(files[0].Dependencies) != items.Len() || files[0].Dependencies[0] != "items/q1-2.xml" {
+		t.Errorf("Expected the test to depend on its items, got %v", files[0].Dependencies)
+	}
+	if !strings.Contains(string(files[1].Content), `<img src="../a.png"/>`) {
+		t.Errorf("Expected item assets to be rebased, got %s", files[1].Content)
+	}
+}
+
+func TestFileName(t *testing.T) {
+	tests := map[string]string{
+		"item-1.v2": "item-1.v2",
+		"a b/c":     "a_b_c",
+		"..":        "fallback",
+		"":          "fallback",
+	}
+	for identifier, expected := range tests {
+		if name := FileName(identifier, "fallback"); name != expected {
+			t.Errorf("FileName(%q) = %q, expected %q", identifier, name, expected)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package xhtml
+
+import "strings"
+
+type tokenType int
+
+const (
+	textToken tokenType = iota
+	startTagToken
+	endTagToken
+	commentToken
+	// Doctypes, processing instructions and other <!...> declarations
+	declarationToken
+)
+
+type attribute struct {
+	name     string
+	value    string
+	hasValue bool
+	quoted   bool
+}
+
+type token struct {
+	kind tokenType
+	// The token exactly as written
+	raw string
+	// Tag name as written, or the text of a text token
+	data        string
+	attrs       []attribute
+	selfClosing bool
+}
+
+// tokenizer splits HTML into tags and text the way a lenient browser would:
+// a < that does not start a tag is text, unterminated tags end the input,
+// and script and style content is read as raw text.
+type tokenizer struct {
+	input string
+	pos   int
+	// Element whose content is raw text up to its end tag
+	rawText string
+}
+
+func newTokenizer(input string) *tokenizer {
+	return &tokenizer{input: input}
+}
+
+func (z *tokenizer) next() (token, bool) {
+	if z.pos >= len(z.input) {
+		return token{}, false
+	}
+
+	if z.rawText != "" {
+		return z.readRawText(), true
+	}
+
+	start := z.pos
+	rest := z.input[z.pos:]
+	switch {
+	case strings.HasPrefix(rest, "<!--"):
+		end := strings.Index(rest[4:], "-->")
+		if end < 0 {
+			z.pos = len(z.input)
+		} else {
+			z.pos += 4 + end + 3
+		}
+		return token{kind: commentToken, raw: z.input[start:z.pos]}, true
+	case strings.HasPrefix(rest, "<![CDATA["):
+		end := strings.Index(rest, "]]>")
+		if end < 0 {
+			z.pos = len(z.input)
+			return token{kind: textToken, raw: rest, data: rest[9:]}, true
+		}
+		z.pos += end + 3
+		return token{kind: textToken, raw: rest[:end+3], data: rest[9:end]}, true
+	case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+		z.skipPast('>')
+		return token{kind: declarationToken, raw: z.input[start:z.pos]}, true
+	case strings.HasPrefix(rest, "</") && len(rest) > 2 && isLetter(rest[2]):
+		z.pos += 2
+		name := z.readName()
+		z.skipPast('>')
+		return token{kind: endTagToken, raw: z.input[start:z.pos], data: name}, true
+	case strings.HasPrefix(rest, "<") && len(rest) > 1 && isLetter(rest[1]):
+		return z.readStartTag(), true
+	}
+
+	// Text runs to the next < that could start markup
+	end := 1
+	for end < len(rest) && rest[end] != '<' {
+		end++
+	}
+	z.pos += end
+	return token{kind: textToken, raw: rest[:end], data: rest[:end]}, true
+}
+
+func (z *tokenizer) readStartTag() token {
+	start := z.pos
+	z.pos++
+	tok := token{kind: startTagToken, data: z.readName()}
+
+	for z.pos < len(z.input) {
+		z.skipSpace()
+		if z.pos >= len(z.input) {
+			break
+		}
+		switch c := z.input[z.pos]; {
+		case c == '>':
+			z.pos++
+			tok.raw = z.input[start:z.pos]
+			z.enterRawText(tok)
+			return tok
+		case c == '/':
+			z.pos++
+			if z.pos < len(z.input) && z.input[z.pos] == '>' {
+				z.pos++
+				tok.selfClosing = true
+				tok.raw = z.input[start:z.pos]
+				return tok
+			}
+		default:
+			tok.attrs = append(tok.attrs, z.readAttribute())
+		}
+	}
+
+	tok.raw = z.input[start:z.pos]
+	return tok
+}
+
+func (z *tokenizer) readAttribute() attribute {
+	start := z.pos
+	for z.pos < len(z.input) && !isSpace(z.input[z.pos]) && !strings.ContainsRune("/>=", rune(z.input[z.pos])) {
+		z.pos++
+	}
+	if z.pos == start {
+		// A stray = or quote; skip it as a nameless attribute
+		z.pos++
+	}
+	attr := attribute{name: z.input[start:z.pos]}
+
+	z.skipSpace()
+	if z.pos >= len(z.input) || z.input[z.pos] != '=' {
+		return attr
+	}
+	z.pos++
+	z.skipSpace()
+	attr.hasValue = true
+	if z.pos >= len(z.input) {
+		return attr
+	}
+
+	if quote := z.input[z.pos]; quote == '"' || quote == '\'' {
+		end := strings.IndexByte(z.input[z.pos+1:], quote)
+		if end < 0 {
+			attr.value = z.input[z.pos+1:]
+			z.pos = len(z.input)
+		} else {
+			attr.value = z.input[z.pos+1 : z.pos+1+end]
+			z.pos += end + 2
+		}
+		attr.quoted = true
+		return attr
+	}
+
+	start = z.pos
+	for z.pos < len(z.input) && !isSpace(z.input[z.pos]) && z.input[z.pos] != '>' {
+		z.pos++
+	}
+	attr.value = z.input[start:z.pos]
+	return attr
+}
+
+func (z *tokenizer) enterRawText(tok token) {
+	switch name := strings.ToLower(tok.data); name {
+	case "script", "style":
+		z.rawText = name
+	}
+}
+
+func (z *tokenizer) readRawText() token {
+	rest := z.input[z.pos:]
+	end := strings.Index(strings.ToLower(rest), "</"+z.rawText)
+	z.rawText = ""
+	if end < 0 {
+		end = len(rest)
+	}
+	z.pos += end
+	return token{kind: textToken, raw: rest[:end], data: rest[:end]}
+}
+
+func (z *tokenizer) readName() string {
+	start := z.pos
+	for z.pos < len(z.input) && !isSpace(z.input[z.pos]) && z.input[z.pos] != '/' && z.input[z.pos] != '>' {
+		z.pos++
+	}
+	return z.input[start:z.pos]
+}
+
+func (z *tokenizer) skipSpace() {
+	for z.pos < len(z.input) && isSpace(z.input[z.pos]) {
+		z.pos++
+	}
+}
+
+func (z *tokenizer) skipPast(c byte) {
+	end := strings.IndexByte(z.input[z.pos:], c)
+	if end < 0 {
+		z.pos = len(z.input)
+		return
+	}
+	z.pos += end + 1
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
@@ -0,0 +1,476 @@
+// Package xhtml turns the loose HTML found in QTI 1.2 mattext into
+// well-formed XHTML that may appear in a QTI 2.x item body.
+package xhtml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Fix describes one change made to the HTML. Replacement is empty when
+// something was removed.
+type Fix struct {
+	Original    string
+	Replacement string
+	Description string
+}
+
+// Elements of the XHTML subset allowed in QTI item bodies
+var allowedElements = setOf(
+	"a", "abbr", "acronym", "address", "b", "big", "blockquote", "br", "caption", "cite", "code",
+	"col", "colgroup", "dd", "dfn", "div", "dl", "dt", "em", "h1", "h2", "h3", "h4", "h5", "h6",
+	"hr", "i", "img", "kbd", "li", "object", "ol", "p", "param", "pre", "q", "samp", "small",
+	"span", "strong", "sub", "sup", "table", "tbody", "td", "tfoot", "th", "thead", "tr", "tt",
+	"ul", "var", "math",
+)
+
+// Disallowed elements whose content is dropped with them; the content of
+// any other disallowed element is kept.
+var droppedElements = setOf("script", "style", "noscript", "iframe", "frame", "frameset",
+	"head", "title", "applet", "embed", "select", "textarea", "button")
+
+var voidElements = setOf("area", "base", "br", "col", "embed", "hr", "img", "input", "link",
+	"meta", "param", "source", "track", "wbr")
+
+// Elements whose start implicitly closes an open paragraph
+var closesParagraph = setOf("address", "blockquote", "div", "dl", "h1", "h2", "h3", "h4", "h5",
+	"h6", "hr", "ol", "p", "pre", "table", "ul")
+
+// implicitEnds lists, for each element, the open elements it closes and the
+// elements that stop the search for them.
+var implicitEnds = map[string]struct{ closes, boundaries map[string]bool }{
+	"li":    {setOf("li"), setOf("ul", "ol")},
+	"dt":    {setOf("dt", "dd"), setOf("dl")},
+	"dd":    {setOf("dt", "dd"), setOf("dl")},
+	"tr":    {setOf("tr"), setOf("table", "thead", "tbody", "tfoot")},
+	"td":    {setOf("td", "th"), setOf("tr", "table")},
+	"th":    {setOf("td", "th"), setOf("tr", "table")},
+	"thead": {setOf("thead", "tbody", "tfoot"), setOf("table")},
+	"tbody": {setOf("thead", "tbody", "tfoot"), setOf("table")},
+	"tfoot": {setOf("thead", "tbody", "tfoot"), setOf("table")},
+}
+
+// Elements that stop the search for an open paragraph
+var paragraphBoundaries = setOf("table", "td", "th", "caption", "object", "li", "dd", "dt", "blockquote", "div")
+
+type openElement struct {
+	name    string
+	dropped bool
+}
+
+type converter struct {
+	out     strings.Builder
+	stack   []openElement
+	dropped int
+	math    int
+	fixes   []Fix
+	seen    map[Fix]bool
+	// Elements kept as written, see ConvertKeeping
+	keep func(name string) bool
+}
+
+// Convert returns html as well-formed XHTML together with the fixes that
+// were needed: tag and attribute names are lowercased, attribute values
+// quoted, void elements self-closed, unclosed elements closed, named
+// entities replaced by character references, and elements that QTI item
+// bodies do not allow removed. Identical fixes are reported once.
+func Convert(html string) (string, []Fix) {
+	return ConvertKeeping(html, nil)
+}
+
+// ConvertKeeping converts html like Convert, but keeps the elements keep
+// reports, such as QTI interactions inside item body markup, with their
+// names and attributes as written.
+func ConvertKeeping(html string, keep func(name string) bool) (string, []Fix) {
+	c := &converter{seen: make(map[Fix]bool), keep: keep}
+	z := newTokenizer(html)
+	for {
+		tok, ok := z.next()
+		if !ok {
+			break
+		}
+		switch tok.kind {
+		case textToken:
+			c.text(tok)
+		case startTagToken:
+			c.startTag(tok)
+		case endTagToken:
+			c.endTag(tok)
+		case commentToken:
+			c.fix(tok.raw, "", "Remove comment")
+		case declarationToken:
+			c.fix(tok.raw, "", "Remove declaration, which is not allowed in an item body")
+		}
+	}
+
+	for len(c.stack) > 0 {
+		c.closeTop(fmt.Sprintf("Close <%s> left open at the end of the content", c.stack[len(c.stack)-1].name))
+	}
+	return c.out.String(), c.fixes
+}
+
+// HasBlocks tells whether content contains block elements such as
+// paragraphs, lists or tables, and so cannot be wrapped in a paragraph.
+func HasBlocks(content string) bool {
+	z := newTokenizer(content)
+	for {
+		tok, ok := z.next()
+		if !ok {
+			return false
+		}
+		if tok.kind == startTagToken && closesParagraph[strings.ToLower(tok.data)] {
+			return true
+		}
+	}
+}
+
+// LooksLikeHTML tells whether text that is not marked as HTML, such as a
+// text/plain mattext an LMS exported, still holds HTML: tags of HTML
+// elements or named character references such as &nbsp;.
+func LooksLikeHTML(text string) bool {
+	z := newTokenizer(text)
+	for {
+		tok, ok := z.next()
+		if !ok {
+			return false
+		}
+		switch tok.kind {
+		case startTagToken, endTagToken:
+			name := strings.ToLower(tok.data)
+			if allowedElements[name] || droppedElements[name] || voidElements[name] {
+				return true
+			}
+		case textToken:
+			if hasNamedEntity(tok.data) {
+				return true
+			}
+		}
+	}
+}
+
+// hasNamedEntity tells whether text holds an HTML named character reference
+// that XML does not predefine.
+func hasNamedEntity(text string) bool {
+	for i := strings.IndexByte(text, '&'); i >= 0; i = strings.IndexByte(text, '&') {
+		text = text[i+1:]
+		if end := strings.IndexByte(text, ';'); end > 0 {
+			if _, ok := entities[text[:end]]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *converter) text(tok token) {
+	if c.dropped > 0 {
+		return
+	}
+	c.out.WriteString(c.escape(tok.data, false))
+}
+
+func (c *converter) startTag(tok token) {
+	name := c.elementName(tok.data)
+
+	if c.dropped > 0 {
+		if !tok.selfClosing && !voidElements[name] {
+			c.stack = append(c.stack, openElement{name: name, dropped: true})
+			c.dropped++
+		}
+		return
+	}
+
+	if c.math == 0 {
+		if droppedElements[name] {
+			c.fix(tok.raw, "", fmt.Sprintf("Remove <%s> and its content, which are not allowed in an item body", name))
+			if !tok.selfClosing && !voidElements[name] {
+				c.stack = append(c.stack, openElement{name: name, dropped: true})
+				c.dropped++
+			}
+			return
+		}
+		if !allowedElements[name] && !c.kept(name) {
+			if voidElements[name] || tok.selfClosing {
+				c.fix(tok.raw, "", fmt.Sprintf("Remove <%s>, which is not allowed in an item body", name))
+			} else {
+				c.fix(tok.raw, "", fmt.Sprintf("Remove <%s> tag, which is not allowed in an item body; its content is kept", name))
+			}
+			return
+		}
+		c.closeImplicitly(name)
+	}
+
+	tag := "<" + name + c.attributes(tok.attrs, c.math == 0 && !c.kept(name))
+	switch {
+	case voidElements[name]:
+		tag += "/>"
+		if !tok.selfClosing {
+			c.fix(tok.raw, tag, fmt.Sprintf("Self-close void element <%s>", name))
+		}
+	case tok.selfClosing:
+		tag += "/>"
+	default:
+		tag += ">"
+		c.stack = append(c.stack, openElement{name: name})
+		if name == "math" {
+			c.math++
+		}
+	}
+
+	if tok.data != name && c.math == 0 {
+		c.fix("<"+tok.data+">", "<"+name+">", "Lowercase tag name")
+	}
+	c.out.WriteString(tag)
+}
+
+func (c *converter) endTag(tok token) {
+	name := c.elementName(tok.data)
+
+	index := -1
+	for i := len(c.stack) - 1; i >= 0; i-- {
+		if c.stack[i].name == name {
+			index = i
+			break
+		}
+	}
+
+	if index < 0 {
+		switch {
+		case c.dropped > 0:
+		case voidElements[name]:
+			c.fix(tok.raw, "", fmt.Sprintf("Remove end tag of void element <%s>", name))
+		case c.math == 0 && !c.kept(name) && (!allowedElements[name] || droppedElements[name]):
+			// The start tag was already removed and reported
+		default:
+			c.fix(tok.raw, "", fmt.Sprintf("Remove </%s> without a matching start tag", name))
+		}
+		return
+	}
+
+	closed := c.stack[index]
+	for len(c.stack)-1 > index {
+		c.closeTop(fmt.Sprintf("Close <%s> before </%s>", c.stack[len(c.stack)-1].name, name))
+	}
+	c.pop()
+	if !closed.dropped && c.dropped == 0 && c.math == 0 && tok.data != name {
+		c.fix("</"+tok.data+">", "</"+name+">", "Lowercase tag name")
+	}
+}
+
+// closeImplicitly closes the elements that HTML ends when name starts, such
+// as an open <p> before a <div> or an open <li> before the next <li>.
+func (c *converter) closeImplicitly(name string) {
+	if closesParagraph[name] {
+		c.closeOpen(setOf("p"), paragraphBoundaries, name)
+	}
+	if ends, ok := implicitEnds[name]; ok {
+		c.closeOpen(ends.closes, ends.boundaries, name)
+	}
+}
+
+func (c *converter) closeOpen(closes, boundaries map[string]bool, name string) {
+	for i := len(c.stack) - 1; i >= 0; i-- {
+		open := c.stack[i].name
+		if closes[open] {
+			for len(c.stack) > i {
+				c.closeTop(fmt.Sprintf("Close <%s> implicitly ended by <%s>", c.stack[len(c.stack)-1].name, name))
+			}
+			return
+		}
+		if boundaries[open] {
+			return
+		}
+	}
+}
+
+// closeTop writes the end tag of the innermost open element.
+func (c *converter) closeTop(description string) {
+	top := c.stack[len(c.stack)-1]
+	if !top.dropped && c.dropped == 0 {
+		c.fix("<"+top.name+">", "</"+top.name+">", description)
+	}
+	c.pop()
+}
+
+func (c *converter) pop() {
+	top := c.stack[len(c.stack)-1]
+	c.stack = c.stack[:len(c.stack)-1]
+	switch {
+	case top.dropped:
+		c.dropped--
+	case c.dropped == 0:
+		c.out.WriteString("</" + top.name + ">")
+		if top.name == "math" {
+			c.math--
+		}
+	}
+}
+
+// elementName lowercases HTML names; MathML and kept elements keep their
+// own case.
+func (c *converter) elementName(name string) string {
+	if c.math > 0 || c.kept(name) {
+		return name
+	}
+	return strings.ToLower(name)
+}
+
+func (c *converter) kept(name string) bool {
+	return c.keep != nil && c.keep(name)
+}
+
+// attributes renders the attributes of a start tag, with lowercased names
+// when lower is set.
+func (c *converter) attributes(attrs []attribute, lower bool) string {
+	var out strings.Builder
+	seen := make(map[string]bool)
+	for _, attr := range attrs {
+		name := attr.name
+		if lower {
+			name = strings.ToLower(name)
+		}
+		original := attr.name
+		if attr.hasValue {
+			original += "=" + attr.value
+			if attr.quoted {
+				original = attr.name + `="` + attr.value + `"`
+			}
+		}
+
+		switch {
+		case !isXMLName(name):
+			c.fix(original, "", "Remove attribute with an invalid name")
+			continue
+		case seen[name]:
+			c.fix(original, "", fmt.Sprintf("Remove duplicate attribute %s", name))
+			continue
+		case strings.HasPrefix(name, "on") && c.math == 0:
+			c.fix(original, "", fmt.Sprintf("Remove event handler attribute %s", name))
+			continue
+		}
+		seen[name] = true
+
+		value := attr.value
+		if !attr.hasValue {
+			value = name
+		}
+		rendered := name + `="` + c.escape(value, true) + `"`
+		switch {
+		case !attr.hasValue:
+			c.fix(original, rendered, "Give minimized attribute a value")
+		case !attr.quoted:
+			c.fix(original, rendered, "Quote attribute value")
+		case name != attr.name:
+			c.fix(original, rendered, "Lowercase attribute name")
+		}
+		out.WriteString(" " + rendered)
+	}
+	return out.String()
+}
+
+// escape makes text or an attribute value safe for XML: named HTML
+// entities become character references, and &, < and (in attributes) "
+// that are not part of a reference are escaped.
+func (c *converter) escape(text string, attribute bool) string {
+	var out strings.Builder
+	for i := 0; i < len(text); {
+		switch ch := text[i]; {
+		case ch == '&':
+			reference, length := c.reference(text[i:])
+			out.WriteString(reference)
+			i += length
+		case ch == '<':
+			c.fix("<", "&lt;", "Escape < that does not start a tag")
+			out.WriteString("&lt;")
+			i++
+		case ch == '"' && attribute:
+			out.WriteString("&quot;")
+			i++
+		default:
+			r, size := utf8.DecodeRuneInString(text[i:])
+			if !isXMLChar(r) || (r == utf8.RuneError && size == 1) {
+				c.fix(text[i:i+size], "", "Remove character that is not allowed in XML")
+			} else {
+				out.WriteString(text[i : i+size])
+			}
+			i += size
+		}
+	}
+	return out.String()
+}
+
+// reference converts the character reference at the start of text and
+// returns it with the number of bytes it used.
+func (c *converter) reference(text string) (string, int) {
+	end := strings.IndexByte(text, ';')
+	if end > 1 && end <= 32 {
+		name := text[1:end]
+		switch {
+		case name == "amp" || name == "lt" || name == "gt" || name == "quot" || name == "apos":
+			return text[:end+1], end + 1
+		case strings.HasPrefix(name, "#"):
+			if code, ok := parseCharRef(name[1:]); ok && isXMLChar(rune(code)) {
+				return text[:end+1], end + 1
+			}
+		default:
+			if code, ok := entities[name]; ok {
+				replacement := "&#" + strconv.Itoa(code) + ";"
+				c.fix(text[:end+1], replacement, "Replace named entity with a character reference")
+				return replacement, end + 1
+			}
+		}
+	}
+
+	c.fix("&", "&amp;", "Escape & that does not start a character reference")
+	return "&amp;", 1
+}
+
+func (c *converter) fix(original, replacement, description string) {
+	fix := Fix{Original: original, Replacement: replacement, Description: description}
+	if c.seen[fix] {
+		return
+	}
+	c.seen[fix] = true
+	c.fixes = append(c.fixes, fix)
+}
+
+func parseCharRef(ref string) (int64, bool) {
+	var code int64
+	var err error
+	if strings.HasPrefix(ref, "x") || strings.HasPrefix(ref, "X") {
+		code, err = strconv.ParseInt(ref[1:], 16, 32)
+	} else {
+		code, err = strconv.ParseInt(ref, 10, 32)
+	}
+	return code, err == nil
+}
+
+func isXMLChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		r >= 0x20 && r <= 0xD7FF || r >= 0xE000 && r <= 0xFFFD || r >= 0x10000 && r <= 0x10FFFF
+}
+
+func isXMLName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_' || r == ':' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z':
+		case i > 0 && (r == '-' || r == '.' || '0' <= r && r <= '9'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func setOf(names ...string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
@@ -0,0 +1,141 @@
+package xhtml
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"void element", "Line 1<br>Line 2", "Line 1<br/>Line 2"},
+		{"closed void element", `<img src="test.jpg"/>`, `<img src="test.jpg"/>`},
+		{"void element among other tags", `<p>See <img src="a.png"> and <b>this</b></p>`, `<p>See <img src="a.png"/> and <b>this</b></p>`},
+		{"unclosed paragraphs", "<p>First<p>Second", "<p>First</p><p>Second</p>"},
+		{"paragraph ended by block", "<p>Intro<ul><li>a</ul>", "<p>Intro</p><ul><li>a</li></ul>"},
+		{"unclosed list items", "<ul><li>a<li>b</ul>", "<ul><li>a</li><li>b</li></ul>"},
+		{"unclosed table cells", "<table><tr><td>a<td>b<tr><td>c</table>", "<table><tr><td>a</td><td>b</td></tr><tr><td>c</td></tr></table>"},
+		{"uppercase tags", `<P ALIGN="left">Text</P>`, `<p align="left">Text</p>`},
+		{"unquoted attributes", `<img src=a.png width=20>`, `<img src="a.png" width="20"/>`},
+		{"minimized attribute", `<ol compact>`, `<ol compact="compact"></ol>`},
+		{"named entities", "A&nbsp;&copy;&eacute;", "A&#160;&#169;&#233;"},
+		{"XML entities", "Tom &amp; Jerry &lt;3 &#169; &#xA9;", "Tom &amp; Jerry &lt;3 &#169; &#xA9;"},
+		{"bare ampersand", "Q&A &unknown;", "Q&amp;A &amp;unknown;"},
+		{"stray less-than", "1 < 2", "1 &lt; 2"},
+		{"quote in attribute", `<span title='say "hi"'>x</span>`, `<span title="say &quot;hi&quot;">x</span>`},
+		{"disallowed element unwrapped", `<font color="red">Red</font> <center>mid</center>`, "Red mid"},
+		{"disallowed element dropped", `a<script>if (a<b) alert(1)</script>b<style>p {}</style>c`, "abc"},
+		{"event handler", `<div onclick="x()">d</div>`, `<div>d</div>`},
+		{"unmatched end tags", "<p>a</b></p></br>", "<p>a</p>"},
+		{"misnested tags", "<b><i>x</b></i>", "<b><i>x</i></b>"},
+		{"comment and declaration", "<!DOCTYPE html><!-- note -->text", "text"},
+		{"Word markup", `<o:p></o:p>text`, "text"},
+		{"MathML", `<math><mi>x</mi><annotation-xml encoding="MathML-Content"/></math>`, `<math><mi>x</mi><annotation-xml encoding="MathML-Content"/></math>`},
+		{"CDATA", "<![CDATA[a < b]]>", "a &lt; b"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, _ := Convert(tc.input)
+			if result != tc.expected {
+				t.Errorf("Convert(%q): expected %q, got %q", tc.input, tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestConvert_WellFormed(t *testing.T) {
+	inputs := []string{
+		`<P>Choose <B>one<BR><IMG SRC=a.gif ALT=pic>&nbsp;<UL><LI>x<LI>y`,
+		`<table border=1><tr><th>a<td>b</table><p>&bogus & <i>unclosed`,
+		`<div><span>a</div></span><input type=text disabled><select><option>x</select>`,
+	}
+
+	for _, input := range inputs {
+		result, _ := Convert(input)
+		decoder := xml.NewDecoder(strings.NewReader("<root>" + result + "</root>"))
+		for {
+			_, err := decoder.Token()
+			if err != nil {
+				if err != io.EOF {
+					t.Errorf("Convert(%q) is not well-formed: %v\n%s", input, err, result)
+				}
+				break
+			}
+		}
+	}
+}
+
+func TestConvert_Fixes(t *testing.T) {
+	_, fixes := Convert(`<P>A&nbsp;B&nbsp;C<img src=a.png>`)
+
+	expected := []Fix{
+		{Original: "<P>", Replacement: "<p>", Description: "Lowercase tag name"},
+		{Original: "&nbsp;", Replacement: "&#160;", Description: "Replace named entity with a character reference"},
+		{Original: "src=a.png", Replacement: `src="a.png"`, Description: "Quote attribute value"},
+		{Original: "<img src=a.png>", Replacement: `<img src="a.png"/>`, Description: "Self-close void element <img>"},
+		{Original: "<p>", Replacement: "</p>", Description: "Close <p> left open at the end of the content"},
+	}
+	if len(fixes) != len(expected) {
+		t.Fatalf("Expected %d fixes, got %d: %+v", len(expected), len(fixes), fixes)
+	}
+	for i, fix := range expected {
+		if fixes[i] != fix {
+			t.Errorf("Fix %d: expected %+v, got %+v", i, fix, fixes[i])
+		}
+	}
+
+	if _, fixes := Convert(`<p>Already <b>fine</b> &amp; valid<br/></p>`); len(fixes) != 0 {
+		t.Errorf("Expected no fixes for valid XHTML, got %+v", fixes)
+	}
+}
+
+func TestConvertKeeping(t *testing.T) {
+	keep := func(name string) bool { return name == "textEntryInteraction" }
+	result, _ := ConvertKeeping(`<P>Type <textEntryInteraction responseIdentifier=R1 expectedLength="5"/> here<BR>`, keep)
+
+	expected := `<p>Type <textEntryInteraction responseIdentifier="R1" expectedLength="5"/> here<br/></p>`
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestLooksLikeHTML(t *testing.T) {
+	testCases := map[string]bool{
+		"plain text":           false,
+		"1 < 2 & 3 > 2":        false,
+		"x<y and z>w":          false,
+		"Tom &amp; Jerry":      false,
+		"Price:&nbsp;5":        true,
+		"<b>bold</b>":          true,
+		"Line<BR>break":        true,
+		"<script>x()</script>": true,
+	}
+
+	for input, expected := range testCases {
+		if result := LooksLikeHTML(input); result != expected {
+			t.Errorf("LooksLikeHTML(%q): expected %t, got %t", input, expected, result)
+		}
+	}
+}
+
+func TestHasBlocks(t *testing.T) {
+	testCases := map[string]bool{
+		"plain text":                   false,
+		"<b>bold</b> and <br/> inline": false,
+		"<P>paragraph</P>":             true,
+		"Intro <ul><li>item</li></ul>": true,
+		"<span><table></table></span>": true,
+	}
+
+	for input, expected := range testCases {
+		if result := HasBlocks(input); result != expected {
+			t.Errorf("HasBlocks(%q): expected %t, got %t", input, expected, result)
+		}
+	}
+}
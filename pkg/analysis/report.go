@@ -0,0 +1,60 @@
+// Package analysis holds the report a migration analysis produces: the
+// warnings, errors and changes found for each item before it is migrated.
+package analysis
+
+// Report is the combined analysis of every step of a migration.
+type Report struct {
+	SourceVersion string
+	TargetVersion string
+	// Versions the migration passes through, from source to target
+	Path       []string
+	TotalItems int
+	// Items that no step reports an error on, and the items that steps
+	// report errors on, each counted once
+	CompatibleItems   int
+	IncompatibleItems int
+	Warnings          []Warning
+	Errors            []Error
+	MigrationDetails  []MigrationDetail
+}
+
+type Warning struct {
+	ItemID      string
+	ElementPath string
+	Message     string
+	Suggestion  string
+	// Step of a multi-step migration, e.g. "2.1 → 3.0"
+	Step string
+	// ID of the consistency rule that found the problem, e.g.
+	// duplicate-identifier
+	Rule string
+}
+
+type Error struct {
+	ItemID      string
+	ElementPath string
+	Message     string
+	Fatal       bool
+	Step        string
+	Rule        string
+}
+
+type MigrationDetail struct {
+	ItemID      string
+	ElementPath string
+	OldValue    string
+	NewValue    string
+	Action      string
+	Description string
+	Step        string
+}
+
+// HasErrors tells whether the report holds an error that blocks migration.
+func (r *Report) HasErrors() bool {
+	for _, err := range r.Errors {
+		if err.Fatal {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"encoding/xml"
+	"strings"
 	"time"
 )
 
@@ -150,6 +151,7 @@ type MatImage struct {
 	URI        string   `xml:"uri,attr"`
 	Width      int      `xml:"width,attr,omitempty"`
 	Height     int      `xml:"height,attr,omitempty"`
+	Label      string   `xml:"label,attr,omitempty"`
 }
 
 type MatAudio struct {
@@ -179,4 +181,70 @@ type RubricBlock struct {
 	Use     string    `xml:"use,attr,omitempty"`
 	View    string    `xml:"view,attr,omitempty"`
 	Content string    `xml:",innerxml"`
-}
\ No newline at end of file
+}
+
+// XMLNode is a generic element tree used where QTI defines open-ended
+// expression languages, such as 1.2 conditions and 2.x response rules.
+// Element names are stored without namespace.
+type XMLNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Value    string     `xml:",chardata"`
+	Children []XMLNode  `xml:",any"`
+}
+
+// NewXMLNode creates an element with the given attributes (name/value pairs)
+// and children.
+func NewXMLNode(name string, attrs []string, children ...XMLNode) XMLNode {
+	node := XMLNode{XMLName: xml.Name{Local: name}, Children: children}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		node.Attrs = append(node.Attrs, xml.Attr{Name: xml.Name{Local: attrs[i]}, Value: attrs[i+1]})
+	}
+	return node
+}
+
+// Attr returns the value of the named attribute.
+func (n XMLNode) Attr(name string) string {
+	for _, attr := range n.Attrs {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// UnmarshalXML decodes the element tree, dropping namespace declarations and
+// the whitespace between child elements.
+func (n *XMLNode) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	n.XMLName = xml.Name{Local: start.Name.Local}
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+			continue
+		}
+		n.Attrs = append(n.Attrs, attr)
+	}
+
+	var value []byte
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			var child XMLNode
+			if err := child.UnmarshalXML(d, t); err != nil {
+				return err
+			}
+			n.Children = append(n.Children, child)
+		case xml.CharData:
+			value = append(value, t...)
+		case xml.EndElement:
+			n.Value = string(value)
+			if len(n.Children) > 0 {
+				n.Value = strings.TrimSpace(n.Value)
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package models
+
+// Kinds of migrated files
+const (
+	FileItem = "item"
+	FileTest = "test"
+)
+
+// File is one document of a migration that writes several files, such as an
+// assessment test and the item files it references.
+type File struct {
+	// Path of the file, relative to the test that references it
+	Href       string
+	Identifier string
+	// FileItem or FileTest
+	Kind    string
+	Content []byte
+	// Hrefs of the item files a test references
+	Dependencies []string
+	// Hrefs of the images, media and stylesheets an item uses, relative to
+	// the test like Href
+	Assets []string
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// Body elements kept as markup hold namespace declarations in their Attrs.
+// encoding/xml decodes xmlns:m as an attribute in the "xmlns" namespace,
+// which it writes back as _xmlns:m, so declarations are stored as plain
+// xmlns:m attributes instead.
+
+const xmlnsPrefix = "xmlns"
+
+// markupAttrs stores the prefix declarations of attrs as plain xmlns:prefix
+// attributes and drops default namespace declarations, which are written from
+// the element name.
+func markupAttrs(attrs []xml.Attr) []xml.Attr {
+	var kept []xml.Attr
+	for _, attr := range attrs {
+		switch {
+		case attr.Name.Space == "" && attr.Name.Local == xmlnsPrefix:
+			continue
+		case attr.Name.Space == xmlnsPrefix:
+			attr.Name = xml.Name{Local: xmlnsPrefix + ":" + attr.Name.Local}
+		}
+		kept = append(kept, attr)
+	}
+	return kept
+}
+
+// withoutDeclarations returns attrs without their namespace declarations.
+func withoutDeclarations(attrs []xml.Attr) []xml.Attr {
+	var kept []xml.Attr
+	for _, attr := range attrs {
+		if _, ok := declaredPrefix(attr); ok || attr.Name.Space == "" && attr.Name.Local == xmlnsPrefix {
+			continue
+		}
+		kept = append(kept, attr)
+	}
+	return kept
+}
+
+// namespaceDeclarations returns the prefixes attrs declare, mapped to their
+// namespace.
+func namespaceDeclarations(attrs []xml.Attr) map[string]string {
+	declarations := make(map[string]string)
+	for _, attr := range attrs {
+		if prefix, ok := declaredPrefix(attr); ok {
+			declarations[prefix] = attr.Value
+		}
+	}
+	return declarations
+}
+
+func declaredPrefix(attr xml.Attr) (string, bool) {
+	if attr.Name.Space == xmlnsPrefix {
+		return attr.Name.Local, true
+	}
+	if attr.Name.Space == "" && strings.HasPrefix(attr.Name.Local, xmlnsPrefix+":") {
+		return strings.TrimPrefix(attr.Name.Local, xmlnsPrefix+":"), true
+	}
+	return "", false
+}
+
+// declarePrefixes adds to attrs a declaration of every prefix of scope that
+// content uses outside of its own declarations and attrs does not declare.
+func declarePrefixes(attrs []xml.Attr, content string, scope map[string]string) []xml.Attr {
+	declared := namespaceDeclarations(attrs)
+	for _, prefix := range undeclaredPrefixes(content) {
+		namespace, ok := scope[prefix]
+		if _, done := declared[prefix]; done || !ok {
+			continue
+		}
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: xmlnsPrefix + ":" + prefix}, Value: namespace})
+		declared[prefix] = namespace
+	}
+	return attrs
+}
+
+// undeclaredPrefixes returns the prefixes of the element and attribute names
+// of content, in order of first use, that are not declared in content where
+// they are used. It returns what it found up to the first syntax error.
+func undeclaredPrefixes(content string) []string {
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	decoder.Strict = false
+
+	var prefixes []string
+	seen := make(map[string]bool)
+	use := func(prefix string, scopes []map[string]string) {
+		if prefix == "" || prefix == "xml" || prefix == xmlnsPrefix || seen[prefix] {
+			return
+		}
+		for _, scope := range scopes {
+			if _, ok := scope[prefix]; ok {
+				return
+			}
+		}
+		seen[prefix] = true
+		prefixes = append(prefixes, prefix)
+	}
+
+	var scopes []map[string]string
+	for {
+		token, err := decoder.RawToken()
+		if err != nil {
+			return prefixes
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			scopes = append(scopes, namespaceDeclarations(t.Attr))
+			use(t.Name.Space, scopes)
+			for _, attr := range t.Attr {
+				use(attr.Name.Space, scopes)
+			}
+		case xml.EndElement:
+			if len(scopes) > 0 {
+				scopes = scopes[:len(scopes)-1]
+			}
+		}
+	}
+}
@@ -4,9 +4,15 @@ import (
 	"encoding/xml"
 )
 
-// QTIDocument is a generic structure for backward compatibility
-// It's a union of all version-specific fields to support existing code
-// New code should use version-specific structures (QTIDocument12, QTIDocument21, QTIDocument30)
+// QTIDocument is the model parsers produce and migrators consume. It is the
+// QTI 1.2 questestinterop document extended with the fields of QTI 2.x and
+// 3.0: parsers of every version wrap their items and tests in it, items keep
+// the 1.2 Presentation and ResponseProc next to the 2.x declarations and
+// body, and tests are flattened into sections. The 2.x and 3.0 fields the
+// migrators write are carried through; the round-trip test of the 2.1 to 3.0
+// migrator lists the ones that are checked.
+// The version-specific structures (QTIDocument12, QTIDocument21,
+// QTIDocument30) are only used to decode documents.
 type QTIDocument struct {
 	XMLName    xml.Name    `xml:"questestinterop"`
 	Version    string      `xml:"version,attr"`
@@ -23,6 +29,19 @@ type Assessment struct {
 	Metadata    *Metadata    `xml:"metadata,omitempty"`
 	Objectives  []Objective  `xml:"objectives>objective,omitempty"`
 	RubricBlock *RubricBlock `xml:"rubricBlock,omitempty"`
+	// QTI 1.2 test controls
+	MaxAttempts       int                `xml:"maxattempts,attr,omitempty"`
+	Duration          string             `xml:"duration,omitempty"`
+	SelectionOrdering *SelectionOrdering `xml:"selection_ordering,omitempty"`
+	// QTI 2.x test time limits
+	TimeLimits *TimeLimits `xml:"timeLimits,omitempty"`
+	// QTI 2.x/3.0 authoring tool of the test
+	ToolName    string `xml:"toolName,attr,omitempty"`
+	ToolVersion string `xml:"toolVersion,attr,omitempty"`
+	// QTI 2.x test outcomes and the feedback shown on them
+	OutcomeDecl       []OutcomeDecl      `xml:"outcomeDeclaration,omitempty"`
+	OutcomeProcessing *OutcomeProcessing `xml:"outcomeProcessing,omitempty"`
+	TestFeedback      []TestFeedback     `xml:"testFeedback,omitempty"`
 }
 
 type Section struct {
@@ -31,16 +50,77 @@ type Section struct {
 	Ident    string    `xml:"ident,attr"`
 	Items    []Item    `xml:"item"`
 	Metadata *Metadata `xml:"metadata,omitempty"`
+	// QTI 2.x tests reference items stored in separate files
+	Sections []Section `xml:"section,omitempty"`
+	ItemRefs []ItemRef `xml:"assessmentItemRef,omitempty"`
+	// Set on the top-level sections that stand for a QTI 2.x testPart
+	NavigationMode string `xml:"navigationMode,attr,omitempty"`
+	SubmissionMode string `xml:"submissionMode,attr,omitempty"`
+	// Order holds SectionItem or SectionSection for every item and
+	// sub-section in document order, see Children
+	Order []string `xml:"-"`
+	// QTI 1.2 test controls
+	MaxAttempts       int                `xml:"maxattempts,attr,omitempty"`
+	Duration          string             `xml:"duration,omitempty"`
+	SelectionOrdering *SelectionOrdering `xml:"selection_ordering,omitempty"`
+	// Unset for QTI 1.2 sections, which are always shown
+	Visible *bool `xml:"visible,attr,omitempty"`
+	// QTI 2.x test controls of a section or test part
+	ItemSessionControl *ItemSessionControl `xml:"itemSessionControl,omitempty"`
+	TimeLimits         *TimeLimits         `xml:"timeLimits,omitempty"`
+	Selection          *Selection          `xml:"selection,omitempty"`
+	Ordering           *Ordering           `xml:"ordering,omitempty"`
+	RubricBlocks       []RubricBlock       `xml:"rubricBlock,omitempty"`
+	// Feedback of a test part
+	TestFeedback []TestFeedback `xml:"testFeedback,omitempty"`
 }
 
-// Item is a generic structure combining all version fields for backward compatibility
-// Use Item12, Item21, or Item30 for version-specific parsing
+// Section child kinds recorded in Section.Order and Section12.Order
+const (
+	SectionItem    = "item"
+	SectionSection = "section"
+)
+
+// Children returns the items (*Item) and sub-sections (*Section) of the
+// section in document order. Children missing from Order, e.g. in sections
+// built as struct literals, follow the ordered ones, items first.
+func (s *Section) Children() []interface{} {
+	var children []interface{}
+	items, sections := 0, 0
+	for _, kind := range s.Order {
+		switch {
+		case kind == SectionItem && items < len(s.Items):
+			children = append(children, &s.Items[items])
+			items++
+		case kind == SectionSection && sections < len(s.Sections):
+			children = append(children, &s.Sections[sections])
+			sections++
+		}
+	}
+	for ; items < len(s.Items); items++ {
+		children = append(children, &s.Items[items])
+	}
+	for ; sections < len(s.Sections); sections++ {
+		children = append(children, &s.Sections[sections])
+	}
+	return children
+}
+
+// Item is an item of the model. Element names and attributes
+// follow the QTI 2.1 vocabulary; parsers of other versions rename them.
 type Item struct {
 	XMLName        xml.Name        `xml:"item"`
 	Title          string          `xml:"title,attr"`
 	Ident          string          `xml:"ident,attr"`
 	MaxAttempts    int             `xml:"maxattempts,attr,omitempty"`
 	Metadata       *Metadata       `xml:"metadata,omitempty"`
+	// QTI 2.x/3.0 item attributes
+	Adaptive      bool   `xml:"adaptive,attr,omitempty"`
+	TimeDependent bool   `xml:"timeDependent,attr,omitempty"`
+	ToolName      string `xml:"toolName,attr,omitempty"`
+	ToolVersion   string `xml:"toolVersion,attr,omitempty"`
+	// QTI 1.2 fields
+	Duration string `xml:"duration,omitempty"`
 	// QTI 1.2/2.1 fields
 	Presentation   *Presentation   `xml:"presentation,omitempty"`
 	ResponseProc   *ResponseProc   `xml:"resprocessing,omitempty"`
@@ -51,17 +131,18 @@ type Item struct {
 	TemplateDecl   []TemplateDecl  `xml:"templateDeclaration,omitempty"`
 	Feedback       []Feedback      `xml:"itemfeedback,omitempty"`
 	RubricBlock    *RubricBlock    `xml:"rubricBlock,omitempty"`
+	ResponseProcessing *ResponseProcessing `xml:"responseProcessing,omitempty"`
+	TemplateProcessing *TemplateProcessing `xml:"templateProcessing,omitempty"`
+	Stylesheets        []Stylesheet        `xml:"stylesheet,omitempty"`
+	// QTI 2.1 output only; parsers read modalFeedback into Feedback
+	ModalFeedback []ModalFeedback `xml:"modalFeedback,omitempty"`
+	// QTI 2.x controls of the item ref that places the item in a test
+	ItemSessionControl *ItemSessionControl `xml:"itemSessionControl,omitempty"`
+	TimeLimits         *TimeLimits         `xml:"timeLimits,omitempty"`
 }
 
-// Legacy types kept for backward compatibility
-// These are generic versions that combine fields from multiple QTI versions
-// For version-specific parsing, use the types in qti12.go, qti21.go, or qti30.go
-
-// Legacy types for backward compatibility
-// Note: Import these from version-specific files when needed
-// For now, we'll define minimal types here to avoid conflicts
-
-// Generic types that combine fields from multiple versions
+// The model uses the QTI 1.2 structures for the 1.2 fields of items and the
+// QTI 2.1 structures for everything else, under names without a version
 type Response = Response12
 type Flow = Flow12
 type ItemBody = ItemBody21
@@ -72,11 +153,32 @@ type SimpleChoice = SimpleChoice21
 type Prompt = Prompt21
 type TextEntryInteraction = TextEntryInteraction21
 type ExtendedTextInteraction = ExtendedTextInteraction21
+type OrderInteraction = OrderInteraction21
+type MatchInteraction = MatchInteraction21
+type SimpleMatchSet = SimpleMatchSet21
+type AssociateInteraction = AssociateInteraction21
+type SimpleAssociableChoice = SimpleAssociableChoice21
 type ResponseDecl = ResponseDecl21
 type CorrectResponse = CorrectResponse21
+type Value = Value21
 type Mapping = Mapping21
 type MapEntry = MapEntry21
+type AreaMapping = AreaMapping21
+type AreaMapEntry = AreaMapEntry21
 type OutcomeDecl = OutcomeDecl21
 type DefaultValue = DefaultValue21
 type TemplateDecl = TemplateDecl21
-type Feedback = Feedback21
\ No newline at end of file
+type Feedback = Feedback21
+type ModalFeedback = ModalFeedback21
+type ResponseProcessing = ResponseProcessing21
+type TemplateProcessing = TemplateProcessing21
+type Stylesheet = Stylesheet21
+type ItemRef = AssessmentItemRef21
+type Weight = Weight21
+type OutcomeProcessing = OutcomeProcessing21
+type TestFeedback = TestFeedback21
+type SelectionOrdering = SelectionOrdering12
+type ItemSessionControl = ItemSessionControl21
+type TimeLimits = TimeLimits21
+type Selection = Selection21
+type Ordering = Ordering21
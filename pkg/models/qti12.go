@@ -1,6 +1,10 @@
 package models
 
-import "encoding/xml"
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
 
 // QTI 1.2 specific structures
 
@@ -20,6 +24,10 @@ type Assessment12 struct {
 	Metadata    *Metadata    `xml:"metadata,omitempty"`
 	Objectives  []Objective  `xml:"objectives>objective,omitempty"`
 	RubricBlock *RubricBlock `xml:"rubricBlock,omitempty"`
+	// Test controls
+	MaxAttempts       int                  `xml:"maxattempts,attr,omitempty"`
+	Duration          string               `xml:"duration,omitempty"`
+	SelectionOrdering *SelectionOrdering12 `xml:"selection_ordering,omitempty"`
 }
 
 type Section12 struct {
@@ -28,18 +36,114 @@ type Section12 struct {
 	Ident    string    `xml:"ident,attr"`
 	Items    []Item12  `xml:"item"`
 	Metadata *Metadata `xml:"metadata,omitempty"`
+	Sections []Section12 `xml:"section,omitempty"`
+	// Order holds SectionItem or SectionSection for every child in
+	// document order
+	Order []string `xml:"-"`
+	// Test controls
+	MaxAttempts       int                  `xml:"maxattempts,attr,omitempty"`
+	Duration          string               `xml:"duration,omitempty"`
+	SelectionOrdering *SelectionOrdering12 `xml:"selection_ordering,omitempty"`
+}
+
+// SelectionOrdering12 picks and orders the children of a section or
+// assessment.
+type SelectionOrdering12 struct {
+	XMLName      xml.Name      `xml:"selection_ordering"`
+	SequenceType string        `xml:"sequence_type,attr,omitempty"`
+	Selection    []Selection12 `xml:"selection,omitempty"`
+	Order        *Order12      `xml:"order,omitempty"`
+}
+
+type Selection12 struct {
+	XMLName         xml.Name `xml:"selection"`
+	SourcebankRef   string   `xml:"sourcebank_ref,omitempty"`
+	SelectionNumber int      `xml:"selection_number,omitempty"`
+	// Selection by metadata (selection_metadata, and_selection, ...)
+	Conditions []XMLNode `xml:",any"`
+}
+
+type Order12 struct {
+	XMLName   xml.Name `xml:"order"`
+	OrderType string   `xml:"order_type,attr"`
+}
+
+// UnmarshalXML decodes the section, recording the order of its items and
+// sub-sections. Elements other than items, sections, metadata and test
+// controls are skipped.
+func (s *Section12) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	s.XMLName = start.Name
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "title":
+			s.Title = attr.Value
+		case "ident":
+			s.Ident = attr.Value
+		case "maxattempts":
+			s.MaxAttempts, _ = strconv.Atoi(attr.Value)
+		}
+	}
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case SectionItem:
+				var item Item12
+				if err := d.DecodeElement(&item, &t); err != nil {
+					return err
+				}
+				s.Items = append(s.Items, item)
+				s.Order = append(s.Order, SectionItem)
+			case SectionSection:
+				var section Section12
+				if err := d.DecodeElement(&section, &t); err != nil {
+					return err
+				}
+				s.Sections = append(s.Sections, section)
+				s.Order = append(s.Order, SectionSection)
+			case "metadata":
+				var metadata Metadata
+				if err := d.DecodeElement(&metadata, &t); err != nil {
+					return err
+				}
+				s.Metadata = &metadata
+			case "duration":
+				if err := d.DecodeElement(&s.Duration, &t); err != nil {
+					return err
+				}
+			case "selection_ordering":
+				var selectionOrdering SelectionOrdering12
+				if err := d.DecodeElement(&selectionOrdering, &t); err != nil {
+					return err
+				}
+				s.SelectionOrdering = &selectionOrdering
+			default:
+				if err := d.Skip(); err != nil {
+					return err
+				}
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
 }
 
 type Item12 struct {
-	XMLName        xml.Name        `xml:"item"`
-	Title          string          `xml:"title,attr"`
-	Ident          string          `xml:"ident,attr"`
-	MaxAttempts    int             `xml:"maxattempts,attr,omitempty"`
-	Metadata       *Metadata       `xml:"metadata,omitempty"`
-	Presentation   *Presentation   `xml:"presentation,omitempty"`
-	ResponseProc   *ResponseProc   `xml:"resprocessing,omitempty"`
-	Feedback       []Feedback12    `xml:"itemfeedback,omitempty"`
-	RubricBlock    *RubricBlock    `xml:"rubricBlock,omitempty"`
+	XMLName      xml.Name      `xml:"item"`
+	Title        string        `xml:"title,attr"`
+	Ident        string        `xml:"ident,attr"`
+	MaxAttempts  int           `xml:"maxattempts,attr,omitempty"`
+	Duration     string        `xml:"duration,omitempty"`
+	Metadata     *Metadata     `xml:"metadata,omitempty"`
+	Presentation *Presentation `xml:"presentation,omitempty"`
+	ResponseProc *ResponseProc `xml:"resprocessing,omitempty"`
+	Feedback     []Feedback12  `xml:"itemfeedback,omitempty"`
+	RubricBlock  *RubricBlock  `xml:"rubricBlock,omitempty"`
 }
 
 // QTI 1.2 Presentation structures
@@ -48,17 +152,237 @@ type Presentation struct {
 	XMLName  xml.Name  `xml:"presentation"`
 	Label    string    `xml:"label,attr,omitempty"`
 	Material *Material `xml:"material,omitempty"`
-	Response []Response12 `xml:"response_lid,omitempty"`
 	Flow     []Flow12     `xml:"flow,omitempty"`
+	// Responses of every type in document order, see Response12.Kind
+	Response []Response12 `xml:",any"`
+	// Content holds all children in document order, see Children
+	Content []FlowContent12 `xml:"-"`
 }
 
+// FlowContent12 is one child of a presentation or flow; exactly one of the
+// fields is set.
+type FlowContent12 struct {
+	Material *Material
+	Response *Response12
+	Flow     *Flow12
+}
+
+// Children returns the material, responses and flows of the presentation
+// in document order. Presentations built in code without Content list their
+// material first, then responses, then flows.
+func (p *Presentation) Children() []FlowContent12 {
+	if len(p.Content) > 0 {
+		return p.Content
+	}
+	var children []FlowContent12
+	if p.Material != nil {
+		children = append(children, FlowContent12{Material: p.Material})
+	}
+	for i := range p.Response {
+		children = append(children, FlowContent12{Response: &p.Response[i]})
+	}
+	for i := range p.Flow {
+		children = append(children, FlowContent12{Flow: &p.Flow[i]})
+	}
+	return children
+}
+
+// UnmarshalXML decodes the presentation, keeping its children in document
+// order. Elements other than material, flows and responses are skipped.
+func (p *Presentation) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	p.XMLName = start.Name
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "label" {
+			p.Label = attr.Value
+		}
+	}
+
+	content, err := decodeFlowContent(d)
+	if err != nil {
+		return err
+	}
+	p.Content = content
+	for _, child := range content {
+		switch {
+		case child.Material != nil && p.Material == nil:
+			p.Material = child.Material
+		case child.Response != nil:
+			p.Response = append(p.Response, *child.Response)
+		case child.Flow != nil:
+			p.Flow = append(p.Flow, *child.Flow)
+		}
+	}
+	return nil
+}
+
+func decodeFlowContent(d *xml.Decoder) ([]FlowContent12, error) {
+	var content []FlowContent12
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch {
+			case t.Name.Local == "material":
+				material := &Material{}
+				if err := d.DecodeElement(material, &t); err != nil {
+					return nil, err
+				}
+				content = append(content, FlowContent12{Material: material})
+			case t.Name.Local == "flow":
+				flow := &Flow12{}
+				if err := d.DecodeElement(flow, &t); err != nil {
+					return nil, err
+				}
+				content = append(content, FlowContent12{Flow: flow})
+			case IsResponseType(t.Name.Local):
+				response := &Response12{}
+				if err := d.DecodeElement(response, &t); err != nil {
+					return nil, err
+				}
+				content = append(content, FlowContent12{Response: response})
+			default:
+				if err := d.Skip(); err != nil {
+					return nil, err
+				}
+			}
+		case xml.EndElement:
+			return content, nil
+		}
+	}
+}
+
+// QTI 1.2 response types
+const (
+	ResponseLid = "response_lid"
+	ResponseStr = "response_str"
+	ResponseNum = "response_num"
+	ResponseGrp = "response_grp"
+	ResponseXY  = "response_xy"
+)
+
+// Response12 is any of the QTI 1.2 response elements; the element name is
+// kept in XMLName so the response type survives parsing.
 type Response12 struct {
-	XMLName      xml.Name      `xml:"response_lid"`
-	Ident        string        `xml:"ident,attr"`
-	RCardinality string        `xml:"rcardinality,attr,omitempty"`
-	RTiming      string        `xml:"rtiming,attr,omitempty"`
-	RenderChoice *RenderChoice `xml:"render_choice,omitempty"`
-	RenderFib    *RenderFib    `xml:"render_fib,omitempty"`
+	XMLName         xml.Name
+	Ident           string           `xml:"ident,attr"`
+	RCardinality    string           `xml:"rcardinality,attr,omitempty"`
+	RTiming         string           `xml:"rtiming,attr,omitempty"`
+	NumType         string           `xml:"numtype,attr,omitempty"`
+	Material        *Material        `xml:"material,omitempty"`
+	RenderChoice    *RenderChoice    `xml:"render_choice,omitempty"`
+	RenderFib       *RenderFib       `xml:"render_fib,omitempty"`
+	RenderHotspot   *RenderHotspot   `xml:"render_hotspot,omitempty"`
+	RenderSlider    *RenderSlider    `xml:"render_slider,omitempty"`
+	RenderExtension *RenderExtension `xml:"render_extension,omitempty"`
+}
+
+// Kind returns the response type, e.g. "response_str". Responses built in
+// code without an element name are treated as response_lid.
+func (r Response12) Kind() string {
+	if r.XMLName.Local == "" {
+		return ResponseLid
+	}
+	return r.XMLName.Local
+}
+
+// IsOrdered tells whether the candidate arranges the labels of the response
+// in order rather than selecting them.
+func (r Response12) IsOrdered() bool {
+	return strings.EqualFold(r.RCardinality, "ordered")
+}
+
+// MatchSets splits the labels of a response into the sources and targets of
+// directed pairs, either by two flow_labels or by the match_group of the
+// source labels. ok is false when the labels form a single set.
+func (r Response12) MatchSets() (sources, targets []ResponseLabel, ok bool) {
+	if r.RenderChoice == nil {
+		return nil, nil, false
+	}
+
+	if len(r.RenderChoice.FlowLabel) == 2 {
+		sources = r.RenderChoice.FlowLabel[0].ResponseLabel
+		targets = r.RenderChoice.FlowLabel[1].ResponseLabel
+		return sources, targets, len(sources) > 0 && len(targets) > 0
+	}
+
+	for _, label := range r.RenderChoice.Labels() {
+		if label.MatchGroup != "" {
+			sources = append(sources, label)
+		} else {
+			targets = append(targets, label)
+		}
+	}
+	return sources, targets, len(sources) > 0 && len(targets) > 0
+}
+
+// IsMatchingLayout tells whether responses are the premises of a matching
+// question laid out as separate response_lids: at least two single
+// responses, each with its own premise material, offering the same answers.
+func IsMatchingLayout(responses []Response12) bool {
+	if len(responses) < 2 {
+		return false
+	}
+
+	answers := ""
+	for i, response := range responses {
+		if response.Kind() != ResponseLid || response.Material == nil || response.RenderChoice == nil ||
+			response.RenderChoice.MaxNumber > 1 || response.IsOrdered() || strings.EqualFold(response.RCardinality, "multiple") {
+			return false
+		}
+
+		var key strings.Builder
+		for _, label := range response.RenderChoice.Labels() {
+			key.WriteString(label.Ident + "\x00")
+			if label.Material != nil {
+				for _, text := range label.Material.MatText {
+					key.WriteString(strings.TrimSpace(text.Content))
+				}
+			}
+			key.WriteString("\x00")
+		}
+		if i == 0 {
+			answers = key.String()
+		} else if key.String() != answers {
+			return false
+		}
+	}
+	return answers != ""
+}
+
+// Responses returns the responses of the presentation and of all its
+// nested flows in document order.
+func (p *Presentation) Responses() []Response12 {
+	return flowResponses(p.Children())
+}
+
+func (f *Flow12) Responses() []Response12 {
+	return flowResponses(f.Children())
+}
+
+func flowResponses(children []FlowContent12) []Response12 {
+	var responses []Response12
+	for _, child := range children {
+		switch {
+		case child.Response != nil:
+			responses = append(responses, *child.Response)
+		case child.Flow != nil:
+			responses = append(responses, child.Flow.Responses()...)
+		}
+	}
+	return responses
+}
+
+// IsResponseType tells whether name is one of the QTI 1.2 response elements.
+func IsResponseType(name string) bool {
+	switch name {
+	case ResponseLid, ResponseStr, ResponseNum, ResponseGrp, ResponseXY:
+		return true
+	default:
+		return false
+	}
 }
 
 type RenderChoice struct {
@@ -67,14 +391,60 @@ type RenderChoice struct {
 	MInNumber  int         `xml:"minnumber,attr,omitempty"`
 	MaxNumber  int         `xml:"maxnumber,attr,omitempty"`
 	ResponseLabel []ResponseLabel `xml:"response_label"`
+	FlowLabel     []FlowLabel     `xml:"flow_label,omitempty"`
+}
+
+// Labels returns the response labels, including those grouped in
+// flow_labels, in document order of the groups.
+func (r *RenderChoice) Labels() []ResponseLabel {
+	labels := append([]ResponseLabel(nil), r.ResponseLabel...)
+	for _, flowLabel := range r.FlowLabel {
+		labels = append(labels, flowLabel.ResponseLabel...)
+	}
+	return labels
+}
+
+type FlowLabel struct {
+	XMLName       xml.Name        `xml:"flow_label"`
+	Class         string          `xml:"class,attr,omitempty"`
+	ResponseLabel []ResponseLabel `xml:"response_label"`
 }
 
 type ResponseLabel struct {
-	XMLName    xml.Name   `xml:"response_label"`
-	Ident      string     `xml:"ident,attr"`
-	RArea      string     `xml:"rarea,attr,omitempty"`
-	RRange     string     `xml:"rrange,attr,omitempty"`
-	Material   *Material  `xml:"material,omitempty"`
+	XMLName    xml.Name  `xml:"response_label"`
+	Ident      string    `xml:"ident,attr"`
+	MatchMax   int       `xml:"match_max,attr,omitempty"`
+	MatchGroup string    `xml:"match_group,attr,omitempty"`
+	RArea      string    `xml:"rarea,attr,omitempty"`
+	RRange     string    `xml:"rrange,attr,omitempty"`
+	Material   *Material `xml:"material,omitempty"`
+	// Coordinates of a hotspot label, given as the label's text
+	Content string `xml:",chardata"`
+}
+
+type RenderHotspot struct {
+	XMLName       xml.Name        `xml:"render_hotspot"`
+	MaxNumber     int             `xml:"maxnumber,attr,omitempty"`
+	MinNumber     int             `xml:"minnumber,attr,omitempty"`
+	Material      *Material       `xml:"material,omitempty"`
+	ResponseLabel []ResponseLabel `xml:"response_label"`
+}
+
+type RenderSlider struct {
+	XMLName     xml.Name `xml:"render_slider"`
+	Orientation string   `xml:"orientation,attr,omitempty"`
+	LowerBound  string   `xml:"lowerbound,attr"`
+	UpperBound  string   `xml:"upperbound,attr"`
+	Step        string   `xml:"step,attr,omitempty"`
+	StartVal    string   `xml:"startval,attr,omitempty"`
+	StepLabel   string   `xml:"steplabel,attr,omitempty"`
+}
+
+// RenderExtension holds proprietary rendering markup, which is kept verbatim
+type RenderExtension struct {
+	XMLName xml.Name   `xml:"render_extension"`
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Content string     `xml:",innerxml"`
 }
 
 type RenderFib struct {
@@ -88,11 +458,60 @@ type RenderFib struct {
 }
 
 type Flow12 struct {
-	XMLName    xml.Name    `xml:"flow"`
-	Class      string      `xml:"class,attr,omitempty"`
-	Material   []Material  `xml:"material,omitempty"`
-	Response   []Response12 `xml:"response_lid,omitempty"`
-	Flow       []Flow12     `xml:"flow,omitempty"`
+	XMLName  xml.Name     `xml:"flow"`
+	Class    string       `xml:"class,attr,omitempty"`
+	Material []Material   `xml:"material,omitempty"`
+	Flow     []Flow12     `xml:"flow,omitempty"`
+	Response []Response12 `xml:",any"`
+	// Content holds all children in document order, see Children
+	Content []FlowContent12 `xml:"-"`
+}
+
+// Children returns the material, responses and flows of the flow in
+// document order. Flows built in code without Content list their material
+// first, then responses, then nested flows.
+func (f *Flow12) Children() []FlowContent12 {
+	if len(f.Content) > 0 {
+		return f.Content
+	}
+	var children []FlowContent12
+	for i := range f.Material {
+		children = append(children, FlowContent12{Material: &f.Material[i]})
+	}
+	for i := range f.Response {
+		children = append(children, FlowContent12{Response: &f.Response[i]})
+	}
+	for i := range f.Flow {
+		children = append(children, FlowContent12{Flow: &f.Flow[i]})
+	}
+	return children
+}
+
+// UnmarshalXML decodes the flow, keeping its children in document order.
+func (f *Flow12) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	f.XMLName = start.Name
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "class" {
+			f.Class = attr.Value
+		}
+	}
+
+	content, err := decodeFlowContent(d)
+	if err != nil {
+		return err
+	}
+	f.Content = content
+	for _, child := range content {
+		switch {
+		case child.Material != nil:
+			f.Material = append(f.Material, *child.Material)
+		case child.Response != nil:
+			f.Response = append(f.Response, *child.Response)
+		case child.Flow != nil:
+			f.Flow = append(f.Flow, *child.Flow)
+		}
+	}
+	return nil
 }
 
 // QTI 1.2 Response Processing structures
@@ -140,6 +559,136 @@ type ConditionVar struct {
 	VarSubset   []VarSubset  `xml:"varsubset,omitempty"`
 	VarInside   []VarInside  `xml:"varinside,omitempty"`
 	VarSubstring []VarSubstring `xml:"varsubstring,omitempty"`
+	Other        *Other         `xml:"other,omitempty"`
+	Unanswered   []Unanswered   `xml:"unanswered,omitempty"`
+	// All conditions in document order, including nested not/and/or
+	Expressions []XMLNode `xml:"-"`
+}
+
+type Other struct {
+	XMLName xml.Name `xml:"other"`
+}
+
+type Unanswered struct {
+	XMLName   xml.Name `xml:"unanswered"`
+	RespIdent string   `xml:"respident,attr"`
+}
+
+// UnmarshalXML fills the typed condition fields and keeps the complete
+// condition tree in Expressions, which the typed fields cannot represent
+// for deeper nesting.
+func (c *ConditionVar) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var node XMLNode
+	if err := d.DecodeElement(&node, &start); err != nil {
+		return err
+	}
+
+	raw, err := xml.Marshal(node)
+	if err != nil {
+		return err
+	}
+
+	type plain ConditionVar
+	if err := xml.Unmarshal(raw, (*plain)(c)); err != nil {
+		return err
+	}
+	c.Expressions = node.Children
+	return nil
+}
+
+// Conditions returns the condition tree. Condition variables built in code
+// without Expressions are converted from their typed fields.
+func (c *ConditionVar) Conditions() []XMLNode {
+	if len(c.Expressions) > 0 {
+		return c.Expressions
+	}
+
+	var nodes []XMLNode
+	for _, v := range c.VarEqual {
+		index := ""
+		if v.Index > 0 {
+			index = strconv.Itoa(v.Index)
+		}
+		nodes = append(nodes, varNode("varequal", v.RespIdent, v.Value, "case", v.Case, "index", index))
+	}
+	for _, v := range c.VarLT {
+		nodes = append(nodes, varNode("varlt", v.RespIdent, v.Value))
+	}
+	for _, v := range c.VarLTE {
+		nodes = append(nodes, varNode("varlte", v.RespIdent, v.Value))
+	}
+	for _, v := range c.VarGT {
+		nodes = append(nodes, varNode("vargt", v.RespIdent, v.Value))
+	}
+	for _, v := range c.VarGTE {
+		nodes = append(nodes, varNode("vargte", v.RespIdent, v.Value))
+	}
+	for _, v := range c.VarSubset {
+		nodes = append(nodes, varNode("varsubset", v.RespIdent, v.Value, "setmatch", v.SetMatch))
+	}
+	for _, v := range c.VarInside {
+		nodes = append(nodes, varNode("varinside", v.RespIdent, v.Value, "areatype", v.AreaType))
+	}
+	for _, v := range c.VarSubstring {
+		nodes = append(nodes, varNode("varsubstring", v.RespIdent, v.Value, "case", v.Case))
+	}
+	for _, v := range c.Unanswered {
+		nodes = append(nodes, varNode("unanswered", v.RespIdent, ""))
+	}
+	if c.Other != nil {
+		nodes = append(nodes, NewXMLNode("other", nil))
+	}
+	if c.Not != nil {
+		nodes = append(nodes, c.Not.node())
+	}
+	if c.And != nil {
+		nodes = append(nodes, c.And.node())
+	}
+	if c.Or != nil {
+		nodes = append(nodes, c.Or.node())
+	}
+	return nodes
+}
+
+func (n *Not) node() XMLNode {
+	return logicNode("not", n.VarEqual, nil, n.And, n.Or)
+}
+
+func (a *And) node() XMLNode {
+	return logicNode("and", a.VarEqual, a.Not, nil, a.Or)
+}
+
+func (o *Or) node() XMLNode {
+	return logicNode("or", o.VarEqual, o.Not, o.And, nil)
+}
+
+func logicNode(name string, varEqual []VarEqual, not *Not, and *And, or *Or) XMLNode {
+	node := NewXMLNode(name, nil)
+	for _, v := range varEqual {
+		node.Children = append(node.Children, varNode("varequal", v.RespIdent, v.Value, "case", v.Case))
+	}
+	if not != nil {
+		node.Children = append(node.Children, not.node())
+	}
+	if and != nil {
+		node.Children = append(node.Children, and.node())
+	}
+	if or != nil {
+		node.Children = append(node.Children, or.node())
+	}
+	return node
+}
+
+func varNode(name, respIdent, value string, attrs ...string) XMLNode {
+	nodeAttrs := []string{"respident", respIdent}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		if attrs[i+1] != "" {
+			nodeAttrs = append(nodeAttrs, attrs[i], attrs[i+1])
+		}
+	}
+	node := NewXMLNode(name, nodeAttrs)
+	node.Value = value
+	return node
 }
 
 type Not struct {
@@ -164,10 +713,11 @@ type Or struct {
 }
 
 type VarEqual struct {
-	XMLName     xml.Name `xml:"varequal"`
-	RespIdent   string   `xml:"respident,attr"`
-	Case        string   `xml:"case,attr,omitempty"`
-	Value       string   `xml:",chardata"`
+	XMLName   xml.Name `xml:"varequal"`
+	RespIdent string   `xml:"respident,attr"`
+	Case      string   `xml:"case,attr,omitempty"`
+	Index     int      `xml:"index,attr,omitempty"`
+	Value     string   `xml:",chardata"`
 }
 
 type VarLT struct {
@@ -202,10 +752,11 @@ type VarSubset struct {
 }
 
 type VarInside struct {
-	XMLName     xml.Name `xml:"varinside"`
-	RespIdent   string   `xml:"respident,attr"`
-	AreaMatch   string   `xml:"areamatch,attr,omitempty"`
-	Value       string   `xml:",chardata"`
+	XMLName   xml.Name `xml:"varinside"`
+	RespIdent string   `xml:"respident,attr"`
+	AreaType  string   `xml:"areatype,attr,omitempty"`
+	AreaMatch string   `xml:"areamatch,attr,omitempty"`
+	Value     string   `xml:",chardata"`
 }
 
 type VarSubstring struct {
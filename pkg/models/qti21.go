@@ -48,68 +48,387 @@ type Item21 struct {
 	TemplateDecl   []TemplateDecl21  `xml:"templateDeclaration,omitempty"`
 	Feedback       []Feedback21    `xml:"itemfeedback,omitempty"`
 	RubricBlock    *RubricBlock    `xml:"rubricBlock,omitempty"`
+	ResponseProcessing *ResponseProcessing21 `xml:"responseProcessing,omitempty"`
+	ModalFeedback      []ModalFeedback21     `xml:"modalFeedback,omitempty"`
 }
 
 // QTI 2.1/2.2 ItemBody structures
 
 type ItemBody21 struct {
 	XMLName     xml.Name     `xml:"itemBody"`
-	P           []P21        `xml:"p,omitempty"`
-	Div         []Div21      `xml:"div,omitempty"`
-	ChoiceInteraction []ChoiceInteraction21 `xml:"choiceInteraction,omitempty"`
-	TextEntryInteraction []TextEntryInteraction21 `xml:"textEntryInteraction,omitempty"`
+	// Attributes of the item body, e.g. class, id or xml:lang
+	Attrs                   []xml.Attr                  `xml:",any,attr"`
+	P                       []P21                       `xml:"p,omitempty"`
+	Div                     []Div21                     `xml:"div,omitempty"`
+	ChoiceInteraction       []ChoiceInteraction21       `xml:"choiceInteraction,omitempty"`
+	TextEntryInteraction    []TextEntryInteraction21    `xml:"textEntryInteraction,omitempty"`
 	ExtendedTextInteraction []ExtendedTextInteraction21 `xml:"extendedTextInteraction,omitempty"`
+	OrderInteraction        []OrderInteraction21        `xml:"orderInteraction,omitempty"`
+	MatchInteraction        []MatchInteraction21        `xml:"matchInteraction,omitempty"`
+	AssociateInteraction    []AssociateInteraction21    `xml:"associateInteraction,omitempty"`
+	// Everything else (lists, tables, images, other interactions) is kept as-is
+	Other []RawElement21 `xml:",any"`
+	// Order holds the kind of every child in document order (see Append),
+	// so the typed slices can be written back interleaved
+	Order []string `xml:"-"`
 }
 
+// Item body child kinds recorded in ItemBody21.Order
+const (
+	BodyP                       = "p"
+	BodyDiv                     = "div"
+	BodyChoiceInteraction       = "choiceInteraction"
+	BodyTextEntryInteraction    = "textEntryInteraction"
+	BodyExtendedTextInteraction = "extendedTextInteraction"
+	BodyOrderInteraction        = "orderInteraction"
+	BodyMatchInteraction        = "matchInteraction"
+	BodyAssociateInteraction    = "associateInteraction"
+	BodyOther                   = "other"
+)
+
+var bodyKinds = []string{BodyP, BodyDiv, BodyChoiceInteraction, BodyTextEntryInteraction, BodyExtendedTextInteraction,
+	BodyOrderInteraction, BodyMatchInteraction, BodyAssociateInteraction, BodyOther}
+
+// Append adds a P21, Div21, interaction or RawElement21 after the current
+// last child of the item body.
+func (b *ItemBody21) Append(element interface{}) {
+	switch e := element.(type) {
+	case P21:
+		b.P = append(b.P, e)
+		b.Order = append(b.Order, BodyP)
+	case Div21:
+		b.Div = append(b.Div, e)
+		b.Order = append(b.Order, BodyDiv)
+	case ChoiceInteraction21:
+		b.ChoiceInteraction = append(b.ChoiceInteraction, e)
+		b.Order = append(b.Order, BodyChoiceInteraction)
+	case TextEntryInteraction21:
+		b.TextEntryInteraction = append(b.TextEntryInteraction, e)
+		b.Order = append(b.Order, BodyTextEntryInteraction)
+	case ExtendedTextInteraction21:
+		b.ExtendedTextInteraction = append(b.ExtendedTextInteraction, e)
+		b.Order = append(b.Order, BodyExtendedTextInteraction)
+	case OrderInteraction21:
+		b.OrderInteraction = append(b.OrderInteraction, e)
+		b.Order = append(b.Order, BodyOrderInteraction)
+	case MatchInteraction21:
+		b.MatchInteraction = append(b.MatchInteraction, e)
+		b.Order = append(b.Order, BodyMatchInteraction)
+	case AssociateInteraction21:
+		b.AssociateInteraction = append(b.AssociateInteraction, e)
+		b.Order = append(b.Order, BodyAssociateInteraction)
+	case RawElement21:
+		b.Other = append(b.Other, e)
+		b.Order = append(b.Order, BodyOther)
+	}
+}
+
+// Elements returns the children of the item body in document order.
+// Children missing from Order, e.g. in bodies built as struct literals,
+// follow the ordered ones grouped by kind.
+func (b ItemBody21) Elements() []interface{} {
+	var elements []interface{}
+	next := make(map[string]int)
+	take := func(kind string) (interface{}, bool) {
+		i := next[kind]
+		next[kind]++
+		switch {
+		case kind == BodyP && i < len(b.P):
+			return b.P[i], true
+		case kind == BodyDiv && i < len(b.Div):
+			return b.Div[i], true
+		case kind == BodyChoiceInteraction && i < len(b.ChoiceInteraction):
+			return b.ChoiceInteraction[i], true
+		case kind == BodyTextEntryInteraction && i < len(b.TextEntryInteraction):
+			return b.TextEntryInteraction[i], true
+		case kind == BodyExtendedTextInteraction && i < len(b.ExtendedTextInteraction):
+			return b.ExtendedTextInteraction[i], true
+		case kind == BodyOrderInteraction && i < len(b.OrderInteraction):
+			return b.OrderInteraction[i], true
+		case kind == BodyMatchInteraction && i < len(b.MatchInteraction):
+			return b.MatchInteraction[i], true
+		case kind == BodyAssociateInteraction && i < len(b.AssociateInteraction):
+			return b.AssociateInteraction[i], true
+		case kind == BodyOther && i < len(b.Other):
+			return b.Other[i], true
+		}
+		next[kind]--
+		return nil, false
+	}
+
+	for _, kind := range b.Order {
+		if element, ok := take(kind); ok {
+			elements = append(elements, element)
+		}
+	}
+	for _, kind := range bodyKinds {
+		for element, ok := take(kind); ok; element, ok = take(kind) {
+			elements = append(elements, element)
+		}
+	}
+	return elements
+}
+
+// UnmarshalXML decodes the children of an item body and records their
+// order. Elements kept as RawElement21 keep their namespace when it is not
+// the one of the item body, e.g. MathML, and the prefixes their markup uses
+// are declared on them, see declareNamespaces.
+func (b *ItemBody21) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	b.XMLName = start.Name
+	b.Attrs = withoutDeclarations(start.Attr)
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			var element interface{}
+			var err error
+			switch t.Name.Local {
+			case BodyP:
+				var p P21
+				err = d.DecodeElement(&p, &t)
+				p.Attrs = markupAttrs(p.Attrs)
+				element = p
+			case BodyDiv:
+				var div Div21
+				err = d.DecodeElement(&div, &t)
+				div.Attrs = markupAttrs(div.Attrs)
+				element = div
+			case BodyChoiceInteraction:
+				var interaction ChoiceInteraction21
+				err = d.DecodeElement(&interaction, &t)
+				element = interaction
+			case BodyTextEntryInteraction:
+				var interaction TextEntryInteraction21
+				err = d.DecodeElement(&interaction, &t)
+				element = interaction
+			case BodyExtendedTextInteraction:
+				var interaction ExtendedTextInteraction21
+				err = d.DecodeElement(&interaction, &t)
+				element = interaction
+			case BodyOrderInteraction:
+				var interaction OrderInteraction21
+				err = d.DecodeElement(&interaction, &t)
+				element = interaction
+			case BodyMatchInteraction:
+				var interaction MatchInteraction21
+				err = d.DecodeElement(&interaction, &t)
+				element = interaction
+			case BodyAssociateInteraction:
+				var interaction AssociateInteraction21
+				err = d.DecodeElement(&interaction, &t)
+				element = interaction
+			default:
+				var raw RawElement21
+				err = d.DecodeElement(&raw, &t)
+				if raw.XMLName.Space == start.Name.Space {
+					raw.XMLName.Space = ""
+				}
+				raw.Attrs = markupAttrs(raw.Attrs)
+				element = raw
+			}
+			if err != nil {
+				return err
+			}
+			b.Append(element)
+		case xml.EndElement:
+			b.declareNamespaces(namespaceDeclarations(start.Attr))
+			return nil
+		}
+	}
+}
+
+// declareNamespaces declares on the paragraphs, divs and raw elements of the
+// body the prefixes of scope, which maps prefixes to namespaces, that their
+// markup uses without declaring them. The elements then stay well-formed
+// once written without the ancestors that declared the prefixes. Prefixes
+// an element already declares are left alone, so the innermost scope is
+// declared first.
+func (b *ItemBody21) declareNamespaces(scope map[string]string) {
+	if len(scope) == 0 {
+		return
+	}
+	for i := range b.P {
+		b.P[i].Attrs = declarePrefixes(b.P[i].Attrs, b.P[i].Content, scope)
+	}
+	for i := range b.Div {
+		b.Div[i].Attrs = declarePrefixes(b.Div[i].Attrs, b.Div[i].Content, scope)
+	}
+	for i := range b.Other {
+		b.Other[i].Attrs = declarePrefixes(b.Other[i].Attrs, b.Other[i].Content, scope)
+	}
+}
+
+// MarshalXML writes the children of the item body in document order.
+func (b ItemBody21) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Attr = append(start.Attr, b.Attrs...)
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, element := range b.Elements() {
+		if err := e.Encode(element); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// RawElement21 keeps an element the model has no dedicated type for,
+// together with its attributes and markup.
+type RawElement21 struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Content string     `xml:",innerxml"`
+}
+
+// Body elements keep the attributes they have no field for in Attrs, e.g. id,
+// xml:lang or attributes only QTI 3.0 defines, so no markup is lost between
+// versions.
+
 type P21 struct {
 	XMLName xml.Name `xml:"p"`
+	Attrs   []xml.Attr `xml:",any,attr"`
 	Content string   `xml:",innerxml"`
 }
 
 type Div21 struct {
 	XMLName xml.Name `xml:"div"`
 	Class   string   `xml:"class,attr,omitempty"`
+	Attrs   []xml.Attr `xml:",any,attr"`
 	Content string   `xml:",innerxml"`
 }
 
 type ChoiceInteraction21 struct {
-	XMLName         xml.Name        `xml:"choiceInteraction"`
-	ResponseIdent   string          `xml:"responseIdentifier,attr"`
-	Shuffle         bool            `xml:"shuffle,attr,omitempty"`
-	MaxChoices      int             `xml:"maxChoices,attr,omitempty"`
-	MinChoices      int             `xml:"minChoices,attr,omitempty"`
-	Prompt          *Prompt21       `xml:"prompt,omitempty"`
-	SimpleChoice    []SimpleChoice21 `xml:"simpleChoice"`
+	XMLName       xml.Name `xml:"choiceInteraction"`
+	ResponseIdent string   `xml:"responseIdentifier,attr"`
+	Class         string   `xml:"class,attr,omitempty"`
+	Shuffle       bool     `xml:"shuffle,attr,omitempty"`
+	MaxChoices    int      `xml:"maxChoices,attr,omitempty"`
+	// Set when the source gives maxChoices, which is 1 when omitted; a
+	// maxChoices of 0 puts no limit on the choices
+	MaxChoicesSet bool             `xml:"-"`
+	MinChoices    int              `xml:"minChoices,attr,omitempty"`
+	Orientation   string           `xml:"orientation,attr,omitempty"`
+	Attrs         []xml.Attr       `xml:",any,attr"`
+	Prompt        *Prompt21        `xml:"prompt,omitempty"`
+	SimpleChoice  []SimpleChoice21 `xml:"simpleChoice"`
+}
+
+// UnmarshalXML decodes a choice interaction and records whether it gives
+// maxChoices.
+func (c *ChoiceInteraction21) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plain ChoiceInteraction21
+	if err := d.DecodeElement((*plain)(c), &start); err != nil {
+		return err
+	}
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "" && attr.Name.Local == "maxChoices" {
+			c.MaxChoicesSet = true
+		}
+	}
+	return nil
+}
+
+// MarshalXML writes a choice interaction, including a maxChoices of 0 the
+// source gives, which omitempty would drop.
+func (c ChoiceInteraction21) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	type plain ChoiceInteraction21
+	if c.MaxChoicesSet && c.MaxChoices == 0 {
+		c.Attrs = append([]xml.Attr{{Name: xml.Name{Local: "maxChoices"}, Value: "0"}}, c.Attrs...)
+	}
+	return e.Encode(plain(c))
 }
 
 type SimpleChoice21 struct {
-	XMLName     xml.Name `xml:"simpleChoice"`
-	Identifier  string   `xml:"identifier,attr"`
-	Fixed       bool     `xml:"fixed,attr,omitempty"`
-	Content     string   `xml:",innerxml"`
+	XMLName    xml.Name   `xml:"simpleChoice"`
+	Identifier string     `xml:"identifier,attr"`
+	Fixed      bool       `xml:"fixed,attr,omitempty"`
+	Attrs      []xml.Attr `xml:",any,attr"`
+	Content    string     `xml:",innerxml"`
 }
 
 type Prompt21 struct {
 	XMLName xml.Name `xml:"prompt"`
+	Attrs   []xml.Attr `xml:",any,attr"`
 	Content string   `xml:",innerxml"`
 }
 
 type TextEntryInteraction21 struct {
-	XMLName         xml.Name `xml:"textEntryInteraction"`
-	ResponseIdent   string   `xml:"responseIdentifier,attr"`
-	ExpectedLength  int      `xml:"expectedLength,attr,omitempty"`
-	PatternMask     string   `xml:"patternMask,attr,omitempty"`
-	PlaceholderText string   `xml:"placeholderText,attr,omitempty"`
+	XMLName         xml.Name   `xml:"textEntryInteraction"`
+	ResponseIdent   string     `xml:"responseIdentifier,attr"`
+	Class           string     `xml:"class,attr,omitempty"`
+	ExpectedLength  int        `xml:"expectedLength,attr,omitempty"`
+	PatternMask     string     `xml:"patternMask,attr,omitempty"`
+	PlaceholderText string     `xml:"placeholderText,attr,omitempty"`
+	Attrs           []xml.Attr `xml:",any,attr"`
 }
 
 type ExtendedTextInteraction21 struct {
-	XMLName         xml.Name `xml:"extendedTextInteraction"`
-	ResponseIdent   string   `xml:"responseIdentifier,attr"`
-	MinStrings      int      `xml:"minStrings,attr,omitempty"`
-	MaxStrings      int      `xml:"maxStrings,attr,omitempty"`
-	ExpectedLines   int      `xml:"expectedLines,attr,omitempty"`
-	ExpectedLength  int      `xml:"expectedLength,attr,omitempty"`
-	Prompt          *Prompt21 `xml:"prompt,omitempty"`
+	XMLName        xml.Name   `xml:"extendedTextInteraction"`
+	ResponseIdent  string     `xml:"responseIdentifier,attr"`
+	Class          string     `xml:"class,attr,omitempty"`
+	MinStrings     int        `xml:"minStrings,attr,omitempty"`
+	MaxStrings     int        `xml:"maxStrings,attr,omitempty"`
+	ExpectedLines  int        `xml:"expectedLines,attr,omitempty"`
+	ExpectedLength int        `xml:"expectedLength,attr,omitempty"`
+	Attrs          []xml.Attr `xml:",any,attr"`
+	Prompt         *Prompt21  `xml:"prompt,omitempty"`
+}
+
+type OrderInteraction21 struct {
+	XMLName       xml.Name         `xml:"orderInteraction"`
+	ResponseIdent string           `xml:"responseIdentifier,attr"`
+	Class         string           `xml:"class,attr,omitempty"`
+	Shuffle       bool             `xml:"shuffle,attr,omitempty"`
+	MaxChoices    int              `xml:"maxChoices,attr,omitempty"`
+	MinChoices    int              `xml:"minChoices,attr,omitempty"`
+	Orientation   string           `xml:"orientation,attr,omitempty"`
+	Attrs         []xml.Attr       `xml:",any,attr"`
+	Prompt        *Prompt21        `xml:"prompt,omitempty"`
+	SimpleChoice  []SimpleChoice21 `xml:"simpleChoice"`
+}
+
+type MatchInteraction21 struct {
+	XMLName         xml.Name   `xml:"matchInteraction"`
+	ResponseIdent   string     `xml:"responseIdentifier,attr"`
+	Class           string     `xml:"class,attr,omitempty"`
+	Shuffle         bool       `xml:"shuffle,attr,omitempty"`
+	MaxAssociations int        `xml:"maxAssociations,attr"`
+	MinAssociations int        `xml:"minAssociations,attr,omitempty"`
+	Attrs           []xml.Attr `xml:",any,attr"`
+	Prompt          *Prompt21  `xml:"prompt,omitempty"`
+	// Exactly two sets: the sources and the targets of the directed pairs
+	SimpleMatchSet []SimpleMatchSet21 `xml:"simpleMatchSet"`
+}
+
+type SimpleMatchSet21 struct {
+	XMLName                xml.Name                   `xml:"simpleMatchSet"`
+	Attrs                  []xml.Attr                 `xml:",any,attr"`
+	SimpleAssociableChoice []SimpleAssociableChoice21 `xml:"simpleAssociableChoice"`
+}
+
+type AssociateInteraction21 struct {
+	XMLName                xml.Name                   `xml:"associateInteraction"`
+	ResponseIdent          string                     `xml:"responseIdentifier,attr"`
+	Class                  string                     `xml:"class,attr,omitempty"`
+	Shuffle                bool                       `xml:"shuffle,attr,omitempty"`
+	MaxAssociations        int                        `xml:"maxAssociations,attr"`
+	MinAssociations        int                        `xml:"minAssociations,attr,omitempty"`
+	Attrs                  []xml.Attr                 `xml:",any,attr"`
+	Prompt                 *Prompt21                  `xml:"prompt,omitempty"`
+	SimpleAssociableChoice []SimpleAssociableChoice21 `xml:"simpleAssociableChoice"`
+}
+
+type SimpleAssociableChoice21 struct {
+	XMLName    xml.Name   `xml:"simpleAssociableChoice"`
+	Identifier string     `xml:"identifier,attr"`
+	Fixed      bool       `xml:"fixed,attr,omitempty"`
+	MatchMax   int        `xml:"matchMax,attr"`
+	MatchMin   int        `xml:"matchMin,attr,omitempty"`
+	Attrs      []xml.Attr `xml:",any,attr"`
+	Content    string     `xml:",innerxml"`
 }
 
 // QTI 2.1/2.2 Declaration structures
@@ -120,20 +439,39 @@ type ResponseDecl21 struct {
 	Cardinality   string        `xml:"cardinality,attr"`
 	BaseType      string        `xml:"baseType,attr,omitempty"`
 	CorrectResponse *CorrectResponse21 `xml:"correctResponse,omitempty"`
-	Mapping       *Mapping21    `xml:"mapping,omitempty"`
+	Mapping         *Mapping21         `xml:"mapping,omitempty"`
+	AreaMapping     *AreaMapping21     `xml:"areaMapping,omitempty"`
 }
 
 type CorrectResponse21 struct {
-	XMLName xml.Name `xml:"correctResponse"`
-	Value   []string `xml:"value"`
+	XMLName xml.Name  `xml:"correctResponse"`
+	Value   []Value21 `xml:"value"`
+}
+
+// Value21 is a single value of a correct response or default value. The base
+// type and field identifier are only set inside records.
+type Value21 struct {
+	XMLName         xml.Name `xml:"value"`
+	FieldIdentifier string   `xml:"fieldIdentifier,attr,omitempty"`
+	BaseType        string   `xml:"baseType,attr,omitempty"`
+	Content         string   `xml:",chardata"`
+}
+
+// NewValues returns a value for each of contents.
+func NewValues(contents ...string) []Value21 {
+	values := make([]Value21, len(contents))
+	for i, content := range contents {
+		values[i] = Value21{XMLName: xml.Name{Local: "value"}, Content: content}
+	}
+	return values
 }
 
 type Mapping21 struct {
-	XMLName        xml.Name    `xml:"mapping"`
-	LowerBound     float64     `xml:"lowerBound,attr,omitempty"`
-	UpperBound     float64     `xml:"upperBound,attr,omitempty"`
-	DefaultValue   float64     `xml:"defaultValue,attr,omitempty"`
-	MapEntry       []MapEntry21 `xml:"mapEntry"`
+	XMLName      xml.Name     `xml:"mapping"`
+	LowerBound   *float64     `xml:"lowerBound,attr,omitempty"`
+	UpperBound   *float64     `xml:"upperBound,attr,omitempty"`
+	DefaultValue float64      `xml:"defaultValue,attr"`
+	MapEntry     []MapEntry21 `xml:"mapEntry"`
 }
 
 type MapEntry21 struct {
@@ -142,26 +480,58 @@ type MapEntry21 struct {
 	MappedValue float64 `xml:"mappedValue,attr"`
 }
 
+type AreaMapping21 struct {
+	XMLName      xml.Name         `xml:"areaMapping"`
+	LowerBound   float64          `xml:"lowerBound,attr,omitempty"`
+	UpperBound   float64          `xml:"upperBound,attr,omitempty"`
+	DefaultValue float64          `xml:"defaultValue,attr"`
+	AreaMapEntry []AreaMapEntry21 `xml:"areaMapEntry"`
+}
+
+type AreaMapEntry21 struct {
+	XMLName     xml.Name `xml:"areaMapEntry"`
+	Shape       string   `xml:"shape,attr"`
+	Coords      string   `xml:"coords,attr"`
+	MappedValue float64  `xml:"mappedValue,attr"`
+}
+
 type OutcomeDecl21 struct {
 	XMLName         xml.Name        `xml:"outcomeDeclaration"`
 	Identifier      string          `xml:"identifier,attr"`
 	Cardinality     string          `xml:"cardinality,attr"`
 	BaseType        string          `xml:"baseType,attr,omitempty"`
-	DefaultValue    *DefaultValue21 `xml:"defaultValue,omitempty"`
+	// Space-separated list of views
+	View               string          `xml:"view,attr,omitempty"`
+	Interpretation     string          `xml:"interpretation,attr,omitempty"`
+	LongInterpretation string          `xml:"longInterpretation,attr,omitempty"`
+	NormalMaximum      *float64        `xml:"normalMaximum,attr,omitempty"`
+	NormalMinimum      *float64        `xml:"normalMinimum,attr,omitempty"`
+	MasteryValue       *float64        `xml:"masteryValue,attr,omitempty"`
+	DefaultValue       *DefaultValue21 `xml:"defaultValue,omitempty"`
 }
 
 type DefaultValue21 struct {
-	XMLName xml.Name `xml:"defaultValue"`
-	Value   string   `xml:"value"`
+	XMLName xml.Name  `xml:"defaultValue"`
+	Value   []Value21 `xml:"value"`
+}
+
+// First returns the content of the first value, the whole default of a
+// single cardinality variable.
+func (v *DefaultValue21) First() string {
+	if v == nil || len(v.Value) == 0 {
+		return ""
+	}
+	return v.Value[0].Content
 }
 
 type TemplateDecl21 struct {
-	XMLName      xml.Name     `xml:"templateDeclaration"`
-	Identifier   string       `xml:"identifier,attr"`
-	Cardinality  string       `xml:"cardinality,attr"`
-	BaseType     string       `xml:"baseType,attr,omitempty"`
-	ParamVariable bool        `xml:"paramVariable,attr,omitempty"`
-	DefaultValue *DefaultValue21 `xml:"defaultValue,omitempty"`
+	XMLName       xml.Name        `xml:"templateDeclaration"`
+	Identifier    string          `xml:"identifier,attr"`
+	Cardinality   string          `xml:"cardinality,attr"`
+	BaseType      string          `xml:"baseType,attr,omitempty"`
+	ParamVariable bool            `xml:"paramVariable,attr,omitempty"`
+	MathVariable  bool            `xml:"mathVariable,attr,omitempty"`
+	DefaultValue  *DefaultValue21 `xml:"defaultValue,omitempty"`
 }
 
 // QTI 2.1/2.2 Feedback structures
@@ -171,6 +541,183 @@ type Feedback21 struct {
 	XMLName        xml.Name       `xml:"itemfeedback"`
 	Ident          string         `xml:"ident,attr"`
 	Title          string         `xml:"title,attr,omitempty"`
-	FlowMat        []FlowMat      `xml:"flow_mat,omitempty"` // Legacy 1.2 style
-	Material       *Material      `xml:"material,omitempty"`  // Can be used directly
-}
\ No newline at end of file
+	// Set for feedback read from modalFeedback
+	OutcomeIdentifier string    `xml:"outcomeIdentifier,attr,omitempty"`
+	ShowHide          string    `xml:"showHide,attr,omitempty"`
+	FlowMat           []FlowMat `xml:"flow_mat,omitempty"` // Legacy 1.2 style
+	Material          *Material `xml:"material,omitempty"` // Can be used directly
+}
+
+// Standard QTI 2.1/2.2 documents
+// Spec-conformant files use assessmentItem or assessmentTest as their root in
+// the imsqti_v2p1 or imsqti_v2p2 namespace rather than questestinterop.
+
+type AssessmentItem21 struct {
+	XMLName            xml.Name              `xml:"assessmentItem"`
+	Identifier         string                `xml:"identifier,attr"`
+	Title              string                `xml:"title,attr"`
+	Adaptive           bool                  `xml:"adaptive,attr"`
+	TimeDependent      bool                  `xml:"timeDependent,attr"`
+	ToolName           string                `xml:"toolName,attr,omitempty"`
+	ToolVersion        string                `xml:"toolVersion,attr,omitempty"`
+	ResponseDecl       []ResponseDecl21      `xml:"responseDeclaration,omitempty"`
+	OutcomeDecl        []OutcomeDecl21       `xml:"outcomeDeclaration,omitempty"`
+	TemplateDecl       []TemplateDecl21      `xml:"templateDeclaration,omitempty"`
+	TemplateProcessing *TemplateProcessing21 `xml:"templateProcessing,omitempty"`
+	Stylesheet         []Stylesheet21        `xml:"stylesheet,omitempty"`
+	ItemBody           *ItemBody21           `xml:"itemBody,omitempty"`
+	ResponseProcessing *ResponseProcessing21 `xml:"responseProcessing,omitempty"`
+	ModalFeedback      []ModalFeedback21     `xml:"modalFeedback,omitempty"`
+}
+
+// UnmarshalXML decodes an item and declares the namespaces bound on the item
+// element on the markup of its body that uses them.
+func (item *AssessmentItem21) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plain AssessmentItem21
+	if err := d.DecodeElement((*plain)(item), &start); err != nil {
+		return err
+	}
+	if item.ItemBody != nil {
+		item.ItemBody.declareNamespaces(namespaceDeclarations(start.Attr))
+	}
+	return nil
+}
+
+type ResponseProcessing21 struct {
+	XMLName          xml.Name  `xml:"responseProcessing"`
+	Template         string    `xml:"template,attr,omitempty"`
+	TemplateLocation string    `xml:"templateLocation,attr,omitempty"`
+	Rules            []XMLNode `xml:",any"`
+}
+
+// TemplateProcessing21 holds the template rules that set up an item's
+// template variables
+type TemplateProcessing21 struct {
+	XMLName xml.Name  `xml:"templateProcessing"`
+	Rules   []XMLNode `xml:",any"`
+}
+
+type Stylesheet21 struct {
+	XMLName xml.Name `xml:"stylesheet"`
+	Href    string   `xml:"href,attr"`
+	Type    string   `xml:"type,attr"`
+	Media   string   `xml:"media,attr,omitempty"`
+	Title   string   `xml:"title,attr,omitempty"`
+}
+
+// Standard response processing templates
+const (
+	TemplateMatchCorrect21 = "http://www.imsglobal.org/question/qti_v2p1/rptemplates/match_correct"
+	TemplateMapResponse21  = "http://www.imsglobal.org/question/qti_v2p1/rptemplates/map_response"
+)
+
+type ModalFeedback21 struct {
+	XMLName           xml.Name `xml:"modalFeedback"`
+	Identifier        string   `xml:"identifier,attr"`
+	OutcomeIdentifier string   `xml:"outcomeIdentifier,attr"`
+	ShowHide          string   `xml:"showHide,attr"`
+	Title             string   `xml:"title,attr,omitempty"`
+	Content           string   `xml:",innerxml"`
+}
+
+type AssessmentTest21 struct {
+	XMLName           xml.Name             `xml:"assessmentTest"`
+	Identifier        string               `xml:"identifier,attr"`
+	Title             string               `xml:"title,attr"`
+	ToolName          string               `xml:"toolName,attr,omitempty"`
+	ToolVersion       string               `xml:"toolVersion,attr,omitempty"`
+	OutcomeDecl       []OutcomeDecl21      `xml:"outcomeDeclaration,omitempty"`
+	TimeLimits        *TimeLimits21        `xml:"timeLimits,omitempty"`
+	TestParts         []TestPart21         `xml:"testPart"`
+	OutcomeProcessing *OutcomeProcessing21 `xml:"outcomeProcessing,omitempty"`
+	TestFeedback      []TestFeedback21     `xml:"testFeedback,omitempty"`
+}
+
+type TestPart21 struct {
+	XMLName            xml.Name              `xml:"testPart"`
+	Identifier         string                `xml:"identifier,attr"`
+	NavigationMode     string                `xml:"navigationMode,attr"`
+	SubmissionMode     string                `xml:"submissionMode,attr"`
+	ItemSessionControl *ItemSessionControl21 `xml:"itemSessionControl,omitempty"`
+	TimeLimits         *TimeLimits21         `xml:"timeLimits,omitempty"`
+	Sections           []AssessmentSection21 `xml:"assessmentSection"`
+	TestFeedback       []TestFeedback21      `xml:"testFeedback,omitempty"`
+}
+
+type AssessmentSection21 struct {
+	XMLName            xml.Name              `xml:"assessmentSection"`
+	Identifier         string                `xml:"identifier,attr"`
+	Title              string                `xml:"title,attr"`
+	Visible            bool                  `xml:"visible,attr"`
+	ItemSessionControl *ItemSessionControl21 `xml:"itemSessionControl,omitempty"`
+	TimeLimits         *TimeLimits21         `xml:"timeLimits,omitempty"`
+	Selection          *Selection21          `xml:"selection,omitempty"`
+	Ordering           *Ordering21           `xml:"ordering,omitempty"`
+	RubricBlock        []RubricBlock         `xml:"rubricBlock,omitempty"`
+	Sections           []AssessmentSection21 `xml:"assessmentSection,omitempty"`
+	ItemRefs           []AssessmentItemRef21 `xml:"assessmentItemRef,omitempty"`
+}
+
+type AssessmentItemRef21 struct {
+	XMLName    xml.Name `xml:"assessmentItemRef"`
+	Identifier string   `xml:"identifier,attr"`
+	Href       string   `xml:"href,attr"`
+	// Space-separated list of categories
+	Category           string                `xml:"category,attr,omitempty"`
+	ItemSessionControl *ItemSessionControl21 `xml:"itemSessionControl,omitempty"`
+	TimeLimits         *TimeLimits21         `xml:"timeLimits,omitempty"`
+	Weights            []Weight21            `xml:"weight,omitempty"`
+}
+
+// Weight21 scales the outcomes of a referenced item in test-level processing
+type Weight21 struct {
+	XMLName    xml.Name `xml:"weight"`
+	Identifier string   `xml:"identifier,attr"`
+	Value      float64  `xml:"value,attr"`
+}
+
+// OutcomeProcessing21 holds the outcome rules that score a test
+type OutcomeProcessing21 struct {
+	XMLName xml.Name  `xml:"outcomeProcessing"`
+	Rules   []XMLNode `xml:",any"`
+}
+
+// TestFeedback21 is feedback shown at the end of, or during, a test or test
+// part
+type TestFeedback21 struct {
+	XMLName xml.Name `xml:"testFeedback"`
+	// atEnd or during
+	Access            string `xml:"access,attr"`
+	OutcomeIdentifier string `xml:"outcomeIdentifier,attr"`
+	ShowHide          string `xml:"showHide,attr"`
+	Identifier        string `xml:"identifier,attr"`
+	Title             string `xml:"title,attr,omitempty"`
+	Content           string `xml:",innerxml"`
+}
+
+// Test controls of test parts, sections and item refs
+
+type ItemSessionControl21 struct {
+	XMLName xml.Name `xml:"itemSessionControl"`
+	// A pointer, since 0 stands for unlimited attempts
+	MaxAttempts *int `xml:"maxAttempts,attr,omitempty"`
+}
+
+// TimeLimits21 holds times in seconds
+type TimeLimits21 struct {
+	XMLName             xml.Name `xml:"timeLimits"`
+	MinTime             float64  `xml:"minTime,attr,omitempty"`
+	MaxTime             float64  `xml:"maxTime,attr,omitempty"`
+	AllowLateSubmission bool     `xml:"allowLateSubmission,attr,omitempty"`
+}
+
+type Selection21 struct {
+	XMLName         xml.Name `xml:"selection"`
+	Select          int      `xml:"select,attr"`
+	WithReplacement bool     `xml:"withReplacement,attr,omitempty"`
+}
+
+type Ordering21 struct {
+	XMLName xml.Name `xml:"ordering"`
+	Shuffle bool     `xml:"shuffle,attr"`
+}
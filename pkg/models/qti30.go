@@ -3,217 +3,369 @@ package models
 import "encoding/xml"
 
 // QTI 3.0 specific structures
-// QTI 3.0 represents a major overhaul with cleaner, more modern XML structure
+// QTI 3.0 represents a major overhaul with cleaner, more modern XML structure.
+// Element names are kebab-case with a qti- prefix and live in the
+// imsqtiasi_v3p0 namespace, e.g. qti-assessment-item or qti-item-body.
+
+const Namespace30 = "http://www.imsglobal.org/xsd/imsqtiasi_v3p0"
 
 type QTIDocument30 struct {
-	XMLName    xml.Name      `xml:"qtiAssessmentItem"`
-	Version    string        `xml:"version,attr"`
-	Identifier string        `xml:"identifier,attr"`
-	Title      string        `xml:"title,attr"`
-	TimeDependent bool       `xml:"timeDependent,attr,omitempty"`
-	Adaptive   bool          `xml:"adaptive,attr,omitempty"`
+	XMLName       xml.Name `xml:"qti-assessment-item"`
+	Version       string   `xml:"version,attr"`
+	Identifier    string   `xml:"identifier,attr"`
+	Title         string   `xml:"title,attr"`
+	TimeDependent bool     `xml:"time-dependent,attr,omitempty"`
+	Adaptive      bool     `xml:"adaptive,attr,omitempty"`
+	ToolName      string   `xml:"tool-name,attr,omitempty"`
+	ToolVersion   string   `xml:"tool-version,attr,omitempty"`
 	// Declarations come first in QTI 3.0
-	ResponseDeclarations []ResponseDecl30 `xml:"responseDeclaration,omitempty"`
-	OutcomeDeclarations  []OutcomeDecl30  `xml:"outcomeDeclaration,omitempty"`
-	TemplateDeclarations []TemplateDecl30 `xml:"templateDeclaration,omitempty"`
+	ResponseDeclarations []ResponseDecl30      `xml:"qti-response-declaration,omitempty"`
+	OutcomeDeclarations  []OutcomeDecl30       `xml:"qti-outcome-declaration,omitempty"`
+	TemplateDeclarations []TemplateDecl30      `xml:"qti-template-declaration,omitempty"`
+	TemplateProcessing   *TemplateProcessing30 `xml:"qti-template-processing,omitempty"`
+	Stylesheets          []Stylesheet30        `xml:"qti-stylesheet,omitempty"`
 	// Content
-	ItemBody       *ItemBody30      `xml:"itemBody"`
-	ResponseProcessing *ResponseProcessing30 `xml:"responseProcessing,omitempty"`
-	ModalFeedback  []ModalFeedback30 `xml:"modalFeedback,omitempty"`
+	ItemBody           *ItemBody30           `xml:"qti-item-body"`
+	ResponseProcessing *ResponseProcessing30 `xml:"qti-response-processing,omitempty"`
+	ModalFeedback      []ModalFeedback30     `xml:"qti-modal-feedback,omitempty"`
 	// Metadata
 	Metadata       *Metadata        `xml:"metadata,omitempty"`
+	// Attributes without a field, such as namespace declarations
+	Attrs []xml.Attr `xml:",any,attr"`
 }
 
 // QTI 3.0 uses a different root for assessments
 type Assessment30 struct {
-	XMLName    xml.Name      `xml:"assessmentTest"`
-	Identifier string        `xml:"identifier,attr"`
-	Title      string        `xml:"title,attr"`
-	TestParts  []TestPart30  `xml:"testPart"`
+	XMLName           xml.Name             `xml:"qti-assessment-test"`
+	Identifier        string               `xml:"identifier,attr"`
+	Title             string               `xml:"title,attr"`
+	ToolName          string               `xml:"tool-name,attr,omitempty"`
+	ToolVersion       string               `xml:"tool-version,attr,omitempty"`
+	OutcomeDecl       []OutcomeDecl30      `xml:"qti-outcome-declaration,omitempty"`
+	TimeLimits        *TimeLimits30        `xml:"qti-time-limits,omitempty"`
+	TestParts         []TestPart30         `xml:"qti-test-part"`
+	OutcomeProcessing *OutcomeProcessing30 `xml:"qti-outcome-processing,omitempty"`
+	TestFeedback      []TestFeedback30     `xml:"qti-test-feedback,omitempty"`
 	Metadata   *Metadata     `xml:"metadata,omitempty"`
 }
 
 type TestPart30 struct {
-	XMLName        xml.Name          `xml:"testPart"`
-	Identifier     string            `xml:"identifier,attr"`
-	NavigationMode string            `xml:"navigationMode,attr,omitempty"`
-	SubmissionMode string            `xml:"submissionMode,attr,omitempty"`
-	Sections       []AssessmentSection30 `xml:"assessmentSection"`
+	XMLName            xml.Name              `xml:"qti-test-part"`
+	Identifier         string                `xml:"identifier,attr"`
+	NavigationMode     string                `xml:"navigation-mode,attr,omitempty"`
+	SubmissionMode     string                `xml:"submission-mode,attr,omitempty"`
+	ItemSessionControl *ItemSessionControl30 `xml:"qti-item-session-control,omitempty"`
+	TimeLimits         *TimeLimits30         `xml:"qti-time-limits,omitempty"`
+	Sections           []AssessmentSection30 `xml:"qti-assessment-section"`
+	TestFeedback       []TestFeedback30      `xml:"qti-test-feedback,omitempty"`
 }
 
 type AssessmentSection30 struct {
-	XMLName    xml.Name   `xml:"assessmentSection"`
-	Identifier string     `xml:"identifier,attr"`
-	Title      string     `xml:"title,attr"`
-	Visible    bool       `xml:"visible,attr,omitempty"`
-	ItemRefs   []ItemRef30 `xml:"assessmentItemRef"`
-	Metadata   *Metadata  `xml:"metadata,omitempty"`
+	XMLName            xml.Name              `xml:"qti-assessment-section"`
+	Identifier         string                `xml:"identifier,attr"`
+	Title              string                `xml:"title,attr"`
+	Visible            bool                  `xml:"visible,attr,omitempty"`
+	ItemSessionControl *ItemSessionControl30 `xml:"qti-item-session-control,omitempty"`
+	TimeLimits         *TimeLimits30         `xml:"qti-time-limits,omitempty"`
+	Selection          *Selection30          `xml:"qti-selection,omitempty"`
+	Ordering           *Ordering30           `xml:"qti-ordering,omitempty"`
+	RubricBlock        []RubricBlock30       `xml:"qti-rubric-block,omitempty"`
+	Sections           []AssessmentSection30 `xml:"qti-assessment-section,omitempty"`
+	ItemRefs           []ItemRef30           `xml:"qti-assessment-item-ref"`
+	Metadata           *Metadata             `xml:"metadata,omitempty"`
 }
 
 type ItemRef30 struct {
-	XMLName    xml.Name `xml:"assessmentItemRef"`
+	XMLName            xml.Name              `xml:"qti-assessment-item-ref"`
+	Identifier         string                `xml:"identifier,attr"`
+	Href               string                `xml:"href,attr"`
+	Category           []string              `xml:"category,attr,omitempty"`
+	ItemSessionControl *ItemSessionControl30 `xml:"qti-item-session-control,omitempty"`
+	TimeLimits         *TimeLimits30         `xml:"qti-time-limits,omitempty"`
+	Weights            []Weight30            `xml:"qti-weight,omitempty"`
+}
+
+type Weight30 struct {
+	XMLName    xml.Name `xml:"qti-weight"`
 	Identifier string   `xml:"identifier,attr"`
-	Href       string   `xml:"href,attr"`
-	Category   []string `xml:"category,attr,omitempty"`
+	Value      float64  `xml:"value,attr"`
+}
+
+type OutcomeProcessing30 struct {
+	XMLName      xml.Name  `xml:"qti-outcome-processing"`
+	OutcomeRules []XMLNode `xml:",any"`
+}
+
+// QTI 3.0 wraps the content of rubric blocks and test feedback in
+// qti-content-body; Content holds the inner markup of either form
+
+type ContentBody30 struct {
+	XMLName xml.Name `xml:"qti-content-body"`
+	Content string   `xml:",innerxml"`
+}
+
+type RubricBlock30 struct {
+	XMLName     xml.Name       `xml:"qti-rubric-block"`
+	Use         string         `xml:"use,attr,omitempty"`
+	View        []string       `xml:"view,attr"`
+	ContentBody *ContentBody30 `xml:"qti-content-body"`
+	Content     string         `xml:",innerxml"`
+}
+
+type TestFeedback30 struct {
+	XMLName           xml.Name       `xml:"qti-test-feedback"`
+	Access            string         `xml:"access,attr"`
+	OutcomeIdentifier string         `xml:"outcome-identifier,attr"`
+	ShowHide          string         `xml:"show-hide,attr"`
+	Identifier        string         `xml:"identifier,attr"`
+	Title             string         `xml:"title,attr,omitempty"`
+	ContentBody       *ContentBody30 `xml:"qti-content-body"`
+	Content           string         `xml:",innerxml"`
+}
+
+// Test controls of test parts, sections and item refs
+
+type ItemSessionControl30 struct {
+	XMLName     xml.Name `xml:"qti-item-session-control"`
+	MaxAttempts *int     `xml:"max-attempts,attr,omitempty"`
+}
+
+// TimeLimits30 holds times in seconds
+type TimeLimits30 struct {
+	XMLName             xml.Name `xml:"qti-time-limits"`
+	MinTime             float64  `xml:"min-time,attr,omitempty"`
+	MaxTime             float64  `xml:"max-time,attr,omitempty"`
+	AllowLateSubmission bool     `xml:"allow-late-submission,attr,omitempty"`
+}
+
+type Selection30 struct {
+	XMLName         xml.Name `xml:"qti-selection"`
+	Select          int      `xml:"select,attr"`
+	WithReplacement bool     `xml:"with-replacement,attr,omitempty"`
+}
+
+type Ordering30 struct {
+	XMLName xml.Name `xml:"qti-ordering"`
+	Shuffle bool     `xml:"shuffle,attr"`
 }
 
 // QTI 3.0 ItemBody - cleaner structure than 2.x
 
 type ItemBody30 struct {
-	XMLName xml.Name `xml:"itemBody"`
-	// Content can include various interactions and block elements
-	Content []interface{} `xml:",any"`
+	XMLName xml.Name   `xml:"qti-item-body"`
+	Attrs   []xml.Attr `xml:",any,attr"`
+	// Content can include various interactions and block elements; it is
+	// kept verbatim and decoded by the parser
+	Content string `xml:",innerxml"`
 }
 
 // QTI 3.0 Interactions - more structured than previous versions
 
 type ChoiceInteraction30 struct {
-	XMLName               xml.Name        `xml:"choiceInteraction"`
-	ResponseIdentifier    string          `xml:"responseIdentifier,attr"`
-	Shuffle               bool            `xml:"shuffle,attr,omitempty"`
-	MaxChoices            int             `xml:"maxChoices,attr,omitempty"`
-	MinChoices            int             `xml:"minChoices,attr,omitempty"`
-	Orientation           string          `xml:"orientation,attr,omitempty"`
-	Prompt                *Prompt30       `xml:"prompt,omitempty"`
-	SimpleChoice          []SimpleChoice30 `xml:"simpleChoice"`
+	XMLName            xml.Name         `xml:"qti-choice-interaction"`
+	ResponseIdentifier string           `xml:"response-identifier,attr"`
+	Shuffle            bool             `xml:"shuffle,attr,omitempty"`
+	MaxChoices         int              `xml:"max-choices,attr,omitempty"`
+	MinChoices         int              `xml:"min-choices,attr,omitempty"`
+	Orientation        string           `xml:"orientation,attr,omitempty"`
+	Prompt             *Prompt30        `xml:"qti-prompt,omitempty"`
+	SimpleChoice       []SimpleChoice30 `xml:"qti-simple-choice"`
 }
 
 type SimpleChoice30 struct {
-	XMLName     xml.Name `xml:"simpleChoice"`
-	Identifier  string   `xml:"identifier,attr"`
-	Fixed       bool     `xml:"fixed,attr,omitempty"`
-	ShowHide    string   `xml:"showHide,attr,omitempty"`
-	Content     string   `xml:",innerxml"`
+	XMLName    xml.Name `xml:"qti-simple-choice"`
+	Identifier string   `xml:"identifier,attr"`
+	Fixed      bool     `xml:"fixed,attr,omitempty"`
+	ShowHide   string   `xml:"show-hide,attr,omitempty"`
+	Content    string   `xml:",innerxml"`
 }
 
 type TextEntryInteraction30 struct {
-	XMLName            xml.Name `xml:"textEntryInteraction"`
-	ResponseIdentifier string   `xml:"responseIdentifier,attr"`
+	XMLName            xml.Name `xml:"qti-text-entry-interaction"`
+	ResponseIdentifier string   `xml:"response-identifier,attr"`
 	Base               int      `xml:"base,attr,omitempty"`
-	StringIdentifier   string   `xml:"stringIdentifier,attr,omitempty"`
-	ExpectedLength     int      `xml:"expectedLength,attr,omitempty"`
-	PatternMask        string   `xml:"patternMask,attr,omitempty"`
-	PlaceholderText    string   `xml:"placeholderText,attr,omitempty"`
+	StringIdentifier   string   `xml:"string-identifier,attr,omitempty"`
+	ExpectedLength     int      `xml:"expected-length,attr,omitempty"`
+	PatternMask        string   `xml:"pattern-mask,attr,omitempty"`
+	PlaceholderText    string   `xml:"placeholder-text,attr,omitempty"`
 }
 
 type ExtendedTextInteraction30 struct {
-	XMLName            xml.Name  `xml:"extendedTextInteraction"`
-	ResponseIdentifier string    `xml:"responseIdentifier,attr"`
+	XMLName            xml.Name  `xml:"qti-extended-text-interaction"`
+	ResponseIdentifier string    `xml:"response-identifier,attr"`
 	Base               int       `xml:"base,attr,omitempty"`
-	StringIdentifier   string    `xml:"stringIdentifier,attr,omitempty"`
-	ExpectedLength     int       `xml:"expectedLength,attr,omitempty"`
-	PatternMask        string    `xml:"patternMask,attr,omitempty"`
-	PlaceholderText    string    `xml:"placeholderText,attr,omitempty"`
-	MaxStrings         int       `xml:"maxStrings,attr,omitempty"`
-	MinStrings         int       `xml:"minStrings,attr,omitempty"`
-	ExpectedLines      int       `xml:"expectedLines,attr,omitempty"`
+	StringIdentifier   string    `xml:"string-identifier,attr,omitempty"`
+	ExpectedLength     int       `xml:"expected-length,attr,omitempty"`
+	PatternMask        string    `xml:"pattern-mask,attr,omitempty"`
+	PlaceholderText    string    `xml:"placeholder-text,attr,omitempty"`
+	MaxStrings         int       `xml:"max-strings,attr,omitempty"`
+	MinStrings         int       `xml:"min-strings,attr,omitempty"`
+	ExpectedLines      int       `xml:"expected-lines,attr,omitempty"`
 	Format             string    `xml:"format,attr,omitempty"`
-	Prompt             *Prompt30 `xml:"prompt,omitempty"`
+	Prompt             *Prompt30 `xml:"qti-prompt,omitempty"`
+}
+
+type OrderInteraction30 struct {
+	XMLName            xml.Name         `xml:"qti-order-interaction"`
+	ResponseIdentifier string           `xml:"response-identifier,attr"`
+	Shuffle            bool             `xml:"shuffle,attr,omitempty"`
+	MaxChoices         int              `xml:"max-choices,attr,omitempty"`
+	MinChoices         int              `xml:"min-choices,attr,omitempty"`
+	Orientation        string           `xml:"orientation,attr,omitempty"`
+	Prompt             *Prompt30        `xml:"qti-prompt,omitempty"`
+	SimpleChoice       []SimpleChoice30 `xml:"qti-simple-choice"`
+}
+
+type MatchInteraction30 struct {
+	XMLName            xml.Name           `xml:"qti-match-interaction"`
+	ResponseIdentifier string             `xml:"response-identifier,attr"`
+	Shuffle            bool               `xml:"shuffle,attr,omitempty"`
+	MaxAssociations    int                `xml:"max-associations,attr"`
+	MinAssociations    int                `xml:"min-associations,attr,omitempty"`
+	Prompt             *Prompt30          `xml:"qti-prompt,omitempty"`
+	SimpleMatchSet     []SimpleMatchSet30 `xml:"qti-simple-match-set"`
+}
+
+type SimpleMatchSet30 struct {
+	XMLName                xml.Name                   `xml:"qti-simple-match-set"`
+	SimpleAssociableChoice []SimpleAssociableChoice30 `xml:"qti-simple-associable-choice"`
+}
+
+type AssociateInteraction30 struct {
+	XMLName                xml.Name                   `xml:"qti-associate-interaction"`
+	ResponseIdentifier     string                     `xml:"response-identifier,attr"`
+	Shuffle                bool                       `xml:"shuffle,attr,omitempty"`
+	MaxAssociations        int                        `xml:"max-associations,attr"`
+	MinAssociations        int                        `xml:"min-associations,attr,omitempty"`
+	Prompt                 *Prompt30                  `xml:"qti-prompt,omitempty"`
+	SimpleAssociableChoice []SimpleAssociableChoice30 `xml:"qti-simple-associable-choice"`
+}
+
+type SimpleAssociableChoice30 struct {
+	XMLName    xml.Name `xml:"qti-simple-associable-choice"`
+	Identifier string   `xml:"identifier,attr"`
+	Fixed      bool     `xml:"fixed,attr,omitempty"`
+	MatchMax   int      `xml:"match-max,attr"`
+	MatchMin   int      `xml:"match-min,attr,omitempty"`
+	Content    string   `xml:",innerxml"`
 }
 
 type Prompt30 struct {
-	XMLName xml.Name `xml:"prompt"`
+	XMLName xml.Name `xml:"qti-prompt"`
 	Content string   `xml:",innerxml"`
 }
 
 // QTI 3.0 Declarations - more structured than 2.x
 
 type ResponseDecl30 struct {
-	XMLName         xml.Name           `xml:"responseDeclaration"`
+	XMLName         xml.Name           `xml:"qti-response-declaration"`
 	Identifier      string             `xml:"identifier,attr"`
 	Cardinality     string             `xml:"cardinality,attr"`
-	BaseType        string             `xml:"baseType,attr,omitempty"`
-	CorrectResponse *CorrectResponse30 `xml:"correctResponse,omitempty"`
-	Mapping         *Mapping30         `xml:"mapping,omitempty"`
-	AreaMapping     *AreaMapping30     `xml:"areaMapping,omitempty"`
+	BaseType        string             `xml:"base-type,attr,omitempty"`
+	CorrectResponse *CorrectResponse30 `xml:"qti-correct-response,omitempty"`
+	Mapping         *Mapping30         `xml:"qti-mapping,omitempty"`
+	AreaMapping     *AreaMapping30     `xml:"qti-area-mapping,omitempty"`
 }
 
 type CorrectResponse30 struct {
-	XMLName xml.Name  `xml:"correctResponse"`
-	Value   []Value30 `xml:"value"`
+	XMLName xml.Name  `xml:"qti-correct-response"`
+	Value   []Value30 `xml:"qti-value"`
 }
 
 type Value30 struct {
-	XMLName    xml.Name `xml:"value"`
-	FieldIdentifier string `xml:"fieldIdentifier,attr,omitempty"`
-	BaseType   string   `xml:"baseType,attr,omitempty"`
-	Content    string   `xml:",chardata"`
+	XMLName         xml.Name `xml:"qti-value"`
+	FieldIdentifier string   `xml:"field-identifier,attr,omitempty"`
+	BaseType        string   `xml:"base-type,attr,omitempty"`
+	Content         string   `xml:",chardata"`
 }
 
 type Mapping30 struct {
-	XMLName      xml.Name      `xml:"mapping"`
-	LowerBound   float64       `xml:"lowerBound,attr,omitempty"`
-	UpperBound   float64       `xml:"upperBound,attr,omitempty"`
-	DefaultValue float64       `xml:"defaultValue,attr"`
-	MapEntry     []MapEntry30  `xml:"mapEntry"`
+	XMLName      xml.Name     `xml:"qti-mapping"`
+	LowerBound   *float64     `xml:"lower-bound,attr,omitempty"`
+	UpperBound   *float64     `xml:"upper-bound,attr,omitempty"`
+	DefaultValue float64      `xml:"default-value,attr"`
+	MapEntry     []MapEntry30 `xml:"qti-map-entry"`
 }
 
 type MapEntry30 struct {
-	XMLName     xml.Name `xml:"mapEntry"`
-	MapKey      string   `xml:"mapKey,attr"`
-	MappedValue float64  `xml:"mappedValue,attr"`
+	XMLName     xml.Name `xml:"qti-map-entry"`
+	MapKey      string   `xml:"map-key,attr"`
+	MappedValue float64  `xml:"mapped-value,attr"`
 }
 
 type AreaMapping30 struct {
-	XMLName          xml.Name         `xml:"areaMapping"`
-	LowerBound       float64          `xml:"lowerBound,attr,omitempty"`
-	UpperBound       float64          `xml:"upperBound,attr,omitempty"`
-	DefaultValue     float64          `xml:"defaultValue,attr"`
-	AreaMapEntry     []AreaMapEntry30 `xml:"areaMapEntry"`
+	XMLName      xml.Name         `xml:"qti-area-mapping"`
+	LowerBound   float64          `xml:"lower-bound,attr,omitempty"`
+	UpperBound   float64          `xml:"upper-bound,attr,omitempty"`
+	DefaultValue float64          `xml:"default-value,attr"`
+	AreaMapEntry []AreaMapEntry30 `xml:"qti-area-map-entry"`
 }
 
 type AreaMapEntry30 struct {
-	XMLName     xml.Name `xml:"areaMapEntry"`
+	XMLName     xml.Name `xml:"qti-area-map-entry"`
 	Shape       string   `xml:"shape,attr"`
 	Coords      string   `xml:"coords,attr"`
-	MappedValue float64  `xml:"mappedValue,attr"`
+	MappedValue float64  `xml:"mapped-value,attr"`
 }
 
 type OutcomeDecl30 struct {
-	XMLName         xml.Name         `xml:"outcomeDeclaration"`
-	Identifier      string           `xml:"identifier,attr"`
-	Cardinality     string           `xml:"cardinality,attr"`
-	BaseType        string           `xml:"baseType,attr,omitempty"`
-	View            []string         `xml:"view,attr,omitempty"`
-	Interpretation  string           `xml:"interpretation,attr,omitempty"`
-	LongInterpretation string        `xml:"longInterpretation,attr,omitempty"`
-	NormalMaximum   float64          `xml:"normalMaximum,attr,omitempty"`
-	NormalMinimum   float64          `xml:"normalMinimum,attr,omitempty"`
-	MasteryValue    float64          `xml:"masteryValue,attr,omitempty"`
-	DefaultValue    *DefaultValue30  `xml:"defaultValue,omitempty"`
+	XMLName            xml.Name        `xml:"qti-outcome-declaration"`
+	Identifier         string          `xml:"identifier,attr"`
+	Cardinality        string          `xml:"cardinality,attr"`
+	BaseType           string          `xml:"base-type,attr,omitempty"`
+	View               []string        `xml:"view,attr,omitempty"`
+	Interpretation     string          `xml:"interpretation,attr,omitempty"`
+	LongInterpretation string          `xml:"long-interpretation,attr,omitempty"`
+	NormalMaximum      *float64        `xml:"normal-maximum,attr,omitempty"`
+	NormalMinimum      *float64        `xml:"normal-minimum,attr,omitempty"`
+	MasteryValue       *float64        `xml:"mastery-value,attr,omitempty"`
+	DefaultValue       *DefaultValue30 `xml:"qti-default-value,omitempty"`
 }
 
 type DefaultValue30 struct {
-	XMLName xml.Name  `xml:"defaultValue"`
-	Value   []Value30 `xml:"value"`
+	XMLName xml.Name  `xml:"qti-default-value"`
+	Value   []Value30 `xml:"qti-value"`
 }
 
 type TemplateDecl30 struct {
-	XMLName       xml.Name        `xml:"templateDeclaration"`
+	XMLName       xml.Name        `xml:"qti-template-declaration"`
 	Identifier    string          `xml:"identifier,attr"`
 	Cardinality   string          `xml:"cardinality,attr"`
-	BaseType      string          `xml:"baseType,attr,omitempty"`
-	ParamVariable bool            `xml:"paramVariable,attr,omitempty"`
-	MathVariable  bool            `xml:"mathVariable,attr,omitempty"`
-	DefaultValue  *DefaultValue30 `xml:"defaultValue,omitempty"`
+	BaseType      string          `xml:"base-type,attr,omitempty"`
+	ParamVariable bool            `xml:"param-variable,attr,omitempty"`
+	MathVariable  bool            `xml:"math-variable,attr,omitempty"`
+	DefaultValue  *DefaultValue30 `xml:"qti-default-value,omitempty"`
 }
 
 // QTI 3.0 Response Processing - completely different from resprocessing
 
 type ResponseProcessing30 struct {
-	XMLName             xml.Name              `xml:"responseProcessing"`
-	Template            string                `xml:"template,attr,omitempty"`
-	TemplateLocation    string                `xml:"templateLocation,attr,omitempty"`
-	ResponseRules       []interface{}         `xml:",any"` // Can contain various rule types
+	XMLName          xml.Name  `xml:"qti-response-processing"`
+	Template         string    `xml:"template,attr,omitempty"`
+	TemplateLocation string    `xml:"template-location,attr,omitempty"`
+	ResponseRules    []XMLNode `xml:",any"` // Can contain various rule types
+}
+
+type TemplateProcessing30 struct {
+	XMLName       xml.Name  `xml:"qti-template-processing"`
+	TemplateRules []XMLNode `xml:",any"`
+}
+
+type Stylesheet30 struct {
+	XMLName xml.Name `xml:"qti-stylesheet"`
+	Href    string   `xml:"href,attr"`
+	Type    string   `xml:"type,attr"`
+	Media   string   `xml:"media,attr,omitempty"`
+	Title   string   `xml:"title,attr,omitempty"`
 }
 
 // QTI 3.0 Modal Feedback
 
 type ModalFeedback30 struct {
-	XMLName    xml.Name `xml:"modalFeedback"`
-	Identifier string   `xml:"identifier,attr"`
-	OutcomeIdentifier string `xml:"outcomeIdentifier,attr"`
-	ShowHide   string   `xml:"showHide,attr"`
-	Title      string   `xml:"title,attr,omitempty"`
-	Content    string   `xml:",innerxml"`
-}
\ No newline at end of file
+	XMLName           xml.Name `xml:"qti-modal-feedback"`
+	Identifier        string   `xml:"identifier,attr"`
+	OutcomeIdentifier string   `xml:"outcome-identifier,attr"`
+	ShowHide          string   `xml:"show-hide,attr"`
+	Title             string   `xml:"title,attr,omitempty"`
+	Content           string   `xml:",innerxml"`
+}
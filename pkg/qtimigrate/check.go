@@ -0,0 +1,34 @@
+package qtimigrate
+
+import (
+	"fmt"
+
+	"github.com/qti-migrator/internal/checker"
+	"github.com/qti-migrator/internal/packaging"
+	"github.com/qti-migrator/pkg/analysis"
+	"github.com/qti-migrator/pkg/models"
+)
+
+// checkFiles runs the consistency checks on migrated files and adds the
+// findings to report. The source was checked during the analysis.
+func checkFiles(report *analysis.Report, files []models.File, version string) error {
+	consistency := checker.New()
+	for _, file := range files {
+		if err := consistency.CheckContent(file.Content, version, report); err != nil {
+			return &Error{Kind: KindMigration, Message: fmt.Sprintf("error checking migrated %s", describeFile(file.Href)), Err: err}
+		}
+	}
+	return nil
+}
+
+// checkResources runs the consistency checks on the migrated QTI files of a
+// package and adds the findings to the report of their resource.
+func checkResources(pkg *packaging.Package, resources []ResourceReport, version string) error {
+	consistency := checker.New()
+	return eachResourceFile(pkg, resources, true, func(report *analysis.Report, content []byte, href string) error {
+		if err := consistency.CheckContent(content, version, report); err != nil {
+			return &Error{Kind: KindMigration, Message: fmt.Sprintf("error checking migrated %s", href), Err: err}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,82 @@
+package qtimigrate
+
+import "context"
+
+// ErrorKind tells why a migration failed.
+type ErrorKind int
+
+const (
+	// The input could not be read
+	KindInput ErrorKind = iota
+	// The source version could not be detected from the input
+	KindDetection
+	// A version is not supported, or there is no migration between them
+	KindUnsupported
+	// The input is not a well-formed document or content package
+	KindParse
+	// The analysis found errors that block the migration
+	KindBlocked
+	// A migration step failed
+	KindMigration
+	// The context of the migration was canceled or its deadline passed
+	KindCanceled
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case KindInput:
+		return "input"
+	case KindDetection:
+		return "detection"
+	case KindUnsupported:
+		return "unsupported"
+	case KindParse:
+		return "parse"
+	case KindBlocked:
+		return "blocked"
+	case KindMigration:
+		return "migration"
+	case KindCanceled:
+		return "canceled"
+	default:
+		return "unknown"
+	}
+}
+
+// Error is the error Migrate and Detect return.
+type Error struct {
+	Kind    ErrorKind
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Err == nil:
+		return e.Message
+	case e.Message == "":
+		return e.Err.Error()
+	default:
+		return e.Message + ": " + e.Err.Error()
+	}
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// checkContext returns the error of a canceled ctx as an *Error, which
+// unwraps to context.Canceled or context.DeadlineExceeded.
+func checkContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return &Error{Kind: KindCanceled, Err: err}
+	}
+	return nil
+}
+
+func errBlocked() *Error {
+	return &Error{
+		Kind:    KindBlocked,
+		Message: "migration cannot proceed due to errors in the analysis report",
+	}
+}
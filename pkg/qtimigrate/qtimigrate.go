@@ -0,0 +1,367 @@
+// Package qtimigrate is the Go API of the QTI migrator. It analyzes and
+// migrates QTI documents and IMS Content Packages the way the qti-migrator
+// command does, for programs that embed the migration instead of running the
+// command.
+//
+//	result, err := qtimigrate.Migrate(ctx, input, qtimigrate.Options{To: "3.0"})
+//	if err != nil {
+//		return err
+//	}
+//	return result.Write(output)
+package qtimigrate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/qti-migrator/internal/migrator"
+	"github.com/qti-migrator/internal/packaging"
+	"github.com/qti-migrator/internal/parser"
+	"github.com/qti-migrator/internal/preprocessor"
+	"github.com/qti-migrator/internal/report"
+	"github.com/qti-migrator/pkg/analysis"
+	"github.com/qti-migrator/pkg/models"
+	"github.com/qti-migrator/pkg/registry"
+)
+
+// Options of a migration.
+type Options struct {
+	// Source version; detected from the input when empty
+	From string
+	// Target version
+	To string
+	// Level of detail of the analysis report, from 0 (minimal) to 3 (debug)
+	Verbosity int
+	// Only analyze the input, without migrating it
+	Preview bool
+	// Check the input and the migrated files against the bundled structure
+	// rules of their version and add the violations to the analysis report
+	// as errors, which do not block the migration
+	Validate bool
+	// Options of the migration steps and the output they write
+	registry.MigrationOptions
+}
+
+// Detection tells which QTI version a document has and why.
+type Detection struct {
+	Version string
+	// Root element and namespace of the document, or of the manifest of a
+	// content package
+	RootElement string
+	Namespace   string
+	// Signals the version was read from, e.g. root element <assessmentItem>
+	Evidence []string
+}
+
+// ResourceReport is the analysis of a single QTI resource of a content
+// package.
+type ResourceReport struct {
+	ResourceID string
+	Href       string
+	Report     *analysis.Report
+}
+
+// Result of a migration.
+type Result struct {
+	// Version the input is parsed as, e.g. 2.1 for QTI 2.2 input
+	SourceVersion string
+	TargetVersion string
+	// How the source version was detected when Options.From is empty. Unset
+	// for content packages, whose version is read from the manifest.
+	Detection *Detection
+	// Analysis of a single document
+	Report *analysis.Report
+	// Analysis of each QTI resource of a content package
+	Resources []ResourceReport
+	// Migrated files of a single document: the document itself without an
+	// href, or a test followed by the items it references. Unset for content
+	// packages and previews.
+	Files []models.File
+
+	pkg *packaging.Package
+	// Profile the files were written for, e.g. 2.2
+	profile string
+}
+
+// Detect reads a QTI document or a zipped content package from input and
+// returns its QTI version. The version of a package is read from the
+// resource types of its manifest.
+func Detect(input io.Reader) (*Detection, error) {
+	content, err := io.ReadAll(input)
+	if err != nil {
+		return nil, &Error{Kind: KindInput, Message: "error reading input", Err: err}
+	}
+	if packaging.IsPackage(content) {
+		return detectPackage(content)
+	}
+	detection, err := parser.Detect(content)
+	if err != nil {
+		return nil, &Error{Kind: KindDetection, Err: err}
+	}
+	return (*Detection)(detection), nil
+}
+
+func detectPackage(content []byte) (*Detection, error) {
+	pkg, err := packaging.Read(content)
+	if err != nil {
+		return nil, &Error{Kind: KindParse, Message: "error reading content package", Err: err}
+	}
+	version, err := packaging.SourceVersion(pkg)
+	if err != nil {
+		return nil, &Error{Kind: KindDetection, Err: err}
+	}
+
+	detection := &Detection{
+		Version:     version,
+		RootElement: pkg.Manifest.XMLName.Local,
+		Namespace:   pkg.Manifest.Namespace,
+		Evidence:    []string{"content package " + packaging.ManifestName},
+	}
+	seen := make(map[string]bool)
+	for _, resource := range pkg.Manifest.Resources {
+		if resource.IsQTI() && !seen[resource.Type] {
+			seen[resource.Type] = true
+			detection.Evidence = append(detection.Evidence, fmt.Sprintf("resource type %s", resource.Type))
+		}
+	}
+	return detection, nil
+}
+
+// Migrate reads a QTI document or a zipped content package from input,
+// analyzes it and migrates it to options.To. Errors are of type *Error. When
+// the analysis ran, its result is returned along with the error so that the
+// report can be shown; this is always the case for errors of KindBlocked.
+func Migrate(ctx context.Context, input io.Reader, options Options) (*Result, error) {
+	content, err := io.ReadAll(input)
+	if err != nil {
+		return nil, &Error{Kind: KindInput, Message: "error reading input", Err: err}
+	}
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	if packaging.IsPackage(content) {
+		return migratePackage(ctx, content, options)
+	}
+	return migrateDocument(ctx, content, options)
+}
+
+func migrateDocument(ctx context.Context, content []byte, options Options) (*Result, error) {
+	result := &Result{TargetVersion: options.To, profile: options.Profile}
+	// Version the input is validated as, before it is mapped to its parser
+	sourceSchema := options.From
+	if sourceSchema == "" {
+		detection, err := parser.Detect(content)
+		if err != nil {
+			return nil, &Error{Kind: KindDetection, Message: "could not detect source version", Err: err}
+		}
+		result.Detection = (*Detection)(detection)
+		sourceSchema = detection.Version
+	}
+	version, err := parserVersion(sourceSchema)
+	if err != nil {
+		return nil, err
+	}
+	result.SourceVersion = version
+	if err := checkPath(result.SourceVersion, result.TargetVersion); err != nil {
+		return nil, err
+	}
+
+	processor := preprocessor.New(options.Verbosity)
+	processor.Options = options.MigrationOptions
+	processor.SourceVersion = sourceSchema
+	report, err := processor.Analyze(content, result.SourceVersion, result.TargetVersion)
+	if err != nil {
+		return nil, &Error{Kind: KindParse, Message: "error analyzing file", Err: err}
+	}
+	result.Report = report
+	if options.Validate {
+		if err := addViolations(report, content, "", sourceSchema); err != nil {
+			return result, err
+		}
+	}
+
+	if options.Preview {
+		return result, nil
+	}
+	if report.HasErrors() {
+		return result, errBlocked()
+	}
+	if err := checkContext(ctx); err != nil {
+		return result, err
+	}
+
+	service := migrator.New()
+	service.Options = options.MigrationOptions
+	result.Files, err = service.MigrateFiles(content, result.SourceVersion, result.TargetVersion)
+	if err != nil {
+		return result, &Error{Kind: KindMigration, Message: "error during migration", Err: err}
+	}
+	if err := checkFiles(report, result.Files, outputVersion(options)); err != nil {
+		return result, err
+	}
+	if options.Validate {
+		if err := validateFiles(report, result.Files, outputVersion(options)); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func migratePackage(ctx context.Context, content []byte, options Options) (*Result, error) {
+	pkg, err := packaging.Read(content)
+	if err != nil {
+		return nil, &Error{Kind: KindParse, Message: "error reading content package", Err: err}
+	}
+
+	result := &Result{TargetVersion: options.To}
+	sourceSchema := options.From
+	if sourceSchema == "" {
+		if sourceSchema, err = packaging.SourceVersion(pkg); err != nil {
+			return nil, &Error{Kind: KindDetection, Message: "could not detect source version", Err: err}
+		}
+	}
+	if result.SourceVersion, err = parserVersion(sourceSchema); err != nil {
+		return nil, err
+	}
+	if err := checkPath(result.SourceVersion, result.TargetVersion); err != nil {
+		return nil, err
+	}
+
+	packageMigrator := packaging.New(options.Verbosity)
+	packageMigrator.Options = options.MigrationOptions
+	resources, err := packageMigrator.Analyze(pkg, result.SourceVersion, result.TargetVersion)
+	if err != nil {
+		return nil, &Error{Kind: KindParse, Message: "error analyzing content package", Err: err}
+	}
+	for _, resource := range resources {
+		result.Resources = append(result.Resources, ResourceReport(resource))
+	}
+	if options.Validate {
+		if err := validateResources(pkg, result.Resources, sourceSchema, false); err != nil {
+			return result, err
+		}
+	}
+
+	if options.Preview {
+		return result, nil
+	}
+	if result.Blocked() {
+		return result, errBlocked()
+	}
+	if err := checkContext(ctx); err != nil {
+		return result, err
+	}
+
+	if err := packageMigrator.Migrate(pkg, result.SourceVersion, result.TargetVersion); err != nil {
+		return result, &Error{Kind: KindMigration, Message: "error during migration", Err: err}
+	}
+	if err := checkResources(pkg, result.Resources, outputVersion(options)); err != nil {
+		return result, err
+	}
+	if options.Validate {
+		if err := validateResources(pkg, result.Resources, outputVersion(options), true); err != nil {
+			return result, err
+		}
+	}
+	result.pkg = pkg
+	return result, nil
+}
+
+// parserVersion returns the version of the parser for a detected version,
+// e.g. 2.1 for QTI 2.2 documents.
+func parserVersion(version string) (string, error) {
+	sourceParser, err := parser.GetParser(version)
+	if err != nil {
+		return "", &Error{Kind: KindUnsupported, Err: err}
+	}
+	return sourceParser.Version(), nil
+}
+
+// outputVersion returns the version the migrated files are written for.
+func outputVersion(options Options) string {
+	if options.Profile != "" {
+		return options.Profile
+	}
+	return options.To
+}
+
+func checkPath(fromVersion, toVersion string) error {
+	if _, err := parser.GetParser(fromVersion); err != nil {
+		return &Error{Kind: KindUnsupported, Err: err}
+	}
+	if _, err := registry.Default.Plan(fromVersion, toVersion); err != nil {
+		return &Error{Kind: KindUnsupported, Err: err}
+	}
+	if _, err := registry.Default.Serializer(toVersion); err != nil {
+		return &Error{Kind: KindUnsupported, Err: err}
+	}
+	return nil
+}
+
+// FormatReport writes an analysis report as text for people to read, with
+// the detail of verbosity, from 0 (minimal) to 3 (debug).
+func FormatReport(analysisReport *analysis.Report, verbosity int) string {
+	return report.New(verbosity).Generate(analysisReport)
+}
+
+// Blocked tells whether the analysis found an error that blocks migration.
+func (r *Result) Blocked() bool {
+	if r.Report != nil && r.Report.HasErrors() {
+		return true
+	}
+	for _, resource := range r.Resources {
+		if resource.Report.HasErrors() {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPackage tells whether the input was a content package.
+func (r *Result) IsPackage() bool {
+	return r.pkg != nil
+}
+
+// Write writes the migrated document, or a content package when the input
+// was a package or the migration wrote several files.
+func (r *Result) Write(w io.Writer) error {
+	if r.pkg == nil && len(r.Files) == 1 {
+		_, err := io.Copy(w, bytes.NewReader(r.Files[0].Content))
+		return err
+	}
+	pkg, err := r.contentPackage()
+	if err != nil {
+		return err
+	}
+	return pkg.Write(w)
+}
+
+// WriteDir writes the files of a migrated package or of a migration that
+// wrote several files below dir, along with their imsmanifest.xml.
+func (r *Result) WriteDir(dir string) error {
+	pkg, err := r.contentPackage()
+	if err != nil {
+		return err
+	}
+	return pkg.WriteDir(dir)
+}
+
+func (r *Result) contentPackage() (*packaging.Package, error) {
+	switch {
+	case r.pkg != nil:
+		return r.pkg, nil
+	case len(r.Files) > 1:
+		version := r.TargetVersion
+		if r.profile != "" {
+			version = r.profile
+		}
+		return packaging.FromFiles(r.Files, version), nil
+	case len(r.Files) == 1:
+		return nil, fmt.Errorf("a single migrated document is not written as a content package")
+	default:
+		return nil, fmt.Errorf("nothing was migrated")
+	}
+}
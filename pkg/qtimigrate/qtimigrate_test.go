@@ -0,0 +1,388 @@
+package qtimigrate
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/qti-migrator/internal/packaging/packagetest"
+	"github.com/qti-migrator/pkg/analysis"
+	"github.com/qti-migrator/pkg/models"
+	"github.com/qti-migrator/pkg/registry"
+)
+
+const testItem12 = `<?xml version="1.0" encoding="UTF-8"?>
+<questestinterop>
+	<item ident="q001" title="Test Question">
+		<presentation>
+			<material><mattext>What is 2 + 2?</mattext></material>
+			<response_lid ident="RESPONSE" rcardinality="single">
+				<render_choice>
+					<response_label ident="A"><material><mattext>3</mattext></material></response_label>
+					<response_label ident="B"><material><mattext>4</mattext></material></response_label>
+				</render_choice>
+			</response_lid>
+		</presentation>
+	</item>
+</questestinterop>`
+
+const testManifest12 = `<?xml version="1.0" encoding="UTF-8"?>
+<manifest identifier="MANIFEST1" xmlns="http://www.imsglobal.org/xsd/imscp_v1p1">
+	<organizations/>
+	<resources>
+		<resource identifier="RES1" type="imsqti_xmlv1p2" href="q001.xml">
+			<file href="q001.xml"/>
+		</resource>
+	</resources>
+</manifest>`
+
+func TestMigrate_Document(t *testing.T) {
+	result, err := Migrate(context.Background(), strings.NewReader(testItem12), Options{To: "3.0"})
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+
+	if result.SourceVersion != "1.2" || result.Detection == nil {
+		t.Errorf("Expected the source version to be detected as 1.2, got %q", result.SourceVersion)
+	}
+	if result.Report == nil || strings.Join(result.Report.Path, " ") != "1.2 2.1 3.0" {
+		t.Errorf("Expected a report of the migration through 2.1, got %+v", result.Report)
+	}
+	if len(result.Files) != 1 || result.IsPackage() {
+		t.Fatalf("Expected a single migrated document, got %d files", len(result.Files))
+	}
+
+	var output bytes.Buffer
+	if err := result.Write(&output); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if !strings.Contains(output.String(), "<qti-choice-interaction") {
+		t.Errorf("Expected a QTI 3.0 choice interaction, got:\n%s", output.String())
+	}
+}
+
+func TestMigrate_Preview(t *testing.T) {
+	result, err := Migrate(context.Background(), strings.NewReader(testItem12), Options{From: "1.2", To: "2.1", Preview: true})
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+	if result.Report == nil || result.Detection != nil {
+		t.Error("Expected a report without detection")
+	}
+	if len(result.Files) != 0 {
+		t.Errorf("Expected a preview to migrate nothing, got %d files", len(result.Files))
+	}
+}
+
+func TestMigrate_Package(t *testing.T) {
+	content := packagetest.Zip(t, map[string]string{
+		"imsmanifest.xml": testManifest12,
+		"q001.xml":        testItem12,
+	})
+
+	result, err := Migrate(context.Background(), bytes.NewReader(content), Options{To: "2.1"})
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	if !result.IsPackage() || len(result.Resources) != 1 || result.Resources[0].ResourceID != "RES1" {
+		t.Fatalf("Expected a migrated package with one resource report, got %+v", result.Resources)
+	}
+
+	var output bytes.Buffer
+	if err := result.Write(&output); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	reader, err := zip.NewReader(bytes.NewReader(output.Bytes()), int64(output.Len()))
+	if err != nil {
+		t.Fatalf("Expected a zip archive: %v", err)
+	}
+	for _, file := range reader.File {
+		if file.Name != "imsmanifest.xml" {
+			continue
+		}
+		rc, _ := file.Open()
+		manifest, _ := io.ReadAll(rc)
+		rc.Close()
+		if !strings.Contains(string(manifest), "imsqti_item_xmlv2p1") {
+			t.Errorf("Expected the manifest to be retargeted, got:\n%s", manifest)
+		}
+	}
+}
+
+func TestMigrate_Errors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		options Options
+		kind    ErrorKind
+	}{
+		{"not QTI", `<html/>`, Options{To: "2.1"}, KindDetection},
+		{"unsupported version", testItem12, Options{From: "1.0", To: "2.1"}, KindUnsupported},
+		{"unsupported path", testItem12, Options{To: "4.0"}, KindUnsupported},
+		{"invalid XML", `<questestinterop><item>`, Options{From: "1.2", To: "2.1"}, KindParse},
+		{"invalid package", "PK\x03\x04", Options{To: "2.1"}, KindParse},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Migrate(context.Background(), strings.NewReader(tc.content), tc.options)
+			var migrationErr *Error
+			if !errors.As(err, &migrationErr) {
+				t.Fatalf("Expected an *Error, got %v", err)
+			}
+			if migrationErr.Kind != tc.kind {
+				t.Errorf("Expected a %s error, got %s: %v", tc.kind, migrationErr.Kind, err)
+			}
+		})
+	}
+}
+
+func TestMigrate_From22(t *testing.T) {
+	item := `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p2" identifier="q1" title="Q1" adaptive="false" timeDependent="false">
+	<itemBody><p>What is 2 + 2?</p></itemBody>
+</assessmentItem>`
+	manifest := `<manifest identifier="M" xmlns="http://www.imsglobal.org/xsd/imscp_v1p1">
+	<organizations/>
+	<resources>
+		<resource identifier="RES1" type="imsqti_item_xmlv2p2" href="q1.xml">
+			<file href="q1.xml"/>
+		</resource>
+	</resources>
+</manifest>`
+
+	for name, content := range map[string]string{
+		"document": item,
+		"package":  string(packagetest.Zip(t, map[string]string{"imsmanifest.xml": manifest, "q1.xml": item})),
+	} {
+		t.Run(name, func(t *testing.T) {
+			result, err := Migrate(context.Background(), strings.NewReader(content), Options{From: "2.2", To: "3.0", Validate: true})
+			if err != nil {
+				t.Fatalf("Migration failed: %v", err)
+			}
+			if result.SourceVersion != "2.1" {
+				t.Errorf("Expected QTI 2.2 input to be read as 2.1, got %s", result.SourceVersion)
+			}
+		})
+	}
+}
+
+func TestMigrate_Blocked(t *testing.T) {
+	err := registry.RegisterStep(registry.Step{
+		From: "2.1",
+		To:   "blocked-test",
+		New:  func() registry.Migrator { return nil },
+		Analyze: func(doc *models.QTIDocument, report *analysis.Report, verbosity int) {
+			report.Errors = append(report.Errors, analysis.Error{Message: "cannot migrate", Fatal: true})
+		},
+	})
+	if err == nil {
+		err = registry.RegisterSerializer(registry.SerializerInfo{
+			Version: "blocked-test",
+			New:     func() registry.Serializer { return nil },
+		})
+	}
+	if err != nil {
+		t.Fatalf("Failed to register step: %v", err)
+	}
+
+	item := `<assessmentItem identifier="q1" title="Q1"/>`
+	result, err := Migrate(context.Background(), strings.NewReader(item), Options{To: "blocked-test"})
+	var migrationErr *Error
+	if !errors.As(err, &migrationErr) || migrationErr.Kind != KindBlocked {
+		t.Fatalf("Expected a blocked error, got %v", err)
+	}
+	if result == nil || !result.Blocked() || len(result.Report.Errors) != 1 {
+		t.Errorf("Expected the report to be returned with the error, got %+v", result)
+	}
+}
+
+func TestMigrate_Options(t *testing.T) {
+	items := `<questestinterop>
+	<item ident="q 1"><presentation><material><mattext>One</mattext></material></presentation></item>
+	<item ident="q2"><presentation><material><mattext>Two</mattext></material></presentation></item>
+</questestinterop>`
+
+	result, err := Migrate(context.Background(), strings.NewReader(items), Options{
+		To: "2.1",
+		MigrationOptions: registry.MigrationOptions{
+			Identifiers: registry.IdentifiersSanitize,
+			Layout:      registry.LayoutFlat,
+			Profile:     "2.2",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	if len(result.Files) != 3 || result.Files[1].Href != "q_1.xml" || result.Files[2].Href != "q2.xml" {
+		t.Fatalf("Expected a test and two item files next to it, got %+v", result.Files)
+	}
+	if !strings.Contains(string(result.Files[1].Content), `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p2" identifier="q_1"`) {
+		t.Errorf("Expected a sanitized QTI 2.2 item, got:\n%s", result.Files[1].Content)
+	}
+
+	_, err = Migrate(context.Background(), strings.NewReader(items), Options{
+		To:               "2.1",
+		MigrationOptions: registry.MigrationOptions{Layout: registry.LayoutSingle},
+	})
+	var migrationErr *Error
+	if !errors.As(err, &migrationErr) || migrationErr.Kind != KindMigration {
+		t.Errorf("Expected a single file layout to fail on two items, got %v", err)
+	}
+}
+
+func TestMigrate_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := Migrate(ctx, strings.NewReader(testItem12), Options{To: "2.1"})
+	var migrationErr *Error
+	if !errors.As(err, &migrationErr) || migrationErr.Kind != KindCanceled || !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a canceled *Error wrapping context.Canceled, got %v", err)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	detection, err := Detect(strings.NewReader(`<qti-assessment-item identifier="q1"/>`))
+	if err != nil {
+		t.Fatalf("Detection failed: %v", err)
+	}
+	if detection.Version != "3.0" {
+		t.Errorf("Expected version 3.0, got %s", detection.Version)
+	}
+
+	content := packagetest.Zip(t, map[string]string{
+		"imsmanifest.xml": testManifest12,
+		"q001.xml":        testItem12,
+	})
+	detection, err = Detect(bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("Package detection failed: %v", err)
+	}
+	if detection.Version != "1.2" || detection.RootElement != "manifest" {
+		t.Errorf("Expected a QTI 1.2 manifest, got %+v", detection)
+	}
+}
+
+func TestMigrate_Validate(t *testing.T) {
+	result, err := Migrate(context.Background(), strings.NewReader(testItem12), Options{To: "2.1", Validate: true})
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	if result.Blocked() {
+		t.Error("Expected structure violations not to block the migration")
+	}
+
+	steps := map[string]int{}
+	for _, reportErr := range result.Report.Errors {
+		steps[reportErr.Step]++
+		if reportErr.Step == "1.2 structure" && reportErr.ElementPath != "/questestinterop/item/presentation/response_lid" {
+			t.Errorf("Expected the source violation on response_lid, got %+v", reportErr)
+		}
+	}
+	// rcardinality="single" is not a QTI 1.2 value, and the migrated
+	// choiceInteraction lacks the attributes QTI 2.1 requires
+	if steps["1.2 structure"] != 1 || steps["2.1 structure"] == 0 {
+		t.Errorf("Expected violations of the source and of the output, got %+v", result.Report.Errors)
+	}
+}
+
+func TestMigrate_ValidateOutput30(t *testing.T) {
+	item := `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="q1" title="Clip" adaptive="false" timeDependent="false">
+	<responseDeclaration identifier="RESPONSE" cardinality="single" baseType="identifier"/>
+	<itemBody>
+		<rubricBlock view="scorer"><p>Key: A</p></rubricBlock>
+		<div><object type="video/mp4" data="clip.mp4">Clip</object></div>
+		<choiceInteraction responseIdentifier="RESPONSE" shuffle="false" maxChoices="1">
+			<simpleChoice identifier="A">A</simpleChoice>
+		</choiceInteraction>
+	</itemBody>
+</assessmentItem>`
+
+	result, err := Migrate(context.Background(), strings.NewReader(item), Options{To: "3.0", Validate: true})
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	for _, reportErr := range result.Report.Errors {
+		if reportErr.Step == "3.0 structure" {
+			t.Errorf("Expected the output to follow the QTI 3.0 structure rules, got %+v", reportErr)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	violations, err := Validate(strings.NewReader(testItem12), "")
+	if err != nil {
+		t.Fatalf("Validation failed: %v", err)
+	}
+	if len(violations) != 1 || !strings.Contains(violations[0].Message, "rcardinality") {
+		t.Errorf("Expected the rcardinality value to be reported, got %v", violations)
+	}
+
+	content := packagetest.Zip(t, map[string]string{
+		"imsmanifest.xml": testManifest12,
+		"q001.xml":        testItem12,
+	})
+	violations, err = Validate(bytes.NewReader(content), "")
+	if err != nil {
+		t.Fatalf("Validation of the package failed: %v", err)
+	}
+	if len(violations) != 1 || violations[0].File != "q001.xml" {
+		t.Errorf("Expected the violation of q001.xml, got %v", violations)
+	}
+
+	_, err = Validate(strings.NewReader(testItem12), "4.0")
+	var validationErr *Error
+	if !errors.As(err, &validationErr) || validationErr.Kind != KindUnsupported {
+		t.Errorf("Expected an unsupported version error, got %v", err)
+	}
+}
+
+func TestMigrate_Consistency(t *testing.T) {
+	item := `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="q1" title="Capital" adaptive="false" timeDependent="false">
+	<responseDeclaration identifier="RESPONSE" cardinality="single" baseType="identifier"/>
+	<itemBody>
+		<choiceInteraction responseIdentifier="RESPONSE" shuffle="false" maxChoices="1">
+			<simpleChoice identifier="A">Paris</simpleChoice>
+		</choiceInteraction>
+	</itemBody>
+	<modalFeedback outcomeIdentifier="FEEDBACK" identifier="fb" showHide="show">Yes</modalFeedback>
+</assessmentItem>`
+
+	result, err := Migrate(context.Background(), strings.NewReader(item), Options{To: "3.0"})
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+
+	// The source is checked during the analysis and the output after the
+	// migration
+	steps := map[string]int{}
+	for _, reportErr := range result.Report.Errors {
+		if reportErr.Rule != "undeclared-feedback-outcome" {
+			t.Errorf("Expected only the undeclared FEEDBACK outcome, got %+v", reportErr)
+		}
+		steps[reportErr.Step]++
+	}
+	if steps["2.1"] != 1 || steps["3.0"] != 1 {
+		t.Errorf("Expected the finding before and after the migration, got %+v", result.Report.Errors)
+	}
+
+	content := packagetest.Zip(t, map[string]string{
+		"imsmanifest.xml": `<manifest identifier="MANIFEST1" xmlns="http://www.imsglobal.org/xsd/imscp_v1p1">
+	<resources>
+		<resource identifier="RES1" type="imsqti_item_xmlv2p1" href="q1.xml"><file href="q1.xml"/></resource>
+	</resources>
+</manifest>`,
+		"q1.xml": item,
+	})
+	packageResult, err := Migrate(context.Background(), bytes.NewReader(content), Options{To: "3.0"})
+	if err != nil {
+		t.Fatalf("Package migration failed: %v", err)
+	}
+	if errs := packageResult.Resources[0].Report.Errors; len(errs) != 2 || errs[1].Step != "3.0" || errs[1].ItemID != "q1" {
+		t.Errorf("Expected the finding in the source and the migrated package, got %+v", errs)
+	}
+}
@@ -0,0 +1,187 @@
+package qtimigrate
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/qti-migrator/internal/packaging"
+	"github.com/qti-migrator/internal/parser"
+	"github.com/qti-migrator/internal/structure"
+	"github.com/qti-migrator/pkg/analysis"
+	"github.com/qti-migrator/pkg/models"
+)
+
+// Violation is a place where a document does not follow the structure rules
+// of its QTI version.
+type Violation struct {
+	// File of a content package the violation is in; empty for a single
+	// document
+	File string
+	// Path of the element, e.g. /assessmentItem/itemBody/p[2]
+	Path    string
+	Line    int
+	Message string
+}
+
+// String returns the violation as "file: path (line 3): message".
+func (v Violation) String() string {
+	return structure.Violation(v).String()
+}
+
+// RuleVersions returns the QTI versions Validate has structure rules for,
+// sorted.
+func RuleVersions() []string {
+	return structure.Versions()
+}
+
+// Validate reads a QTI document or a zipped content package from input and
+// checks it against the bundled structure rules of version, which is
+// detected from the input when empty. The rules are not the official IMS
+// Global schemas, so a document without violations may still be invalid.
+// For packages the main file of each QTI resource is checked, and violations
+// carry the file they are in.
+func Validate(input io.Reader, version string) ([]Violation, error) {
+	content, err := io.ReadAll(input)
+	if err != nil {
+		return nil, &Error{Kind: KindInput, Message: "error reading input", Err: err}
+	}
+	if packaging.IsPackage(content) {
+		return validatePackageFiles(content, version)
+	}
+
+	if version == "" {
+		detection, err := parser.Detect(content)
+		if err != nil {
+			return nil, &Error{Kind: KindDetection, Message: "could not detect QTI version", Err: err}
+		}
+		version = detection.Version
+	}
+	return validateFile(content, "", version)
+}
+
+func validatePackageFiles(content []byte, version string) ([]Violation, error) {
+	pkg, err := packaging.Read(content)
+	if err != nil {
+		return nil, &Error{Kind: KindParse, Message: "error reading content package", Err: err}
+	}
+	if version == "" {
+		if version, err = packaging.SourceVersion(pkg); err != nil {
+			return nil, &Error{Kind: KindDetection, Message: "could not detect QTI version", Err: err}
+		}
+	}
+
+	var violations []Violation
+	for _, resource := range pkg.Manifest.Resources {
+		if !resource.IsQTI() {
+			continue
+		}
+		href := resource.MainFile()
+		file, ok := pkg.File(href)
+		if !ok {
+			return nil, &Error{Kind: KindParse, Message: fmt.Sprintf("resource %s references missing file %s", resource.Identifier, href)}
+		}
+		fileViolations, err := validateFile(file, href, version)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, fileViolations...)
+	}
+	return violations, nil
+}
+
+// validateFile checks a single document and sets the file of its
+// violations to href.
+func validateFile(content []byte, href, version string) ([]Violation, error) {
+	rules, err := structure.For(version)
+	if err != nil {
+		return nil, &Error{Kind: KindUnsupported, Err: err}
+	}
+	found, err := rules.Validate(content)
+	if err != nil {
+		return nil, &Error{Kind: KindParse, Message: fmt.Sprintf("error checking %s", describeFile(href)), Err: err}
+	}
+	var violations []Violation
+	for _, violation := range found {
+		violation.File = href
+		violations = append(violations, Violation(violation))
+	}
+	return violations, nil
+}
+
+func describeFile(href string) string {
+	if href == "" {
+		return "document"
+	}
+	return href
+}
+
+// addViolations checks a document against the structure rules of version
+// and adds what it finds to report as errors that do not block the migration.
+func addViolations(report *analysis.Report, content []byte, href, version string) error {
+	violations, err := validateFile(content, href, version)
+	if err != nil {
+		return err
+	}
+	for _, violation := range violations {
+		report.Errors = append(report.Errors, analysis.Error{
+			ItemID:      href,
+			ElementPath: violation.Path,
+			Message:     fmt.Sprintf("%s (line %d)", violation.Message, violation.Line),
+			Step:        version + " structure",
+		})
+	}
+	return nil
+}
+
+// validateFiles adds the violations of migrated files to report.
+func validateFiles(report *analysis.Report, files []models.File, version string) error {
+	for _, file := range files {
+		if err := addViolations(report, file.Content, file.Href, version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateResources adds the violations of the QTI files of a package to the
+// report of the resource they belong to.
+func validateResources(pkg *packaging.Package, resources []ResourceReport, version string, migrated bool) error {
+	return eachResourceFile(pkg, resources, migrated, func(report *analysis.Report, content []byte, href string) error {
+		return addViolations(report, content, href, version)
+	})
+}
+
+// eachResourceFile calls visit with the report of each resource and the
+// content of its main file, or after a migration of each of its QTI files.
+// Files a migration split off a resource, such as the items of a test, are
+// visited with that resource unless they have a report of their own.
+func eachResourceFile(pkg *packaging.Package, resources []ResourceReport, migrated bool, visit func(report *analysis.Report, content []byte, href string) error) error {
+	reported := map[string]bool{}
+	for _, resource := range resources {
+		reported[resource.Href] = true
+	}
+
+	for _, resourceReport := range resources {
+		resource := pkg.Manifest.Resource(resourceReport.ResourceID)
+		if resource == nil {
+			continue
+		}
+		files := []string{resourceReport.Href}
+		if migrated {
+			files = pkg.QTIFiles(resource)
+		}
+		for i, href := range files {
+			if i > 0 && reported[href] {
+				continue
+			}
+			content, ok := pkg.File(href)
+			if !ok {
+				continue
+			}
+			if err := visit(resourceReport.Report, content, href); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/qti-migrator/pkg/models"
+)
+
+// MigrationOptions configure a single migration run. The zero value migrates
+// the way the command does without flags.
+type MigrationOptions struct {
+	// Strict makes a step fail on content it would otherwise approximate or
+	// drop, such as responses without an interaction in the target version
+	Strict bool
+	// How identifiers of items, tests and sections that are not valid in
+	// the target version are handled
+	Identifiers IdentifierPolicy
+	// Outcome declared for QTI 1.2 items whose response processing declares
+	// none; SCORE, a float defaulting to 0.0, when nil
+	ScoreOutcome *models.OutcomeDecl
+	// How items and tests are laid out in files
+	Layout Layout
+	// Version of the target version's family the output is written for,
+	// e.g. 2.2 for a migration to 2.1; the target version when empty
+	Profile string
+}
+
+// Layout tells a serializer how to write a document in files.
+type Layout int
+
+const (
+	// A single item is one file; several items, or a test, are one file per
+	// item under items/ and a test that references them
+	LayoutFiles Layout = iota
+	// As LayoutFiles, with the item files next to the test
+	LayoutFlat
+	// A single file; documents that need several fail
+	LayoutSingle
+)
+
+var layoutNames = []string{"files", "flat", "single"}
+
+func (l Layout) String() string {
+	if int(l) < len(layoutNames) {
+		return layoutNames[l]
+	}
+	return fmt.Sprintf("Layout(%d)", int(l))
+}
+
+// ParseLayout reads a layout by its name, e.g. "flat".
+func ParseLayout(name string) (Layout, error) {
+	for i, layoutName := range layoutNames {
+		if name == layoutName {
+			return Layout(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown layout %q, expected one of %s", name, strings.Join(layoutNames, ", "))
+}
+
+// ItemDirectory returns the directory that holds the item files of a test,
+// relative to the test.
+func (l Layout) ItemDirectory() string {
+	if l == LayoutFlat {
+		return ""
+	}
+	return "items"
+}
+
+// IdentifierPolicy tells how identifiers that are not valid in QTI 2.x and
+// 3.0, where they have to start with a letter or underscore and may only
+// hold letters, digits, dots, dashes and underscores, are handled.
+type IdentifierPolicy int
+
+const (
+	// Identifiers are kept as they are
+	IdentifiersKeep IdentifierPolicy = iota
+	// Characters that are not allowed are replaced by underscores, and
+	// identifiers that do not start with a letter get an underscore prefix
+	IdentifiersSanitize
+	// Invalid identifiers fail the migration
+	IdentifiersReject
+)
+
+var identifierPolicyNames = []string{"keep", "sanitize", "reject"}
+
+func (p IdentifierPolicy) String() string {
+	if int(p) < len(identifierPolicyNames) {
+		return identifierPolicyNames[p]
+	}
+	return fmt.Sprintf("IdentifierPolicy(%d)", int(p))
+}
+
+// ParseIdentifierPolicy reads an identifier policy by its name, e.g.
+// "sanitize".
+func ParseIdentifierPolicy(name string) (IdentifierPolicy, error) {
+	for i, policyName := range identifierPolicyNames {
+		if name == policyName {
+			return IdentifierPolicy(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown identifier policy %q, expected one of %s", name, strings.Join(identifierPolicyNames, ", "))
+}
+
+// Identifier returns identifier as the policy has it. Empty identifiers are
+// left to the serializers, which name them.
+func (p IdentifierPolicy) Identifier(identifier string) (string, error) {
+	if p == IdentifiersKeep || identifier == "" || ValidIdentifier(identifier) {
+		return identifier, nil
+	}
+	if p == IdentifiersReject {
+		return "", fmt.Errorf("%q is not a valid QTI identifier", identifier)
+	}
+
+	sanitized := strings.Map(func(r rune) rune {
+		if isIdentifierRune(r) {
+			return r
+		}
+		return '_'
+	}, identifier)
+	if !isIdentifierStart(rune(sanitized[0])) {
+		sanitized = "_" + sanitized
+	}
+	return sanitized, nil
+}
+
+// Apply applies the policy to the identifiers of the items, tests and
+// sections of doc.
+func (p IdentifierPolicy) Apply(doc *models.QTIDocument) error {
+	if p == IdentifiersKeep {
+		return nil
+	}
+	for i := range doc.Items {
+		if err := p.applyItem(&doc.Items[i]); err != nil {
+			return err
+		}
+	}
+	if doc.Assessment == nil {
+		return nil
+	}
+	ident, err := p.Identifier(doc.Assessment.Ident)
+	if err != nil {
+		return fmt.Errorf("test: %w", err)
+	}
+	doc.Assessment.Ident = ident
+	for i := range doc.Assessment.Sections {
+		if err := p.applySection(&doc.Assessment.Sections[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p IdentifierPolicy) applySection(section *models.Section) error {
+	ident, err := p.Identifier(section.Ident)
+	if err != nil {
+		return fmt.Errorf("section: %w", err)
+	}
+	section.Ident = ident
+	for i := range section.ItemRefs {
+		ident, err := p.Identifier(section.ItemRefs[i].Identifier)
+		if err != nil {
+			return fmt.Errorf("item ref: %w", err)
+		}
+		section.ItemRefs[i].Identifier = ident
+	}
+	for i := range section.Items {
+		if err := p.applyItem(&section.Items[i]); err != nil {
+			return err
+		}
+	}
+	for i := range section.Sections {
+		if err := p.applySection(&section.Sections[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p IdentifierPolicy) applyItem(item *models.Item) error {
+	ident, err := p.Identifier(item.Ident)
+	if err != nil {
+		return fmt.Errorf("item: %w", err)
+	}
+	item.Ident = ident
+	return nil
+}
+
+// ValidIdentifier tells whether identifier is a valid QTI 2.x and 3.0
+// identifier.
+func ValidIdentifier(identifier string) bool {
+	for i, r := range identifier {
+		if !isIdentifierRune(r) || i == 0 && !isIdentifierStart(r) {
+			return false
+		}
+	}
+	return identifier != ""
+}
+
+func isIdentifierStart(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '_'
+}
+
+func isIdentifierRune(r rune) bool {
+	return isIdentifierStart(r) || r >= '0' && r <= '9' || r == '.' || r == '-'
+}
@@ -0,0 +1,80 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/qti-migrator/pkg/models"
+)
+
+func TestParseLayout(t *testing.T) {
+	for _, layout := range []Layout{LayoutFiles, LayoutFlat, LayoutSingle} {
+		parsed, err := ParseLayout(layout.String())
+		if err != nil || parsed != layout {
+			t.Errorf("Expected %s to parse back, got %s, %v", layout, parsed, err)
+		}
+	}
+	if _, err := ParseLayout("nested"); err == nil {
+		t.Error("Expected an unknown layout to fail")
+	}
+
+	if LayoutFiles.ItemDirectory() != "items" || LayoutFlat.ItemDirectory() != "" {
+		t.Errorf("Unexpected item directories %q and %q", LayoutFiles.ItemDirectory(), LayoutFlat.ItemDirectory())
+	}
+}
+
+func TestIdentifierPolicy_Identifier(t *testing.T) {
+	tests := []struct {
+		policy     IdentifierPolicy
+		identifier string
+		expected   string
+		fails      bool
+	}{
+		{IdentifiersKeep, "1 a", "1 a", false},
+		{IdentifiersSanitize, "q-1.a_b", "q-1.a_b", false},
+		{IdentifiersSanitize, "item 1/a", "item_1_a", false},
+		{IdentifiersSanitize, "1a", "_1a", false},
+		{IdentifiersSanitize, "", "", false},
+		{IdentifiersReject, "q1", "q1", false},
+		{IdentifiersReject, "q 1", "", true},
+	}
+
+	for _, tt := range tests {
+		identifier, err := tt.policy.Identifier(tt.identifier)
+		if (err != nil) != tt.fails || identifier != tt.expected {
+			t.Errorf("%s of %q: expected %q (fails: %v), got %q, %v", tt.policy, tt.identifier, tt.expected, tt.fails, identifier, err)
+		}
+	}
+
+	if _, err := ParseIdentifierPolicy("sanitize"); err != nil {
+		t.Errorf("Expected sanitize to parse, got %v", err)
+	}
+	if _, err := ParseIdentifierPolicy("fix"); err == nil {
+		t.Error("Expected an unknown policy to fail")
+	}
+}
+
+func TestIdentifierPolicy_Apply(t *testing.T) {
+	doc := &models.QTIDocument{
+		Items: []models.Item{{Ident: "item 1"}},
+		Assessment: &models.Assessment{
+			Ident: "test 1",
+			Sections: []models.Section{{
+				Ident:    "s1",
+				ItemRefs: []models.ItemRef{{Identifier: "ref 1"}},
+				Sections: []models.Section{{Items: []models.Item{{Ident: "2"}}}},
+			}},
+		},
+	}
+
+	if err := IdentifiersReject.Apply(doc); err == nil {
+		t.Error("Expected reject to fail on invalid identifiers")
+	}
+	if err := IdentifiersSanitize.Apply(doc); err != nil {
+		t.Fatalf("Sanitize failed: %v", err)
+	}
+	section := doc.Assessment.Sections[0]
+	if doc.Items[0].Ident != "item_1" || doc.Assessment.Ident != "test_1" ||
+		section.ItemRefs[0].Identifier != "ref_1" || section.Sections[0].Items[0].Ident != "_2" {
+		t.Errorf("Unexpected identifiers after sanitizing: %+v", doc)
+	}
+}
@@ -0,0 +1,318 @@
+// Package registry lets parsers, migration steps and serializers register
+// themselves with the QTI versions they handle. Migrations between two
+// versions are planned over the registered steps, so a step registered from
+// another module, such as a vendor dialect reader or an in-house transform,
+// is picked up by the migrate command without changes to this module.
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/qti-migrator/pkg/analysis"
+	"github.com/qti-migrator/pkg/models"
+)
+
+// Parser reads documents of a QTI version into the generic model.
+type Parser interface {
+	Parse(content []byte) (*models.QTIDocument, error)
+	Version() string
+}
+
+// Migrator migrates the model of a document to the model of the next
+// version. The result can be handed to the next step, changed, or written
+// with the Serializer of its version.
+type Migrator interface {
+	Migrate(doc *models.QTIDocument, options MigrationOptions) (*models.QTIDocument, error)
+}
+
+// Serializer writes the model of a document as the files of its version: a
+// test first, followed by the items it references, or a single document.
+type Serializer interface {
+	Serialize(doc *models.QTIDocument, options MigrationOptions) ([]models.File, error)
+}
+
+// AnalyzeFunc adds the findings of a migration step for doc to report.
+// Details meant for verbose output are only added from verbosity 2 on.
+type AnalyzeFunc func(doc *models.QTIDocument, report *analysis.Report, verbosity int)
+
+// ParserInfo registers a parser.
+type ParserInfo struct {
+	// Version the parser reads, e.g. "2.1"
+	Version string
+	// Prefixes of the versions the parser accepts, e.g. "2.1" and "2.2";
+	// Version alone when empty. The longest matching prefix wins.
+	Accepts []string
+	New     func() Parser
+}
+
+// SerializerInfo registers a serializer.
+type SerializerInfo struct {
+	// Version the serializer writes, e.g. "3.0"
+	Version string
+	New     func() Serializer
+}
+
+// Capabilities of a migration step besides handing on a model
+const (
+	// A serializer writes the version the step migrates to, so the step can
+	// end a migration
+	CapabilityFiles = "files"
+	// The step analyzes documents before they are migrated
+	CapabilityAnalysis = "analysis"
+)
+
+// Step migrates documents from one QTI version to another.
+type Step struct {
+	From    string
+	To      string
+	New     func() Migrator
+	Analyze AnalyzeFunc
+}
+
+// String returns the step as "1.2 → 2.1".
+func (s Step) String() string {
+	return s.From + " → " + s.To
+}
+
+// Migrate runs the step on doc and returns the migrated model.
+func (s Step) Migrate(doc *models.QTIDocument, options MigrationOptions) (*models.QTIDocument, error) {
+	migrated, err := s.New().Migrate(doc, options)
+	if err != nil {
+		return nil, fmt.Errorf("QTI %s migration failed: %w", s, err)
+	}
+	return migrated, nil
+}
+
+// Path is a migration between two versions and the steps it takes.
+type Path struct {
+	From  string
+	To    string
+	Steps []Step
+}
+
+// String returns the path as "1.2 → 3.0 (via 2.1)".
+func (p Path) String() string {
+	path := p.From + " → " + p.To
+	if len(p.Steps) > 1 {
+		var via []string
+		for _, step := range p.Steps[1:] {
+			via = append(via, step.From)
+		}
+		path += " (via " + strings.Join(via, ", ") + ")"
+	}
+	return path
+}
+
+// Registry holds the registered parsers, migration steps and serializers.
+type Registry struct {
+	mu          sync.RWMutex
+	parsers     []ParserInfo
+	steps       []Step
+	serializers []SerializerInfo
+}
+
+func New() *Registry {
+	return &Registry{}
+}
+
+// Default is the registry the built-in parsers and steps register with.
+var Default = New()
+
+// RegisterParser registers a parser with Default.
+func RegisterParser(info ParserInfo) error {
+	return Default.RegisterParser(info)
+}
+
+// RegisterStep registers a migration step with Default.
+func RegisterStep(step Step) error {
+	return Default.RegisterStep(step)
+}
+
+// RegisterSerializer registers a serializer with Default.
+func RegisterSerializer(info SerializerInfo) error {
+	return Default.RegisterSerializer(info)
+}
+
+// RegisterParser adds a parser. A version can only be registered once.
+func (r *Registry) RegisterParser(info ParserInfo) error {
+	if info.Version == "" || info.New == nil {
+		return fmt.Errorf("parser registration needs a version and a constructor")
+	}
+	if len(info.Accepts) == 0 {
+		info.Accepts = []string{info.Version}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, registered := range r.parsers {
+		if registered.Version == info.Version {
+			return fmt.Errorf("a parser for QTI %s is already registered", info.Version)
+		}
+	}
+	r.parsers = append(r.parsers, info)
+	return nil
+}
+
+// RegisterStep adds a migration step. A pair of versions can only be
+// registered once.
+func (r *Registry) RegisterStep(step Step) error {
+	if step.From == "" || step.To == "" || step.New == nil {
+		return fmt.Errorf("migration step registration needs both versions and a constructor")
+	}
+	if step.From == step.To {
+		return fmt.Errorf("migration step %s does not change the version", step)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, registered := range r.steps {
+		if registered.From == step.From && registered.To == step.To {
+			return fmt.Errorf("a migration step for QTI %s is already registered", step)
+		}
+	}
+	r.steps = append(r.steps, step)
+	return nil
+}
+
+// RegisterSerializer adds a serializer. A version can only be registered
+// once.
+func (r *Registry) RegisterSerializer(info SerializerInfo) error {
+	if info.Version == "" || info.New == nil {
+		return fmt.Errorf("serializer registration needs a version and a constructor")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, registered := range r.serializers {
+		if registered.Version == info.Version {
+			return fmt.Errorf("a serializer for QTI %s is already registered", info.Version)
+		}
+	}
+	r.serializers = append(r.serializers, info)
+	return nil
+}
+
+// Parser returns a parser for version, which may carry a patch level such
+// as 1.2.1.
+func (r *Registry) Parser(version string) (Parser, error) {
+	version = strings.TrimSpace(version)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var match *ParserInfo
+	longest := 0
+	for i, info := range r.parsers {
+		for _, prefix := range info.Accepts {
+			if strings.HasPrefix(version, prefix) && len(prefix) > longest {
+				match, longest = &r.parsers[i], len(prefix)
+			}
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("unsupported QTI version: %s", version)
+	}
+	return match.New(), nil
+}
+
+// Serializer returns the serializer that writes version.
+func (r *Registry) Serializer(version string) (Serializer, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, info := range r.serializers {
+		if info.Version == version {
+			return info.New(), nil
+		}
+	}
+	return nil, fmt.Errorf("QTI %s documents cannot be written", version)
+}
+
+// Capabilities lists what step supports besides handing on a model.
+func (r *Registry) Capabilities(step Step) []string {
+	var capabilities []string
+	if _, err := r.Serializer(step.To); err == nil {
+		capabilities = append(capabilities, CapabilityFiles)
+	}
+	if step.Analyze != nil {
+		capabilities = append(capabilities, CapabilityAnalysis)
+	}
+	return capabilities
+}
+
+// Plan returns the shortest sequence of steps that migrates documents from
+// one version to another.
+func (r *Registry) Plan(fromVersion, toVersion string) ([]Step, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Breadth-first search, remembering the step that reached each version
+	reachedBy := map[string]*Step{fromVersion: nil}
+	queue := []string{fromVersion}
+	for len(queue) > 0 && fromVersion != toVersion {
+		version := queue[0]
+		queue = queue[1:]
+		for i := range r.steps {
+			step := &r.steps[i]
+			if _, seen := reachedBy[step.To]; step.From != version || seen {
+				continue
+			}
+			reachedBy[step.To] = step
+			if step.To == toVersion {
+				var plan []Step
+				for v := toVersion; v != fromVersion; v = reachedBy[v].From {
+					plan = append([]Step{*reachedBy[v]}, plan...)
+				}
+				return plan, nil
+			}
+			queue = append(queue, step.To)
+		}
+	}
+	return nil, fmt.Errorf("unsupported migration path: %s to %s", fromVersion, toVersion)
+}
+
+// Steps returns the registered steps in the order they were registered.
+func (r *Registry) Steps() []Step {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Step(nil), r.steps...)
+}
+
+// Versions returns the QTI versions the registered parsers read, sorted,
+// e.g. 2.2 for a 2.1 parser that also accepts 2.2.
+func (r *Registry) Versions() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var versions []string
+	for _, info := range r.parsers {
+		versions = append(versions, info.Accepts...)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// Paths returns every migration the registered steps support, ordered by
+// source and target version.
+func (r *Registry) Paths() []Path {
+	versions := map[string]bool{}
+	for _, step := range r.Steps() {
+		versions[step.From] = true
+		versions[step.To] = true
+	}
+	var sorted []string
+	for version := range versions {
+		sorted = append(sorted, version)
+	}
+	sort.Strings(sorted)
+
+	var paths []Path
+	for _, from := range sorted {
+		for _, to := range sorted {
+			if plan, err := r.Plan(from, to); err == nil {
+				paths = append(paths, Path{From: from, To: to, Steps: plan})
+			}
+		}
+	}
+	return paths
+}
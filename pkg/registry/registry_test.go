@@ -0,0 +1,193 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/qti-migrator/pkg/analysis"
+	"github.com/qti-migrator/pkg/models"
+)
+
+type fakeParser struct{ version string }
+
+func (p fakeParser) Parse(content []byte) (*models.QTIDocument, error) {
+	return &models.QTIDocument{Version: p.version}, nil
+}
+
+func (p fakeParser) Version() string { return p.version }
+
+type fakeMigrator struct{ version string }
+
+func (m fakeMigrator) Migrate(doc *models.QTIDocument, options MigrationOptions) (*models.QTIDocument, error) {
+	if options.Strict {
+		return nil, fmt.Errorf("strict")
+	}
+	return &models.QTIDocument{Version: m.version}, nil
+}
+
+type fakeSerializer struct{}
+
+func (fakeSerializer) Serialize(doc *models.QTIDocument, options MigrationOptions) ([]models.File, error) {
+	return []models.File{{Content: []byte(doc.Version)}}, nil
+}
+
+func step(from, to string) Step {
+	return Step{From: from, To: to, New: func() Migrator { return fakeMigrator{version: to} }}
+}
+
+func TestRegistry_Parser(t *testing.T) {
+	r := New()
+	for _, info := range []ParserInfo{
+		{Version: "1.2", New: func() Parser { return fakeParser{"1.2"} }},
+		{Version: "2.1", Accepts: []string{"2.1", "2.2"}, New: func() Parser { return fakeParser{"2.1"} }},
+		{Version: "1.2-vendor", New: func() Parser { return fakeParser{"1.2-vendor"} }},
+	} {
+		if err := r.RegisterParser(info); err != nil {
+			t.Fatalf("RegisterParser(%s) failed: %v", info.Version, err)
+		}
+	}
+
+	tests := map[string]string{
+		"1.2":          "1.2",
+		" 1.2.1 ":      "1.2",
+		"2.2.0":        "2.1",
+		"1.2-vendor":   "1.2-vendor",
+		"1.2-vendor.3": "1.2-vendor",
+	}
+	for version, expected := range tests {
+		parser, err := r.Parser(version)
+		if err != nil || parser.Version() != expected {
+			t.Errorf("Parser(%q) = %v, %v, expected the %s parser", version, parser, err, expected)
+		}
+	}
+
+	if versions := strings.Join(r.Versions(), ", "); versions != "1.2, 1.2-vendor, 2.1, 2.2" {
+		t.Errorf("Expected the versions the parsers accept, got %s", versions)
+	}
+
+	for _, version := range []string{"3.0", "", "invalid"} {
+		if _, err := r.Parser(version); err == nil || !strings.Contains(err.Error(), "unsupported QTI version") {
+			t.Errorf("Expected unsupported QTI version for %q, got %v", version, err)
+		}
+	}
+
+	if err := r.RegisterParser(ParserInfo{Version: "1.2", New: func() Parser { return fakeParser{"1.2"} }}); err == nil {
+		t.Error("Expected a second 1.2 parser to be refused")
+	}
+	if err := r.RegisterParser(ParserInfo{Version: "4.0"}); err == nil {
+		t.Error("Expected a parser without constructor to be refused")
+	}
+}
+
+func TestRegistry_Plan(t *testing.T) {
+	r := New()
+	for _, s := range []Step{step("1.2", "2.1"), step("2.1", "3.0"), step("1.2-vendor", "1.2"), step("2.1", "2.2")} {
+		if err := r.RegisterStep(s); err != nil {
+			t.Fatalf("RegisterStep(%s) failed: %v", s, err)
+		}
+	}
+
+	tests := []struct {
+		from, to string
+		expected string
+	}{
+		{"1.2", "2.1", "1.2 → 2.1"},
+		{"1.2", "3.0", "1.2 → 2.1, 2.1 → 3.0"},
+		{"1.2-vendor", "3.0", "1.2-vendor → 1.2, 1.2 → 2.1, 2.1 → 3.0"},
+	}
+	for _, tt := range tests {
+		plan, err := r.Plan(tt.from, tt.to)
+		if err != nil {
+			t.Errorf("Plan(%s, %s) failed: %v", tt.from, tt.to, err)
+			continue
+		}
+		var steps []string
+		for _, s := range plan {
+			steps = append(steps, s.String())
+		}
+		if strings.Join(steps, ", ") != tt.expected {
+			t.Errorf("Plan(%s, %s) = %v, expected %s", tt.from, tt.to, steps, tt.expected)
+		}
+	}
+
+	for _, path := range [][2]string{{"3.0", "2.1"}, {"2.1", "2.1"}, {"1.2", "4.0"}} {
+		_, err := r.Plan(path[0], path[1])
+		if err == nil || err.Error() != "unsupported migration path: "+path[0]+" to "+path[1] {
+			t.Errorf("Expected unsupported migration path for %s to %s, got %v", path[0], path[1], err)
+		}
+	}
+}
+
+func TestRegistry_RegisterStep_Invalid(t *testing.T) {
+	r := New()
+	if err := r.RegisterStep(step("1.2", "2.1")); err != nil {
+		t.Fatalf("RegisterStep failed: %v", err)
+	}
+
+	for _, s := range []Step{step("1.2", "2.1"), step("2.1", "2.1"), step("", "2.1"), {From: "2.1", To: "3.0"}} {
+		if err := r.RegisterStep(s); err == nil {
+			t.Errorf("Expected step %+v to be refused", s)
+		}
+	}
+}
+
+func TestRegistry_Paths(t *testing.T) {
+	r := New()
+	r.RegisterStep(step("2.1", "3.0"))
+	r.RegisterStep(step("1.2", "2.1"))
+
+	var paths []string
+	for _, path := range r.Paths() {
+		paths = append(paths, path.String())
+	}
+	if strings.Join(paths, "; ") != "1.2 → 2.1; 1.2 → 3.0 (via 2.1); 2.1 → 3.0" {
+		t.Errorf("Unexpected paths: %v", paths)
+	}
+}
+
+func TestRegistry_Serializer(t *testing.T) {
+	r := New()
+	if err := r.RegisterSerializer(SerializerInfo{Version: "2.1", New: func() Serializer { return fakeSerializer{} }}); err != nil {
+		t.Fatalf("Failed to register serializer: %v", err)
+	}
+	if err := r.RegisterSerializer(SerializerInfo{Version: "2.1", New: func() Serializer { return fakeSerializer{} }}); err == nil {
+		t.Error("Expected a second serializer for 2.1 to be refused")
+	}
+
+	if _, err := r.Serializer("2.1"); err != nil {
+		t.Errorf("Expected a serializer for 2.1, got %v", err)
+	}
+	if _, err := r.Serializer("3.0"); err == nil || !strings.Contains(err.Error(), "cannot be written") {
+		t.Errorf("Expected no serializer for 3.0, got %v", err)
+	}
+}
+
+func TestRegistry_Capabilities(t *testing.T) {
+	r := New()
+	r.RegisterSerializer(SerializerInfo{Version: "2.1", New: func() Serializer { return fakeSerializer{} }})
+	analyze := func(doc *models.QTIDocument, report *analysis.Report, verbosity int) {}
+	tests := []struct {
+		step     Step
+		expected string
+	}{
+		{step("2.1", "3.0"), ""},
+		{Step{From: "1.2", To: "2.1", New: func() Migrator { return fakeMigrator{} }, Analyze: analyze}, "files, analysis"},
+	}
+	for _, tt := range tests {
+		if capabilities := strings.Join(r.Capabilities(tt.step), ", "); capabilities != tt.expected {
+			t.Errorf("Expected capabilities %q, got %q", tt.expected, capabilities)
+		}
+	}
+}
+
+func TestStep_Migrate(t *testing.T) {
+	doc, err := step("1.2", "2.1").Migrate(&models.QTIDocument{Version: "1.2"}, MigrationOptions{})
+	if err != nil || doc.Version != "2.1" {
+		t.Errorf("Expected a 2.1 model, got %+v, %v", doc, err)
+	}
+
+	if _, err := step("1.2", "2.1").Migrate(&models.QTIDocument{}, MigrationOptions{Strict: true}); err == nil || !strings.Contains(err.Error(), "QTI 1.2 → 2.1 migration failed") {
+		t.Errorf("Expected the error of the step to name it, got %v", err)
+	}
+}